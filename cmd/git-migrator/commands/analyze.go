@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/adamf123git/git-migrator/internal/core"
 	"github.com/adamf123git/git-migrator/internal/mapping"
 	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
 	"github.com/spf13/cobra"
@@ -15,6 +16,24 @@ var analyzeCmd = &cobra.Command{
 	Long: `Analyze a version control repository to understand its structure,
 including the number of commits, branches, tags, and unique authors.
 
+A size preflight lists files larger than --large-file-size across the whole
+history, the estimated repository size and the size per file extension,
+with recommendations such as tracking binaries with Git LFS.
+Paths Windows cannot check out (reserved names like CON, invalid
+characters, trailing dots or spaces, 260 characters and more) are listed
+too.
+
+Malformed RCS files are read as far as possible and listed under parse
+diagnostics with the file, line and column of every anomaly, so the files
+needing manual attention are known before migrating. With --strict the
+analysis fails on the first malformed file instead.
+
+A cruft report lists what tends to break a migration halfway: locks left by
+a crashed CVS server (#cvs.lock, #cvs.rfl.*), temporary files of interrupted
+RCS writes, truncated RCS files, files without a valid head revision, and
+tags and branches pointing at revisions a file does not have, each with a
+suggested remedy.
+
 This command is useful for understanding what will be migrated before
 running the actual migration.`,
 	RunE: runAnalyze,
@@ -23,6 +42,8 @@ running the actual migration.`,
 var (
 	analyzeSourceType string
 	analyzeSource     string
+	analyzeLargeMiB   int64
+	analyzeStrict     bool
 )
 
 func init() {
@@ -30,6 +51,8 @@ func init() {
 
 	analyzeCmd.Flags().StringVarP(&analyzeSourceType, "source-type", "t", "cvs", "Source VCS type (cvs or svn)")
 	analyzeCmd.Flags().StringVarP(&analyzeSource, "source", "s", "", "Path to source repository")
+	analyzeCmd.Flags().Int64Var(&analyzeLargeMiB, "large-file-size", core.DefaultLargeFileThreshold>>20, "Report files larger than this many MiB")
+	analyzeCmd.Flags().BoolVar(&analyzeStrict, "strict", false, "Fail on the first malformed RCS file")
 	var err = analyzeCmd.MarkFlagRequired("source")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
@@ -50,6 +73,7 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 
 	// Create reader
 	reader := cvs.NewReader(analyzeSource)
+	reader.SetStrict(analyzeStrict)
 
 	// Validate repository
 	fmt.Printf("Analyzing %s repository at: %s\n\n", analyzeSourceType, analyzeSource)
@@ -76,12 +100,14 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	}
 
 	authorExtractor := mapping.NewAuthorExtractor()
+	preflight := core.NewPreflight(analyzeLargeMiB << 20)
 	commitCount := 0
 
 	for commitIter.Next() {
 		commit := commitIter.Commit()
 		commitCount++
 		authorExtractor.Add(commit.Author)
+		preflight.Add(commit)
 	}
 
 	if err := commitIter.Err(); err != nil {
@@ -128,7 +154,136 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	printPreflightReport(preflight.Report())
+
+	diagnostics := reader.Diagnostics()
+	printDiagnostics(diagnostics)
+	skipped := reader.SkippedFiles()
+	printSkippedFiles(skipped)
+
+	cruft, err := reader.Cruft()
+	if err != nil {
+		return fmt.Errorf("failed to scan for cruft: %w", err)
+	}
+	printCruft(cruft)
+
+	if errors := cvs.CountErrors(diagnostics); errors > 0 || len(cruft) > 0 || len(skipped) > 0 {
+		fmt.Printf("Repository is readable, but %d parse errors and %d anomalies need attention before migrating.\n",
+			errors, len(cruft))
+		return nil
+	}
 	fmt.Println("Repository is valid and ready for migration.")
 
 	return nil
 }
+
+// printDiagnostics lists the anomalies found while parsing the RCS files
+func printDiagnostics(diagnostics []cvs.Diagnostic) {
+	if len(diagnostics) == 0 {
+		return
+	}
+	files := make(map[string]bool)
+	for _, d := range diagnostics {
+		files[d.File] = true
+	}
+	fmt.Println("Parse Diagnostics")
+	fmt.Println("=================")
+	fmt.Printf("%d errors, %d warnings in %d files\n", cvs.CountErrors(diagnostics),
+		len(diagnostics)-cvs.CountErrors(diagnostics), len(files))
+	for _, d := range diagnostics {
+		fmt.Printf("  %s\n", d)
+	}
+	fmt.Println()
+}
+
+// printSkippedFiles lists the RCS files that could not be read at all
+func printSkippedFiles(skipped []cvs.SkippedFile) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Println("Skipped Files")
+	fmt.Println("=============")
+	fmt.Printf("%d RCS files could not be read; their history will not be migrated\n", len(skipped))
+	for _, file := range skipped {
+		fmt.Printf("  %s: %v\n", file.Path, file.Err)
+	}
+	fmt.Println()
+}
+
+// printCruft lists the repository anomalies with their remedies
+func printCruft(cruft []cvs.Cruft) {
+	if len(cruft) == 0 {
+		return
+	}
+	fmt.Println("Repository Cruft")
+	fmt.Println("================")
+	for _, c := range cruft {
+		fmt.Printf("  %s: %s: %s\n", c.Path, c.Kind, c.Message)
+		fmt.Printf("    fix: %s\n", c.Remedy)
+	}
+	fmt.Println()
+}
+
+// maxExtensionRows limits the per-extension breakdown to the largest entries
+const maxExtensionRows = 10
+
+func printPreflightReport(report *core.PreflightReport) {
+	fmt.Println("Size Preflight")
+	fmt.Println("==============")
+	fmt.Printf("Files:          %d (%d revisions)\n", report.Files, report.Revisions)
+	fmt.Printf("History Size:   %s\n", core.FormatSize(report.TotalSize))
+	fmt.Printf("Estimated Pack: %s (upper bound, before delta compression)\n\n", core.FormatSize(report.EstimatedPackSize))
+
+	if len(report.LargeFiles) > 0 {
+		fmt.Printf("Files larger than %s:\n", core.FormatSize(report.Threshold))
+		for _, lf := range report.LargeFiles {
+			kind := "text"
+			if lf.Binary {
+				kind = "binary"
+			}
+			fmt.Printf("  - %s: %s at revision %s, %d large revisions (%s)\n",
+				lf.Path, core.FormatSize(lf.MaxSize), lf.Revision, lf.Revisions, kind)
+		}
+		fmt.Println()
+	}
+
+	if len(report.Extensions) > 0 {
+		fmt.Println("Size by extension:")
+		for i, es := range report.Extensions {
+			if i == maxExtensionRows {
+				fmt.Printf("  ... %d more\n", len(report.Extensions)-maxExtensionRows)
+				break
+			}
+			ext := es.Extension
+			if ext == "" {
+				ext = "(none)"
+			}
+			fmt.Printf("  %-12s %10s  %d files, %d revisions\n", ext, core.FormatSize(es.TotalSize), es.Files, es.Revisions)
+		}
+		fmt.Println()
+	}
+
+	if len(report.Unreadable) > 0 {
+		fmt.Println("Unreadable revisions:")
+		for _, msg := range report.Unreadable {
+			fmt.Printf("  - %s\n", msg)
+		}
+		fmt.Println()
+	}
+
+	if len(report.WindowsPaths) > 0 {
+		fmt.Println("Paths invalid on Windows:")
+		for _, wp := range report.WindowsPaths {
+			fmt.Printf("  - %s: %s\n", wp.Path, wp.Problem)
+		}
+		fmt.Println()
+	}
+
+	if len(report.Recommendations) > 0 {
+		fmt.Println("Recommendations:")
+		for _, rec := range report.Recommendations {
+			fmt.Printf("  - %s\n", rec)
+		}
+		fmt.Println()
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/adamf123git/git-migrator/internal/profile"
 	"github.com/stretchr/testify/require"
 )
 
@@ -31,6 +33,41 @@ func TestRunAnalyze_SuccessEmptyRepo(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestRunAnalyze_Strict(t *testing.T) {
+	dir := makeEmptyCVSRepo(t)
+	malformed := strings.Replace(resumeTestRCS, "next 1.1;", "next 1.0;", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt,v"), []byte(malformed), 0644))
+
+	oldType, oldSource, oldStrict := analyzeSourceType, analyzeSource, analyzeStrict
+	defer func() { analyzeSourceType, analyzeSource, analyzeStrict = oldType, oldSource, oldStrict }()
+	analyzeSourceType, analyzeSource = "cvs", dir
+
+	// Lenient analysis lists the diagnostics
+	analyzeStrict = false
+	require.NoError(t, runAnalyze(nil, nil))
+
+	analyzeStrict = true
+	err := runAnalyze(nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "a.txt,v:6:1: error: revision 1.2: next revision 1.0 is not in the delta list")
+}
+
+func TestRunAnalyze_Cruft(t *testing.T) {
+	dir := makeEmptyCVSRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt,v"), []byte(resumeTestRCS), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "#cvs.lock"), 0755))
+
+	oldType, oldSource := analyzeSourceType, analyzeSource
+	defer func() { analyzeSourceType, analyzeSource = oldType, oldSource }()
+	analyzeSourceType, analyzeSource = "cvs", dir
+
+	out, err := captureStdout(t, func() error { return runAnalyze(nil, nil) })
+	require.NoError(t, err)
+	require.Contains(t, out, "Repository Cruft")
+	require.Contains(t, out, "#cvs.lock: lock: CVS lock taken")
+	require.Contains(t, out, "0 parse errors and 1 anomalies need attention")
+}
+
 func TestRunAuthorsExtract_SuccessEmptyRepo(t *testing.T) {
 	dir := makeEmptyCVSRepo(t)
 
@@ -223,3 +260,53 @@ text
 	err := runAuthorsExtract(nil, nil)
 	require.NoError(t, err)
 }
+
+func TestRunMigrate_Profile(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt,v"), []byte(resumeTestRCS), 0644))
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + filepath.Join(t.TempDir(), "repo") + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	profilePath := filepath.Join(t.TempDir(), "profile.json")
+	oldCfg, oldProfile := migrateConfigFile, migrateProfile
+	defer func() { migrateConfigFile, migrateProfile = oldCfg, oldProfile }()
+	migrateConfigFile, migrateProfile = cfgPath, profilePath
+	require.NoError(t, runMigrate(migrateCmd, nil))
+
+	data, err := os.ReadFile(profilePath)
+	require.NoError(t, err)
+	var p profile.Profile
+	require.NoError(t, json.Unmarshal(data, &p))
+	require.Equal(t, 1, p.Summary[profile.KindParse].Count)
+	require.Equal(t, 2, p.Summary[profile.KindApply].Count)
+}
+
+func TestRunAuthorsExtract_DryRunResolutions(t *testing.T) {
+	dir := makeEmptyCVSRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt,v"), []byte(resumeTestRCS), 0644))
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("mapping:\n  authors:\n    bob: Bob <bob@example.com>\n"), 0644))
+
+	oldSource, oldFormat, oldConfig, oldDryRun := authorsSource, authorsFormat, authorsConfig, authorsDryRun
+	defer func() {
+		authorsSource, authorsFormat, authorsConfig, authorsDryRun = oldSource, oldFormat, oldConfig, oldDryRun
+	}()
+	authorsSource, authorsFormat, authorsConfig, authorsDryRun = dir, "yaml", cfgPath, true
+
+	out, err := captureStdout(t, func() error { return runAuthorsExtract(nil, nil) })
+	require.NoError(t, err)
+	require.Contains(t, out, "? alice (unresolved)")
+	require.Contains(t, out, "1 authors: 0 from the author map, 0 resolved from the directory, 1 unresolved")
+
+	// Without --dry-run the map gets a placeholder for the unresolved login
+	authorsDryRun = false
+	out, err = captureStdout(t, func() error { return runAuthorsExtract(nil, nil) })
+	require.NoError(t, err)
+	require.Equal(t, "alice: alice <alice@example.com>\nbob: Bob <bob@example.com>\n", out)
+
+	// An incomplete LDAP section is reported before any lookup
+	require.NoError(t, os.WriteFile(cfgPath, []byte("mapping:\n  ldap:\n    url: ldap://localhost\n"), 0644))
+	_, err = captureStdout(t, func() error { return runAuthorsExtract(nil, nil) })
+	require.ErrorContains(t, err, "mapping.ldap: ldap: baseDN is required")
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"maps"
 	"os"
 
 	"github.com/adamf123git/git-migrator/internal/mapping"
@@ -27,13 +28,22 @@ var authorsExtractCmd = &cobra.Command{
 This is useful for creating author mappings before migration.
 
 The output can be in plain text (one author per line) or YAML format
-(ready to be included in a migration config file).`,
+(ready to be included in a migration config file).
+
+With --config, the YAML output starts from the mapping.authors of the
+configuration file. If it has a mapping.ldap section, the logins missing
+from it are looked up in that LDAP or Active Directory server to fill in
+display names and corporate email addresses. --dry-run prints which authors
+come from the author map, which were resolved from the directory and which
+are unresolved, without writing the directory cache.`,
 	RunE: runAuthorsExtract,
 }
 
 var (
 	authorsSource string
 	authorsFormat string
+	authorsConfig string
+	authorsDryRun bool
 )
 
 func init() {
@@ -42,6 +52,8 @@ func init() {
 
 	authorsExtractCmd.Flags().StringVarP(&authorsSource, "source", "s", "", "Path to source repository")
 	authorsExtractCmd.Flags().StringVarP(&authorsFormat, "format", "f", "text", "Output format (text or yaml)")
+	authorsExtractCmd.Flags().StringVarP(&authorsConfig, "config", "c", "", "Configuration file with the author map and LDAP server to start from")
+	authorsExtractCmd.Flags().BoolVar(&authorsDryRun, "dry-run", false, "Show how each author would be resolved instead of printing the map")
 	var err = authorsExtractCmd.MarkFlagRequired("source")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
@@ -85,6 +97,10 @@ func runAuthorsExtract(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to close reader: %w", err)
 	}
 
+	if authorsConfig != "" || authorsDryRun {
+		return resolveExtractedAuthors(authorExtractor.List())
+	}
+
 	// Output based on format
 	switch authorsFormat {
 	case "text":
@@ -104,3 +120,110 @@ func runAuthorsExtract(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// resolveExtractedAuthors prints the author map of --config completed from
+// its directory, or with --dry-run how each login was resolved
+func resolveExtractedAuthors(logins []string) error {
+	var config ConfigFile
+	if authorsConfig != "" {
+		data, err := os.ReadFile(authorsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	var dir mapping.Directory = noDirectory{}
+	var cache *mapping.CachedDirectory
+	if ldap := config.Mapping.LDAP; ldap != nil {
+		ldapDir, err := mapping.NewLDAPDirectory(mapping.LDAPConfig{
+			URL:            ldap.URL,
+			BindDN:         ldap.BindDN,
+			Password:       os.Getenv(ldap.PasswordEnv),
+			BaseDN:         ldap.BaseDN,
+			LoginAttribute: ldap.LoginAttribute,
+			NameAttribute:  ldap.NameAttribute,
+			MailAttribute:  ldap.MailAttribute,
+			ObjectClass:    ldap.ObjectClass,
+			Timeout:        ldap.Timeout,
+			AllowPlaintext: ldap.AllowPlaintext,
+		})
+		if err != nil {
+			return fmt.Errorf("mapping.ldap: %w", err)
+		}
+		defer func() { _ = ldapDir.Close() }()
+		dir = ldapDir
+		if ldap.CacheFile != "" {
+			if cache, err = mapping.NewCachedDirectory(ldapDir, ldap.CacheFile, ldap.CacheMaxAge); err != nil {
+				return err
+			}
+			dir = cache
+		}
+	}
+
+	resolutions, err := mapping.ResolveAuthors(logins, config.Mapping.Authors, dir)
+	if err != nil {
+		return err
+	}
+
+	if authorsDryRun {
+		printAuthorResolutions(resolutions)
+		return nil
+	}
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			return err
+		}
+	}
+
+	switch authorsFormat {
+	case "text":
+		for _, r := range resolutions {
+			fmt.Println(r.Login)
+		}
+	case "yaml":
+		// Entries for logins not in this repository are kept
+		authors := make(map[string]string, len(resolutions))
+		maps.Copy(authors, config.Mapping.Authors)
+		for _, r := range resolutions {
+			authors[r.Login] = r.Author
+			if r.Source == mapping.AuthorUnresolved {
+				authors[r.Login] = fmt.Sprintf("%s <%s@example.com>", r.Login, r.Login)
+			}
+		}
+		output, err := yaml.Marshal(authors)
+		if err != nil {
+			return fmt.Errorf("failed to generate YAML: %w", err)
+		}
+		fmt.Print(string(output))
+	}
+	return nil
+}
+
+// printAuthorResolutions prints one line per login, marked with + when the
+// directory resolved it and ? when nothing did
+func printAuthorResolutions(resolutions []mapping.AuthorResolution) {
+	counts := make(map[string]int)
+	for _, r := range resolutions {
+		counts[r.Source]++
+		switch r.Source {
+		case mapping.AuthorFromDirectory:
+			fmt.Printf("+ %s: %s (directory)\n", r.Login, r.Author)
+		case mapping.AuthorUnresolved:
+			fmt.Printf("? %s (unresolved)\n", r.Login)
+		default:
+			fmt.Printf("  %s: %s (author map)\n", r.Login, r.Author)
+		}
+	}
+	fmt.Printf("\n%d authors: %d from the author map, %d resolved from the directory, %d unresolved\n",
+		len(resolutions), counts[mapping.AuthorFromMap], counts[mapping.AuthorFromDirectory], counts[mapping.AuthorUnresolved])
+}
+
+// noDirectory is the directory of a configuration without mapping.ldap
+type noDirectory struct{}
+
+func (noDirectory) Lookup(string) (mapping.DirectoryEntry, bool, error) {
+	return mapping.DirectoryEntry{}, false, nil
+}
@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Migrate several CVS modules into separate Git repositories",
+	Long: `Migrate every module listed under "modules" in the configuration file.
+
+Each module is read from source.path/<module> and written to its own
+repository at target.path/<module>. Progress is recorded per module in the
+state database, so an interrupted or failed batch can be continued with
+--resume: completed modules are skipped and the failed module continues
+from its last checkpoint.
+
+A "{module}" placeholder in target.remote is replaced with the module name.
+
+With an "umbrella" section, the migrated modules are wired together in an
+umbrella repository once all of them are migrated, either as submodules or
+as subtree merges. A relative umbrella path is placed below target.path:
+
+  umbrella:
+    path: platform
+    mode: submodule    # or subtree
+
+Example usage:
+  git-migrator batch --config batch.yaml
+  git-migrator batch --config batch.yaml --resume
+  git-migrator batch --config batch.yaml --continue-on-error`,
+	RunE: runBatch,
+}
+
+var (
+	batchConfigFile      string
+	batchResume          bool
+	batchContinueOnError bool
+)
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringVarP(&batchConfigFile, "config", "c", "", "Path to configuration file (required)")
+	batchCmd.Flags().BoolVarP(&batchResume, "resume", "r", false, "Skip modules completed by a previous run")
+	batchCmd.Flags().BoolVar(&batchContinueOnError, "continue-on-error", false, "Continue with the next module when one fails")
+
+	var err = batchCmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigFile(batchConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	modules, err := loadBatchModules(batchConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	umbrella, err := loadBatchUmbrella(batchConfigFile, config.Target.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	batchConfig := buildBatchConfig(config, modules)
+	batchConfig.Umbrella = umbrella
+	batchConfig.Resume = batchResume || config.Options.Resume
+	batchConfig.ContinueOnError = batchContinueOnError
+
+	fmt.Printf("Migrating %d modules from %s\n", len(modules), config.Source.Path)
+	result, err := core.RunBatch(batchConfig)
+	if result != nil {
+		printBatchResult(result)
+	}
+	if err != nil {
+		if !batchConfig.ContinueOnError {
+			fmt.Println("\nRun again with --resume to continue from the failed module")
+		}
+		return fmt.Errorf("batch migration failed: %w", err)
+	}
+
+	fmt.Println("\n✓ Batch migration completed successfully!")
+	return nil
+}
+
+// loadBatchModules reads the module list of a batch configuration file
+func loadBatchModules(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var batch struct {
+		Modules []string `yaml:"modules"`
+	}
+	if err := yaml.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(batch.Modules) == 0 {
+		return nil, fmt.Errorf("modules is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, module := range batch.Modules {
+		if module == "" {
+			return nil, fmt.Errorf("module names must not be empty")
+		}
+		if seen[module] {
+			return nil, fmt.Errorf("duplicate module: %s", module)
+		}
+		seen[module] = true
+	}
+	return batch.Modules, nil
+}
+
+// loadBatchUmbrella reads the umbrella section of a batch configuration
+// file, returning nil if there is none
+func loadBatchUmbrella(path, targetPath string) (*core.UmbrellaConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var batch struct {
+		Umbrella *struct {
+			Path string `yaml:"path"`
+			Mode string `yaml:"mode"`
+		} `yaml:"umbrella"`
+	}
+	if err := yaml.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if batch.Umbrella == nil {
+		return nil, nil
+	}
+	if batch.Umbrella.Path == "" {
+		return nil, fmt.Errorf("umbrella.path is required")
+	}
+	switch batch.Umbrella.Mode {
+	case "", git.UmbrellaSubmodule, git.UmbrellaSubtree:
+	default:
+		return nil, fmt.Errorf("umbrella.mode must be %s or %s", git.UmbrellaSubmodule, git.UmbrellaSubtree)
+	}
+
+	umbrellaPath := batch.Umbrella.Path
+	if !filepath.IsAbs(umbrellaPath) {
+		umbrellaPath = filepath.Join(targetPath, umbrellaPath)
+	}
+	return &core.UmbrellaConfig{Path: umbrellaPath, Mode: batch.Umbrella.Mode}, nil
+}
+
+// buildBatchConfig derives the batch configuration from a configuration file.
+// All modules share the state database next to their repositories.
+func buildBatchConfig(config *ConfigFile, modules []string) *core.BatchConfig {
+	hash := sha256.Sum256([]byte(config.Source.Path + ":" + config.Target.Path))
+	firstTarget := filepath.Join(config.Target.Path, modules[0])
+
+	return &core.BatchConfig{
+		BatchID:   hex.EncodeToString(hash[:8]),
+		Modules:   modules,
+		StateFile: defaultStateFile(firstTarget),
+		ModuleConfig: func(module string) *core.MigrationConfig {
+			moduleConfig := *config
+			moduleConfig.Source.Module = module
+			moduleConfig.Target.Path = filepath.Join(config.Target.Path, module)
+			moduleConfig.Target.Remote = strings.ReplaceAll(config.Target.Remote, "{module}", module)
+			return buildMigrationConfig(&moduleConfig)
+		},
+	}
+}
+
+func printBatchResult(result *core.BatchResult) {
+	fmt.Println("\nBatch Summary")
+	fmt.Println("=============")
+	fmt.Printf("Completed: %d\n", len(result.Completed))
+	fmt.Printf("Skipped:   %d\n", len(result.Skipped))
+	fmt.Printf("Failed:    %d\n", len(result.Failed))
+
+	failed := make([]string, 0, len(result.Failed))
+	for module := range result.Failed {
+		failed = append(failed, module)
+	}
+	sort.Strings(failed)
+	for _, module := range failed {
+		fmt.Printf("  %s: %s\n", module, result.Failed[module])
+	}
+
+	if result.Umbrella != nil {
+		fmt.Printf("\nUmbrella repository: %s (%ss)\n", result.Umbrella.Path, result.Umbrella.Mode)
+		for _, entry := range result.Umbrella.Modules {
+			fmt.Printf("  %s/ -> %s @ %s\n", entry.Path, entry.Repository, shortCommit(entry.Commit))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const batchTestRCS = "head 1.1;\naccess;\nsymbols;\nlocks;\n\n1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\ndesc\n@@\n\n1.1\nlog\n@initial\n@\ntext\n@hello\n@\n"
+
+func writeBatchConfig(t *testing.T, src, tgt string) string {
+	t.Helper()
+	cfgPath := filepath.Join(t.TempDir(), "batch.yaml")
+	cfg := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + tgt + "\nmodules:\n  - alpha\n  - beta\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+	return cfgPath
+}
+
+func TestRunBatch_Resume(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	tgt := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "alpha"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "alpha", "a.txt,v"), []byte(batchTestRCS), 0644))
+
+	oldCfg, oldResume, oldContinue := batchConfigFile, batchResume, batchContinueOnError
+	defer func() { batchConfigFile, batchResume, batchContinueOnError = oldCfg, oldResume, oldContinue }()
+	batchConfigFile = writeBatchConfig(t, src, tgt)
+	batchResume, batchContinueOnError = false, false
+
+	// beta is missing, so the batch stops after alpha
+	require.Error(t, runBatch(nil, nil))
+	require.DirExists(t, filepath.Join(tgt, "alpha", ".git"))
+	require.FileExists(t, filepath.Join(tgt, ".git-migrator-state.db"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "beta"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "beta", "b.txt,v"), []byte(batchTestRCS), 0644))
+
+	batchResume = true
+	require.NoError(t, runBatch(nil, nil))
+	require.DirExists(t, filepath.Join(tgt, "beta", ".git"))
+}
+
+func TestLoadBatchModules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "cfg.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	modules, err := loadBatchModules(write("modules: [a, b]\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, modules)
+
+	_, err = loadBatchModules(write("source:\n  type: cvs\n"))
+	require.Error(t, err)
+
+	_, err = loadBatchModules(write("modules: [a, a]\n"))
+	require.Error(t, err)
+
+	_, err = loadBatchModules(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestBuildBatchConfig(t *testing.T) {
+	config := &ConfigFile{}
+	config.Source.Type = "cvs"
+	config.Source.Path = "/cvs"
+	config.Target.Path = "/git"
+	config.Target.Remote = "git@example.com:org/{module}.git"
+
+	batch := buildBatchConfig(config, []string{"alpha", "beta"})
+	require.NotEmpty(t, batch.BatchID)
+	require.Equal(t, filepath.Join("/git", ".git-migrator-state.db"), batch.StateFile)
+
+	mc := batch.ModuleConfig("beta")
+	require.Equal(t, "beta", mc.SourceModule)
+	require.Equal(t, filepath.Join("/git", "beta"), mc.TargetPath)
+	require.Equal(t, batch.StateFile, mc.StateFile)
+	require.Equal(t, "git@example.com:org/beta.git", mc.Push.URL)
+
+	// The shared configuration is not modified
+	require.Equal(t, "/git", config.Target.Path)
+}
+
+func TestLoadBatchUmbrella(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "cfg.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	umbrella, err := loadBatchUmbrella(write("modules: [a]\n"), "/git")
+	require.NoError(t, err)
+	require.Nil(t, umbrella)
+
+	umbrella, err = loadBatchUmbrella(write("umbrella:\n  path: platform\n  mode: subtree\n"), "/git")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("/git", "platform"), umbrella.Path)
+	require.Equal(t, "subtree", umbrella.Mode)
+
+	_, err = loadBatchUmbrella(write("umbrella:\n  mode: submodule\n"), "/git")
+	require.Error(t, err)
+	_, err = loadBatchUmbrella(write("umbrella:\n  path: p\n  mode: copy\n"), "/git")
+	require.Error(t, err)
+}
@@ -5,8 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/validation"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
 	"github.com/stretchr/testify/require"
 )
 
@@ -50,6 +55,467 @@ func TestLoadConfigFile_MissingFields(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestLoadConfigFile_TagType(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(tagType string) string {
+		cfgPath := filepath.Join(tmp, "cfg.yaml")
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nmapping:\n  tagType: " + tagType + "\n  tagMessage: \"Release {tag}\"\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+		return cfgPath
+	}
+
+	cfg, err := loadConfigFile(write("annotated"))
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.True(t, mc.AnnotatedTags)
+	require.Equal(t, "Release {tag}", mc.TagMessage)
+
+	_, err = loadConfigFile(write("signed"))
+	require.Error(t, err)
+}
+
+func TestLoadConfigFile_Dates(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(policy string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\n" +
+			"mapping:\n  authorTimezones:\n    alice: Europe/Berlin\n" +
+			"options:\n  datePolicy: " + policy + "\n  dateTimezone: \"+01:00\"\n  monotonicDates: true\n  deterministic: true\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("per-author")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, core.DatePerAuthor, mc.DatePolicy)
+	require.Equal(t, "+01:00", mc.DateTimezone)
+	require.Equal(t, map[string]string{"alice": "Europe/Berlin"}, mc.AuthorTimezones)
+	require.True(t, mc.MonotonicDates)
+	require.True(t, mc.Deterministic)
+
+	write("local")
+	_, err = loadConfigFile(cfgPath)
+	require.Error(t, err)
+}
+
+func TestLoadConfigFile_AuthorsFile(t *testing.T) {
+	tmp := t.TempDir()
+	authorsPath := filepath.Join(tmp, "authors.yaml")
+	require.NoError(t, os.WriteFile(authorsPath, []byte("alice: Alice File <alice@file.example>\nbob: Bob <bob@example.com>\n"), 0644))
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nmapping:\n  authorsFile: " + authorsPath + "\n  authors:\n    alice: Alice <alice@example.com>\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"alice": "Alice <alice@example.com>",
+		"bob":   "Bob <bob@example.com>",
+	}, cfg.Mapping.Authors)
+
+	require.NoError(t, os.WriteFile(authorsPath, []byte("alice: alice\n"), 0644))
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "mapping.authorsFile")
+}
+
+func TestLoadConfigFile_LicenseHeaders(t *testing.T) {
+	tmp := t.TempDir()
+	headerPath := filepath.Join(tmp, "LICENSE-HEADER")
+	require.NoError(t, os.WriteFile(headerPath, []byte("Copyright Example\n"), 0644))
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	write := func(rules string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  licenseHeaders:\n" + rules
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("    - pattern: \"*.c\"\n      headerFile: " + headerPath + "\n    - pattern: \"*.sh\"\n      header: MIT\n      comment: \"#\"\n")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, []core.LicenseRule{
+		{Pattern: "*.c", Header: "Copyright Example\n"},
+		{Pattern: "*.sh", Header: "MIT", Comment: "#"},
+	}, buildMigrationConfig(cfg).LicenseHeaders)
+
+	write("    - pattern: \"*.c\"\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "header or headerFile")
+
+	write("    - pattern: \"*.c\"\n      headerFile: " + filepath.Join(tmp, "missing") + "\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "options.licenseHeaders")
+}
+
+func TestLoadConfigFile_RevisionRules(t *testing.T) {
+	tmp := t.TempDir()
+	rulesPath := filepath.Join(tmp, "rules.yaml")
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  revisionRules: " + rulesPath + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	require.NoError(t, os.WriteFile(rulesPath, []byte("rules:\n  - path: a.c\n    revision: \"1.2\"\n    action: skip\n"), 0644))
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, rulesPath, buildMigrationConfig(cfg).RevisionRules)
+
+	require.NoError(t, os.WriteFile(rulesPath, []byte("rules:\n  - path: a.c\n    action: drop\n"), 0644))
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "options.revisionRules")
+}
+
+func TestLoadConfigFile_MessageTemplate(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(template string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  messageTemplate: " + template + "\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write(`"{{.Message}}\n\nMigrated-from: CVS {{.Revision}} by {{.OriginalAuthor}}"`)
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, "{{.Message}}\n\nMigrated-from: CVS {{.Revision}} by {{.OriginalAuthor}}", buildMigrationConfig(cfg).MessageTemplate)
+
+	write(`"{{.Login}}"`)
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "options.messageTemplate")
+}
+
+func TestLoadConfigFile_HistoryLimits(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(options string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n" + options
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("  historySince: 2020-01-01\n  historyDepth: 5\n")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.True(t, mc.HistorySince.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, 5, mc.HistoryDepth)
+
+	write("  historySince: \"2020-06-01T12:00:00+02:00\"\n")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.True(t, cfg.Options.HistorySince.Equal(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)))
+
+	write("  historyDepth: -1\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "historyDepth")
+
+	write("  maxCommitFiles: 1000\n  maxCommitMB: 64\n")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc = buildMigrationConfig(cfg)
+	require.Equal(t, 1000, mc.MaxCommitFiles)
+	require.Equal(t, int64(64)<<20, mc.MaxCommitBytes)
+
+	write("  maxCommitFiles: -1\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "maxCommitFiles")
+
+	write("  ioLimitMBps: 2.5\n  ioOpsPerSecond: 300\n")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc = buildMigrationConfig(cfg)
+	require.Equal(t, int64(5)<<19, mc.ReadLimit)
+	require.Equal(t, int64(300), mc.OpsLimit)
+
+	write("  verifyManifest: true\n")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.True(t, buildMigrationConfig(cfg).VerifyManifest)
+
+	write("  ioOpsPerSecond: -1\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "ioOpsPerSecond")
+
+	write("  renameSimilarity: 60\n")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, 60, buildMigrationConfig(cfg).RenameSimilarity)
+
+	write("  renameSimilarity: 101\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "renameSimilarity")
+
+	write("  renameSimilarity: 60\n  renameLimit: 200\n")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, 200, buildMigrationConfig(cfg).RenameLimit)
+
+	write("  renameLimit: -1\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "renameLimit")
+}
+
+func TestLoadConfigFile_Join(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	for _, module := range []string{"foo", "bar"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(src, module), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, module, "a.txt,v"), []byte(batchTestRCS), 0644))
+	}
+	target := filepath.Join(t.TempDir(), "mono")
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: " + src + "\n  join:\n    - module: foo\n      path: libs/foo\n    - module: bar\ntarget:\n  path: " + target + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, []core.JoinModule{{Module: "foo", Path: "libs/foo"}, {Module: "bar"}}, mc.JoinModules)
+	mc.Logger = logging.Discard()
+	require.NoError(t, core.NewMigrator(mc).Run())
+	require.FileExists(t, filepath.Join(target, "libs", "foo", "a.txt"))
+	require.FileExists(t, filepath.Join(target, "bar", "a.txt"))
+
+	content = "source:\n  type: cvs\n  path: /tmp/src\n  module: foo\n  join:\n    - module: bar\ntarget:\n  path: /tmp/target\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestLoadConfigFile_Notifications(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(email string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nnotifications:\n  email:\n" + email
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("    host: smtp.example.com\n    from: migrator@example.com\n    to: [team@example.com]\n    on: [failed]\n")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.True(t, cfg.Notifications.Email.Enabled())
+	require.Equal(t, []string{"team@example.com"}, cfg.Notifications.Email.To)
+
+	write("    host: smtp.example.com\n    from: migrator@example.com\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "notifications.email")
+}
+
+func TestLoadConfigFile_EOL(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(eol string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  eol: " + eol + "\n  crlfExtensions: [\".bat\"]\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("crlf-by-extension")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, core.EOLCRLFByExtension, mc.EOL)
+	require.Equal(t, []string{".bat"}, mc.CRLFExtensions)
+
+	write("native")
+	_, err = loadConfigFile(cfgPath)
+	require.Error(t, err)
+}
+
+func TestLoadConfigFile_CaseConflicts(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(policy string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  caseConflicts: " + policy + "\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("unify")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, core.CaseConflictUnify, buildMigrationConfig(cfg).CaseConflicts)
+
+	write("suffix\n  sanitizeWindowsPaths: true")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.True(t, buildMigrationConfig(cfg).SanitizeWindows)
+
+	write("report\n  pathNormalization: nfc")
+	cfg, err = loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, core.NormalizeNFC, buildMigrationConfig(cfg).UnicodeForm)
+
+	write("report\n  pathNormalization: nfkc")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "options.pathNormalization")
+
+	write("lowercase")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "options.caseConflicts")
+}
+
+func TestLoadConfigFile_Keywords(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(mode string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  keywords:\n" +
+			"    - pattern: \"*.c\"\n      mode: " + mode + "\n    - pattern: \"*\"\n      mode: strip\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("expand")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, []core.KeywordRule{{Pattern: "*.c", Mode: core.KeywordsExpand}, {Pattern: "*", Mode: core.KeywordsStrip}}, mc.Keywords)
+
+	write("kv")
+	_, err = loadConfigFile(cfgPath)
+	require.Error(t, err)
+}
+
+func TestLoadConfigFile_Graft(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(mode string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\n  graft:\n" +
+			"    commit: v0\n    mode: " + mode + "\n    date: 2020-01-01\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("replace")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, "v0", mc.Graft.Commit)
+	require.Equal(t, core.GraftReplace, mc.Graft.Mode)
+	require.True(t, mc.Graft.Date.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	write("merge")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "target.graft.mode")
+}
+
+func TestLoadConfigFile_ErrorPolicy(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	write := func(policy string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  errorPolicy: " + policy + "\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("continue-on-error")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, core.ErrorPolicyContinue, buildMigrationConfig(cfg).ErrorPolicy)
+
+	write("ignore")
+	_, err = loadConfigFile(cfgPath)
+	require.Error(t, err)
+}
+
+func TestLoadConfigFile_Retries(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  retries: 5\n  retryDelay: 500ms\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, 5, mc.Retries)
+	require.Equal(t, 500*time.Millisecond, mc.RetryDelay)
+}
+
+func TestLoadConfigFile_Timeouts(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\noptions:\n  timeouts:\n    sourceScan: 30m\n    commit: 2m\n    refs: 10m\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, core.Timeouts{SourceScan: 30 * time.Minute, Commit: 2 * time.Minute, Refs: 10 * time.Minute}, buildMigrationConfig(cfg).Timeouts)
+
+	content += "    cvsCommand: -1s\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "options.timeouts must not be negative")
+}
+
+func TestLoadConfigFile_AllViolations(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\ntarget:\n  path: /tmp/target\nmapping:\n  requireAuthors: true\noptions:\n  eol: crlf\n  historyDepth: -1\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	_, err := loadConfigFile(cfgPath)
+	var violations validation.Errors
+	require.ErrorAs(t, err, &violations)
+	fields := make([]string, len(violations))
+	for i, v := range violations {
+		fields[i] = v.Field
+	}
+	require.Equal(t, []string{"source.path", "options.eol", "options.historyDepth", "mapping.requireAuthors"}, fields)
+}
+
+func TestValidateMigration(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(src, "CVSROOT"), 0755))
+	target := filepath.Join(t.TempDir(), "git")
+	config := &ConfigFile{}
+	config.Source.Type, config.Source.Path, config.Target.Path = "cvs", src, target
+	require.NoError(t, validateMigration(config))
+
+	// Resuming needs the state of an earlier run
+	config.Options.Resume = true
+	require.ErrorContains(t, validateMigration(config), "options.resume needs the state of an earlier run")
+
+	// The state file of a sibling repository does not count
+	db, err := storage.NewStateDB(defaultStateFile(target))
+	require.NoError(t, err)
+	require.NoError(t, db.Save(&storage.MigrationState{MigrationID: "sibling", TargetPath: target + "-sibling", Status: "completed"}))
+	require.ErrorContains(t, validateMigration(config), "options.resume needs the state of an earlier run")
+	id := core.NewMigrator(buildMigrationConfig(config)).MigrationID()
+	require.NoError(t, db.Save(&storage.MigrationState{MigrationID: id, TargetPath: target, Status: "running"}))
+	require.NoError(t, db.Close())
+	require.NoError(t, validateMigration(config))
+	config.Options.Simulate = true
+	require.ErrorContains(t, validateMigration(config), "cannot be combined")
+
+	config.Options.Resume = false
+	config.Source.Path = filepath.Join(src, "missing")
+	require.NoError(t, os.WriteFile(target, nil, 0644))
+	err = validateMigration(config)
+	require.ErrorContains(t, err, "source.path does not exist")
+	require.ErrorContains(t, err, "target.path is a file")
+}
+
+func TestLoadConfigFile_CVSClient(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: :ext:alice@cvs.example.com:/cvsroot\n  module: mod\n  client:\n    binary: cvs-1.12\n    path: /opt/cvs/bin\n    rsh: /usr/bin/ssh\n    env:\n      CVS_SERVER: /usr/local/bin/cvs\ntarget:\n  path: /tmp/target\noptions:\n  timeouts:\n    cvsCommand: 5m\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, cvs.Client{Binary: "cvs-1.12", SearchPath: "/opt/cvs/bin", RSH: "/usr/bin/ssh", Env: map[string]string{"CVS_SERVER": "/usr/local/bin/cvs"}}, mc.CVSClient)
+	require.Equal(t, 5*time.Minute, mc.Timeouts.CVSCommand)
+
+	// The binary must exist in the configured path
+	cfg.Target.Path = filepath.Join(t.TempDir(), "git")
+	require.ErrorContains(t, validateMigration(cfg), `source.client.binary "cvs-1.12" not found`)
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "cvs-1.12"), []byte("#!/bin/sh\n"), 0755))
+	cfg.Source.Client.Path = bin
+	require.NoError(t, validateMigration(cfg))
+}
+
+func TestBuildMigrationConfig_Hooks(t *testing.T) {
+	cfg := &ConfigFile{}
+	require.Empty(t, buildMigrationConfig(cfg).Hooks)
+
+	cfg.Hooks.PreCommit = "scrub --strict"
+	mc := buildMigrationConfig(cfg)
+	require.Len(t, mc.Hooks, 1)
+	require.Equal(t, &core.CommandHook{Before: "scrub --strict"}, mc.Hooks[0])
+}
+
+func TestBuildMigrationConfig_ReleaseTags(t *testing.T) {
+	t.Setenv("TAG_KEY_PASSPHRASE", "secret")
+	cfg := &ConfigFile{}
+	cfg.Mapping.ReleaseTags = "v*"
+	cfg.Mapping.SigningKey = "/keys/release.asc"
+	cfg.Mapping.SigningKeyPassphraseEnv = "TAG_KEY_PASSPHRASE"
+
+	mc := buildMigrationConfig(cfg)
+	require.Equal(t, "v*", mc.ReleaseTags)
+	require.Equal(t, "/keys/release.asc", mc.SigningKey)
+	require.Equal(t, "secret", mc.KeyPassphrase)
+}
+
 func TestPrintMigrationInfo_DoesNotPanic(t *testing.T) {
 	buf := &bytes.Buffer{}
 	// Temporarily redirect stdout
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/test/gen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunGenFixture(t *testing.T) {
+	old := genOptions
+	defer func() { genOptions = old }()
+	genOptions = gen.Options{Files: 4, Revisions: 3, FilesPerCommit: 2}
+
+	dir := filepath.Join(t.TempDir(), "cvs")
+	require.NoError(t, runGenFixture(genFixtureCmd, []string{dir}))
+	require.NoError(t, cvs.NewReader(dir).Validate())
+	require.FileExists(t, filepath.Join(dir, "dir00", "file00000.txt,v"))
+
+	require.Error(t, runGenFixture(genFixtureCmd, []string{dir}), "the directory is not empty")
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/importer"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Convert configuration of other migration tools",
+	Long: `Commands for translating the configuration of other CVS migration tools
+into a git-migrator configuration file.`,
+}
+
+var importCVS2GitCmd = &cobra.Command{
+	Use:   "cvs2git <options-file>",
+	Short: "Convert a cvs2git or cvs2svn options file",
+	Long: `Read a cvs2git or cvs2svn options file and write an equivalent
+git-migrator configuration file.
+
+The project path, author transforms, trunk_only, exclusion rules that can be
+expressed as globs and exact symbol renames are translated. Settings that
+cannot be translated are listed on stderr.
+
+Example usage:
+  git-migrator import cvs2git cvs2git.options --target ./repo -o config.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportCVS2Git,
+}
+
+var (
+	importTarget string
+	importOutput string
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importCVS2GitCmd)
+
+	importCVS2GitCmd.Flags().StringVarP(&importTarget, "target", "t", "", "Target Git repository path (default: project name)")
+	importCVS2GitCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Write the configuration to a file instead of stdout")
+}
+
+func runImportCVS2Git(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open options file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close options file: %v\n", err)
+		}
+	}()
+
+	result, err := importer.ImportCVS2Git(f)
+	if err != nil {
+		return err
+	}
+
+	target := importTarget
+	if target == "" {
+		target = filepath.Base(result.Config.SourcePath)
+	}
+	result.Config.TargetPath = target
+
+	data, err := yaml.Marshal(configFileFromMigration(result.Config))
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	if importOutput == "" {
+		fmt.Print(string(data))
+	} else if err := os.WriteFile(importOutput, data, 0644); err != nil {
+		return fmt.Errorf("failed to write configuration: %w", err)
+	}
+
+	if len(result.Unmapped) > 0 {
+		fmt.Fprintf(os.Stderr, "%d settings could not be translated:\n", len(result.Unmapped))
+		for _, msg := range result.Unmapped {
+			fmt.Fprintf(os.Stderr, "  - %s\n", msg)
+		}
+	}
+	return nil
+}
+
+// configFileFromMigration is the inverse of buildMigrationConfig for the
+// settings an importer can produce
+func configFileFromMigration(mc *core.MigrationConfig) *ConfigFile {
+	config := &ConfigFile{}
+	config.Source.Type = mc.SourceType
+	config.Source.Path = mc.SourcePath
+	config.Source.Module = mc.SourceModule
+	config.Target.Path = mc.TargetPath
+	config.Target.DefaultBranch = mc.DefaultBranch
+	config.Mapping.Authors = mc.AuthorMap
+	config.Mapping.Committer = mc.Committer
+	config.Mapping.Branches = mc.BranchMap
+	config.Mapping.Tags = mc.TagMap
+	config.Mapping.IncludeBranches = mc.IncludeBranches
+	config.Mapping.ExcludeBranches = mc.ExcludeBranches
+	config.Mapping.IncludeTags = mc.IncludeTags
+	config.Mapping.ExcludeTags = mc.ExcludeTags
+	return config
+}
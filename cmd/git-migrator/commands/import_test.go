@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunImportCVS2Git(t *testing.T) {
+	dir := t.TempDir()
+	options := filepath.Join(dir, "cvs2git.options")
+	src := `author_transforms = {'jrandom': ('J. Random', 'jrandom@example.com')}
+ctx.output_option = GitOutputOption(GitRevisionMarkWriter(), author_transforms=author_transforms)
+ctx.trunk_only = True
+run_options.add_project(r'/srv/cvs/product')
+`
+	require.NoError(t, os.WriteFile(options, []byte(src), 0644))
+
+	oldTarget, oldOutput := importTarget, importOutput
+	defer func() { importTarget, importOutput = oldTarget, oldOutput }()
+	importTarget = "/srv/git/product"
+	importOutput = filepath.Join(dir, "config.yaml")
+
+	require.NoError(t, runImportCVS2Git(nil, []string{options}))
+
+	// The generated file is a valid migration configuration
+	config, err := loadConfigFile(importOutput)
+	require.NoError(t, err)
+	require.Equal(t, "cvs", config.Source.Type)
+	require.Equal(t, "/srv/cvs/product", config.Source.Path)
+	require.Equal(t, "/srv/git/product", config.Target.Path)
+	require.Equal(t, "J. Random <jrandom@example.com>", config.Mapping.Authors["jrandom"])
+	require.Equal(t, []string{"*"}, config.Mapping.ExcludeTags)
+}
+
+func TestRunImportCVS2Git_Errors(t *testing.T) {
+	require.Error(t, runImportCVS2Git(nil, []string{filepath.Join(t.TempDir(), "missing")}))
+
+	options := filepath.Join(t.TempDir(), "empty.options")
+	require.NoError(t, os.WriteFile(options, []byte("ctx.trunk_only = True\n"), 0644))
+	require.Error(t, runImportCVS2Git(nil, []string{options}))
+}
@@ -0,0 +1,282 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a migration configuration interactively",
+	Long: `Ask for the source repository and target path, scan the source for
+authors, branches and tags, and write a ready-to-run migration configuration.
+
+The authors found are written as an author map skeleton ("user <user@domain>")
+to be completed before migrating. Press Enter to accept the default shown in
+brackets.
+
+Example usage:
+  git-migrator init
+  git-migrator init -o config.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
+}
+
+var (
+	initOutput string
+	initForce  bool
+)
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVarP(&initOutput, "output", "o", "migration.yaml", "Path of the configuration file to write")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing configuration file")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	if !initForce {
+		if _, err := os.Stat(initOutput); err == nil {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", initOutput)
+		}
+	}
+
+	p := &prompter{in: bufio.NewReader(cmd.InOrStdin()), out: cmd.OutOrStdout()}
+	config, err := runWizard(p)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	header := "# Generated by git-migrator init. Complete mapping.authors before migrating.\n"
+	if err := os.WriteFile(initOutput, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write configuration: %w", err)
+	}
+
+	fmt.Fprintf(p.out, "\n✓ Wrote %s\n", initOutput)
+	fmt.Fprintln(p.out, "Review the author map, then preview the migration with:")
+	fmt.Fprintf(p.out, "  git-migrator migrate --config %s --dry-run\n", initOutput)
+	return nil
+}
+
+// runWizard asks the questions and scans the source repository
+func runWizard(p *prompter) (*ConfigFile, error) {
+	config := &ConfigFile{}
+
+	sourceType, err := p.ask("Source type (cvs)", "cvs")
+	if err != nil {
+		return nil, err
+	}
+	if sourceType != "cvs" {
+		return nil, fmt.Errorf("unsupported source type: %s (supported: cvs)", sourceType)
+	}
+	config.Source.Type = sourceType
+
+	var reader *cvs.Reader
+	for {
+		path, err := p.ask("CVS repository path (CVSROOT)", "")
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			continue
+		}
+
+		def := ""
+		if modules := listCVSModules(path); len(modules) > 0 {
+			fmt.Fprintf(p.out, "Modules: %s\n", strings.Join(modules, ", "))
+			if len(modules) == 1 {
+				def = modules[0]
+			}
+		}
+		module, err := p.ask("Module (empty for the whole repository)", def)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = cvs.NewModuleReader(path, module)
+		if err := reader.Validate(); err != nil {
+			fmt.Fprintf(p.out, "✗ %v\n", err)
+			continue
+		}
+		config.Source.Path = path
+		config.Source.Module = module
+		break
+	}
+
+	fmt.Fprintln(p.out, "\nScanning the repository...")
+	scan, err := scanSource(reader)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(p.out, "Found %d commits, %d authors, %d branches and %d tags\n\n",
+		scan.commits, len(scan.authors), len(scan.branches), len(scan.tags))
+
+	name := config.Source.Module
+	if name == "" {
+		name = filepath.Base(config.Source.Path)
+	}
+	if config.Target.Path, err = p.ask("Target Git repository path", "./"+name); err != nil {
+		return nil, err
+	}
+	if config.Target.DefaultBranch, err = p.ask("Default branch", "main"); err != nil {
+		return nil, err
+	}
+
+	if len(scan.authors) > 0 {
+		domain, err := p.ask("Email domain for the author map", "example.com")
+		if err != nil {
+			return nil, err
+		}
+		config.Mapping.Authors = make(map[string]string, len(scan.authors))
+		for _, author := range scan.authors {
+			config.Mapping.Authors[author] = fmt.Sprintf("%s <%s@%s>", author, author, domain)
+		}
+	}
+
+	if len(scan.branches) > 0 {
+		fmt.Fprintf(p.out, "Branches: %s\n", strings.Join(scan.branches, ", "))
+		if config.Mapping.IncludeBranches, err = p.askList("Branches to migrate (comma-separated globs, empty for all)"); err != nil {
+			return nil, err
+		}
+	}
+	if len(scan.tags) > 0 {
+		fmt.Fprintf(p.out, "Tags: %s\n", strings.Join(scan.tags, ", "))
+		if config.Mapping.IncludeTags, err = p.askList("Tags to migrate (comma-separated globs, empty for all)"); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// sourceScan is what the wizard learns from the source repository
+type sourceScan struct {
+	commits  int
+	authors  []string
+	branches []string
+	tags     []string
+}
+
+// scanSource reads the authors, branches and tags of the source
+func scanSource(reader *cvs.Reader) (*sourceScan, error) {
+	defer func() {
+		if err := reader.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close reader: %v\n", err)
+		}
+	}()
+
+	scan := &sourceScan{}
+	branches, err := reader.GetBranches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branches: %w", err)
+	}
+	sort.Strings(branches)
+	scan.branches = branches
+
+	tags, err := reader.GetTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	for name := range tags {
+		scan.tags = append(scan.tags, name)
+	}
+	sort.Strings(scan.tags)
+
+	commitIter, err := reader.GetCommits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	authors := mapping.NewAuthorExtractor()
+	for commitIter.Next() {
+		scan.commits++
+		authors.Add(commitIter.Commit().Author)
+	}
+	if err := commitIter.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating commits: %w", err)
+	}
+	scan.authors = authors.List()
+	sort.Strings(scan.authors)
+	return scan, nil
+}
+
+// listCVSModules returns the top-level directories of a local CVSROOT and
+// the modules defined in its CVSROOT/modules
+func listCVSModules(root string) []string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var modules []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "CVSROOT" && !strings.HasPrefix(e.Name(), ".") {
+			modules = append(modules, e.Name())
+		}
+	}
+	if defined, err := cvs.LoadModules(root); err == nil {
+		for name := range defined {
+			if !slices.Contains(modules, name) {
+				modules = append(modules, name)
+			}
+		}
+		sort.Strings(modules)
+	}
+	return modules
+}
+
+// prompter asks questions on a terminal, or any reader and writer
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints question and returns the trimmed answer, or def if it is empty
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("input ended before the configuration was complete")
+		}
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askList asks for a comma-separated list
+func (p *prompter) askList(question string) ([]string, error) {
+	answer, err := p.ask(question, "")
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setInitFlags(t *testing.T, output string, force bool) {
+	t.Helper()
+	oldOutput, oldForce := initOutput, initForce
+	t.Cleanup(func() { initOutput, initForce = oldOutput, oldForce })
+	initOutput, initForce = output, force
+}
+
+func TestRunInit(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "config.yaml")
+	setInitFlags(t, output, false)
+
+	answers := strings.Join([]string{
+		"",                                    // source type: cvs
+		"/nonexistent/cvsroot",                // invalid, asked again
+		"",                                    // module
+		"../../../test/fixtures/cvs/branches", // valid repository
+		"",                                    // module: whole repository
+		"./out",                               // target
+		"",                                    // default branch: main
+		"corp.example",                        // author email domain
+		"",                                    // all branches
+		"",                                    // all tags
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	initCmd.SetIn(strings.NewReader(answers))
+	initCmd.SetOut(&out)
+	defer func() {
+		initCmd.SetIn(nil)
+		initCmd.SetOut(nil)
+	}()
+
+	require.NoError(t, runInit(initCmd, nil))
+	require.Contains(t, out.String(), "✗ ")
+	require.Contains(t, out.String(), "Found ")
+
+	config, err := loadConfigFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "cvs", config.Source.Type)
+	require.Equal(t, "../../../test/fixtures/cvs/branches", config.Source.Path)
+	require.Equal(t, "./out", config.Target.Path)
+	require.Equal(t, "main", config.Target.DefaultBranch)
+	require.NotEmpty(t, config.Mapping.Authors)
+	for user, author := range config.Mapping.Authors {
+		require.Equal(t, user+" <"+user+"@corp.example>", author)
+	}
+
+	// An existing file is kept unless --force is given
+	err = runInit(initCmd, nil)
+	require.ErrorContains(t, err, "already exists")
+}
+
+func TestRunInit_InputEnds(t *testing.T) {
+	setInitFlags(t, filepath.Join(t.TempDir(), "config.yaml"), false)
+
+	initCmd.SetIn(strings.NewReader("cvs\n"))
+	initCmd.SetOut(&bytes.Buffer{})
+	defer func() {
+		initCmd.SetIn(nil)
+		initCmd.SetOut(nil)
+	}()
+
+	err := runInit(initCmd, nil)
+	require.ErrorContains(t, err, "input ended")
+	_, statErr := os.Stat(initOutput)
+	require.True(t, os.IsNotExist(statErr))
+}
+
+func TestRunInit_UnsupportedSource(t *testing.T) {
+	setInitFlags(t, filepath.Join(t.TempDir(), "config.yaml"), false)
+
+	initCmd.SetIn(strings.NewReader("svn\n"))
+	initCmd.SetOut(&bytes.Buffer{})
+	defer func() {
+		initCmd.SetIn(nil)
+		initCmd.SetOut(nil)
+	}()
+
+	require.ErrorContains(t, runInit(initCmd, nil), "unsupported source type")
+}
+
+func TestPrompterAskList(t *testing.T) {
+	p := &prompter{in: bufio.NewReader(strings.NewReader("main, REL_* ,,\n")), out: &bytes.Buffer{}}
+	items, err := p.askList("Branches")
+	require.NoError(t, err)
+	require.Equal(t, []string{"main", "REL_*"}, items)
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var mapCmd = &cobra.Command{
+	Use:   "map <revision|hash>...",
+	Short: "Translate between source revisions and Git commit hashes",
+	Long: `Look up the revision mapping recorded during migration.
+
+Arguments that look like Git hashes (7 to 40 hex digits, abbreviations
+allowed) are translated to the source revisions they were created from.
+Anything else is treated as a source revision and translated to the Git
+hash. CVS file revisions are written as path:revision, e.g.
+
+  git-migrator map --target ./repo src/main.c:1.4
+  git-migrator map --target ./repo 3f2a9c1`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMap,
+}
+
+var (
+	mapStateFile string
+	mapTarget    string
+)
+
+var gitHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+func init() {
+	rootCmd.AddCommand(mapCmd)
+
+	mapCmd.Flags().StringVar(&mapStateFile, "state", "", "Path to the migration state database")
+	mapCmd.Flags().StringVarP(&mapTarget, "target", "t", "", "Path to the migrated Git repository (locates the state database)")
+}
+
+func runMap(cmd *cobra.Command, args []string) error {
+	db, err := openStateDB(mapStateFile, mapTarget)
+	if err != nil {
+		return err
+	}
+	defer closeStateDB(db)
+
+	notFound := 0
+	for _, ref := range args {
+		if gitHashPattern.MatchString(ref) {
+			revisions, err := db.LookupSourceRev(ref)
+			if errors.Is(err, storage.ErrMappingNotFound) {
+				fmt.Fprintf(os.Stderr, "%s: no mapping found\n", ref)
+				notFound++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to look up %s: %w", ref, err)
+			}
+			for _, rev := range revisions {
+				fmt.Printf("%s\t%s\n", ref, rev)
+			}
+			continue
+		}
+
+		hash, err := db.LookupGitHash(ref)
+		if errors.Is(err, storage.ErrMappingNotFound) {
+			fmt.Fprintf(os.Stderr, "%s: no mapping found\n", ref)
+			notFound++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up %s: %w", ref, err)
+		}
+		fmt.Printf("%s\t%s\n", ref, hash)
+	}
+
+	if notFound > 0 {
+		return fmt.Errorf("%d of %d references could not be mapped", notFound, len(args))
+	}
+	return nil
+}
+
+// defaultStateFile returns the state database location used by migrate for
+// the given target repository
+func defaultStateFile(target string) string {
+	return filepath.Join(filepath.Dir(target), ".git-migrator-state.db")
+}
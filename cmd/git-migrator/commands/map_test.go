@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMap(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	db, err := storage.NewStateDB(defaultStateFile(target))
+	require.NoError(t, err)
+	require.NoError(t, db.SaveMapping("m1", "src/main.c:1.2", "3f2a9c1e0000000000000000000000000000abcd"))
+	require.NoError(t, db.Close())
+
+	oldState, oldTarget := mapStateFile, mapTarget
+	defer func() { mapStateFile, mapTarget = oldState, oldTarget }()
+	mapStateFile, mapTarget = "", target
+
+	require.NoError(t, runMap(nil, []string{"src/main.c:1.2"}))
+	require.NoError(t, runMap(nil, []string{"3f2a9c1"}))
+
+	err = runMap(nil, []string{"src/main.c:1.2", "src/other.c:1.1"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 of 2")
+}
+
+func TestRunMap_RequiresStateOrTarget(t *testing.T) {
+	oldState, oldTarget := mapStateFile, mapTarget
+	defer func() { mapStateFile, mapTarget = oldState, oldTarget }()
+	mapStateFile, mapTarget = "", ""
+
+	require.Error(t, runMap(nil, []string{"1.1"}))
+
+	mapStateFile = filepath.Join(t.TempDir(), "missing.db")
+	err := runMap(nil, []string{"1.1"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+}
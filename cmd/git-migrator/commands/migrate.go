@@ -3,9 +3,17 @@ package commands
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/notify"
+	"github.com/adamf123git/git-migrator/internal/profile"
+	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/validation"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -22,11 +30,24 @@ The migration preserves complete history including:
 - File changes and content
 
 Use --dry-run to preview the migration without making changes.
+Use --simulate to run the whole migration into an in-memory repository and
+report its object counts, estimated pack size, refs and HEAD tree without
+writing the target.
+Progress is shown as a bar on the terminal. Use --verbose for the
+configuration and a line per applied commit, or --quiet for CI to print only
+the summary.
 Use --resume to continue an interrupted migration.
+Use --continue-on-error to record failing commits, branches and tags in the
+report instead of aborting, or --fail-fast to abort on any failure.
+
+A lock file next to the target keeps two runs from writing to the same
+repository. A lock left by a crashed run is taken over once its process is
+gone; use --force-unlock to take over a lock that is still held.
 
 Example usage:
   git-migrator migrate --config migration-config.yaml
   git-migrator migrate --config config.yaml --dry-run --verbose
+  git-migrator migrate --config config.yaml --simulate
   git-migrator migrate --config config.yaml --resume`,
 	RunE: runMigrate,
 }
@@ -34,36 +55,239 @@ Example usage:
 var (
 	migrateConfigFile string
 	migrateDryRun     bool
+	migrateSimulate   bool
 	migrateVerbose    bool
+	migrateQuiet      bool
 	migrateResume     bool
+
+	migrateFailFast        bool
+	migrateContinueOnError bool
+	migrateForceUnlock     bool
+	migrateAllowExisting   bool
+	migrateProfile         string
 )
 
 // ConfigFile represents the YAML configuration file structure
 type ConfigFile struct {
 	Source struct {
-		Type   string `yaml:"type"`
-		Path   string `yaml:"path"`
-		Module string `yaml:"module"`
-	} `yaml:"source"`
+		Type   string `yaml:"type,omitempty"`
+		Path   string `yaml:"path,omitempty"`
+		Module string `yaml:"module,omitempty"`
+		Join   []struct {
+			Module string `yaml:"module"`
+			Path   string `yaml:"path,omitempty"`
+		} `yaml:"join,omitempty"`
+
+		Client CVSClientConfig `yaml:"client,omitempty"` // How the cvs client reading remote sources is run
+	} `yaml:"source,omitempty"`
 
 	Target struct {
-		Type   string `yaml:"type"`
-		Path   string `yaml:"path"`
-		Remote string `yaml:"remote"`
-	} `yaml:"target"`
+		Type          string            `yaml:"type,omitempty"`
+		Path          string            `yaml:"path,omitempty"`
+		DefaultBranch string            `yaml:"defaultBranch,omitempty"`
+		Remote        string            `yaml:"remote,omitempty"`
+		RemoteName    string            `yaml:"remoteName,omitempty"`
+		Options       map[string]string `yaml:"options,omitempty"`
+		Push          PushConfig        `yaml:"push,omitempty"`
+		Graft         *GraftConfig      `yaml:"graft,omitempty"` // Graft the history onto an existing import commit
+	} `yaml:"target,omitempty"`
 
 	Mapping struct {
-		Authors  map[string]string `yaml:"authors"`
-		Branches map[string]string `yaml:"branches"`
-		Tags     map[string]string `yaml:"tags"`
-	} `yaml:"mapping"`
+		Authors     map[string]string `yaml:"authors,omitempty"`
+		AuthorsFile string            `yaml:"authorsFile,omitempty"` // YAML author map; inline authors take priority
+		Committer   string            `yaml:"committer,omitempty"`
+		Branches    map[string]string `yaml:"branches,omitempty"`
+		Tags        map[string]string `yaml:"tags,omitempty"`
+
+		AuthorTimezones map[string]string `yaml:"authorTimezones,omitempty"` // Login -> UTC offset or timezone name
+
+		RequireAuthors bool `yaml:"requireAuthors,omitempty"` // Fail on the first author the author map does not cover
+
+		TagType    string `yaml:"tagType,omitempty"`
+		TagMessage string `yaml:"tagMessage,omitempty"`
+
+		ReleaseTags             string `yaml:"releaseTags,omitempty"`             // Glob of Git tags annotated with release notes, e.g. "v*"
+		SigningKey              string `yaml:"signingKey,omitempty"`              // Armored OpenPGP private key signing release tags
+		SigningKeyPassphraseEnv string `yaml:"signingKeyPassphraseEnv,omitempty"` // Environment variable holding the key passphrase
+
+		IncludeBranches []string `yaml:"includeBranches,omitempty"`
+		ExcludeBranches []string `yaml:"excludeBranches,omitempty"`
+		IncludeTags     []string `yaml:"includeTags,omitempty"`
+		ExcludeTags     []string `yaml:"excludeTags,omitempty"`
+
+		LDAP *LDAPConfig `yaml:"ldap,omitempty"` // Directory prefilling the author map in authors extract
+	} `yaml:"mapping,omitempty"`
+
+	Hooks struct {
+		PreCommit  string `yaml:"preCommit,omitempty"`
+		PostCommit string `yaml:"postCommit,omitempty"`
+	} `yaml:"hooks,omitempty"`
 
 	Options struct {
-		DryRun    bool `yaml:"dryRun"`
-		Verbose   bool `yaml:"verbose"`
-		ChunkSize int  `yaml:"chunkSize"`
-		Resume    bool `yaml:"resume"`
-	} `yaml:"options"`
+		DryRun    bool   `yaml:"dryRun,omitempty"`
+		Simulate  bool   `yaml:"simulate,omitempty"` // Migrate into memory and report the resulting repository
+		Verbose   bool   `yaml:"verbose,omitempty"`
+		ChunkSize int    `yaml:"chunkSize,omitempty"`
+		Resume    bool   `yaml:"resume,omitempty"`
+		LogDir    string `yaml:"logDir,omitempty"`
+		Compat    string `yaml:"compat,omitempty"`
+
+		EOL            string   `yaml:"eol,omitempty"`
+		CRLFExtensions []string `yaml:"crlfExtensions,omitempty"`
+
+		CaseConflicts        string `yaml:"caseConflicts,omitempty"`        // Paths differing only by case: report, suffix, unify or fail
+		SanitizeWindowsPaths bool   `yaml:"sanitizeWindowsPaths,omitempty"` // Rewrite paths Windows cannot check out
+		PathNormalization    string `yaml:"pathNormalization,omitempty"`    // Unicode normalization form of all paths: nfc or nfd
+
+		Keywords []struct {
+			Pattern string `yaml:"pattern"`
+			Mode    string `yaml:"mode"` // keep, strip or expand
+		} `yaml:"keywords,omitempty"` // RCS keyword handling per path; the first matching rule applies
+
+		LicenseHeaders []struct {
+			Pattern    string `yaml:"pattern"`
+			Header     string `yaml:"header,omitempty"`
+			HeaderFile string `yaml:"headerFile,omitempty"` // Read into Header when the config is loaded
+			Comment    string `yaml:"comment,omitempty"`    // Comment style; by file extension if empty
+		} `yaml:"licenseHeaders,omitempty"` // License header written per path; the first matching rule applies
+
+		RevisionRules string `yaml:"revisionRules,omitempty"` // Rules file skipping or replacing source file revisions
+
+		MessageTemplate string `yaml:"messageTemplate,omitempty"` // Go text/template of every commit message
+
+		DatePolicy     string `yaml:"datePolicy,omitempty"`
+		DateTimezone   string `yaml:"dateTimezone,omitempty"`
+		MonotonicDates bool   `yaml:"monotonicDates,omitempty"`
+		Deterministic  bool   `yaml:"deterministic,omitempty"` // Byte-identical history on every run of the same snapshot
+
+		HistorySince time.Time `yaml:"historySince,omitempty"` // Migrate only changes from this date on
+		HistoryDepth int       `yaml:"historyDepth,omitempty"` // Migrate only the last N changes of every file
+
+		MaxCommitFiles int `yaml:"maxCommitFiles,omitempty"` // Split source commits with more file changes
+		MaxCommitMB    int `yaml:"maxCommitMB,omitempty"`    // Split source commits with more content
+
+		RenameSimilarity int `yaml:"renameSimilarity,omitempty"` // Percentage of equal lines pairing a deleted and an added file as a rename (0 = off)
+		RenameLimit      int `yaml:"renameLimit,omitempty"`      // Added and deleted files of a commit compared line by line (0 = core.DefaultRenameLimit)
+
+		ErrorPolicy string        `yaml:"errorPolicy,omitempty"`
+		Retries     int           `yaml:"retries,omitempty"`
+		RetryDelay  time.Duration `yaml:"retryDelay,omitempty"`
+
+		RepackEvery int `yaml:"repackEvery,omitempty"` // Pack the target's objects every N commits and at the end
+		VerifyEvery int `yaml:"verifyEvery,omitempty"` // Verify the mapped commits and HEAD of the target every N commits
+
+		VerifyManifest bool `yaml:"verifyManifest,omitempty"` // Compare the tip of every branch with a fresh CVS checkout after migrating
+
+		ContentCacheMB  int    `yaml:"contentCacheMB,omitempty"`  // Memory for reconstructed CVS file revisions (0 = default, -1 = no cache)
+		ContentCacheDir string `yaml:"contentCacheDir,omitempty"` // Keep reconstructed CVS file revisions on disk across runs
+
+		MemoryBudgetMB int    `yaml:"memoryBudgetMB,omitempty"` // Source texts and commit content held in memory (0 = unlimited)
+		SpillDir       string `yaml:"spillDir,omitempty"`       // Directory for commit content beyond the budget
+
+		IOLimitMBps    float64 `yaml:"ioLimitMBps,omitempty"`    // MiB per second read from and written to CVS repositories (0 = unlimited)
+		IOOpsPerSecond int64   `yaml:"ioOpsPerSecond,omitempty"` // File operations per second on CVS repositories (0 = unlimited)
+
+		StrictParsing bool `yaml:"strictParsing,omitempty"` // Fail on malformed RCS files instead of migrating what can be parsed
+		ImportHistory bool `yaml:"importHistory,omitempty"` // List the events of CVSROOT/history in the migration report
+
+		Timeouts TimeoutsConfig `yaml:"timeouts,omitempty"` // Fail phases that hang instead of waiting forever
+	} `yaml:"options,omitempty"`
+
+	Notifications struct {
+		Email notify.EmailConfig `yaml:"email,omitempty"` // Sent when a migration completes or fails
+	} `yaml:"notifications,omitempty"`
+
+	Web struct {
+		Tokens []WebToken `yaml:"tokens,omitempty"` // API tokens of the web server (none = no authentication)
+	} `yaml:"web,omitempty"`
+}
+
+// TimeoutsConfig limits how long the phases of a migration or sync may
+// take (0 = no limit)
+type TimeoutsConfig struct {
+	SourceScan time.Duration `yaml:"sourceScan,omitempty"` // Reading the source history
+	Commit     time.Duration `yaml:"commit,omitempty"`     // Applying one commit
+	Refs       time.Duration `yaml:"refs,omitempty"`       // Creating the branches, and then the tags
+	CVSCommand time.Duration `yaml:"cvsCommand,omitempty"` // One call of the cvs client
+}
+
+// timeouts returns the core timeouts of the config
+func (c TimeoutsConfig) timeouts() core.Timeouts {
+	return core.Timeouts{SourceScan: c.SourceScan, Commit: c.Commit, Refs: c.Refs, CVSCommand: c.CVSCommand}
+}
+
+// validate records a violation for every negative timeout
+func (c TimeoutsConfig) validate(v *validation.Validator, field string) {
+	v.Check(c.SourceScan >= 0 && c.Commit >= 0 && c.Refs >= 0 && c.CVSCommand >= 0, field, "%s must not be negative", field)
+}
+
+// CVSClientConfig sets how the cvs client is run. It does not inherit the
+// environment, so everything it needs beyond the defaults is configured here.
+type CVSClientConfig struct {
+	Binary string            `yaml:"binary,omitempty"` // cvs executable, a path or a name looked up in path (default "cvs")
+	Path   string            `yaml:"path,omitempty"`   // PATH of the cvs process (default: PATH of git-migrator)
+	RSH    string            `yaml:"rsh,omitempty"`    // CVS_RSH of :ext: roots (default "ssh")
+	Env    map[string]string `yaml:"env,omitempty"`    // Additional environment variables, e.g. CVS_SERVER
+}
+
+// client returns the cvs client of the config
+func (c CVSClientConfig) client() cvs.Client {
+	return cvs.Client{Binary: c.Binary, SearchPath: c.Path, RSH: c.RSH, Env: c.Env}
+}
+
+// validate records a violation if a configured cvs binary cannot be found
+func (c CVSClientConfig) validate(v *validation.Validator, field string) {
+	if c.Binary == "" {
+		return
+	}
+	_, err := c.client().LookPath()
+	v.Check(err == nil, field+".binary", "%s.binary %q not found", field, c.Binary)
+}
+
+// WebToken is an API token of the web server. Like the push credentials, the
+// token itself is read from an environment variable.
+type WebToken struct {
+	TokenEnv string `yaml:"tokenEnv"`
+	Role     string `yaml:"role"` // viewer or operator
+}
+
+// LDAPConfig is the LDAP or Active Directory server that authors extract
+// resolves logins with. The bind password is read from an environment
+// variable.
+type LDAPConfig struct {
+	URL            string        `yaml:"url"`
+	BindDN         string        `yaml:"bindDN,omitempty"`
+	PasswordEnv    string        `yaml:"passwordEnv,omitempty"`
+	BaseDN         string        `yaml:"baseDN"`
+	LoginAttribute string        `yaml:"loginAttribute,omitempty"` // Default uid; sAMAccountName for Active Directory
+	NameAttribute  string        `yaml:"nameAttribute,omitempty"`  // Default displayName
+	MailAttribute  string        `yaml:"mailAttribute,omitempty"`  // Default mail
+	ObjectClass    string        `yaml:"objectClass,omitempty"`    // Only match entries of this class
+	Timeout        time.Duration `yaml:"timeout,omitempty"`
+	CacheFile      string        `yaml:"cacheFile,omitempty"`      // Keeps the lookups across runs
+	CacheMaxAge    time.Duration `yaml:"cacheMaxAge,omitempty"`    // Look cached logins up again after this long (0 = never)
+	AllowPlaintext bool          `yaml:"allowPlaintext,omitempty"` // Bind over ldap:// without StartTLS
+}
+
+// PushConfig holds the credentials and safety settings used to push to
+// target.remote. Secrets are read from environment variables so they never
+// need to be stored in the configuration file.
+type PushConfig struct {
+	Username          string `yaml:"username,omitempty"`
+	PasswordEnv       string `yaml:"passwordEnv,omitempty"`
+	SSHKey            string `yaml:"sshKey,omitempty"`
+	SSHKeyPasswordEnv string `yaml:"sshKeyPasswordEnv,omitempty"`
+	ForceWithLease    bool   `yaml:"forceWithLease,omitempty"`
+	Retries           int    `yaml:"retries,omitempty"`
+}
+
+// GraftConfig selects the commit of an existing target repository that
+// holds a hand-made import of the CVS sources
+type GraftConfig struct {
+	Commit string    `yaml:"commit,omitempty"` // Commit or ref of the import (default: HEAD)
+	Mode   string    `yaml:"mode,omitempty"`   // onto (default) or replace
+	Date   time.Time `yaml:"date,omitempty"`   // CVS state the import holds (default: its author date)
+	Branch string    `yaml:"branch,omitempty"` // Branch receiving the history in replace mode
 }
 
 func init() {
@@ -71,8 +295,18 @@ func init() {
 
 	migrateCmd.Flags().StringVarP(&migrateConfigFile, "config", "c", "", "Path to configuration file (required)")
 	migrateCmd.Flags().BoolVarP(&migrateDryRun, "dry-run", "d", false, "Preview migration without making changes")
+	migrateCmd.Flags().BoolVar(&migrateSimulate, "simulate", false, "Migrate into an in-memory repository and report its size and refs")
+	migrateCmd.MarkFlagsMutuallyExclusive("dry-run", "simulate")
 	migrateCmd.Flags().BoolVarP(&migrateVerbose, "verbose", "v", false, "Show detailed progress information")
+	migrateCmd.Flags().BoolVarP(&migrateQuiet, "quiet", "q", false, "Only print the summary")
+	migrateCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
 	migrateCmd.Flags().BoolVarP(&migrateResume, "resume", "r", false, "Resume an interrupted migration")
+	migrateCmd.Flags().BoolVar(&migrateFailFast, "fail-fast", false, "Abort on the first failure, including branches and tags")
+	migrateCmd.Flags().BoolVar(&migrateContinueOnError, "continue-on-error", false, "Record failing commits, branches and tags and keep going")
+	migrateCmd.Flags().BoolVar(&migrateForceUnlock, "force-unlock", false, "Take over the target lock even if another run holds it")
+	migrateCmd.Flags().BoolVar(&migrateAllowExisting, "allow-existing", false, "Create the repository in a non-empty target directory")
+	migrateCmd.Flags().StringVar(&migrateProfile, "profile", "", "Write the time spent per RCS file and commit to this JSON file")
+	migrateCmd.MarkFlagsMutuallyExclusive("fail-fast", "continue-on-error")
 
 	var err = migrateCmd.MarkFlagRequired("config")
 	if err != nil {
@@ -92,66 +326,266 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	if migrateDryRun {
 		config.Options.DryRun = true
 	}
+	if migrateSimulate {
+		config.Options.Simulate = true
+	}
 	if migrateVerbose {
 		config.Options.Verbose = true
 	}
+	if migrateQuiet {
+		config.Options.Verbose = false
+		if err := quietLogging(cmd); err != nil {
+			return err
+		}
+	}
 	if migrateResume {
 		config.Options.Resume = true
 	}
-
-	// Convert config file to migration config
-	migrationConfig := &core.MigrationConfig{
-		SourceType: config.Source.Type,
-		SourcePath: config.Source.Path,
-		TargetPath: config.Target.Path,
-		AuthorMap:  config.Mapping.Authors,
-		BranchMap:  config.Mapping.Branches,
-		TagMap:     config.Mapping.Tags,
-		DryRun:     config.Options.DryRun,
-		Resume:     config.Options.Resume,
-		ChunkSize:  config.Options.ChunkSize,
+	if migrateFailFast {
+		config.Options.ErrorPolicy = core.ErrorPolicyFailFast
 	}
-
-	// Set default chunk size if not specified
-	if migrationConfig.ChunkSize == 0 {
-		migrationConfig.ChunkSize = 100
+	if migrateContinueOnError {
+		config.Options.ErrorPolicy = core.ErrorPolicyContinue
+	}
+	if err := validateMigration(config); err != nil {
+		return fmt.Errorf("invalid migration: %w", err)
 	}
 
-	// Set state file path
-	stateFile := filepath.Join(
-		filepath.Dir(migrationConfig.TargetPath),
-		".git-migrator-state.db",
-	)
-	migrationConfig.StateFile = stateFile
-
-	// Display migration information
-	if config.Options.Verbose || config.Options.DryRun {
-		printMigrationInfo(config, migrationConfig)
+	migrationConfig := buildMigrationConfig(config)
+	if migrateProfile != "" {
+		migrationConfig.Profile = profile.NewRecorder()
 	}
 
-	if config.Options.DryRun {
-		fmt.Println("\n🔍 DRY RUN MODE - No changes will be made")
+	// Display migration information
+	if !migrateQuiet {
+		if config.Options.Verbose || config.Options.DryRun {
+			printMigrationInfo(config, migrationConfig)
+		}
+		if config.Options.DryRun {
+			fmt.Println("\n🔍 DRY RUN MODE - No changes will be made")
+		}
+		if config.Options.Simulate {
+			fmt.Println("\n🧪 SIMULATION MODE - The target is built in memory")
+		}
 	}
 
 	// Create migrator
+	var bar *progress.Bar
+	if !migrateQuiet {
+		var restore func()
+		bar, restore = newProgressBar(false)
+		defer restore()
+		if config.Options.Verbose {
+			migrationConfig.Hooks = append(migrationConfig.Hooks, &commitPrinter{bar: bar})
+		}
+	}
 	migrator := core.NewMigrator(migrationConfig)
 
 	// Run migration
-	fmt.Println("\nStarting migration...")
-	if err := migrator.Run(); err != nil {
+	if bar != nil {
+		fmt.Println("\nStarting migration...")
+		done := bar.Attach(migrator.ProgressReporter())
+		err = migrator.Run()
+		done()
+	} else {
+		err = migrator.Run()
+	}
+	if migrationConfig.Profile != nil {
+		if err := migrationConfig.Profile.WriteFile(migrateProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			fmt.Printf("Profile: %s\n", migrateProfile)
+		}
+	}
+	if warnings, errors := core.CountIssues(migrator.Issues()); warnings+errors > 0 {
+		fmt.Printf("\n%d warnings, %d errors\n", warnings, errors)
+	}
+	if config.Notifications.Email.Enabled() {
+		if err := notify.NewMailer(config.Notifications.Email).SendReport(migrator.Report()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if err != nil {
+		if !config.Options.DryRun {
+			fmt.Printf("Report: %s\n", core.ReportPath(migrationConfig.TargetPath)+core.ReportExtMarkdown)
+		}
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	if config.Options.DryRun {
 		fmt.Println("\n✓ Dry run completed successfully")
-		fmt.Println("Run without --dry-run to perform actual migration")
+		if !migrateQuiet {
+			fmt.Println("Run without --dry-run to perform actual migration")
+		}
+	} else if config.Options.Simulate {
+		fmt.Println("\n✓ Simulation completed successfully")
+		printSimulation(migrator.Report().Simulation)
+		fmt.Printf("Report: %s\n", core.ReportPath(migrationConfig.TargetPath)+core.ReportExtMarkdown)
 	} else {
 		fmt.Println("\n✓ Migration completed successfully!")
+		fmt.Printf("Report: %s\n", core.ReportPath(migrationConfig.TargetPath)+core.ReportExtMarkdown)
 	}
 
 	return nil
 }
 
+// buildMigrationConfig converts a configuration file into a migration config
+func buildMigrationConfig(config *ConfigFile) *core.MigrationConfig {
+	migrationConfig := &core.MigrationConfig{
+		SourceType:      config.Source.Type,
+		SourcePath:      config.Source.Path,
+		SourceModule:    config.Source.Module,
+		TargetType:      config.Target.Type,
+		TargetPath:      config.Target.Path,
+		TargetOpts:      config.Target.Options,
+		AuthorMap:       config.Mapping.Authors,
+		Committer:       config.Mapping.Committer,
+		BranchMap:       config.Mapping.Branches,
+		DefaultBranch:   config.Target.DefaultBranch,
+		TagMap:          config.Mapping.Tags,
+		AnnotatedTags:   config.Mapping.TagType == "annotated",
+		TagMessage:      config.Mapping.TagMessage,
+		ReleaseTags:     config.Mapping.ReleaseTags,
+		SigningKey:      config.Mapping.SigningKey,
+		IncludeBranches: config.Mapping.IncludeBranches,
+		ExcludeBranches: config.Mapping.ExcludeBranches,
+		IncludeTags:     config.Mapping.IncludeTags,
+		ExcludeTags:     config.Mapping.ExcludeTags,
+		DryRun:          config.Options.DryRun,
+		Simulate:        config.Options.Simulate,
+		Resume:          config.Options.Resume,
+		ChunkSize:       config.Options.ChunkSize,
+		RepackEvery:     config.Options.RepackEvery,
+		VerifyEvery:     config.Options.VerifyEvery,
+		LogDir:          config.Options.LogDir,
+		Compat:          config.Options.Compat,
+		EOL:             config.Options.EOL,
+		CRLFExtensions:  config.Options.CRLFExtensions,
+		RevisionRules:   config.Options.RevisionRules,
+		MessageTemplate: config.Options.MessageTemplate,
+		DatePolicy:      config.Options.DatePolicy,
+		DateTimezone:    config.Options.DateTimezone,
+		AuthorTimezones: config.Mapping.AuthorTimezones,
+		MonotonicDates:  config.Options.MonotonicDates,
+		Deterministic:   config.Options.Deterministic,
+		HistorySince:    config.Options.HistorySince,
+		HistoryDepth:    config.Options.HistoryDepth,
+		MaxCommitFiles:  config.Options.MaxCommitFiles,
+		MaxCommitBytes:  int64(config.Options.MaxCommitMB) << 20,
+		ErrorPolicy:     config.Options.ErrorPolicy,
+		Retries:         config.Options.Retries,
+		RetryDelay:      config.Options.RetryDelay,
+		ForceUnlock:     migrateForceUnlock,
+		AllowExisting:   migrateAllowExisting,
+		ContentCacheDir: config.Options.ContentCacheDir,
+		MemoryBudget:    int64(config.Options.MemoryBudgetMB) << 20,
+		SpillDir:        config.Options.SpillDir,
+		StrictParsing:   config.Options.StrictParsing,
+		ImportHistory:   config.Options.ImportHistory,
+	}
+	if config.Options.ContentCacheMB < 0 {
+		migrationConfig.ContentCacheSize = -1
+	} else {
+		migrationConfig.ContentCacheSize = int64(config.Options.ContentCacheMB) << 20
+	}
+	migrationConfig.RenameSimilarity = config.Options.RenameSimilarity
+	migrationConfig.RenameLimit = config.Options.RenameLimit
+	migrationConfig.Timeouts = config.Options.Timeouts.timeouts()
+	migrationConfig.CVSClient = config.Source.Client.client()
+	migrationConfig.CaseConflicts = config.Options.CaseConflicts
+	migrationConfig.SanitizeWindows = config.Options.SanitizeWindowsPaths
+	migrationConfig.UnicodeForm = config.Options.PathNormalization
+	if config.Options.IOLimitMBps > 0 {
+		migrationConfig.ReadLimit = max(int64(config.Options.IOLimitMBps*(1<<20)), 1)
+	}
+	migrationConfig.OpsLimit = config.Options.IOOpsPerSecond
+	migrationConfig.VerifyManifest = config.Options.VerifyManifest
+	migrationConfig.RequireAuthors = config.Mapping.RequireAuthors
+
+	for _, join := range config.Source.Join {
+		migrationConfig.JoinModules = append(migrationConfig.JoinModules, core.JoinModule{Module: join.Module, Path: join.Path})
+	}
+	for _, rule := range config.Options.Keywords {
+		migrationConfig.Keywords = append(migrationConfig.Keywords, core.KeywordRule{Pattern: rule.Pattern, Mode: rule.Mode})
+	}
+	for _, rule := range config.Options.LicenseHeaders {
+		migrationConfig.LicenseHeaders = append(migrationConfig.LicenseHeaders, core.LicenseRule{Pattern: rule.Pattern, Header: rule.Header, Comment: rule.Comment})
+	}
+
+	if config.Mapping.SigningKeyPassphraseEnv != "" {
+		migrationConfig.KeyPassphrase = os.Getenv(config.Mapping.SigningKeyPassphraseEnv)
+	}
+
+	if graft := config.Target.Graft; graft != nil {
+		migrationConfig.Graft = &core.GraftConfig{Commit: graft.Commit, Mode: graft.Mode, Date: graft.Date, Branch: graft.Branch}
+	}
+
+	// A simulation keeps the target in memory, so there is nothing to push
+	if !config.Options.Simulate {
+		migrationConfig.Push = pushOptions(config)
+	}
+	if config.Hooks.PreCommit != "" || config.Hooks.PostCommit != "" {
+		migrationConfig.Hooks = []core.CommitHook{&core.CommandHook{
+			Before: config.Hooks.PreCommit,
+			After:  config.Hooks.PostCommit,
+		}}
+	}
+
+	// Set default chunk size if not specified
+	if migrationConfig.ChunkSize == 0 {
+		migrationConfig.ChunkSize = 100
+	}
+
+	// Set state file path
+	migrationConfig.StateFile = defaultStateFile(migrationConfig.TargetPath)
+
+	return migrationConfig
+}
+
+// pushOptions converts the target remote settings into push options, or
+// returns nil when no remote is configured
+func pushOptions(config *ConfigFile) *git.PushOptions {
+	if config.Target.Remote == "" {
+		return nil
+	}
+
+	push := config.Target.Push
+	opts := &git.PushOptions{
+		URL:            config.Target.Remote,
+		RemoteName:     config.Target.RemoteName,
+		Username:       push.Username,
+		SSHKeyPath:     push.SSHKey,
+		ForceWithLease: push.ForceWithLease,
+		Retries:        push.Retries,
+	}
+	if push.PasswordEnv != "" {
+		opts.Password = os.Getenv(push.PasswordEnv)
+	}
+	if push.SSHKeyPasswordEnv != "" {
+		opts.SSHKeyPassword = os.Getenv(push.SSHKeyPasswordEnv)
+	}
+	return opts
+}
+
+// mergeAuthorsFile returns authors with the entries of the author map file
+// at path added under them. An empty path only makes sure the map exists.
+func mergeAuthorsFile(authors map[string]string, path string) (map[string]string, error) {
+	merged := make(map[string]string, len(authors))
+	if path != "" {
+		fileAuthors, err := mapping.LoadAuthorFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for login, author := range fileAuthors {
+			merged[login] = author
+		}
+	}
+	for login, author := range authors {
+		merged[login] = author
+	}
+	return merged, nil
+}
+
 func loadConfigFile(path string) (*ConfigFile, error) {
 	// Read file
 	data, err := os.ReadFile(path)
@@ -165,24 +599,19 @@ func loadConfigFile(path string) (*ConfigFile, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// Validate required fields
-	if config.Source.Type == "" {
-		return nil, fmt.Errorf("source.type is required")
-	}
-	if config.Source.Path == "" {
-		return nil, fmt.Errorf("source.path is required")
-	}
-	if config.Target.Path == "" {
-		return nil, fmt.Errorf("target.path is required")
+	if err := validateConfigFile(&config); err != nil {
+		return nil, err
 	}
 
 	// Set defaults
 	if config.Target.Type == "" {
 		config.Target.Type = "git"
 	}
-	if config.Mapping.Authors == nil {
-		config.Mapping.Authors = make(map[string]string)
+	authors, err := mergeAuthorsFile(config.Mapping.Authors, config.Mapping.AuthorsFile)
+	if err != nil {
+		return nil, fmt.Errorf("mapping.authorsFile: %w", err)
 	}
+	config.Mapping.Authors = authors
 	if config.Mapping.Branches == nil {
 		config.Mapping.Branches = make(map[string]string)
 	}
@@ -193,6 +622,133 @@ func loadConfigFile(path string) (*ConfigFile, error) {
 	return &config, nil
 }
 
+// validateConfigFile checks the fields of a migration config file and
+// loads its license header files, reporting every violation at once
+func validateConfigFile(config *ConfigFile) error {
+	v := &validation.Validator{}
+	v.Required("source.type", config.Source.Type)
+	v.Required("source.path", config.Source.Path)
+	v.Required("target.path", config.Target.Path)
+
+	v.OneOf("mapping.tagType", config.Mapping.TagType, "", "lightweight", "annotated")
+	if graft := config.Target.Graft; graft != nil {
+		v.OneOf("target.graft.mode", graft.Mode, "", core.GraftOnto, core.GraftReplace)
+	}
+
+	v.OneOf("options.eol", config.Options.EOL, "", core.EOLAsIs, core.EOLLF, core.EOLCRLFByExtension)
+	v.OneOf("options.caseConflicts", config.Options.CaseConflicts, "", core.CaseConflictReport, core.CaseConflictSuffix, core.CaseConflictUnify, core.CaseConflictFail)
+	v.OneOf("options.pathNormalization", config.Options.PathNormalization, "", core.NormalizeNFC, core.NormalizeNFD)
+
+	for _, rule := range config.Options.Keywords {
+		v.Check(rule.Mode == core.KeywordsKeep || rule.Mode == core.KeywordsStrip || rule.Mode == core.KeywordsExpand,
+			"options.keywords", "options.keywords mode for %q must be %s, %s or %s", rule.Pattern, core.KeywordsKeep, core.KeywordsStrip, core.KeywordsExpand)
+		v.Check(rule.Pattern != "", "options.keywords", "options.keywords entries require a pattern")
+	}
+
+	for i := range config.Options.LicenseHeaders {
+		rule := &config.Options.LicenseHeaders[i]
+		if !v.Check(rule.Pattern != "", "options.licenseHeaders", "options.licenseHeaders entries require a pattern") {
+			continue
+		}
+		if !v.Check((rule.Header == "") != (rule.HeaderFile == ""), "options.licenseHeaders", "options.licenseHeaders for %q requires either header or headerFile", rule.Pattern) {
+			continue
+		}
+		if rule.HeaderFile != "" {
+			header, err := os.ReadFile(rule.HeaderFile)
+			if v.Check(err == nil, "options.licenseHeaders", "options.licenseHeaders for %q: %v", rule.Pattern, err) {
+				rule.Header = string(header)
+			}
+		}
+	}
+
+	if config.Options.RevisionRules != "" {
+		_, err := core.LoadRevisionRules(config.Options.RevisionRules)
+		v.Check(err == nil, "options.revisionRules", "options.revisionRules: %v", err)
+	}
+	if config.Options.MessageTemplate != "" {
+		_, err := core.ParseMessageTemplate(config.Options.MessageTemplate)
+		v.Check(err == nil, "options.messageTemplate", "options.messageTemplate: %v", err)
+	}
+
+	v.OneOf("options.datePolicy", config.Options.DatePolicy, "", core.DatePreserveUTC, core.DateFixedOffset, core.DatePerAuthor)
+	v.OneOf("options.errorPolicy", config.Options.ErrorPolicy, core.ErrorPolicyDefault, core.ErrorPolicyFailFast, core.ErrorPolicyContinue)
+
+	v.Check(config.Options.Retries >= 0 && config.Options.RetryDelay >= 0, "options.retries", "options.retries and options.retryDelay must not be negative")
+	v.Check(config.Options.HistoryDepth >= 0, "options.historyDepth", "options.historyDepth must not be negative")
+	v.Check(config.Options.MaxCommitFiles >= 0 && config.Options.MaxCommitMB >= 0, "options.maxCommitFiles", "options.maxCommitFiles and options.maxCommitMB must not be negative")
+	v.Check(config.Options.RenameSimilarity >= 0 && config.Options.RenameSimilarity <= 100, "options.renameSimilarity", "options.renameSimilarity must be a percentage between 0 and 100")
+	v.Check(config.Options.RenameLimit >= 0, "options.renameLimit", "options.renameLimit must not be negative")
+	v.Check(config.Options.MemoryBudgetMB >= 0, "options.memoryBudgetMB", "options.memoryBudgetMB must not be negative")
+	v.Check(config.Options.IOLimitMBps >= 0 && config.Options.IOOpsPerSecond >= 0, "options.ioLimitMBps", "options.ioLimitMBps and options.ioOpsPerSecond must not be negative")
+	config.Options.Timeouts.validate(v, "options.timeouts")
+
+	v.Check(len(config.Source.Join) == 0 || config.Source.Module == "", "source.join", "source.module and source.join are mutually exclusive")
+	for _, join := range config.Source.Join {
+		v.Check(join.Module != "", "source.join", "source.join entries require a module")
+	}
+
+	// Options that only make sense together
+	v.Check(!config.Mapping.RequireAuthors || len(config.Mapping.Authors) > 0 || config.Mapping.AuthorsFile != "",
+		"mapping.requireAuthors", "mapping.requireAuthors needs mapping.authors or mapping.authorsFile")
+
+	if err := config.Notifications.Email.Validate(); err != nil {
+		v.Addf("notifications.email", "notifications.email: %v", err)
+	}
+	return v.Err()
+}
+
+// validateMigration checks the repositories and the options of a migration
+// once the command-line flags are applied, reporting every violation at once
+func validateMigration(config *ConfigFile) error {
+	v := &validation.Validator{}
+	if v.OneOf("source.type", config.Source.Type, "cvs") {
+		v.Repository("source.path", config.Source.Path, config.Source.Type)
+	}
+	config.Source.Client.validate(v, "source.client")
+	v.Target("target.path", config.Target.Path)
+
+	if config.Options.Resume {
+		v.Check(!config.Options.Simulate && !config.Options.DryRun, "options.resume",
+			"options.resume cannot be combined with a dry run or a simulation")
+		if !config.Options.Simulate && !config.Options.DryRun {
+			v.Check(hasResumeState(config), "options.resume", "options.resume needs the state of an earlier run of this migration in %s", defaultStateFile(config.Target.Path))
+		}
+	}
+	return v.Err()
+}
+
+// hasResumeState reports whether the state file of the target, which
+// sibling repositories share, records an earlier run of this migration
+func hasResumeState(config *ConfigFile) bool {
+	stateFile := defaultStateFile(config.Target.Path)
+	if _, err := os.Stat(stateFile); err != nil {
+		return false
+	}
+	db, err := storage.NewStateDB(stateFile)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = db.Close() }()
+	_, err = db.Load(core.NewMigrator(buildMigrationConfig(config)).MigrationID())
+	return err == nil
+}
+
+// printSimulation prints the repository a simulated migration built
+func printSimulation(sim *core.ReportSimulation) {
+	if sim == nil {
+		return
+	}
+	fmt.Printf("Objects:        %d commits, %d trees, %d blobs, %d tags\n", sim.Commits, sim.Trees, sim.Blobs, sim.Tags)
+	fmt.Printf("Raw Size:       %d bytes\n", sim.RawBytes)
+	fmt.Printf("Pack Estimate:  %d bytes\n", sim.EstimatedPackBytes)
+	if sim.HeadTree != "" {
+		fmt.Printf("HEAD Tree:      %s\n", sim.HeadTree)
+	}
+	for _, ref := range sim.Refs {
+		fmt.Printf("  %s %s\n", ref.Hash, ref.Name)
+	}
+}
+
 func printMigrationInfo(config *ConfigFile, migrationConfig *core.MigrationConfig) {
 	fmt.Println("\nMigration Configuration")
 	fmt.Println("======================")
@@ -201,13 +757,63 @@ func printMigrationInfo(config *ConfigFile, migrationConfig *core.MigrationConfi
 	if config.Source.Module != "" {
 		fmt.Printf("Source Module:  %s\n", config.Source.Module)
 	}
+	for _, join := range migrationConfig.JoinModules {
+		path := join.Path
+		if path == "" {
+			path = join.Module
+		}
+		fmt.Printf("Joined Module:  %s -> %s/\n", join.Module, path)
+	}
 	fmt.Printf("Target Path:    %s\n", config.Target.Path)
+	if config.Target.DefaultBranch != "" {
+		fmt.Printf("Default Branch: %s\n", config.Target.DefaultBranch)
+	}
 	if config.Target.Remote != "" {
-		fmt.Printf("Target Remote:  %s\n", config.Target.Remote)
+		fmt.Printf("Target Remote:  %s\n", git.RedactURL(config.Target.Remote))
+	}
+	if graft := config.Target.Graft; graft != nil {
+		commit, mode := graft.Commit, graft.Mode
+		if commit == "" {
+			commit = "HEAD"
+		}
+		if mode == "" {
+			mode = core.GraftOnto
+		}
+		fmt.Printf("Graft:          %s (%s)\n", commit, mode)
 	}
 	fmt.Printf("Dry Run:        %v\n", config.Options.DryRun)
+	if config.Options.Simulate {
+		fmt.Printf("Simulate:       %v\n", config.Options.Simulate)
+	}
 	fmt.Printf("Resume:         %v\n", config.Options.Resume)
 	fmt.Printf("Chunk Size:     %d\n", config.Options.ChunkSize)
+	if config.Options.RepackEvery > 0 {
+		fmt.Printf("Repack Every:   %d commits\n", config.Options.RepackEvery)
+	}
+	if config.Options.VerifyEvery > 0 {
+		fmt.Printf("Verify Every:   %d commits\n", config.Options.VerifyEvery)
+	}
+	if config.Options.LogDir != "" {
+		fmt.Printf("Log Directory:  %s\n", config.Options.LogDir)
+	}
+	if config.Options.ContentCacheDir != "" {
+		fmt.Printf("Content Cache:  %s\n", config.Options.ContentCacheDir)
+	}
+	if config.Options.MemoryBudgetMB > 0 {
+		fmt.Printf("Memory Budget:  %d MB\n", config.Options.MemoryBudgetMB)
+	}
+	if config.Options.StrictParsing {
+		fmt.Printf("Strict Parsing: %v\n", config.Options.StrictParsing)
+	}
+	if config.Options.ImportHistory {
+		fmt.Printf("Import History: %v\n", config.Options.ImportHistory)
+	}
+	if config.Options.Compat != "" {
+		fmt.Printf("Compatibility:  %s\n", config.Options.Compat)
+	}
+	if config.Options.ErrorPolicy != "" {
+		fmt.Printf("Error Policy:   %s\n", config.Options.ErrorPolicy)
+	}
 
 	if len(config.Mapping.Authors) > 0 {
 		fmt.Printf("\nAuthor Mappings: %d\n", len(config.Mapping.Authors))
@@ -218,6 +824,10 @@ func printMigrationInfo(config *ConfigFile, migrationConfig *core.MigrationConfi
 		}
 	}
 
+	if config.Mapping.Committer != "" {
+		fmt.Printf("\nCommitter:       %s\n", config.Mapping.Committer)
+	}
+
 	if len(config.Mapping.Branches) > 0 {
 		fmt.Printf("\nBranch Mappings: %d\n", len(config.Mapping.Branches))
 		if config.Options.Verbose {
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/spf13/cobra"
+)
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// quietLogging raises the log level to warnings for --quiet, unless
+// --log-level was given explicitly
+func quietLogging(cmd *cobra.Command) error {
+	if cmd != nil && cmd.Flags().Changed("log-level") {
+		return nil
+	}
+	logger, err := logging.New(logging.Options{Level: "warn", Format: logFormat, Output: os.Stderr})
+	if err != nil {
+		return err
+	}
+	logging.SetDefault(logger)
+	return nil
+}
+
+// newProgressBar creates a bar on stderr and sends log records through it
+// so they do not break it. It must be called before the migrator or syncer
+// is created, as they keep the logger. verbose prints every operation on
+// its own line. The returned function restores the previous logger.
+func newProgressBar(verbose bool) (*progress.Bar, func()) {
+	bar := progress.NewBar(os.Stderr, isTerminal(os.Stderr))
+	bar.SetVerbose(verbose)
+
+	previous := logging.Default()
+	if logger, err := logging.New(logging.Options{Level: logLevel, Format: logFormat, Output: bar.Writer(os.Stderr)}); err == nil {
+		logging.SetDefault(logger)
+	}
+	return bar, func() { logging.SetDefault(previous) }
+}
+
+// commitPrinter is a commit hook printing a line per applied commit
+type commitPrinter struct {
+	bar *progress.Bar
+}
+
+func (p *commitPrinter) BeforeCommit(*vcs.Commit) error { return nil }
+
+func (p *commitPrinter) AfterCommit(commit *vcs.Commit, hash string) error {
+	if len(hash) > 8 {
+		hash = hash[:8]
+	}
+	subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	p.bar.Println(fmt.Sprintf("  %s → %s %s: %s", commit.Revision, hash, commit.Author, subject))
+	return nil
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a migration, optionally from an earlier checkpoint",
+	Long: `Resume the migration described by the configuration file.
+
+Without --from, the migration continues after its last checkpoint, like
+migrate --resume. With --from, the target repository and the state are
+first rewound to the given checkpoint, like rollback: the commits written
+after it are removed and applied again with the current
+configuration, e.g. after correcting the author map. List the checkpoints
+with the checkpoints command.
+
+Example usage:
+  git-migrator checkpoints --config config.yaml
+  git-migrator resume --config config.yaml --from 42`,
+	Args: cobra.NoArgs,
+	RunE: runResume,
+}
+
+var checkpointsCmd = &cobra.Command{
+	Use:   "checkpoints",
+	Short: "List the checkpoints of a migration",
+	Long: `List the checkpoints recorded for the migration described by the
+configuration file, oldest first. Every commit written to the target is a
+checkpoint; pass its ID to resume --from to continue from there.
+
+  git-migrator checkpoints --config config.yaml --last 20`,
+	Args: cobra.NoArgs,
+	RunE: runCheckpoints,
+}
+
+var (
+	resumeConfigFile  string
+	resumeFrom        int64
+	resumeForceUnlock bool
+	checkpointsConfig string
+	checkpointsLast   int
+)
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(checkpointsCmd)
+
+	resumeCmd.Flags().StringVarP(&resumeConfigFile, "config", "c", "", "Path to configuration file (required)")
+	resumeCmd.Flags().Int64Var(&resumeFrom, "from", 0, "Rewind to this checkpoint before resuming")
+	resumeCmd.Flags().BoolVar(&resumeForceUnlock, "force-unlock", false, "Take over the target lock even if another run holds it")
+	checkpointsCmd.Flags().StringVarP(&checkpointsConfig, "config", "c", "", "Path to configuration file (required)")
+	checkpointsCmd.Flags().IntVar(&checkpointsLast, "last", 0, "Only list the most recent checkpoints")
+
+	for _, cmd := range []*cobra.Command{resumeCmd, checkpointsCmd} {
+		if err := cmd.MarkFlagRequired("config"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	if resumeFrom != 0 {
+		config, err := loadConfigFile(resumeConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		migrationConfig := buildMigrationConfig(config)
+		migrationConfig.ForceUnlock = resumeForceUnlock
+
+		result, err := core.RewindToCheckpoint(migrationConfig, resumeFrom)
+		if err != nil {
+			return fmt.Errorf("failed to rewind to checkpoint %d: %w", resumeFrom, err)
+		}
+		printRewindResult(result)
+	}
+
+	oldConfig, oldResume, oldUnlock := migrateConfigFile, migrateResume, migrateForceUnlock
+	defer func() { migrateConfigFile, migrateResume, migrateForceUnlock = oldConfig, oldResume, oldUnlock }()
+	migrateConfigFile, migrateResume, migrateForceUnlock = resumeConfigFile, true, resumeForceUnlock
+	return runMigrate(cmd, args)
+}
+
+func runCheckpoints(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigFile(checkpointsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	checkpoints, err := core.Checkpoints(buildMigrationConfig(config))
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints recorded")
+		return nil
+	}
+	if checkpointsLast > 0 && len(checkpoints) > checkpointsLast {
+		checkpoints = checkpoints[len(checkpoints)-checkpointsLast:]
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPROGRESS\tREVISION\tCOMMIT\tCREATED")
+	for _, cp := range checkpoints {
+		fmt.Fprintf(tw, "%d\t%d/%d\t%s\t%s\t%s\n",
+			cp.ID, cp.Processed, cp.Total, cp.LastCommit, shortCommit(cp.GitHash),
+			cp.CreatedAt.Local().Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+func printRewindResult(result *core.RewindResult) {
+	cp := result.Checkpoint
+	fmt.Printf("Rewound to checkpoint %d: %d/%d commits, revision %s, commit %s\n",
+		cp.ID, cp.Processed, cp.Total, cp.LastCommit, shortCommit(cp.GitHash))
+	if len(result.Branches) > 0 {
+		fmt.Printf("Moved branches: %s\n", strings.Join(result.Branches, ", "))
+	}
+	if len(result.Tags) > 0 {
+		fmt.Printf("Deleted tags:   %s\n", strings.Join(result.Tags, ", "))
+	}
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
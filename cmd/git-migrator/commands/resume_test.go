@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+const resumeTestRCS = "head 1.2;\naccess;\nsymbols;\nlocks;\n\n" +
+	"1.2\ndate 2024.01.02.00.00.00; author alice; state Exp;\nbranches;\nnext 1.1;\n\n" +
+	"1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\n" +
+	"desc\n@@\n\n" +
+	"1.2\nlog\n@second\n@\ntext\n@world\n@\n\n" +
+	"1.1\nlog\n@initial\n@\ntext\n@d1 1\na1 1\nhello\n@\n"
+
+func TestRunResume_FromCheckpoint(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt,v"), []byte(resumeTestRCS), 0644))
+	target := filepath.Join(t.TempDir(), "repo")
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + target + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	oldCfg := migrateConfigFile
+	defer func() { migrateConfigFile = oldCfg }()
+	migrateConfigFile = cfgPath
+	require.NoError(t, runMigrate(migrateCmd, nil))
+
+	config, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	checkpoints, err := core.Checkpoints(buildMigrationConfig(config))
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+
+	oldResumeCfg, oldFrom, oldLast := resumeConfigFile, resumeFrom, checkpointsConfig
+	defer func() { resumeConfigFile, resumeFrom, checkpointsConfig = oldResumeCfg, oldFrom, oldLast }()
+	checkpointsConfig = cfgPath
+	require.NoError(t, runCheckpoints(checkpointsCmd, nil))
+
+	resumeConfigFile, resumeFrom = cfgPath, checkpoints[0].ID
+	require.NoError(t, runResume(resumeCmd, nil))
+
+	data, err := os.ReadFile(filepath.Join(target, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "world\n", string(data))
+	checkpoints, err = core.Checkpoints(buildMigrationConfig(config))
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2, "the second commit is applied again")
+
+	resumeFrom = 12345
+	require.Error(t, runResume(resumeCmd, nil))
+}
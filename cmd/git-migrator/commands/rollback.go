@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll the target repository and state back to a checkpoint",
+	Long: `Roll the migration described by the configuration file back to an
+earlier checkpoint, given by its ID or by the full or abbreviated hash of its
+commit. List the checkpoints with the checkpoints command.
+
+The current branch and the working tree are reset to the checkpoint commit,
+branches beyond it are moved back to it and tags on later commits are
+deleted. The state database forgets the later commits, so migrate --resume
+applies them again.
+
+Rollback refuses to run if the target has commits after the checkpoint that
+the migration did not write, e.g. commits made by hand.
+
+Example usage:
+  git-migrator rollback --config config.yaml --to 42
+  git-migrator rollback --config config.yaml --to 3f2a9c1e`,
+	Args: cobra.NoArgs,
+	RunE: runRollback,
+}
+
+var (
+	rollbackConfigFile  string
+	rollbackTo          string
+	rollbackForceUnlock bool
+)
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().StringVarP(&rollbackConfigFile, "config", "c", "", "Path to configuration file (required)")
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Checkpoint ID or commit hash to roll back to (required)")
+	rollbackCmd.Flags().BoolVar(&rollbackForceUnlock, "force-unlock", false, "Take over the target lock even if another run holds it")
+
+	for _, flag := range []string{"config", "to"} {
+		if err := rollbackCmd.MarkFlagRequired(flag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigFile(rollbackConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	migrationConfig := buildMigrationConfig(config)
+	migrationConfig.ForceUnlock = rollbackForceUnlock
+
+	cp, err := core.FindCheckpoint(migrationConfig, rollbackTo)
+	if err != nil {
+		return fmt.Errorf("failed to find checkpoint %s: %w", rollbackTo, err)
+	}
+	result, err := core.RewindToCheckpoint(migrationConfig, cp.ID)
+	if err != nil {
+		return fmt.Errorf("failed to roll back to checkpoint %d: %w", cp.ID, err)
+	}
+	printRewindResult(result)
+	fmt.Println("\nRun migrate --resume to apply the later commits again")
+	return nil
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRollback(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt,v"), []byte(resumeTestRCS), 0644))
+	target := filepath.Join(t.TempDir(), "repo")
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + target + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	oldCfg := migrateConfigFile
+	defer func() { migrateConfigFile = oldCfg }()
+	migrateConfigFile = cfgPath
+	require.NoError(t, runMigrate(migrateCmd, nil))
+
+	config, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	checkpoints, err := core.Checkpoints(buildMigrationConfig(config))
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+
+	oldRollbackCfg, oldTo := rollbackConfigFile, rollbackTo
+	defer func() { rollbackConfigFile, rollbackTo = oldRollbackCfg, oldTo }()
+	rollbackConfigFile, rollbackTo = cfgPath, checkpoints[0].GitHash[:8]
+	require.NoError(t, runRollback(rollbackCmd, nil))
+
+	data, err := os.ReadFile(filepath.Join(target, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", string(data))
+	checkpoints, err = core.Checkpoints(buildMigrationConfig(config))
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 1)
+
+	rollbackTo = "no-such-checkpoint"
+	require.Error(t, runRollback(rollbackCmd, nil))
+}
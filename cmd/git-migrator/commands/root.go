@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/adamf123git/git-migrator/internal/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -20,8 +21,14 @@ var rootCmd = &cobra.Command{
 	Long: `Git-Migrator is an open-source tool for migrating repositories from legacy
 version control systems (CVS, SVN) to Git while preserving complete history,
 including commits, branches, tags, and author information.`,
+	PersistentPreRunE: setupLogging,
 }
 
+var (
+	logLevel  string
+	logFormat string
+)
+
 // Execute runs the root command
 func Execute() error {
 	return rootCmd.Execute()
@@ -29,6 +36,24 @@ func Execute() error {
 
 func init() {
 	rootCmd.Version = Version
+
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text or json)")
+}
+
+// setupLogging configures the process-wide structured logger from the
+// persistent logging flags.
+func setupLogging(cmd *cobra.Command, args []string) error {
+	logger, err := logging.New(logging.Options{
+		Level:  logLevel,
+		Format: logFormat,
+		Output: os.Stderr,
+	})
+	if err != nil {
+		return err
+	}
+	logging.SetDefault(logger)
+	return nil
 }
 
 // handleError provides centralized error handling for commands.
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Split one CVS repository into several Git repositories",
+	Long: `Migrate the directories listed under "split" in the configuration file
+into separate Git repositories.
+
+The source is read once. Every file goes to the rule with the longest
+matching path, and that directory becomes the root of the rule's
+repository. A rule with an empty path takes every file no other rule
+claims; without one, unclaimed files are not migrated. Relative targets
+are placed below target.path.
+
+Each repository keeps its own state, so options.resume continues every
+repository from its last checkpoint.
+A "remote" set on a rule replaces target.remote for that repository.
+
+Example configuration:
+  split:
+    - path: lib/net
+      target: net
+    - path: lib
+      target: lib
+    - path: ""
+      target: rest
+
+Example usage:
+  git-migrator split --config split.yaml
+  git-migrator split --config split.yaml --continue-on-error`,
+	RunE: runSplit,
+}
+
+var (
+	splitConfigFile      string
+	splitContinueOnError bool
+)
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+
+	splitCmd.Flags().StringVarP(&splitConfigFile, "config", "c", "", "Path to configuration file (required)")
+	splitCmd.Flags().BoolVar(&splitContinueOnError, "continue-on-error", false, "Continue with the next repository when one fails")
+
+	var err = splitCmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runSplit(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigFile(splitConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	rules, err := loadSplitRules(splitConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	splitConfig := buildSplitConfig(config, rules)
+	splitConfig.ContinueOnError = splitContinueOnError
+
+	fmt.Printf("Splitting %s into %d repositories\n", config.Source.Path, len(rules))
+	result, err := core.RunSplit(splitConfig)
+	if result != nil {
+		printSplitResult(result)
+	}
+	if err != nil {
+		return fmt.Errorf("split migration failed: %w", err)
+	}
+
+	fmt.Println("\n✓ Split migration completed successfully!")
+	return nil
+}
+
+// splitRule is a rule of the split list in a configuration file
+type splitRule struct {
+	Path   string `yaml:"path"`
+	Target string `yaml:"target"`
+	Remote string `yaml:"remote"`
+}
+
+// loadSplitRules reads the split rules of a configuration file
+func loadSplitRules(path string) ([]splitRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var split struct {
+		Split []splitRule `yaml:"split"`
+	}
+	if err := yaml.Unmarshal(data, &split); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(split.Split) == 0 {
+		return nil, fmt.Errorf("split is required")
+	}
+	for _, rule := range split.Split {
+		if rule.Target == "" {
+			return nil, fmt.Errorf("split rule for %q has no target", rule.Path)
+		}
+	}
+	return split.Split, nil
+}
+
+// buildSplitConfig derives the split configuration from a configuration
+// file. Relative targets are resolved against target.path.
+func buildSplitConfig(config *ConfigFile, rules []splitRule) *core.SplitConfig {
+	remotes := make(map[string]string)
+	splitConfig := &core.SplitConfig{}
+	for _, rule := range rules {
+		target := rule.Target
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(config.Target.Path, target)
+		}
+		if rule.Remote != "" {
+			remotes[target] = rule.Remote
+		}
+		splitConfig.Rules = append(splitConfig.Rules, core.SplitRule{Path: rule.Path, Target: target})
+	}
+
+	splitConfig.TargetConfig = func(rule core.SplitRule) *core.MigrationConfig {
+		targetConfig := *config
+		targetConfig.Target.Path = rule.Target
+		if remote, ok := remotes[rule.Target]; ok {
+			targetConfig.Target.Remote = remote
+		}
+		return buildMigrationConfig(&targetConfig)
+	}
+	return splitConfig
+}
+
+func printSplitResult(result *core.SplitResult) {
+	fmt.Println("\nSplit Summary")
+	fmt.Println("=============")
+	targets := make([]string, 0, len(result.Reports))
+	for target := range result.Reports {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		if msg, failed := result.Failed[target]; failed {
+			fmt.Printf("  %s: failed: %s\n", target, msg)
+			continue
+		}
+		fmt.Printf("  %s: %d commits\n", target, result.Reports[target].Commits.Applied)
+	}
+}
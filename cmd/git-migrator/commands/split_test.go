@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSplit(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	tgt := t.TempDir()
+	for _, dir := range []string{"lib", "app"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(src, dir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, dir, "a.txt,v"), []byte(batchTestRCS), 0644))
+	}
+
+	cfgPath := filepath.Join(t.TempDir(), "split.yaml")
+	cfg := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + tgt +
+		"\nsplit:\n  - path: lib\n    target: lib\n  - path: app\n    target: " + filepath.Join(tgt, "application") + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+
+	oldCfg, oldContinue := splitConfigFile, splitContinueOnError
+	defer func() { splitConfigFile, splitContinueOnError = oldCfg, oldContinue }()
+	splitConfigFile, splitContinueOnError = cfgPath, false
+
+	require.NoError(t, runSplit(nil, nil))
+	require.FileExists(t, filepath.Join(tgt, "lib", "a.txt"))
+	require.FileExists(t, filepath.Join(tgt, "application", "a.txt"))
+}
+
+func TestLoadSplitRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "cfg.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	rules, err := loadSplitRules(write("split:\n  - path: lib\n    target: lib\n    remote: git@example.com:lib.git\n"))
+	require.NoError(t, err)
+	require.Equal(t, []splitRule{{Path: "lib", Target: "lib", Remote: "git@example.com:lib.git"}}, rules)
+
+	_, err = loadSplitRules(write("source:\n  type: cvs\n"))
+	require.Error(t, err)
+
+	_, err = loadSplitRules(write("split:\n  - path: lib\n"))
+	require.Error(t, err)
+
+	config := &ConfigFile{}
+	config.Target.Path, config.Target.Remote = "/repos", "git@example.com:all.git"
+	splitConfig := buildSplitConfig(config, rules)
+	require.Equal(t, "/repos/lib", splitConfig.Rules[0].Target)
+	require.Equal(t, "git@example.com:lib.git", splitConfig.TargetConfig(splitConfig.Rules[0]).Push.URL)
+}
@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the migrations recorded in the state database",
+	Long: `List every migration recorded in the state database, most recently
+updated first, with its source, target, status and progress.
+
+  git-migrator history --target ./repo`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [migration-id]",
+	Short: "Show the state of a migration",
+	Long: `Show the state of one migration from the state database: status,
+progress, the last migrated revision, a commit interrupted by a crash and
+the issues recorded so far. The migration ID may be abbreviated to any
+unique prefix. Without an ID, the most recently updated migration is shown.
+
+  git-migrator status --target ./repo
+  git-migrator status --state .git-migrator-state.db 3f2a9c1e`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStatus,
+}
+
+var (
+	stateDBFile   string
+	stateDBTarget string
+)
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(statusCmd)
+
+	for _, cmd := range []*cobra.Command{historyCmd, statusCmd} {
+		cmd.Flags().StringVar(&stateDBFile, "state", "", "Path to the migration state database")
+		cmd.Flags().StringVarP(&stateDBTarget, "target", "t", "", "Path to the migrated Git repository (locates the state database)")
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	db, err := openStateDB(stateDBFile, stateDBTarget)
+	if err != nil {
+		return err
+	}
+	defer closeStateDB(db)
+
+	history, err := db.History()
+	if err != nil {
+		return fmt.Errorf("failed to read migration history: %w", err)
+	}
+	if len(history) == 0 {
+		fmt.Println("No migrations recorded")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSOURCE\tTARGET\tSTATUS\tPROGRESS\tUPDATED")
+	for _, state := range history {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			state.MigrationID, state.SourcePath, state.TargetPath, state.Status,
+			formatProgress(state), state.LastUpdated.Local().Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	db, err := openStateDB(stateDBFile, stateDBTarget)
+	if err != nil {
+		return err
+	}
+	defer closeStateDB(db)
+
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+	state, err := findMigration(db, prefix)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Migration:    %s\n", state.MigrationID)
+	fmt.Printf("Source:       %s\n", state.SourcePath)
+	fmt.Printf("Target:       %s\n", state.TargetPath)
+	fmt.Printf("Status:       %s\n", state.Status)
+	fmt.Printf("Progress:     %s\n", formatProgress(state))
+	if state.LastCommit != "" {
+		fmt.Printf("Last commit:  %s\n", state.LastCommit)
+	}
+	fmt.Printf("Last update:  %s (%s ago)\n", state.LastUpdated.Local().Format(time.DateTime),
+		time.Since(state.LastUpdated).Round(time.Second))
+
+	pending, err := db.PendingCommit(state.MigrationID)
+	switch {
+	case err == nil:
+		fmt.Printf("\nInterrupted while writing the commit for %s (started %s);\nit is checked on --resume\n",
+			strings.Join(pending.Revisions, ", "), pending.StartedAt.Local().Format(time.DateTime))
+	case !errors.Is(err, storage.ErrNoPendingCommit):
+		return fmt.Errorf("failed to read pending commit: %w", err)
+	}
+
+	issues, err := db.LoadIssues(state.MigrationID)
+	if err != nil {
+		return fmt.Errorf("failed to read issues: %w", err)
+	}
+	if len(issues) > 0 {
+		fmt.Printf("\nIssues: %d\n", len(issues))
+		for _, issue := range issues {
+			fmt.Printf("  [%s] %s %s: %s\n", issue.Severity, issue.Phase, issue.Subject, issue.Message)
+		}
+	}
+	return nil
+}
+
+// findMigration returns the migration whose ID starts with prefix, or the
+// most recently updated one if prefix is empty
+func findMigration(db *storage.StateDB, prefix string) (*storage.MigrationState, error) {
+	if prefix != "" {
+		state, err := db.Load(prefix)
+		if err == nil {
+			return state, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to load migration: %w", err)
+		}
+	}
+
+	history, err := db.History()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	if prefix == "" {
+		if len(history) == 0 {
+			return nil, fmt.Errorf("no migrations recorded")
+		}
+		return history[0], nil
+	}
+
+	var found *storage.MigrationState
+	for _, state := range history {
+		if !strings.HasPrefix(state.MigrationID, prefix) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("migration ID %q is ambiguous", prefix)
+		}
+		found = state
+	}
+	if found == nil {
+		return nil, fmt.Errorf("migration %q not found", prefix)
+	}
+	return found, nil
+}
+
+// targetMigrations returns the IDs of the migrations into target recorded
+// in db. The database next to a target is shared by the repositories of
+// the same parent directory.
+func targetMigrations(db *storage.StateDB, target string) ([]string, error) {
+	history, err := db.History()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	var ids []string
+	for _, state := range history {
+		if samePath(state.TargetPath, target) {
+			ids = append(ids, state.MigrationID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no migration into %s recorded in the state database", target)
+	}
+	return ids, nil
+}
+
+// samePath reports whether two paths name the same file, comparing their
+// absolute forms
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// formatProgress describes processed of total commits
+func formatProgress(state *storage.MigrationState) string {
+	if state.Total == 0 {
+		return fmt.Sprintf("%d", state.Processed)
+	}
+	return fmt.Sprintf("%d/%d (%.0f%%)", state.Processed, state.Total,
+		float64(state.Processed)/float64(state.Total)*100)
+}
+
+// openStateDB opens an existing state database, given directly or located
+// from the migrated target repository
+func openStateDB(stateFile, target string) (*storage.StateDB, error) {
+	if stateFile == "" {
+		if target == "" {
+			return nil, fmt.Errorf("either --state or --target is required")
+		}
+		stateFile = defaultStateFile(target)
+	}
+
+	if _, err := os.Stat(stateFile); err != nil {
+		return nil, fmt.Errorf("state database not found: %s", stateFile)
+	}
+
+	db, err := storage.NewStateDB(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	return db, nil
+}
+
+// closeStateDB closes db, warning on failure
+func closeStateDB(db *storage.StateDB) {
+	if err := db.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close state database: %v\n", err)
+	}
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	orig := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		out <- buf.String()
+	}()
+
+	fnErr := fn()
+	_ = w.Close()
+	os.Stdout = orig
+	return <-out, fnErr
+}
+
+// createStatusTestDB records two migrations for the target repository
+func createStatusTestDB(t *testing.T) string {
+	t.Helper()
+	target := filepath.Join(t.TempDir(), "repo")
+	db, err := storage.NewStateDB(defaultStateFile(target))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	now := time.Now()
+	require.NoError(t, db.Save(&storage.MigrationState{
+		MigrationID: "aaaa1111", SourcePath: "/cvs/old", TargetPath: target,
+		Processed: 10, Total: 10, LastCommit: "1.10", LastUpdated: now.Add(-time.Hour), Status: "completed",
+	}))
+	require.NoError(t, db.Save(&storage.MigrationState{
+		MigrationID: "bbbb2222", SourcePath: "/cvs/new", TargetPath: target,
+		Processed: 3, Total: 12, LastCommit: "1.3", LastUpdated: now, Status: "in_progress",
+	}))
+	require.NoError(t, db.BeginCommit(&storage.PendingCommit{
+		MigrationID: "bbbb2222", Revisions: []string{"main.c:1.4"}, LastCommit: "1.4", Processed: 4, Total: 12,
+	}))
+	require.NoError(t, db.SaveIssue(&storage.MigrationIssue{
+		MigrationID: "bbbb2222", Severity: "warning", Phase: "tags", Subject: "REL_1", Message: "tag skipped",
+	}))
+	return target
+}
+
+func setStateDBFlags(t *testing.T, stateFile, target string) {
+	t.Helper()
+	oldState, oldTarget := stateDBFile, stateDBTarget
+	t.Cleanup(func() { stateDBFile, stateDBTarget = oldState, oldTarget })
+	stateDBFile, stateDBTarget = stateFile, target
+}
+
+func TestRunHistory(t *testing.T) {
+	setStateDBFlags(t, "", createStatusTestDB(t))
+
+	out, err := captureStdout(t, func() error { return runHistory(nil, nil) })
+	require.NoError(t, err)
+	require.Contains(t, out, "ID")
+	require.Contains(t, out, "3/12 (25%)")
+	require.Less(t, strings.Index(out, "bbbb2222"), strings.Index(out, "aaaa1111"),
+		"most recent migration first")
+}
+
+func TestRunStatus(t *testing.T) {
+	setStateDBFlags(t, "", createStatusTestDB(t))
+
+	// Latest migration by default
+	out, err := captureStdout(t, func() error { return runStatus(nil, nil) })
+	require.NoError(t, err)
+	require.Contains(t, out, "bbbb2222")
+	require.Contains(t, out, "in_progress")
+	require.Contains(t, out, "main.c:1.4")
+	require.Contains(t, out, "[warning] tags REL_1: tag skipped")
+
+	// Unique prefix
+	out, err = captureStdout(t, func() error { return runStatus(nil, []string{"aaaa"}) })
+	require.NoError(t, err)
+	require.Contains(t, out, "/cvs/old")
+	require.NotContains(t, out, "Interrupted")
+
+	_, err = captureStdout(t, func() error { return runStatus(nil, []string{"cccc"}) })
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestFindMigration_Ambiguous(t *testing.T) {
+	db, err := storage.NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	_, err = findMigration(db, "")
+	require.ErrorContains(t, err, "no migrations")
+
+	require.NoError(t, db.Save(&storage.MigrationState{MigrationID: "ab01", LastUpdated: time.Now()}))
+	require.NoError(t, db.Save(&storage.MigrationState{MigrationID: "ab02", LastUpdated: time.Now()}))
+	_, err = findMigration(db, "ab")
+	require.ErrorContains(t, err, "ambiguous")
+
+	state, err := findMigration(db, "ab02")
+	require.NoError(t, err)
+	require.Equal(t, "ab02", state.MigrationID)
+}
+
+func TestRunStatus_RequiresStateOrTarget(t *testing.T) {
+	setStateDBFlags(t, "", "")
+	require.Error(t, runStatus(nil, nil))
+
+	setStateDBFlags(t, filepath.Join(t.TempDir(), "missing.db"), "")
+	require.ErrorContains(t, runHistory(nil, nil), "not found")
+}
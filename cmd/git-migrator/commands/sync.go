@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/validation"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -20,11 +25,29 @@ Three sync directions are supported:
   bidirectional Sync in both directions (default)
 
 Use --dry-run to preview planned changes without applying them.
+Progress is shown as a bar on the terminal. Use --verbose for the
+configuration and every step of the sync, or --quiet for CI to print only
+the summary.
+
+Use --watch to keep running as a daemon: the sync runs on start, then every
+--interval (with random jitter) and, with daemon.watch enabled, whenever RCS
+files in the CVS module change. Syncs never overlap. SIGINT or SIGTERM stops
+the daemon after the sync in progress finishes.
+
+Use --check to compare the files at the CVS trunk head with the Git HEAD
+without changing anything. The command fails if they differ, so it can guard
+a dual-running period in CI; --report writes the differences as JSON.
+
+A sync locks the Git repository, so it never runs at the same time as a
+migration or another sync of it. Use --force-unlock to take over a lock that
+a crashed run left behind on another host.
 
 Example usage:
   git-migrator sync --config sync-config.yaml
   git-migrator sync --config sync-config.yaml --direction git-to-cvs
-  git-migrator sync --config sync-config.yaml --dry-run`,
+  git-migrator sync --config sync-config.yaml --dry-run
+  git-migrator sync --config sync-config.yaml --watch --interval 10m --health-addr :8081
+  git-migrator sync --config sync-config.yaml --check --report drift.json`,
 	RunE: runSync,
 }
 
@@ -32,7 +55,17 @@ var (
 	syncConfigFile string
 	syncDryRun     bool
 	syncVerbose    bool
+	syncQuiet      bool
 	syncDirection  string
+
+	syncWatch      bool
+	syncInterval   time.Duration
+	syncHealthAddr string
+
+	syncCheck      bool
+	syncReportFile string
+
+	syncForceUnlock bool
 )
 
 // SyncConfigFile is the YAML schema for a sync configuration file.
@@ -45,21 +78,38 @@ type SyncConfigFile struct {
 		Path    string `yaml:"path"`
 		Module  string `yaml:"module"`
 		WorkDir string `yaml:"workDir"`
+		Writer  string `yaml:"writer"` // auto (default), client or native
+
+		Client CVSClientConfig `yaml:"client"` // How the cvs client is run
 	} `yaml:"cvs"`
 
 	Sync struct {
-		Direction string `yaml:"direction"`
-		StateFile string `yaml:"stateFile"`
+		Direction   string   `yaml:"direction"`
+		StateFile   string   `yaml:"stateFile"`
+		CheckIgnore []string `yaml:"checkIgnore"` // Files --check does not compare
 	} `yaml:"sync"`
 
 	Mapping struct {
-		Authors map[string]string `yaml:"authors"`
+		Authors     map[string]string `yaml:"authors"`
+		AuthorsFile string            `yaml:"authorsFile,omitempty"` // YAML author map; inline authors take priority
 	} `yaml:"mapping"`
 
 	Options struct {
-		DryRun  bool `yaml:"dryRun"`
-		Verbose bool `yaml:"verbose"`
+		DryRun  bool   `yaml:"dryRun"`
+		Verbose bool   `yaml:"verbose"`
+		LogDir  string `yaml:"logDir"`
+
+		Timeouts TimeoutsConfig `yaml:"timeouts"` // Fail reads, commits and cvs calls that hang
 	} `yaml:"options"`
+
+	Daemon struct {
+		Interval     time.Duration `yaml:"interval"`
+		Jitter       *float64      `yaml:"jitter"`
+		Watch        bool          `yaml:"watch"`
+		PollInterval time.Duration `yaml:"pollInterval"`
+		Debounce     time.Duration `yaml:"debounce"`
+		HealthAddr   string        `yaml:"healthAddr"`
+	} `yaml:"daemon"`
 }
 
 func init() {
@@ -68,7 +118,16 @@ func init() {
 	syncCmd.Flags().StringVarP(&syncConfigFile, "config", "c", "", "Path to sync configuration file (required)")
 	syncCmd.Flags().BoolVarP(&syncDryRun, "dry-run", "d", false, "Preview sync without making changes")
 	syncCmd.Flags().BoolVarP(&syncVerbose, "verbose", "v", false, "Show detailed output")
+	syncCmd.Flags().BoolVarP(&syncQuiet, "quiet", "q", false, "Only print the summary")
+	syncCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
 	syncCmd.Flags().StringVar(&syncDirection, "direction", "", "Sync direction: git-to-cvs, cvs-to-git, bidirectional")
+	syncCmd.Flags().BoolVar(&syncWatch, "watch", false, "Run continuously as a daemon")
+	syncCmd.Flags().DurationVar(&syncInterval, "interval", 0, "Time between daemon syncs (default 5m)")
+	syncCmd.Flags().StringVar(&syncHealthAddr, "health-addr", "", "Serve daemon health at http://<addr>/health")
+	syncCmd.Flags().BoolVar(&syncCheck, "check", false, "Only report drift between CVS and Git; fail if they differ")
+	syncCmd.Flags().StringVar(&syncReportFile, "report", "", "Write the --check drift report to this JSON file")
+	syncCmd.Flags().BoolVar(&syncForceUnlock, "force-unlock", false, "Take over the repository lock even if another run holds it")
+	syncCmd.MarkFlagsMutuallyExclusive("check", "watch")
 
 	if err := syncCmd.MarkFlagRequired("config"); err != nil {
 		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
@@ -89,39 +148,80 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if syncVerbose {
 		config.Options.Verbose = true
 	}
+	if syncQuiet {
+		config.Options.Verbose = false
+		if err := quietLogging(cmd); err != nil {
+			return err
+		}
+	}
 	if syncDirection != "" {
 		config.Sync.Direction = syncDirection
 	}
+	if syncInterval > 0 {
+		config.Daemon.Interval = syncInterval
+	}
+	if syncHealthAddr != "" {
+		config.Daemon.HealthAddr = syncHealthAddr
+	}
+	if err := validateSync(config); err != nil {
+		return fmt.Errorf("invalid sync: %w", err)
+	}
 
 	syncConfig := &core.SyncConfig{
 		GitPath:    config.Git.Path,
 		CVSPath:    config.CVS.Path,
 		CVSModule:  config.CVS.Module,
 		CVSWorkDir: config.CVS.WorkDir,
+		CVSWriter:  config.CVS.Writer,
 		Direction:  core.SyncDirection(config.Sync.Direction),
 		AuthorMap:  config.Mapping.Authors,
 		StateFile:  config.Sync.StateFile,
 		DryRun:     config.Options.DryRun,
+		LogDir:     config.Options.LogDir,
+		Timeouts:   config.Options.Timeouts.timeouts(),
+		CVSClient:  config.CVS.Client.client(),
+
+		CheckIgnore: config.Sync.CheckIgnore,
+		ForceUnlock: syncForceUnlock,
 	}
 
-	if config.Options.Verbose || config.Options.DryRun {
-		printSyncInfo(config, syncConfig)
+	if syncCheck {
+		return runSyncCheck(syncConfig, syncReportFile)
 	}
 
-	if config.Options.DryRun {
-		fmt.Println("\n🔍 DRY RUN MODE - No changes will be made")
+	if !syncQuiet {
+		if config.Options.Verbose || config.Options.DryRun {
+			printSyncInfo(config, syncConfig)
+		}
+		if config.Options.DryRun {
+			fmt.Println("\n🔍 DRY RUN MODE - No changes will be made")
+		}
 	}
 
-	syncer := core.NewSyncer(syncConfig)
+	if syncWatch {
+		return runSyncDaemon(syncConfig, daemonConfig(config))
+	}
 
-	fmt.Printf("\nStarting %s sync...\n", syncConfig.Direction)
-	if err := syncer.Run(); err != nil {
+	if syncQuiet {
+		err = core.NewSyncer(syncConfig).Run()
+	} else {
+		bar, restore := newProgressBar(config.Options.Verbose)
+		defer restore()
+		syncer := core.NewSyncer(syncConfig)
+		fmt.Printf("\nStarting %s sync...\n", syncConfig.Direction)
+		done := bar.Attach(syncer.ProgressReporter())
+		err = syncer.Run()
+		done()
+	}
+	if err != nil {
 		return fmt.Errorf("sync failed: %w", err)
 	}
 
 	if config.Options.DryRun {
 		fmt.Println("\n✓ Dry run completed successfully")
-		fmt.Println("Run without --dry-run to apply changes")
+		if !syncQuiet {
+			fmt.Println("Run without --dry-run to apply changes")
+		}
 	} else {
 		fmt.Println("\n✓ Sync completed successfully!")
 	}
@@ -129,6 +229,74 @@ func runSync(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runSyncCheck compares CVS and Git and fails if they have drifted
+func runSyncCheck(syncConfig *core.SyncConfig, reportFile string) error {
+	report, err := core.NewSyncer(syncConfig).Check()
+	if err != nil {
+		return fmt.Errorf("drift check failed: %w", err)
+	}
+	if reportFile != "" {
+		if err := report.WriteFile(reportFile); err != nil {
+			return fmt.Errorf("failed to write drift report: %w", err)
+		}
+	}
+
+	for _, f := range report.Different {
+		fmt.Printf("  M %s (CVS %s)\n", f.Path, f.CVSRevision)
+	}
+	for _, p := range report.OnlyInCVS {
+		fmt.Printf("  C %s (only in CVS)\n", p)
+	}
+	for _, p := range report.OnlyInGit {
+		fmt.Printf("  G %s (only in Git)\n", p)
+	}
+	if report.Drifted() {
+		return fmt.Errorf("%s", report.Summary())
+	}
+	fmt.Printf("✓ CVS and Git are %s\n", report.Summary())
+	return nil
+}
+
+// runSyncDaemon runs the sync until SIGINT or SIGTERM is received
+func runSyncDaemon(syncConfig *core.SyncConfig, config core.DaemonConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("\nStarting %s sync daemon (every %s", syncConfig.Direction, config.Interval)
+	if config.Watch {
+		fmt.Print(", on CVS changes")
+	}
+	fmt.Println("); press Ctrl+C to stop")
+	if config.HealthAddr != "" {
+		fmt.Printf("Health: http://%s/health\n", config.HealthAddr)
+	}
+
+	if err := core.NewSyncDaemon(syncConfig, config).Run(ctx); err != nil {
+		return fmt.Errorf("sync daemon failed: %w", err)
+	}
+	fmt.Println("\n✓ Sync daemon stopped")
+	return nil
+}
+
+// daemonConfig converts the daemon section of a sync configuration file
+func daemonConfig(config *SyncConfigFile) core.DaemonConfig {
+	daemon := core.DaemonConfig{
+		Interval:     config.Daemon.Interval,
+		Jitter:       core.DefaultSyncJitter,
+		Watch:        config.Daemon.Watch,
+		PollInterval: config.Daemon.PollInterval,
+		Debounce:     config.Daemon.Debounce,
+		HealthAddr:   config.Daemon.HealthAddr,
+	}
+	if config.Daemon.Jitter != nil {
+		daemon.Jitter = *config.Daemon.Jitter
+	}
+	if daemon.Interval <= 0 {
+		daemon.Interval = core.DefaultSyncInterval
+	}
+	return daemon
+}
+
 func loadSyncConfigFile(path string) (*SyncConfigFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -140,27 +308,59 @@ func loadSyncConfigFile(path string) (*SyncConfigFile, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	if config.Git.Path == "" {
-		return nil, fmt.Errorf("git.path is required")
+	v := &validation.Validator{}
+	v.Required("git.path", config.Git.Path)
+	v.Required("cvs.path", config.CVS.Path)
+	v.Required("cvs.module", config.CVS.Module)
+	if config.CVS.Writer == "auto" {
+		config.CVS.Writer = core.CVSWriterAuto
 	}
-	if config.CVS.Path == "" {
-		return nil, fmt.Errorf("cvs.path is required")
+	switch config.CVS.Writer {
+	case core.CVSWriterAuto, core.CVSWriterClient, core.CVSWriterNative:
+	default:
+		v.Addf("cvs.writer", "cvs.writer must be auto, client or native, not %q", config.CVS.Writer)
 	}
-	if config.CVS.Module == "" {
-		return nil, fmt.Errorf("cvs.module is required")
+	validateSyncDirection(v, config.Sync.Direction)
+	v.Check(config.Daemon.Interval >= 0 && config.Daemon.PollInterval >= 0 && config.Daemon.Debounce >= 0, "daemon.interval",
+		"daemon.interval, daemon.pollInterval and daemon.debounce must not be negative")
+	if j := config.Daemon.Jitter; j != nil {
+		v.Check(*j >= 0 && *j <= 1, "daemon.jitter", "daemon.jitter must be between 0 and 1")
+	}
+	config.Options.Timeouts.validate(v, "options.timeouts")
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
 
 	// Defaults
 	if config.Sync.Direction == "" {
 		config.Sync.Direction = string(core.SyncBidirectional)
 	}
-	if config.Mapping.Authors == nil {
-		config.Mapping.Authors = make(map[string]string)
+	authors, err := mergeAuthorsFile(config.Mapping.Authors, config.Mapping.AuthorsFile)
+	if err != nil {
+		return nil, fmt.Errorf("mapping.authorsFile: %w", err)
 	}
+	config.Mapping.Authors = authors
 
 	return &config, nil
 }
 
+// validateSyncDirection records a violation unless direction is a
+// core.SyncDirection or empty
+func validateSyncDirection(v *validation.Validator, direction string) {
+	v.OneOf("sync.direction", direction, "", string(core.SyncGitToCVS), string(core.SyncCVSToGit), string(core.SyncBidirectional))
+}
+
+// validateSync checks the repositories and the direction of a sync once the
+// command-line flags are applied, reporting every violation at once
+func validateSync(config *SyncConfigFile) error {
+	v := &validation.Validator{}
+	v.Repository("git.path", config.Git.Path, "git")
+	v.Repository("cvs.path", config.CVS.Path, "cvs")
+	config.CVS.Client.validate(v, "cvs.client")
+	validateSyncDirection(v, config.Sync.Direction)
+	return v.Err()
+}
+
 func printSyncInfo(config *SyncConfigFile, syncConfig *core.SyncConfig) {
 	fmt.Println("\nSync Configuration")
 	fmt.Println("==================")
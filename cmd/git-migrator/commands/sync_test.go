@@ -2,8 +2,11 @@ package commands
 
 import (
 	"bytes"
+	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 
 	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/logging"
 	"github.com/stretchr/testify/require"
 )
 
@@ -244,11 +248,15 @@ func TestRunSync_InvalidConfig(t *testing.T) {
 }
 
 // TestRunSync_SyncerRunFails covers the path where syncer.Run() returns an
-// error (valid YAML config but unreachable repositories).
+// error (valid repositories but an unreadable CVSROOT/cvswrappers).
 func TestRunSync_SyncerRunFails(t *testing.T) {
+	gitDir := createSyncTestGitRepo(t)
+	cvsDir := createSyncTestCVSRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(cvsDir, "CVSROOT", "cvswrappers"), 0755))
+
 	tmp := t.TempDir()
 	cfgPath := filepath.Join(tmp, "sync.yaml")
-	content := "git:\n  path: /nonexistent/git\ncvs:\n  path: /nonexistent/cvs\n  module: mod\nsync:\n  direction: cvs-to-git\n"
+	content := "git:\n  path: " + gitDir + "\ncvs:\n  path: " + cvsDir + "\n  module: mod\nsync:\n  direction: cvs-to-git\n"
 	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
 
 	origCfg := syncConfigFile
@@ -272,6 +280,23 @@ func TestRunSync_SyncerRunFails(t *testing.T) {
 	require.Contains(t, err.Error(), "sync failed")
 }
 
+// TestRunSync_InvalidPaths ensures runSync reports every repository that
+// does not exist before syncing.
+func TestRunSync_InvalidPaths(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sync.yaml")
+	content := "git:\n  path: /nonexistent/git\ncvs:\n  path: /nonexistent/cvs\n  module: mod\nsync:\n  direction: cvs-to-git\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	origCfg, origDir := syncConfigFile, syncDirection
+	defer func() { syncConfigFile, syncDirection = origCfg, origDir }()
+	syncConfigFile, syncDirection = cfgPath, "sideways"
+
+	err := runSync(nil, nil)
+	require.ErrorContains(t, err, "git.path does not exist")
+	require.ErrorContains(t, err, "cvs.path does not exist")
+	require.ErrorContains(t, err, `sync.direction must be git-to-cvs, cvs-to-git or bidirectional, not "sideways"`)
+}
+
 // TestLoadSyncConfigFile_InvalidYAML tests that malformed YAML returns an error.
 func TestLoadSyncConfigFile_InvalidYAML(t *testing.T) {
 	tmp := t.TempDir()
@@ -280,3 +305,90 @@ func TestLoadSyncConfigFile_InvalidYAML(t *testing.T) {
 	_, err := loadSyncConfigFile(cfgPath)
 	require.Error(t, err)
 }
+
+func TestLoadSyncConfigFile_Daemon(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sync.yaml")
+	content := "git:\n  path: /g\ncvs:\n  path: /c\n  module: mod\ndaemon:\n  interval: 10m\n  jitter: 0.25\n  watch: true\n  pollInterval: 30s\n  debounce: 2s\n  healthAddr: \":8081\"\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadSyncConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, core.DaemonConfig{
+		Interval:     10 * time.Minute,
+		Jitter:       0.25,
+		Watch:        true,
+		PollInterval: 30 * time.Second,
+		Debounce:     2 * time.Second,
+		HealthAddr:   ":8081",
+	}, daemonConfig(cfg))
+
+	// Defaults
+	cfg.Daemon = SyncConfigFile{}.Daemon
+	require.Equal(t, core.DaemonConfig{Interval: core.DefaultSyncInterval, Jitter: core.DefaultSyncJitter}, daemonConfig(cfg))
+
+	require.NoError(t, os.WriteFile(cfgPath, []byte(strings.Replace(content, "jitter: 0.25", "jitter: 2", 1)), 0644))
+	_, err = loadSyncConfigFile(cfgPath)
+	require.Error(t, err)
+}
+
+func TestLoadSyncConfigFile_CVSWriter(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sync.yaml")
+	for writer, want := range map[string]string{
+		"":       core.CVSWriterAuto,
+		"auto":   core.CVSWriterAuto,
+		"client": core.CVSWriterClient,
+		"native": core.CVSWriterNative,
+	} {
+		content := "git:\n  path: /g\ncvs:\n  path: /c\n  module: mod\n  writer: \"" + writer + "\"\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+		cfg, err := loadSyncConfigFile(cfgPath)
+		require.NoError(t, err, writer)
+		require.Equal(t, want, cfg.CVS.Writer, writer)
+	}
+
+	require.NoError(t, os.WriteFile(cfgPath, []byte("git:\n  path: /g\ncvs:\n  path: /c\n  module: mod\n  writer: rsh\n"), 0644))
+	_, err := loadSyncConfigFile(cfgPath)
+	require.Error(t, err)
+}
+
+func TestLoadSyncConfigFile_CheckIgnore(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sync.yaml")
+	content := "git:\n  path: /g\ncvs:\n  path: /c\n  module: mod\nsync:\n  checkIgnore:\n    - .gitignore\n    - \"*.bak\"\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadSyncConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{".gitignore", "*.bak"}, cfg.Sync.CheckIgnore)
+}
+
+func TestRunSyncCheck_InvalidRepositories(t *testing.T) {
+	err := runSyncCheck(&core.SyncConfig{GitPath: t.TempDir(), CVSPath: t.TempDir(), CVSModule: "mod"}, "")
+	require.ErrorContains(t, err, "drift check failed")
+}
+
+// TestRunSync_Quiet runs a sync with --quiet, which only prints the summary.
+func TestRunSync_Quiet(t *testing.T) {
+	gitDir := createSyncTestGitRepo(t)
+	cvsDir := createSyncTestCVSRepo(t)
+
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "sync.yaml")
+	content := "git:\n  path: " + gitDir + "\ncvs:\n  path: " + cvsDir + "\n  module: mod\nsync:\n  direction: git-to-cvs\noptions:\n  dryRun: true\n  verbose: true\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	origCfg := syncConfigFile
+	origQuiet := syncQuiet
+	origLogger := logging.Default()
+	defer func() {
+		syncConfigFile = origCfg
+		syncQuiet = origQuiet
+		logging.SetDefault(origLogger)
+	}()
+
+	syncConfigFile = cfgPath
+	syncQuiet = true
+
+	err := runSync(nil, nil)
+	require.NoError(t, err)
+	require.False(t, logging.Default().Enabled(context.Background(), slog.LevelInfo))
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTrace(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt,v"), []byte(resumeTestRCS), 0644))
+	target := filepath.Join(t.TempDir(), "repo")
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	content := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + target + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	oldCfg := migrateConfigFile
+	defer func() { migrateConfigFile = oldCfg }()
+	migrateConfigFile = cfgPath
+	require.NoError(t, runMigrate(migrateCmd, nil))
+
+	oldState, oldTarget := traceStateFile, traceTarget
+	defer func() { traceStateFile, traceTarget = oldState, oldTarget }()
+	traceStateFile, traceTarget = "", target
+
+	require.NoError(t, runTrace(traceCmd, []string{"./a.txt"}))
+
+	// A sibling repository shares the state database
+	db, err := storage.NewStateDB(defaultStateFile(target))
+	require.NoError(t, err)
+	require.NoError(t, db.SaveMapping("sibling", "a.txt:1.9", "0123456789abcdef0123456789abcdef01234567"))
+	require.NoError(t, db.Close())
+	out, err := captureStdout(t, func() error { return runTrace(traceCmd, []string{"a.txt"}) })
+	require.NoError(t, err)
+	require.Contains(t, out, "1.1\t")
+	require.NotContains(t, out, "1.9")
+
+	traceTarget = filepath.Join(filepath.Dir(target), "other")
+	err = runTrace(traceCmd, []string{"a.txt"})
+	require.ErrorContains(t, err, "no migration into")
+	traceTarget = target
+
+	err = runTrace(traceCmd, []string{"b.txt"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no revisions of b.txt")
+
+	traceStateFile, traceTarget = "", ""
+	require.Error(t, runTrace(traceCmd, []string{"a.txt"}))
+}
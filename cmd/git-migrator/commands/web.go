@@ -1,15 +1,28 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/adamf123git/git-migrator/internal/logging"
 	"github.com/adamf123git/git-migrator/internal/web"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var webCmd = &cobra.Command{
-	Use:   "web",
-	Short: "Start the web UI server",
+	Use:     "web",
+	Aliases: []string{"serve"},
+	Short:   "Start the web UI server",
 	Long: `Start the Git-Migrator web interface for managing migrations
 through a browser-based UI.
 
@@ -21,39 +34,284 @@ The web interface provides:
 - Migration history and logs
 
 By default, the server starts on port 8080, but this can be
-customized with the --port flag.`,
+customized with the --port flag.
+
+With --config, the notifications section of a configuration file is
+applied to migrations started from the browser, e.g. to email the
+report when a migration completes or fails. Its web.tokens section
+requires an API token: viewer tokens can only read migrations and
+reports, operator tokens can also start, stop, pause and resume them.
+
+With --pprof, the Go runtime profiles of the server and the migrations it
+runs are served under /debug/pprof/, e.g. for
+  go tool pprof http://localhost:8080/debug/pprof/profile?seconds=60
+
+With --max-concurrent, migrations started beyond the limit are queued and
+started by priority (the "priority" option of the request: low, normal or
+high) as running ones finish.
+
+The settings edited on the configuration page (chunk size, verbosity,
+default author map and work directories) are kept in --settings, by
+default web.yaml in the git-migrator directory of the user configuration
+directory, and applied to the migrations started afterwards.
+
+The migrations of the server are kept in --migrations, by default
+web-migrations.json next to the settings file. Migrations that were
+running when the server stopped are shown as interrupted after a restart
+and can be resumed from their last checkpoint. Presets, named sets of
+migration request fields managed under /api/presets, are kept in
+--presets, by default web-presets.json next to the settings file.
+
+SIGHUP reloads --config and --settings: API tokens, report emails and
+migration defaults change without affecting running migrations. SIGTERM
+or SIGINT stops accepting requests and lets running migrations finish for
+up to --drain-timeout; those still running are then stopped after their
+current commit and resume after a restart.
+
+With --daemon, the server runs as a system service: the process ID is
+written to --pid-file (default: web.pid next to the settings file), which
+also keeps a second server from starting, and startup is logged instead
+of printed.`,
 	RunE: runWeb,
 }
 
 var (
-	webPort int
+	webPort       int
+	webConfigFile string
+	webPprof      bool
+	webMaxRunning int
+	webSettings   string
+	webMigrations string
+	webPresets    string
+	webDaemon     bool
+	webPIDFile    string
+	webDrain      time.Duration
 )
 
 func init() {
 	rootCmd.AddCommand(webCmd)
 
 	webCmd.Flags().IntVarP(&webPort, "port", "p", 8080, "Port to run the web server on")
+	webCmd.Flags().StringVarP(&webConfigFile, "config", "c", "", "Configuration file with the notifications and API tokens of the server")
+	webCmd.Flags().BoolVar(&webPprof, "pprof", false, "Serve Go runtime profiles under /debug/pprof/")
+	webCmd.Flags().IntVar(&webMaxRunning, "max-concurrent", 0, "Migrations run at a time, the rest queued by priority (0 = unlimited)")
+	webCmd.Flags().StringVar(&webSettings, "settings", "", "File keeping the settings edited in the browser (default: <user config dir>/git-migrator/web.yaml)")
+	webCmd.Flags().StringVar(&webMigrations, "migrations", "", "File keeping the migrations across restarts (default: web-migrations.json next to the settings file)")
+	webCmd.Flags().StringVar(&webPresets, "presets", "", "File keeping the migration presets (default: web-presets.json next to the settings file)")
+	webCmd.Flags().BoolVar(&webDaemon, "daemon", false, "Run as a system service with a PID file")
+	webCmd.Flags().StringVar(&webPIDFile, "pid-file", "", "File holding the process ID (default with --daemon: web.pid next to the settings file)")
+	webCmd.Flags().DurationVar(&webDrain, "drain-timeout", web.DefaultDrainTimeout, "Time running migrations get to finish on SIGTERM before they are interrupted")
 }
 
 func runWeb(cmd *cobra.Command, args []string) error {
-	// Create server configuration
-	config := web.ServerConfig{
-		Port:         webPort,
-		ConfigPath:   "", // Use default
-		DatabasePath: "", // Use default
+	config, err := webServerConfig()
+	if err != nil {
+		return err
+	}
+
+	pidFile := webPIDFile
+	if pidFile == "" && webDaemon {
+		pidFile = filepath.Join(filepath.Dir(config.ConfigPath), "web.pid")
+	}
+	if pidFile != "" {
+		release, err := writePIDFile(pidFile)
+		if err != nil {
+			return err
+		}
+		defer release()
 	}
 
 	// Create server
 	server := web.NewServer(config)
 
-	// Display startup message
-	fmt.Printf("Starting Git-Migrator web interface...\n")
-	fmt.Printf("Open http://localhost:%d in your browser\n\n", webPort)
+	if webDaemon {
+		logging.Default().Info("starting web server", "port", webPort, "pid", os.Getpid(), "pid_file", pidFile,
+			"settings", config.ConfigPath, "migrations", config.DatabasePath)
+	} else {
+		fmt.Printf("Starting Git-Migrator web interface...\n")
+		fmt.Printf("Settings: %s\n", config.ConfigPath)
+		fmt.Printf("Migrations: %s\n", config.DatabasePath)
+		fmt.Printf("Open http://localhost:%d in your browser\n\n", webPort)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go reloadOnHangup(ctx, server)
 
-	// Start server (this blocks until server stops)
-	if err := server.Start(); err != nil {
+	// Serve until a stop signal, then drain the running migrations
+	if err := server.Serve(ctx, webDrain); err != nil {
 		return fmt.Errorf("failed to start web server: %w", err)
 	}
+	logging.Default().Info("web server stopped")
+	return nil
+}
+
+// webServerConfig builds the server configuration from the flags and
+// --config; it is built again on SIGHUP
+func webServerConfig() (web.ServerConfig, error) {
+	if webMaxRunning < 0 {
+		return web.ServerConfig{}, fmt.Errorf("--max-concurrent must not be negative")
+	}
+
+	settingsPath := webSettings
+	if settingsPath == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return web.ServerConfig{}, fmt.Errorf("failed to locate the settings file, set --settings: %w", err)
+		}
+		settingsPath = filepath.Join(dir, "git-migrator", "web.yaml")
+	}
+	migrationsPath := webMigrations
+	if migrationsPath == "" {
+		migrationsPath = filepath.Join(filepath.Dir(settingsPath), "web-migrations.json")
+	}
+	presetsPath := webPresets
+	if presetsPath == "" {
+		presetsPath = filepath.Join(filepath.Dir(settingsPath), "web-presets.json")
+	}
 
+	config := web.ServerConfig{
+		Port:          webPort,
+		ConfigPath:    settingsPath,
+		DatabasePath:  migrationsPath,
+		PresetsPath:   presetsPath,
+		Pprof:         webPprof,
+		MaxConcurrent: webMaxRunning,
+	}
+	if webConfigFile != "" {
+		if err := loadWebConfig(webConfigFile, &config); err != nil {
+			return web.ServerConfig{}, err
+		}
+	}
+	return config, nil
+}
+
+// reloadOnHangup reloads the configuration of the server on every SIGHUP
+// until ctx is done. A configuration that fails to load is not applied.
+func reloadOnHangup(ctx context.Context, server *web.Server) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+		config, err := webServerConfig()
+		if err == nil {
+			err = server.Reload(config)
+		}
+		if err != nil {
+			logging.Default().Error("failed to reload the configuration", "error", err)
+			continue
+		}
+		logging.Default().Info("configuration reloaded")
+	}
+}
+
+// pidFileAttempts is how often writePIDFile tries to create the PID file
+// after removing a stale one another server may replace concurrently
+const pidFileAttempts = 3
+
+// writePIDFile writes the process ID to path and returns the function
+// removing it again. The file is created exclusively, so of two servers
+// starting at once only one gets it; it fails if the file names another
+// running process.
+func writePIDFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to write PID file: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		err := createPIDFile(path)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) || attempt == pidFileAttempts {
+			return nil, fmt.Errorf("failed to write PID file: %w", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Removed by its server meanwhile
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PID file: %w", err)
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() && processRunning(pid) {
+			return nil, fmt.Errorf("server already running with PID %d (%s)", pid, path)
+		}
+		// The file of a server that is gone
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale PID file: %w", err)
+		}
+	}
+	return func() {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logging.Default().Warn("failed to remove PID file", "path", path, "error", err)
+		}
+	}, nil
+}
+
+// createPIDFile writes the process ID to path, failing if it exists
+func createPIDFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// processRunning reports whether a process with the PID exists
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 checks for the process without signalling it; EPERM means
+	// it belongs to another user
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// loadWebConfig applies the notifications.email and web sections of a
+// configuration file to the server configuration. Unlike loadConfigFile, no
+// source or target is required.
+func loadWebConfig(path string, server *web.ServerConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var config ConfigFile
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := config.Notifications.Email.Validate(); err != nil {
+		return fmt.Errorf("notifications.email: %w", err)
+	}
+	server.Email = config.Notifications.Email
+
+	for i, t := range config.Web.Tokens {
+		role, err := web.ParseRole(t.Role)
+		if err != nil {
+			return fmt.Errorf("web.tokens[%d]: %w", i, err)
+		}
+		token := os.Getenv(t.TokenEnv)
+		if t.TokenEnv == "" || token == "" {
+			return fmt.Errorf("web.tokens[%d]: environment variable %q holding the token is not set", i, t.TokenEnv)
+		}
+		if server.Tokens == nil {
+			server.Tokens = make(map[string]web.Role)
+		}
+		server.Tokens[token] = role
+	}
 	return nil
 }
@@ -1,8 +1,12 @@
 package commands
 
 import (
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 
+	"github.com/adamf123git/git-migrator/internal/web"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,3 +30,78 @@ func TestRunWeb_ErrorOnInvalidPort(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to start web server")
 }
+
+func TestLoadWebConfig(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "web.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("notifications:\n  email:\n    host: smtp.example.com\n    from: a@example.com\n    to: [b@example.com]\n"), 0644))
+	var config web.ServerConfig
+	require.NoError(t, loadWebConfig(cfgPath, &config))
+	require.Equal(t, "smtp.example.com", config.Email.Host)
+	require.Empty(t, config.Tokens)
+
+	require.NoError(t, os.WriteFile(cfgPath, []byte("notifications:\n  email:\n    host: smtp.example.com\n"), 0644))
+	require.ErrorContains(t, loadWebConfig(cfgPath, &config), "notifications.email")
+
+	old := webConfigFile
+	webConfigFile = filepath.Join(t.TempDir(), "missing.yaml")
+	defer func() { webConfigFile = old }()
+	require.ErrorContains(t, runWeb(nil, nil), "failed to read config file")
+}
+
+func TestLoadWebConfig_Tokens(t *testing.T) {
+	t.Setenv("VIEW_TOKEN", "v-secret")
+	t.Setenv("OPS_TOKEN", "o-secret")
+	cfgPath := filepath.Join(t.TempDir(), "web.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("web:\n  tokens:\n    - tokenEnv: VIEW_TOKEN\n      role: viewer\n    - tokenEnv: OPS_TOKEN\n      role: operator\n"), 0644))
+	var config web.ServerConfig
+	require.NoError(t, loadWebConfig(cfgPath, &config))
+	require.Equal(t, map[string]web.Role{"v-secret": web.RoleViewer, "o-secret": web.RoleOperator}, config.Tokens)
+
+	require.NoError(t, os.WriteFile(cfgPath, []byte("web:\n  tokens:\n    - tokenEnv: VIEW_TOKEN\n      role: admin\n"), 0644))
+	require.ErrorContains(t, loadWebConfig(cfgPath, &web.ServerConfig{}), `web.tokens[0]: unknown role "admin"`)
+
+	require.NoError(t, os.WriteFile(cfgPath, []byte("web:\n  tokens:\n    - tokenEnv: UNSET_TOKEN\n      role: viewer\n"), 0644))
+	require.ErrorContains(t, loadWebConfig(cfgPath, &web.ServerConfig{}), `environment variable "UNSET_TOKEN" holding the token is not set`)
+}
+
+func TestWritePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "web.pid")
+	release, err := writePIDFile(path)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+	release()
+	require.NoFileExists(t, path)
+
+	// A stale or unreadable PID is replaced
+	require.NoError(t, os.WriteFile(path, []byte("not a pid\n"), 0o644))
+	release, err = writePIDFile(path)
+	require.NoError(t, err)
+	release()
+
+	// A running process keeps its PID file
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0o644))
+	_, err = writePIDFile(path)
+	require.ErrorContains(t, err, "server already running")
+	require.FileExists(t, path)
+}
+
+func TestWebServerConfig(t *testing.T) {
+	oldSettings, oldMigrations := webSettings, webMigrations
+	defer func() { webSettings, webMigrations = oldSettings, oldMigrations }()
+	webSettings = filepath.Join(t.TempDir(), "web.yaml")
+	webMigrations = ""
+
+	config, err := webServerConfig()
+	require.NoError(t, err)
+	require.Equal(t, webSettings, config.ConfigPath)
+	require.Equal(t, filepath.Join(filepath.Dir(webSettings), "web-migrations.json"), config.DatabasePath)
+	require.Equal(t, filepath.Join(filepath.Dir(webSettings), "web-presets.json"), config.PresetsPath)
+
+	old := webMaxRunning
+	webMaxRunning = -1
+	defer func() { webMaxRunning = old }()
+	_, err = webServerConfig()
+	require.ErrorContains(t, err, "--max-concurrent")
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/storage"
+)
+
+// BatchConfig holds the configuration of a multi-module batch migration
+type BatchConfig struct {
+	BatchID         string                               // Identifies the batch in the state database
+	Modules         []string                             // Modules to migrate, in order
+	StateFile       string                               // Path to the state database holding batch progress
+	Resume          bool                                 // Skip modules completed by a previous run
+	ContinueOnError bool                                 // Keep going after a module fails
+	Logger          *slog.Logger                         // Structured logger (nil = logging.Default())
+	ModuleConfig    func(module string) *MigrationConfig // Builds the migration config of a module
+	Umbrella        *UmbrellaConfig                      // Umbrella repository wiring the modules together (nil = none)
+}
+
+// BatchResult summarizes a batch migration run
+type BatchResult struct {
+	Completed []string          // Modules migrated by this run
+	Skipped   []string          // Modules already completed by a previous run
+	Failed    map[string]string // Module -> error message
+	Umbrella  *UmbrellaReport   // Umbrella repository, once every module is migrated
+}
+
+// RunBatch migrates each module of the batch in order, recording per-module
+// status so that an interrupted or failed batch can be resumed. Modules that
+// failed or were in progress are re-run with Resume set, so their own
+// checkpoints are reused.
+func RunBatch(config *BatchConfig) (*BatchResult, error) {
+	if config.BatchID == "" {
+		return nil, fmt.Errorf("batch ID is required")
+	}
+	if config.StateFile == "" {
+		return nil, fmt.Errorf("state file is required")
+	}
+	if config.ModuleConfig == nil {
+		return nil, fmt.Errorf("module config builder is required")
+	}
+
+	logger := logging.OrDefault(config.Logger).With("batch_id", config.BatchID)
+
+	db, err := storage.NewStateDB(config.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Warn("failed to close state db", "error", err)
+		}
+	}()
+
+	if !config.Resume {
+		if err := db.DeleteBatch(config.BatchID); err != nil {
+			return nil, fmt.Errorf("failed to reset batch state: %w", err)
+		}
+	}
+
+	previous, err := db.LoadBatch(config.BatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load batch state: %w", err)
+	}
+
+	result := &BatchResult{Failed: make(map[string]string)}
+	for _, module := range config.Modules {
+		prev, seen := previous[module]
+		if seen && prev.Status == storage.BatchCompleted {
+			logger.Info("skipping completed module", "module", module)
+			result.Skipped = append(result.Skipped, module)
+			continue
+		}
+
+		migrationConfig := config.ModuleConfig(module)
+		if migrationConfig.Logger == nil {
+			migrationConfig.Logger = config.Logger
+		}
+		// A module that was started before continues from its checkpoint
+		if seen {
+			migrationConfig.Resume = true
+		}
+
+		migrator := NewMigrator(migrationConfig)
+		state := &storage.BatchModuleState{
+			BatchID:     config.BatchID,
+			Module:      module,
+			MigrationID: migrator.MigrationID(),
+			Status:      storage.BatchInProgress,
+		}
+		if err := db.SaveBatchModule(state); err != nil {
+			return result, fmt.Errorf("failed to save batch state: %w", err)
+		}
+
+		logger.Info("migrating module", "module", module)
+		runErr := migrator.Run()
+
+		state.Status = storage.BatchCompleted
+		if runErr != nil {
+			state.Status = storage.BatchFailed
+			state.Error = runErr.Error()
+		}
+		if err := db.SaveBatchModule(state); err != nil {
+			return result, fmt.Errorf("failed to save batch state: %w", err)
+		}
+
+		if runErr != nil {
+			logger.Error("module migration failed", "module", module, "error", runErr)
+			result.Failed[module] = runErr.Error()
+			if !config.ContinueOnError {
+				return result, fmt.Errorf("module %s failed: %w", module, runErr)
+			}
+			continue
+		}
+		result.Completed = append(result.Completed, module)
+	}
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d of %d modules failed", len(result.Failed), len(config.Modules))
+	}
+
+	if config.Umbrella != nil {
+		logger.Info("writing umbrella repository", "path", config.Umbrella.Path)
+		if result.Umbrella, err = writeUmbrella(config); err != nil {
+			return result, fmt.Errorf("failed to write umbrella repository: %w", err)
+		}
+	}
+	return result, nil
+}
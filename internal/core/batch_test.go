@@ -0,0 +1,140 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+const batchTestRCS = "head 1.1;\naccess;\nsymbols;\nlocks;\n\n1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\ndesc\n@@\n\n1.1\nlog\n@initial\n@\ntext\n@hello\n@\n"
+
+// addBatchModule creates a module with one RCS file in the CVS repository
+func addBatchModule(t *testing.T, repo, module string) {
+	t.Helper()
+	dir := filepath.Join(repo, module)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README,v"), []byte(batchTestRCS), 0644))
+}
+
+func newTestBatchConfig(repo, target string, modules ...string) *BatchConfig {
+	return &BatchConfig{
+		BatchID:   "batch1",
+		Modules:   modules,
+		StateFile: filepath.Join(target, "state.db"),
+		Logger:    logging.Discard(),
+		ModuleConfig: func(module string) *MigrationConfig {
+			return &MigrationConfig{
+				SourceType:   "cvs",
+				SourcePath:   repo,
+				SourceModule: module,
+				TargetPath:   filepath.Join(target, module),
+				StateFile:    filepath.Join(target, "state.db"),
+				Logger:       logging.Discard(),
+			}
+		},
+	}
+}
+
+func TestRunBatch_ResumeSkipsCompletedModules(t *testing.T) {
+	repo := createTestCVSRepo(t)
+	target := t.TempDir()
+	addBatchModule(t, repo, "alpha")
+	addBatchModule(t, repo, "gamma")
+
+	// beta does not exist yet, so the first run stops there
+	config := newTestBatchConfig(repo, target, "alpha", "beta", "gamma")
+	result, err := RunBatch(config)
+	require.Error(t, err)
+	require.Equal(t, []string{"alpha"}, result.Completed)
+	require.Contains(t, result.Failed, "beta")
+
+	db, err := storage.NewStateDB(config.StateFile)
+	require.NoError(t, err)
+	states, err := db.LoadBatch("batch1")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	require.Equal(t, storage.BatchCompleted, states["alpha"].Status)
+	require.Equal(t, storage.BatchFailed, states["beta"].Status)
+	require.NotEmpty(t, states["beta"].Error)
+	require.NotContains(t, states, "gamma")
+
+	addBatchModule(t, repo, "beta")
+	config.Resume = true
+	result, err = RunBatch(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alpha"}, result.Skipped)
+	require.Equal(t, []string{"beta", "gamma"}, result.Completed)
+	require.Empty(t, result.Failed)
+
+	for _, module := range []string{"alpha", "beta", "gamma"} {
+		require.DirExists(t, filepath.Join(target, module, ".git"))
+	}
+}
+
+func TestRunBatch_ContinueOnError(t *testing.T) {
+	repo := createTestCVSRepo(t)
+	target := t.TempDir()
+	addBatchModule(t, repo, "alpha")
+	addBatchModule(t, repo, "gamma")
+
+	config := newTestBatchConfig(repo, target, "alpha", "beta", "gamma")
+	config.ContinueOnError = true
+	result, err := RunBatch(config)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 of 3")
+	require.Equal(t, []string{"alpha", "gamma"}, result.Completed)
+	require.Len(t, result.Failed, 1)
+}
+
+func TestRunBatch_WithoutResumeStartsOver(t *testing.T) {
+	repo := createTestCVSRepo(t)
+	target := t.TempDir()
+	addBatchModule(t, repo, "alpha")
+
+	config := newTestBatchConfig(repo, target, "alpha")
+	_, err := RunBatch(config)
+	require.NoError(t, err)
+
+	result, err := RunBatch(config)
+	require.NoError(t, err)
+	require.Empty(t, result.Skipped)
+	require.Equal(t, []string{"alpha"}, result.Completed)
+}
+
+func TestRunBatch_RequiresConfig(t *testing.T) {
+	_, err := RunBatch(&BatchConfig{StateFile: "s.db", ModuleConfig: func(string) *MigrationConfig { return nil }})
+	require.Error(t, err)
+	_, err = RunBatch(&BatchConfig{BatchID: "b", ModuleConfig: func(string) *MigrationConfig { return nil }})
+	require.Error(t, err)
+	_, err = RunBatch(&BatchConfig{BatchID: "b", StateFile: "s.db"})
+	require.Error(t, err)
+}
+
+func TestRunBatch_Umbrella(t *testing.T) {
+	repo := createTestCVSRepo(t)
+	target := t.TempDir()
+	addBatchModule(t, repo, "alpha")
+	addBatchModule(t, repo, "beta")
+
+	config := newTestBatchConfig(repo, target, "alpha", "beta")
+	config.Umbrella = &UmbrellaConfig{Path: filepath.Join(target, "umbrella")}
+	result, err := RunBatch(config)
+	require.NoError(t, err)
+	require.NotNil(t, result.Umbrella)
+	require.NotEmpty(t, result.Umbrella.Commit)
+	require.Len(t, result.Umbrella.Modules, 2)
+	require.Equal(t, UmbrellaEntry{
+		Module:     "alpha",
+		Path:       "alpha",
+		Repository: filepath.Join(target, "alpha"),
+		URL:        filepath.Join(target, "alpha"),
+		Commit:     result.Umbrella.Modules[0].Commit,
+	}, result.Umbrella.Modules[0])
+	require.NotEmpty(t, result.Umbrella.Modules[0].Commit)
+	require.FileExists(t, filepath.Join(target, "umbrella", ".gitmodules"))
+	require.FileExists(t, ReportPath(filepath.Join(target, "umbrella"))+ReportExtJSON)
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Case conflict policies, applied to paths that differ from an earlier path
+// only by case
+const (
+	// CaseConflictReport keeps the paths and lists them in the report
+	CaseConflictReport = "report"
+	// CaseConflictSuffix renames the later spelling by appending a number
+	// to the conflicting name, e.g. "Readme_2.txt"
+	CaseConflictSuffix = "suffix"
+	// CaseConflictUnify writes the later spelling with the case seen first
+	CaseConflictUnify = "unify"
+	// CaseConflictFail fails the migration on the first conflict
+	CaseConflictFail = "fail"
+)
+
+// ReportCaseClash is a path of the source history that differs from an
+// earlier path only by case
+type ReportCaseClash struct {
+	Path     string `json:"path"`               // Spelling seen first
+	Conflict string `json:"conflict"`           // Later spelling
+	Commit   string `json:"commit"`             // Source revision of the first commit using the later spelling
+	Resolved string `json:"resolved,omitempty"` // Path written instead, if a policy rewrote it
+}
+
+// validateCaseConflicts checks the configured case conflict policy
+func (m *Migrator) validateCaseConflicts() error {
+	switch m.config.CaseConflicts {
+	case "", CaseConflictReport, CaseConflictSuffix, CaseConflictUnify, CaseConflictFail:
+		return nil
+	default:
+		return fmt.Errorf("unsupported case conflict policy: %s", m.config.CaseConflicts)
+	}
+}
+
+// resolveCaseConflicts finds the file and directory paths of the history
+// that differ only by case from a path that exists at the same time on the
+// same branch, which case-insensitive file systems cannot check out side by
+// side, and applies the case conflict policy to them. A path deleted before
+// another spelling is added, such as a case-only rename, is no conflict.
+// The commits must be ordered.
+func (m *Migrator) resolveCaseConflicts(commits []*vcs.Commit) error {
+	folder := &caseFolder{
+		policy:   m.config.CaseConflicts,
+		branches: map[string]*caseBranch{},
+		report:   m.currentReport(),
+	}
+	for _, commit := range commits {
+		b := folder.branch(commit.Branch)
+		for i := range commit.Files {
+			fc := &commit.Files[i]
+			var err error
+			switch fc.Action {
+			case vcs.ActionDelete:
+				fc.Path = b.remove(fc.Path)
+				continue
+			case vcs.ActionRename:
+				fc.OldPath = b.remove(fc.OldPath)
+			default:
+				if fc.OldPath != "" {
+					fc.OldPath = b.written(fc.OldPath)
+				}
+			}
+			if fc.Path, err = folder.add(b, fc.Path, commit.Revision); err != nil {
+				return err
+			}
+		}
+	}
+	if n := len(folder.report.CaseClashes); n > 0 && folder.policy != CaseConflictSuffix && folder.policy != CaseConflictUnify {
+		m.warn("paths differ only by case and cannot be checked out on case-insensitive file systems", "conflicts", n)
+	}
+	return nil
+}
+
+// caseFolder tracks the spellings of the paths of every branch
+type caseFolder struct {
+	policy   string
+	branches map[string]*caseBranch
+	report   *MigrationReport
+}
+
+// caseBranch holds the paths of a branch that exist after the commits
+// resolved so far
+type caseBranch struct {
+	live     map[string]*casePath // Case folded path written -> the path
+	resolved map[string]string    // Conflicting source spelling -> path written
+}
+
+// casePath is a file or directory written on a branch
+type casePath struct {
+	spelling string
+	files    int  // Files at or under the path
+	file     bool // The path is a file
+}
+
+// branch returns the paths of the named branch. A branch seen for the first
+// time starts with the paths of the trunk.
+func (f *caseFolder) branch(name string) *caseBranch {
+	if b, ok := f.branches[name]; ok {
+		return b
+	}
+	b := &caseBranch{live: map[string]*casePath{}, resolved: map[string]string{}}
+	if trunk, ok := f.branches[""]; ok {
+		for lower, p := range trunk.live {
+			copied := *p
+			b.live[lower] = &copied
+		}
+		maps.Copy(b.resolved, trunk.resolved)
+	}
+	f.branches[name] = b
+	return b
+}
+
+// add returns the path written for a file added or changed on branch b,
+// checking the path of every directory leading to it too, and records it
+func (f *caseFolder) add(b *caseBranch, source, revision string) (string, error) {
+	parts := strings.Split(source, "/")
+	out := ""
+	for i, part := range parts {
+		spelled := strings.Join(parts[:i+1], "/")
+		if resolved, ok := b.resolved[spelled]; ok {
+			out = resolved
+			continue
+		}
+		candidate := path.Join(out, part)
+		live, ok := b.live[strings.ToLower(candidate)]
+		if !ok || live.spelling == candidate {
+			out = candidate
+			continue
+		}
+
+		conflict := ReportCaseClash{Path: live.spelling, Conflict: spelled, Commit: revision}
+		switch f.policy {
+		case CaseConflictFail:
+			return "", fmt.Errorf("path %s of commit %s differs from %s only by case", spelled, revision, live.spelling)
+		case CaseConflictUnify:
+			out = live.spelling
+			conflict.Resolved = out
+		case CaseConflictSuffix:
+			out = b.suffixed(out, part)
+			conflict.Resolved = out
+		default:
+			out = candidate
+		}
+		b.resolved[spelled] = out
+		f.report.CaseClashes = append(f.report.CaseClashes, conflict)
+	}
+
+	if p, ok := b.live[strings.ToLower(out)]; ok && p.file {
+		return out, nil
+	}
+	b.visit(out, func(prefix string, p *casePath) {
+		if p == nil {
+			p = &casePath{spelling: prefix}
+			b.live[strings.ToLower(prefix)] = p
+		}
+		p.files++
+	})
+	b.live[strings.ToLower(out)].file = true
+	return out, nil
+}
+
+// remove returns the path written for a file deleted from branch b, and
+// forgets it and the directories it leaves empty
+func (b *caseBranch) remove(source string) string {
+	out := b.written(source)
+	if p, ok := b.live[strings.ToLower(out)]; !ok || !p.file {
+		return out
+	}
+	b.visit(out, func(prefix string, p *casePath) {
+		if p.files--; p.files == 0 {
+			delete(b.live, strings.ToLower(prefix))
+		}
+	})
+	// Spellings written elsewhere are free again once that path is gone
+	for spelled, resolved := range b.resolved {
+		if _, ok := b.live[strings.ToLower(resolved)]; !ok && (spelled == source || strings.HasPrefix(source, spelled+"/")) {
+			delete(b.resolved, spelled)
+		}
+	}
+	return out
+}
+
+// written returns the path written for a source path seen before
+func (b *caseBranch) written(source string) string {
+	parts := strings.Split(source, "/")
+	out := ""
+	for i, part := range parts {
+		if resolved, ok := b.resolved[strings.Join(parts[:i+1], "/")]; ok {
+			out = resolved
+		} else {
+			out = path.Join(out, part)
+		}
+	}
+	return out
+}
+
+// visit calls fn for every directory leading to the written path out and
+// for out itself, with the recorded path or nil
+func (b *caseBranch) visit(out string, fn func(prefix string, p *casePath)) {
+	parts := strings.Split(out, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		fn(prefix, b.live[strings.ToLower(prefix)])
+	}
+}
+
+// suffixed returns the first path dir/name_N, with N from 2, whose case
+// folded form is not in use, keeping the extension of files last
+func (b *caseBranch) suffixed(dir, name string) string {
+	ext := path.Ext(name)
+	if ext == name {
+		ext = "" // Dot files like ".cvsignore"
+	}
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; ; n++ {
+		candidate := path.Join(dir, fmt.Sprintf("%s_%d%s", base, n, ext))
+		if _, taken := b.live[strings.ToLower(candidate)]; !taken {
+			return candidate
+		}
+	}
+}
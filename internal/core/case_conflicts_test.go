@@ -0,0 +1,111 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCaseConflicts(t *testing.T) {
+	history := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "c1", Files: []vcs.FileChange{
+				{Path: "README", Action: vcs.ActionAdd},
+				{Path: "Src/main.c", Action: vcs.ActionAdd},
+			}},
+			{Revision: "c2", Files: []vcs.FileChange{
+				{Path: "Readme", Action: vcs.ActionAdd},
+				{Path: "src/util.c", Action: vcs.ActionAdd},
+				{Path: "Src/Main.c", Action: vcs.ActionModify},
+			}},
+			{Revision: "c3", Files: []vcs.FileChange{
+				{Path: "src/util.c", Action: vcs.ActionModify},
+				{Path: "lib/a.c", Action: vcs.ActionAdd},
+			}},
+		}
+	}
+	paths := func(commits []*vcs.Commit) [][]string {
+		var out [][]string
+		for _, c := range commits {
+			var names []string
+			for _, fc := range c.Files {
+				names = append(names, fc.Path)
+			}
+			out = append(out, names)
+		}
+		return out
+	}
+	run := func(policy string) ([]*vcs.Commit, *MigrationReport, error) {
+		m := NewMigrator(&MigrationConfig{CaseConflicts: policy, Logger: logging.Discard()})
+		require.NoError(t, m.validateCaseConflicts())
+		commits := history()
+		err := m.resolveCaseConflicts(commits)
+		return commits, m.currentReport(), err
+	}
+
+	// By default conflicts are reported and the paths kept
+	commits, report, err := run("")
+	require.NoError(t, err)
+	require.Equal(t, paths(history()), paths(commits))
+	require.Equal(t, []ReportCaseClash{
+		{Path: "README", Conflict: "Readme", Commit: "c2"},
+		{Path: "Src", Conflict: "src", Commit: "c2"},
+		{Path: "Src/main.c", Conflict: "Src/Main.c", Commit: "c2"},
+	}, report.CaseClashes)
+	require.Len(t, report.Warnings, 1)
+
+	commits, report, err = run(CaseConflictUnify)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"README", "Src/main.c"},
+		{"README", "Src/util.c", "Src/main.c"},
+		{"Src/util.c", "lib/a.c"},
+	}, paths(commits))
+	require.Equal(t, "Src", report.CaseClashes[1].Resolved)
+	require.Empty(t, report.Warnings)
+
+	commits, report, err = run(CaseConflictSuffix)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"README", "Src/main.c"},
+		{"Readme_2", "src_2/util.c", "Src/Main_2.c"},
+		{"src_2/util.c", "lib/a.c"},
+	}, paths(commits))
+	require.Len(t, report.CaseClashes, 3)
+
+	_, _, err = run(CaseConflictFail)
+	require.ErrorContains(t, err, "path Readme of commit c2 differs from README only by case")
+
+	// Spellings that never exist at the same time on a branch do not
+	// conflict: case-only renames, and files of different branches
+	for _, policy := range []string{CaseConflictReport, CaseConflictSuffix, CaseConflictUnify, CaseConflictFail} {
+		m := NewMigrator(&MigrationConfig{CaseConflicts: policy, Logger: logging.Discard()})
+		commits := []*vcs.Commit{
+			{Revision: "c1", Files: []vcs.FileChange{{Path: "README", Action: vcs.ActionAdd}, {Path: "Doc/a.txt", Action: vcs.ActionAdd}}},
+			{Revision: "c2", Files: []vcs.FileChange{{Path: "README", Action: vcs.ActionDelete}, {Path: "Readme", Action: vcs.ActionAdd}}},
+			{Revision: "c3", Files: []vcs.FileChange{{Path: "doc/a.txt", OldPath: "Doc/a.txt", Action: vcs.ActionRename}}},
+			{Revision: "c4", Branch: "b1", Files: []vcs.FileChange{{Path: "Readme", Action: vcs.ActionDelete}, {Path: "NOTES", Action: vcs.ActionAdd}}},
+			{Revision: "c5", Files: []vcs.FileChange{{Path: "notes", Action: vcs.ActionAdd}}},
+		}
+		require.NoError(t, m.resolveCaseConflicts(commits), policy)
+		require.Empty(t, m.currentReport().CaseClashes, policy)
+		require.Equal(t, [][]string{{"README", "Doc/a.txt"}, {"README", "Readme"}, {"doc/a.txt"}, {"Readme", "NOTES"}, {"notes"}}, paths(commits), policy)
+	}
+
+	// A resolved spelling keeps its path while it exists, and is free again
+	// once deleted
+	m := NewMigrator(&MigrationConfig{CaseConflicts: CaseConflictSuffix, Logger: logging.Discard()})
+	commits = []*vcs.Commit{
+		{Revision: "c1", Files: []vcs.FileChange{{Path: "README", Action: vcs.ActionAdd}, {Path: "Readme", Action: vcs.ActionAdd}}},
+		{Revision: "c2", Files: []vcs.FileChange{{Path: "Readme", Action: vcs.ActionModify}}},
+		{Revision: "c3", Files: []vcs.FileChange{{Path: "Readme", Action: vcs.ActionDelete}, {Path: "README", Action: vcs.ActionDelete}}},
+		{Revision: "c4", Files: []vcs.FileChange{{Path: "Readme", Action: vcs.ActionAdd}}},
+	}
+	require.NoError(t, m.resolveCaseConflicts(commits))
+	require.Equal(t, [][]string{{"README", "Readme_2"}, {"Readme_2"}, {"Readme_2", "README"}, {"Readme"}}, paths(commits))
+
+	m = NewMigrator(&MigrationConfig{CaseConflicts: "lower"})
+	require.ErrorContains(t, m.validateCaseConflicts(), "unsupported case conflict policy")
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// RewindResult describes a rewind of the target and the migration state
+type RewindResult struct {
+	Checkpoint *storage.SavedCheckpoint
+	Branches   []string // Branches moved back to the checkpoint commit
+	Tags       []string // Tags deleted because they named later commits
+}
+
+// Checkpoints returns the checkpoint history of the migration described by
+// config, oldest first. Every commit written to the target is a checkpoint.
+func Checkpoints(config *MigrationConfig) ([]*storage.SavedCheckpoint, error) {
+	m := NewMigrator(config)
+	db, err := m.openStateDB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	return db.Checkpoints(m.MigrationID())
+}
+
+// FindCheckpoint resolves ref to a checkpoint of the migration described by
+// config. ref is a checkpoint ID or a full or abbreviated Git commit hash.
+func FindCheckpoint(config *MigrationConfig, ref string) (*storage.SavedCheckpoint, error) {
+	checkpoints, err := Checkpoints(config)
+	if err != nil {
+		return nil, err
+	}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		for _, cp := range checkpoints {
+			if cp.ID == id {
+				return cp, nil
+			}
+		}
+	}
+
+	prefix := strings.ToLower(ref)
+	if len(prefix) < 4 {
+		return nil, fmt.Errorf("%w: %s", storage.ErrNoCheckpoint, ref)
+	}
+	var found *storage.SavedCheckpoint
+	for _, cp := range checkpoints {
+		if !strings.HasPrefix(cp.GitHash, prefix) {
+			continue
+		}
+		if found != nil && found.GitHash != cp.GitHash {
+			return nil, fmt.Errorf("commit %s is ambiguous", ref)
+		}
+		// A commit checkpointed twice was written once; keep the later
+		// record, which has the same state
+		found = cp
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", storage.ErrNoCheckpoint, ref)
+	}
+	return found, nil
+}
+
+// RewindToCheckpoint moves the target and the migration state back to
+// checkpoint id. The current branch and the working tree are reset to the
+// checkpoint commit, other branches beyond it are moved back to it and tags
+// on later commits are deleted. A resumed run then applies the commits after
+// the checkpoint again, with the current configuration.
+//
+// The rewind is refused if the target has commits beyond the checkpoint that
+// the migration did not write, since they would be lost.
+func RewindToCheckpoint(config *MigrationConfig, id int64) (*RewindResult, error) {
+	m := NewMigrator(config)
+	if _, err := os.Stat(config.TargetPath); err != nil {
+		return nil, fmt.Errorf("target repository not found: %w", err)
+	}
+
+	lock, err := AcquireLock(LockPath(config.TargetPath), "rewind", config.ForceUnlock)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			m.Logger().Warn("failed to release target lock", "error", err)
+		}
+	}()
+
+	db, err := m.openStateDB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	migrationID := m.MigrationID()
+	cp, err := db.LoadCheckpoint(migrationID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	targetType := config.TargetType
+	if targetType == "" {
+		targetType = "git"
+	}
+	target, err := vcs.NewWriter(targetType, config.TargetOpts)
+	if err != nil {
+		return nil, err
+	}
+	rewinder, ok := target.(vcs.HistoryRewinder)
+	if !ok {
+		return nil, fmt.Errorf("target type %s does not support rewinding", targetType)
+	}
+	if err := target.Open(config.TargetPath); err != nil {
+		return nil, err
+	}
+	defer func() { _ = target.Close() }()
+
+	if err := checkCommitsKnown(db, migrationID, rewinder, cp.GitHash); err != nil {
+		return nil, err
+	}
+
+	// Every step can be repeated, so a rewind that fails halfway is
+	// completed by running it again
+	if err := rewinder.ResetHead(cp.GitHash); err != nil {
+		return nil, fmt.Errorf("failed to rewind target: %w", err)
+	}
+	branches, tags, err := rewinder.RewindRefs(cp.GitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind branches and tags: %w", err)
+	}
+	if err := db.RewindToCheckpoint(cp); err != nil {
+		return nil, fmt.Errorf("failed to rewind state: %w", err)
+	}
+	m.Logger().Info("rewound migration to checkpoint",
+		"checkpoint", cp.ID,
+		"revision", cp.LastCommit,
+		"git_hash", cp.GitHash,
+		"processed", cp.Processed,
+		"branches", len(branches),
+		"tags", len(tags),
+	)
+	return &RewindResult{Checkpoint: cp, Branches: branches, Tags: tags}, nil
+}
+
+// checkCommitsKnown returns an error if the target has commits after hash
+// that the migration did not record
+func checkCommitsKnown(db *storage.StateDB, migrationID string, target vcs.HistoryRewinder, hash string) error {
+	after, err := target.CommitsAfter(hash)
+	if err != nil {
+		return fmt.Errorf("failed to inspect target: %w", err)
+	}
+	if len(after) == 0 {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	mappings, err := db.RevisionMappings(migrationID)
+	if err != nil {
+		return err
+	}
+	for _, mapping := range mappings {
+		known[mapping.GitHash] = true
+	}
+	checkpoints, err := db.Checkpoints(migrationID)
+	if err != nil {
+		return err
+	}
+	for _, cp := range checkpoints {
+		known[cp.GitHash] = true
+	}
+
+	var unknown []string
+	for _, commit := range after {
+		if !known[commit] {
+			unknown = append(unknown, commit)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("target has %d commits after %s that the migration did not write (e.g. %s); refusing to discard them",
+			len(unknown), hash, unknown[0])
+	}
+	return nil
+}
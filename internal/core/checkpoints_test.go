@@ -0,0 +1,134 @@
+package core
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewindToCheckpoint(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := func() []*vcs.Commit {
+		var commits []*vcs.Commit
+		for i, rev := range []string{"1.1", "1.2", "1.3"} {
+			commits = append(commits, &vcs.Commit{Revision: rev, Author: "alice", Date: date.Add(time.Duration(i) * time.Hour), Message: "change " + rev,
+				Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Revision: rev, Content: []byte(rev + "\n")}}})
+		}
+		return commits
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	config := func() *MigrationConfig {
+		return &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Logger: logging.Discard()}
+	}
+
+	m := NewMigrator(config())
+	m.source = &mockReaderWithCommits{commits: commits()}
+	require.NoError(t, m.Run())
+
+	checkpoints, err := Checkpoints(config())
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 3)
+	require.Equal(t, "1.1", checkpoints[0].LastCommit)
+
+	// The author map was wrong after the first commit
+	result, err := RewindToCheckpoint(config(), checkpoints[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Checkpoint.Processed)
+	checkpoints, err = Checkpoints(config())
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 1)
+
+	resumed := config()
+	resumed.Resume = true
+	resumed.AuthorMap = map[string]string{"alice": "Alice Smith <alice@example.com>"}
+	m = NewMigrator(resumed)
+	m.source = &mockReaderWithCommits{commits: commits()}
+	require.NoError(t, m.Run())
+	require.Equal(t, 2, m.Report().Commits.Applied)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	log, err := repo.Log(&gogit.LogOptions{})
+	require.NoError(t, err)
+	var authors []string
+	require.NoError(t, log.ForEach(func(c *object.Commit) error {
+		authors = append(authors, c.Author.Name)
+		return nil
+	}))
+	require.Equal(t, []string{"Alice Smith", "Alice Smith", "alice"}, authors)
+
+	_, err = RewindToCheckpoint(config(), 9999)
+	require.Error(t, err)
+}
+
+func TestRewindToCheckpoint_Refs(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var commits []*vcs.Commit
+	for i, rev := range []string{"1.1", "1.2", "1.3"} {
+		commits = append(commits, &vcs.Commit{Revision: rev, Author: "alice", Date: date.Add(time.Duration(i) * time.Hour), Message: "change " + rev,
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Revision: rev, Content: []byte(rev + "\n")}}})
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	config := func() *MigrationConfig {
+		return &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Logger: logging.Discard()}
+	}
+	m := NewMigrator(config())
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	checkpoints, err := Checkpoints(config())
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 3)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/release", plumbing.NewHash(checkpoints[2].GitHash))))
+	_, err = repo.CreateTag("v1", plumbing.NewHash(checkpoints[0].GitHash), nil)
+	require.NoError(t, err)
+	_, err = repo.CreateTag("v3", plumbing.NewHash(checkpoints[2].GitHash), nil)
+	require.NoError(t, err)
+
+	// Checkpoints are found by ID or by an abbreviated commit hash
+	cp, err := FindCheckpoint(config(), checkpoints[1].GitHash[:10])
+	require.NoError(t, err)
+	require.Equal(t, checkpoints[1].ID, cp.ID)
+	cp, err = FindCheckpoint(config(), strconv.FormatInt(checkpoints[0].ID, 10))
+	require.NoError(t, err)
+	require.Equal(t, checkpoints[0].ID, cp.ID)
+	_, err = FindCheckpoint(config(), "ffffffff")
+	require.ErrorIs(t, err, storage.ErrNoCheckpoint)
+
+	result, err := RewindToCheckpoint(config(), checkpoints[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"release"}, result.Branches)
+	require.Equal(t, []string{"v3"}, result.Tags)
+	release, err := repo.Reference("refs/heads/release", true)
+	require.NoError(t, err)
+	require.Equal(t, checkpoints[1].GitHash, release.Hash().String())
+	_, err = repo.Reference("refs/tags/v1", true)
+	require.NoError(t, err)
+
+	// A commit made by hand on top of the migration is never discarded
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Commit("manual fix", &gogit.CommitOptions{AllowEmptyCommits: true,
+		Author: &object.Signature{Name: "bob", Email: "bob@example.com", When: date}})
+	require.NoError(t, err)
+	_, err = RewindToCheckpoint(config(), checkpoints[0].ID)
+	require.ErrorContains(t, err, "did not write")
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.NotEqual(t, checkpoints[0].GitHash, head.Hash().String())
+	remaining, err := Checkpoints(config())
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+}
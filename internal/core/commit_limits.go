@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// splitTrailer is the trailer linking the commits a source commit was split
+// into, e.g. "Split-Commit: 2/3 of 1.4"
+const splitTrailer = "Split-Commit"
+
+// validateCommitLimits checks MaxCommitFiles and MaxCommitBytes
+func (m *Migrator) validateCommitLimits() error {
+	if m.config.MaxCommitFiles < 0 || m.config.MaxCommitBytes < 0 {
+		return fmt.Errorf("commit file and size limits must not be negative")
+	}
+	return nil
+}
+
+// splitLargeCommits splits the commits with more file changes than
+// MaxCommitFiles, or more content than MaxCommitBytes, into sequential
+// commits within the limits. The last part keeps the source revision and
+// the merged parents, so it stands for the complete source commit; every
+// part carries a Split-Commit trailer. It returns the commits and the
+// number of source commits that were split.
+func (m *Migrator) splitLargeCommits(commits []*vcs.Commit) ([]*vcs.Commit, int, error) {
+	maxFiles, maxBytes := m.config.MaxCommitFiles, m.config.MaxCommitBytes
+	if maxFiles <= 0 && maxBytes <= 0 {
+		return commits, 0, nil
+	}
+
+	var out []*vcs.Commit
+	split := 0
+	for _, commit := range commits {
+		// Sizing reads lazy content, so only commits the size limit could
+		// split are measured
+		commitBytes := maxBytes
+		if contentChanges(commit.Files) < 2 {
+			commitBytes = 0
+		}
+		chunks, err := chunkFiles(commit.Files, maxFiles, commitBytes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to split commit %s: %w", commit.Revision, err)
+		}
+		if len(chunks) <= 1 {
+			out = append(out, commit)
+			continue
+		}
+
+		split++
+		m.Logger().Info("splitting large commit", "revision", commit.Revision, "files", len(commit.Files), "parts", len(chunks))
+		for i, files := range chunks {
+			part := *commit
+			part.Files = files
+			part.Message = splitMessage(commit.Message, commit.Revision, i+1, len(chunks))
+			if i < len(chunks)-1 {
+				part.Revision = fmt.Sprintf("%s/part-%d", commit.Revision, i+1)
+				part.Parents = nil
+			}
+			out = append(out, &part)
+		}
+	}
+	return out, split, nil
+}
+
+// chunkFiles divides file changes into groups of at most maxFiles changes
+// and maxBytes of content; a single larger file forms its own group. A
+// limit of zero is no limit.
+func chunkFiles(files []vcs.FileChange, maxFiles int, maxBytes int64) ([][]vcs.FileChange, error) {
+	var chunks [][]vcs.FileChange
+	var chunk []vcs.FileChange
+	var size int64
+	for _, fc := range files {
+		var n int64
+		if maxBytes > 0 && fc.Action != vcs.ActionDelete {
+			var err error
+			if n, err = contentSize(&fc); err != nil {
+				return nil, fmt.Errorf("%s: %w", fc.Path, err)
+			}
+		}
+		full := maxFiles > 0 && len(chunk) >= maxFiles
+		if maxBytes > 0 && len(chunk) > 0 && size+n > maxBytes {
+			full = true
+		}
+		if full {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, fc)
+		size += n
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// contentChanges counts the file changes that carry content
+func contentChanges(files []vcs.FileChange) int {
+	n := 0
+	for _, fc := range files {
+		if fc.Action != vcs.ActionDelete {
+			n++
+		}
+	}
+	return n
+}
+
+// contentSize returns the size of the content of a file change, reading it
+// if the source provides it lazily
+func contentSize(fc *vcs.FileChange) (int64, error) {
+	if fc.Source == nil {
+		return int64(len(fc.Content)), nil
+	}
+	rc, err := fc.Source()
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(io.Discard, rc)
+	if closeErr := rc.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// splitMessage appends the Split-Commit trailer of part n of total to a
+// commit message, joining the message's own trailers if it ends with some
+func splitMessage(message, revision string, n, total int) string {
+	message = strings.TrimRight(message, "\n")
+	separator := "\n\n"
+	if message == "" {
+		separator = ""
+	} else if i := strings.LastIndex(message, "\n\n"); i >= 0 && isTrailerBlock(message[i+2:]) {
+		separator = "\n"
+	}
+	return fmt.Sprintf("%s%s%s: %d/%d of %s\n", message, separator, splitTrailer, n, total, revision)
+}
+
+// isTrailerBlock reports whether every line of a paragraph is a
+// "Key: value" trailer
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		key, _, ok := strings.Cut(line, ": ")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkFiles(t *testing.T) {
+	lazy := func(s string) vcs.ContentSource {
+		return func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(s)), nil }
+	}
+	files := []vcs.FileChange{
+		{Path: "a", Action: vcs.ActionAdd, Content: []byte("1234")},
+		{Path: "b", Action: vcs.ActionAdd, Source: lazy("12345678")},
+		{Path: "c", Action: vcs.ActionDelete},
+		{Path: "d", Action: vcs.ActionAdd, Content: []byte("12")},
+		{Path: "e", Action: vcs.ActionAdd, Content: []byte("1")},
+	}
+	paths := func(chunks [][]vcs.FileChange) [][]string {
+		var out [][]string
+		for _, chunk := range chunks {
+			var names []string
+			for _, fc := range chunk {
+				names = append(names, fc.Path)
+			}
+			out = append(out, names)
+		}
+		return out
+	}
+
+	chunks, err := chunkFiles(files, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, paths(chunks))
+
+	// A file larger than the limit forms its own chunk
+	chunks, err = chunkFiles(files, 0, 6)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a"}, {"b"}, {"c", "d", "e"}}, paths(chunks))
+
+	chunks, err = chunkFiles(files, 2, 6)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a"}, {"b"}, {"c", "d"}, {"e"}}, paths(chunks))
+}
+
+func TestSplitLargeCommits_MeasuresSplittableCommits(t *testing.T) {
+	opened := 0
+	source := func() (io.ReadCloser, error) {
+		opened++
+		return io.NopCloser(strings.NewReader("1234")), nil
+	}
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Files: []vcs.FileChange{{Path: "a", Action: vcs.ActionAdd, Source: source}}},
+		{Revision: "1.2", Files: []vcs.FileChange{{Path: "a", Action: vcs.ActionModify, Source: source}, {Path: "b", Action: vcs.ActionDelete}}},
+		{Revision: "1.3", Files: []vcs.FileChange{{Path: "a", Action: vcs.ActionModify, Source: source}, {Path: "c", Action: vcs.ActionAdd, Source: source}}},
+	}
+	m := NewMigrator(&MigrationConfig{MaxCommitBytes: 6, Logger: logging.Discard()})
+	out, split, err := m.splitLargeCommits(commits)
+	require.NoError(t, err)
+	require.Equal(t, 1, split)
+	require.Len(t, out, 4)
+	require.Equal(t, 2, opened, "only the commit with two files is measured")
+}
+
+func TestSplitMessage(t *testing.T) {
+	require.Equal(t, "Import\n\nSplit-Commit: 1/2 of 1.1\n", splitMessage("Import\n", "1.1", 1, 2))
+	require.Equal(t, "Import\n\nSigned-off-by: A <a@example.com>\nSplit-Commit: 2/2 of 1.1\n",
+		splitMessage("Import\n\nSigned-off-by: A <a@example.com>\n", "1.1", 2, 2))
+	require.Equal(t, "Split-Commit: 1/3 of x\n", splitMessage("", "x", 1, 3))
+}
+
+func TestRun_SplitsLargeCommits(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var files []vcs.FileChange
+	for i := 0; i < 5; i++ {
+		files = append(files, vcs.FileChange{Path: fmt.Sprintf("f%d.txt", i), Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("x")})
+	}
+	commits := []*vcs.Commit{
+		{Revision: "import", Author: "alice", Date: date, Message: "Mass import", Files: files},
+		{Revision: "fix", Author: "alice", Date: date.Add(time.Hour), Message: "fix", Files: []vcs.FileChange{
+			{Path: "f0.txt", Action: vcs.ActionModify, Revision: "1.2", Content: []byte("y")},
+		}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType:     "cvs",
+		SourcePath:     "/src",
+		TargetPath:     target,
+		MaxCommitFiles: 2,
+		Logger:         logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	report := m.Report()
+	require.Equal(t, ReportCommits{Total: 4, Applied: 4, Split: 1}, report.Commits)
+	require.True(t, report.Verification.Passed, report.Verification.Problems)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	log, err := repo.Log(&gogit.LogOptions{From: head.Hash()})
+	require.NoError(t, err)
+	var messages []string
+	require.NoError(t, log.ForEach(func(c *object.Commit) error {
+		messages = append([]string{c.Message}, messages...)
+		return nil
+	}))
+	require.Equal(t, []string{
+		"Mass import\n\nSplit-Commit: 1/3 of import\n",
+		"Mass import\n\nSplit-Commit: 2/3 of import\n",
+		"Mass import\n\nSplit-Commit: 3/3 of import\n",
+		"fix",
+	}, messages)
+
+	require.Error(t, NewMigrator(&MigrationConfig{MaxCommitFiles: -1}).validateCommitLimits())
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// CompatCVSImport selects output compatible with git-cvsimport: trunk is
+// imported to the "origin" branch, "master" is created from it when
+// missing, unmapped authors use their CVS username as email, and log
+// messages are stripped of trailing whitespace.
+const CompatCVSImport = "git-cvsimport"
+
+const (
+	cvsImportTrunkBranch = "origin"
+	cvsImportHeadBranch  = "master"
+)
+
+// validateCompat checks the configured compatibility mode
+func (m *Migrator) validateCompat() error {
+	switch m.config.Compat {
+	case "", CompatCVSImport:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compatibility mode: %s", m.config.Compat)
+	}
+}
+
+// trunkBranch returns the branch receiving trunk history, or an empty
+// string to keep the writer default
+func (m *Migrator) trunkBranch() string {
+	if m.config.DefaultBranch == "" && m.config.Compat == CompatCVSImport {
+		return cvsImportTrunkBranch
+	}
+	return m.config.DefaultBranch
+}
+
+// mapAuthor replaces the CVS username of a commit with the mapped identity
+// and applies the message conventions of the compatibility mode
+func (m *Migrator) mapAuthor(commit *vcs.Commit) {
+	if m.config.Compat == CompatCVSImport {
+		commit.Message = cvsImportMessage(commit.Message)
+		if _, ok := m.config.AuthorMap[commit.Author]; !ok {
+			// git-cvsimport uses the bare username as name and email
+			commit.Email = commit.Author
+			return
+		}
+	}
+
+	commit.Author, commit.Email = m.authorMap.Get(commit.Author)
+}
+
+// cvsImportMessage formats a log message the way git-cvsimport does
+func cvsImportMessage(message string) string {
+	return strings.TrimRight(message, " \t\r\n") + "\n"
+}
+
+// finishCVSImport creates the master branch from trunk if it does not exist
+// and checks it out, as git-cvsimport does after an import. On later runs
+// master is moved forward to the new trunk commits, unless it has diverged
+// from trunk.
+func (m *Migrator) finishCVSImport() error {
+	setter, ok := m.target.(interface{ SetDefaultBranch(string) error })
+	if !ok {
+		return fmt.Errorf("target does not support %s compatibility", CompatCVSImport)
+	}
+
+	var heads map[string]string
+	if lister, ok := m.target.(interface {
+		BranchHeads() (map[string]string, error)
+	}); ok {
+		var err error
+		if heads, err = lister.BranchHeads(); err != nil {
+			return err
+		}
+	}
+
+	master, exists := heads[cvsImportHeadBranch]
+	trunk := heads[m.trunkBranch()]
+	switch {
+	case !exists:
+		if err := m.target.CreateBranch(cvsImportHeadBranch, "HEAD"); err != nil {
+			return err
+		}
+	case trunk != "" && master != trunk:
+		checker, ok := m.target.(interface {
+			IsAncestor(ancestor, descendant string) (bool, error)
+		})
+		if !ok {
+			break
+		}
+		forward, err := checker.IsAncestor(master, trunk)
+		if err != nil {
+			return err
+		}
+		if !forward {
+			m.warn("branch has diverged from trunk; merge the new trunk commits by hand",
+				"branch", cvsImportHeadBranch, "trunk", m.trunkBranch())
+			break
+		}
+		if err := m.target.CreateBranch(cvsImportHeadBranch, trunk); err != nil {
+			return err
+		}
+	}
+	return setter.SetDefaultBranch(cvsImportHeadBranch)
+}
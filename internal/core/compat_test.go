@@ -0,0 +1,114 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_CVSImportCompat(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: repo,
+		TargetPath: target,
+		AuthorMap:  map[string]string{"alice": "Alice Smith <alice@example.com>"},
+		Compat:     CompatCVSImport,
+		Logger:     logging.Discard(),
+	}
+	require.NoError(t, NewMigrator(cfg).Run())
+
+	r, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+
+	origin, err := r.Reference(plumbing.NewBranchReferenceName("origin"), false)
+	require.NoError(t, err)
+	master, err := r.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+	require.Equal(t, origin.Hash(), master.Hash())
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+
+	tip, err := r.CommitObject(origin.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "bob", tip.Author.Name)
+	require.Equal(t, "bob", tip.Author.Email)
+	require.Equal(t, "second\n", tip.Message)
+
+	parent, err := tip.Parent(0)
+	require.NoError(t, err)
+	require.Equal(t, "Alice Smith", parent.Author.Name)
+	require.Equal(t, "alice@example.com", parent.Author.Email)
+
+	// A rerun leaves the layout intact
+	require.NoError(t, NewMigrator(cfg).Run())
+	head, err = r.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+}
+
+func TestRun_CVSImportCompatAdvancesMaster(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	first := "head 1.1;\naccess;\nsymbols;\nlocks;\n\n" +
+		"1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\n" +
+		"desc\n@@\n\n1.1\nlog\n@first\n@\ntext\n@one\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(first), 0644))
+
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: repo,
+		TargetPath: target,
+		Compat:     CompatCVSImport,
+		Logger:     logging.Discard(),
+	}
+	require.NoError(t, NewMigrator(cfg).Run())
+
+	// The next run imports revision 1.2 to origin and moves master along
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+	require.NoError(t, NewMigrator(cfg).Run())
+
+	r, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	origin, err := r.Reference(plumbing.NewBranchReferenceName("origin"), false)
+	require.NoError(t, err)
+	master, err := r.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+	require.Equal(t, origin.Hash(), master.Hash())
+	tip, err := r.CommitObject(master.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "second\n", tip.Message)
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+	data, err := os.ReadFile(filepath.Join(target, "f.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "two\n", string(data))
+}
+
+func TestRun_UnknownCompatMode(t *testing.T) {
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: "/t", DryRun: true, Compat: "cvs2git"}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{}
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported compatibility mode")
+}
+
+func TestCVSImportMessage(t *testing.T) {
+	require.Equal(t, "fix bug\n", cvsImportMessage("fix bug\n\n  \n"))
+	require.Equal(t, "a\n\nb\n", cvsImportMessage("a\n\nb"))
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Timezone names work without a system zoneinfo database
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Date policies
+const (
+	// DatePreserveUTC keeps the UTC dates recorded by the source
+	DatePreserveUTC = "preserve-utc"
+	// DateFixedOffset shows all dates in the DateTimezone timezone
+	DateFixedOffset = "fixed-offset"
+	// DatePerAuthor shows the dates of each author in their timezone from
+	// AuthorTimezones, falling back to DateTimezone or UTC
+	DatePerAuthor = "per-author"
+)
+
+// utcOffsetPattern matches offsets such as "+02:00", "-0530" or "+01"
+var utcOffsetPattern = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})?$`)
+
+// parseTimezone parses a UTC offset or an IANA timezone name such as
+// "Europe/Berlin"
+func parseTimezone(name string) (*time.Location, error) {
+	if m := utcOffsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("invalid UTC offset %q", name)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// validateDates checks the date policy and resolves its timezones
+func (m *Migrator) validateDates() error {
+	m.dateLocation = nil
+	m.authorLocations = nil
+
+	switch m.config.DatePolicy {
+	case "", DatePreserveUTC:
+		return nil
+	case DateFixedOffset:
+		if m.config.DateTimezone == "" {
+			return fmt.Errorf("date policy %s requires a timezone", DateFixedOffset)
+		}
+	case DatePerAuthor:
+		if len(m.config.AuthorTimezones) == 0 {
+			return fmt.Errorf("date policy %s requires author timezones", DatePerAuthor)
+		}
+	default:
+		return fmt.Errorf("unsupported date policy: %s", m.config.DatePolicy)
+	}
+
+	if m.config.DateTimezone != "" {
+		loc, err := parseTimezone(m.config.DateTimezone)
+		if err != nil {
+			return err
+		}
+		m.dateLocation = loc
+	}
+	if m.config.DatePolicy == DatePerAuthor {
+		m.authorLocations = make(map[string]*time.Location, len(m.config.AuthorTimezones))
+		for author, name := range m.config.AuthorTimezones {
+			loc, err := parseTimezone(name)
+			if err != nil {
+				return fmt.Errorf("timezone of %s: %w", author, err)
+			}
+			m.authorLocations[author] = loc
+		}
+	}
+	return nil
+}
+
+// applyDates applies the date policy to a commit before its author is
+// mapped, so per-author timezones are keyed on the source login. With
+// MonotonicDates, a commit dated before the previous one is moved to the
+// date of the previous one; it returns whether the date was moved.
+func (m *Migrator) applyDates(commit *vcs.Commit) bool {
+	loc := m.dateLocation
+	if l, ok := m.authorLocations[commit.Author]; ok {
+		loc = l
+	}
+	if loc != nil {
+		commit.Date = commit.Date.In(loc)
+		if !commit.CommitDate.IsZero() {
+			commit.CommitDate = commit.CommitDate.In(loc)
+		}
+	}
+
+	if !m.config.MonotonicDates {
+		return false
+	}
+	moved := false
+	if commit.Date.Before(m.lastDate) {
+		commit.Date = m.lastDate.In(commit.Date.Location())
+		moved = true
+	}
+	if !commit.CommitDate.IsZero() && commit.CommitDate.Before(commit.Date) {
+		commit.CommitDate = commit.Date
+	}
+	m.lastDate = commit.Date
+	return moved
+}
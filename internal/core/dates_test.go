@@ -0,0 +1,115 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimezone(t *testing.T) {
+	for name, offset := range map[string]int{"+02:00": 7200, "-0530": -19800, "+01": 3600, "UTC": 0} {
+		loc, err := parseTimezone(name)
+		require.NoError(t, err, name)
+		_, got := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+		require.Equal(t, offset, got, name)
+	}
+
+	loc, err := parseTimezone("Europe/Berlin")
+	require.NoError(t, err)
+	_, summer := time.Date(2024, 7, 1, 0, 0, 0, 0, loc).Zone()
+	require.Equal(t, 7200, summer)
+
+	for _, name := range []string{"+25:00", "+02:75", "Mars/Olympus"} {
+		_, err := parseTimezone(name)
+		require.Error(t, err, name)
+	}
+}
+
+func TestApplyDates(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMigrator(&MigrationConfig{
+		DatePolicy:      DatePerAuthor,
+		DateTimezone:    "+01:00",
+		AuthorTimezones: map[string]string{"alice": "America/New_York"},
+		MonotonicDates:  true,
+	})
+	require.NoError(t, m.validateDates())
+
+	first := &vcs.Commit{Author: "alice", Date: base}
+	require.False(t, m.applyDates(first))
+	require.True(t, first.Date.Equal(base))
+	require.Equal(t, "-0500", first.Date.Format("-0700"))
+
+	// Authors without a timezone use DateTimezone
+	skewed := &vcs.Commit{Author: "bob", Date: base.Add(-time.Minute), CommitDate: base.Add(-time.Minute)}
+	require.True(t, m.applyDates(skewed))
+	require.True(t, skewed.Date.Equal(base))
+	require.True(t, skewed.CommitDate.Equal(base))
+	require.Equal(t, "+0100", skewed.Date.Format("-0700"))
+
+	later := &vcs.Commit{Author: "bob", Date: base.Add(time.Hour)}
+	require.False(t, m.applyDates(later))
+	require.True(t, later.Date.Equal(base.Add(time.Hour)))
+}
+
+func TestValidateDates(t *testing.T) {
+	valid := []*MigrationConfig{
+		{},
+		{DatePolicy: DatePreserveUTC},
+		{DatePolicy: DateFixedOffset, DateTimezone: "+02:00"},
+		{DatePolicy: DatePerAuthor, AuthorTimezones: map[string]string{"alice": "Asia/Tokyo"}},
+	}
+	for _, cfg := range valid {
+		require.NoError(t, NewMigrator(cfg).validateDates(), cfg.DatePolicy)
+	}
+
+	invalid := []*MigrationConfig{
+		{DatePolicy: "local"},
+		{DatePolicy: DateFixedOffset},
+		{DatePolicy: DateFixedOffset, DateTimezone: "nowhere"},
+		{DatePolicy: DatePerAuthor},
+		{DatePolicy: DatePerAuthor, AuthorTimezones: map[string]string{"alice": "+99"}},
+	}
+	for _, cfg := range invalid {
+		require.Error(t, NewMigrator(cfg).validateDates(), cfg.DatePolicy)
+	}
+}
+
+func TestRun_DateFixedOffset(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType:     "cvs",
+		SourcePath:     "/src",
+		TargetPath:     target,
+		DatePolicy:     DateFixedOffset,
+		DateTimezone:   "+02:00",
+		MonotonicDates: true,
+		Logger:         logging.Discard(),
+	}
+	// Clock skew dates the second revision of main.c before the first
+	commits := eolTestCommits()
+	commits[0].Files[0].Revision = "1.1"
+	commits[1].Files[0].Revision = "1.2"
+	commits[1].Date = commits[0].Date.Add(-time.Hour)
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	first, err := tip.Parent(0)
+	require.NoError(t, err)
+
+	require.Equal(t, "+0200", tip.Author.When.Format("-0700"))
+	require.True(t, tip.Author.When.Equal(first.Author.When))
+	require.True(t, first.Author.When.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// applyDeterminism sorts the file changes of a commit by path and fixes its
+// committer date to the author date, so repeated runs write byte-identical
+// commits. With Deterministic, orderCommits also breaks ties by content.
+func (m *Migrator) applyDeterminism(commit *vcs.Commit) {
+	if !m.config.Deterministic {
+		return
+	}
+	sort.SliceStable(commit.Files, func(i, j int) bool {
+		return commit.Files[i].Path < commit.Files[j].Path
+	})
+	commit.CommitDate = commit.Date
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+// simultaneousCommits returns commits of which two share a date, with the
+// files and the simultaneous commits in the given order
+func simultaneousCommits(reversed bool) []*vcs.Commit {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []vcs.FileChange{
+		{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a\n")},
+		{Path: "b.txt", Action: vcs.ActionAdd, Content: []byte("b\n")},
+	}
+	alice := &vcs.Commit{Revision: "1.1", Author: "alice", Date: date.Add(time.Hour), Message: "alice", Files: []vcs.FileChange{
+		{Path: "c.txt", Action: vcs.ActionAdd, Content: []byte("c\n")},
+	}}
+	bob := &vcs.Commit{Revision: "1.1", Author: "bob", Date: date.Add(time.Hour), Message: "bob", Files: []vcs.FileChange{
+		{Path: "c.txt", Action: vcs.ActionModify, Content: []byte("bob\n")},
+	}}
+	first := &vcs.Commit{Revision: "1.1", Author: "alice", Date: date, Message: "initial", Files: files}
+	if reversed {
+		first.Files = []vcs.FileChange{files[1], files[0]}
+		first.CommitDate = date.Add(time.Minute) // Recorded by a source with committer dates
+		return []*vcs.Commit{first, bob, alice}
+	}
+	return []*vcs.Commit{first, alice, bob}
+}
+
+func runDeterministic(t *testing.T, commits []*vcs.Commit) *MigrationReport {
+	t.Helper()
+	cfg := &MigrationConfig{
+		SourceType:    "cvs",
+		SourcePath:    "/src",
+		TargetPath:    filepath.Join(t.TempDir(), "repo"),
+		Deterministic: true,
+		Logger:        logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+	return m.Report()
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	first := runDeterministic(t, simultaneousCommits(false))
+	second := runDeterministic(t, simultaneousCommits(true))
+
+	require.NotNil(t, first.Verification)
+	require.NotEmpty(t, first.Verification.Heads["master"])
+	require.Equal(t, first.Verification.Heads, second.Verification.Heads)
+}
+
+func TestOrderCommits(t *testing.T) {
+	commits := simultaneousCommits(true)
+	NewMigrator(&MigrationConfig{}).orderCommits(commits)
+	require.Equal(t, "bob", commits[1].Author, "order is kept without Deterministic")
+
+	NewMigrator(&MigrationConfig{Deterministic: true}).orderCommits(commits)
+	require.Equal(t, []string{"initial", "alice", "bob"}, []string{commits[0].Message, commits[1].Message, commits[2].Message})
+}
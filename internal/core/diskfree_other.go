@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package core
+
+import "errors"
+
+// diskFree is not supported on this platform, so the disk space check is
+// skipped
+func diskFree(string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
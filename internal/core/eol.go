@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// End-of-line policies
+const (
+	// EOLAsIs keeps file contents unchanged
+	EOLAsIs = "as-is"
+	// EOLLF normalizes text files to LF and checks them out with LF
+	EOLLF = "lf"
+	// EOLCRLFByExtension normalizes text files to LF and checks out files
+	// with the configured extensions with CRLF
+	EOLCRLFByExtension = "crlf-by-extension"
+)
+
+// DefaultCRLFExtensions are the extensions checked out with CRLF by
+// EOLCRLFByExtension when none are configured
+var DefaultCRLFExtensions = []string{".bat", ".cmd"}
+
+// gitattributesPath is the file describing the EOL policy and keyword
+// expansion to Git
+const gitattributesPath = ".gitattributes"
+
+// validateEOL checks the configured end-of-line policy
+func (m *Migrator) validateEOL() error {
+	switch m.config.EOL {
+	case "", EOLAsIs, EOLLF, EOLCRLFByExtension:
+	default:
+		return fmt.Errorf("unsupported eol policy: %s", m.config.EOL)
+	}
+	for _, ext := range m.config.CRLFExtensions {
+		if !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext, "/ *?[") {
+			return fmt.Errorf("invalid crlf extension %q: must look like \".bat\"", ext)
+		}
+	}
+	return nil
+}
+
+// normalizesEOL reports whether text file contents are rewritten
+func (m *Migrator) normalizesEOL() bool {
+	return m.config.EOL == EOLLF || m.config.EOL == EOLCRLFByExtension
+}
+
+// applyEOL normalizes the line endings of the text files of a commit
+func (m *Migrator) applyEOL(commit *vcs.Commit) {
+	if !m.normalizesEOL() {
+		return
+	}
+	for i := range commit.Files {
+		fc := &commit.Files[i]
+		if fc.Action == vcs.ActionDelete || fc.Binary {
+			continue
+		}
+		transformContent(fc, normalizeEOL)
+	}
+}
+
+// applyAttributes adds a .gitattributes file describing the EOL policy to
+// the first commit, unless the source already provides one
+func (m *Migrator) applyAttributes(commit *vcs.Commit, first bool) {
+	attributes := m.gitattributes()
+	if !first || attributes == "" {
+		return
+	}
+	for _, fc := range commit.Files {
+		if fc.Path == gitattributesPath {
+			m.Logger().Warn("source provides .gitattributes, not generating one", "revision", commit.Revision)
+			return
+		}
+	}
+	commit.Files = append(commit.Files, vcs.FileChange{
+		Path:    gitattributesPath,
+		Action:  vcs.ActionAdd,
+		Content: []byte(attributes),
+	})
+}
+
+// normalizeEOL converts CRLF line endings of text content to LF. Binary
+// content is returned unchanged.
+func normalizeEOL(content []byte) []byte {
+	if isBinary(content) || !bytes.Contains(content, []byte("\r\n")) {
+		return content
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
+
+// gitattributes returns the .gitattributes content for the EOL policy, or
+// "" if it needs none
+func (m *Migrator) gitattributes() string {
+	var b strings.Builder
+	if m.normalizesEOL() {
+		b.WriteString("# Generated by git-migrator: text files are stored with LF line endings\n")
+		b.WriteString("* text=auto eol=lf\n")
+		if m.config.EOL == EOLCRLFByExtension {
+			exts := m.config.CRLFExtensions
+			if len(exts) == 0 {
+				exts = DefaultCRLFExtensions
+			}
+			for _, ext := range exts {
+				fmt.Fprintf(&b, "*%s text eol=crlf\n", ext)
+			}
+		}
+		if len(m.binaryPaths) > 0 {
+			b.WriteString("# Stored as binary in the source\n")
+			for _, p := range m.binaryPaths {
+				fmt.Fprintf(&b, "%s -text\n", attributePattern(p))
+			}
+		}
+	}
+	return b.String()
+}
+
+// binaryPaths returns the sorted paths the commits add or modify as binary
+// files (CVS -kb). Git would otherwise apply text=auto to them and convert
+// line endings in those that look like text.
+func binaryPaths(commits []*vcs.Commit) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, commit := range commits {
+		for _, fc := range commit.Files {
+			if fc.Binary && fc.Action != vcs.ActionDelete && !seen[fc.Path] {
+				seen[fc.Path] = true
+				paths = append(paths, fc.Path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// attributePattern returns a .gitattributes pattern matching exactly the
+// file at p: anchored, with glob characters escaped and quoted if it
+// contains spaces or quotes
+func attributePattern(p string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for _, r := range p {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	pattern := b.String()
+	if !strings.ContainsAny(pattern, " \t\"") {
+		return pattern
+	}
+	return strconv.Quote(pattern)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEOL(t *testing.T) {
+	require.Equal(t, "a\nb\n", string(normalizeEOL([]byte("a\r\nb\r\n"))))
+	require.Equal(t, "a\nb", string(normalizeEOL([]byte("a\nb"))))
+
+	binary := []byte("\x00\r\n")
+	require.Equal(t, binary, normalizeEOL(binary))
+}
+
+func eolTestCommits() []*vcs.Commit {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lazy := func(s string) vcs.ContentSource {
+		return func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(s)), nil }
+	}
+	return []*vcs.Commit{
+		{Revision: "1", Author: "alice", Date: date, Message: "first", Files: []vcs.FileChange{
+			{Path: "main.c", Action: vcs.ActionAdd, Content: []byte("int x;\r\n")},
+			{Path: "build.bat", Action: vcs.ActionAdd, Source: lazy("@echo off\r\n")},
+			{Path: "logo.bin", Action: vcs.ActionAdd, Content: []byte("\x00\r\n")},
+			{Path: "table.dat", Action: vcs.ActionAdd, Content: []byte("a\r\nb\r\n"), Binary: true},
+		}},
+		{Revision: "2", Author: "alice", Date: date.Add(time.Hour), Message: "second", Files: []vcs.FileChange{
+			{Path: "main.c", Action: vcs.ActionModify, Content: []byte("int y;\r\n")},
+		}},
+	}
+}
+
+func readTreeFile(t *testing.T, tree *object.Tree, name string) string {
+	t.Helper()
+	f, err := tree.File(name)
+	require.NoError(t, err)
+	content, err := f.Contents()
+	require.NoError(t, err)
+	return content
+}
+
+func TestRun_EOLCRLFByExtension(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: target,
+		EOL:        EOLCRLFByExtension,
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: eolTestCommits()}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := tip.Tree()
+	require.NoError(t, err)
+
+	require.Equal(t, "int y;\n", readTreeFile(t, tree, "main.c"))
+	require.Equal(t, "@echo off\n", readTreeFile(t, tree, "build.bat"))
+	require.Equal(t, "\x00\r\n", readTreeFile(t, tree, "logo.bin"))
+	// Files the source marks binary keep their bytes even if they look like text
+	require.Equal(t, "a\r\nb\r\n", readTreeFile(t, tree, "table.dat"))
+
+	// The attributes are part of the first commit
+	first, err := tip.Parent(0)
+	require.NoError(t, err)
+	firstTree, err := first.Tree()
+	require.NoError(t, err)
+	attributes := readTreeFile(t, firstTree, ".gitattributes")
+	require.Contains(t, attributes, "* text=auto eol=lf\n")
+	require.Contains(t, attributes, "*.bat text eol=crlf\n")
+	require.Contains(t, attributes, "*.cmd text eol=crlf\n")
+	require.Contains(t, attributes, "/table.dat -text\n")
+}
+
+func TestRun_EOLAsIsKeepsContent(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: eolTestCommits()}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := tip.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "int y;\r\n", readTreeFile(t, tree, "main.c"))
+	_, err = tree.File(".gitattributes")
+	require.Error(t, err)
+}
+
+func TestGitattributes(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{EOL: EOLLF})
+	require.Equal(t, "# Generated by git-migrator: text files are stored with LF line endings\n* text=auto eol=lf\n", m.gitattributes())
+
+	m = NewMigrator(&MigrationConfig{EOL: EOLCRLFByExtension, CRLFExtensions: []string{".ps1"}})
+	require.True(t, strings.HasSuffix(m.gitattributes(), "* text=auto eol=lf\n*.ps1 text eol=crlf\n"))
+
+	// Binary files are excluded from the text conversion
+	m.binaryPaths = []string{"img/logo.gif", "doc/a b.doc", "odd[1].bin"}
+	require.True(t, strings.HasSuffix(m.gitattributes(),
+		"/img/logo.gif -text\n\"/doc/a b.doc\" -text\n/odd\\[1].bin -text\n"))
+
+	m = NewMigrator(&MigrationConfig{})
+	m.binaryPaths = []string{"img/logo.gif"}
+	require.Empty(t, m.gitattributes())
+}
+
+func TestBinaryPaths(t *testing.T) {
+	require.Equal(t, []string{"table.dat"}, binaryPaths(eolTestCommits()))
+}
+
+func TestRun_InvalidEOLPolicy(t *testing.T) {
+	for _, cfg := range []*MigrationConfig{
+		{EOL: "native"},
+		{EOL: EOLCRLFByExtension, CRLFExtensions: []string{"bat"}},
+	} {
+		cfg.SourceType, cfg.SourcePath, cfg.TargetPath, cfg.DryRun = "cvs", "/src", "/t", true
+		m := NewMigrator(cfg)
+		m.source = &mockReaderWithCommits{}
+		require.Error(t, m.Run())
+	}
+}
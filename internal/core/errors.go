@@ -0,0 +1,25 @@
+package core
+
+// Error kinds matched with errors.Is by callers mapping failures to their
+// own codes. Errors of a kind match both their own sentinel and the kind.
+var (
+	// ErrConflict matches failures caused by another run or change to the
+	// same target: ErrLocked and ErrTargetModified
+	ErrConflict = newKind("conflict with the target")
+	// ErrInterrupted matches runs ended before all commits were applied,
+	// leaving a checkpoint to resume from: ErrStopped and InterruptAt
+	ErrInterrupted = newKind("migration interrupted")
+)
+
+// kindError is a sentinel error of a kind
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func newKind(msg string) error { return &kindError{msg: msg} }
+
+func (e *kindError) Error() string { return e.msg }
+
+// Is reports the kind of the error as matching
+func (e *kindError) Is(target error) bool { return e.kind != nil && target == e.kind }
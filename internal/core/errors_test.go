@@ -0,0 +1,42 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKinds(t *testing.T) {
+	require.ErrorIs(t, fmt.Errorf("lock: %w", ErrLocked), ErrConflict)
+	require.ErrorIs(t, ErrTargetModified, ErrConflict)
+	require.ErrorIs(t, ErrStopped, ErrInterrupted)
+	require.False(t, errors.Is(ErrStopped, ErrConflict))
+	require.False(t, errors.Is(ErrConflict, ErrLocked))
+	require.False(t, errors.Is(ErrLocked, ErrTargetModified))
+	require.Equal(t, "target is locked by another run", ErrLocked.Error())
+}
+
+func TestRun_RequireAuthors(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "alice", Date: date, Message: "m1", Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a")}}},
+		{Revision: "r2", Author: "bob", Date: date.Add(time.Minute), Message: "m2", Files: []vcs.FileChange{{Path: "b.txt", Action: vcs.ActionAdd, Content: []byte("b")}}},
+	}
+	cfg := &MigrationConfig{
+		SourceType: "cvs", SourcePath: "/src", TargetPath: filepath.Join(t.TempDir(), "repo"),
+		StateFile: filepath.Join(t.TempDir(), "state.db"), Logger: logging.Discard(),
+		AuthorMap: map[string]string{"alice": "Alice <alice@example.com>"}, RequireAuthors: true,
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	err := m.Run()
+	require.ErrorIs(t, err, mapping.ErrUnmappedAuthor)
+	require.ErrorContains(t, err, "commit r2: unmapped author: bob")
+}
@@ -0,0 +1,256 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Graft modes
+const (
+	// GraftOnto applies the CVS history after the existing commit on top of
+	// it, leaving out the changes the commit already holds
+	GraftOnto = "onto"
+	// GraftReplace migrates the whole CVS history onto a branch of its own
+	// and replaces the existing commit with its CVS counterpart through
+	// refs/replace, so the history built on the commit reaches back into CVS
+	GraftReplace = "replace"
+)
+
+// DefaultGraftBranch receives the migrated history in GraftReplace mode
+const DefaultGraftBranch = "cvs"
+
+// GraftConfig grafts the migration onto a commit of an existing target
+// repository, typically a hand-made initial import of the CVS sources
+type GraftConfig struct {
+	Commit string    // Commit or ref holding the import (default: HEAD)
+	Mode   string    // GraftOnto (default) or GraftReplace
+	Date   time.Time // The import holds the CVS changes up to this date (zero = author date of Commit)
+	Branch string    // Branch receiving the history in GraftReplace mode (default: DefaultGraftBranch)
+}
+
+// ReportGraft describes how the migration was grafted onto an existing
+// commit
+type ReportGraft struct {
+	Commit      string    `json:"commit"`
+	Mode        string    `json:"mode"`
+	Date        time.Time `json:"date"`
+	Commits     int       `json:"commits"`               // CVS commits the existing commit holds
+	Replacement string    `json:"replacement,omitempty"` // Commit replacing it in GraftReplace mode
+
+	depth int // Commits in the history of Commit
+}
+
+// graftTarget is implemented by writers that can graft a migration onto an
+// existing commit
+type graftTarget interface {
+	TreeState(rev string) (*git.TreeState, error)
+	StartOrphanBranch(name string) error
+	ResumeOrphanBranch(name string) error
+	CreateReplaceRef(original, replacement string) error
+}
+
+// maxGraftDifferences limits the paths listed when the trees differ
+const maxGraftDifferences = 10
+
+// validateGraft checks the graft settings
+func (m *Migrator) validateGraft() error {
+	g := m.config.Graft
+	if g == nil {
+		return nil
+	}
+	switch g.Mode {
+	case "", GraftOnto, GraftReplace:
+	default:
+		return fmt.Errorf("unsupported graft mode %q (want %s or %s)", g.Mode, GraftOnto, GraftReplace)
+	}
+	if m.config.Simulate {
+		return fmt.Errorf("a simulation cannot graft onto an existing repository")
+	}
+	return nil
+}
+
+// graftMode returns the configured mode, GraftOnto by default
+func (g *GraftConfig) graftMode() string {
+	if g.Mode == "" {
+		return GraftOnto
+	}
+	return g.Mode
+}
+
+// graftBranch returns the branch receiving the history in GraftReplace mode
+func (g *GraftConfig) graftBranch() string {
+	if g.Branch == "" {
+		return DefaultGraftBranch
+	}
+	return g.Branch
+}
+
+// prepareGraft finds the commits the existing commit holds, checks that its
+// tree is the CVS state they produce and readies the target for the
+// remaining history. It returns how many leading commits the graft covers;
+// in GraftOnto mode they are not applied.
+func (m *Migrator) prepareGraft(commits []*vcs.Commit, resuming bool) (int, error) {
+	g := m.config.Graft
+	target, ok := m.target.(graftTarget)
+	if !ok {
+		return 0, fmt.Errorf("target does not support grafting")
+	}
+	rev := g.Commit
+	if rev == "" {
+		rev = "HEAD"
+	}
+	state, err := target.TreeState(rev)
+	if err != nil {
+		return 0, err
+	}
+	date := g.Date
+	if date.IsZero() {
+		date = state.Date
+	}
+	covered := len(commits)
+	for i, c := range commits {
+		if c.Date.After(date) {
+			covered = i
+			break
+		}
+	}
+	mode := g.graftMode()
+	m.report.Graft = &ReportGraft{Commit: state.Hash, Mode: mode, Date: date, Commits: covered, depth: state.Depth}
+	if mode == GraftReplace && covered == 0 {
+		return 0, fmt.Errorf("no CVS changes on or before %s to replace %s with", date.Format(time.RFC3339), state.Hash)
+	}
+
+	// A resumed run has moved past the graft point already; in GraftReplace
+	// mode it goes on with the history on the branch of its own
+	if resuming {
+		if mode == GraftReplace {
+			return covered, target.ResumeOrphanBranch(g.graftBranch())
+		}
+		return covered, nil
+	}
+	expected, err := m.graftTree(commits[:covered])
+	if err != nil {
+		return 0, err
+	}
+	if diff := diffGraftTree(expected, state.Files); len(diff) > 0 {
+		return 0, fmt.Errorf("commit %s does not match the CVS state of %s: %s",
+			state.Hash, date.Format(time.RFC3339), strings.Join(diff, "; "))
+	}
+	m.Logger().Info("graft commit matches the CVS state", "commit", state.Hash, "date", date, "cvs_commits", covered)
+
+	if mode == GraftReplace {
+		return covered, target.StartOrphanBranch(g.graftBranch())
+	}
+	if heads, ok := m.target.(vcs.HeadTracker); ok && heads.HeadHash() != state.Hash {
+		return 0, fmt.Errorf("graft commit %s is not the head of the current branch", state.Hash)
+	}
+	return covered, m.mapGraftedRevisions(commits[:covered], state.Hash)
+}
+
+// graftTree returns the blob hashes of the files the commits leave behind,
+// with keywords and line endings handled as the migration would
+func (m *Migrator) graftTree(commits []*vcs.Commit) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, commit := range commits {
+		c := *commit
+		c.Files = slices.Clone(commit.Files)
+		m.applyKeywords(&c)
+		m.applyEOL(&c)
+		for i := range c.Files {
+			fc := &c.Files[i]
+			if fc.Action == vcs.ActionDelete {
+				delete(files, fc.Path)
+				continue
+			}
+			if fc.Action == vcs.ActionRename {
+				delete(files, fc.OldPath)
+			}
+			hash, err := blobHash(fc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s %s: %w", fc.Path, fc.Revision, err)
+			}
+			files[fc.Path] = hash
+		}
+	}
+	return files, nil
+}
+
+// blobHash returns the Git blob hash of a file change's content
+func blobHash(fc *vcs.FileChange) (string, error) {
+	rc, err := fc.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, content).String(), nil
+}
+
+// diffGraftTree lists how the files of the existing commit differ from the
+// CVS state; the generated .gitattributes is not compared
+func diffGraftTree(expected, actual map[string]string) []string {
+	var diff []string
+	for path, hash := range expected {
+		if other, ok := actual[path]; !ok {
+			diff = append(diff, path+" is missing")
+		} else if other != hash {
+			diff = append(diff, path+" differs")
+		}
+	}
+	for path := range actual {
+		if _, ok := expected[path]; !ok && path != gitattributesPath {
+			diff = append(diff, path+" is not in CVS")
+		}
+	}
+	sort.Strings(diff)
+	if len(diff) > maxGraftDifferences {
+		diff = append(diff[:maxGraftDifferences], fmt.Sprintf("%d more", len(diff)-maxGraftDifferences))
+	}
+	return diff
+}
+
+// mapGraftedRevisions maps the file revisions the existing commit holds to
+// it, so tags and traces of those revisions find a commit
+func (m *Migrator) mapGraftedRevisions(commits []*vcs.Commit, hash string) error {
+	if m.db == nil {
+		return nil
+	}
+	for _, commit := range commits {
+		for _, key := range revisionKeys(sourceRevisionKey(commit), commit)[1:] {
+			if err := m.db.SaveMapping(m.state.migrationID, key, hash); err != nil {
+				return fmt.Errorf("failed to record revision mapping: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// finishGraft replaces the existing commit with the migrated commit of the
+// same CVS state in GraftReplace mode
+func (m *Migrator) finishGraft(commits []*vcs.Commit, covered int) error {
+	g := m.report.Graft
+	if g == nil || g.Mode != GraftReplace {
+		return nil
+	}
+	hash, ok := m.appliedHash(sourceRevisionKey(commits[covered-1]))
+	if !ok {
+		return fmt.Errorf("commit %s was not migrated", commits[covered-1].Revision)
+	}
+	if err := m.target.(graftTarget).CreateReplaceRef(g.Commit, hash); err != nil {
+		return err
+	}
+	g.Replacement = hash
+	m.Logger().Info("replaced graft commit", "commit", g.Commit, "replacement", hash)
+	return nil
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+// writeImport creates a target holding a hand-made import of f.txt with
+// content, dated like the CVS commit 1.2 of integrityTestCommits
+func writeImport(t *testing.T, content string) (string, string) {
+	t.Helper()
+	target := filepath.Join(t.TempDir(), "repo")
+	w := git.NewWriter()
+	require.NoError(t, w.Init(target))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "importer", Date: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		Message: "Initial import", Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte(content)}}}))
+	return target, w.LastCommitHash()
+}
+
+func graftMigrator(t *testing.T, target string, graft *GraftConfig) *Migrator {
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Graft: graft,
+		StateFile: filepath.Join(t.TempDir(), "state.db"), Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+	return m
+}
+
+func TestRun_GraftOnto(t *testing.T) {
+	target, imported := writeImport(t, "1.2\n")
+	m := graftMigrator(t, target, &GraftConfig{})
+	require.NoError(t, m.Run())
+
+	report := m.Report()
+	require.Equal(t, 2, report.Commits.Applied, "1.1 and 1.2 are part of the import")
+	require.Equal(t, imported, report.Graft.Commit)
+	require.Equal(t, GraftOnto, report.Graft.Mode)
+	require.True(t, report.Graft.Date.Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)))
+	require.Equal(t, 2, report.Graft.Commits)
+	require.True(t, report.Verification.Passed, report.Verification.Problems)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "change 1.4", commit.Message)
+	root, err := commit.Parent(0)
+	require.NoError(t, err)
+	root, err = root.Parent(0)
+	require.NoError(t, err)
+	require.Equal(t, imported, root.Hash.String())
+}
+
+func TestRun_GraftMismatch(t *testing.T) {
+	target, imported := writeImport(t, "1.1\n")
+	m := graftMigrator(t, target, &GraftConfig{})
+	err := m.Run()
+	require.ErrorContains(t, err, "commit "+imported+" does not match the CVS state of 2024-01-01T01:00:00Z: f.txt differs")
+
+	// An earlier graft date matches the import
+	m = graftMigrator(t, target, &GraftConfig{Date: time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)})
+	require.NoError(t, m.Run())
+	require.Equal(t, 3, m.Report().Commits.Applied)
+}
+
+func TestRun_GraftReplace(t *testing.T) {
+	target, imported := writeImport(t, "1.2\n")
+	m := graftMigrator(t, target, &GraftConfig{Commit: "master", Mode: GraftReplace})
+	require.NoError(t, m.Run())
+	require.Equal(t, 4, m.Report().Commits.Applied)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	branch, err := repo.Reference(plumbing.NewBranchReferenceName(DefaultGraftBranch), false)
+	require.NoError(t, err)
+	replacement := m.Report().Graft.Replacement
+	commit, err := repo.CommitObject(plumbing.NewHash(replacement))
+	require.NoError(t, err)
+	require.Equal(t, "change 1.2", commit.Message)
+	ref, err := repo.Reference(plumbing.ReferenceName("refs/replace/"+imported), false)
+	require.NoError(t, err)
+	require.Equal(t, replacement, ref.Hash().String())
+	require.NotEqual(t, branch.Hash().String(), imported)
+
+	data, err := os.ReadFile(ReportPath(target) + ReportExtMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "Replaced "+imported+" with "+replacement+", which holds 2 CVS commits up to 2024-01-01 01:00:00")
+}
+
+func TestRun_GraftReplaceResumed(t *testing.T) {
+	target, imported := writeImport(t, "1.2\n")
+	m := graftMigrator(t, target, &GraftConfig{Mode: GraftReplace})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()[:3]}
+	require.NoError(t, m.Run())
+
+	// The resumed run goes on with the branch of its own, in the object store
+	resumed := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Resume: true,
+		Graft: &GraftConfig{Mode: GraftReplace}, StateFile: m.config.StateFile, Logger: logging.Discard()})
+	resumed.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+	require.NoError(t, resumed.Run())
+	require.Equal(t, 1, resumed.Report().Commits.Applied)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+	require.Equal(t, imported, master.Hash().String())
+	branch, err := repo.Reference(plumbing.NewBranchReferenceName(DefaultGraftBranch), false)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(branch.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "change 1.4", commit.Message)
+	parent, err := commit.Parent(0)
+	require.NoError(t, err)
+	require.Equal(t, "change 1.3", parent.Message, "1.4 follows 1.3 on the branch")
+	data, err := os.ReadFile(filepath.Join(target, "f.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "1.2\n", string(data), "the working tree of the import is left alone")
+}
+
+func TestValidateGraft(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{Graft: &GraftConfig{Mode: "merge"}})
+	require.ErrorContains(t, m.validateGraft(), `unsupported graft mode "merge"`)
+	m = NewMigrator(&MigrationConfig{Simulate: true, Graft: &GraftConfig{}})
+	require.ErrorContains(t, m.validateGraft(), "simulation cannot graft")
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// ReportHistory lists the events of CVSROOT/history, such as checkouts and
+// tags, which the converted history does not record otherwise
+type ReportHistory struct {
+	Events    []ReportHistoryEvent `json:"events"`
+	Unmatched int                  `json:"unmatched"` // Commit events whose revision was not migrated
+}
+
+// ReportHistoryEvent is a CVSROOT/history record. Commit events carry the
+// Git commit containing their file revision.
+type ReportHistoryEvent struct {
+	Event    string    `json:"event"`
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Path     string    `json:"path,omitempty"` // File, or directory or module without a file
+	Revision string    `json:"revision,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+}
+
+// historyReader is implemented by sources that can read CVSROOT/history
+type historyReader interface {
+	History() ([]cvs.HistoryRecord, error)
+}
+
+// importHistory adds the CVSROOT/history events of the source to the
+// report, matching commit events to the Git commits of their revisions
+func (m *Migrator) importHistory() {
+	hr, ok := m.source.(historyReader)
+	if !ok {
+		m.warn("source does not provide CVSROOT/history; history events not imported")
+		return
+	}
+	records, err := hr.History()
+	if err != nil {
+		m.warn("failed to import CVSROOT/history", "error", err)
+		return
+	}
+
+	history := &ReportHistory{Events: []ReportHistoryEvent{}}
+	for _, h := range records {
+		event := ReportHistoryEvent{
+			Event:    h.Event(),
+			Time:     h.Time,
+			User:     h.User,
+			Path:     h.Path(),
+			Revision: h.Revision,
+		}
+		if h.IsCommit() {
+			event.Commit = m.lookupFileRevision(h.Path(), h.Revision)
+			if event.Commit == "" {
+				history.Unmatched++
+			}
+		}
+		history.Events = append(history.Events, event)
+	}
+	m.report.History = history
+	m.Logger().Info("imported CVSROOT/history", "events", len(history.Events), "unmatched", history.Unmatched)
+}
+
+// lookupFileRevision returns the Git commit the migration created for a
+// file revision, or "" if it is unknown
+func (m *Migrator) lookupFileRevision(path, revision string) string {
+	if m.db == nil || m.state == nil {
+		return ""
+	}
+	hash, ok, err := m.db.LookupRevision(m.state.migrationID, path+":"+revision)
+	if err != nil || !ok {
+		return ""
+	}
+	return hash
+}
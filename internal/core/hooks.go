@@ -0,0 +1,239 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// ErrSkipCommit is returned by a BeforeCommit hook to leave a commit out of
+// the migration
+var ErrSkipCommit = errors.New("commit skipped by hook")
+
+// CommitHook is invoked around every commit applied to the target. Hooks
+// run after author mapping, in the order they are configured.
+type CommitHook interface {
+	// BeforeCommit may modify the commit before it is applied. Returning
+	// ErrSkipCommit vetoes the commit; any other error aborts the migration.
+	BeforeCommit(commit *vcs.Commit) error
+
+	// AfterCommit is called once the commit has been applied. hash is the
+	// target identifier of the new commit, if the target reports one.
+	AfterCommit(commit *vcs.Commit, hash string) error
+}
+
+// runBeforeHooks runs the BeforeCommit hooks in order, stopping at the first
+// veto or error
+func (m *Migrator) runBeforeHooks(commit *vcs.Commit) error {
+	for _, hook := range m.config.Hooks {
+		if err := hook.BeforeCommit(commit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterHooks runs the AfterCommit hooks in order
+func (m *Migrator) runAfterHooks(commit *vcs.Commit) error {
+	if len(m.config.Hooks) == 0 {
+		return nil
+	}
+	hash := ""
+	if tracker, ok := m.target.(vcs.CommitTracker); ok {
+		hash = tracker.LastCommitHash()
+	}
+	for _, hook := range m.config.Hooks {
+		if err := hook.AfterCommit(commit, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandHook runs external commands around every commit. The commit is
+// written to the standard input of the command as JSON. A BeforeCommit
+// command may print a modified commit in the same format, or an object with
+// "skip": true to veto it; empty output keeps the commit unchanged. A
+// non-zero exit status aborts the migration.
+type CommandHook struct {
+	Before string // Command run before each commit (empty = none)
+	After  string // Command run after each commit (empty = none)
+}
+
+// hookCommit is the JSON form of a commit exchanged with hook commands
+type hookCommit struct {
+	Revision       string     `json:"revision"`
+	Author         string     `json:"author"`
+	Email          string     `json:"email"`
+	Date           time.Time  `json:"date"`
+	Committer      string     `json:"committer,omitempty"`
+	CommitterEmail string     `json:"committerEmail,omitempty"`
+	CommitDate     time.Time  `json:"commitDate,omitzero"`
+	Message        string     `json:"message"`
+	Branch         string     `json:"branch,omitempty"`
+	Files          []hookFile `json:"files"`
+	Hash           string     `json:"hash,omitempty"` // Set for AfterCommit
+	Skip           bool       `json:"skip,omitempty"` // Set by a BeforeCommit command to veto the commit
+}
+
+// hookFile is the JSON form of a file change. Content is base64 encoded.
+type hookFile struct {
+	Path     string `json:"path"`
+	Action   string `json:"action"`            // add, modify, delete, rename or copy
+	OldPath  string `json:"oldPath,omitempty"` // Path renamed or copied from
+	Revision string `json:"revision,omitempty"`
+	Content  []byte `json:"content,omitempty"`
+}
+
+var hookActions = map[vcs.Action]string{
+	vcs.ActionAdd:    "add",
+	vcs.ActionModify: "modify",
+	vcs.ActionDelete: "delete",
+	vcs.ActionRename: "rename",
+	vcs.ActionCopy:   "copy",
+}
+
+// BeforeCommit runs the Before command and applies its changes to commit
+func (h *CommandHook) BeforeCommit(commit *vcs.Commit) error {
+	if h.Before == "" {
+		return nil
+	}
+	input, err := encodeHookCommit(commit, "")
+	if err != nil {
+		return err
+	}
+	output, err := runHookCommand(h.Before, input)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+
+	var result hookCommit
+	if err := json.Unmarshal(output, &result); err != nil {
+		return fmt.Errorf("invalid output of hook %q: %w", h.Before, err)
+	}
+	if result.Skip {
+		return ErrSkipCommit
+	}
+	return decodeHookCommit(&result, commit)
+}
+
+// AfterCommit runs the After command
+func (h *CommandHook) AfterCommit(commit *vcs.Commit, hash string) error {
+	if h.After == "" {
+		return nil
+	}
+	input, err := encodeHookCommit(commit, hash)
+	if err != nil {
+		return err
+	}
+	_, err = runHookCommand(h.After, input)
+	return err
+}
+
+func encodeHookCommit(commit *vcs.Commit, hash string) ([]byte, error) {
+	hc := hookCommit{
+		Revision:       commit.Revision,
+		Author:         commit.Author,
+		Email:          commit.Email,
+		Date:           commit.Date,
+		Committer:      commit.Committer,
+		CommitterEmail: commit.CommitterEmail,
+		CommitDate:     commit.CommitDate,
+		Message:        commit.Message,
+		Branch:         commit.Branch,
+		Files:          make([]hookFile, 0, len(commit.Files)),
+		Hash:           hash,
+	}
+	for i := range commit.Files {
+		fc := &commit.Files[i]
+		hf := hookFile{Path: fc.Path, Action: hookActions[fc.Action], OldPath: fc.OldPath, Revision: fc.Revision}
+		if fc.Action != vcs.ActionDelete {
+			content, err := fc.ReadContent()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", fc.Path, err)
+			}
+			hf.Content = content
+		}
+		hc.Files = append(hc.Files, hf)
+	}
+	return json.Marshal(hc)
+}
+
+// decodeHookCommit copies the fields of a hook result into commit. The
+// revision identifies the source commit and cannot be changed.
+func decodeHookCommit(hc *hookCommit, commit *vcs.Commit) error {
+	files := make([]vcs.FileChange, 0, len(hc.Files))
+	for _, hf := range hc.Files {
+		action, ok := parseHookAction(hf.Action)
+		if !ok {
+			return fmt.Errorf("invalid action %q for %s", hf.Action, hf.Path)
+		}
+		if (action == vcs.ActionRename || action == vcs.ActionCopy) && hf.OldPath == "" {
+			return fmt.Errorf("%s of %s requires an oldPath", hf.Action, hf.Path)
+		}
+		files = append(files, vcs.FileChange{
+			Path:     hf.Path,
+			Action:   action,
+			OldPath:  hf.OldPath,
+			Revision: hf.Revision,
+			Content:  hf.Content,
+		})
+	}
+
+	commit.Author = hc.Author
+	commit.Email = hc.Email
+	commit.Date = hc.Date
+	commit.Committer = hc.Committer
+	commit.CommitterEmail = hc.CommitterEmail
+	commit.CommitDate = hc.CommitDate
+	commit.Message = hc.Message
+	commit.Branch = hc.Branch
+	commit.Files = files
+	return nil
+}
+
+func parseHookAction(name string) (vcs.Action, bool) {
+	for action, n := range hookActions {
+		if n == name {
+			return action, true
+		}
+	}
+	return 0, false
+}
+
+// runHookCommand runs a hook command line through the shell, so quoting,
+// variables and redirections work as they do in a terminal, with input on
+// stdin, and returns its standard output
+func runHookCommand(command string, input []byte) ([]byte, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, nil
+	}
+	args := []string{"/bin/sh", "-c", command}
+	if runtime.GOOS == "windows" {
+		args = []string{"cmd.exe", "/C", command}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec // hook commands come from the user's configuration
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return nil, fmt.Errorf("hook %q failed: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("hook %q failed: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHook vetoes commits whose message contains "skip", rewrites the
+// others and records the hashes passed to AfterCommit
+type recordingHook struct {
+	hashes []string
+}
+
+func (h *recordingHook) BeforeCommit(commit *vcs.Commit) error {
+	if strings.Contains(commit.Message, "skip") {
+		return ErrSkipCommit
+	}
+	commit.Message = "[hooked] " + commit.Message
+	return nil
+}
+
+func (h *recordingHook) AfterCommit(commit *vcs.Commit, hash string) error {
+	h.hashes = append(h.hashes, hash)
+	return nil
+}
+
+func hookTestCommits() []*vcs.Commit {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []*vcs.Commit{
+		{Revision: "1", Author: "alice", Date: date, Message: "first",
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a")}}},
+		{Revision: "2", Author: "alice", Date: date.Add(time.Hour), Message: "please skip",
+			Files: []vcs.FileChange{{Path: "secret.txt", Action: vcs.ActionAdd, Content: []byte("s")}}},
+		{Revision: "3", Author: "alice", Date: date.Add(2 * time.Hour), Message: "third",
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionModify, Content: []byte("b")}}},
+	}
+}
+
+func TestRun_CommitHooks(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	hook := &recordingHook{}
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: target,
+		Hooks:      []CommitHook{hook},
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: hookTestCommits()}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+
+	require.Len(t, hook.hashes, 2)
+	require.Equal(t, head.Hash().String(), hook.hashes[1])
+
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "[hooked] third", tip.Message)
+	tree, err := tip.Tree()
+	require.NoError(t, err)
+	_, err = tree.File("secret.txt")
+	require.Error(t, err, "vetoed commit must not be applied")
+
+	parent, err := tip.Parent(0)
+	require.NoError(t, err)
+	require.Equal(t, "[hooked] first", parent.Message)
+	require.Zero(t, parent.NumParents())
+}
+
+func writeHookScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755))
+	return path
+}
+
+func TestCommandHook(t *testing.T) {
+	dir := t.TempDir()
+	commit := hookTestCommits()[0]
+	commit.Files = append(commit.Files, vcs.FileChange{Path: "old.txt", Action: vcs.ActionDelete, Revision: "1.2"},
+		vcs.FileChange{Path: "new.txt", OldPath: "moved.txt", Action: vcs.ActionRename, Content: []byte("moved\n")})
+
+	// Echoing the input back leaves the commit unchanged
+	hook := &CommandHook{Before: writeHookScript(t, dir, "echo.sh", "cat\n")}
+	before := *commit
+	require.NoError(t, hook.BeforeCommit(commit))
+	require.Equal(t, before.Message, commit.Message)
+	require.True(t, before.Date.Equal(commit.Date))
+	require.Equal(t, before.Files, commit.Files)
+
+	// Replacing the commit
+	replaced := `{"author":"bob","email":"bob@example.com","date":"2024-02-01T00:00:00Z","message":"scrubbed",` +
+		`"files":[{"path":"a.txt","action":"modify","content":"` + "Yg==" + `"}]}`
+	hook = &CommandHook{Before: writeHookScript(t, dir, "replace.sh", "cat >/dev/null\necho '"+replaced+"'\n")}
+	require.NoError(t, hook.BeforeCommit(commit))
+	require.Equal(t, "1", commit.Revision)
+	require.Equal(t, "bob", commit.Author)
+	require.Equal(t, "scrubbed", commit.Message)
+	require.Equal(t, []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionModify, Content: []byte("b")}}, commit.Files)
+
+	// Vetoing the commit
+	hook = &CommandHook{Before: writeHookScript(t, dir, "skip.sh", "cat >/dev/null\necho '{\"skip\": true}'\n")}
+	require.ErrorIs(t, hook.BeforeCommit(commit), ErrSkipCommit)
+
+	// The after hook receives the commit and its hash
+	out := filepath.Join(dir, "after.json")
+	hook = &CommandHook{After: writeHookScript(t, dir, "after.sh", "cat > \"$1\"\n") + " " + out}
+	require.NoError(t, hook.AfterCommit(commit, "abc123"))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var got hookCommit
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, "abc123", got.Hash)
+	require.Equal(t, "scrubbed", got.Message)
+
+	// Commands run through the shell, so quoted arguments keep their spaces
+	spaced := filepath.Join(dir, "with space")
+	require.NoError(t, os.Mkdir(spaced, 0755))
+	out = filepath.Join(spaced, "after.json")
+	hook = &CommandHook{After: writeHookScript(t, dir, "after.sh", "cat > \"$1\"\n") + " '" + out + "'"}
+	require.NoError(t, hook.AfterCommit(commit, "abc123"))
+	require.FileExists(t, out)
+}
+
+func TestCommandHook_Errors(t *testing.T) {
+	dir := t.TempDir()
+	commit := hookTestCommits()[0]
+
+	hook := &CommandHook{Before: writeHookScript(t, dir, "fail.sh", "echo 'secret found' >&2\nexit 1\n")}
+	err := hook.BeforeCommit(commit)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "secret found")
+
+	hook = &CommandHook{Before: writeHookScript(t, dir, "garbage.sh", "echo 'not json'\n")}
+	require.Error(t, hook.BeforeCommit(commit))
+
+	hook = &CommandHook{Before: writeHookScript(t, dir, "action.sh", "echo '{\"files\":[{\"path\":\"x\",\"action\":\"rename\"}]}'\n")}
+	require.Error(t, hook.BeforeCommit(commit))
+
+	// A failing hook aborts the migration
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Hooks:      []CommitHook{&CommandHook{After: filepath.Join(dir, "fail.sh")}},
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: hookTestCommits()}
+	err = m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "post-commit hook failed")
+}
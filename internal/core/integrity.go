@@ -0,0 +1,46 @@
+package core
+
+import "fmt"
+
+// ErrTargetModified is returned by Run when the integrity check finds that
+// the target repository was changed outside the migration
+var ErrTargetModified error = &kindError{msg: "target repository was modified outside the migration", kind: ErrConflict}
+
+// integrityChecker is implemented by writers that can verify the commits
+// recorded in the revision map against the repository
+type integrityChecker interface {
+	CheckIntegrity(hashes []string, head string) error
+}
+
+// checkIntegrity verifies that the target still contains every commit in
+// the revision map and that its HEAD is the commit mapped last. A failure
+// means another process rewrote or reset the target during the migration,
+// which no error policy can recover from.
+func (m *Migrator) checkIntegrity() error {
+	checker, ok := m.target.(integrityChecker)
+	if m.db == nil || !ok {
+		return nil
+	}
+	mappings, err := m.db.RevisionMappings(m.state.migrationID)
+	if err != nil {
+		return fmt.Errorf("failed to read revision mappings: %w", err)
+	}
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(mappings))
+	var hashes []string
+	for _, mapping := range mappings {
+		if !seen[mapping.GitHash] {
+			seen[mapping.GitHash] = true
+			hashes = append(hashes, mapping.GitHash)
+		}
+	}
+	head := mappings[len(mappings)-1].GitHash
+	if err := checker.CheckIntegrity(hashes, head); err != nil {
+		return fmt.Errorf("%w: %v; restore the target or roll back to a checkpoint before resuming", ErrTargetModified, err)
+	}
+	m.Logger().Debug("verified target integrity", "commits", len(hashes), "head", head)
+	return nil
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+// resetHook moves the target's branch back to the first commit after the
+// commit with revision at, as "git reset --hard" run by someone else would
+type resetHook struct {
+	target string
+	at     string
+	first  string
+}
+
+func (h *resetHook) BeforeCommit(*vcs.Commit) error { return nil }
+
+func (h *resetHook) AfterCommit(commit *vcs.Commit, hash string) error {
+	if h.first == "" {
+		h.first = hash
+	}
+	if commit.Revision != h.at {
+		return nil
+	}
+	repo, err := gogit.PlainOpen(h.target)
+	if err != nil {
+		return err
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return err
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(head.Target(), plumbing.NewHash(h.first)))
+}
+
+func integrityTestCommits() []*vcs.Commit {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var commits []*vcs.Commit
+	for i, rev := range []string{"1.1", "1.2", "1.3", "1.4"} {
+		commits = append(commits, &vcs.Commit{Revision: rev, Author: "alice", Date: date.Add(time.Duration(i) * time.Hour), Message: "change " + rev,
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Revision: rev, Content: []byte(rev + "\n")}}})
+	}
+	return commits
+}
+
+func TestRun_VerifyEvery(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: filepath.Join(t.TempDir(), "repo"),
+		VerifyEvery: 1, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+	require.NoError(t, m.Run())
+	require.Equal(t, 4, m.Report().Commits.Applied)
+}
+
+func TestRun_VerifyEveryDetectsReset(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target,
+		VerifyEvery: 2, ErrorPolicy: ErrorPolicyContinue, Logger: logging.Discard(),
+		Hooks: []CommitHook{&resetHook{target: target, at: "1.2"}}})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+
+	err := m.Run()
+	require.ErrorIs(t, err, ErrTargetModified)
+	require.ErrorContains(t, err, "HEAD is ")
+	require.Equal(t, 2, m.Report().Commits.Applied, "the migration stops at the check")
+}
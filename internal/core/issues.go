@@ -0,0 +1,211 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// Error policies decide which failures abort a migration
+const (
+	// ErrorPolicyDefault aborts on commit failures and records branch and
+	// tag failures as warnings
+	ErrorPolicyDefault = ""
+	// ErrorPolicyFailFast aborts on the first failure of any kind
+	ErrorPolicyFailFast = "fail-fast"
+	// ErrorPolicyContinue records commit, hook and ref failures as errors
+	// and carries on with the next commit
+	ErrorPolicyContinue = "continue-on-error"
+)
+
+// Issue severities
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Issue is a non-fatal problem recorded during a migration
+type Issue struct {
+	Severity string    `json:"severity"`
+	Phase    string    `json:"phase,omitempty"`
+	Subject  string    `json:"subject,omitempty"` // Revision, branch or tag the issue is about
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+}
+
+// validateErrorPolicy checks the configured error policy
+func (m *Migrator) validateErrorPolicy() error {
+	switch m.config.ErrorPolicy {
+	case ErrorPolicyDefault, ErrorPolicyFailFast, ErrorPolicyContinue:
+		return nil
+	default:
+		return fmt.Errorf("unsupported error policy: %s", m.config.ErrorPolicy)
+	}
+}
+
+// Issues returns the issues recorded by the migration, including those of
+// the runs it resumed
+func (m *Migrator) Issues() []Issue {
+	return m.issues
+}
+
+// warn logs a non-fatal problem and records it as a warning
+func (m *Migrator) warn(msg string, args ...any) {
+	m.Logger().Warn(msg, args...)
+	m.recordIssue(SeverityWarning, msg, args...)
+}
+
+// diagnosticsReader is implemented by sources reporting anomalies of the
+// files they parsed
+type diagnosticsReader interface {
+	Diagnostics() []cvs.Diagnostic
+}
+
+// warnDiagnostics records a warning for every source file the parser had
+// to recover from malformed input in
+func (m *Migrator) warnDiagnostics() {
+	dr, ok := m.source.(diagnosticsReader)
+	if !ok {
+		return
+	}
+	var files []string
+	first := make(map[string]cvs.Diagnostic)
+	errors := make(map[string]int)
+	for _, d := range dr.Diagnostics() {
+		if d.Severity != cvs.SeverityError {
+			continue
+		}
+		if errors[d.File] == 0 {
+			files = append(files, d.File)
+			first[d.File] = d
+		}
+		errors[d.File]++
+	}
+	for _, file := range files {
+		m.warn("malformed RCS file needs attention", "file", file, "errors", errors[file], "first", first[file].String())
+	}
+}
+
+// skippedFilesReader is implemented by sources that leave unreadable files
+// out of the history instead of failing
+type skippedFilesReader interface {
+	SkippedFiles() []cvs.SkippedFile
+}
+
+// warnSkippedFiles reports the source files left out of the history
+func (m *Migrator) warnSkippedFiles() {
+	sr, ok := m.source.(skippedFilesReader)
+	if !ok {
+		return
+	}
+	report := m.currentReport()
+	for _, file := range sr.SkippedFiles() {
+		m.warn("skipped unreadable RCS file; its history is not migrated", "file", file.Path, "error", file.Err)
+		report.SkippedFiles = append(report.SkippedFiles, ReportSkippedFile{Path: file.Path, Error: file.Err.Error()})
+	}
+}
+
+// fail logs a failure the error policy tolerates and records it as an error
+func (m *Migrator) fail(msg string, args ...any) {
+	m.Logger().Error(msg, args...)
+	m.recordIssue(SeverityError, msg, args...)
+}
+
+// refFailure handles a branch or tag that could not be created. Only
+// ErrorPolicyFailFast makes it fatal.
+func (m *Migrator) refFailure(kind, name string, err error) error {
+	switch m.config.ErrorPolicy {
+	case ErrorPolicyFailFast:
+		return fmt.Errorf("failed to create %s %s: %w", kind, name, err)
+	case ErrorPolicyContinue:
+		m.fail("failed to create "+kind, kind, name, "error", err)
+	default:
+		m.warn("failed to create "+kind, kind, name, "error", err)
+	}
+	return nil
+}
+
+// continuesOnError reports whether commit failures are recorded instead of
+// aborting the migration
+func (m *Migrator) continuesOnError() bool {
+	return m.config.ErrorPolicy == ErrorPolicyContinue
+}
+
+// recordIssue adds an issue to the report and the state database. The first
+// argument value names the subject, e.g. the branch of "branch", name.
+func (m *Migrator) recordIssue(severity, msg string, args ...any) {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	issue := Issue{
+		Severity: severity,
+		Phase:    string(m.reporter.Phase()),
+		Message:  b.String(),
+		Time:     time.Now(),
+	}
+	if len(args) > 1 {
+		issue.Subject = fmt.Sprint(args[1])
+	}
+	m.issues = append(m.issues, issue)
+
+	report := m.currentReport()
+	if severity == SeverityError {
+		report.Errors = append(report.Errors, issue.Message)
+	} else {
+		report.Warnings = append(report.Warnings, issue.Message)
+	}
+
+	if m.db == nil || m.state == nil {
+		return
+	}
+	if err := m.db.SaveIssue(&storage.MigrationIssue{
+		MigrationID: m.state.migrationID,
+		Severity:    issue.Severity,
+		Phase:       issue.Phase,
+		Subject:     issue.Subject,
+		Message:     issue.Message,
+		CreatedAt:   issue.Time,
+	}); err != nil {
+		m.Logger().Warn("failed to save issue", "error", err)
+	}
+}
+
+// loadIssues restores the issues recorded by the runs a resumed migration
+// continues, or clears them when the migration starts over
+func (m *Migrator) loadIssues() error {
+	m.issues = nil
+	if !m.config.Resume {
+		return m.db.DeleteIssues(m.state.migrationID)
+	}
+	saved, err := m.db.LoadIssues(m.state.migrationID)
+	if err != nil {
+		return err
+	}
+	for _, s := range saved {
+		m.issues = append(m.issues, Issue{
+			Severity: s.Severity,
+			Phase:    s.Phase,
+			Subject:  s.Subject,
+			Message:  s.Message,
+			Time:     s.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// CountIssues returns the number of warnings and errors among issues
+func CountIssues(issues []Issue) (warnings, errors int) {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return warnings, errors
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+// issueTestSource has an unreadable second commit and a tag on a missing
+// revision
+func issueTestSource() *mockReaderWithRefs {
+	commits := hookTestCommits()
+	commits[1].Message = "corrupt"
+	commits[1].Files = []vcs.FileChange{{Path: "bad.txt", Action: vcs.ActionAdd,
+		Source: func() (io.ReadCloser, error) { return nil, errors.New("corrupt revision") }}}
+	return &mockReaderWithRefs{
+		mockReaderWithCommits: mockReaderWithCommits{commits: commits},
+		branches:              []string{"dev"},
+		tags:                  map[string]string{"BROKEN": "no-such-revision"},
+	}
+}
+
+func issueTestConfig(t *testing.T, policy string) *MigrationConfig {
+	return &MigrationConfig{
+		SourceType:  "cvs",
+		SourcePath:  "/src",
+		TargetPath:  filepath.Join(t.TempDir(), "repo"),
+		ErrorPolicy: policy,
+		Logger:      logging.Discard(),
+	}
+}
+
+func TestRun_ErrorPolicyDefault(t *testing.T) {
+	m := NewMigrator(issueTestConfig(t, ErrorPolicyDefault))
+	m.source = issueTestSource()
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to apply commit 2")
+	require.Empty(t, m.Issues())
+}
+
+func TestRun_ErrorPolicyContinue(t *testing.T) {
+	cfg := issueTestConfig(t, ErrorPolicyContinue)
+	m := NewMigrator(cfg)
+	m.source = issueTestSource()
+	require.NoError(t, m.Run())
+
+	report := m.Report()
+	require.Equal(t, 2, report.Commits.Applied)
+	require.Equal(t, 1, report.Commits.Failed)
+	require.Len(t, report.Errors, 2)
+	require.Contains(t, report.Errors[0], "failed to apply commit revision=2")
+	require.Contains(t, report.Errors[1], "failed to create tag tag=BROKEN")
+	require.Equal(t, []string{"dev"}, report.Branches.Created)
+	require.True(t, report.Verification.Passed, report.Verification.Problems)
+
+	issues := m.Issues()
+	require.Len(t, issues, 2)
+	require.Equal(t, SeverityError, issues[0].Severity)
+	require.Equal(t, "2", issues[0].Subject)
+	require.Equal(t, "apply_commits", issues[0].Phase)
+	require.Equal(t, "BROKEN", issues[1].Subject)
+	warnings, errs := CountIssues(issues)
+	require.Equal(t, 0, warnings)
+	require.Equal(t, 2, errs)
+
+	// Issues are kept in the state database for resumed runs and cleared
+	// when the migration starts over
+	cfg.Resume = true
+	m = NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: hookTestCommits()}
+	require.NoError(t, m.Run())
+	require.Len(t, m.Issues(), 2)
+	require.Empty(t, m.Report().Errors)
+
+	cfg.Resume = false
+	m = NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: hookTestCommits()}
+	require.NoError(t, m.Run())
+	require.Empty(t, m.Issues())
+}
+
+func TestRun_ErrorPolicyFailFast(t *testing.T) {
+	m := NewMigrator(issueTestConfig(t, ErrorPolicyFailFast))
+	source := issueTestSource()
+	source.commits = hookTestCommits()
+	m.source = source
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to create tag BROKEN")
+	require.Equal(t, "failed", m.Report().Status)
+}
+
+func TestRun_ErrorPolicyInvalid(t *testing.T) {
+	m := NewMigrator(issueTestConfig(t, "sometimes"))
+	m.source = &mockReaderWithCommits{}
+	require.ErrorContains(t, m.Run(), "unsupported error policy")
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// JoinModule places a CVS module in a subdirectory of the target repository
+type JoinModule struct {
+	Module string // CVS module below SourcePath
+	Path   string // Subdirectory in the target ("" = Module)
+}
+
+// validateJoinModules checks JoinModules and fills in default paths
+func (m *Migrator) validateJoinModules() error {
+	if len(m.config.JoinModules) == 0 {
+		return nil
+	}
+	if m.config.SourceModule != "" {
+		return fmt.Errorf("a source module cannot be combined with joined modules")
+	}
+	paths := make(map[string]bool)
+	for i := range m.config.JoinModules {
+		join := &m.config.JoinModules[i]
+		if join.Module == "" {
+			return fmt.Errorf("joined module names must not be empty")
+		}
+		if join.Path = strings.Trim(join.Path, "/"); join.Path == "" {
+			join.Path = strings.Trim(join.Module, "/")
+		}
+		if paths[join.Path] {
+			return fmt.Errorf("more than one module is joined into %s", join.Path)
+		}
+		paths[join.Path] = true
+	}
+	return nil
+}
+
+// newJoinReader returns a reader over the CVS modules of JoinModules
+func (m *Migrator) newJoinReader() *joinReader {
+	r := &joinReader{}
+	for _, join := range m.config.JoinModules {
+		reader := cvs.NewModuleReader(m.config.SourcePath, join.Module)
+		reader.SetContentCache(m.contentCache)
+		reader.SetTextBudget(m.textBudget)
+		reader.SetProfile(m.config.Profile)
+		reader.SetStrict(m.config.StrictParsing)
+		reader.SetParseWorkers(m.config.ParseWorkers)
+		reader.SetReadLimit(m.ioLimit())
+		reader.SetClient(m.cvsClient())
+		r.parts = append(r.parts, joinPart{prefix: join.Path, reader: reader})
+	}
+	return r
+}
+
+// joinPart is a module read into a subdirectory
+type joinPart struct {
+	prefix string
+	reader vcs.VCSReader
+}
+
+// joinReader presents several modules as one source. File paths are
+// prefixed with the module's subdirectory and commit revisions with its
+// path, so revisions of different modules never collide; the migrator's
+// commit ordering interleaves the modules by date. Branches of the same
+// name are merged, and a tag found in several modules points at the newest
+// tagged revision among them.
+type joinReader struct {
+	parts []joinPart
+}
+
+func (r *joinReader) Validate() error {
+	for _, part := range r.parts {
+		if err := part.reader.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", part.prefix, err)
+		}
+	}
+	return nil
+}
+
+func (r *joinReader) GetCommits() (vcs.CommitIterator, error) {
+	var commits []*vcs.Commit
+	for _, part := range r.parts {
+		iter, err := part.reader.GetCommits()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part.prefix, err)
+		}
+		for iter.Next() {
+			c := iter.Commit()
+			c.Revision = part.prefix + ":" + c.Revision
+			for i := range c.Files {
+				c.Files[i].Path = part.prefix + "/" + c.Files[i].Path
+				if c.Files[i].OldPath != "" {
+					c.Files[i].OldPath = part.prefix + "/" + c.Files[i].OldPath
+				}
+			}
+			for i, parent := range c.Parents {
+				c.Parents[i] = part.prefix + ":" + parent
+			}
+			commits = append(commits, c)
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", part.prefix, err)
+		}
+	}
+	return &sliceIterator{commits: commits, index: -1}, nil
+}
+
+func (r *joinReader) GetBranches() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, part := range r.parts {
+		branches, err := part.reader.GetBranches()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part.prefix, err)
+		}
+		for _, branch := range branches {
+			seen[branch] = true
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+func (r *joinReader) GetTags() (map[string]string, error) {
+	details, err := r.GetTagDetails()
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(details))
+	for name, info := range details {
+		tags[name] = info.Revision
+	}
+	return tags, nil
+}
+
+// GetTagDetails merges the tags of all modules. Modules whose reader cannot
+// describe its tags contribute the tag name and revision only.
+func (r *joinReader) GetTagDetails() (map[string]vcs.TagInfo, error) {
+	merged := make(map[string]vcs.TagInfo)
+	for _, part := range r.parts {
+		var details map[string]vcs.TagInfo
+		if detailer, ok := part.reader.(vcs.TagDetailer); ok {
+			var err error
+			if details, err = detailer.GetTagDetails(); err != nil {
+				return nil, fmt.Errorf("%s: %w", part.prefix, err)
+			}
+		} else {
+			tags, err := part.reader.GetTags()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", part.prefix, err)
+			}
+			details = make(map[string]vcs.TagInfo, len(tags))
+			for name, rev := range tags {
+				details[name] = vcs.TagInfo{Name: name, Revision: part.prefix + ":" + rev}
+			}
+		}
+
+		for name, info := range details {
+			if info.Path != "" {
+				info.Path = part.prefix + "/" + info.Path
+			}
+			if old, ok := merged[name]; ok && !info.Date.After(old.Date) {
+				continue
+			}
+			merged[name] = info
+		}
+	}
+	return merged, nil
+}
+
+// SetContext stops the following history reads of all modules early once
+// ctx is done
+func (r *joinReader) SetContext(ctx context.Context) {
+	for _, part := range r.parts {
+		if setter, ok := part.reader.(vcs.ContextSetter); ok {
+			setter.SetContext(ctx)
+		}
+	}
+}
+
+// Diagnostics returns the RCS parse anomalies of all modules
+func (r *joinReader) Diagnostics() []cvs.Diagnostic {
+	var diags []cvs.Diagnostic
+	for _, part := range r.parts {
+		if dr, ok := part.reader.(diagnosticsReader); ok {
+			diags = append(diags, dr.Diagnostics()...)
+		}
+	}
+	return diags
+}
+
+// SkippedFiles returns the unreadable RCS files of all modules
+func (r *joinReader) SkippedFiles() []cvs.SkippedFile {
+	var skipped []cvs.SkippedFile
+	for _, part := range r.parts {
+		if sr, ok := part.reader.(skippedFilesReader); ok {
+			skipped = append(skipped, sr.SkippedFiles()...)
+		}
+	}
+	return skipped
+}
+
+// History returns the CVSROOT/history records of all modules, with
+// repository paths prefixed like the file paths
+func (r *joinReader) History() ([]cvs.HistoryRecord, error) {
+	var records []cvs.HistoryRecord
+	for _, part := range r.parts {
+		hr, ok := part.reader.(historyReader)
+		if !ok {
+			continue
+		}
+		history, err := hr.History()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part.prefix, err)
+		}
+		for _, h := range history {
+			h.Repository = path.Join(part.prefix, h.Repository)
+			records = append(records, h)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+func (r *joinReader) Close() error {
+	var first error
+	for _, part := range r.parts {
+		if err := part.reader.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// joinedModules returns the modules of JoinModules, sorted, for the
+// migration ID
+func (m *Migrator) joinedModules() string {
+	modules := make([]string, 0, len(m.config.JoinModules))
+	for _, join := range m.config.JoinModules {
+		modules = append(modules, join.Module)
+	}
+	sort.Strings(modules)
+	return strings.Join(modules, ",")
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+// tagMockReader is a mock source with branches and detailed tags
+type tagMockReader struct {
+	mockReaderWithCommits
+	branches []string
+	details  map[string]vcs.TagInfo
+}
+
+func (r *tagMockReader) GetBranches() ([]string, error) { return r.branches, nil }
+func (r *tagMockReader) GetTags() (map[string]string, error) {
+	tags := make(map[string]string)
+	for name, info := range r.details {
+		tags[name] = info.Revision
+	}
+	return tags, nil
+}
+func (r *tagMockReader) GetTagDetails() (map[string]vcs.TagInfo, error) { return r.details, nil }
+
+func TestJoinReader(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(path string) vcs.FileChange {
+		return vcs.FileChange{Path: path, Action: vcs.ActionAdd, Revision: "1.1", Content: []byte(path + "\n")}
+	}
+	reader := &joinReader{parts: []joinPart{
+		{prefix: "libs/foo", reader: &tagMockReader{
+			mockReaderWithCommits: mockReaderWithCommits{commits: []*vcs.Commit{
+				{Revision: "1.1", Author: "alice", Date: date, Message: "foo", Files: []vcs.FileChange{add("foo.c")}},
+			}},
+			branches: []string{"STABLE"},
+			details:  map[string]vcs.TagInfo{"REL_1": {Name: "REL_1", Path: "foo.c", Revision: "1.1", Date: date}},
+		}},
+		{prefix: "bar", reader: &tagMockReader{
+			mockReaderWithCommits: mockReaderWithCommits{commits: []*vcs.Commit{
+				{Revision: "1.1", Author: "bob", Date: date.Add(time.Hour), Message: "bar", Files: []vcs.FileChange{add("bar.c")}},
+			}},
+			branches: []string{"DEV", "STABLE"},
+			details:  map[string]vcs.TagInfo{"REL_1": {Name: "REL_1", Path: "bar.c", Revision: "1.1", Date: date.Add(time.Hour)}},
+		}},
+	}}
+
+	iter, err := reader.GetCommits()
+	require.NoError(t, err)
+	var revs, paths []string
+	for iter.Next() {
+		revs = append(revs, iter.Commit().Revision)
+		paths = append(paths, iter.Commit().Files[0].Path)
+	}
+	require.Equal(t, []string{"libs/foo:1.1", "bar:1.1"}, revs)
+	require.Equal(t, []string{"libs/foo/foo.c", "bar/bar.c"}, paths)
+
+	branches, err := reader.GetBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"DEV", "STABLE"}, branches)
+
+	details, err := reader.GetTagDetails()
+	require.NoError(t, err)
+	require.Equal(t, "bar/bar.c", details["REL_1"].Path, "the newest tagged revision wins")
+}
+
+func TestValidateJoinModules(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{JoinModules: []JoinModule{{Module: "foo"}, {Module: "bar", Path: "/libs/bar/"}}})
+	require.NoError(t, m.validateJoinModules())
+	require.Equal(t, []JoinModule{{Module: "foo", Path: "foo"}, {Module: "bar", Path: "libs/bar"}}, m.config.JoinModules)
+
+	require.Error(t, NewMigrator(&MigrationConfig{JoinModules: []JoinModule{{Module: ""}}}).validateJoinModules())
+	require.Error(t, NewMigrator(&MigrationConfig{JoinModules: []JoinModule{{Module: "a", Path: "x"}, {Module: "b", Path: "x"}}}).validateJoinModules())
+	require.Error(t, NewMigrator(&MigrationConfig{SourceModule: "a", JoinModules: []JoinModule{{Module: "b"}}}).validateJoinModules())
+}
+
+func TestRun_JoinModules(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	change := func(path, rev string) vcs.FileChange {
+		return vcs.FileChange{Path: path, Action: vcs.ActionModify, Revision: rev, Content: []byte(path + " " + rev + "\n")}
+	}
+	foo := &mockReaderWithCommits{commits: []*vcs.Commit{
+		{Revision: "1.1", Author: "alice", Date: date, Message: "foo 1", Files: []vcs.FileChange{change("a.txt", "1.1")}},
+		{Revision: "1.2", Author: "alice", Date: date.Add(2 * time.Hour), Message: "foo 2", Files: []vcs.FileChange{change("a.txt", "1.2")}},
+	}}
+	bar := &mockReaderWithCommits{commits: []*vcs.Commit{
+		{Revision: "1.1", Author: "bob", Date: date.Add(time.Hour), Message: "bar 1", Files: []vcs.FileChange{change("a.txt", "1.1")}},
+	}}
+
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType:  "cvs",
+		SourcePath:  "/src",
+		TargetPath:  target,
+		JoinModules: []JoinModule{{Module: "foo"}, {Module: "bar"}},
+		Logger:      logging.Discard(),
+	})
+	m.source = &joinReader{parts: []joinPart{{prefix: "foo", reader: foo}, {prefix: "bar", reader: bar}}}
+	require.NoError(t, m.Run())
+	require.Equal(t, 3, m.Report().Commits.Applied)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	log, err := repo.Log(&gogit.LogOptions{})
+	require.NoError(t, err)
+	var messages []string
+	require.NoError(t, log.ForEach(func(c *object.Commit) error {
+		messages = append(messages, c.Message)
+		return nil
+	}))
+	require.Equal(t, []string{"foo 2", "bar 1", "foo 1"}, messages, "the modules are interleaved by date")
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "a.txt 1.2\n", readTreeFile(t, tree, "foo/a.txt"))
+	require.Equal(t, "a.txt 1.1\n", readTreeFile(t, tree, "bar/a.txt"))
+}
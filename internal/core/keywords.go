@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// Keyword modes of a KeywordRule
+const (
+	// KeywordsKeep keeps RCS keywords as stored in the RCS file, with the
+	// values of the revision before
+	KeywordsKeep = "keep"
+	// KeywordsStrip collapses RCS keywords to their names, e.g. "$Id$"
+	KeywordsStrip = "strip"
+	// KeywordsExpand expands RCS keywords for the revision, as cvs checkout
+	// does
+	KeywordsExpand = "expand"
+)
+
+// KeywordRule selects the RCS keyword handling of the files matching
+// Pattern, a path.Match pattern that matches the file name if it contains
+// no "/" and the whole path otherwise, as in .gitattributes
+type KeywordRule struct {
+	Pattern string
+	Mode    string // KeywordsKeep, KeywordsStrip or KeywordsExpand
+}
+
+// matches reports whether the rule applies to file
+func (r KeywordRule) matches(file string) bool {
+	return pathMatches(r.Pattern, file)
+}
+
+// pathMatches reports whether a path.Match pattern matches file, comparing
+// the file name if the pattern contains no "/" and the whole path otherwise
+func pathMatches(pattern, file string) bool {
+	name := file
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(file)
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), name)
+	return ok
+}
+
+// validateKeywords checks the keyword rules
+func (m *Migrator) validateKeywords() error {
+	for _, rule := range m.config.Keywords {
+		if _, err := path.Match(rule.Pattern, ""); err != nil || rule.Pattern == "" {
+			return fmt.Errorf("invalid keyword pattern %q", rule.Pattern)
+		}
+		switch rule.Mode {
+		case KeywordsKeep, KeywordsStrip, KeywordsExpand:
+		default:
+			return fmt.Errorf("unsupported keyword mode %q for %s", rule.Mode, rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// keywordMode returns the mode of the first rule matching file
+func (m *Migrator) keywordMode(file string) string {
+	for _, rule := range m.config.Keywords {
+		if rule.matches(file) {
+			return rule.Mode
+		}
+	}
+	return KeywordsKeep
+}
+
+// applyKeywords rewrites the RCS keywords of the files of a commit by the
+// keyword rules. It must run before the author and date are mapped, which
+// the expansions record as CVS did.
+func (m *Migrator) applyKeywords(commit *vcs.Commit) {
+	if len(m.config.Keywords) == 0 {
+		return
+	}
+	for i := range commit.Files {
+		fc := &commit.Files[i]
+		if fc.Action == vcs.ActionDelete || fc.Binary || fc.Keywords == "o" {
+			continue
+		}
+		var mode string
+		switch m.keywordMode(fc.Path) {
+		case KeywordsStrip:
+			mode = "kk"
+		case KeywordsExpand:
+			mode = fc.Keywords // The file's own mode, e.g. "kvl"
+		default:
+			continue
+		}
+		info := cvs.KeywordInfo{
+			RCSFile:  path.Base(fc.Path) + ",v",
+			Source:   path.Join(m.config.SourcePath, m.config.SourceModule, fc.Path) + ",v",
+			Revision: fc.Revision,
+			Date:     commit.Date,
+			Author:   commit.Author,
+			State:    "Exp",
+		}
+		transformContent(fc, func(content []byte) []byte {
+			if isBinary(content) {
+				return content
+			}
+			return cvs.ExpandKeywords(content, mode, info)
+		})
+	}
+}
+
+// transformContent applies fn to the content of a file change, lazily if
+// the content is read from a source
+func transformContent(fc *vcs.FileChange, fn func([]byte) []byte) {
+	if fc.Source == nil {
+		fc.Content = fn(fc.Content)
+		return
+	}
+	source := fc.Source
+	fc.Source = func() (io.ReadCloser, error) {
+		rc, err := source()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		if closeErr := rc.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(fn(data))), nil
+	}
+}
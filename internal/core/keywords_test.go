@@ -0,0 +1,80 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordRuleMatches(t *testing.T) {
+	require.True(t, KeywordRule{Pattern: "*.c"}.matches("src/main.c"))
+	require.False(t, KeywordRule{Pattern: "*.c"}.matches("src/main.h"))
+	require.True(t, KeywordRule{Pattern: "src/*.c"}.matches("src/main.c"))
+	require.True(t, KeywordRule{Pattern: "/src/*.c"}.matches("src/main.c"))
+	require.False(t, KeywordRule{Pattern: "src/*.c"}.matches("lib/src/main.c"))
+}
+
+func TestValidateKeywords(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{Keywords: []KeywordRule{{Pattern: "*.c", Mode: KeywordsExpand}}})
+	require.NoError(t, m.validateKeywords())
+	m.config.Keywords = []KeywordRule{{Pattern: "*.c", Mode: "expand-all"}}
+	require.Error(t, m.validateKeywords())
+	m.config.Keywords = []KeywordRule{{Pattern: "[", Mode: KeywordsStrip}}
+	require.Error(t, m.validateKeywords())
+}
+
+func TestRun_KeywordRules(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	id := []byte("/* $Id: old.c,v 1.1 2023/01/01 00:00:00 bob Exp $ */\n")
+	commits := []*vcs.Commit{
+		{Revision: "1.2", Author: "alice", Date: date, Message: "change", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionModify, Revision: "1.2", Content: id},
+			{Path: "src/gen/table.c", Action: vcs.ActionModify, Revision: "1.2", Content: id},
+			{Path: "raw.c", Action: vcs.ActionModify, Revision: "1.2", Content: id, Keywords: "o"},
+			{Path: "README", Action: vcs.ActionModify, Revision: "1.2", Content: id},
+			{Path: "notes.txt", Action: vcs.ActionModify, Revision: "1.2", Content: id},
+		}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/cvsroot",
+		TargetPath: target,
+		AuthorMap:  map[string]string{"alice": "Alice Smith <alice@example.com>"},
+		Keywords: []KeywordRule{
+			{Pattern: "src/gen/*.c", Mode: KeywordsStrip},
+			{Pattern: "*.c", Mode: KeywordsExpand},
+			{Pattern: "README", Mode: KeywordsStrip},
+		},
+		Logger: logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := tip.Tree()
+	require.NoError(t, err)
+
+	// Expansions record the CVS user, not the mapped author
+	require.Equal(t, "/* $Id: main.c,v 1.2 2024/01/02 03:04:05 alice Exp $ */\n", readTreeFile(t, tree, "src/main.c"))
+	require.Equal(t, "/* $Id$ */\n", readTreeFile(t, tree, "src/gen/table.c"))
+	require.Equal(t, string(id), readTreeFile(t, tree, "raw.c"))
+	require.Equal(t, "/* $Id$ */\n", readTreeFile(t, tree, "README"))
+	require.Equal(t, string(id), readTreeFile(t, tree, "notes.txt"))
+
+	// Git's ident attribute would collapse the CVS expansions on checkin, so
+	// the paths are not marked with it
+	_, err = tree.File(".gitattributes")
+	require.ErrorIs(t, err, object.ErrFileNotFound)
+}
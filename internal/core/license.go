@@ -0,0 +1,231 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Comment styles of a LicenseRule
+const (
+	CommentSlashes = "//"   // Every line starts with "// "
+	CommentHash    = "#"    // Every line starts with "# "
+	CommentDashes  = "--"   // Every line starts with "-- "
+	CommentBlock   = "/*"   // A "/* ... */" block with " * " lines
+	CommentXML     = "<!--" // A "<!-- ... -->" block
+)
+
+// licenseComments maps file extensions to the comment style used when a
+// LicenseRule does not set one
+var licenseComments = map[string]string{
+	".c": CommentBlock, ".h": CommentBlock, ".cc": CommentBlock, ".cpp": CommentBlock, ".hpp": CommentBlock,
+	".java": CommentBlock, ".js": CommentBlock, ".ts": CommentBlock, ".css": CommentBlock, ".php": CommentBlock,
+	".go": CommentSlashes, ".rs": CommentSlashes, ".swift": CommentSlashes, ".kt": CommentSlashes,
+	".sh": CommentHash, ".py": CommentHash, ".pl": CommentHash, ".pm": CommentHash, ".rb": CommentHash,
+	".tcl": CommentHash, ".mk": CommentHash, ".yaml": CommentHash, ".yml": CommentHash, ".cmake": CommentHash,
+	".sql": CommentDashes, ".lua": CommentDashes, ".hs": CommentDashes,
+	".html": CommentXML, ".xml": CommentXML, ".xsl": CommentXML,
+}
+
+// licenseMarker finds license headers among the leading comments of a file
+var licenseMarker = regexp.MustCompile(`(?i)copyright|\(c\)|©|licen[cs]e|spdx-license-identifier`)
+
+// LicenseRule writes Header as the license header of the files matching
+// Pattern, a KeywordRule pattern. A leading comment mentioning a copyright
+// or license is replaced; otherwise the header is inserted. Every revision
+// is rewritten the same way, so the diffs between revisions are unchanged.
+type LicenseRule struct {
+	Pattern string
+	Header  string // Header text without comment markers
+	Comment string // Comment style (empty = by extension)
+}
+
+// validateLicenseRules checks the license header rules
+func (m *Migrator) validateLicenseRules() error {
+	for _, rule := range m.config.LicenseHeaders {
+		if _, err := path.Match(rule.Pattern, ""); err != nil || rule.Pattern == "" {
+			return fmt.Errorf("invalid license header pattern %q", rule.Pattern)
+		}
+		if strings.TrimSpace(rule.Header) == "" {
+			return fmt.Errorf("license header for %s is empty", rule.Pattern)
+		}
+		switch rule.Comment {
+		case "":
+			if ext, literal := patternExtension(rule.Pattern); literal && licenseComments[strings.ToLower(ext)] == "" {
+				return fmt.Errorf("license header for %s needs a comment, its files have no default comment style", rule.Pattern)
+			}
+		case CommentSlashes, CommentHash, CommentDashes, CommentBlock, CommentXML:
+		default:
+			return fmt.Errorf("unsupported comment style %q for %s", rule.Comment, rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// patternExtension returns the extension of the files pattern matches, and
+// whether they all share it; "*" and "*.[ch]" match several
+func patternExtension(pattern string) (string, bool) {
+	name := path.Base(pattern)
+	ext := path.Ext(name)
+	if ext == "" {
+		ext = name
+	}
+	if strings.ContainsAny(ext, `*?[\`) {
+		return "", false
+	}
+	return path.Ext(name), true
+}
+
+// licenseRule returns the first license rule matching file, and the comment
+// style of the file
+func (m *Migrator) licenseRule(file string) (*LicenseRule, string) {
+	for i := range m.config.LicenseHeaders {
+		rule := &m.config.LicenseHeaders[i]
+		if !pathMatches(rule.Pattern, file) {
+			continue
+		}
+		comment := rule.Comment
+		if comment == "" {
+			comment = licenseComments[strings.ToLower(path.Ext(file))]
+		}
+		return rule, comment
+	}
+	return nil, ""
+}
+
+// applyLicenseHeaders rewrites the license headers of the files of a commit
+// by the license rules
+func (m *Migrator) applyLicenseHeaders(commit *vcs.Commit) {
+	if len(m.config.LicenseHeaders) == 0 {
+		return
+	}
+	for i := range commit.Files {
+		fc := &commit.Files[i]
+		if fc.Action == vcs.ActionDelete || fc.Binary {
+			continue
+		}
+		rule, comment := m.licenseRule(fc.Path)
+		if rule == nil {
+			continue
+		}
+		if comment == "" {
+			if !m.unlicensed[fc.Path] {
+				if m.unlicensed == nil {
+					m.unlicensed = make(map[string]bool)
+				}
+				m.unlicensed[fc.Path] = true
+				m.warn("no comment style for license header; file left unchanged", "path", fc.Path, "pattern", rule.Pattern)
+			}
+			continue
+		}
+		transformContent(fc, func(content []byte) []byte {
+			if isBinary(content) {
+				return content
+			}
+			return setLicenseHeader(content, rule.Header, comment)
+		})
+	}
+}
+
+// setLicenseHeader replaces the license comment at the top of content by
+// header, or inserts header there. A "#!" line or XML declaration stays
+// first.
+func setLicenseHeader(content []byte, header, comment string) []byte {
+	eol := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		eol = "\r\n"
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var out strings.Builder
+	start := 0
+	if len(lines) > 0 && (strings.HasPrefix(lines[0], "#!") || strings.HasPrefix(lines[0], "<?xml")) {
+		out.WriteString(lines[0])
+		if !strings.HasSuffix(lines[0], "\n") {
+			out.WriteString(eol)
+		}
+		start = 1
+	}
+
+	// Drop an existing license comment and the blank lines after it
+	rest := lines[start:]
+	if end := leadingComment(rest, comment); end > 0 && licenseMarker.MatchString(strings.Join(rest[:end], "")) {
+		for end < len(rest) && strings.TrimSpace(rest[end]) == "" {
+			end++
+		}
+		rest = rest[end:]
+	}
+
+	for _, line := range renderLicenseHeader(header, comment) {
+		out.WriteString(line + eol)
+	}
+	if len(rest) > 0 {
+		out.WriteString(eol)
+	}
+	for _, line := range rest {
+		out.WriteString(line)
+	}
+	return []byte(out.String())
+}
+
+// leadingComment returns how many of lines belong to the comment they
+// start with, or 0 if they do not start with one
+func leadingComment(lines []string, comment string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	switch comment {
+	case CommentBlock, CommentXML:
+		closing := "*/"
+		if comment == CommentXML {
+			closing = "-->"
+		}
+		if !strings.HasPrefix(strings.TrimSpace(lines[0]), comment) {
+			return 0
+		}
+		for i, line := range lines {
+			if strings.Contains(line, closing) {
+				return i + 1
+			}
+		}
+		return 0 // Unterminated
+	default:
+		n := 0
+		for n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[n]), comment) {
+			n++
+		}
+		return n
+	}
+}
+
+// renderLicenseHeader returns the lines of header as a comment
+func renderLicenseHeader(header, comment string) []string {
+	text := strings.Split(strings.TrimRight(strings.ReplaceAll(header, "\r\n", "\n"), "\n"), "\n")
+	var lines []string
+	switch comment {
+	case CommentBlock:
+		lines = append(lines, "/*")
+		for _, line := range text {
+			lines = append(lines, strings.TrimRight(" * "+line, " "))
+		}
+		lines = append(lines, " */")
+	case CommentXML:
+		lines = append(lines, "<!--")
+		for _, line := range text {
+			lines = append(lines, strings.TrimRight("  "+line, " "))
+		}
+		lines = append(lines, "-->")
+	default:
+		for _, line := range text {
+			lines = append(lines, strings.TrimRight(comment+" "+line, " "))
+		}
+	}
+	return lines
+}
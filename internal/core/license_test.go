@@ -0,0 +1,117 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testLicense = "Copyright (c) Example Corp.\nSPDX-License-Identifier: MIT\n"
+
+func TestSetLicenseHeader(t *testing.T) {
+	tests := []struct {
+		name, content, comment, want string
+	}{
+		{"insert block", "int x;\n", CommentBlock,
+			"/*\n * Copyright (c) Example Corp.\n * SPDX-License-Identifier: MIT\n */\n\nint x;\n"},
+		{"replace block", "/* Copyright 1998 Someone\n * All rights reserved */\n\n\nint x;\n", CommentBlock,
+			"/*\n * Copyright (c) Example Corp.\n * SPDX-License-Identifier: MIT\n */\n\nint x;\n"},
+		{"keep other comment", "/* Parser */\nint x;\n", CommentBlock,
+			"/*\n * Copyright (c) Example Corp.\n * SPDX-License-Identifier: MIT\n */\n\n/* Parser */\nint x;\n"},
+		{"shebang", "#!/bin/sh\n# Licensed under the GPL\necho hi\n", CommentHash,
+			"#!/bin/sh\n# Copyright (c) Example Corp.\n# SPDX-License-Identifier: MIT\n\necho hi\n"},
+		{"crlf", "-- license: none\r\nselect 1;\r\n", CommentDashes,
+			"-- Copyright (c) Example Corp.\r\n-- SPDX-License-Identifier: MIT\r\n\r\nselect 1;\r\n"},
+		{"xml", "<?xml version=\"1.0\"?>\n<a/>\n", CommentXML,
+			"<?xml version=\"1.0\"?>\n<!--\n  Copyright (c) Example Corp.\n  SPDX-License-Identifier: MIT\n-->\n\n<a/>\n"},
+		{"empty", "", CommentSlashes, "// Copyright (c) Example Corp.\n// SPDX-License-Identifier: MIT\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(setLicenseHeader([]byte(tt.content), testLicense, tt.comment))
+			require.Equal(t, tt.want, got)
+			// Rewriting is idempotent, so every revision gets the same header
+			require.Equal(t, tt.want, string(setLicenseHeader([]byte(got), testLicense, tt.comment)))
+		})
+	}
+}
+
+func TestValidateLicenseRules(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{LicenseHeaders: []LicenseRule{{Pattern: "*.c", Header: testLicense}}})
+	require.NoError(t, m.validateLicenseRules())
+	m.config.LicenseHeaders = []LicenseRule{{Pattern: "*.c", Header: " \n"}}
+	require.Error(t, m.validateLicenseRules())
+	m.config.LicenseHeaders = []LicenseRule{{Pattern: "*.c", Header: testLicense, Comment: ";"}}
+	require.Error(t, m.validateLicenseRules())
+	m.config.LicenseHeaders = []LicenseRule{{Pattern: "[", Header: testLicense}}
+	require.Error(t, m.validateLicenseRules())
+
+	// Files without a default comment style need one
+	for _, pattern := range []string{"*.txt", "README", "docs/*.TXT"} {
+		m.config.LicenseHeaders = []LicenseRule{{Pattern: pattern, Header: testLicense}}
+		require.ErrorContains(t, m.validateLicenseRules(), "needs a comment", pattern)
+		m.config.LicenseHeaders[0].Comment = CommentHash
+		require.NoError(t, m.validateLicenseRules(), pattern)
+	}
+	for _, pattern := range []string{"*", "src/*", "*.[ch]", "*.C"} {
+		m.config.LicenseHeaders = []LicenseRule{{Pattern: pattern, Header: testLicense}}
+		require.NoError(t, m.validateLicenseRules(), pattern)
+	}
+}
+
+func TestRun_LicenseHeaders(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "1", Author: "alice", Date: date, Message: "first", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("/* (C) 1997 Old Corp */\nint x;\n")},
+			{Path: "tools/gen.py", Action: vcs.ActionAdd, Content: []byte("print(1)\n")},
+			{Path: "logo.gif", Action: vcs.ActionAdd, Content: []byte("GIF\x00"), Binary: true},
+			{Path: "NOTES", Action: vcs.ActionAdd, Content: []byte("notes\n")},
+		}},
+		{Revision: "2", Author: "alice", Date: date.Add(time.Hour), Message: "second", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionModify, Content: []byte("/* Copyright 1997 Old Corp */\nint y;\n")},
+		}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: target,
+		LicenseHeaders: []LicenseRule{
+			{Pattern: "*.gif", Header: testLicense, Comment: CommentHash},
+			{Pattern: "*", Header: testLicense},
+		},
+		Logger: logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := tip.Tree()
+	require.NoError(t, err)
+
+	header := "/*\n * Copyright (c) Example Corp.\n * SPDX-License-Identifier: MIT\n */\n\n"
+	require.Equal(t, header+"int y;\n", readTreeFile(t, tree, "src/main.c"))
+	require.Equal(t, "# Copyright (c) Example Corp.\n# SPDX-License-Identifier: MIT\n\nprint(1)\n", readTreeFile(t, tree, "tools/gen.py"))
+	require.Equal(t, "GIF\x00", readTreeFile(t, tree, "logo.gif"))
+	require.Equal(t, "notes\n", readTreeFile(t, tree, "NOTES")) // No comment style
+	require.Len(t, m.Issues(), 1)
+	require.Equal(t, "no comment style for license header; file left unchanged path=NOTES pattern=*", m.Issues()[0].Message)
+
+	// Both revisions get the same header, so the diff between them is unchanged
+	first, err := tip.Parent(0)
+	require.NoError(t, err)
+	firstTree, err := first.Tree()
+	require.NoError(t, err)
+	require.Equal(t, header+"int x;\n", readTreeFile(t, firstTree, "src/main.c"))
+}
@@ -0,0 +1,293 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+)
+
+// Lock timing: a running process refreshes its lock every
+// lockHeartbeatInterval, and a lock not refreshed for lockStaleAfter is
+// considered abandoned
+const (
+	lockHeartbeatInterval = 10 * time.Second
+	lockStaleAfter        = 6 * lockHeartbeatInterval
+)
+
+// ErrLocked is returned when another process holds the lock of a target
+var ErrLocked error = &kindError{msg: "target is locked by another run", kind: ErrConflict}
+
+// ErrLockLost is returned by a run whose lock another process took over
+var ErrLockLost error = &kindError{msg: "lock of the target was taken over by another run", kind: ErrConflict}
+
+// lockAttempts limits how often AcquireLock tries to create the lock file
+// while other runs take over the same stale lock
+const lockAttempts = 3
+
+// LockInfo is the content of a lock file
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Command   string    `json:"command"` // e.g. migrate or sync
+	Token     string    `json:"token"`   // Distinguishes runs of the same process
+	StartedAt time.Time `json:"startedAt"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// Lock is an advisory lock on a target repository held by this process
+type Lock struct {
+	path string
+	info LockInfo
+
+	mu      sync.Mutex
+	lost    bool // Another process took the lock over
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// LockPath returns the lock file guarding a target repository, next to the
+// target so that it exists before the repository does
+func LockPath(targetPath string) string {
+	target := filepath.Clean(targetPath)
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".git-migrator.lock")
+}
+
+// AcquireLock takes the lock at path for command. A lock held by a process
+// that is gone, or whose heartbeat stopped, is taken over; a live lock
+// fails with ErrLocked unless force is set. The lock is refreshed in the
+// background until Release; Err reports when it was lost meanwhile.
+func AcquireLock(path, command string, force bool) (*Lock, error) {
+	host, _ := os.Hostname()
+	now := time.Now()
+	l := &Lock{
+		path: path,
+		info: LockInfo{
+			PID:       os.Getpid(),
+			Host:      host,
+			Command:   command,
+			Token:     strconv.FormatInt(now.UnixNano(), 36),
+			StartedAt: now,
+			Heartbeat: now,
+		},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		err := l.create()
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) || attempt == lockAttempts {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+
+		data, readErr := os.ReadFile(path)
+		if errors.Is(readErr, fs.ErrNotExist) {
+			continue // Released meanwhile
+		}
+		var holder *LockInfo
+		if readErr == nil {
+			holder, readErr = parseLock(data)
+		}
+		switch {
+		case force:
+		case readErr != nil:
+			// Unreadable: half-written by a crashed process or not ours
+			if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) < lockStaleAfter {
+				return nil, fmt.Errorf("%w: %s exists but cannot be read: %v", ErrLocked, path, readErr)
+			}
+		case holder.alive():
+			return nil, fmt.Errorf("%w: %s (pid %d on %s) since %s; use --force-unlock if it is not running",
+				ErrLocked, holder.Command, holder.PID, holder.Host, holder.StartedAt.Format(time.RFC3339))
+		}
+		if err := takeOver(path, data, force); err != nil {
+			return nil, err
+		}
+	}
+
+	go l.heartbeat()
+	return l, nil
+}
+
+// ReadLock returns the content of the lock file at path
+func ReadLock(path string) (*LockInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseLock(data)
+}
+
+// parseLock decodes the content of a lock file
+func parseLock(data []byte) (*LockInfo, error) {
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// takeOver removes the stale lock file at path, whose content was data. It
+// is renamed away first, so of several runs taking it over at once only one
+// removes it. A file that changed meanwhile is the lock of a run that took
+// it over first and is put back, unless force is set.
+func takeOver(path string, data []byte, force bool) error {
+	stale := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, stale); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Taken over by another run first
+		}
+		return fmt.Errorf("failed to remove stale lock %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(stale) }()
+	if force {
+		return nil
+	}
+	if current, err := os.ReadFile(stale); err == nil && !bytes.Equal(current, data) {
+		// Linking fails if yet another run holds the lock by now
+		if err := os.Link(stale, path); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("failed to restore lock %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ForceUnlock removes the lock file at path regardless of its holder
+func ForceUnlock(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// alive reports whether the lock holder may still be running
+func (i *LockInfo) alive() bool {
+	if time.Since(i.Heartbeat) > lockStaleAfter {
+		return false
+	}
+	host, _ := os.Hostname()
+	if i.Host != host {
+		return true // Cannot check processes on other hosts
+	}
+	return processAlive(i.PID)
+}
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true // No signal 0; rely on the heartbeat
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// create writes the lock file, failing if it exists
+func (l *Lock) create() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(l.info)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(l.path)
+	}
+	return err
+}
+
+// heartbeat refreshes the lock until Release
+func (l *Lock) heartbeat() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(lockHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.refresh()
+		}
+	}
+}
+
+// refresh updates the heartbeat of the lock file, unless another process
+// has taken the lock over; the lock is then lost for good
+func (l *Lock) refresh() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost {
+		return
+	}
+	if !l.owned() {
+		l.lost = true
+		return
+	}
+	l.info.Heartbeat = time.Now()
+	data, err := json.Marshal(l.info)
+	if err != nil {
+		return
+	}
+	_ = storage.WriteFileAtomic(l.path, data, 0644)
+}
+
+// owned reports whether the lock file still belongs to this lock
+func (l *Lock) owned() bool {
+	info, err := ReadLock(l.path)
+	return err == nil && info.PID == l.info.PID && info.Token == l.info.Token
+}
+
+// Err returns ErrLockLost once another process has taken the lock over, so
+// the holder stops before it changes the target any further. A nil lock,
+// as held by runs that need none, is never lost.
+func (l *Lock) Err() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release stops the heartbeat and removes the lock file if this lock still
+// holds it
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.stopped
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.owned() {
+		return nil
+	}
+	return ForceUnlock(l.path)
+}
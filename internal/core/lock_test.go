@@ -0,0 +1,189 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLockFile writes a lock file as another process would
+func writeLockFile(t *testing.T, path string, info LockInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func TestLockPath(t *testing.T) {
+	require.Equal(t, filepath.Join("/work", ".repo.git-migrator.lock"), LockPath("/work/repo/"))
+}
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+
+	lock, err := AcquireLock(path, "migrate", false)
+	require.NoError(t, err)
+	info, err := ReadLock(path)
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), info.PID)
+	require.Equal(t, "migrate", info.Command)
+
+	_, err = AcquireLock(path, "sync", false)
+	require.ErrorIs(t, err, ErrLocked)
+	require.ErrorContains(t, err, "migrate (pid")
+
+	require.NoError(t, lock.Release())
+	require.NoFileExists(t, path)
+
+	lock, err = AcquireLock(path, "sync", false)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestAcquireLock_Stale(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Now()
+	tests := []struct {
+		name   string
+		holder LockInfo
+		locked bool
+	}{
+		{"live process", LockInfo{PID: os.Getpid(), Host: host, Heartbeat: now}, true},
+		{"other host", LockInfo{PID: 1, Host: host + "-other", Heartbeat: now}, true},
+		{"dead process", LockInfo{PID: 1 << 30, Host: host, Heartbeat: now}, false},
+		{"heartbeat stopped", LockInfo{PID: 1, Host: host + "-other", Heartbeat: now.Add(-2 * lockStaleAfter)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "repo.lock")
+			writeLockFile(t, path, tt.holder)
+
+			lock, err := AcquireLock(path, "migrate", false)
+			if tt.locked {
+				require.ErrorIs(t, err, ErrLocked)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, lock.Release())
+		})
+	}
+}
+
+func TestAcquireLock_Unreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+
+	_, err := AcquireLock(path, "migrate", false)
+	require.ErrorIs(t, err, ErrLocked)
+
+	old := time.Now().Add(-2 * lockStaleAfter)
+	require.NoError(t, os.Chtimes(path, old, old))
+	lock, err := AcquireLock(path, "migrate", false)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestAcquireLock_StaleTakeOverRace(t *testing.T) {
+	host, _ := os.Hostname()
+	path := filepath.Join(t.TempDir(), "repo.lock")
+	writeLockFile(t, path, LockInfo{PID: 1 << 30, Host: host, Heartbeat: time.Now()})
+	stale, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// Another run took the stale lock over after this one read it
+	winner, err := AcquireLock(path, "sync", false)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, winner.Release()) }()
+	require.NoError(t, takeOver(path, stale, false))
+	info, err := ReadLock(path)
+	require.NoError(t, err)
+	require.Equal(t, "sync", info.Command, "the fresh lock is put back")
+
+	_, err = AcquireLock(path, "migrate", false)
+	require.ErrorIs(t, err, ErrLocked)
+	matches, err := filepath.Glob(path + ".stale-*")
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
+
+func TestAcquireLock_Force(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+	first, err := AcquireLock(path, "migrate", false)
+	require.NoError(t, err)
+
+	second, err := AcquireLock(path, "sync", true)
+	require.NoError(t, err)
+
+	// The first holder must not remove or refresh the lock it lost
+	require.NoError(t, first.Err())
+	first.refresh()
+	require.ErrorIs(t, first.Err(), ErrLockLost)
+	require.ErrorIs(t, first.Err(), ErrConflict)
+	require.NoError(t, first.Release())
+	info, err := ReadLock(path)
+	require.NoError(t, err)
+	require.Equal(t, "sync", info.Command)
+
+	require.NoError(t, second.Release())
+	require.NoFileExists(t, path)
+}
+
+func TestLockRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+	lock, err := AcquireLock(path, "migrate", false)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, lock.Release()) }()
+
+	before, err := ReadLock(path)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	lock.refresh()
+	after, err := ReadLock(path)
+	require.NoError(t, err)
+	require.True(t, after.Heartbeat.After(before.Heartbeat))
+	require.Equal(t, before.StartedAt.UnixNano(), after.StartedAt.UnixNano())
+}
+
+func TestRun_TargetLocked(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	held, err := AcquireLock(LockPath(target), "migrate", false)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, held.Release()) }()
+
+	commits := []*vcs.Commit{{Revision: "r1", Author: "a1", Date: time.Now(), Message: "m1",
+		Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}}}
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.ErrorIs(t, m.Run(), ErrLocked)
+	require.NoDirExists(t, target, "a locked target must not be touched")
+
+	m = NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, ForceUnlock: true})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+	require.NoFileExists(t, LockPath(target))
+}
+
+func TestSyncerRun_Locked(t *testing.T) {
+	gitDir := createTestGitRepo(t)
+	held, err := AcquireLock(LockPath(gitDir), "migrate", false)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, held.Release()) }()
+
+	s := NewSyncer(&SyncConfig{
+		GitPath:   gitDir,
+		CVSPath:   createTestCVSRepo(t),
+		CVSModule: "mod",
+		CVSWriter: CVSWriterNative,
+		Direction: SyncGitToCVS,
+	})
+	require.ErrorIs(t, s.Run(), ErrLocked)
+
+	s.config.ForceUnlock = true
+	require.NoError(t, s.Run())
+	require.NoFileExists(t, LockPath(gitDir))
+}
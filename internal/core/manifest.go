@@ -0,0 +1,285 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// Problems of a ManifestMismatch
+const (
+	ManifestDifferent = "different" // Content differs
+	ManifestMissing   = "missing"   // In the CVS checkout only
+	ManifestExtra     = "extra"     // At the Git tip only
+)
+
+// How the CVS side of a ReportManifest was checked out
+const (
+	ManifestCheckoutClient = "cvs-export" // Exported by the cvs client
+	ManifestCheckoutRCS    = "rcs"        // Read from the RCS files, without the cvs client
+)
+
+// Manifest maps the paths of a tree to the hex SHA-256 of their contents
+type Manifest map[string]string
+
+// ReportManifest compares the tree at the tip of a migrated branch with a
+// fresh checkout of its CVS branch
+type ReportManifest struct {
+	Branch     string             `json:"branch"`           // Git branch
+	Source     string             `json:"source,omitempty"` // CVS branch, empty for trunk
+	Checkout   string             `json:"checkout"`         // ManifestCheckoutClient or ManifestCheckoutRCS
+	Files      int                `json:"files"`            // Files at the Git tip
+	Matched    int                `json:"matched"`          // Files equal in both
+	Mismatches []ManifestMismatch `json:"mismatches"`
+}
+
+// ManifestMismatch is a file that differs between the tip of a migrated
+// branch and the CVS checkout
+type ManifestMismatch struct {
+	Path        string `json:"path"`
+	Problem     string `json:"problem"` // ManifestDifferent, ManifestMissing or ManifestExtra
+	CVSRevision string `json:"cvsRevision,omitempty"`
+	CVSHash     string `json:"cvsHash,omitempty"`
+	GitHash     string `json:"gitHash,omitempty"`
+}
+
+// ManifestPath returns the path of the branch manifests written next to the
+// target repository
+func ManifestPath(targetPath string) string {
+	target := filepath.Clean(targetPath)
+	return filepath.Join(filepath.Dir(target), filepath.Base(target)+".manifest.json")
+}
+
+// validateVerifyManifest checks that the source can be checked out for
+// VerifyManifest, and that the migration writes the files of the checkout
+// unchanged so that they can be compared
+func (m *Migrator) validateVerifyManifest() error {
+	if !m.config.VerifyManifest {
+		return nil
+	}
+	if len(m.config.JoinModules) > 0 {
+		return errors.New("manifest verification does not support joined modules")
+	}
+	var rewrites []string
+	if m.normalizesEOL() {
+		rewrites = append(rewrites, "eol")
+	}
+	for _, rule := range m.config.Keywords {
+		if rule.Mode != KeywordsKeep {
+			rewrites = append(rewrites, "keywords")
+			break
+		}
+	}
+	if len(m.config.LicenseHeaders) > 0 {
+		rewrites = append(rewrites, "licenseHeaders")
+	}
+	if m.config.RevisionRules != "" {
+		rewrites = append(rewrites, "revisionRules")
+	}
+	if m.config.UnicodeForm != "" {
+		rewrites = append(rewrites, "pathNormalization")
+	}
+	if m.config.CaseConflicts == CaseConflictSuffix || m.config.CaseConflicts == CaseConflictUnify {
+		rewrites = append(rewrites, "caseConflicts "+m.config.CaseConflicts)
+	}
+	if m.config.SanitizeWindows {
+		rewrites = append(rewrites, "sanitizeWindowsPaths")
+	}
+	if m.config.HistoryDepth > 0 || !m.config.HistorySince.IsZero() {
+		rewrites = append(rewrites, "historyDepth or historySince")
+	}
+	if len(rewrites) > 0 {
+		return fmt.Errorf("manifest verification compares with an unchanged CVS checkout and cannot be combined with %s, which rewrite files or paths", strings.Join(rewrites, ", "))
+	}
+	return nil
+}
+
+// buildManifest hashes the contents of files
+func buildManifest(files []vcs.FileChange) (Manifest, error) {
+	manifest := make(Manifest, len(files))
+	for _, fc := range files {
+		hash, err := hashContent(fc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fc.Path, err)
+		}
+		manifest[fc.Path] = hash
+	}
+	return manifest, nil
+}
+
+// verifyManifests builds the manifest of the tree at the tip of every
+// migrated branch, compares it with a fresh checkout of the CVS branch it
+// was created for and writes the manifests to ManifestPath. Differences
+// are listed per file and fail the verification.
+func (m *Migrator) verifyManifests(v *ReportVerification) {
+	target, ok := m.target.(interface {
+		BranchFiles(branch string) ([]vcs.FileChange, error)
+	})
+	if !ok {
+		v.Problems = append(v.Problems, fmt.Sprintf("target type %s cannot list branch files for manifest verification", m.config.TargetType))
+		return
+	}
+
+	reader := cvs.NewModuleReader(m.config.SourcePath, m.config.SourceModule)
+	reader.SetReadLimit(m.ioLimit())
+	defer func() {
+		if err := reader.Close(); err != nil {
+			m.Logger().Warn("failed to close CVS reader", "error", err)
+		}
+	}()
+
+	// Trunk is at HEAD, on the default branch
+	trunk := m.trunkBranch()
+	if trunk == "" {
+		trunk = "HEAD"
+	}
+	branches := []string{trunk}
+	for _, b := range m.report.Branches.Created {
+		if b != trunk {
+			branches = append(branches, b)
+		}
+	}
+
+	manifests := map[string]Manifest{}
+	for _, branch := range branches {
+		source, gitRef := m.branchSources[branch], branch
+		if branch == trunk {
+			source, gitRef = "", m.trunkBranch()
+		}
+		rm, manifest, err := m.compareManifest(target.BranchFiles, reader, gitRef, source)
+		if err != nil {
+			v.Problems = append(v.Problems, fmt.Sprintf("failed to verify the manifest of branch %s: %v", branch, err))
+			continue
+		}
+		rm.Branch = branch
+		manifests[branch] = manifest
+		v.Manifests = append(v.Manifests, *rm)
+		if n := len(rm.Mismatches); n > 0 {
+			v.Problems = append(v.Problems, fmt.Sprintf("branch %s: %d files differ from the CVS checkout", branch, n))
+		}
+	}
+
+	if m.config.Simulate {
+		return
+	}
+	data, err := json.MarshalIndent(manifests, "", "  ")
+	if err == nil {
+		err = os.WriteFile(ManifestPath(m.config.TargetPath), append(data, '\n'), 0644)
+	}
+	if err != nil {
+		m.Logger().Warn("failed to write branch manifests", "error", err)
+		return
+	}
+	v.ManifestFile = ManifestPath(m.config.TargetPath)
+}
+
+// compareManifest compares the tip of the Git branch gitRef (empty = HEAD)
+// with the checkout of the CVS branch source (empty = trunk)
+func (m *Migrator) compareManifest(branchFiles func(string) ([]vcs.FileChange, error), reader *cvs.Reader, gitRef, source string) (*ReportManifest, Manifest, error) {
+	gitFiles, err := branchFiles(gitRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	gitManifest, err := buildManifest(gitFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	cvsManifest, revisions, checkout, err := m.checkoutManifest(reader, source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rm := &ReportManifest{Source: source, Checkout: checkout, Files: len(gitManifest), Mismatches: []ManifestMismatch{}}
+	for p, cvsHash := range cvsManifest {
+		gitHash, ok := gitManifest[p]
+		switch {
+		case !ok:
+			rm.Mismatches = append(rm.Mismatches, ManifestMismatch{Path: p, Problem: ManifestMissing, CVSRevision: revisions[p], CVSHash: cvsHash})
+		case gitHash != cvsHash:
+			rm.Mismatches = append(rm.Mismatches, ManifestMismatch{Path: p, Problem: ManifestDifferent, CVSRevision: revisions[p], CVSHash: cvsHash, GitHash: gitHash})
+		default:
+			rm.Matched++
+		}
+	}
+	for p, gitHash := range gitManifest {
+		if _, ok := cvsManifest[p]; !ok && !(p == ".gitattributes" && m.gitattributes() != "") {
+			rm.Mismatches = append(rm.Mismatches, ManifestMismatch{Path: p, Problem: ManifestExtra, GitHash: gitHash})
+		}
+	}
+	sort.Slice(rm.Mismatches, func(i, j int) bool { return rm.Mismatches[i].Path < rm.Mismatches[j].Path })
+	return rm, gitManifest, nil
+}
+
+// checkoutManifest returns the manifest of a fresh checkout of the CVS
+// branch source (empty = trunk), the revision of every file, and how it was
+// checked out. With the cvs client installed the branch is exported by it,
+// so the comparison does not depend on the RCS parser of the migration;
+// otherwise the checkout is read from the RCS files.
+func (m *Migrator) checkoutManifest(reader *cvs.Reader, source string) (Manifest, map[string]string, string, error) {
+	cvsFiles, err := reader.Checkout(source)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to check out CVS branch: %w", err)
+	}
+	revisions := make(map[string]string, len(cvsFiles))
+	for _, fc := range cvsFiles {
+		revisions[fc.Path] = fc.Revision
+	}
+
+	client := m.cvsClient()
+	if _, err := client.LookPath(); err != nil {
+		manifest, err := buildManifest(cvsFiles)
+		return manifest, revisions, ManifestCheckoutRCS, err
+	}
+	tmp, err := os.MkdirTemp("", "git-migrator-manifest-")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	dir := filepath.Join(tmp, "export")
+	if err := client.Export(m.config.SourcePath, m.config.SourceModule, source, dir); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to export CVS branch: %w", err)
+	}
+	manifest, err := dirManifest(dir, m.config.SourceModule == "")
+	return manifest, revisions, ManifestCheckoutClient, err
+}
+
+// dirManifest hashes the files below dir; skipRoot leaves out the CVSROOT
+// directory an export of the whole repository contains
+func dirManifest(dir string, skipRoot bool) (Manifest, error) {
+	manifest := make(Manifest)
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if skipRoot && rel == "CVSROOT" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		hash, err := hashContent(vcs.FileChange{Path: rel, Content: data})
+		if err != nil {
+			return err
+		}
+		manifest[rel] = hash
+		return nil
+	})
+	return manifest, err
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/stretchr/testify/require"
+)
+
+const manifestRCS = "head\t1.1;\naccess;\nsymbols;\nlocks; strict;\n\n" +
+	"1.1\ndate\t2024.01.01.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\n\n" +
+	"desc\n@@\n\n1.1\nlog\n@add\n@\ntext\n@hello\n@\n"
+
+func manifestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mod"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mod", "hello.txt,v"), []byte(manifestRCS), 0644))
+	return dir
+}
+
+func TestRun_VerifyManifest(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs", SourcePath: manifestRepo(t), SourceModule: "mod", TargetPath: target,
+		VerifyManifest: true, Logger: logging.Discard(),
+	})
+	require.NoError(t, m.Run())
+
+	v := m.report.Verification
+	require.True(t, v.Passed, v.Problems)
+	require.Len(t, v.Manifests, 1)
+	require.Equal(t, "HEAD", v.Manifests[0].Branch)
+	require.Equal(t, 1, v.Manifests[0].Matched)
+	require.Empty(t, v.Manifests[0].Mismatches)
+
+	require.Equal(t, ManifestPath(target), v.ManifestFile)
+	data, err := os.ReadFile(v.ManifestFile)
+	require.NoError(t, err)
+	var manifests map[string]Manifest
+	require.NoError(t, json.Unmarshal(data, &manifests))
+	require.Equal(t, Manifest{"hello.txt": "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"}, manifests["HEAD"])
+}
+
+func TestCompareManifest_Mismatches(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{})
+	reader := cvs.NewModuleReader(manifestRepo(t), "mod")
+	gitFiles := func(string) ([]vcs.FileChange, error) {
+		return []vcs.FileChange{
+			{Path: "hello.txt", Content: []byte("hello, world\n")},
+			{Path: "extra.txt", Content: []byte("x")},
+		}, nil
+	}
+
+	rm, manifest, err := m.compareManifest(gitFiles, reader, "", "")
+	require.NoError(t, err)
+	require.Len(t, manifest, 2)
+	require.Equal(t, 2, rm.Files)
+	require.Zero(t, rm.Matched)
+	require.Len(t, rm.Mismatches, 2)
+	require.Equal(t, ManifestMismatch{Path: "extra.txt", Problem: ManifestExtra, GitHash: manifest["extra.txt"]}, rm.Mismatches[0])
+	require.Equal(t, "hello.txt", rm.Mismatches[1].Path)
+	require.Equal(t, ManifestDifferent, rm.Mismatches[1].Problem)
+	require.Equal(t, "1.1", rm.Mismatches[1].CVSRevision)
+}
+
+func TestRun_VerifyManifestExport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cvs client is a shell script")
+	}
+	// The fake client exports a file differing from the RCS file
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > \"$(dirname \"$0\")/args\"\nmkdir \"${10}\" && printf 'hello from cvs\\n' > \"${10}/hello.txt\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "cvs"), []byte(script), 0755))
+
+	source := manifestRepo(t)
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs", SourcePath: source, SourceModule: "mod", TargetPath: filepath.Join(t.TempDir(), "repo"),
+		VerifyManifest: true, CVSClient: cvs.Client{SearchPath: bin + string(os.PathListSeparator) + "/usr/bin:/bin"}, Logger: logging.Discard(),
+	})
+	require.NoError(t, m.Run())
+
+	v := m.report.Verification
+	require.False(t, v.Passed)
+	require.Len(t, v.Manifests, 1)
+	require.Equal(t, ManifestCheckoutClient, v.Manifests[0].Checkout)
+	require.Len(t, v.Manifests[0].Mismatches, 1)
+	require.Equal(t, ManifestDifferent, v.Manifests[0].Mismatches[0].Problem)
+	require.Equal(t, "1.1", v.Manifests[0].Mismatches[0].CVSRevision)
+
+	args, err := os.ReadFile(filepath.Join(bin, "args"))
+	require.NoError(t, err)
+	require.Equal(t, "-f -d "+source+" -Q export -ko -r HEAD -d export mod\n", string(args))
+}
+
+func TestValidateVerifyManifest(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{VerifyManifest: true, JoinModules: []JoinModule{{Module: "a"}}})
+	require.Error(t, m.validateVerifyManifest())
+
+	m = NewMigrator(&MigrationConfig{VerifyManifest: true, Keywords: []KeywordRule{{Pattern: "*", Mode: KeywordsKeep}}, CaseConflicts: CaseConflictReport})
+	require.NoError(t, m.validateVerifyManifest())
+
+	m = NewMigrator(&MigrationConfig{
+		VerifyManifest: true, EOL: EOLLF, Keywords: []KeywordRule{{Pattern: "*.c", Mode: KeywordsExpand}},
+		LicenseHeaders: []LicenseRule{{Pattern: "*.c", Header: "Copyright"}}, CaseConflicts: CaseConflictUnify, HistoryDepth: 1,
+	})
+	require.EqualError(t, m.validateVerifyManifest(), "manifest verification compares with an unchanged CVS checkout and cannot be combined with "+
+		"eol, keywords, licenseHeaders, caseConflicts unify, historyDepth or historySince, which rewrite files or paths")
+}
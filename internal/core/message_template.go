@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// MessageData is the data of a MessageTemplate: the fields of the commit
+// after author mapping, e.g. {{.Revision}}, {{.Author}}, {{.Email}},
+// {{.Date}} and {{.Branch}}, and the fields below.
+type MessageData struct {
+	*vcs.Commit
+	Message        string // Source message without trailing newlines
+	OriginalAuthor string // Source login, before author mapping
+	SourceType     string // Source VCS, e.g. "cvs"
+}
+
+// ParseMessageTemplate parses a MessageTemplate and executes it on an empty
+// commit, so unknown fields fail before a migration starts
+func ParseMessageTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	sample := MessageData{Commit: &vcs.Commit{Date: time.Unix(0, 0).UTC()}}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// validateMessageTemplate parses MessageTemplate
+func (m *Migrator) validateMessageTemplate() error {
+	m.messageTemplate = nil
+	if m.config.MessageTemplate == "" {
+		return nil
+	}
+	tmpl, err := ParseMessageTemplate(m.config.MessageTemplate)
+	if err != nil {
+		return err
+	}
+	m.messageTemplate = tmpl
+	return nil
+}
+
+// applyMessageTemplate rewrites the message of a commit whose author has
+// been mapped; login is the source login. The message ends with a newline.
+func (m *Migrator) applyMessageTemplate(commit *vcs.Commit, login string) error {
+	if m.messageTemplate == nil {
+		return nil
+	}
+	var message strings.Builder
+	err := m.messageTemplate.Execute(&message, MessageData{
+		Commit:         commit,
+		Message:        strings.TrimRight(commit.Message, "\r\n"),
+		OriginalAuthor: login,
+		SourceType:     m.config.SourceType,
+	})
+	if err != nil {
+		return fmt.Errorf("message template failed for %s: %w", commit.Revision, err)
+	}
+	commit.Message = strings.TrimRight(message.String(), "\r\n") + "\n"
+	return nil
+}
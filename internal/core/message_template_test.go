@@ -0,0 +1,57 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMessageTemplate(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{})
+	require.NoError(t, m.validateMessageTemplate())
+	require.Nil(t, m.messageTemplate)
+
+	for _, valid := range []string{"{{.Message}}", `{{.Message}} ({{.Date.Format "2006-01-02"}}, {{.OriginalAuthor}}, {{.SourceType}})`} {
+		m.config.MessageTemplate = valid
+		require.NoError(t, m.validateMessageTemplate(), valid)
+		require.NotNil(t, m.messageTemplate)
+	}
+	for _, invalid := range []string{"{{.Message", "{{.Unknown}}"} {
+		m.config.MessageTemplate = invalid
+		require.ErrorContains(t, m.validateMessageTemplate(), "invalid message template", invalid)
+	}
+}
+
+func TestRun_MessageTemplate(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "alice", Date: date, Message: "first\n\n", Files: []vcs.FileChange{
+			{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a\n")},
+		}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType:      "cvs",
+		SourcePath:      "/src",
+		TargetPath:      target,
+		AuthorMap:       map[string]string{"alice": "Alice Smith <alice@corp.example>"},
+		MessageTemplate: "{{.Message}}\n\nMigrated-from: {{.SourceType}} {{.Revision}} by {{.OriginalAuthor}} ({{.Author}}) on {{.Date.Format \"2006-01-02\"}}\n\n",
+		Logger:          logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "first\n\nMigrated-from: cvs 1.1 by alice (Alice Smith) on 2024-01-02\n", tip.Message)
+	require.Equal(t, "Alice Smith", tip.Author.Name)
+}
@@ -4,15 +4,23 @@ package core
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/adamf123git/git-migrator/internal/logging"
 	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/profile"
 	"github.com/adamf123git/git-migrator/internal/progress"
 	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/throttle"
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
 	"github.com/adamf123git/git-migrator/internal/vcs/git"
@@ -20,28 +28,129 @@ import (
 
 // MigrationConfig holds migration configuration
 type MigrationConfig struct {
-	SourceType  string            // cvs, svn
-	SourcePath  string            // Path to source repo
-	TargetPath  string            // Path to target Git repo
-	AuthorMap   map[string]string // CVS user -> "Name <email>"
-	BranchMap   map[string]string // CVS branch -> Git branch
-	TagMap      map[string]string // CVS tag -> Git tag
-	DryRun      bool              // Preview without changes
-	Resume      bool              // Resume from last checkpoint
-	StateFile   string            // Path to state file
-	ChunkSize   int               // Save state every N commits
-	InterruptAt int               // For testing: interrupt after N commits
+	SourceType       string            // cvs, svn
+	SourcePath       string            // Path to source repo
+	SourceModule     string            // CVS module to migrate (empty = whole repository)
+	JoinModules      []JoinModule      // CVS modules joined into subdirectories of the target (replaces SourceModule)
+	TargetType       string            // Registered writer type (default: git)
+	TargetPath       string            // Path to target repo
+	TargetOpts       map[string]string // Target-specific writer options
+	AuthorMap        map[string]string // CVS user -> "Name <email>"
+	RequireAuthors   bool              // Fail with mapping.ErrUnmappedAuthor on the first author AuthorMap does not cover
+	Committer        string            // Fixed committer "Name <email>" (empty = same as author)
+	BranchMap        map[string]string // CVS branch -> Git branch
+	DefaultBranch    string            // Branch receiving trunk history and HEAD (empty = writer default)
+	Compat           string            // Output compatibility mode, e.g. CompatCVSImport (empty = native)
+	TagMap           map[string]string // CVS tag -> Git tag
+	AnnotatedTags    bool              // Create annotated tags with the original symbol and date
+	TagMessage       string            // Annotated tag message template (default: DefaultTagMessage)
+	ReleaseTags      string            // Glob pattern of Git tags annotated with release notes, e.g. "v*" (empty = none)
+	SigningKey       string            // Armored OpenPGP private key file signing release tags (empty = unsigned)
+	KeyPassphrase    string            // Passphrase of SigningKey
+	MessageTemplate  string            // text/template of commit messages over MessageData (empty = source message)
+	IncludeBranches  []string          // Glob patterns of branches to migrate (empty = all)
+	ExcludeBranches  []string          // Glob patterns of branches to skip
+	IncludeTags      []string          // Glob patterns of tags to migrate (empty = all)
+	ExcludeTags      []string          // Glob patterns of tags to skip
+	EOL              string            // End-of-line policy: EOLAsIs (default), EOLLF or EOLCRLFByExtension
+	CRLFExtensions   []string          // Extensions checked out with CRLF by EOLCRLFByExtension (default: DefaultCRLFExtensions)
+	CaseConflicts    string            // Paths differing only by case: CaseConflictReport (default), CaseConflictSuffix, CaseConflictUnify or CaseConflictFail
+	UnicodeForm      string            // Unicode normalization form of all paths: NormalizeNFC or NormalizeNFD (empty = unchanged)
+	SanitizeWindows  bool              // Rewrite paths Windows cannot check out (reserved names, invalid characters, too long) instead of warning
+	Keywords         []KeywordRule     // RCS keyword handling per path; the first matching rule applies (none = keep)
+	LicenseHeaders   []LicenseRule     // License headers written per path; the first matching rule applies
+	RevisionRules    string            // File of rules skipping or replacing source file revisions (see LoadRevisionRules)
+	DatePolicy       string            // Timezone of commit dates: DatePreserveUTC (default), DateFixedOffset or DatePerAuthor
+	DateTimezone     string            // UTC offset ("+02:00") or timezone name of DateFixedOffset, and the DatePerAuthor fallback
+	AuthorTimezones  map[string]string // Source login -> UTC offset or timezone name for DatePerAuthor
+	MonotonicDates   bool              // Move commits dated before their predecessor to its date, hiding clock skew
+	Deterministic    bool              // Write byte-identical history on every run of the same source snapshot
+	HistorySince     time.Time         // Migrate only changes from this date on (zero = all)
+	HistoryDepth     int               // Migrate only the last N changes of every file (0 = all)
+	MaxCommitFiles   int               // Split source commits with more file changes into several Git commits (0 = no limit)
+	MaxCommitBytes   int64             // Split source commits with more content into several Git commits (0 = no limit)
+	RenameSimilarity int               // Pair deleted and added files with at least this percentage of equal lines as renames (0 = no rename detection)
+	RenameLimit      int               // Compare the lines of added and deleted files only for commits with at most this many of each (0 = DefaultRenameLimit)
+	ErrorPolicy      string            // Which failures abort: ErrorPolicyDefault, ErrorPolicyFailFast or ErrorPolicyContinue
+	Retries          int               // Additional attempts after a transient commit or state save failure
+	RetryDelay       time.Duration     // Delay before the first retry; doubles with each attempt (default: DefaultRetryDelay)
+	DryRun           bool              // Preview without changes
+	Simulate         bool              // Migrate into an in-memory repository and report its size; nothing is written to the target
+	Resume           bool              // Resume from last checkpoint
+	ForceUnlock      bool              // Take over the target lock even if another run holds it
+	AllowExisting    bool              // Create the repository in a non-empty target directory that is not one
+	StateFile        string            // Path to state file
+	ChunkSize        int               // Save state every N commits
+	RepackEvery      int               // Pack the target's objects every N commits and at the end (0 = never)
+	VerifyEvery      int               // Check every N commits that the target holds all mapped commits and HEAD is the last (0 = never)
+	VerifyManifest   bool              // Compare the tree at the tip of every branch with a fresh CVS checkout when verifying
+	Graft            *GraftConfig      // Graft the history onto an existing commit of the target (nil = target must be new or migrated by this tool)
+	ContentCacheSize int64             // Bytes of CVS file revisions cached in memory (0 = cvs.DefaultContentCacheSize, negative = no cache)
+	ContentCacheDir  string            // Directory keeping CVS file revisions across runs (empty = memory only)
+	MemoryBudget     int64             // Bytes of RCS delta texts and commit content held in memory (0 = unlimited)
+	SpillDir         string            // Directory for commit content beyond MemoryBudget (empty = os.TempDir())
+	Profile          *profile.Recorder // Records the time spent per RCS file and commit (nil = disabled)
+	StrictParsing    bool              // Fail on malformed RCS files instead of migrating what can be parsed
+	ParseWorkers     int               // RCS files parsed in parallel (0 = one at a time)
+	ReadLimit        int64             // Bytes per second read from the CVS source and written to a CVS target (0 = unlimited)
+	OpsLimit         int64             // File opens, directory listings and renames per second on CVS repositories (0 = unlimited)
+	ImportHistory    bool              // List the events of CVSROOT/history in the migration report
+	InterruptAt      int               // For testing: interrupt after N commits
+	Stop             <-chan struct{}   // Closing it stops the migration after the current commit, keeping a checkpoint to resume from
+	Pause            *PauseSwitch      // Pauses the migration after the current commit until resumed (nil = cannot be paused)
+	Logger           *slog.Logger      // Structured logger (nil = logging.Default())
+	LogDir           string            // Directory for per-migration log files (empty = disabled)
+	Push             *git.PushOptions  // Push the converted history to a remote (nil = disabled)
+	Hooks            []CommitHook      // Hooks run around every applied commit
+	Timeouts         Timeouts          // Time limits of the source scan, each commit, the refs and each cvs client call (zero = none)
+	CVSClient        cvs.Client        // How the cvs client reading remote sources is run (zero = "cvs" from PATH); Timeout is set from Timeouts
 }
 
+// ErrStopped is returned by Run when the migration was stopped through
+// MigrationConfig.Stop
+var ErrStopped error = &kindError{msg: "migration stopped", kind: ErrInterrupted}
+
 // Migrator orchestrates the migration process
 type Migrator struct {
 	config    *MigrationConfig
 	source    vcs.VCSReader
-	target    *git.Writer
+	target    vcs.VCSWriter
 	authorMap *mapping.AuthorMap
 	reporter  *progress.Reporter
 	state     *MigrationState
 	db        *storage.StateDB
+	lock      *Lock // Held on the target while Run writes it (nil = none)
+	logger    *slog.Logger
+
+	committer      string // Fixed committer name (empty = use the source committer)
+	committerEmail string
+	refRenames     []mapping.RefRename
+	branchFilter   *mapping.RefFilter
+	tagFilter      *mapping.RefFilter
+
+	dateLocation    *time.Location            // Timezone of DateTimezone (nil = keep the source timezone)
+	authorLocations map[string]*time.Location // Timezones of DatePerAuthor
+	lastDate        time.Time                 // Date of the previous commit for MonotonicDates
+
+	revisionRules   []RevisionRule     // Loaded from RevisionRules
+	messageTemplate *template.Template // Parsed MessageTemplate (nil = none)
+	signKey         *openpgp.Entity    // Loaded from SigningKey (nil = unsigned)
+	unlicensed      map[string]bool    // Files a license rule matched without a comment style
+	binaryPaths     []string           // Files the source stores as binary, kept out of the EOL policy
+
+	contentCache *cvs.ContentCache // Shared by the CVS readers (nil = no cache)
+	textBudget   *cvs.TextBudget   // Shared by the CVS readers (nil = unlimited)
+	readLimit    *throttle.Limiter // Shared by the CVS readers and writers (nil = unlimited)
+
+	profileName    string    // Current commit as named in the profile
+	changesetStart time.Time // When preparing the current commit began
+
+	report          *MigrationReport
+	mappedAuthors   map[string]bool
+	unmappedAuthors map[string]bool
+	usedAuthors     map[string]string // Source login -> "Name <email>" written to the target
+	branchSources   map[string]string // Git branch -> CVS branch it was created for
+	issues          []Issue
 }
 
 // NewMigrator creates a new migrator
@@ -50,11 +159,120 @@ func NewMigrator(config *MigrationConfig) *Migrator {
 		config:    config,
 		authorMap: mapping.NewAuthorMap(config.AuthorMap),
 		reporter:  progress.NewReporter(0),
+		logger:    logging.OrDefault(config.Logger),
 	}
 }
 
-// Run executes the migration
-func (m *Migrator) Run() error {
+// Logger returns the logger used by the migrator
+func (m *Migrator) Logger() *slog.Logger {
+	return logging.OrDefault(m.logger)
+}
+
+// Run executes the migration. A MigrationReport is written next to the
+// target repository when it finishes, whether or not it succeeded.
+func (m *Migrator) Run() (err error) {
+	// Attach the migration ID to every log entry and optionally capture the
+	// entries in a per-migration log file
+	migrationID := m.MigrationID()
+	m.logger = logging.OrDefault(m.config.Logger).With("migration_id", migrationID)
+	if m.config.LogDir != "" {
+		migrationLog, err := logging.OpenMigrationLog(m.config.Logger, m.config.LogDir, migrationID)
+		if err != nil {
+			return fmt.Errorf("failed to open migration log: %w", err)
+		}
+		m.logger = migrationLog.Logger
+		defer func() {
+			if err := migrationLog.Close(); err != nil {
+				m.Logger().Warn("failed to close migration log", "error", err)
+			}
+		}()
+	}
+	m.resetReport(migrationID)
+	m.refRenames = nil
+	m.issues = nil
+	defer func() { m.finishReport(err) }()
+
+	m.Logger().Info("starting migration",
+		"source_type", m.config.SourceType,
+		"source", m.config.SourcePath,
+		"target", m.config.TargetPath,
+		"dry_run", m.config.DryRun,
+		"simulate", m.config.Simulate,
+	)
+
+	if m.config.Committer != "" {
+		name, email, err := mapping.ParseAuthor(m.config.Committer)
+		if err != nil {
+			return fmt.Errorf("invalid committer: %w", err)
+		}
+		m.committer, m.committerEmail = name, email
+	}
+
+	if err := m.validateCompat(); err != nil {
+		return err
+	}
+	if err := m.validateKeywords(); err != nil {
+		return err
+	}
+	if err := m.validateLicenseRules(); err != nil {
+		return err
+	}
+	if err := m.loadRevisionRules(); err != nil {
+		return err
+	}
+	if err := m.validateMessageTemplate(); err != nil {
+		return err
+	}
+	if err := m.validateReleaseTags(); err != nil {
+		return err
+	}
+	if err := m.validateEOL(); err != nil {
+		return err
+	}
+	if err := m.validateCaseConflicts(); err != nil {
+		return err
+	}
+	if err := m.validatePathNormalization(); err != nil {
+		return err
+	}
+	if err := m.validateDates(); err != nil {
+		return err
+	}
+	if err := m.validateHistoryLimits(); err != nil {
+		return err
+	}
+	if err := m.validateCommitLimits(); err != nil {
+		return err
+	}
+	if err := m.validateRenameSimilarity(); err != nil {
+		return err
+	}
+	if err := m.validateJoinModules(); err != nil {
+		return err
+	}
+	if err := m.validateVerifyManifest(); err != nil {
+		return err
+	}
+	if err := m.validateErrorPolicy(); err != nil {
+		return err
+	}
+	if err := m.validateSimulate(); err != nil {
+		return err
+	}
+	if err := m.validateGraft(); err != nil {
+		return err
+	}
+
+	branchFilter, err := mapping.NewRefFilter(m.config.IncludeBranches, m.config.ExcludeBranches)
+	if err != nil {
+		return fmt.Errorf("invalid branch filter: %w", err)
+	}
+	tagFilter, err := mapping.NewRefFilter(m.config.IncludeTags, m.config.ExcludeTags)
+	if err != nil {
+		return fmt.Errorf("invalid tag filter: %w", err)
+	}
+	m.branchFilter, m.tagFilter = branchFilter, tagFilter
+
 	// Initialize source reader (if not already set, e.g., in tests)
 	if m.source == nil {
 		if err := m.initSource(); err != nil {
@@ -69,13 +287,27 @@ func (m *Migrator) Run() error {
 
 	// Initialize target
 	if !m.config.DryRun {
+		if !m.config.Simulate {
+			lock, err := AcquireLock(LockPath(m.config.TargetPath), "migrate", m.config.ForceUnlock)
+			if err != nil {
+				return err
+			}
+			m.lock = lock
+			defer func() {
+				if err := lock.Release(); err != nil {
+					m.Logger().Warn("failed to release target lock", "error", err)
+				}
+				m.lock = nil
+			}()
+		}
+
 		if err := m.initTarget(); err != nil {
 			return fmt.Errorf("failed to init target: %w", err)
 		}
 		defer func() {
 			if err := m.target.Close(); err != nil {
 				// Log error but don't fail - cleanup is best effort
-				log.Printf("Warning: failed to close target repository: %v", err)
+				m.Logger().Warn("failed to close target repository", "error", err)
 			}
 		}()
 	}
@@ -87,33 +319,55 @@ func (m *Migrator) Run() error {
 	if m.db != nil {
 		defer func() {
 			if err := m.db.Close(); err != nil {
-				log.Printf("Warning: failed to close state db: %v", err)
+				m.Logger().Warn("failed to close state db", "error", err)
 			}
 		}()
+		if err := m.recoverPendingCommit(); err != nil {
+			return fmt.Errorf("failed to recover interrupted commit: %w", err)
+		}
 	}
 
 	// Get commits from source
-	iter, err := m.source.GetCommits()
+	m.reporter.StartPhase(progress.PhaseReadSource)
+	m.reporter.SetOperation("Reading source history")
+	commits, err := m.readCommits()
 	if err != nil {
-		return fmt.Errorf("failed to get commits: %w", err)
+		return err
 	}
+	m.warnDiagnostics()
+	m.warnSkippedFiles()
 
-	// Collect commits
-	var commits []*vcs.Commit
-	for iter.Next() {
-		commits = append(commits, iter.Commit())
+	if commits, err = m.applyRevisionRules(commits); err != nil {
+		return err
 	}
-	if err := iter.Err(); err != nil {
-		return fmt.Errorf("iterator error: %w", err)
+	if cycles := m.orderCommits(commits); cycles > 0 {
+		m.warn("commit dates contradict the revision history; ordered some commits by date", "cycles", cycles)
+	}
+	if commits, err = m.detectRenames(commits); err != nil {
+		return err
+	}
+	if err := m.rewritePaths(commits); err != nil {
+		return err
+	}
+	m.binaryPaths = binaryPaths(commits)
+	commits, m.report.Commits.Folded = m.limitHistory(commits)
+	if commits, m.report.Commits.Split, err = m.splitLargeCommits(commits); err != nil {
+		return err
+	}
+	m.reporter.SetTotal(len(commits))
+	m.report.Commits.Total = len(commits)
+
+	resuming := m.config.Resume && m.state != nil
+	grafted := 0
+	if m.config.Graft != nil && !m.config.DryRun {
+		if grafted, err = m.prepareGraft(commits, resuming && m.state.lastCommit != ""); err != nil {
+			return fmt.Errorf("failed to graft onto %s: %w", m.config.TargetPath, err)
+		}
 	}
-
-	m.reporter = progress.NewReporter(len(commits))
-	m.reporter.Start()
-	m.reporter.SetOperation("Starting migration")
 
 	// Determine start position (for resume)
 	startIdx := 0
-	if m.config.Resume && m.state != nil {
+	if resuming {
 		// Find the commit index to resume from
 		for i, c := range commits {
 			if c.Revision == m.state.lastCommit {
@@ -122,30 +376,137 @@ func (m *Migrator) Run() error {
 			}
 		}
 		m.reporter.SetCurrent(m.state.processed)
+		m.report.Commits.AlreadyApplied = startIdx
 	}
 
+	// The commits an existing import holds are not applied again
+	if g := m.report.Graft; g != nil && g.Mode == GraftOnto && startIdx < grafted {
+		startIdx = grafted
+		m.reporter.SetCurrent(grafted)
+	}
+
+	// Keep the content of the pending commits within the memory budget
+	var spool *commitSpool
+	if m.config.MemoryBudget > 0 {
+		if spool, err = m.startSpool(commits, startIdx); err != nil {
+			return err
+		}
+		defer func() {
+			if err := spool.close(); err != nil {
+				m.Logger().Warn("failed to remove spill directory", "error", err)
+			}
+		}()
+	}
+
+	// Start the clock after any resumed progress so throughput only
+	// reflects commits applied by this run
+	m.reporter.StartPhase(progress.PhaseApplyCommits)
+	m.reporter.Start()
+	m.reporter.SetOperation("Starting migration")
+
+	// The first commit written to the target carries the generated
+	// .gitattributes; a resumed run has written it already
+	targetEmpty := startIdx == 0
+
+	// Monotonic dates depend on the commits before the resume point, and
+	// the exported author map covers their authors too
+	m.lastDate = time.Time{}
+	for _, commit := range commits[:startIdx] {
+		m.applyDates(commit)
+		mapped := *commit
+		m.mapAuthor(&mapped)
+		m.recordAuthor(commit.Author, mapped.Author, mapped.Email)
+	}
+	datesMoved := 0
+
 	// Process commits
 	for i := startIdx; i < len(commits); i++ {
 		commit := commits[i]
+		if err := m.lock.Err(); err != nil {
+			return err
+		}
 
 		rev := commit.Revision
 		if len(rev) > 8 {
 			rev = rev[:8]
 		}
 		m.reporter.SetOperation(fmt.Sprintf("Processing commit %s", rev))
+		m.Logger().Debug("processing commit", "revision", commit.Revision, "author", commit.Author)
+
+		// Key the commit on its source identity before the author is mapped
+		sourceKey := sourceRevisionKey(commit)
+		if m.config.Profile != nil {
+			m.profileName = fmt.Sprintf("%s (files: %d)", sourceKey, len(commit.Files))
+			m.changesetStart = time.Now()
+		}
+
+		m.applyKeywords(commit)
+		m.applyLicenseHeaders(commit)
+		if m.applyDates(commit) {
+			m.Logger().Debug("moved commit date after its predecessor", "revision", commit.Revision, "date", commit.Date)
+			datesMoved++
+		}
+		m.applyDeterminism(commit)
 
 		// Map author
-		name, email := m.authorMap.Get(commit.Author)
-		commit.Author = name
-		commit.Email = email
+		login := commit.Author
+		if m.config.RequireAuthors {
+			if _, _, err := m.authorMap.Lookup(login); err != nil {
+				return fmt.Errorf("commit %s: %w", commit.Revision, err)
+			}
+		}
+		m.mapAuthor(commit)
+		m.recordAuthor(login, commit.Author, commit.Email)
+		m.applyCommitter(commit)
+		if err := m.applyMessageTemplate(commit, login); err != nil {
+			return err
+		}
 
-		// Apply commit (if not dry run)
+		// Apply commit (if not dry run), unless an earlier run already did
 		if !m.config.DryRun {
-			if err := m.target.ApplyCommit(commit); err != nil {
-				return fmt.Errorf("failed to apply commit %s: %w", commit.Revision, err)
+			if hash, ok := m.appliedHash(sourceKey); ok {
+				m.Logger().Debug("skipping already applied commit", "revision", commit.Revision, "git_hash", hash)
+				targetEmpty = false
+				m.report.Commits.AlreadyApplied++
+			} else if err := m.runBeforeHooks(commit); errors.Is(err, ErrSkipCommit) {
+				m.Logger().Info("commit skipped by hook", "revision", commit.Revision)
+				m.report.Commits.Vetoed++
+			} else if err != nil {
+				if !m.continuesOnError() {
+					return fmt.Errorf("pre-commit hook failed for %s: %w", commit.Revision, err)
+				}
+				m.fail("pre-commit hook failed", "revision", commit.Revision, "error", err)
+				m.report.Commits.Failed++
+			} else if err := m.beginCommit(sourceKey, commit, i+1, len(commits)); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			} else if err := m.applyCommit(commit, targetEmpty); err != nil {
+				// A timed-out commit may be half written, so it never continues
+				if !m.continuesOnError() || errors.Is(err, vcs.ErrTimeout) {
+					return err
+				}
+				m.fail("failed to apply commit", "revision", commit.Revision, "error", err)
+				m.report.Commits.Failed++
+			} else {
+				targetEmpty = false
+				m.report.Commits.Applied++
+				if err := m.recordRevision(sourceKey, commit, i+1, len(commits)); err != nil {
+					if !m.continuesOnError() {
+						return fmt.Errorf("failed to record revision mapping for %s: %w", commit.Revision, err)
+					}
+					m.fail("failed to record revision mapping", "revision", commit.Revision, "error", err)
+				}
+				if err := m.runAfterHooks(commit); err != nil {
+					if !m.continuesOnError() {
+						return fmt.Errorf("post-commit hook failed for %s: %w", commit.Revision, err)
+					}
+					m.fail("post-commit hook failed", "revision", commit.Revision, "error", err)
+				}
 			}
 		}
 
+		if spool != nil {
+			spool.release(commit)
+		}
 		m.reporter.Increment()
 
 		// Save state periodically
@@ -155,57 +516,250 @@ func (m *Migrator) Run() error {
 			}
 		}
 
+		// Pack the loose objects periodically so the target does not slow down
+		if !m.config.DryRun && !m.config.Simulate && m.config.RepackEvery > 0 && (i+1)%m.config.RepackEvery == 0 {
+			m.repack(false)
+		}
+
+		// Stop if the target was changed behind the migration's back
+		if !m.config.DryRun && m.config.VerifyEvery > 0 && (i+1)%m.config.VerifyEvery == 0 {
+			if err := m.checkIntegrity(); err != nil {
+				return err
+			}
+		}
+
+		// Stop on request, unless this was the last commit
+		if m.stopRequested() && i+1 < len(commits) {
+			if err := m.saveState(commit.Revision, i+1, len(commits)); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+			m.Logger().Info("migration stopped", "processed", i+1, "total", len(commits))
+			return ErrStopped
+		}
+
+		// Pause on request, unless this was the last commit
+		if resumed := m.config.Pause.wait(); resumed != nil && i+1 < len(commits) {
+			if err := m.pause(resumed, commit.Revision, i+1, len(commits)); err != nil {
+				return err
+			}
+		}
+
 		// Test interruption
 		if m.config.InterruptAt > 0 && i+1 >= m.config.InterruptAt {
 			if err := m.saveState(commit.Revision, i+1, len(commits)); err != nil {
 				// Log error but continue - this is test interruption
-				log.Printf("Warning: failed to save state during test interruption: %v", err)
+				m.Logger().Warn("failed to save state during test interruption", "error", err)
 			}
-			return fmt.Errorf("interrupted at commit %d", i+1)
+			return fmt.Errorf("%w at commit %d", ErrInterrupted, i+1)
+		}
+	}
+	if datesMoved > 0 {
+		m.Logger().Info("moved commit dates to keep history monotonic", "commits", datesMoved)
+	}
+
+	if !m.config.DryRun {
+		if err := m.finishGraft(commits, grafted); err != nil {
+			return fmt.Errorf("failed to replace graft commit: %w", err)
 		}
 	}
 
 	// Create branches
 	if !m.config.DryRun {
-		if err := m.createBranches(); err != nil {
+		m.reporter.StartPhase(progress.PhaseBranches)
+		if err := withTimeout(m.config.Timeouts.Refs, m.createBranches, m.target); err != nil {
 			return fmt.Errorf("failed to create branches: %w", err)
 		}
+		if m.config.Compat == CompatCVSImport {
+			if err := m.finishCVSImport(); err != nil {
+				return fmt.Errorf("failed to create %s: %w", cvsImportHeadBranch, err)
+			}
+		}
 	}
 
 	// Create tags
 	if !m.config.DryRun {
-		if err := m.createTags(); err != nil {
+		m.reporter.StartPhase(progress.PhaseTags)
+		if err := withTimeout(m.config.Timeouts.Refs, m.createTags, m.target); err != nil {
 			return fmt.Errorf("failed to create tags: %w", err)
 		}
 	}
 
+	if !m.config.DryRun && m.config.ImportHistory {
+		m.importHistory()
+	}
+
+	// Final repack
+	if !m.config.DryRun && !m.config.Simulate && m.config.RepackEvery > 0 {
+		m.reporter.StartPhase(progress.PhaseRepack)
+		m.repack(true)
+	}
+
+	// Push to remote
+	if !m.config.DryRun && m.config.Push != nil {
+		m.reporter.StartPhase(progress.PhasePush)
+		if err := m.push(); err != nil {
+			return fmt.Errorf("failed to push: %w", err)
+		}
+	}
+	m.reporter.EndPhase()
+
 	// Mark complete
 	if !m.config.DryRun {
+		m.verify()
+		if m.config.Simulate {
+			m.recordSimulation()
+		}
 		if err := m.markComplete(); err != nil {
 			return fmt.Errorf("failed to mark complete: %w", err)
 		}
 	}
 
 	m.reporter.SetOperation("Migration complete")
+	status := m.reporter.Status()
+	m.Logger().Info("migration complete",
+		"commits", len(commits),
+		"elapsed", status.Elapsed,
+		"commits_per_sec", status.Rate,
+	)
 
 	return nil
 }
 
+// stopRequested reports whether MigrationConfig.Stop has been closed
+func (m *Migrator) stopRequested() bool {
+	select {
+	case <-m.config.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// readCommits collects the commits of the source within the SourceScan
+// timeout
+func (m *Migrator) readCommits() ([]*vcs.Commit, error) {
+	var commits []*vcs.Commit
+	err := withTimeout(m.config.Timeouts.SourceScan, func() error {
+		iter, err := m.source.GetCommits()
+		if err != nil {
+			return fmt.Errorf("failed to get commits: %w", err)
+		}
+		var read []*vcs.Commit
+		for iter.Next() {
+			read = append(read, iter.Commit())
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("iterator error: %w", err)
+		}
+		commits = read
+		return nil
+	}, m.source)
+	if errors.Is(err, vcs.ErrTimeout) {
+		return nil, fmt.Errorf("failed to read source history: %w", err)
+	}
+	return commits, err
+}
+
+// applyCommit normalizes and writes a commit to the target
+func (m *Migrator) applyCommit(commit *vcs.Commit, first bool) error {
+	m.applyEOL(commit)
+	m.applyAttributes(commit, first)
+	m.resolveParents(commit)
+	if m.config.Profile != nil {
+		m.config.Profile.Record(profile.KindChangeset, m.profileName, time.Since(m.changesetStart))
+		defer m.config.Profile.Start(profile.KindApply, m.profileName)()
+	}
+	err := withTimeout(m.config.Timeouts.Commit, func() error { return m.applyWithRetry(commit) }, m.target)
+	if err != nil {
+		return fmt.Errorf("failed to apply commit %s: %w", commit.Revision, err)
+	}
+	return nil
+}
+
+// applyCommitter replaces the committer of a commit with the configured fixed
+// committer, keeping the original author and commit date
+func (m *Migrator) applyCommitter(commit *vcs.Commit) {
+	if m.committer == "" {
+		return
+	}
+	commit.Committer = m.committer
+	commit.CommitterEmail = m.committerEmail
+}
+
 func (m *Migrator) initSource() error {
 	switch m.config.SourceType {
 	case "cvs":
-		m.source = cvs.NewReader(m.config.SourcePath)
+		if m.config.ContentCacheSize >= 0 {
+			cache, err := cvs.NewContentCache(m.contentCacheSize(), m.config.ContentCacheDir)
+			if err != nil {
+				return err
+			}
+			m.contentCache = cache
+		}
+		if m.config.MemoryBudget > 0 {
+			m.textBudget = cvs.NewTextBudget(m.config.MemoryBudget - m.cacheReserve())
+		}
+		if len(m.config.JoinModules) > 0 {
+			m.source = m.newJoinReader()
+			return nil
+		}
+		reader := cvs.NewModuleReader(m.config.SourcePath, m.config.SourceModule)
+		reader.SetContentCache(m.contentCache)
+		reader.SetTextBudget(m.textBudget)
+		reader.SetProfile(m.config.Profile)
+		reader.SetStrict(m.config.StrictParsing)
+		reader.SetParseWorkers(m.config.ParseWorkers)
+		reader.SetReadLimit(m.ioLimit())
+		reader.SetClient(m.cvsClient())
+		m.source = reader
 	default:
 		return fmt.Errorf("unsupported source type: %s", m.config.SourceType)
 	}
 	return nil
 }
 
+// cvsClient returns how the cvs client reading remote sources is run
+func (m *Migrator) cvsClient() cvs.Client {
+	client := m.config.CVSClient
+	client.Timeout = m.config.Timeouts.CVSCommand
+	return client
+}
+
+// ioLimit returns the limiter pacing the CVS readers and writers, shared so
+// that together they stay within ReadLimit and OpsLimit
+func (m *Migrator) ioLimit() *throttle.Limiter {
+	if m.readLimit == nil {
+		m.readLimit = throttle.NewWithOps(m.config.ReadLimit, m.config.OpsLimit)
+	}
+	return m.readLimit
+}
+
 func (m *Migrator) initTarget() error {
-	m.target = git.NewWriter()
+	targetType := m.config.TargetType
+	if targetType == "" {
+		targetType = "git"
+	}
+
+	target, err := vcs.NewWriter(targetType, m.config.TargetOpts)
+	if err != nil {
+		return err
+	}
+	m.target = target
+	if ls, ok := target.(interface{ SetLogger(*slog.Logger) }); ok {
+		ls.SetLogger(m.Logger())
+	}
+	if ls, ok := target.(interface{ SetIOLimit(*throttle.Limiter) }); ok {
+		ls.SetIOLimit(m.ioLimit())
+	}
 
 	// Check if target exists
-	if _, err := os.Stat(m.config.TargetPath); os.IsNotExist(err) {
+	if m.config.Simulate {
+		if err := m.initSimulatedTarget(targetType); err != nil {
+			return err
+		}
+	} else if fresh, err := m.checkTarget(); err != nil {
+		return err
+	} else if fresh {
 		// Create new repo
 		if err := m.target.Init(m.config.TargetPath); err != nil {
 			return err
@@ -217,11 +771,22 @@ func (m *Migrator) initTarget() error {
 		}
 	}
 
+	// Trunk history is written to the default branch
+	if trunk := m.trunkBranch(); trunk != "" {
+		setter, ok := m.target.(interface{ SetDefaultBranch(string) error })
+		if !ok {
+			return fmt.Errorf("target type %s does not support setting the default branch", targetType)
+		}
+		if err := setter.SetDefaultBranch(trunk); err != nil {
+			return fmt.Errorf("failed to set default branch: %w", err)
+		}
+	}
+
 	return nil
 }
 
 func (m *Migrator) initState() error {
-	migrationID := m.generateMigrationID()
+	migrationID := m.MigrationID()
 
 	// In dry run mode, skip database creation but still initialize in-memory state
 	if m.config.DryRun {
@@ -231,11 +796,7 @@ func (m *Migrator) initState() error {
 		return nil
 	}
 
-	if m.config.StateFile == "" {
-		m.config.StateFile = filepath.Join(m.config.TargetPath, ".migration-state.db")
-	}
-
-	db, err := storage.NewStateDB(m.config.StateFile)
+	db, err := m.openStateDB()
 	if err != nil {
 		return err
 	}
@@ -256,12 +817,32 @@ func (m *Migrator) initState() error {
 		}
 	}
 
-	return nil
+	m.loadSnapshot()
+	return m.loadIssues()
+}
+
+// openStateDB opens the state database, defaulting StateFile to a file
+// inside the target repository
+func (m *Migrator) openStateDB() (*storage.StateDB, error) {
+	if m.config.Simulate {
+		return openSimulationDB()
+	}
+	if m.config.StateFile == "" {
+		m.config.StateFile = filepath.Join(m.config.TargetPath, ".migration-state.db")
+	}
+	return storage.NewStateDB(m.config.StateFile)
 }
 
-func (m *Migrator) generateMigrationID() string {
-	// Generate a unique ID based on source and target paths
+// MigrationID returns the ID of the migration, which is derived from its
+// source and target so that every run of the same migration shares it
+func (m *Migrator) MigrationID() string {
 	data := m.config.SourcePath + ":" + m.config.TargetPath
+	if m.config.SourceModule != "" {
+		data = m.config.SourcePath + "/" + m.config.SourceModule + ":" + m.config.TargetPath
+	}
+	if len(m.config.JoinModules) > 0 {
+		data = m.config.SourcePath + "/{" + m.joinedModules() + "}:" + m.config.TargetPath
+	}
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:8])
 }
@@ -276,17 +857,148 @@ func (m *Migrator) saveState(lastCommit string, processed, total int) error {
 		return nil
 	}
 
-	state := &storage.MigrationState{
+	state := m.stateRecord("in_progress")
+	return m.retry("save state", func() error { return m.db.Save(state) })
+}
+
+// stateRecord returns the current state for the state database
+func (m *Migrator) stateRecord(status string) *storage.MigrationState {
+	return &storage.MigrationState{
 		MigrationID: m.state.migrationID,
-		LastCommit:  lastCommit,
-		Processed:   processed,
-		Total:       total,
+		LastCommit:  m.state.lastCommit,
+		Processed:   m.state.processed,
+		Total:       m.state.total,
 		SourcePath:  m.config.SourcePath,
 		TargetPath:  m.config.TargetPath,
-		Status:      "in_progress",
+		Status:      status,
 	}
+}
+
+// sourceRevisionKey identifies a source commit across runs. CVS revision
+// numbers are per file, so the author and date are part of the key.
+func sourceRevisionKey(commit *vcs.Commit) string {
+	return fmt.Sprintf("%s|%s|%d", commit.Revision, commit.Author, commit.Date.Unix())
+}
+
+// appliedHash returns the Git hash an earlier run recorded for the source
+// revision, provided that commit still exists in the target repository
+func (m *Migrator) appliedHash(sourceKey string) (string, bool) {
+	tracker, ok := m.target.(vcs.CommitTracker)
+	if m.db == nil || !ok {
+		return "", false
+	}
+
+	hash, ok, err := m.db.LookupRevision(m.state.migrationID, sourceKey)
+	if err != nil {
+		m.Logger().Warn("failed to look up revision mapping", "revision", sourceKey, "error", err)
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+	if !tracker.HasCommit(hash) {
+		// The target was reset or recreated; the mapping is stale
+		m.Logger().Debug("ignoring stale revision mapping", "revision", sourceKey, "git_hash", hash)
+		return "", false
+	}
+	return hash, true
+}
+
+// resolveParents replaces the merged commits of a merge that are recorded
+// in the revision map, such as "path:rev" file revisions or commits applied
+// by an earlier run, with their Git hashes. Other parents are left for the
+// target to resolve.
+func (m *Migrator) resolveParents(commit *vcs.Commit) {
+	for i, parent := range commit.Parents {
+		if hash, ok := m.appliedHash(parent); ok {
+			commit.Parents[i] = hash
+		}
+	}
+}
+
+// revisionKeys returns the source revisions a commit is mapped under: the
+// commit itself and each file revision ("path:rev"), which is how CVS
+// revisions are usually referred to outside the repository
+func revisionKeys(sourceKey string, commit *vcs.Commit) []string {
+	keys := []string{sourceKey}
+	for _, fc := range commit.Files {
+		if fc.Revision != "" {
+			keys = append(keys, fc.Path+":"+fc.Revision)
+		}
+	}
+	return keys
+}
 
-	return m.db.Save(state)
+// beginCommit records the commit about to be written to the target, so that
+// a crash before its checkpoint is recovered instead of replaying the commit
+func (m *Migrator) beginCommit(sourceKey string, commit *vcs.Commit, processed, total int) error {
+	if _, ok := m.target.(vcs.CommitTracker); m.db == nil || !ok {
+		return nil
+	}
+	pending := &storage.PendingCommit{
+		MigrationID: m.state.migrationID,
+		Revisions:   revisionKeys(sourceKey, commit),
+		LastCommit:  commit.Revision,
+		Processed:   processed,
+		Total:       total,
+	}
+	if heads, ok := m.target.(vcs.HeadTracker); ok {
+		pending.ParentHash = heads.HeadHash()
+	}
+	return m.retry("record pending commit", func() error { return m.db.BeginCommit(pending) })
+}
+
+// recordRevision stores the mapping from the source revision to the commit
+// just created in the target, together with the state, as one checkpoint
+func (m *Migrator) recordRevision(sourceKey string, commit *vcs.Commit, processed, total int) error {
+	tracker, ok := m.target.(vcs.CommitTracker)
+	if m.db == nil || !ok {
+		return nil
+	}
+	hash := tracker.LastCommitHash()
+	if hash == "" {
+		return nil
+	}
+
+	m.state.lastCommit = commit.Revision
+	m.state.processed = processed
+	m.state.total = total
+	state := m.stateRecord("in_progress")
+	keys := revisionKeys(sourceKey, commit)
+	return m.retry("save checkpoint", func() error { return m.db.Checkpoint(state, keys, hash) })
+}
+
+// recoverPendingCommit settles the commit an interrupted run was writing.
+// If it reached the target, its checkpoint is completed so the commit is not
+// applied twice; otherwise it is forgotten and applied again.
+func (m *Migrator) recoverPendingCommit() error {
+	pending, err := m.db.PendingCommit(m.state.migrationID)
+	if errors.Is(err, storage.ErrNoPendingCommit) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	head := ""
+	if heads, ok := m.target.(vcs.HeadTracker); ok {
+		head = heads.HeadHash()
+	}
+	if head == "" || head == pending.ParentHash {
+		m.Logger().Info("interrupted commit was not written, applying it again", "revision", pending.LastCommit)
+		return m.db.ClearPendingCommit(m.state.migrationID)
+	}
+
+	m.Logger().Warn("recovering commit written before the interruption", "revision", pending.LastCommit, "git_hash", head)
+	state := m.stateRecord("in_progress")
+	state.LastCommit, state.Processed, state.Total = pending.LastCommit, pending.Processed, pending.Total
+	if err := m.db.Checkpoint(state, pending.Revisions, head); err != nil {
+		return err
+	}
+	if m.config.Resume {
+		m.state.lastCommit, m.state.processed, m.state.total = pending.LastCommit, pending.Processed, pending.Total
+	}
+	return nil
 }
 
 func (m *Migrator) createBranches() error {
@@ -294,18 +1006,44 @@ func (m *Migrator) createBranches() error {
 	if err != nil {
 		return err
 	}
+	sort.Strings(branches)
 
+	report := m.currentReport()
+	namer := mapping.NewRefNamer()
+	if trunk := m.trunkBranch(); trunk != "" {
+		namer.Reserve(trunk)
+	}
+	skipped := 0
 	for _, branch := range branches {
+		if !m.branchFilter.Allows(branch) {
+			skipped++
+			report.Branches.Filtered = append(report.Branches.Filtered, branch)
+			continue
+		}
 		gitBranch := branch
 		if mapped, ok := m.config.BranchMap[branch]; ok {
 			gitBranch = mapped
 		}
+		gitBranch = m.assignRefName(namer, "branch", gitBranch)
 
 		m.reporter.SetOperation(fmt.Sprintf("Creating branch %s", gitBranch))
-		if err := m.target.CreateBranch(gitBranch, "HEAD"); err != nil {
-			// Log error but don't fail - branch creation is best effort
-			log.Printf("Warning: failed to create branch %s: %v", gitBranch, err)
+		err := m.target.CreateBranch(gitBranch, "HEAD")
+		m.reporter.RecordRef(progress.RefBranch, gitBranch, err)
+		if err != nil {
+			report.Branches.Failed[gitBranch] = err.Error()
+			if err := m.refFailure("branch", gitBranch, err); err != nil {
+				return err
+			}
+			continue
 		}
+		report.Branches.Created = append(report.Branches.Created, gitBranch)
+		if m.branchSources == nil {
+			m.branchSources = make(map[string]string)
+		}
+		m.branchSources[gitBranch] = branch
+	}
+	if skipped > 0 {
+		m.Logger().Info("skipped filtered branches", "count", skipped)
 	}
 
 	return nil
@@ -317,40 +1055,192 @@ func (m *Migrator) createTags() error {
 		return err
 	}
 
-	for tagName, commitHash := range tags {
+	report := m.currentReport()
+	names := make([]string, 0, len(tags))
+	for tagName := range tags {
+		if m.tagFilter.Allows(tagName) {
+			names = append(names, tagName)
+		} else {
+			report.Tags.Filtered = append(report.Tags.Filtered, tagName)
+		}
+	}
+	if skipped := len(tags) - len(names); skipped > 0 {
+		m.Logger().Info("skipped filtered tags", "count", skipped)
+	}
+	sort.Strings(names)
+	sort.Strings(report.Tags.Filtered)
+
+	var details map[string]vcs.TagInfo
+	if detailer, ok := m.source.(vcs.TagDetailer); ok {
+		if details, err = detailer.GetTagDetails(); err != nil {
+			return err
+		}
+	}
+
+	namer := mapping.NewRefNamer()
+	var releases []releaseTag
+	for _, tagName := range names {
 		gitTag := tagName
 		if mapped, ok := m.config.TagMap[tagName]; ok {
 			gitTag = mapped
 		}
+		gitTag = m.assignRefName(namer, "tag", gitTag)
+
+		info, hasInfo := details[tagName]
+		revision := tags[tagName]
+		if hasInfo {
+			revision = m.tagTarget(info, revision)
+		}
+
+		if m.isReleaseTag(gitTag) {
+			releases = append(releases, releaseTag{name: gitTag, symbol: tagName, revision: revision, info: info})
+			continue
+		}
 
 		m.reporter.SetOperation(fmt.Sprintf("Creating tag %s", gitTag))
-		if err := m.target.CreateTag(gitTag, commitHash, ""); err != nil {
-			// Log error but don't fail - tag creation is best effort
-			log.Printf("Warning: failed to create tag %s: %v", gitTag, err)
+		if err := m.recordTag(report, gitTag, m.createTag(gitTag, tagName, revision, info)); err != nil {
+			return err
 		}
 	}
 
+	return m.createReleaseTags(report, releases)
+}
+
+// recordTag reports the creation of a tag, returning err if it fails the
+// migration
+func (m *Migrator) recordTag(report *MigrationReport, gitTag string, err error) error {
+	m.reporter.RecordRef(progress.RefTag, gitTag, err)
+	if err != nil {
+		report.Tags.Failed[gitTag] = err.Error()
+		return m.refFailure("tag", gitTag, err)
+	}
+	report.Tags.Created = append(report.Tags.Created, gitTag)
 	return nil
 }
 
-func (m *Migrator) markComplete() error {
-	m.reporter.SetOperation("Finalizing migration")
+// DefaultTagMessage is the annotated tag message used when
+// MigrationConfig.TagMessage is empty
+const DefaultTagMessage = "Converted from CVS tag {symbol}"
 
-	state := &storage.MigrationState{
-		MigrationID: m.state.migrationID,
-		LastCommit:  m.state.lastCommit,
-		Processed:   m.state.processed,
-		Total:       m.state.total,
-		SourcePath:  m.config.SourcePath,
-		TargetPath:  m.config.TargetPath,
-		Status:      "completed",
+// createTag creates a lightweight tag, or an annotated tag carrying the
+// original symbol name and tagging date when AnnotatedTags is set
+func (m *Migrator) createTag(gitTag, symbol, revision string, info vcs.TagInfo) error {
+	if !m.config.AnnotatedTags {
+		return m.target.CreateTag(gitTag, revision, "")
+	}
+
+	opts := m.tagOptions(gitTag, symbol, info)
+	if annotator, ok := m.target.(interface {
+		CreateAnnotatedTag(name, revision string, opts git.TagOptions) error
+	}); ok {
+		return annotator.CreateAnnotatedTag(gitTag, revision, opts)
+	}
+	return m.target.CreateTag(gitTag, revision, opts.Message)
+}
+
+// tagOptions returns the tagger, date and message of an annotated tag
+func (m *Migrator) tagOptions(gitTag, symbol string, info vcs.TagInfo) git.TagOptions {
+	template := m.config.TagMessage
+	if template == "" {
+		template = DefaultTagMessage
+	}
+	opts := git.TagOptions{Date: info.Date}
+	if info.Author != "" {
+		opts.Tagger, opts.Email = m.authorMap.Get(info.Author)
+		m.usedAuthors[info.Author] = fmt.Sprintf("%s <%s>", opts.Tagger, opts.Email)
+	}
+	if m.committer != "" {
+		opts.Tagger, opts.Email = m.committer, m.committerEmail
+	}
+
+	date := ""
+	if !info.Date.IsZero() {
+		date = info.Date.UTC().Format(time.RFC3339)
+	}
+	opts.Message = strings.NewReplacer(
+		"{tag}", gitTag,
+		"{symbol}", symbol,
+		"{date}", date,
+		"{author}", opts.Tagger,
+	).Replace(template)
+	if !strings.HasSuffix(opts.Message, "\n") {
+		opts.Message += "\n"
+	}
+	return opts
+}
+
+// tagTarget returns the commit that introduced the newest tagged file
+// revision, falling back to revision if it is not in the revision map
+func (m *Migrator) tagTarget(info vcs.TagInfo, revision string) string {
+	if m.db == nil || m.state == nil || info.Path == "" {
+		return revision
+	}
+	hash, ok, err := m.db.LookupRevision(m.state.migrationID, info.Path+":"+info.Revision)
+	if err != nil {
+		m.Logger().Warn("failed to look up tagged revision", "path", info.Path, "revision", info.Revision, "error", err)
+		return revision
 	}
+	if !ok {
+		return revision
+	}
+	return hash
+}
+
+// assignRefName sanitizes a branch or tag name and records any rename
+func (m *Migrator) assignRefName(namer *mapping.RefNamer, kind, name string) string {
+	sanitized := namer.Assign(name)
+	if sanitized != name {
+		m.Logger().Info("renamed "+kind, "original", name, "sanitized", sanitized)
+		m.refRenames = append(m.refRenames, mapping.RefRename{Kind: kind, Original: name, Sanitized: sanitized})
+	}
+	return sanitized
+}
+
+// RefRenames returns the branches and tags that were renamed because their
+// names were not valid Git ref names or collided with another ref
+func (m *Migrator) RefRenames() []mapping.RefRename {
+	return m.refRenames
+}
+
+// push sends all branches and tags to the configured remote
+func (m *Migrator) push() error {
+	pusher, ok := m.target.(interface{ Push(git.PushOptions) error })
+	if !ok {
+		return fmt.Errorf("target does not support pushing to a remote")
+	}
+
+	remote := git.RedactURL(m.config.Push.URL)
+	m.reporter.SetOperation(fmt.Sprintf("Pushing to %s", remote))
+	m.Logger().Info("pushing to remote", "url", remote, "force_with_lease", m.config.Push.ForceWithLease)
+	return pusher.Push(*m.config.Push)
+}
+
+// repack packs the objects of the target; full packs everything, otherwise
+// the target decides whether enough loose objects accumulated. Packing only
+// saves space and time, so a failure is a warning.
+func (m *Migrator) repack(full bool) {
+	repacker, ok := m.target.(interface{ Repack(full bool) error })
+	if !ok {
+		return
+	}
+	m.reporter.SetOperation("Packing objects")
+	start := time.Now()
+	if err := repacker.Repack(full); err != nil {
+		m.warn("failed to repack target", "error", err)
+		return
+	}
+	m.Logger().Debug("packed target objects", "full", full, "elapsed", time.Since(start))
+}
+
+func (m *Migrator) markComplete() error {
+	m.reporter.SetOperation("Finalizing migration")
 
-	if err := m.db.Save(state); err != nil {
+	state := m.stateRecord("completed")
+	if err := m.retry("save state", func() error { return m.db.Save(state) }); err != nil {
 		return err
 	}
 
-	return m.db.Complete(m.state.migrationID)
+	return m.retry("complete state", func() error { return m.db.Complete(m.state.migrationID) })
 }
 
 // ProgressReporter returns the progress reporter for subscribing to updates
@@ -385,9 +1275,9 @@ func (s *SimpleState) Save(commit string, processed, total int) error {
 	s.processed = processed
 	s.total = total
 
-	// Write to file
+	// Replace the file atomically so a crash never leaves a partial state
 	data := fmt.Sprintf("%s\n%d\n%d", commit, processed, total)
-	return os.WriteFile(s.path, []byte(data), 0644)
+	return storage.WriteFileAtomic(s.path, []byte(data), 0644)
 }
 
 // Load loads the state from file
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/mapping"
 	"github.com/adamf123git/git-migrator/internal/progress"
 	"github.com/adamf123git/git-migrator/internal/storage"
 	"github.com/adamf123git/git-migrator/internal/vcs"
@@ -105,6 +106,45 @@ func TestCreateTags_TargetErrorsAndSuccess(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestCreateBranchesAndTags_SanitizesNames(t *testing.T) {
+	w := git.NewWriter()
+	require.NoError(t, w.Init(t.TempDir()))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "test", Email: "test@example.com", Date: time.Now(), Message: "initial"}))
+	head := w.LastCommitHash()
+
+	m := &Migrator{
+		config: &MigrationConfig{
+			BranchMap: map[string]string{"dev": "Feature X"},
+			TagMap:    map[string]string{},
+		},
+		source: &mockSource{
+			branches: []string{"dev", "feature_x", "FEATURE_X"},
+			tags:     map[string]string{"nightly..1": head, "Nightly.1": head, "v1~beta": head},
+		},
+		target:   w,
+		reporter: progress.NewReporter(0),
+	}
+	require.NoError(t, m.createBranches())
+	require.NoError(t, m.createTags())
+
+	branches, err := w.ListBranches()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"master", "FEATURE_X", "Feature_X-2", "feature_x-3"}, branches)
+
+	tags, err := w.ListTags()
+	require.NoError(t, err)
+	assert.Contains(t, tags, "Nightly.1")
+	assert.Contains(t, tags, "nightly.1-2")
+	assert.Contains(t, tags, "v1_beta")
+
+	assert.Equal(t, []mapping.RefRename{
+		{Kind: "branch", Original: "Feature X", Sanitized: "Feature_X-2"},
+		{Kind: "branch", Original: "feature_x", Sanitized: "feature_x-3"},
+		{Kind: "tag", Original: "nightly..1", Sanitized: "nightly.1-2"},
+		{Kind: "tag", Original: "v1~beta", Sanitized: "v1_beta"},
+	}, m.RefRenames())
+}
+
 func TestMarkCompleteAndSaveState(t *testing.T) {
 	dbPath := filepath.Join(t.TempDir(), "state.db")
 	sdb, err := storage.NewStateDB(dbPath)
@@ -149,3 +189,14 @@ func TestSimpleState_SaveLoadClear(t *testing.T) {
 	_, err = os.Stat(path)
 	require.True(t, os.IsNotExist(err))
 }
+
+func TestIOLimit(t *testing.T) {
+	require.Nil(t, NewMigrator(&MigrationConfig{}).ioLimit())
+
+	// The CVS readers and writers share one limiter
+	m := NewMigrator(&MigrationConfig{ReadLimit: 1 << 20, OpsLimit: 100})
+	limit := m.ioLimit()
+	require.Same(t, limit, m.ioLimit())
+	require.Equal(t, int64(1<<20), limit.Rate())
+	require.Equal(t, int64(100), limit.Ops())
+}
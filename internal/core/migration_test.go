@@ -214,7 +214,7 @@ func TestMigratorGenerateMigrationID(t *testing.T) {
 	}
 
 	m := NewMigrator(config)
-	id := m.generateMigrationID()
+	id := m.MigrationID()
 
 	if id == "" {
 		t.Error("migration ID should not be empty")
@@ -225,14 +225,14 @@ func TestMigratorGenerateMigrationID(t *testing.T) {
 	}
 
 	// Same paths should generate same ID
-	id2 := m.generateMigrationID()
+	id2 := m.MigrationID()
 	if id != id2 {
 		t.Error("Same paths should generate same ID")
 	}
 
 	// Different paths should generate different ID
 	m.config.SourcePath = "/different/source"
-	id3 := m.generateMigrationID()
+	id3 := m.MigrationID()
 	if id == id3 {
 		t.Error("Different paths should generate different ID")
 	}
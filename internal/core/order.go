@@ -0,0 +1,151 @@
+package core
+
+import (
+	"container/heap"
+	"strconv"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// previousRevision returns the revision a CVS file revision derives from:
+// "1.3" derives from "1.2" and the first revision of a branch, "1.2.2.1",
+// from its branch point "1.2". It returns "" for first revisions and
+// revisions that are not CVS revision numbers.
+func previousRevision(rev string) string {
+	parts := strings.Split(rev, ".")
+	if len(parts) < 2 || len(parts)%2 != 0 {
+		return ""
+	}
+	last, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || last < 1 {
+		return ""
+	}
+	if last > 1 {
+		parts[len(parts)-1] = strconv.Itoa(last - 1)
+		return strings.Join(parts, ".")
+	}
+	if len(parts) == 2 {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-2], ".")
+}
+
+// orderCommits sorts the commits read from the source topologically: a
+// commit comes after the commits holding the previous revision of each of
+// its files, so a branch commit never precedes its branch point even when
+// clock skew dates it earlier. Among the commits whose predecessors are
+// placed, the oldest comes first. Ties keep the source order or, with
+// MigrationConfig.Deterministic, are ordered by branch, author, message and
+// revision so the order does not depend on how the source was read.
+//
+// Dependency cycles, which only arise from inconsistent source dates, are
+// broken by placing the oldest remaining commit; the number of cycles
+// broken is returned.
+func (m *Migrator) orderCommits(commits []*vcs.Commit) int {
+	// Index the commit holding every file revision
+	holder := make(map[string]int)
+	for i, c := range commits {
+		for _, fc := range c.Files {
+			if fc.Revision != "" {
+				holder[fc.Path+"@"+fc.Revision] = i
+			}
+		}
+	}
+
+	dependents := make([][]int, len(commits))
+	pending := make([]int, len(commits)) // Unplaced predecessors
+	for i, c := range commits {
+		seen := make(map[int]bool)
+		for _, fc := range c.Files {
+			prev := previousRevision(fc.Revision)
+			if prev == "" {
+				continue
+			}
+			if j, ok := holder[fc.Path+"@"+prev]; ok && j != i && !seen[j] {
+				seen[j] = true
+				dependents[j] = append(dependents[j], i)
+				pending[i]++
+			}
+		}
+	}
+
+	queue := &commitQueue{commits: commits, deterministic: m.config.Deterministic}
+	for i := range commits {
+		if pending[i] == 0 {
+			heap.Push(queue, i)
+		}
+	}
+
+	ordered := make([]*vcs.Commit, 0, len(commits))
+	placed := make([]bool, len(commits))
+	cycles := 0
+	for len(ordered) < len(commits) {
+		if queue.Len() == 0 {
+			// Every remaining commit waits for another: break the cycle
+			next := -1
+			for i := range commits {
+				if !placed[i] && (next < 0 || queue.less(i, next)) {
+					next = i
+				}
+			}
+			pending[next] = 0
+			heap.Push(queue, next)
+			cycles++
+		}
+		i := heap.Pop(queue).(int)
+		if placed[i] {
+			continue
+		}
+		placed[i] = true
+		ordered = append(ordered, commits[i])
+		for _, d := range dependents[i] {
+			if pending[d]--; pending[d] == 0 && !placed[d] {
+				heap.Push(queue, d)
+			}
+		}
+	}
+	copy(commits, ordered)
+	return cycles
+}
+
+// commitQueue is a heap of commit indexes, oldest commit first
+type commitQueue struct {
+	commits       []*vcs.Commit
+	deterministic bool
+	items         []int
+}
+
+// less orders two commits by date, then by content or source position
+func (q *commitQueue) less(i, j int) bool {
+	a, b := q.commits[i], q.commits[j]
+	if !a.Date.Equal(b.Date) {
+		return a.Date.Before(b.Date)
+	}
+	if q.deterministic {
+		if c := strings.Compare(a.Branch, b.Branch); c != 0 {
+			return c < 0
+		}
+		if c := strings.Compare(a.Author, b.Author); c != 0 {
+			return c < 0
+		}
+		if c := strings.Compare(a.Message, b.Message); c != 0 {
+			return c < 0
+		}
+		if a.Revision != b.Revision {
+			return a.Revision < b.Revision
+		}
+	}
+	return i < j
+}
+
+func (q *commitQueue) Len() int           { return len(q.items) }
+func (q *commitQueue) Less(i, j int) bool { return q.less(q.items[i], q.items[j]) }
+func (q *commitQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *commitQueue) Push(x any)         { q.items = append(q.items, x.(int)) }
+func (q *commitQueue) Pop() any {
+	n := len(q.items)
+	x := q.items[n-1]
+	q.items = q.items[:n-1]
+	return x
+}
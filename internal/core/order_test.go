@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviousRevision(t *testing.T) {
+	for rev, want := range map[string]string{
+		"1.1":         "",
+		"1.3":         "1.2",
+		"1.2.2.1":     "1.2",
+		"1.2.2.3":     "1.2.2.2",
+		"1.1.1.1":     "1.1",
+		"1.2.2.1.4.1": "1.2.2.1",
+		"":            "",
+		"abc123":      "",
+		"1.2.2":       "",
+		"1.x":         "",
+	} {
+		require.Equal(t, want, previousRevision(rev), rev)
+	}
+}
+
+// revCommit returns a commit changing f.txt at the given revision
+func revCommit(message, rev string, date time.Time) *vcs.Commit {
+	return &vcs.Commit{Message: message, Revision: rev, Date: date, Files: []vcs.FileChange{
+		{Path: "f.txt", Action: vcs.ActionModify, Revision: rev},
+	}}
+}
+
+func messages(commits []*vcs.Commit) []string {
+	var out []string
+	for _, c := range commits {
+		out = append(out, c.Message)
+	}
+	return out
+}
+
+func TestOrderCommits_BranchPoint(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// The branch was created from 1.2, but a skewed clock dates its first
+	// commit before 1.2; trunk and branch commits interleave afterwards
+	commits := []*vcs.Commit{
+		revCommit("1.1", "1.1", base),
+		revCommit("1.2.2.1", "1.2.2.1", base.Add(time.Minute)),
+		revCommit("1.2", "1.2", base.Add(2*time.Minute)),
+		revCommit("1.3", "1.3", base.Add(3*time.Minute)),
+		revCommit("1.2.2.2", "1.2.2.2", base.Add(4*time.Minute)),
+		revCommit("1.4", "1.4", base.Add(5*time.Minute)),
+	}
+	require.Zero(t, NewMigrator(&MigrationConfig{}).orderCommits(commits))
+	require.Equal(t, []string{"1.1", "1.2", "1.2.2.1", "1.3", "1.2.2.2", "1.4"}, messages(commits))
+}
+
+func TestOrderCommits_Changesets(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// The second changeset holds g.txt 1.2, which derives from g.txt 1.1 in
+	// the third one, so the third one has to come first
+	second := &vcs.Commit{Message: "second", Date: base.Add(time.Minute), Files: []vcs.FileChange{
+		{Path: "f.txt", Revision: "1.2"}, {Path: "g.txt", Revision: "1.2"},
+	}}
+	third := &vcs.Commit{Message: "third", Date: base.Add(2 * time.Minute), Files: []vcs.FileChange{
+		{Path: "g.txt", Revision: "1.1"},
+	}}
+	commits := []*vcs.Commit{revCommit("first", "1.1", base), second, third}
+	require.Zero(t, NewMigrator(&MigrationConfig{}).orderCommits(commits))
+	require.Equal(t, []string{"first", "third", "second"}, messages(commits))
+}
+
+func TestOrderCommits_Cycle(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Each changeset holds a revision the other one derives from
+	a := &vcs.Commit{Message: "a", Date: base.Add(time.Minute), Files: []vcs.FileChange{
+		{Path: "f.txt", Revision: "1.2"}, {Path: "g.txt", Revision: "1.1"},
+	}}
+	b := &vcs.Commit{Message: "b", Date: base, Files: []vcs.FileChange{
+		{Path: "f.txt", Revision: "1.1"}, {Path: "g.txt", Revision: "1.2"},
+	}}
+	c := revCommit("c", "1.3", base.Add(2*time.Minute))
+	commits := []*vcs.Commit{a, b, c}
+	require.Equal(t, 1, NewMigrator(&MigrationConfig{}).orderCommits(commits))
+	require.Equal(t, []string{"b", "a", "c"}, messages(commits))
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patchHunk is a hunk of a unified diff
+type patchHunk struct {
+	oldStart int      // First line replaced, counting from 1
+	old      []string // Context and removed lines
+	new      []string // Context and added lines
+	oldNoEOL bool     // The last old line has no newline
+	newNoEOL bool     // The last new line has no newline
+}
+
+// parsePatch reads the hunks of a unified diff of a single file
+func parsePatch(patch []byte) ([]patchHunk, error) {
+	var hunks []patchHunk
+	var hunk *patchHunk
+	oldLeft, newLeft := 0, 0 // Lines of the current hunk still to read
+	var last byte
+	headers := 0
+	for _, line := range strings.Split(strings.TrimSuffix(string(patch), "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if hunk != nil && strings.HasPrefix(line, "\\") {
+			// No newline at end of file, after the line it applies to
+			hunk.oldNoEOL = hunk.oldNoEOL || last != '+'
+			hunk.newNoEOL = hunk.newNoEOL || last != '-'
+			continue
+		}
+		if oldLeft == 0 && newLeft == 0 {
+			switch {
+			case strings.HasPrefix(line, "@@ "):
+				h, oldCount, newCount, err := parseHunkHeader(line)
+				if err != nil {
+					return nil, err
+				}
+				hunks = append(hunks, h)
+				hunk = &hunks[len(hunks)-1]
+				oldLeft, newLeft = oldCount, newCount
+			case strings.HasPrefix(line, "--- "):
+				if headers++; headers > 1 {
+					return nil, fmt.Errorf("patch changes more than one file")
+				}
+				hunk = nil
+			}
+			continue // Headers and commentary between hunks
+		}
+
+		if line == "" {
+			line = " " // Some tools strip the space of empty context lines
+		}
+		switch line[0] {
+		case ' ':
+			hunk.old = append(hunk.old, line[1:])
+			hunk.new = append(hunk.new, line[1:])
+			oldLeft--
+			newLeft--
+		case '-':
+			hunk.old = append(hunk.old, line[1:])
+			oldLeft--
+		case '+':
+			hunk.new = append(hunk.new, line[1:])
+			newLeft--
+		default:
+			return nil, fmt.Errorf("invalid patch line %q", line)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return nil, fmt.Errorf("hunk at line %d is longer than its header says", hunk.oldStart)
+		}
+		last = line[0]
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("patch ends within a hunk")
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch has no hunks")
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader reads "@@ -start,count +start,count @@", returning the
+// line counts; a missing count is 1
+func parseHunkHeader(line string) (patchHunk, int, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return patchHunk{}, 0, 0, fmt.Errorf("invalid hunk header %q", line)
+	}
+	oldStart, oldCount, err1 := parseHunkRange(fields[1][1:])
+	_, newCount, err2 := parseHunkRange(fields[2][1:])
+	if err1 != nil || err2 != nil {
+		return patchHunk{}, 0, 0, fmt.Errorf("invalid hunk header %q", line)
+	}
+	return patchHunk{oldStart: oldStart}, oldCount, newCount, nil
+}
+
+// parseHunkRange reads "start,count" or "start"
+func parseHunkRange(r string) (int, int, error) {
+	startText, countText, found := strings.Cut(r, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !found {
+		return start, 1, nil
+	}
+	count, err := strconv.Atoi(countText)
+	return start, count, err
+}
+
+// applyPatch applies a unified diff to content. Hunks whose lines moved are
+// found by searching around their position; a hunk whose lines are not
+// found fails the patch.
+func applyPatch(content, patch []byte) ([]byte, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(content)
+	endsEOL := text == "" || strings.HasSuffix(text, "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	var out []string
+	pos, offset := 0, 0
+	for i, hunk := range hunks {
+		start := hunk.oldStart - 1
+		if len(hunk.old) == 0 {
+			start = hunk.oldStart // A pure addition follows line oldStart
+		}
+		at, ok := findHunk(lines, hunk.old, start+offset, pos)
+		if !ok {
+			return nil, fmt.Errorf("hunk %d does not apply at line %d", i+1, hunk.oldStart)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, hunk.new...)
+		pos = at + len(hunk.old)
+		offset = at - start
+		if pos == len(lines) {
+			endsEOL = !hunk.newNoEOL // The hunk ends the file
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	var buf bytes.Buffer
+	buf.WriteString(strings.Join(out, "\n"))
+	if len(out) > 0 && endsEOL {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// findHunk returns the line at which old appears in lines, searching
+// outwards from want but not before from
+func findHunk(lines, old []string, want, from int) (int, bool) {
+	matchesAt := func(at int) bool {
+		if at < from || at+len(old) > len(lines) {
+			return false
+		}
+		for i, line := range old {
+			if lines[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	for delta := 0; delta <= len(lines); delta++ {
+		if matchesAt(want - delta) {
+			return want - delta, true
+		}
+		if matchesAt(want + delta) {
+			return want + delta, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive\n"
+	patch := `--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -5 +5,2 @@
+ five
++six
+`
+	out, err := applyPatch([]byte(content), []byte(patch))
+	require.NoError(t, err)
+	require.Equal(t, "one\nTWO\nthree\nfour\nfive\nsix\n", string(out))
+
+	// Hunks are found when the lines moved
+	out, err = applyPatch([]byte("zero\n"+content), []byte(patch))
+	require.NoError(t, err)
+	require.Equal(t, "zero\none\nTWO\nthree\nfour\nfive\nsix\n", string(out))
+
+	_, err = applyPatch([]byte("one\n2\nthree\n"), []byte(patch))
+	require.ErrorContains(t, err, "hunk 1 does not apply")
+}
+
+func TestApplyPatch_NoNewlineAtEnd(t *testing.T) {
+	patch := "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n"
+	out, err := applyPatch([]byte("a\nb"), []byte(patch))
+	require.NoError(t, err)
+	require.Equal(t, "a\nc\n", string(out))
+
+	patch = "@@ -1 +1 @@\n-a\n+b\n\\ No newline at end of file\n"
+	out, err = applyPatch([]byte("a\n"), []byte(patch))
+	require.NoError(t, err)
+	require.Equal(t, "b", string(out))
+}
+
+func TestParsePatch_Invalid(t *testing.T) {
+	for _, patch := range []string{
+		"",
+		"just text\n",
+		"@@ -1,2 +1,2 @@\n a\n",
+		"@@ -x +1 @@\n",
+		"--- a\n+++ a\n@@ -1 +1 @@\n-a\n+b\n--- b\n+++ b\n@@ -1 +1 @@\n-a\n+b\n",
+	} {
+		_, err := parsePatch([]byte(patch))
+		require.Error(t, err, patch)
+	}
+
+	// Removed lines looking like file headers belong to their hunk
+	hunks, err := parsePatch([]byte("@@ -1,2 +1 @@\n--- x\n keep\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"-- x", "keep"}, hunks[0].old)
+}
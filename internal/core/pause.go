@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PauseSwitch pauses a running migration after the current commit. The
+// paused run saves a checkpoint and waits, keeping the source history and
+// the open target, until it is resumed; closing MigrationConfig.Stop ends
+// it instead. The zero value is ready to use.
+type PauseSwitch struct {
+	mu      sync.Mutex
+	resumed chan struct{} // Non-nil while paused; closed by Resume
+}
+
+// Pause asks the migration to pause after the current commit. It returns
+// false if the migration is paused already.
+func (p *PauseSwitch) Pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		return false
+	}
+	p.resumed = make(chan struct{})
+	return true
+}
+
+// Resume lets a paused migration continue. It returns false if the
+// migration is not paused.
+func (p *PauseSwitch) Resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		return false
+	}
+	close(p.resumed)
+	p.resumed = nil
+	return true
+}
+
+// Paused reports whether a pause was requested and not resumed yet
+func (p *PauseSwitch) Paused() bool {
+	return p.wait() != nil
+}
+
+// wait returns the channel closed when the current pause ends, or nil if
+// the migration is not paused
+func (p *PauseSwitch) wait() <-chan struct{} {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumed
+}
+
+// pause checkpoints the migration after processed commits and blocks until
+// it is resumed or stopped
+func (m *Migrator) pause(resumed <-chan struct{}, lastCommit string, processed, total int) error {
+	if err := m.saveState(lastCommit, processed, total); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	m.Logger().Info("migration paused", "processed", processed, "total", total)
+	m.reporter.SetOperation(fmt.Sprintf("Paused after %d of %d commits", processed, total))
+
+	select {
+	case <-resumed:
+		m.Logger().Info("migration resumed", "processed", processed, "total", total)
+		m.reporter.SetOperation("Resuming migration")
+		return nil
+	case <-m.config.Stop:
+		m.Logger().Info("migration stopped", "processed", processed, "total", total)
+		return ErrStopped
+	}
+}
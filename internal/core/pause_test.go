@@ -0,0 +1,91 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/stretchr/testify/require"
+)
+
+// pauseAtHook pauses the migration once the commit with revision at is
+// applied
+type pauseAtHook struct {
+	pause *PauseSwitch
+	at    string
+}
+
+func (h *pauseAtHook) BeforeCommit(*vcs.Commit) error { return nil }
+
+func (h *pauseAtHook) AfterCommit(commit *vcs.Commit, _ string) error {
+	if commit.Revision == h.at {
+		h.pause.Pause()
+	}
+	return nil
+}
+
+// runPaused starts the migration and waits until it is paused
+func runPaused(t *testing.T, m *Migrator) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- m.Run() }()
+	require.Eventually(t, func() bool {
+		return strings.HasPrefix(m.ProgressReporter().Status().Operation, "Paused after 2 of 4 commits")
+	}, 10*time.Second, 10*time.Millisecond)
+	return done
+}
+
+func targetCommitCount(t *testing.T, target string) int {
+	t.Helper()
+	w := git.NewWriter()
+	require.NoError(t, w.OpenReadOnly(target))
+	count, err := w.GetCommitCount()
+	require.NoError(t, err)
+	return count
+}
+
+func TestRun_PauseAndResume(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	pause := &PauseSwitch{}
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target,
+		StateFile: filepath.Join(t.TempDir(), "state.db"), Pause: pause,
+		Hooks: []CommitHook{&pauseAtHook{pause: pause, at: "1.2"}}, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+
+	done := runPaused(t, m)
+	require.True(t, pause.Paused())
+	require.False(t, pause.Pause(), "already paused")
+	require.Equal(t, 2, targetCommitCount(t, target))
+
+	require.True(t, pause.Resume())
+	require.False(t, pause.Resume(), "not paused")
+	require.NoError(t, <-done)
+	require.Equal(t, 4, m.Report().Commits.Applied)
+	require.Equal(t, 4, targetCommitCount(t, target))
+}
+
+func TestRun_StopWhilePaused(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	stateFile := filepath.Join(t.TempDir(), "state.db")
+	pause, stop := &PauseSwitch{}, make(chan struct{})
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target,
+		StateFile: stateFile, Pause: pause, Stop: stop,
+		Hooks: []CommitHook{&pauseAtHook{pause: pause, at: "1.2"}}, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+
+	done := runPaused(t, m)
+	close(stop)
+	require.ErrorIs(t, <-done, ErrStopped)
+
+	// The pause checkpoint is where a new run resumes
+	m = NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target,
+		StateFile: stateFile, Resume: true, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+	require.NoError(t, m.Run())
+	require.Equal(t, 2, m.Report().Commits.Applied)
+	require.Equal(t, 4, targetCommitCount(t, target))
+}
@@ -0,0 +1,296 @@
+package core
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1" //nolint:gosec // content identity only, as in git
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// DefaultLargeFileThreshold is the size above which a file revision is
+// reported as large
+const DefaultLargeFileThreshold = 10 << 20
+
+// largePackSize is the estimated pack size above which the repository is
+// reported as large; many hosting services reject bigger pushes
+const largePackSize = 2 << 30
+
+// LargeFile describes a file with at least one revision above the threshold
+type LargeFile struct {
+	Path      string `json:"path"`
+	MaxSize   int64  `json:"maxSize"`   // Size of the largest revision
+	Revision  string `json:"revision"`  // Source revision of the largest revision
+	Revisions int    `json:"revisions"` // Number of revisions above the threshold
+	Binary    bool   `json:"binary"`
+}
+
+// ExtensionSize summarizes the history of all files with an extension
+type ExtensionSize struct {
+	Extension string `json:"extension"` // Lower-case extension, "" for none
+	Files     int    `json:"files"`
+	Revisions int    `json:"revisions"`
+	TotalSize int64  `json:"totalSize"` // Size of all revisions
+}
+
+// PreflightReport summarizes the size of a source repository across its
+// whole history
+type PreflightReport struct {
+	Threshold         int64           `json:"threshold"`
+	Commits           int             `json:"commits"`
+	Files             int             `json:"files"`
+	Revisions         int             `json:"revisions"`
+	TotalSize         int64           `json:"totalSize"`         // Size of all file revisions
+	EstimatedPackSize int64           `json:"estimatedPackSize"` // Compressed size of unique contents
+	LargeFiles        []LargeFile     `json:"largeFiles"`
+	Extensions        []ExtensionSize `json:"extensions"`
+	Unreadable        []string        `json:"unreadable"`   // Revisions whose content could not be read
+	WindowsPaths      []WindowsPath   `json:"windowsPaths"` // Paths Windows cannot check out
+	Recommendations   []string        `json:"recommendations"`
+}
+
+// Preflight collects a PreflightReport from the commits passed to Add
+type Preflight struct {
+	threshold  int64
+	commits    int
+	revisions  int
+	totalSize  int64
+	packSize   int64
+	files      map[string]bool
+	blobs      map[[sha1.Size]byte]bool
+	large      map[string]*LargeFile
+	extensions map[string]*ExtensionSize
+	extFiles   map[string]map[string]bool
+	unreadable []string
+	windows    []WindowsPath
+}
+
+// NewPreflight creates a preflight analysis. A threshold <= 0 selects
+// DefaultLargeFileThreshold.
+func NewPreflight(threshold int64) *Preflight {
+	if threshold <= 0 {
+		threshold = DefaultLargeFileThreshold
+	}
+	return &Preflight{
+		threshold:  threshold,
+		files:      make(map[string]bool),
+		blobs:      make(map[[sha1.Size]byte]bool),
+		large:      make(map[string]*LargeFile),
+		extensions: make(map[string]*ExtensionSize),
+		extFiles:   make(map[string]map[string]bool),
+	}
+}
+
+// RunPreflight analyzes every commit of reader
+func RunPreflight(reader vcs.VCSReader, threshold int64) (*PreflightReport, error) {
+	iter, err := reader.GetCommits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	p := NewPreflight(threshold)
+	for iter.Next() {
+		p.Add(iter.Commit())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterator error: %w", err)
+	}
+	return p.Report(), nil
+}
+
+// Add records the file revisions of a commit. Revisions whose content cannot
+// be read are reported instead of failing the analysis.
+func (p *Preflight) Add(commit *vcs.Commit) {
+	p.commits++
+	for i := range commit.Files {
+		fc := &commit.Files[i]
+		if fc.Action == vcs.ActionDelete {
+			continue
+		}
+		if !p.files[fc.Path] {
+			if problem := WindowsPathProblem(fc.Path); problem != "" {
+				p.windows = append(p.windows, WindowsPath{Path: fc.Path, Problem: problem})
+			}
+		}
+		content, err := fc.ReadContent()
+		if err != nil {
+			p.unreadable = append(p.unreadable, fmt.Sprintf("%s:%s: %v", fc.Path, fc.Revision, err))
+			continue
+		}
+		p.addRevision(fc.Path, fc.Revision, content, fc.Binary)
+	}
+}
+
+func (p *Preflight) addRevision(file, revision string, content []byte, binary bool) {
+	size := int64(len(content))
+	p.revisions++
+	p.totalSize += size
+	p.files[file] = true
+
+	ext := strings.ToLower(path.Ext(file))
+	es, ok := p.extensions[ext]
+	if !ok {
+		es = &ExtensionSize{Extension: ext}
+		p.extensions[ext] = es
+		p.extFiles[ext] = make(map[string]bool)
+	}
+	es.Revisions++
+	es.TotalSize += size
+	if !p.extFiles[ext][file] {
+		p.extFiles[ext][file] = true
+		es.Files++
+	}
+
+	// Git stores identical contents once
+	sum := sha1.Sum(content) //nolint:gosec // content identity only
+	if !p.blobs[sum] {
+		p.blobs[sum] = true
+		p.packSize += compressedSize(content)
+	}
+
+	if size > p.threshold {
+		lf, ok := p.large[file]
+		if !ok {
+			lf = &LargeFile{Path: file}
+			p.large[file] = lf
+		}
+		lf.Revisions++
+		lf.Binary = lf.Binary || binary || isBinary(content)
+		if size > lf.MaxSize {
+			lf.MaxSize = size
+			lf.Revision = revision
+		}
+	}
+}
+
+// compressedSize returns the zlib-compressed size of content, which is how
+// git stores objects that are not deltified
+func compressedSize(content []byte) int64 {
+	var counter countingWriter
+	zw := zlib.NewWriter(&counter)
+	// Writes to a countingWriter cannot fail
+	_, _ = zw.Write(content)
+	_ = zw.Close()
+	return counter.n
+}
+
+type countingWriter struct{ n int64 }
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
+
+// isBinary uses the same heuristic as git: a NUL byte in the first 8000
+// bytes
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// Report returns the analysis of the commits added so far. Large files and
+// extensions are sorted by size, largest first.
+func (p *Preflight) Report() *PreflightReport {
+	report := &PreflightReport{
+		Threshold:         p.threshold,
+		Commits:           p.commits,
+		Files:             len(p.files),
+		Revisions:         p.revisions,
+		TotalSize:         p.totalSize,
+		EstimatedPackSize: p.packSize,
+		LargeFiles:        []LargeFile{},
+		Extensions:        []ExtensionSize{},
+		Unreadable:        append([]string{}, p.unreadable...),
+		WindowsPaths:      append([]WindowsPath{}, p.windows...),
+		Recommendations:   []string{},
+	}
+
+	for _, lf := range p.large {
+		report.LargeFiles = append(report.LargeFiles, *lf)
+	}
+	sort.Slice(report.LargeFiles, func(i, j int) bool {
+		a, b := report.LargeFiles[i], report.LargeFiles[j]
+		if a.MaxSize != b.MaxSize {
+			return a.MaxSize > b.MaxSize
+		}
+		return a.Path < b.Path
+	})
+
+	for _, es := range p.extensions {
+		report.Extensions = append(report.Extensions, *es)
+	}
+	sort.Slice(report.Extensions, func(i, j int) bool {
+		a, b := report.Extensions[i], report.Extensions[j]
+		if a.TotalSize != b.TotalSize {
+			return a.TotalSize > b.TotalSize
+		}
+		return a.Extension < b.Extension
+	})
+
+	report.Recommendations = recommendations(report)
+	return report
+}
+
+func recommendations(report *PreflightReport) []string {
+	recs := []string{}
+
+	// Binary files are best tracked with LFS, grouped by extension
+	lfs := make(map[string]int)
+	var text []string
+	for _, lf := range report.LargeFiles {
+		if lf.Binary && path.Ext(lf.Path) != "" {
+			lfs[strings.ToLower(path.Ext(lf.Path))]++
+		} else {
+			text = append(text, lf.Path)
+		}
+	}
+	exts := make([]string, 0, len(lfs))
+	for ext := range lfs {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		recs = append(recs, fmt.Sprintf("Track *%s with Git LFS (%d large files)", ext, lfs[ext]))
+	}
+	for _, file := range text {
+		recs = append(recs, fmt.Sprintf("Consider excluding %s from the migration or tracking it with Git LFS", file))
+	}
+
+	if len(report.Unreadable) > 0 {
+		recs = append(recs, fmt.Sprintf(
+			"%d file revisions could not be read and will fail the migration; repair the RCS files first",
+			len(report.Unreadable)))
+	}
+
+	if len(report.WindowsPaths) > 0 {
+		recs = append(recs, fmt.Sprintf(
+			"%d paths cannot be checked out on Windows; rename them or enable options.sanitizeWindowsPaths",
+			len(report.WindowsPaths)))
+	}
+
+	if report.EstimatedPackSize > largePackSize {
+		recs = append(recs, fmt.Sprintf(
+			"The estimated repository size of %s exceeds the push limit of many hosting services; consider splitting the repository or excluding large files",
+			FormatSize(report.EstimatedPackSize)))
+	}
+	return recs
+}
+
+// FormatSize formats a byte count using binary units, e.g. "1.5 MiB"
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflight(t *testing.T) {
+	big := bytes.Repeat([]byte("a"), 200)
+	bigBinary := append([]byte{0}, bytes.Repeat([]byte("b"), 300)...)
+
+	commits := []*vcs.Commit{
+		{Revision: "1", Files: []vcs.FileChange{
+			{Path: "README", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("hello")},
+			{Path: "logo.PNG", Action: vcs.ActionAdd, Revision: "1.1", Content: bigBinary},
+			{Path: "data.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: big},
+		}},
+		{Revision: "2", Files: []vcs.FileChange{
+			{Path: "data.txt", Action: vcs.ActionModify, Revision: "1.2", Content: append(big, big...)},
+			{Path: "copy.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("hello")},
+			{Path: "README", Action: vcs.ActionDelete},
+		}},
+	}
+	report, err := RunPreflight(&mockReaderWithCommits{commits: commits}, 100)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, report.Commits)
+	require.Equal(t, 4, report.Files)
+	require.Equal(t, 5, report.Revisions)
+	require.Equal(t, int64(5+301+200+400+5), report.TotalSize)
+	require.Positive(t, report.EstimatedPackSize)
+	require.Less(t, report.EstimatedPackSize, report.TotalSize)
+
+	require.Equal(t, []LargeFile{
+		{Path: "data.txt", MaxSize: 400, Revision: "1.2", Revisions: 2},
+		{Path: "logo.PNG", MaxSize: 301, Revision: "1.1", Revisions: 1, Binary: true},
+	}, report.LargeFiles)
+
+	require.Equal(t, []ExtensionSize{
+		{Extension: ".txt", Files: 2, Revisions: 3, TotalSize: 605},
+		{Extension: ".png", Files: 1, Revisions: 1, TotalSize: 301},
+		{Extension: "", Files: 1, Revisions: 1, TotalSize: 5},
+	}, report.Extensions)
+
+	require.Equal(t, []string{
+		"Track *.png with Git LFS (1 large files)",
+		"Consider excluding data.txt from the migration or tracking it with Git LFS",
+	}, report.Recommendations)
+}
+
+func TestPreflight_UnreadableRevision(t *testing.T) {
+	p := NewPreflight(0)
+	p.Add(&vcs.Commit{Files: []vcs.FileChange{{
+		Path:     "broken.c",
+		Action:   vcs.ActionAdd,
+		Revision: "1.3",
+		Source:   func() (io.ReadCloser, error) { return nil, errors.New("bad delta") },
+	}}})
+
+	report := p.Report()
+	require.Equal(t, []string{"broken.c:1.3: bad delta"}, report.Unreadable)
+	require.Zero(t, report.Revisions)
+	require.Len(t, report.Recommendations, 1)
+}
+
+func TestPreflight_WindowsPaths(t *testing.T) {
+	p := NewPreflight(0)
+	for i := 0; i < 2; i++ {
+		p.Add(&vcs.Commit{Files: []vcs.FileChange{
+			{Path: "docs/aux.txt", Action: vcs.ActionAdd, Content: []byte("x")},
+			{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("x")},
+		}})
+	}
+	report := p.Report()
+	require.Equal(t, []WindowsPath{{Path: "docs/aux.txt", Problem: `"aux.txt" is a reserved device name`}}, report.WindowsPaths)
+	require.Contains(t, report.Recommendations, "1 paths cannot be checked out on Windows; rename them or enable options.sanitizeWindowsPaths")
+}
+
+func TestPreflight_DefaultThreshold(t *testing.T) {
+	report := NewPreflight(0).Report()
+	require.Equal(t, int64(DefaultLargeFileThreshold), report.Threshold)
+	require.Empty(t, report.LargeFiles)
+	require.Empty(t, report.Recommendations)
+}
+
+func TestFormatSize(t *testing.T) {
+	require.Equal(t, "512 B", FormatSize(512))
+	require.Equal(t, "1.5 KiB", FormatSize(1536))
+	require.Equal(t, "10.0 MiB", FormatSize(10<<20))
+	require.Equal(t, "2.0 GiB", FormatSize(2<<30))
+}
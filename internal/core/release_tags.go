@@ -0,0 +1,143 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+)
+
+// releaseNotesLimit is the number of commits listed in a release tag
+// message before the rest are only counted
+const releaseNotesLimit = 500
+
+// releaseTag is a tag matching ReleaseTags, created after the other tags
+type releaseTag struct {
+	name     string // Git tag
+	symbol   string // Source tag
+	revision string
+	info     vcs.TagInfo
+}
+
+// releaseWriter is implemented by targets that can write release tags
+type releaseWriter interface {
+	CommitsBetween(since, revision string) ([]*vcs.Commit, error)
+	CreateAnnotatedTag(name, revision string, opts git.TagOptions) error
+}
+
+// validateReleaseTags checks the ReleaseTags pattern and loads SigningKey
+func (m *Migrator) validateReleaseTags() error {
+	m.signKey = nil
+	if m.config.ReleaseTags != "" {
+		if _, err := path.Match(m.config.ReleaseTags, ""); err != nil {
+			return fmt.Errorf("invalid release tag pattern %q: %w", m.config.ReleaseTags, err)
+		}
+	}
+	if m.config.SigningKey == "" {
+		return nil
+	}
+	if m.config.ReleaseTags == "" {
+		return fmt.Errorf("a signing key requires a release tag pattern")
+	}
+	key, err := git.ReadSigningKey(m.config.SigningKey, m.config.KeyPassphrase)
+	if err != nil {
+		return err
+	}
+	m.signKey = key
+	return nil
+}
+
+// isReleaseTag reports whether a Git tag matches ReleaseTags
+func (m *Migrator) isReleaseTag(gitTag string) bool {
+	if m.config.ReleaseTags == "" {
+		return false
+	}
+	matched, _ := path.Match(m.config.ReleaseTags, gitTag)
+	return matched
+}
+
+// createReleaseTags creates annotated, optionally signed, release tags whose
+// messages list the commits since the previous release. Releases are
+// ordered by the date of their newest tagged revision, then by name.
+func (m *Migrator) createReleaseTags(report *MigrationReport, releases []releaseTag) error {
+	if len(releases) == 0 {
+		return nil
+	}
+	writer, ok := m.target.(releaseWriter)
+	if !ok {
+		m.warn("target cannot write release notes; creating release tags as plain tags", "count", len(releases))
+		for _, release := range releases {
+			err := m.createTag(release.name, release.symbol, release.revision, release.info)
+			if err := m.recordTag(report, release.name, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		if !releases[i].info.Date.Equal(releases[j].info.Date) {
+			return releases[i].info.Date.Before(releases[j].info.Date)
+		}
+		return releases[i].name < releases[j].name
+	})
+
+	var previous *releaseTag
+	for i := range releases {
+		release := &releases[i]
+		m.reporter.SetOperation(fmt.Sprintf("Creating release tag %s", release.name))
+		err := m.createReleaseTag(writer, release, previous)
+		if err := m.recordTag(report, release.name, err); err != nil {
+			return err
+		}
+		if err == nil {
+			previous = release
+		}
+	}
+	return nil
+}
+
+// createReleaseTag creates one release tag, listing the commits since
+// previous (nil = the whole history)
+func (m *Migrator) createReleaseTag(writer releaseWriter, release, previous *releaseTag) error {
+	since := ""
+	if previous != nil {
+		since = previous.revision
+	}
+	commits, err := writer.CommitsBetween(since, release.revision)
+	if err != nil {
+		return err
+	}
+
+	opts := m.tagOptions(release.name, release.symbol, release.info)
+	opts.Message = releaseNotes(opts.Message, previous, commits)
+	opts.SignKey = m.signKey
+	return writer.CreateAnnotatedTag(release.name, release.revision, opts)
+}
+
+// releaseNotes appends the subjects and authors of commits, newest first,
+// to a tag message
+func releaseNotes(message string, previous *releaseTag, commits []*vcs.Commit) string {
+	var notes strings.Builder
+	notes.WriteString(message)
+	if previous != nil {
+		fmt.Fprintf(&notes, "\nChanges since %s:\n\n", previous.name)
+	} else {
+		notes.WriteString("\nChanges:\n\n")
+	}
+	if len(commits) == 0 {
+		notes.WriteString("- No changes\n")
+	}
+	for i, commit := range commits {
+		if i == releaseNotesLimit {
+			fmt.Fprintf(&notes, "- ... and %d more\n", len(commits)-i)
+			break
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		fmt.Fprintf(&notes, "- %s (%s)\n", strings.TrimSpace(subject), commit.Author)
+	}
+	return notes.String()
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReleaseTags(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{ReleaseTags: "v*"})
+	require.NoError(t, m.validateReleaseTags())
+	require.True(t, m.isReleaseTag("v1.0"))
+	require.False(t, m.isReleaseTag("nightly"))
+
+	m.config.ReleaseTags = "v["
+	require.ErrorContains(t, m.validateReleaseTags(), "invalid release tag pattern")
+	m.config = &MigrationConfig{SigningKey: "release.asc"}
+	require.ErrorContains(t, m.validateReleaseTags(), "requires a release tag pattern")
+	m.config.ReleaseTags = "v*"
+	m.config.SigningKey = filepath.Join(t.TempDir(), "missing.asc")
+	require.ErrorContains(t, m.validateReleaseTags(), "failed to read signing key")
+}
+
+func TestCreateReleaseTags(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	writer := git.NewWriter()
+	require.NoError(t, writer.Init(target))
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var hashes []string
+	for i, message := range []string{"Initial import\n", "Fix parser crash\n\nDetails.\n", "Add export\n"} {
+		require.NoError(t, writer.ApplyCommit(&vcs.Commit{
+			Author: "Alice Smith", Email: "alice@corp.example", Date: date.Add(time.Duration(i) * time.Hour), Message: message,
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionModify, Content: []byte(message)}},
+		}))
+		hashes = append(hashes, writer.LastCommitHash())
+	}
+
+	m := NewMigrator(&MigrationConfig{ReleaseTags: "v*", Logger: logging.Discard()})
+	require.NoError(t, m.validateReleaseTags())
+	m.target = writer
+	report := m.currentReport()
+	require.NoError(t, m.createReleaseTags(report, []releaseTag{
+		{name: "v1.1", symbol: "REL_1_1", revision: hashes[2], info: vcs.TagInfo{Date: date.Add(2 * time.Hour)}},
+		{name: "v1.0", symbol: "REL_1_0", revision: hashes[0], info: vcs.TagInfo{Date: date}},
+	}))
+	require.Equal(t, []string{"v1.0", "v1.1"}, report.Tags.Created)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	message := func(name string) string {
+		ref, err := repo.Reference(plumbing.NewTagReferenceName(name), true)
+		require.NoError(t, err)
+		tag, err := repo.TagObject(ref.Hash())
+		require.NoError(t, err)
+		require.Empty(t, tag.PGPSignature)
+		return tag.Message
+	}
+	require.Equal(t, "Converted from CVS tag REL_1_0\n\nChanges:\n\n- Initial import (Alice Smith)\n", message("v1.0"))
+	require.Equal(t, "Converted from CVS tag REL_1_1\n\nChanges since v1.0:\n\n- Add export (Alice Smith)\n- Fix parser crash (Alice Smith)\n", message("v1.1"))
+}
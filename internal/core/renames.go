@@ -0,0 +1,329 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// ReportFileRename is a deleted and an added file paired as a rename by
+// rename detection
+type ReportFileRename struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Commit     string `json:"commit"`     // Source revision of the commit holding both changes
+	Similarity int    `json:"similarity"` // Percentage of equal lines
+}
+
+// DefaultRenameLimit is the RenameLimit used when none is configured; git
+// uses the same default for diff.renameLimit
+const DefaultRenameLimit = 1000
+
+// validateRenameSimilarity checks RenameSimilarity and RenameLimit
+func (m *Migrator) validateRenameSimilarity() error {
+	if s := m.config.RenameSimilarity; s < 0 || s > 100 {
+		return fmt.Errorf("rename similarity must be a percentage between 0 and 100, got %d", s)
+	}
+	if m.config.RenameLimit < 0 {
+		return fmt.Errorf("rename limit must not be negative, got %d", m.config.RenameLimit)
+	}
+	return nil
+}
+
+// renameLimit returns the RenameLimit of the configuration or its default
+func (m *Migrator) renameLimit() int {
+	if m.config.RenameLimit > 0 {
+		return m.config.RenameLimit
+	}
+	return DefaultRenameLimit
+}
+
+// renameCandidate is a deleted file of a commit with the content it had
+// before the deletion
+type renameCandidate struct {
+	commit int // Index of the commit deleting the file
+	file   int // Index of the deletion in the commit's files
+	before *vcs.FileChange
+}
+
+// detectRenames pairs files deleted and added within a commit, or by the
+// commits before and after it on the same branch, whose contents are at
+// least RenameSimilarity percent equal. CVS records a rename as a deletion
+// and an addition; a pair becomes a rename of the commit of the addition,
+// so a deletion of a neighbouring commit is moved into it. A commit left
+// without file changes (with no merged parents) is dropped and its message
+// appended to the receiving commit. The commits must be ordered.
+//
+// Files moved unchanged are paired by their content first. Like git, the
+// line comparison of the remaining files is skipped for a commit whose
+// added times deleted files exceed the square of the rename limit.
+func (m *Migrator) detectRenames(commits []*vcs.Commit) ([]*vcs.Commit, error) {
+	threshold := m.config.RenameSimilarity
+	if threshold <= 0 {
+		return commits, nil
+	}
+
+	report := m.currentReport()
+
+	// Find the content every deleted file had, following branches back
+	// to trunk
+	latest := map[string]map[string]*vcs.FileChange{}
+	deleted := map[int][]renameCandidate{}
+	for i, commit := range commits {
+		if latest[commit.Branch] == nil {
+			latest[commit.Branch] = map[string]*vcs.FileChange{}
+		}
+		for j := range commit.Files {
+			fc := &commit.Files[j]
+			if fc.Action != vcs.ActionDelete {
+				if fc.Action == vcs.ActionRename {
+					delete(latest[commit.Branch], fc.OldPath) // Recorded by the source already
+				}
+				latest[commit.Branch][fc.Path] = fc
+				continue
+			}
+			before, ok := latest[commit.Branch][fc.Path]
+			if !ok {
+				before, ok = latest[""][fc.Path]
+			}
+			if ok {
+				deleted[i] = append(deleted[i], renameCandidate{commit: i, file: j, before: before})
+			}
+			delete(latest[commit.Branch], fc.Path)
+		}
+	}
+	if len(deleted) == 0 {
+		return commits, nil
+	}
+
+	// Neighbouring commits on the same branch
+	prev, next := make([]int, len(commits)), make([]int, len(commits))
+	last := map[string]int{}
+	for i, commit := range commits {
+		prev[i], next[i] = -1, -1
+		if j, ok := last[commit.Branch]; ok {
+			prev[i], next[j] = j, i
+		}
+		last[commit.Branch] = i
+	}
+
+	movedTo := map[[2]int]int{} // Paired deletions -> index of the commit of the addition
+	for i, commit := range commits {
+		touched := map[string]bool{}
+		var added []int
+		for j, fc := range commit.Files {
+			touched[fc.Path] = true
+			if fc.Action == vcs.ActionAdd {
+				added = append(added, j)
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+
+		contents := newRenameContents()
+		var candidates []renameCandidate
+		for _, k := range []int{i, prev[i], next[i]} {
+			if k < 0 {
+				continue
+			}
+			for _, c := range deleted[k] {
+				path := commits[k].Files[c.file].Path
+				if _, ok := movedTo[[2]int{k, c.file}]; ok {
+					continue
+				}
+				if k == i || (!touched[path] && len(commits[k].Parents) == 0) {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+
+		type pair struct {
+			add        int
+			del        renameCandidate
+			similarity int
+		}
+		var pairs []pair
+
+		// Files moved unchanged pair by content, however many there are
+		byContent := map[[sha256.Size]byte][]renameCandidate{}
+		for _, c := range candidates {
+			data, err := contents.read(c.before)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", commits[c.commit].Files[c.file].Path, err)
+			}
+			if len(data) > 0 {
+				sum := sha256.Sum256(data)
+				byContent[sum] = append(byContent[sum], c)
+			}
+		}
+		exact := map[[2]int]bool{}
+		var rest []int
+		for _, a := range added {
+			data, err := contents.read(&commit.Files[a])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", commit.Files[a].Path, err)
+			}
+			sum := sha256.Sum256(data)
+			if same := byContent[sum]; len(data) > 0 && len(same) > 0 {
+				pairs = append(pairs, pair{a, same[0], 100})
+				exact[[2]int{same[0].commit, same[0].file}] = true
+				byContent[sum] = same[1:]
+				continue
+			}
+			rest = append(rest, a)
+		}
+		candidates = slices.DeleteFunc(candidates, func(c renameCandidate) bool { return exact[[2]int{c.commit, c.file}] })
+
+		limit := m.renameLimit()
+		if len(rest)*len(candidates) > limit*limit {
+			m.warn("too many added and deleted files to compare; only unchanged files were paired as renames",
+				"revision", commit.Revision, "added", len(rest), "deleted", len(candidates), "renameLimit", limit)
+			rest = nil
+		}
+		var similar []pair
+		for _, a := range rest {
+			for _, c := range candidates {
+				s, err := contents.similarity(&commit.Files[a], c.before)
+				if err != nil {
+					return nil, fmt.Errorf("failed to compare %s with %s: %w", commit.Files[a].Path, commits[c.commit].Files[c.file].Path, err)
+				}
+				if s >= threshold {
+					similar = append(similar, pair{a, c, s})
+				}
+			}
+		}
+		// The most similar pairs win; ties keep the file order
+		sort.SliceStable(similar, func(x, y int) bool { return similar[x].similarity > similar[y].similarity })
+		pairs = append(pairs, similar...)
+
+		paired := map[int]bool{}
+		for _, p := range pairs {
+			key := [2]int{p.del.commit, p.del.file}
+			if _, ok := movedTo[key]; ok || paired[p.add] {
+				continue
+			}
+			paired[p.add], movedTo[key] = true, i
+			from, to := commits[p.del.commit].Files[p.del.file], &commit.Files[p.add]
+			touched[from.Path] = true
+			to.Action, to.OldPath = vcs.ActionRename, from.Path
+			m.Logger().Debug("detected rename", "from", from.Path, "to", to.Path, "revision", commit.Revision, "similarity", p.similarity)
+			report.FileRenames = append(report.FileRenames, ReportFileRename{
+				From: from.Path, To: to.Path, Commit: commit.Revision, Similarity: p.similarity,
+			})
+		}
+	}
+
+	// Remove the paired deletions, which the renames replace
+	out := make([]*vcs.Commit, 0, len(commits))
+	for i, commit := range commits {
+		var files []vcs.FileChange
+		receiver, removed := -1, false
+		for j, fc := range commit.Files {
+			if to, ok := movedTo[[2]int{i, j}]; ok {
+				if to != i {
+					receiver = to
+				}
+				removed = true
+				continue
+			}
+			files = append(files, fc)
+		}
+		if !removed {
+			out = append(out, commit)
+			continue
+		}
+		if receiver >= 0 && len(files) == 0 {
+			m.Logger().Debug("folded commit into rename", "revision", commit.Revision, "into", commits[receiver].Revision)
+			commits[receiver].Message = appendMessage(commits[receiver].Message, commit.Message)
+			report.Commits.Renamed++
+			continue
+		}
+		commit.Files = files
+		out = append(out, commit)
+	}
+	return out, nil
+}
+
+// appendMessage appends the message of a folded commit to message unless
+// it is the same
+func appendMessage(message, folded string) string {
+	folded = strings.TrimRight(folded, "\n")
+	if folded == "" || strings.TrimRight(message, "\n") == folded {
+		return message
+	}
+	return strings.TrimRight(message, "\n") + "\n\n" + folded + "\n"
+}
+
+// renameContents caches the file contents compared by rename detection
+type renameContents struct {
+	lines map[*vcs.FileChange][]string
+	data  map[*vcs.FileChange][]byte
+}
+
+func newRenameContents() *renameContents {
+	return &renameContents{lines: map[*vcs.FileChange][]string{}, data: map[*vcs.FileChange][]byte{}}
+}
+
+func (rc *renameContents) read(fc *vcs.FileChange) ([]byte, error) {
+	if data, ok := rc.data[fc]; ok {
+		return data, nil
+	}
+	data, err := fc.ReadContent()
+	if err != nil {
+		return nil, err
+	}
+	rc.data[fc] = data
+	return data, nil
+}
+
+// similarity returns the percentage of lines two file contents share,
+// relative to the longer one. Binary and empty files are only similar
+// when equal.
+func (rc *renameContents) similarity(a, b *vcs.FileChange) (int, error) {
+	da, err := rc.read(a)
+	if err != nil {
+		return 0, err
+	}
+	db, err := rc.read(b)
+	if err != nil {
+		return 0, err
+	}
+	if len(da) == 0 || len(db) == 0 || a.Binary || b.Binary {
+		if len(da) > 0 && bytes.Equal(da, db) {
+			return 100, nil
+		}
+		return 0, nil
+	}
+
+	la, lb := rc.split(a, da), rc.split(b, db)
+	counts := make(map[string]int, len(la))
+	for _, line := range la {
+		counts[line]++
+	}
+	common := 0
+	for _, line := range lb {
+		if counts[line] > 0 {
+			counts[line]--
+			common++
+		}
+	}
+	return common * 100 / max(len(la), len(lb)), nil
+}
+
+func (rc *renameContents) split(fc *vcs.FileChange, data []byte) []string {
+	if lines, ok := rc.lines[fc]; ok {
+		return lines
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	rc.lines[fc] = lines
+	return lines
+}
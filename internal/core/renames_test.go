@@ -0,0 +1,127 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectRenames(t *testing.T) {
+	source := []byte(strings.Repeat("line\n", 8) + "int main() {}\n" + "return 0;\n")
+	moved := []byte(strings.Repeat("line\n", 8) + "int main() { return 1; }\n" + "return 0;\n")
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "c1", Date: date, Message: "Import\n", Files: []vcs.FileChange{
+				{Path: "old.c", Action: vcs.ActionAdd, Content: source},
+				{Path: "keep.c", Action: vcs.ActionAdd, Content: []byte("keep\n")},
+				{Path: "util.c", Action: vcs.ActionAdd, Content: source},
+			}},
+			{Revision: "c2", Date: date.Add(time.Hour), Message: "Remove old.c\n", Files: []vcs.FileChange{
+				{Path: "old.c", Action: vcs.ActionDelete},
+			}},
+			{Revision: "b1", Branch: "FEATURE", Date: date.Add(90 * time.Minute), Message: "Branch work\n", Files: []vcs.FileChange{
+				{Path: "feature.c", Action: vcs.ActionAdd, Content: source},
+			}},
+			{Revision: "c3", Date: date.Add(2 * time.Hour), Message: "Add new.c\n", Files: []vcs.FileChange{
+				{Path: "new.c", Action: vcs.ActionAdd, Content: moved},
+			}},
+			{Revision: "c4", Date: date.Add(3 * time.Hour), Message: "Rename util.c\n", Files: []vcs.FileChange{
+				{Path: "util.c", Action: vcs.ActionDelete},
+				{Path: "lib/util.c", Action: vcs.ActionAdd, Content: source},
+				{Path: "keep.c", Action: vcs.ActionDelete},
+				{Path: "other.c", Action: vcs.ActionAdd, Content: []byte("other\n")},
+			}},
+		}
+	}
+
+	// Disabled by default
+	m := NewMigrator(&MigrationConfig{Logger: logging.Discard()})
+	commits, err := m.detectRenames(history())
+	require.NoError(t, err)
+	require.Len(t, commits, 5)
+
+	m = NewMigrator(&MigrationConfig{RenameSimilarity: 80, Logger: logging.Discard()})
+	commits, err = m.detectRenames(history())
+	require.NoError(t, err)
+	var revisions []string
+	for _, c := range commits {
+		revisions = append(revisions, c.Revision)
+	}
+	require.Equal(t, []string{"c1", "b1", "c3", "c4"}, revisions)
+
+	// The deletion of old.c moved into the commit adding new.c, which
+	// renames it
+	c3 := commits[2]
+	require.Equal(t, "Add new.c\n\nRemove old.c\n", c3.Message)
+	require.Equal(t, []vcs.FileChange{{Path: "new.c", OldPath: "old.c", Action: vcs.ActionRename, Content: moved}}, c3.Files)
+	require.Len(t, commits[3].Files, 3)
+	require.Equal(t, vcs.FileChange{Path: "lib/util.c", OldPath: "util.c", Action: vcs.ActionRename, Content: source}, commits[3].Files[0])
+	require.Equal(t, 1, m.report.Commits.Renamed)
+	require.Equal(t, []ReportFileRename{
+		{From: "old.c", To: "new.c", Commit: "c3", Similarity: 90},
+		{From: "util.c", To: "lib/util.c", Commit: "c4", Similarity: 100},
+	}, m.report.FileRenames)
+
+	// Below the threshold, files are not paired
+	m = NewMigrator(&MigrationConfig{RenameSimilarity: 95, Logger: logging.Discard()})
+	commits, err = m.detectRenames(history())
+	require.NoError(t, err)
+	require.Len(t, commits, 5)
+	require.Equal(t, []ReportFileRename{{From: "util.c", To: "lib/util.c", Commit: "c4", Similarity: 100}}, m.report.FileRenames)
+
+	m.config.RenameSimilarity = 101
+	require.ErrorContains(t, m.validateRenameSimilarity(), "percentage")
+	m.config.RenameSimilarity, m.config.RenameLimit = 50, -1
+	require.ErrorContains(t, m.validateRenameSimilarity(), "rename limit")
+}
+
+func TestDetectRenames_Limit(t *testing.T) {
+	content := func(name string, changed bool) []byte {
+		data := strings.Repeat(name+"\n", 9)
+		if changed {
+			data += "changed\n"
+		}
+		return []byte(data)
+	}
+	history := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "c1", Files: []vcs.FileChange{
+				{Path: "a.c", Action: vcs.ActionAdd, Content: content("a", false)},
+				{Path: "b.c", Action: vcs.ActionAdd, Content: content("b", false)},
+				{Path: "c.c", Action: vcs.ActionAdd, Content: content("c", false)},
+			}},
+			{Revision: "c2", Files: []vcs.FileChange{
+				{Path: "a.c", Action: vcs.ActionDelete},
+				{Path: "b.c", Action: vcs.ActionDelete},
+				{Path: "c.c", Action: vcs.ActionDelete},
+				{Path: "src/b.c", Action: vcs.ActionAdd, Content: content("b", true)},
+				{Path: "src/c.c", Action: vcs.ActionAdd, Content: content("c", true)},
+				{Path: "src/a.c", Action: vcs.ActionAdd, Content: content("a", false)},
+			}},
+		}
+	}
+
+	m := NewMigrator(&MigrationConfig{RenameSimilarity: 80, Logger: logging.Discard()})
+	commits, err := m.detectRenames(history())
+	require.NoError(t, err)
+	require.Len(t, commits[1].Files, 3)
+	require.Equal(t, []ReportFileRename{
+		{From: "a.c", To: "src/a.c", Commit: "c2", Similarity: 100},
+		{From: "b.c", To: "src/b.c", Commit: "c2", Similarity: 90},
+		{From: "c.c", To: "src/c.c", Commit: "c2", Similarity: 90},
+	}, m.report.FileRenames)
+
+	// Beyond the limit only the unchanged file is paired
+	m = NewMigrator(&MigrationConfig{RenameSimilarity: 80, RenameLimit: 1, Logger: logging.Discard()})
+	commits, err = m.detectRenames(history())
+	require.NoError(t, err)
+	require.Equal(t, []ReportFileRename{{From: "a.c", To: "src/a.c", Commit: "c2", Similarity: 100}}, m.report.FileRenames)
+	require.Len(t, commits[1].Files, 5)
+	require.Len(t, m.issues, 1)
+	require.Contains(t, m.issues[0].Message, "renameLimit=1")
+}
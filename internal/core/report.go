@@ -0,0 +1,768 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Report file extensions, appended to ReportPath
+const (
+	ReportExtJSON     = ".json"
+	ReportExtMarkdown = ".md"
+	ReportExtHTML     = ".html"
+)
+
+// MigrationReport summarizes a migration run
+type MigrationReport struct {
+	MigrationID     string              `json:"migrationId"`
+	SourceType      string              `json:"sourceType"`
+	SourcePath      string              `json:"sourcePath"`
+	SourceSnapshot  time.Time           `json:"sourceSnapshot,omitzero"` // When the source was fetched with FetchCVSRoot
+	TargetPath      string              `json:"targetPath"`
+	DryRun          bool                `json:"dryRun"`
+	Simulate        bool                `json:"simulate,omitempty"`
+	Status          string              `json:"status"` // completed or failed
+	Error           string              `json:"error,omitempty"`
+	StartedAt       time.Time           `json:"startedAt"`
+	FinishedAt      time.Time           `json:"finishedAt"`
+	DurationSeconds float64             `json:"durationSeconds"`
+	Commits         ReportCommits       `json:"commits"`
+	Authors         ReportAuthors       `json:"authors"`
+	Branches        ReportRefs          `json:"branches"`
+	Tags            ReportRefs          `json:"tags"`
+	Renames         []mapping.RefRename `json:"renames"`
+	Overrides       []ReportOverride    `json:"overrides"`              // Source revisions skipped or replaced by revision rules
+	SkippedFiles    []ReportSkippedFile `json:"skippedFiles,omitempty"` // Source files that could not be read
+	FileRenames     []ReportFileRename  `json:"fileRenames,omitempty"`  // Deleted and added files paired by rename detection
+	CaseClashes     []ReportCaseClash   `json:"caseClashes,omitempty"`  // Paths differing from an earlier path only by case
+	Normalized      []ReportNormalized  `json:"normalized,omitempty"`   // Paths rewritten to the configured Unicode normalization form
+	Sanitized       []ReportSanitized   `json:"sanitized,omitempty"`    // Paths rewritten to be valid on Windows
+	Phases          []ReportPhase       `json:"phases"`
+	ContentCache    *ReportContentCache `json:"contentCache,omitempty"` // CVS file revision cache, if the source used one
+	Memory          *ReportMemory       `json:"memory,omitempty"`       // Memory budget, if one was set
+	History         *ReportHistory      `json:"history,omitempty"`      // CVSROOT/history events, if imported
+	Warnings        []string            `json:"warnings"`
+	Errors          []string            `json:"errors"` // Failures tolerated by the error policy
+	Verification    *ReportVerification `json:"verification,omitempty"`
+	Simulation      *ReportSimulation   `json:"simulation,omitempty"` // In-memory target of a simulated run
+	Graft           *ReportGraft        `json:"graft,omitempty"`      // Existing commit the history was grafted onto
+}
+
+// ReportCommits counts the source commits by outcome
+type ReportCommits struct {
+	Total          int `json:"total"`          // Commits read from the source, after history limits
+	Applied        int `json:"applied"`        // Commits written by this run
+	AlreadyApplied int `json:"alreadyApplied"` // Commits written by an earlier run
+	Vetoed         int `json:"vetoed"`         // Commits skipped by a hook
+	Failed         int `json:"failed"`         // Commits that failed under ErrorPolicyContinue
+	Folded         int `json:"folded"`         // File changes folded into the initial commit by a history limit
+	Dropped        int `json:"dropped"`        // Commits left without file changes by revision rules
+	Split          int `json:"split"`          // Source commits split into several commits by the commit limits
+	Renamed        int `json:"renamed"`        // Commits folded into the commit of a rename by rename detection
+}
+
+// ReportOverride is a source file revision a revision rule skipped or
+// replaced
+type ReportOverride struct {
+	Path     string `json:"path"`
+	Revision string `json:"revision"`
+	Commit   string `json:"commit"` // Source revision of the commit
+	Action   string `json:"action"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ReportSkippedFile is a source file left out of the history because it
+// could not be read
+type ReportSkippedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// ReportAuthors lists the source authors by whether the author map covered
+// them
+type ReportAuthors struct {
+	Mapped   []string `json:"mapped"`
+	Unmapped []string `json:"unmapped"`
+	MapFile  string   `json:"mapFile,omitempty"` // Author map of the identities used, including fallbacks
+}
+
+// ReportRefs lists the branches or tags by outcome
+type ReportRefs struct {
+	Created  []string          `json:"created"`
+	Filtered []string          `json:"filtered"` // Excluded by include/exclude patterns
+	Failed   map[string]string `json:"failed"`   // Name -> error
+}
+
+// ReportPhase records how long a migration phase ran
+type ReportPhase struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// ReportContentCache records how the file revisions read from CVS were
+// produced
+type ReportContentCache struct {
+	Hits      int64   `json:"hits"`      // Served from memory
+	DiskHits  int64   `json:"diskHits"`  // Served from the disk cache
+	Misses    int64   `json:"misses"`    // Reconstructed from the RCS deltas
+	Evictions int64   `json:"evictions"` // Dropped from memory to stay within the size
+	HitRate   float64 `json:"hitRate"`   // Share of revisions served from a cache
+}
+
+// ReportVerification compares the target with what the migration expected
+// to write
+type ReportVerification struct {
+	Passed          bool              `json:"passed"`
+	ExpectedCommits int               `json:"expectedCommits"`
+	TargetCommits   int               `json:"targetCommits"`
+	Heads           map[string]string `json:"heads,omitempty"` // Branch -> head commit hash
+	Problems        []string          `json:"problems"`
+
+	// Branch tips compared with fresh CVS checkouts, if VerifyManifest is set
+	Manifests    []ReportManifest `json:"manifests,omitempty"`
+	ManifestFile string           `json:"manifestFile,omitempty"` // Manifests of the branch tips, see ManifestPath
+}
+
+// ReportPath returns the path, without extension, of the report files
+// written next to the target repository
+func ReportPath(targetPath string) string {
+	target := filepath.Clean(targetPath)
+	return filepath.Join(filepath.Dir(target), filepath.Base(target)+".migration-report")
+}
+
+// AuthorMapPath returns the path of the author map exported next to the
+// target repository
+func AuthorMapPath(targetPath string) string {
+	target := filepath.Clean(targetPath)
+	return filepath.Join(filepath.Dir(target), filepath.Base(target)+".authors.yaml")
+}
+
+func newMigrationReport(config *MigrationConfig, migrationID string) *MigrationReport {
+	return &MigrationReport{
+		MigrationID: migrationID,
+		SourceType:  config.SourceType,
+		SourcePath:  config.SourcePath,
+		TargetPath:  config.TargetPath,
+		DryRun:      config.DryRun,
+		Simulate:    config.Simulate,
+		StartedAt:   time.Now(),
+		Authors:     ReportAuthors{Mapped: []string{}, Unmapped: []string{}},
+		Branches:    ReportRefs{Created: []string{}, Filtered: []string{}, Failed: map[string]string{}},
+		Tags:        ReportRefs{Created: []string{}, Filtered: []string{}, Failed: map[string]string{}},
+		Renames:     []mapping.RefRename{},
+		Overrides:   []ReportOverride{},
+		Phases:      []ReportPhase{},
+		Warnings:    []string{},
+		Errors:      []string{},
+	}
+}
+
+// resetReport starts a new report for a run
+func (m *Migrator) resetReport(migrationID string) {
+	m.report = newMigrationReport(m.config, migrationID)
+	m.mappedAuthors = make(map[string]bool)
+	m.unmappedAuthors = make(map[string]bool)
+	m.usedAuthors = make(map[string]string)
+}
+
+// currentReport returns the report of the running migration, starting one
+// for migrators that were not started through Run
+func (m *Migrator) currentReport() *MigrationReport {
+	if m.report == nil {
+		m.resetReport("")
+	}
+	return m.report
+}
+
+// Report returns the report of the last Run, or nil if Run was not called
+func (m *Migrator) Report() *MigrationReport {
+	return m.report
+}
+
+// recordAuthor notes whether the author map covered a source author and
+// the identity the author was given
+func (m *Migrator) recordAuthor(login, name, email string) {
+	if _, ok := m.config.AuthorMap[login]; ok {
+		m.mappedAuthors[login] = true
+	} else {
+		m.unmappedAuthors[login] = true
+	}
+	m.usedAuthors[login] = fmt.Sprintf("%s <%s>", name, email)
+}
+
+// writeAuthorMap exports the identities used by the run as an author map
+// that later runs can be configured with
+func (m *Migrator) writeAuthorMap(path string) error {
+	data, err := yaml.Marshal(m.usedAuthors)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf("# Authors of migration %s from %s, including default identities\n", m.report.MigrationID, m.config.SourcePath)
+	return storage.WriteFileAtomic(path, append([]byte(header), data...), 0o644)
+}
+
+// finishReport completes the report after Run and writes it next to the
+// target repository
+func (m *Migrator) finishReport(runErr error) {
+	r := m.report
+	r.FinishedAt = time.Now()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	r.Status = "completed"
+	if runErr != nil {
+		r.Status = "failed"
+		r.Error = runErr.Error()
+	}
+
+	r.Authors.Mapped = sortedKeys(m.mappedAuthors)
+	r.Authors.Unmapped = sortedKeys(m.unmappedAuthors)
+	r.Renames = append(r.Renames, m.refRenames...)
+	for _, p := range m.reporter.Phases() {
+		r.Phases = append(r.Phases, ReportPhase{Name: string(p.Phase), DurationSeconds: p.Duration.Seconds()})
+		m.config.Profile.AddPhase(string(p.Phase), p.Duration)
+	}
+	if m.contentCache != nil {
+		stats := m.contentCache.Stats()
+		r.ContentCache = &ReportContentCache{
+			Hits:      stats.Hits,
+			DiskHits:  stats.DiskHits,
+			Misses:    stats.Misses,
+			Evictions: stats.Evictions,
+		}
+		if total := stats.Hits + stats.DiskHits + stats.Misses; total > 0 {
+			r.ContentCache.HitRate = float64(stats.Hits+stats.DiskHits) / float64(total)
+		}
+	}
+
+	if m.config.DryRun || m.config.TargetPath == "" {
+		return
+	}
+	if len(m.usedAuthors) > 0 {
+		path := AuthorMapPath(m.config.TargetPath)
+		if err := m.writeAuthorMap(path); err != nil {
+			m.Logger().Warn("failed to write author map", "error", err)
+		} else {
+			r.Authors.MapFile = path
+		}
+	}
+	if err := r.WriteFiles(ReportPath(m.config.TargetPath)); err != nil {
+		m.Logger().Warn("failed to write migration report", "error", err)
+		return
+	}
+	m.Logger().Info("wrote migration report", "path", ReportPath(m.config.TargetPath)+ReportExtJSON)
+}
+
+// verify compares the target repository with the outcome of the run
+func (m *Migrator) verify() {
+	v := &ReportVerification{Problems: []string{}}
+	c := m.report.Commits
+	v.ExpectedCommits = c.Total - c.Vetoed - c.Failed
+	if g := m.report.Graft; g != nil && g.Mode == GraftOnto {
+		// The existing history stands in for the commits it holds
+		v.ExpectedCommits += g.depth - g.Commits
+	}
+
+	if counter, ok := m.target.(interface{ GetCommitCount() (int, error) }); ok {
+		count, err := counter.GetCommitCount()
+		if err != nil {
+			v.Problems = append(v.Problems, fmt.Sprintf("failed to count target commits: %v", err))
+		} else {
+			v.TargetCommits = count
+			if count != v.ExpectedCommits {
+				v.Problems = append(v.Problems, fmt.Sprintf("target has %d commits, expected %d", count, v.ExpectedCommits))
+			}
+		}
+	}
+
+	if lister, ok := m.target.(interface{ ListBranches() ([]string, error) }); ok {
+		branches, err := lister.ListBranches()
+		if err != nil {
+			v.Problems = append(v.Problems, fmt.Sprintf("failed to list target branches: %v", err))
+		}
+		existing := make(map[string]bool, len(branches))
+		for _, b := range branches {
+			existing[b] = true
+		}
+		for _, b := range m.report.Branches.Created {
+			if err == nil && !existing[b] {
+				v.Problems = append(v.Problems, fmt.Sprintf("branch %s is missing", b))
+			}
+		}
+	}
+
+	if lister, ok := m.target.(interface {
+		ListTags() (map[string]string, error)
+	}); ok {
+		tags, err := lister.ListTags()
+		if err != nil {
+			v.Problems = append(v.Problems, fmt.Sprintf("failed to list target tags: %v", err))
+		}
+		for _, t := range m.report.Tags.Created {
+			if _, ok := tags[t]; err == nil && !ok {
+				v.Problems = append(v.Problems, fmt.Sprintf("tag %s is missing", t))
+			}
+		}
+	}
+
+	// The heads identify the converted history, e.g. to compare a rehearsal
+	// with the final run in deterministic mode
+	if lister, ok := m.target.(interface {
+		BranchHeads() (map[string]string, error)
+	}); ok {
+		heads, err := lister.BranchHeads()
+		if err != nil {
+			v.Problems = append(v.Problems, fmt.Sprintf("failed to list target branch heads: %v", err))
+		}
+		v.Heads = heads
+	}
+
+	if m.config.VerifyManifest {
+		m.verifyManifests(v)
+	}
+
+	v.Passed = len(v.Problems) == 0
+	m.report.Verification = v
+	if !v.Passed {
+		m.Logger().Warn("verification found problems", "problems", strings.Join(v.Problems, "; "))
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteFiles writes the report as JSON, Markdown and HTML to base plus the
+// respective extension
+func (r *MigrationReport) WriteFiles(base string) error {
+	writers := []struct {
+		ext   string
+		write func(io.Writer) error
+	}{
+		{ReportExtJSON, r.WriteJSON},
+		{ReportExtMarkdown, r.WriteMarkdown},
+		{ReportExtHTML, r.WriteHTML},
+	}
+	for _, w := range writers {
+		f, err := os.Create(base + w.ext)
+		if err != nil {
+			return err
+		}
+		if err := w.write(f); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *MigrationReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as a Markdown document
+func (r *MigrationReport) WriteMarkdown(w io.Writer) error {
+	return markdownReport.Execute(w, r)
+}
+
+// WriteHTML writes the report as a standalone HTML page
+func (r *MigrationReport) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, r)
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+func formatPercent(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+var (
+	htmlReport = htmltemplate.Must(htmltemplate.New("report").
+			Funcs(htmltemplate.FuncMap{"duration": formatSeconds, "percent": formatPercent}).Parse(reportHTML))
+	markdownReport = texttemplate.Must(texttemplate.New("report").
+			Funcs(texttemplate.FuncMap{"duration": formatSeconds, "percent": formatPercent}).Parse(reportMarkdown))
+)
+
+const reportMarkdown = `# Migration Report
+
+| | |
+|---|---|
+| Migration | {{.MigrationID}} |
+| Status | {{.Status}}{{if .Error}}: {{.Error}}{{end}} |
+| Source | {{.SourceType}} {{.SourcePath}} |
+{{- if not .SourceSnapshot.IsZero}}
+| Snapshot | {{.SourceSnapshot.UTC.Format "2006-01-02 15:04:05 UTC"}} |
+{{- end}}
+| Target | {{.TargetPath}} |
+| Duration | {{duration .DurationSeconds}} |
+{{- if .Overrides}}
+
+## Source overrides
+
+{{len .Overrides}} source file revisions were skipped or replaced by revision rules.
+
+| Path | Revision | Commit | Action | Reason |
+|---|---|---|---|---|
+{{- range .Overrides}}
+| {{.Path}} | {{.Revision}} | {{.Commit}} | {{.Action}} | {{.Reason}} |{{end}}{{end}}
+{{- if .SkippedFiles}}
+
+## Skipped files
+
+{{len .SkippedFiles}} source files could not be read; their history was not migrated.
+
+| Path | Error |
+|---|---|
+{{- range .SkippedFiles}}
+| {{.Path}} | {{.Error}} |{{end}}{{end}}
+
+## Commits
+
+| Total | Applied | Already applied | Vetoed by hooks | Failed |
+|---|---|---|---|---|
+| {{.Commits.Total}} | {{.Commits.Applied}} | {{.Commits.AlreadyApplied}} | {{.Commits.Vetoed}} | {{.Commits.Failed}} |
+{{- if .Commits.Folded}}
+
+{{.Commits.Folded}} older file changes were folded into the initial commit by the history limit.
+{{- end}}
+{{- if .Commits.Split}}
+
+{{.Commits.Split}} large source commits were split into several commits by the commit limits.
+{{- end}}
+{{- if .Commits.Dropped}}
+
+{{.Commits.Dropped}} commits were dropped because revision rules skipped all their file changes.
+{{- end}}
+{{- if .Commits.Renamed}}
+
+{{.Commits.Renamed}} commits holding only deletions were folded into the commit of the rename.
+{{- end}}
+{{- if .FileRenames}}
+
+## File renames
+
+{{len .FileRenames}} deleted and added files were paired as renames.
+
+| From | To | Commit | Similarity |
+|---|---|---|---|
+{{- range .FileRenames}}
+| {{.From}} | {{.To}} | {{.Commit}} | {{.Similarity}}% |{{end}}{{end}}
+{{- if .CaseClashes}}
+
+## Case conflicts
+
+{{len .CaseClashes}} paths differ from an earlier path only by case.
+
+| Path | Conflict | Commit | Written as |
+|---|---|---|---|
+{{- range .CaseClashes}}
+| {{.Path}} | {{.Conflict}} | {{.Commit}} | {{if .Resolved}}{{.Resolved}}{{else}}unchanged{{end}} |{{end}}{{end}}
+{{- if .Normalized}}
+
+## Unicode paths
+
+{{len .Normalized}} paths were rewritten to one Unicode normalization form.
+
+| Path | Written as |
+|---|---|
+{{- range .Normalized}}
+| {{.Path}} | {{.Normalized}} |{{end}}{{end}}
+{{- if .Sanitized}}
+
+## Windows paths
+
+{{len .Sanitized}} paths Windows cannot check out were rewritten.
+
+| Path | Written as | Problem |
+|---|---|---|
+{{- range .Sanitized}}
+| {{.Path}} | {{.Sanitized}} | {{.Problem}} |{{end}}{{end}}
+
+## Authors
+
+Mapped: {{len .Authors.Mapped}}, unmapped: {{len .Authors.Unmapped}}
+{{- if .Authors.Unmapped}}
+{{range .Authors.Unmapped}}
+- {{.}}{{end}}{{end}}
+{{- if .Authors.MapFile}}
+
+Identities used: {{.Authors.MapFile}}
+{{- end}}
+
+## Branches
+
+{{template "refs" .Branches}}
+
+## Tags
+
+{{template "refs" .Tags}}
+{{- if .Renames}}
+
+## Renamed refs
+
+| Kind | Original | Git name |
+|---|---|---|{{range .Renames}}
+| {{.Kind}} | {{.Original}} | {{.Sanitized}} |{{end}}{{end}}
+{{- if .Phases}}
+
+## Phases
+{{range .Phases}}
+- {{.Name}}: {{duration .DurationSeconds}}{{end}}{{end}}
+{{- with .ContentCache}}
+
+## Content cache
+
+| Memory hits | Disk hits | Misses | Evictions | Hit rate |
+|---|---|---|---|---|
+| {{.Hits}} | {{.DiskHits}} | {{.Misses}} | {{.Evictions}} | {{percent .HitRate}} |{{end}}
+{{- with .Memory}}
+
+## Memory
+
+| Budget | Content cache | Source texts in memory | Source files on disk | Peak buffered | Spilled |
+|---|---|---|---|---|---|
+| {{.BudgetBytes}} B | {{.ContentCacheBytes}} B | {{.SourceTextBytes}} B | {{.SourceFilesOnDisk}} | {{.PeakBufferedBytes}} B | {{.SpilledFiles}} files, {{.SpilledBytes}} B |{{end}}
+{{- with .History}}
+
+## CVS history
+
+{{len .Events}} events from CVSROOT/history{{if .Unmatched}}, {{.Unmatched}} commit events without a migrated revision{{end}}.
+{{- if .Events}}
+
+| Time | Event | User | Path | Revision | Commit |
+|---|---|---|---|---|---|
+{{- range .Events}}
+| {{.Time.UTC.Format "2006-01-02 15:04:05"}} | {{.Event}} | {{.User}} | {{.Path}} | {{.Revision}} | {{.Commit}} |{{end}}{{end}}{{end}}
+{{- if .Errors}}
+
+## Errors
+{{range .Errors}}
+- {{.}}{{end}}{{end}}
+{{- if .Warnings}}
+
+## Warnings
+{{range .Warnings}}
+- {{.}}{{end}}{{end}}
+{{- with .Verification}}
+
+## Verification
+
+{{if .Passed}}Passed{{else}}Failed{{end}}: {{.TargetCommits}} of {{.ExpectedCommits}} expected commits
+{{- if .Problems}}
+{{range .Problems}}
+- {{.}}{{end}}{{end}}
+{{- if .Heads}}
+
+| Branch | Head |
+|--------|------|
+{{- range $branch, $hash := .Heads}}
+| {{$branch}} | {{$hash}} |{{end}}{{end}}
+{{- range .Manifests}}
+
+### Manifest of {{.Branch}}
+
+{{.Matched}} of {{.Files}} files match the CVS checkout{{with .Source}} of {{.}}{{end}}
+{{- if .Mismatches}}
+
+| Path | Problem | CVS revision |
+|------|---------|--------------|
+{{- range .Mismatches}}
+| {{.Path}} | {{.Problem}} | {{.CVSRevision}} |{{end}}{{end}}{{end}}{{end}}
+{{- with .Simulation}}
+
+## Simulation
+
+| Commits | Trees | Blobs | Tags | Raw size | Estimated pack size |
+|---|---|---|---|---|---|
+| {{.Commits}} | {{.Trees}} | {{.Blobs}} | {{.Tags}} | {{.RawBytes}} B | {{.EstimatedPackBytes}} B |
+{{- if .HeadTree}}
+
+HEAD {{.HeadCommit}}, tree {{.HeadTree}}
+{{- end}}
+{{- if .Refs}}
+
+| Ref | Hash |
+|---|---|
+{{- range .Refs}}
+| {{.Name}} | {{.Hash}} |{{end}}{{end}}{{end}}
+{{- with .Graft}}
+
+## Graft
+
+{{if eq .Mode "replace"}}Replaced {{.Commit}} with {{.Replacement}}{{else}}Grafted onto {{.Commit}}{{end}}, which holds {{.Commits}} CVS commits up to {{.Date.UTC.Format "2006-01-02 15:04:05"}}{{end}}
+{{define "refs"}}Created: {{len .Created}}, filtered: {{len .Filtered}}, failed: {{len .Failed}}
+{{- if .Failed}}
+{{range $name, $err := .Failed}}
+- {{$name}}: {{$err}}{{end}}{{end}}{{end}}`
+
+const reportHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>Migration Report {{.MigrationID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2rem; }
+table { border-collapse: collapse; margin-bottom: 1rem; }
+th, td { border: 1px solid #ccc; padding: 0.25rem 0.75rem; text-align: left; }
+.failed { color: #c62828; }
+.completed { color: #2e7d32; }
+</style>
+</head>
+<body>
+<h1>Migration Report</h1>
+<table>
+<tr><th>Migration</th><td>{{.MigrationID}}</td></tr>
+<tr><th>Status</th><td class="{{.Status}}">{{.Status}}{{if .Error}}: {{.Error}}{{end}}</td></tr>
+<tr><th>Source</th><td>{{.SourceType}} {{.SourcePath}}</td></tr>
+{{- if not .SourceSnapshot.IsZero}}
+<tr><th>Snapshot</th><td>{{.SourceSnapshot.UTC.Format "2006-01-02 15:04:05 UTC"}}</td></tr>
+{{- end}}
+<tr><th>Target</th><td>{{.TargetPath}}</td></tr>
+<tr><th>Duration</th><td>{{duration .DurationSeconds}}</td></tr>
+</table>
+{{if .Overrides}}<h2 class="failed">Source overrides</h2>
+<p>{{len .Overrides}} source file revisions were skipped or replaced by revision rules.</p>
+<table><tr><th>Path</th><th>Revision</th><th>Commit</th><th>Action</th><th>Reason</th></tr>
+{{range .Overrides}}<tr><td>{{.Path}}</td><td>{{.Revision}}</td><td>{{.Commit}}</td><td>{{.Action}}</td><td>{{.Reason}}</td></tr>{{end}}
+</table>{{end}}
+{{if .SkippedFiles}}<h2 class="failed">Skipped files</h2>
+<p>{{len .SkippedFiles}} source files could not be read; their history was not migrated.</p>
+<table><tr><th>Path</th><th>Error</th></tr>
+{{range .SkippedFiles}}<tr><td>{{.Path}}</td><td>{{.Error}}</td></tr>{{end}}
+</table>{{end}}
+<h2>Commits</h2>
+<table>
+<tr><th>Total</th><td>{{.Commits.Total}}</td></tr>
+<tr><th>Applied</th><td>{{.Commits.Applied}}</td></tr>
+<tr><th>Already applied</th><td>{{.Commits.AlreadyApplied}}</td></tr>
+<tr><th>Vetoed by hooks</th><td>{{.Commits.Vetoed}}</td></tr>
+<tr><th>Failed</th><td>{{.Commits.Failed}}</td></tr>
+{{- if .Commits.Folded}}
+<tr><th>File changes folded by the history limit</th><td>{{.Commits.Folded}}</td></tr>
+{{- end}}
+{{- if .Commits.Split}}
+<tr><th>Split by the commit limits</th><td>{{.Commits.Split}}</td></tr>
+{{- end}}
+{{- if .Commits.Dropped}}
+<tr><th>Dropped by revision rules</th><td>{{.Commits.Dropped}}</td></tr>
+{{- end}}
+{{- if .Commits.Renamed}}
+<tr><th>Folded into renames</th><td>{{.Commits.Renamed}}</td></tr>
+{{- end}}
+</table>
+{{if .FileRenames}}<h2>File renames</h2>
+<p>{{len .FileRenames}} deleted and added files were paired as renames.</p>
+<table><tr><th>From</th><th>To</th><th>Commit</th><th>Similarity</th></tr>
+{{range .FileRenames}}<tr><td>{{.From}}</td><td>{{.To}}</td><td>{{.Commit}}</td><td>{{.Similarity}}%</td></tr>{{end}}
+</table>{{end}}
+{{if .CaseClashes}}<h2 class="failed">Case conflicts</h2>
+<p>{{len .CaseClashes}} paths differ from an earlier path only by case.</p>
+<table><tr><th>Path</th><th>Conflict</th><th>Commit</th><th>Written as</th></tr>
+{{range .CaseClashes}}<tr><td>{{.Path}}</td><td>{{.Conflict}}</td><td>{{.Commit}}</td><td>{{if .Resolved}}{{.Resolved}}{{else}}unchanged{{end}}</td></tr>{{end}}
+</table>{{end}}
+{{if .Normalized}}<h2>Unicode paths</h2>
+<p>{{len .Normalized}} paths were rewritten to one Unicode normalization form.</p>
+<table><tr><th>Path</th><th>Written as</th></tr>
+{{range .Normalized}}<tr><td>{{.Path}}</td><td>{{.Normalized}}</td></tr>{{end}}
+</table>{{end}}
+{{if .Sanitized}}<h2>Windows paths</h2>
+<p>{{len .Sanitized}} paths Windows cannot check out were rewritten.</p>
+<table><tr><th>Path</th><th>Written as</th><th>Problem</th></tr>
+{{range .Sanitized}}<tr><td>{{.Path}}</td><td>{{.Sanitized}}</td><td>{{.Problem}}</td></tr>{{end}}
+</table>{{end}}
+<h2>Authors</h2>
+<p>Mapped: {{len .Authors.Mapped}}, unmapped: {{len .Authors.Unmapped}}</p>
+{{if .Authors.Unmapped}}<ul>{{range .Authors.Unmapped}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Authors.MapFile}}<p>Identities used: <code>{{.Authors.MapFile}}</code></p>{{end}}
+<h2>Branches</h2>
+{{template "refs" .Branches}}
+<h2>Tags</h2>
+{{template "refs" .Tags}}
+{{if .Renames}}<h2>Renamed refs</h2>
+<table><tr><th>Kind</th><th>Original</th><th>Git name</th></tr>
+{{range .Renames}}<tr><td>{{.Kind}}</td><td>{{.Original}}</td><td>{{.Sanitized}}</td></tr>{{end}}
+</table>{{end}}
+{{if .Phases}}<h2>Phases</h2>
+<table>{{range .Phases}}<tr><th>{{.Name}}</th><td>{{duration .DurationSeconds}}</td></tr>{{end}}</table>{{end}}
+{{with .ContentCache}}<h2>Content cache</h2>
+<table>
+<tr><th>Memory hits</th><td>{{.Hits}}</td></tr>
+<tr><th>Disk hits</th><td>{{.DiskHits}}</td></tr>
+<tr><th>Misses</th><td>{{.Misses}}</td></tr>
+<tr><th>Evictions</th><td>{{.Evictions}}</td></tr>
+<tr><th>Hit rate</th><td>{{percent .HitRate}}</td></tr>
+</table>{{end}}
+{{with .Memory}}<h2>Memory</h2>
+<table>
+<tr><th>Budget</th><td>{{.BudgetBytes}} B</td></tr>
+<tr><th>Content cache</th><td>{{.ContentCacheBytes}} B</td></tr>
+<tr><th>Source texts in memory</th><td>{{.SourceTextBytes}} B</td></tr>
+<tr><th>Source files on disk</th><td>{{.SourceFilesOnDisk}}</td></tr>
+<tr><th>Peak buffered</th><td>{{.PeakBufferedBytes}} B</td></tr>
+<tr><th>Spilled</th><td>{{.SpilledFiles}} files, {{.SpilledBytes}} B</td></tr>
+</table>{{end}}
+{{with .History}}<h2>CVS history</h2>
+<p>{{len .Events}} events from CVSROOT/history{{if .Unmatched}}, {{.Unmatched}} commit events without a migrated revision{{end}}.</p>
+{{if .Events}}<table><tr><th>Time</th><th>Event</th><th>User</th><th>Path</th><th>Revision</th><th>Commit</th></tr>
+{{range .Events}}<tr><td>{{.Time.UTC.Format "2006-01-02 15:04:05"}}</td><td>{{.Event}}</td><td>{{.User}}</td><td>{{.Path}}</td><td>{{.Revision}}</td><td><code>{{.Commit}}</code></td></tr>{{end}}
+</table>{{end}}{{end}}
+{{if .Errors}}<h2>Errors</h2>
+<ul>{{range .Errors}}<li class="failed">{{.}}</li>{{end}}</ul>{{end}}
+{{if .Warnings}}<h2>Warnings</h2>
+<ul>{{range .Warnings}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{with .Verification}}<h2>Verification</h2>
+<p class="{{if .Passed}}completed{{else}}failed{{end}}">{{if .Passed}}Passed{{else}}Failed{{end}}:
+{{.TargetCommits}} of {{.ExpectedCommits}} expected commits</p>
+{{if .Problems}}<ul>{{range .Problems}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Heads}}<table>{{range $branch, $hash := .Heads}}<tr><th>{{$branch}}</th><td><code>{{$hash}}</code></td></tr>{{end}}</table>{{end}}
+{{range .Manifests}}<h3>Manifest of {{.Branch}}</h3>
+<p>{{.Matched}} of {{.Files}} files match the CVS checkout{{with .Source}} of {{.}}{{end}}</p>
+{{if .Mismatches}}<table>
+<tr><th>Path</th><th>Problem</th><th>CVS revision</th></tr>
+{{range .Mismatches}}<tr><td>{{.Path}}</td><td class="failed">{{.Problem}}</td><td>{{.CVSRevision}}</td></tr>
+{{end}}</table>{{end}}{{end}}{{end}}
+{{with .Simulation}}<h2>Simulation</h2>
+<table>
+<tr><th>Commits</th><td>{{.Commits}}</td></tr>
+<tr><th>Trees</th><td>{{.Trees}}</td></tr>
+<tr><th>Blobs</th><td>{{.Blobs}}</td></tr>
+<tr><th>Tags</th><td>{{.Tags}}</td></tr>
+<tr><th>Raw size</th><td>{{.RawBytes}} B</td></tr>
+<tr><th>Estimated pack size</th><td>{{.EstimatedPackBytes}} B</td></tr>
+{{- if .HeadTree}}
+<tr><th>HEAD</th><td><code>{{.HeadCommit}}</code></td></tr>
+<tr><th>HEAD tree</th><td><code>{{.HeadTree}}</code></td></tr>
+{{- end}}
+</table>
+{{if .Refs}}<table>{{range .Refs}}<tr><th>{{.Name}}</th><td><code>{{.Hash}}</code></td></tr>{{end}}</table>{{end}}{{end}}
+{{with .Graft}}<h2>Graft</h2>
+<p>{{if eq .Mode "replace"}}Replaced <code>{{.Commit}}</code> with <code>{{.Replacement}}</code>{{else}}Grafted onto <code>{{.Commit}}</code>{{end}},
+which holds {{.Commits}} CVS commits up to {{.Date.UTC.Format "2006-01-02 15:04:05"}}</p>{{end}}
+</body>
+</html>
+{{define "refs"}}<p>Created: {{len .Created}}, filtered: {{len .Filtered}}, failed: {{len .Failed}}</p>
+{{if .Failed}}<ul>{{range $name, $err := .Failed}}<li class="failed">{{$name}}: {{$err}}</li>{{end}}</ul>{{end}}
+{{end}}`
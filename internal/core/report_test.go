@@ -0,0 +1,259 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/profile"
+	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRun_WritesMigrationReport(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "1", Author: "alice", Date: date, Message: "first",
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a")}}},
+		{Revision: "2", Author: "bob", Date: date.Add(time.Hour), Message: "please skip",
+			Files: []vcs.FileChange{{Path: "b.txt", Action: vcs.ActionAdd, Content: []byte("b")}}},
+		{Revision: "3", Author: "carol", Date: date.Add(2 * time.Hour), Message: "third",
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionModify, Content: []byte("c")}}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType:      "cvs",
+		SourcePath:      "/src",
+		TargetPath:      target,
+		AuthorMap:       map[string]string{"alice": "Alice <alice@example.com>"},
+		ExcludeBranches: []string{"tmp-*"},
+		Hooks:           []CommitHook{&recordingHook{}},
+		Logger:          logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithRefs{
+		mockReaderWithCommits: mockReaderWithCommits{commits: commits},
+		branches:              []string{"dev", "tmp-x", "bad name"},
+		tags:                  map[string]string{"REL_1": "HEAD", "BROKEN": "no-such-revision"},
+	}
+	require.NoError(t, m.Run())
+
+	report := m.Report()
+	require.Equal(t, "completed", report.Status)
+	require.Equal(t, ReportCommits{Total: 3, Applied: 2, Vetoed: 1}, report.Commits)
+	require.Equal(t, []string{"alice"}, report.Authors.Mapped)
+	require.Equal(t, []string{"bob", "carol"}, report.Authors.Unmapped)
+
+	// The identities used, fallbacks included, are exported as an author map
+	require.Equal(t, AuthorMapPath(target), report.Authors.MapFile)
+	data, err := os.ReadFile(report.Authors.MapFile)
+	require.NoError(t, err)
+	var used map[string]string
+	require.NoError(t, yaml.Unmarshal(data, &used))
+	require.Equal(t, map[string]string{
+		"alice": "Alice <alice@example.com>",
+		"bob":   "bob <bob@users.noreply.cvs.example.org>",
+		"carol": "carol <carol@users.noreply.cvs.example.org>",
+	}, used)
+	require.Equal(t, []string{"bad_name", "dev"}, report.Branches.Created)
+	require.Equal(t, []string{"tmp-x"}, report.Branches.Filtered)
+	require.Equal(t, []string{"REL_1"}, report.Tags.Created)
+	require.Contains(t, report.Tags.Failed, "BROKEN")
+	require.Len(t, report.Warnings, 1)
+	require.Contains(t, report.Warnings[0], "failed to create tag tag=BROKEN")
+	require.Len(t, report.Renames, 1)
+	require.NotEmpty(t, report.Phases)
+	require.NotNil(t, report.Verification)
+	require.True(t, report.Verification.Passed, report.Verification.Problems)
+	require.Equal(t, 2, report.Verification.TargetCommits)
+
+	// The report is written next to the target in all formats
+	base := ReportPath(target)
+	require.Equal(t, filepath.Join(filepath.Dir(target), "repo.migration-report"), base)
+	data, err = os.ReadFile(base + ReportExtJSON)
+	require.NoError(t, err)
+	var decoded MigrationReport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, report.Commits, decoded.Commits)
+
+	markdown, err := os.ReadFile(base + ReportExtMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(markdown), "# Migration Report")
+	require.Contains(t, string(markdown), "- BROKEN: ")
+	require.Contains(t, string(markdown), "| branch | bad name | bad_name |")
+
+	html, err := os.ReadFile(base + ReportExtHTML)
+	require.NoError(t, err)
+	require.Contains(t, string(html), "<h1>Migration Report</h1>")
+}
+
+func TestRun_ReportsCreatedRefs(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithRefs{
+		mockReaderWithCommits: mockReaderWithCommits{commits: []*vcs.Commit{{Revision: "1", Author: "alice", Date: date, Message: "first",
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a")}}}}},
+		branches: []string{"dev"},
+		tags:     map[string]string{"REL_1": "HEAD", "BROKEN": "no-such-revision"},
+	}
+	var refs []progress.RefResult
+	m.ProgressReporter().SubscribeRefs(func(ref progress.RefResult) {
+		refs = append(refs, ref)
+	})
+	require.NoError(t, m.Run())
+
+	require.Len(t, refs, 3)
+	require.Equal(t, progress.RefBranch, refs[0].Kind)
+	require.Equal(t, "dev", refs[0].Name)
+	require.Empty(t, refs[0].Error)
+	require.Equal(t, "BROKEN", refs[1].Name)
+	require.NotEmpty(t, refs[1].Error)
+	require.Equal(t, "REL_1", refs[2].Name)
+	require.Empty(t, refs[2].Error)
+
+	status := m.ProgressReporter().Status()
+	require.Equal(t, 2, status.RefsCreated)
+	require.Equal(t, 1, status.RefsFailed)
+}
+func TestRun_FailedMigrationReport(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType:  "cvs",
+		SourcePath:  "/src",
+		TargetPath:  target,
+		InterruptAt: 1,
+		Logger:      logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: hookTestCommits()}
+	require.Error(t, m.Run())
+
+	require.Equal(t, "failed", m.Report().Status)
+	require.Contains(t, m.Report().Error, "interrupted")
+	_, err := os.Stat(ReportPath(target) + ReportExtJSON)
+	require.NoError(t, err)
+}
+
+func TestRun_DryRunDoesNotWriteReport(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, DryRun: true, Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: hookTestCommits()}
+	require.NoError(t, m.Run())
+
+	require.Equal(t, 3, m.Report().Commits.Total)
+	require.Nil(t, m.Report().Verification)
+	_, err := os.Stat(ReportPath(target) + ReportExtJSON)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestMigrationReport_HTMLEscapes(t *testing.T) {
+	report := newMigrationReport(&MigrationConfig{SourcePath: "<script>"}, "id")
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteHTML(&buf))
+	require.NotContains(t, buf.String(), "<script>")
+	require.Contains(t, buf.String(), "&lt;script&gt;")
+}
+
+func TestRun_ContentCacheReport(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+	cacheDir := t.TempDir()
+	config := func() *MigrationConfig {
+		return &MigrationConfig{SourceType: "cvs", SourcePath: repo, TargetPath: filepath.Join(t.TempDir(), "repo"),
+			ContentCacheDir: cacheDir, Logger: logging.Discard()}
+	}
+
+	// Reconstructing 1.1 from the head also caches 1.2
+	m := NewMigrator(config())
+	require.NoError(t, m.Run())
+	require.Equal(t, &ReportContentCache{Hits: 1, Misses: 1, HitRate: 0.5}, m.Report().ContentCache)
+
+	// A later run reads both revisions from disk
+	m = NewMigrator(config())
+	require.NoError(t, m.Run())
+	require.Equal(t, &ReportContentCache{DiskHits: 2, HitRate: 1}, m.Report().ContentCache)
+
+	var md bytes.Buffer
+	require.NoError(t, m.Report().WriteMarkdown(&md))
+	require.Contains(t, md.String(), "| 0 | 2 | 0 | 0 | 100.0% |")
+
+	noCache := config()
+	noCache.ContentCacheSize = -1
+	m = NewMigrator(noCache)
+	require.NoError(t, m.Run())
+	require.Nil(t, m.Report().ContentCache)
+}
+
+func TestRun_Profile(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+
+	rec := profile.NewRecorder()
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: repo, TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Profile: rec, Logger: logging.Discard()})
+	require.NoError(t, m.Run())
+
+	p := rec.Profile()
+	require.Len(t, p.Entries[profile.KindParse], 1)
+	require.Equal(t, filepath.Join(repo, "f.txt,v"), p.Entries[profile.KindParse][0].Name)
+	require.Len(t, p.Entries[profile.KindChangeset], 2)
+	require.Len(t, p.Entries[profile.KindApply], 2)
+	require.Contains(t, p.Entries[profile.KindApply][0].Name, "alice")
+	require.Contains(t, p.Entries[profile.KindApply][0].Name, "(files: 1)")
+	require.NotEmpty(t, p.Phases)
+}
+
+func TestRun_AuthorMapCoversResumedHistory(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCommits := func() []*vcs.Commit {
+		var commits []*vcs.Commit
+		for i, author := range []string{"alice", "bob", "bob"} {
+			commits = append(commits, &vcs.Commit{
+				Revision: fmt.Sprintf("r%d", i+1), Author: author, Date: date.Add(time.Duration(i) * time.Minute),
+				Message: fmt.Sprintf("m%d", i+1),
+				Files:   []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Content: []byte(fmt.Sprint(i))}},
+			})
+		}
+		return commits
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	stateFile := filepath.Join(t.TempDir(), "state.db")
+	authors := map[string]string{"alice": "Alice <alice@example.com>"}
+	hook := &stopAfterHook{after: 1, stop: make(chan struct{})}
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, StateFile: stateFile,
+		AuthorMap: authors, Stop: hook.stop, Hooks: []CommitHook{hook}, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: newCommits()}
+	require.ErrorIs(t, m.Run(), ErrStopped)
+
+	m = NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, StateFile: stateFile,
+		AuthorMap: authors, Resume: true, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: newCommits()}
+	require.NoError(t, m.Run())
+
+	// The authors of the commits applied before the resume are kept
+	data, err := os.ReadFile(AuthorMapPath(target))
+	require.NoError(t, err)
+	var used map[string]string
+	require.NoError(t, yaml.Unmarshal(data, &used))
+	require.Equal(t, map[string]string{
+		"alice": "Alice <alice@example.com>",
+		"bob":   "bob <bob@users.noreply.cvs.example.org>",
+	}, used)
+	require.Equal(t, []string{"alice"}, m.Report().Authors.Mapped)
+}
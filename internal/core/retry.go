@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// DefaultRetryDelay is the delay before the first retry when
+// MigrationConfig.RetryDelay is not set
+const DefaultRetryDelay = time.Second
+
+// maxRetryDelay caps the exponential backoff between retries
+const maxRetryDelay = time.Minute
+
+// retryableErrnos are the system errors network filesystems report for
+// conditions that usually clear up on their own
+var retryableErrnos = []syscall.Errno{
+	syscall.EIO,
+	syscall.EAGAIN,
+	syscall.EBUSY,
+	syscall.EINTR,
+	syscall.ENOLCK,
+	syscall.ESTALE,
+	syscall.ETIMEDOUT,
+}
+
+// IsRetryable reports whether err is a transient I/O or locking failure
+// that may succeed when the operation is repeated
+func IsRetryable(err error) bool {
+	var partial *partialCommitError
+	if err == nil || errors.As(err, &partial) {
+		return false
+	}
+	// A done context stays done; its DeadlineExceeded is a net.Error too
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	for _, errno := range retryableErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// SQLite reports a lock held by another process by message only
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
+
+// retry runs op until it succeeds, fails with an error that is not
+// retryable or MigrationConfig.Retries additional attempts are used up. The
+// delay between attempts doubles each time.
+func (m *Migrator) retry(what string, op func() error) error {
+	delay := m.config.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= m.config.Retries || !IsRetryable(err) {
+			return err
+		}
+
+		m.Logger().Warn(what+" failed, retrying",
+			"attempt", attempt+1,
+			"delay", delay,
+			"error", err,
+		)
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// applyWithRetry applies a commit to the target, retrying transient
+// failures. A failed attempt is only repeated if it did not create a
+// commit, so a retry can never apply the same change twice.
+func (m *Migrator) applyWithRetry(commit *vcs.Commit) error {
+	tracker, _ := m.target.(vcs.CommitTracker)
+	return m.retry("apply commit "+commit.Revision, func() error {
+		before := ""
+		if tracker != nil {
+			before = tracker.LastCommitHash()
+		}
+		err := m.target.ApplyCommit(commit)
+		if err != nil && tracker != nil && tracker.LastCommitHash() != before {
+			return &partialCommitError{err: err}
+		}
+		return err
+	})
+}
+
+// partialCommitError marks a failure after the target already created the
+// commit, which must not be retried
+type partialCommitError struct{ err error }
+
+func (e *partialCommitError) Error() string {
+	return "commit created before failure: " + e.err.Error()
+}
+
+func (e *partialCommitError) Unwrap() error { return e.err }
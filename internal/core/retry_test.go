@@ -0,0 +1,104 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("invalid revision"), false},
+		{&os.PathError{Op: "write", Path: "a.txt", Err: syscall.EIO}, true},
+		{fmt.Errorf("failed to add file: %w", &os.PathError{Op: "open", Path: "x", Err: syscall.ESTALE}), true},
+		{&os.PathError{Op: "open", Path: "x", Err: syscall.ENOENT}, false},
+		{errors.New("database is locked (5) (SQLITE_BUSY)"), true},
+		{&partialCommitError{err: syscall.EIO}, false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, IsRetryable(tt.err), "%v", tt.err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{Retries: 2, RetryDelay: time.Millisecond, Logger: logging.Discard()})
+
+	calls := 0
+	err := m.retry("write", func() error {
+		calls++
+		if calls < 3 {
+			return syscall.EIO
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	// Retries are used up
+	calls = 0
+	err = m.retry("write", func() error { calls++; return syscall.EIO })
+	require.ErrorIs(t, err, syscall.EIO)
+	require.Equal(t, 3, calls)
+
+	// Fatal errors are not retried
+	calls = 0
+	err = m.retry("write", func() error { calls++; return syscall.ENOSPC })
+	require.ErrorIs(t, err, syscall.ENOSPC)
+	require.Equal(t, 1, calls)
+}
+
+// flakyWriter fails ApplyCommit with err for the first failures calls,
+// optionally after recording the commit
+type flakyWriter struct {
+	vcs.VCSWriter
+	failures int
+	err      error
+	commit   bool // Create the commit before failing
+	hash     string
+	applied  int
+}
+
+func (w *flakyWriter) ApplyCommit(commit *vcs.Commit) error {
+	if w.failures > 0 {
+		w.failures--
+		if w.commit {
+			w.applied++
+			w.hash = fmt.Sprintf("h%d", w.applied)
+		}
+		return w.err
+	}
+	w.applied++
+	w.hash = fmt.Sprintf("h%d", w.applied)
+	return nil
+}
+
+func (w *flakyWriter) HasCommit(hash string) bool { return true }
+func (w *flakyWriter) LastCommitHash() string     { return w.hash }
+
+func TestApplyWithRetry(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{Retries: 3, RetryDelay: time.Millisecond, Logger: logging.Discard()})
+	commit := hookTestCommits()[0]
+
+	w := &flakyWriter{failures: 2, err: &os.PathError{Op: "write", Path: "a.txt", Err: syscall.EIO}}
+	m.target = w
+	require.NoError(t, m.applyWithRetry(commit))
+	require.Equal(t, 1, w.applied)
+
+	// A failure after the commit was created is not retried
+	w = &flakyWriter{failures: 1, commit: true, err: syscall.EIO}
+	m.target = w
+	err := m.applyWithRetry(commit)
+	require.ErrorIs(t, err, syscall.EIO)
+	require.Equal(t, 1, w.applied)
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of a RevisionRule
+const (
+	// RevisionSkip drops the file revision; the file keeps its previous
+	// content until its next revision
+	RevisionSkip = "skip"
+	// RevisionReplace writes the content of a file instead of the revision
+	RevisionReplace = "replace"
+	// RevisionPatch applies a unified diff to the content of the revision
+	RevisionPatch = "patch"
+)
+
+// RevisionRule drops or replaces the source file revisions it matches, e.g.
+// a corrupt CVS revision. Path is matched like a KeywordRule pattern; the
+// other conditions are optional.
+type RevisionRule struct {
+	Path     string `yaml:"path"`
+	Revision string `yaml:"revision,omitempty"` // File revision, e.g. "1.4"
+	Author   string `yaml:"author,omitempty"`   // Source login of the commit
+	Message  string `yaml:"message,omitempty"`  // Regular expression matching the commit message
+	Action   string `yaml:"action"`             // RevisionSkip, RevisionReplace or RevisionPatch
+	File     string `yaml:"file,omitempty"`     // Replacement content of RevisionReplace
+	Patch    string `yaml:"patch,omitempty"`    // Unified diff of RevisionPatch
+	Reason   string `yaml:"reason,omitempty"`   // Shown in the report
+
+	content []byte         // Read from File or Patch
+	message *regexp.Regexp // Compiled Message
+	matched int
+}
+
+// revisionRulesFile is the file format of LoadRevisionRules
+type revisionRulesFile struct {
+	Rules []RevisionRule `yaml:"rules"`
+}
+
+// LoadRevisionRules reads and checks a rules file. The files and patches
+// of the rules are read relative to the rules file.
+func LoadRevisionRules(rulesPath string) ([]RevisionRule, error) {
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision rules: %w", err)
+	}
+	var file revisionRulesFile
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse revision rules %s: %w", rulesPath, err)
+	}
+
+	dir := filepath.Dir(rulesPath)
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if err := rule.load(dir); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", rulesPath, i+1, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// load checks the rule and reads its content relative to dir
+func (r *RevisionRule) load(dir string) error {
+	if _, err := path.Match(r.Path, ""); err != nil || r.Path == "" {
+		return fmt.Errorf("invalid path pattern %q", r.Path)
+	}
+	if r.Message != "" {
+		message, err := regexp.Compile(r.Message)
+		if err != nil {
+			return fmt.Errorf("invalid message pattern: %w", err)
+		}
+		r.message = message
+	}
+
+	var source string
+	switch r.Action {
+	case RevisionSkip:
+		if r.File != "" || r.Patch != "" {
+			return fmt.Errorf("%s takes no file or patch", r.Action)
+		}
+		return nil
+	case RevisionReplace:
+		if r.File == "" || r.Patch != "" {
+			return fmt.Errorf("%s requires a file and no patch", r.Action)
+		}
+		source = r.File
+	case RevisionPatch:
+		if r.Patch == "" || r.File != "" {
+			return fmt.Errorf("%s requires a patch and no file", r.Action)
+		}
+		source = r.Patch
+	default:
+		return fmt.Errorf("unsupported action %q: must be %s, %s or %s", r.Action, RevisionSkip, RevisionReplace, RevisionPatch)
+	}
+
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(dir, source)
+	}
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	if r.Action == RevisionPatch {
+		if _, err := parsePatch(content); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	r.content = content
+	return nil
+}
+
+// matches reports whether the rule applies to a file change of commit
+func (r *RevisionRule) matches(commit *vcs.Commit, fc *vcs.FileChange) bool {
+	if r.Action != RevisionSkip && fc.Action == vcs.ActionDelete {
+		return false
+	}
+	return pathMatches(r.Path, fc.Path) &&
+		(r.Revision == "" || r.Revision == fc.Revision) &&
+		(r.Author == "" || r.Author == commit.Author) &&
+		(r.message == nil || r.message.MatchString(commit.Message))
+}
+
+// loadRevisionRules reads the rules file of the configuration
+func (m *Migrator) loadRevisionRules() error {
+	m.revisionRules = nil
+	if m.config.RevisionRules == "" {
+		return nil
+	}
+	rules, err := LoadRevisionRules(m.config.RevisionRules)
+	if err != nil {
+		return err
+	}
+	m.revisionRules = rules
+	return nil
+}
+
+// applyRevisionRules drops and replaces the file revisions matched by the
+// revision rules, recording each override in the report. Commits left
+// without file changes are dropped.
+func (m *Migrator) applyRevisionRules(commits []*vcs.Commit) ([]*vcs.Commit, error) {
+	if len(m.revisionRules) == 0 {
+		return commits, nil
+	}
+
+	kept := commits[:0]
+	for _, commit := range commits {
+		files := commit.Files[:0]
+		for _, fc := range commit.Files {
+			rule := m.revisionRule(commit, &fc)
+			if rule == nil {
+				files = append(files, fc)
+				continue
+			}
+			if err := applyRevisionRule(rule, &fc); err != nil {
+				return nil, fmt.Errorf("revision rule for %s %s: %w", fc.Path, fc.Revision, err)
+			}
+			m.Logger().Warn("source revision overridden by rule",
+				"path", fc.Path, "revision", fc.Revision, "commit", commit.Revision, "action", rule.Action, "reason", rule.Reason)
+			m.report.Overrides = append(m.report.Overrides, ReportOverride{
+				Path:     fc.Path,
+				Revision: fc.Revision,
+				Commit:   commit.Revision,
+				Action:   rule.Action,
+				Reason:   rule.Reason,
+			})
+			if rule.Action != RevisionSkip {
+				files = append(files, fc)
+			}
+		}
+		commit.Files = files
+		if len(files) == 0 {
+			m.Logger().Warn("commit dropped by revision rules", "revision", commit.Revision)
+			m.report.Commits.Dropped++
+			continue
+		}
+		kept = append(kept, commit)
+	}
+
+	for _, rule := range m.revisionRules {
+		if rule.matched == 0 {
+			m.warn("revision rule matched no source revision", "path", rule.Path, "revision", rule.Revision, "action", rule.Action)
+		}
+	}
+	return kept, nil
+}
+
+// revisionRule returns the first rule matching a file change, if any
+func (m *Migrator) revisionRule(commit *vcs.Commit, fc *vcs.FileChange) *RevisionRule {
+	for i := range m.revisionRules {
+		rule := &m.revisionRules[i]
+		if rule.matches(commit, fc) {
+			rule.matched++
+			return rule
+		}
+	}
+	return nil
+}
+
+// applyRevisionRule replaces the content of a file change by the rule
+func applyRevisionRule(rule *RevisionRule, fc *vcs.FileChange) error {
+	switch rule.Action {
+	case RevisionReplace:
+		fc.Content, fc.Source = rule.content, nil
+	case RevisionPatch:
+		content, err := fc.ReadContent()
+		if err != nil {
+			return err
+		}
+		patched, err := applyPatch(content, rule.content)
+		if err != nil {
+			return err
+		}
+		fc.Content, fc.Source = patched, nil
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRevisionRules(t *testing.T, rules string, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	path := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(rules), 0644))
+	return path
+}
+
+func TestLoadRevisionRules_Invalid(t *testing.T) {
+	for _, rules := range []string{
+		"rules:\n  - path: a.c\n    action: drop\n",
+		"rules:\n  - path: a.c\n    action: replace\n",
+		"rules:\n  - path: a.c\n    action: replace\n    file: missing.c\n",
+		"rules:\n  - path: a.c\n    action: skip\n    file: fix.c\n",
+		"rules:\n  - path: a.c\n    action: patch\n    patch: fix.c\n",
+		"rules:\n  - path: \"[\"\n    action: skip\n",
+		"rules:\n  - path: a.c\n    message: \"(\"\n    action: skip\n",
+		"rules:\n  - path: a.c\n    action: skip\n    unknown: 1\n",
+	} {
+		_, err := LoadRevisionRules(writeRevisionRules(t, rules, map[string]string{"fix.c": "fixed\n"}))
+		require.Error(t, err, rules)
+	}
+}
+
+func TestRun_RevisionRules(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "1", Author: "alice", Date: date, Message: "first", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("int main;\n")},
+			{Path: "data/big.dat", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("garbage")},
+			{Path: "notes.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("one\ntwo\n")},
+		}},
+		{Revision: "2", Author: "alice", Date: date.Add(time.Hour), Message: "corrupt", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionModify, Revision: "1.2", Content: []byte("\x00\x00")},
+		}},
+		{Revision: "3", Author: "bob", Date: date.Add(2 * time.Hour), Message: "third", Files: []vcs.FileChange{
+			{Path: "notes.txt", Action: vcs.ActionModify, Revision: "1.2", Content: []byte("one\ntwo\nthree\n")},
+		}},
+	}
+	rules := writeRevisionRules(t, `rules:
+  - path: src/main.c
+    revision: "1.2"
+    action: skip
+    reason: corrupt delta
+  - path: "*.dat"
+    action: replace
+    file: empty.dat
+  - path: notes.txt
+    author: bob
+    action: patch
+    patch: notes.diff
+    reason: typo
+  - path: never.c
+    action: skip
+`, map[string]string{
+		"empty.dat":  "",
+		"notes.diff": "--- notes.txt\n+++ notes.txt\n@@ -2,2 +2,2 @@\n two\n-three\n+3\n",
+	})
+
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType:    "cvs",
+		SourcePath:    "/src",
+		TargetPath:    target,
+		RevisionRules: rules,
+		Logger:        logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	report := m.Report()
+	require.Equal(t, ReportCommits{Total: 2, Applied: 2, Dropped: 1}, report.Commits)
+	require.Equal(t, []ReportOverride{
+		{Path: "data/big.dat", Revision: "1.1", Commit: "1", Action: RevisionReplace},
+		{Path: "src/main.c", Revision: "1.2", Commit: "2", Action: RevisionSkip, Reason: "corrupt delta"},
+		{Path: "notes.txt", Revision: "1.2", Commit: "3", Action: RevisionPatch, Reason: "typo"},
+	}, report.Overrides)
+	require.Len(t, report.Warnings, 1)
+	require.Contains(t, report.Warnings[0], "revision rule matched no source revision")
+	require.True(t, report.Verification.Passed, report.Verification.Problems)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	tip, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := tip.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "int main;\n", readTreeFile(t, tree, "src/main.c"))
+	require.Equal(t, "", readTreeFile(t, tree, "data/big.dat"))
+	require.Equal(t, "one\ntwo\n3\n", readTreeFile(t, tree, "notes.txt"))
+
+	markdown, err := os.ReadFile(ReportPath(target) + ReportExtMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(markdown), "## Source overrides")
+	require.Contains(t, string(markdown), "| src/main.c | 1.2 | 2 | skip | corrupt delta |")
+}
@@ -2,10 +2,21 @@ package core
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/storage"
 	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
 )
 
@@ -293,3 +304,638 @@ func TestRun_InitSourceError(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to init source")
 }
+
+func TestRun_WritesPerMigrationLog(t *testing.T) {
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a1", Date: time.Now(), Message: "m1"},
+	}
+	logDir := t.TempDir()
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: "/t",
+		DryRun:     true,
+		Logger:     logging.Discard(),
+		LogDir:     logDir,
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	id := m.MigrationID()
+	data, err := os.ReadFile(filepath.Join(logDir, id+".log"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"migration_id":"`+id+`"`)
+	require.Contains(t, string(data), "starting migration")
+	require.Contains(t, string(data), "processing commit")
+}
+
+func TestRun_RecordsPhaseTimings(t *testing.T) {
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a1", Email: "a1@example.com", Date: time.Now(), Message: "m1",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}},
+		{Revision: "r2", Author: "a2", Email: "a2@example.com", Date: time.Now(), Message: "m2"},
+	}
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: filepath.Join(t.TempDir(), "repo")}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+
+	reporter := m.ProgressReporter()
+	require.NoError(t, m.Run())
+	require.Same(t, reporter, m.ProgressReporter(), "subscribers must survive the run")
+
+	status := reporter.Status()
+	require.Equal(t, 2, status.Current)
+	require.Equal(t, 2, status.Total)
+	require.Empty(t, status.Phase)
+	require.Greater(t, status.Rate, 0.0)
+
+	var names []progress.Phase
+	for _, p := range status.Phases {
+		require.False(t, p.End.IsZero(), "phase %s should be finished", p.Phase)
+		names = append(names, p.Phase)
+	}
+	require.Equal(t, []progress.Phase{
+		progress.PhaseReadSource,
+		progress.PhaseApplyCommits,
+		progress.PhaseBranches,
+		progress.PhaseTags,
+	}, names)
+}
+
+func TestRun_RerunDoesNotDuplicateCommits(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCommits := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "1.1", Author: "a1", Date: date, Message: "m1",
+				Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("x")}}},
+			{Revision: "1.2", Author: "a1", Date: date.Add(time.Hour), Message: "m2",
+				Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Revision: "1.2", Content: []byte("y")}}},
+		}
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+
+	run := func() []string {
+		cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Logger: logging.Discard()}
+		m := NewMigrator(cfg)
+		m.source = &mockReaderWithCommits{commits: newCommits()}
+		require.NoError(t, m.Run())
+
+		w := git.NewWriter()
+		require.NoError(t, w.Open(target))
+		hashes, err := w.GetCommitHashes()
+		require.NoError(t, err)
+		return hashes
+	}
+
+	first := run()
+	require.Len(t, first, 2)
+
+	// Without Resume the lastCommit checkpoint is ignored; the revision
+	// mapping alone must prevent duplicates
+	second := run()
+	require.Equal(t, first, second)
+
+	// File revisions are mapped to the commit that introduced them
+	db, err := storage.NewStateDB(filepath.Join(target, ".migration-state.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	hash, err := db.LookupGitHash("f.txt:1.2")
+	require.NoError(t, err)
+	require.Equal(t, first[1], hash)
+}
+
+func TestRun_UnknownTargetType(t *testing.T) {
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetType: "no-such-target",
+		TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{}
+
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported target type")
+}
+
+func TestRun_CVSTargetRequiresOptions(t *testing.T) {
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetType: "cvs",
+		TargetPath: filepath.Join(t.TempDir(), "work"),
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{}
+
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cvsroot")
+}
+
+func TestRun_PushesToRemote(t *testing.T) {
+	remotePath := filepath.Join(t.TempDir(), "remote.git")
+	remote, err := gogit.PlainInit(remotePath, true)
+	require.NoError(t, err)
+
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "a1", Date: time.Now(), Message: "m1",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}},
+	}
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Logger:     logging.Discard(),
+		Push:       &git.PushOptions{URL: remotePath},
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	refs, err := remote.References()
+	require.NoError(t, err)
+	count := 0
+	require.NoError(t, refs.ForEach(func(*plumbing.Reference) error { count++; return nil }))
+	require.Positive(t, count, "remote should have received refs")
+
+	var phases []progress.Phase
+	for _, p := range m.ProgressReporter().Phases() {
+		phases = append(phases, p.Phase)
+	}
+	require.Contains(t, phases, progress.PhasePush)
+}
+
+func TestRun_FixedCommitterPreservesAuthor(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "alice", Date: date, Message: "m1",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: target,
+		AuthorMap:  map[string]string{"alice": "Alice Smith <alice@example.com>"},
+		Committer:  "Migration Bot <bot@example.com>",
+		Logger:     logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	c, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "Alice Smith", c.Author.Name)
+	require.Equal(t, "alice@example.com", c.Author.Email)
+	require.Equal(t, "Migration Bot", c.Committer.Name)
+	require.Equal(t, "bot@example.com", c.Committer.Email)
+	require.True(t, date.Equal(c.Committer.When))
+}
+
+func TestRun_InvalidCommitter(t *testing.T) {
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: "/t", DryRun: true, Committer: "bot"}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{}
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid committer")
+}
+
+func TestRun_DefaultBranch(t *testing.T) {
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "a1", Date: time.Now(), Message: "m1",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, DefaultBranch: "main", Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("main"), head.Name())
+	_, err = repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.Error(t, err)
+}
+
+type mockReaderWithRefs struct {
+	mockReaderWithCommits
+	branches []string
+	tags     map[string]string
+}
+
+func (m *mockReaderWithRefs) GetBranches() ([]string, error)      { return m.branches, nil }
+func (m *mockReaderWithRefs) GetTags() (map[string]string, error) { return m.tags, nil }
+
+func TestRun_FiltersBranchesAndTags(t *testing.T) {
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "a1", Date: time.Now(), Message: "m1",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType:      "cvs",
+		SourcePath:      "/src",
+		TargetPath:      target,
+		ExcludeBranches: []string{"tmp-*"},
+		IncludeTags:     []string{"RELEASE_*"},
+		ExcludeTags:     []string{"*_RC*"},
+		Logger:          logging.Discard(),
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithRefs{
+		mockReaderWithCommits: mockReaderWithCommits{commits: commits},
+		branches:              []string{"dev", "tmp-experiment"},
+		tags: map[string]string{
+			"RELEASE_1_0":     "HEAD",
+			"RELEASE_2_0_RC1": "HEAD",
+			"NIGHTLY_0101":    "HEAD",
+		},
+	}
+	require.NoError(t, m.Run())
+
+	w := git.NewWriter()
+	require.NoError(t, w.Open(target))
+	branches, err := w.ListBranches()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"master", "dev"}, branches)
+	tags, err := w.ListTags()
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	require.Contains(t, tags, "RELEASE_1_0")
+}
+
+func TestRun_InvalidFilterPattern(t *testing.T) {
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: "/t", DryRun: true, ExcludeTags: []string{"[a-"}}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{}
+	err := m.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid tag filter")
+}
+
+// taggedRCS has two trunk revisions with REL_1 on the first one
+const taggedRCS = "head 1.2;\naccess;\nsymbols\n\tREL_1:1.1;\nlocks;\n\n" +
+	"1.2\ndate 2024.01.02.00.00.00; author bob; state Exp;\nbranches;\nnext 1.1;\n\n" +
+	"1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\n" +
+	"desc\n@@\n\n" +
+	"1.2\nlog\n@second\n@\ntext\n@two\n@\n\n" +
+	"1.1\nlog\n@first\n@\ntext\n@d1 1\na1 1\none\n@\n"
+
+func TestRun_AnnotatedTagsFromCVS(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType:    "cvs",
+		SourcePath:    repo,
+		TargetPath:    target,
+		AuthorMap:     map[string]string{"alice": "Alice Smith <alice@example.com>"},
+		TagMap:        map[string]string{"REL_1": "v1.0"},
+		AnnotatedTags: true,
+		TagMessage:    "Release {tag} (CVS {symbol}, {date})",
+		Logger:        logging.Discard(),
+	}
+	require.NoError(t, NewMigrator(cfg).Run())
+
+	r, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	ref, err := r.Tag("v1.0")
+	require.NoError(t, err)
+	tag, err := r.TagObject(ref.Hash())
+	require.NoError(t, err)
+
+	require.Equal(t, "Release v1.0 (CVS REL_1, 2024-01-01T00:00:00Z)\n", tag.Message)
+	require.Equal(t, "Alice Smith", tag.Tagger.Name)
+	require.True(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Equal(tag.Tagger.When))
+
+	// The tag points at the commit of the tagged revision, not HEAD
+	tagged, err := tag.Commit()
+	require.NoError(t, err)
+	require.Equal(t, "first", strings.TrimSpace(tagged.Message))
+}
+
+// simulateCrash leaves the target and state as a run killed while applying
+// commit would: the commit is pending and, if written, exists in the target
+// without a checkpoint
+func simulateCrash(t *testing.T, target, stateFile string, commit *vcs.Commit, written bool) {
+	t.Helper()
+	w := git.NewWriter()
+	require.NoError(t, w.Open(target))
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target})
+	db, err := storage.NewStateDB(stateFile)
+	require.NoError(t, err)
+	require.NoError(t, db.BeginCommit(&storage.PendingCommit{
+		MigrationID: m.MigrationID(),
+		ParentHash:  w.HeadHash(),
+		Revisions:   []string{sourceRevisionKey(commit)},
+		LastCommit:  commit.Revision,
+		Processed:   2,
+		Total:       3,
+	}))
+	require.NoError(t, db.Close())
+	if written {
+		c := *commit
+		require.NoError(t, w.ApplyCommit(&c))
+	}
+	require.NoError(t, w.Close())
+}
+
+func TestRun_RecoversInterruptedCommit(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	newCommits := func() []*vcs.Commit {
+		var commits []*vcs.Commit
+		for i := 1; i <= 3; i++ {
+			commits = append(commits, &vcs.Commit{
+				Revision: fmt.Sprintf("r%d", i), Author: "a", Email: "a@example.com",
+				Date: date.Add(time.Duration(i) * time.Minute), Message: fmt.Sprintf("m%d", i),
+				Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Content: []byte(fmt.Sprint(i))}},
+			})
+		}
+		return commits
+	}
+
+	for _, written := range []bool{true, false} {
+		t.Run(fmt.Sprintf("written=%v", written), func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "repo")
+			stateFile := filepath.Join(t.TempDir(), "state.db")
+			cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, StateFile: stateFile, InterruptAt: 1, Logger: logging.Discard()}
+			m := NewMigrator(cfg)
+			m.source = &mockReaderWithCommits{commits: newCommits()}
+			require.ErrorContains(t, m.Run(), "interrupted at commit 1")
+
+			simulateCrash(t, target, stateFile, newCommits()[1], written)
+
+			cfg = &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, StateFile: stateFile, Resume: true, Logger: logging.Discard()}
+			m = NewMigrator(cfg)
+			m.source = &mockReaderWithCommits{commits: newCommits()}
+			require.NoError(t, m.Run())
+
+			repo, err := gogit.PlainOpen(target)
+			require.NoError(t, err)
+			iter, err := repo.Log(&gogit.LogOptions{})
+			require.NoError(t, err)
+			var messages []string
+			require.NoError(t, iter.ForEach(func(c *object.Commit) error {
+				messages = append(messages, c.Message)
+				return nil
+			}))
+			require.Equal(t, []string{"m3", "m2", "m1"}, messages, "every commit is applied exactly once")
+
+			db, err := storage.NewStateDB(stateFile)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, db.Close()) }()
+			_, err = db.PendingCommit(m.MigrationID())
+			require.ErrorIs(t, err, storage.ErrNoPendingCommit)
+		})
+	}
+}
+
+// repackWriter records the repacks requested from a Git writer
+type repackWriter struct {
+	*git.Writer
+	repacks []bool
+}
+
+func (w *repackWriter) Repack(full bool) error {
+	w.repacks = append(w.repacks, full)
+	return w.Writer.Repack(full)
+}
+
+var lastRepackWriter *repackWriter
+
+func init() {
+	vcs.RegisterWriter("repack-test", func(map[string]string) (vcs.VCSWriter, error) {
+		lastRepackWriter = &repackWriter{Writer: git.NewWriter()}
+		return lastRepackWriter, nil
+	})
+}
+
+func TestRun_RepackEvery(t *testing.T) {
+	var commits []*vcs.Commit
+	for i := 1; i <= 5; i++ {
+		commits = append(commits, &vcs.Commit{
+			Revision: fmt.Sprintf("r%d", i), Author: "a", Email: "a@example.com", Date: time.Now(), Message: "m",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Content: []byte(fmt.Sprint(i))}},
+		})
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs", SourcePath: "/src", TargetType: "repack-test", TargetPath: target,
+		RepackEvery: 2, Logger: logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	require.Equal(t, []bool{false, false, true}, lastRepackWriter.repacks, "every 2 commits, then a full repack")
+	packs, err := filepath.Glob(filepath.Join(target, ".git", "objects", "pack", "*.pack"))
+	require.NoError(t, err)
+	require.NotEmpty(t, packs)
+
+	var phases []progress.Phase
+	for _, p := range m.ProgressReporter().Status().Phases {
+		phases = append(phases, p.Phase)
+	}
+	require.Contains(t, phases, progress.PhaseRepack)
+}
+
+// stopAfterHook closes stop once the given number of commits was applied
+type stopAfterHook struct {
+	after, applied int
+	stop           chan struct{}
+}
+
+func (h *stopAfterHook) BeforeCommit(*vcs.Commit) error { return nil }
+
+func (h *stopAfterHook) AfterCommit(*vcs.Commit, string) error {
+	h.applied++
+	if h.applied == h.after {
+		close(h.stop)
+	}
+	return nil
+}
+
+func TestRun_StopAndResume(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCommits := func() []*vcs.Commit {
+		var commits []*vcs.Commit
+		for i := 1; i <= 3; i++ {
+			commits = append(commits, &vcs.Commit{
+				Revision: fmt.Sprintf("r%d", i), Author: "a", Email: "a@example.com",
+				Date: date.Add(time.Duration(i) * time.Minute), Message: fmt.Sprintf("m%d", i),
+				Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Content: []byte(fmt.Sprint(i))}},
+			})
+		}
+		return commits
+	}
+
+	target := filepath.Join(t.TempDir(), "repo")
+	stateFile := filepath.Join(t.TempDir(), "state.db")
+	hook := &stopAfterHook{after: 1, stop: make(chan struct{})}
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, StateFile: stateFile,
+		Stop: hook.stop, Hooks: []CommitHook{hook}, Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: newCommits()}
+	require.ErrorIs(t, m.Run(), ErrStopped)
+	require.Equal(t, 1, m.ProgressReporter().Current())
+
+	cfg = &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, StateFile: stateFile, Resume: true, Logger: logging.Discard()}
+	m = NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: newCommits()}
+	require.NoError(t, m.Run())
+
+	w := git.NewWriter()
+	require.NoError(t, w.Open(target))
+	defer func() { _ = w.Close() }()
+	count, err := w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 3, count, "the resumed run applies the remaining commits")
+}
+
+func TestRun_MergeCommitParents(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "a", Date: date, Message: "m1",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("x")}}},
+		{Revision: "1.2", Author: "a", Date: date.Add(time.Hour), Message: "m2",
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Revision: "1.2", Content: []byte("y")}}},
+		{Revision: "1.1", Author: "a", Date: date.Add(2 * time.Hour), Message: "merge", Parents: []string{"f.txt:1.1"},
+			Files: []vcs.FileChange{{Path: "g.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("z")}}},
+	}
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	merge, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	require.Len(t, merge.ParentHashes, 2)
+
+	// The file revision resolves through the revision mapping
+	first, err := merge.Parent(0)
+	require.NoError(t, err)
+	first, err = first.Parent(0)
+	require.NoError(t, err)
+	require.Equal(t, first.Hash, merge.ParentHashes[1])
+}
+
+func TestRun_CommitModeObjects(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target,
+		TargetOpts: map[string]string{"commitMode": git.CommitModeObjects}, Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: eolTestCommits()}
+	require.NoError(t, m.Run())
+
+	require.NoFileExists(t, filepath.Join(target, "main.c"), "the working tree is not written")
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "int y;\r\n", readTreeFile(t, tree, "main.c"))
+}
+
+func TestRun_StrictParsing(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	malformed := strings.Replace(taggedRCS, "head 1.2;", "head 1.2", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(malformed), 0644))
+	config := func(strict bool) *MigrationConfig {
+		return &MigrationConfig{SourceType: "cvs", SourcePath: repo, TargetPath: filepath.Join(t.TempDir(), "repo"),
+			StrictParsing: strict, Logger: logging.Discard()}
+	}
+
+	// Lenient parsing migrates the file and flags it
+	m := NewMigrator(config(false))
+	require.NoError(t, m.Run())
+	require.Equal(t, 2, m.Report().Commits.Applied)
+	require.Len(t, m.Report().Warnings, 1)
+	require.Contains(t, m.Report().Warnings[0], "malformed RCS file needs attention")
+	require.Contains(t, m.Report().Warnings[0], "f.txt,v:2:1: error: expected ';' after head")
+
+	err := NewMigrator(config(true)).Run()
+	var parseErr *cvs.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, 2, parseErr.Line)
+}
+
+func TestRun_ImportHistory(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "src", "f.txt,v"), []byte(taggedRCS), 0644))
+	history := "O65920080|alice|~/work/*0|src||src\n" +
+		"A65920100|alice|~/work/src|src|1.1|f.txt\n" +
+		"M65920200|bob|~/work/src|src|1.2|f.txt\n" +
+		"M65920300|bob|~/work/src|src|1.9|gone.txt\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "CVSROOT", "history"), []byte(history), 0644))
+
+	target := filepath.Join(t.TempDir(), "repo")
+	stateFile := filepath.Join(t.TempDir(), "state.db")
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: repo, TargetPath: target,
+		StateFile: stateFile, ImportHistory: true, Logger: logging.Discard()})
+	require.NoError(t, m.Run())
+
+	h := m.Report().History
+	require.NotNil(t, h)
+	require.Len(t, h.Events, 4)
+	require.Equal(t, 1, h.Unmatched)
+	require.Equal(t, "checkout", h.Events[0].Event)
+	require.Equal(t, "src", h.Events[0].Path)
+	require.Empty(t, h.Events[0].Commit)
+
+	db, err := storage.NewStateDB(stateFile)
+	require.NoError(t, err)
+	defer db.Close()
+	hash, err := db.LookupGitHash("src/f.txt:1.2")
+	require.NoError(t, err)
+	require.Equal(t, "src/f.txt", h.Events[2].Path)
+	require.Equal(t, hash, h.Events[2].Commit)
+	require.NotEmpty(t, h.Events[1].Commit)
+	require.Empty(t, h.Events[3].Commit)
+
+	data, err := os.ReadFile(ReportPath(target) + ReportExtMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "## CVS history")
+	require.Contains(t, string(data), "| commit | bob | src/f.txt | 1.2 | "+hash+" |")
+}
+
+func TestRun_SkipsUnreadableFiles(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "bad.txt,v"), []byte("\x00\x01 not an RCS file"), 0644))
+
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: repo, TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Logger: logging.Discard()})
+	require.NoError(t, m.Run())
+	require.Equal(t, 2, m.Report().Commits.Applied)
+	require.Len(t, m.Report().SkippedFiles, 1)
+	require.Equal(t, filepath.Join(repo, "bad.txt,v"), m.Report().SkippedFiles[0].Path)
+	require.Len(t, m.Report().Warnings, 1)
+	require.Contains(t, m.Report().Warnings[0], "skipped unreadable RCS file")
+}
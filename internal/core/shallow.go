@@ -0,0 +1,100 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// shallowRevision identifies the initial commit created by a history limit
+const shallowRevision = "shallow-root"
+
+// shallowMessage is the message of the initial commit created by a history
+// limit
+const shallowMessage = "Initial state of the files before the migrated history\n\nEarlier revisions remain in the source repository.\n"
+
+// validateHistoryLimits checks HistoryDepth
+func (m *Migrator) validateHistoryLimits() error {
+	if m.config.HistoryDepth < 0 {
+		return fmt.Errorf("invalid history depth %d: must not be negative", m.config.HistoryDepth)
+	}
+	return nil
+}
+
+// limitHistory applies HistorySince and HistoryDepth to the ordered
+// commits. File changes outside the limits are folded into one initial
+// commit holding the state of every file before the migrated history;
+// commits left without changes are dropped. It returns the commits to
+// migrate and the number of folded file changes.
+func (m *Migrator) limitHistory(commits []*vcs.Commit) ([]*vcs.Commit, int) {
+	since, depth := m.config.HistorySince, m.config.HistoryDepth
+	if since.IsZero() && depth <= 0 {
+		return commits, 0
+	}
+
+	// Mark the changes to migrate, counting the later changes of every file
+	later := make(map[string]int)
+	keep := make([][]bool, len(commits))
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		keep[i] = make([]bool, len(c.Files))
+		for j, fc := range c.Files {
+			keep[i][j] = (since.IsZero() || !c.Date.Before(since)) && (depth <= 0 || later[fc.Path] < depth)
+			later[fc.Path]++
+		}
+	}
+
+	state := make(map[string]vcs.FileChange) // Newest folded change of every file
+	var last *vcs.Commit                     // Newest commit with folded changes
+	folded := 0
+	kept := make([]*vcs.Commit, 0, len(commits)+1)
+	for i, c := range commits {
+		var files []vcs.FileChange
+		for j, fc := range c.Files {
+			if keep[i][j] {
+				files = append(files, fc)
+				continue
+			}
+			state[fc.Path] = fc
+			if fc.Action == vcs.ActionRename {
+				state[fc.OldPath] = vcs.FileChange{Path: fc.OldPath, Action: vcs.ActionDelete}
+			}
+			last = c
+			folded++
+		}
+		if len(files) == 0 && (len(c.Files) > 0 || c.Date.Before(since)) {
+			continue
+		}
+		c.Files = files
+		kept = append(kept, c)
+	}
+	if last == nil {
+		return commits, 0
+	}
+
+	// Files deleted before the cut are not part of the initial state
+	root := &vcs.Commit{
+		Revision: shallowRevision,
+		Author:   last.Author,
+		Email:    last.Email,
+		Date:     last.Date,
+		Message:  shallowMessage,
+	}
+	for _, fc := range state {
+		if fc.Action != vcs.ActionDelete {
+			fc.Action, fc.OldPath = vcs.ActionAdd, ""
+			root.Files = append(root.Files, fc)
+		}
+	}
+	sort.Slice(root.Files, func(i, j int) bool { return root.Files[i].Path < root.Files[j].Path })
+
+	m.Logger().Info("limited migrated history",
+		"since", since,
+		"depth", depth,
+		"folded_changes", folded,
+		"dropped_commits", len(commits)-len(kept),
+		"initial_files", len(root.Files),
+	)
+	return append([]*vcs.Commit{root}, kept...), folded
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// shallowTestCommits returns three revisions of f.txt and a g.txt that is
+// added with the first and deleted with the second
+func shallowTestCommits() []*vcs.Commit {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	change := func(path, rev string, action vcs.Action) vcs.FileChange {
+		return vcs.FileChange{Path: path, Action: action, Revision: rev, Content: []byte(path + " " + rev + "\n")}
+	}
+	return []*vcs.Commit{
+		{Revision: "1.1", Author: "alice", Date: date, Message: "first", Files: []vcs.FileChange{
+			change("f.txt", "1.1", vcs.ActionAdd), change("g.txt", "1.1", vcs.ActionAdd), change("h.txt", "1.1", vcs.ActionAdd),
+		}},
+		{Revision: "1.2", Author: "bob", Date: date.Add(time.Hour), Message: "second", Files: []vcs.FileChange{
+			change("f.txt", "1.2", vcs.ActionModify), change("g.txt", "1.2", vcs.ActionDelete),
+		}},
+		{Revision: "1.3", Author: "alice", Date: date.Add(2 * time.Hour), Message: "third", Files: []vcs.FileChange{
+			change("f.txt", "1.3", vcs.ActionModify),
+		}},
+	}
+}
+
+func TestLimitHistory_Depth(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{HistoryDepth: 1, Logger: logging.Discard()})
+	commits, folded := m.limitHistory(shallowTestCommits())
+
+	// Only the last change of every file is kept; h.txt has just one
+	require.Equal(t, 3, folded)
+	require.Equal(t, []string{shallowRevision, "1.1", "1.2", "1.3"}, commitRevisions(commits))
+	root := commits[0]
+	require.Equal(t, "bob", root.Author, "the initial commit takes the newest folded commit's author")
+	require.True(t, root.Date.Equal(commits[2].Date))
+	require.Equal(t, []vcs.FileChange{
+		{Path: "f.txt", Action: vcs.ActionAdd, Revision: "1.2", Content: []byte("f.txt 1.2\n")},
+		{Path: "g.txt", Action: vcs.ActionAdd, Revision: "1.1", Content: []byte("g.txt 1.1\n")},
+	}, root.Files)
+	require.Equal(t, "h.txt", commits[1].Files[0].Path)
+	require.Equal(t, vcs.ActionDelete, commits[2].Files[0].Action)
+}
+
+func TestLimitHistory_Since(t *testing.T) {
+	commits := shallowTestCommits()
+	m := NewMigrator(&MigrationConfig{HistorySince: commits[2].Date, Logger: logging.Discard()})
+	commits, folded := m.limitHistory(commits)
+
+	// g.txt was deleted before the cut and is not part of the initial state
+	require.Equal(t, 5, folded)
+	require.Equal(t, []string{shallowRevision, "1.3"}, commitRevisions(commits))
+	require.Equal(t, "bob", commits[0].Author)
+	var paths []string
+	for _, fc := range commits[0].Files {
+		paths = append(paths, fc.Path+"@"+fc.Revision)
+	}
+	require.Equal(t, []string{"f.txt@1.2", "h.txt@1.1"}, paths)
+}
+
+func TestLimitHistory_NothingFolded(t *testing.T) {
+	commits := shallowTestCommits()
+	m := NewMigrator(&MigrationConfig{HistoryDepth: 3, HistorySince: commits[0].Date})
+	limited, folded := m.limitHistory(commits)
+	require.Zero(t, folded)
+	require.Equal(t, commits, limited)
+
+	require.Error(t, NewMigrator(&MigrationConfig{HistoryDepth: -1}).validateHistoryLimits())
+}
+
+func commitRevisions(commits []*vcs.Commit) []string {
+	var revs []string
+	for _, c := range commits {
+		revs = append(revs, c.Revision)
+	}
+	return revs
+}
+
+func TestRun_HistoryDepth(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target, HistoryDepth: 1, Logger: logging.Discard()}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: shallowTestCommits()}
+	require.NoError(t, m.Run())
+
+	report := m.Report()
+	require.Equal(t, 3, report.Commits.Folded)
+	require.Equal(t, 4, report.Commits.Applied)
+	require.Empty(t, report.Verification.Problems)
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "f.txt 1.3\n", readTreeFile(t, tree, "f.txt"))
+	require.Equal(t, "h.txt 1.1\n", readTreeFile(t, tree, "h.txt"))
+	_, err = tree.File("g.txt")
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+)
+
+// ReportSimulation describes the repository a simulated migration built in
+// memory
+type ReportSimulation struct {
+	Commits            int         `json:"commits"`
+	Trees              int         `json:"trees"`
+	Blobs              int         `json:"blobs"`
+	Tags               int         `json:"tags"` // Annotated tag objects
+	RawBytes           int64       `json:"rawBytes"`
+	EstimatedPackBytes int64       `json:"estimatedPackBytes"` // Upper bound: objects compressed without deltas
+	Refs               []ReportRef `json:"refs"`
+	HeadCommit         string      `json:"headCommit,omitempty"`
+	HeadTree           string      `json:"headTree,omitempty"`
+}
+
+// ReportRef is a reference of the simulated repository
+type ReportRef struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// simulationTarget is implemented by writers that can build the target
+// repository in memory
+type simulationTarget interface {
+	InitMemory() error
+	Stats() (*git.RepoStats, error)
+}
+
+// validateSimulate rejects options a simulation cannot honor
+func (m *Migrator) validateSimulate() error {
+	if !m.config.Simulate {
+		return nil
+	}
+	switch {
+	case m.config.DryRun:
+		return fmt.Errorf("simulate and dry run cannot be combined")
+	case m.config.Resume:
+		return fmt.Errorf("a simulation cannot be resumed")
+	case m.config.Push != nil:
+		return fmt.Errorf("a simulation cannot push to a remote")
+	}
+	return nil
+}
+
+// initSimulatedTarget starts the target repository in memory
+func (m *Migrator) initSimulatedTarget(targetType string) error {
+	sim, ok := m.target.(simulationTarget)
+	if !ok {
+		return fmt.Errorf("target type %s does not support simulation", targetType)
+	}
+	return sim.InitMemory()
+}
+
+// openSimulationDB opens a state database in memory, so revision mappings
+// resolve tags and merges as in a real run
+func openSimulationDB() (*storage.StateDB, error) {
+	return storage.NewStateDB(":memory:")
+}
+
+// recordSimulation adds the objects and references of the simulated target
+// to the report
+func (m *Migrator) recordSimulation() {
+	sim, ok := m.target.(simulationTarget)
+	if !ok {
+		return
+	}
+	stats, err := sim.Stats()
+	if err != nil {
+		m.warn("failed to measure simulated repository", "error", err)
+		return
+	}
+	r := &ReportSimulation{
+		Commits:            stats.Commits,
+		Trees:              stats.Trees,
+		Blobs:              stats.Blobs,
+		Tags:               stats.Tags,
+		RawBytes:           stats.RawBytes,
+		EstimatedPackBytes: stats.PackBytes,
+		Refs:               []ReportRef{},
+		HeadCommit:         stats.HeadCommit,
+		HeadTree:           stats.HeadTree,
+	}
+	for name, hash := range stats.Refs {
+		r.Refs = append(r.Refs, ReportRef{Name: name, Hash: hash})
+	}
+	sort.Slice(r.Refs, func(i, j int) bool { return r.Refs[i].Name < r.Refs[j].Name })
+	m.report.Simulation = r
+	m.Logger().Info("simulated migration",
+		"objects", stats.Commits+stats.Trees+stats.Blobs+stats.Tags,
+		"estimated_pack_bytes", stats.PackBytes,
+		"head_tree", stats.HeadTree,
+	)
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_Simulate(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+	config := func(target string, simulate bool) *MigrationConfig {
+		return &MigrationConfig{
+			SourceType:    "cvs",
+			SourcePath:    repo,
+			TargetPath:    target,
+			StateFile:     filepath.Join(t.TempDir(), "state.db"),
+			AnnotatedTags: true,
+			Simulate:      simulate,
+			Logger:        logging.Discard(),
+		}
+	}
+
+	simulated := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(config(simulated, true))
+	require.NoError(t, m.Run())
+	_, err := os.Stat(simulated)
+	require.True(t, os.IsNotExist(err), "simulation must not create the target")
+
+	sim := m.Report().Simulation
+	require.NotNil(t, sim)
+	require.Equal(t, 2, sim.Commits)
+	require.Equal(t, 1, sim.Tags)
+	require.Equal(t, 2, sim.Blobs)
+	require.Positive(t, sim.EstimatedPackBytes)
+	require.True(t, m.Report().Verification.Passed)
+
+	// The simulation builds the same history as a real run
+	real := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, NewMigrator(config(real, false)).Run())
+	r, err := gogit.PlainOpen(real)
+	require.NoError(t, err)
+	head, err := r.Head()
+	require.NoError(t, err)
+	commit, err := r.CommitObject(head.Hash())
+	require.NoError(t, err)
+	require.Equal(t, head.Hash().String(), sim.HeadCommit)
+	require.Equal(t, commit.TreeHash.String(), sim.HeadTree)
+
+	tag, err := r.Reference(plumbing.NewTagReferenceName("REL_1"), false)
+	require.NoError(t, err)
+	require.Contains(t, sim.Refs, ReportRef{Name: "refs/tags/REL_1", Hash: tag.Hash().String()})
+	require.Contains(t, sim.Refs, ReportRef{Name: head.Name().String(), Hash: head.Hash().String()})
+
+	data, err := os.ReadFile(ReportPath(simulated) + ReportExtMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "## Simulation")
+}
+
+func TestRun_SimulateRejectsDryRun(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: t.TempDir(), TargetPath: t.TempDir(),
+		Simulate: true, DryRun: true, Logger: logging.Discard()})
+	require.ErrorContains(t, m.Run(), "simulate and dry run")
+}
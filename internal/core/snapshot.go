@@ -0,0 +1,368 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// Methods used to mirror a remote CVSROOT
+const (
+	// SnapshotRsync mirrors the repository with rsync, transferring only
+	// changed files on refreshes
+	SnapshotRsync = "rsync"
+	// SnapshotSSHTar streams a tar archive of the repository over ssh, for
+	// servers without rsync
+	SnapshotSSHTar = "ssh-tar"
+)
+
+// SnapshotConfig describes a CVSROOT to mirror
+type SnapshotConfig struct {
+	Remote     string // [user@]host:/path, or an rsync:// URL for SnapshotRsync
+	Dest       string // Local directory receiving the CVSROOT
+	Method     string // SnapshotRsync (default) or SnapshotSSHTar
+	Module     string // Only mirror this module and CVSROOT (empty = everything)
+	SSHCommand string // ssh executable (default: ssh)
+}
+
+// CVSSnapshot describes a completed mirror of a remote CVSROOT
+type CVSSnapshot struct {
+	Remote   string
+	Dest     string
+	Method   string
+	Module   string
+	TakenAt  time.Time // When the transfer started; later commits are not included
+	Duration time.Duration
+	Files    int   // RCS files in the snapshot
+	Bytes    int64 // Total size of the RCS files
+}
+
+// FetchCVSRoot mirrors the remote CVSROOT into config.Dest and verifies
+// that the copy is complete and consistent. Running it again refreshes the
+// mirror.
+func FetchCVSRoot(ctx context.Context, config SnapshotConfig) (*CVSSnapshot, error) {
+	if config.Remote == "" || config.Dest == "" {
+		return nil, fmt.Errorf("remote and destination are required")
+	}
+	if config.Method == "" {
+		config.Method = SnapshotRsync
+	}
+	if config.SSHCommand == "" {
+		config.SSHCommand = "ssh"
+	}
+	if config.Module != "" && (!fs.ValidPath(config.Module) || config.Module == "." || config.Module == "CVSROOT") {
+		return nil, fmt.Errorf("invalid module %q", config.Module)
+	}
+
+	snapshot := &CVSSnapshot{
+		Remote:  config.Remote,
+		Dest:    config.Dest,
+		Method:  config.Method,
+		Module:  config.Module,
+		TakenAt: time.Now(),
+	}
+
+	var err error
+	switch config.Method {
+	case SnapshotRsync:
+		err = fetchRsync(ctx, config)
+	case SnapshotSSHTar:
+		err = fetchSSHTar(ctx, config)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot method: %s", config.Method)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.Files, snapshot.Bytes, err = VerifyCVSRoot(config.Dest, config.Module)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot of %s is incomplete: %w", config.Remote, err)
+	}
+	snapshot.Duration = time.Since(snapshot.TakenAt)
+	return snapshot, nil
+}
+
+// snapshotDirs returns the repository directories to mirror
+func snapshotDirs(module string) []string {
+	if module == "" {
+		return nil
+	}
+	return []string{"CVSROOT", module}
+}
+
+// fetchRsync mirrors the remote with "rsync -a --delete", so files removed
+// on the server are removed from the mirror as well
+func fetchRsync(ctx context.Context, config SnapshotConfig) error {
+	remote := strings.TrimSuffix(config.Remote, "/")
+	dirs := snapshotDirs(config.Module)
+	if dirs == nil {
+		dirs = []string{""}
+	}
+	for _, dir := range dirs {
+		src, dest := remote+"/", config.Dest
+		if dir != "" {
+			src = remote + "/" + dir + "/"
+			dest = filepath.Join(config.Dest, dir)
+		}
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return err
+		}
+		args := []string{"-a", "--delete", "-e", config.SSHCommand, src, dest + string(filepath.Separator)}
+		if err := runSnapshotCommand(ctx, nil, "rsync", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchSSHTar runs tar on the server and unpacks its output into a fresh
+// directory, which then replaces config.Dest
+func fetchSSHTar(ctx context.Context, config SnapshotConfig) error {
+	host, dir, ok := strings.Cut(config.Remote, ":")
+	if !ok || host == "" || !strings.HasPrefix(dir, "/") {
+		return fmt.Errorf("remote must look like [user@]host:/path for %s", SnapshotSSHTar)
+	}
+
+	parent := filepath.Dir(filepath.Clean(config.Dest))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(parent, ".cvsroot-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	remoteCmd := "tar -C " + shellQuote(dir) + " -cf -"
+	if dirs := snapshotDirs(config.Module); dirs != nil {
+		for _, d := range dirs {
+			remoteCmd += " " + shellQuote(d)
+		}
+	} else {
+		remoteCmd += " ."
+	}
+
+	pr, pw := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := extractTar(pr, tmp)
+		// Drain the rest so ssh does not block on a full pipe
+		_, _ = io.Copy(io.Discard, pr)
+		extracted <- err
+	}()
+	err = runSnapshotCommand(ctx, pw, config.SSHCommand, host, remoteCmd)
+	_ = pw.CloseWithError(err)
+	if extractErr := <-extracted; err == nil {
+		err = extractErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Swap the new snapshot into place
+	old := tmp + ".old"
+	if err := os.Rename(config.Dest, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(tmp, config.Dest); err != nil {
+		_ = os.Rename(old, config.Dest)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// runSnapshotCommand runs a transfer command, writing its output to stdout
+// if set
+func runSnapshotCommand(ctx context.Context, stdout io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// extractTar unpacks regular files and directories below dest, rejecting
+// entries that would escape it
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot archive: %w", err)
+		}
+
+		name := filepath.FromSlash(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "" || name == "." {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("snapshot archive contains unsafe path %q", hdr.Name)
+		}
+		target := filepath.Join(dest, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, hdr.FileInfo().Mode().Perm()|0200)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		default:
+			// Links and devices have no place in a CVS repository
+		}
+	}
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// VerifyCVSRoot checks that dir holds a complete CVS repository: CVSROOT and
+// the module exist, no CVS lock is held (a commit was in progress when the
+// snapshot was taken) and every RCS file parses. It returns the number and
+// total size of the RCS files.
+func VerifyCVSRoot(dir, module string) (files int, size int64, err error) {
+	if info, err := os.Stat(filepath.Join(dir, "CVSROOT")); err != nil || !info.IsDir() {
+		return 0, 0, fmt.Errorf("CVSROOT directory not found in %s", dir)
+	}
+	root := dir
+	if module != "" {
+		root = filepath.Join(dir, filepath.FromSlash(module))
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			return 0, 0, fmt.Errorf("module %s not found in %s", module, dir)
+		}
+	}
+
+	var broken []string
+	err = filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if isCVSLock(name) {
+			return fmt.Errorf("%s is locked (a commit was in progress); fetch again", p)
+		}
+		if entry.IsDir() || !strings.HasSuffix(name, ",v") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		if err := checkRCSFile(p); err != nil {
+			broken = append(broken, fmt.Sprintf("%s: %v", p, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return files, size, err
+	}
+	if len(broken) > 0 {
+		return files, size, fmt.Errorf("%d unreadable RCS files, first: %s", len(broken), broken[0])
+	}
+	return files, size, nil
+}
+
+// isCVSLock reports whether name is a CVS directory lock (#cvs.lock,
+// #cvs.rfl.*, #cvs.wfl.*) or an RCS lock file (,name,)
+func isCVSLock(name string) bool {
+	return strings.HasPrefix(name, "#cvs.") ||
+		(len(name) > 2 && strings.HasPrefix(name, ",") && strings.HasSuffix(name, ","))
+}
+
+// checkRCSFile reports an RCS file that does not parse, lacks its head
+// revision or is truncated; a complete file ends with the @ closing the
+// last delta text
+func checkRCSFile(p string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(bytes.TrimRight(data, " \t\r\n"), []byte("@")) {
+		return fmt.Errorf("truncated")
+	}
+	rcs, err := cvs.NewRCSParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		return err
+	}
+	if rcs.Head == "" || rcs.Deltas[rcs.Head] == nil {
+		return fmt.Errorf("head revision missing")
+	}
+	return nil
+}
+
+// RecordSnapshot stores the snapshot in the state database, where later
+// migrations of the mirror find it
+func RecordSnapshot(db *storage.StateDB, snapshot *CVSSnapshot) error {
+	dest, err := filepath.Abs(snapshot.Dest)
+	if err != nil {
+		return err
+	}
+	return db.SaveSnapshot(&storage.CVSSnapshot{
+		Path:    dest,
+		Remote:  snapshot.Remote,
+		Method:  snapshot.Method,
+		Module:  snapshot.Module,
+		TakenAt: snapshot.TakenAt,
+		Files:   snapshot.Files,
+		Bytes:   snapshot.Bytes,
+	})
+}
+
+// loadSnapshot adds the snapshot the source was fetched from, if one was
+// recorded, to the report
+func (m *Migrator) loadSnapshot() {
+	source, err := filepath.Abs(m.config.SourcePath)
+	if err != nil {
+		return
+	}
+	snapshot, err := m.db.LatestSnapshot(source)
+	if err != nil {
+		if !errors.Is(err, storage.ErrSnapshotNotFound) {
+			m.Logger().Warn("failed to load source snapshot", "error", err)
+		}
+		return
+	}
+	m.Logger().Info("migrating CVS snapshot", "remote", snapshot.Remote, "taken_at", snapshot.TakenAt)
+	if m.report != nil {
+		m.report.SourceSnapshot = snapshot.TakenAt
+	}
+}
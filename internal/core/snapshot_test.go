@@ -0,0 +1,134 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// fakeSnapshotTools puts rsync and ssh stand-ins that copy local
+// directories on PATH
+func fakeSnapshotTools(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake rsync and ssh need a POSIX shell")
+	}
+	bin := t.TempDir()
+	rsync := "#!/bin/sh\nwhile [ $# -gt 2 ]; do shift; done\nsrc=${1#*:}\nmkdir -p \"$2\" && cp -R \"$src\". \"$2\"\n"
+	ssh := "#!/bin/sh\nshift\nexec sh -c \"$1\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "rsync"), []byte(rsync), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "ssh"), []byte(ssh), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// snapshotServer returns a copy of the simple CVS fixture with an extra
+// module to be left out of module snapshots
+func snapshotServer(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(filepath.Join(dir, "mod"), os.DirFS("../../test/fixtures/cvs/simple")))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "other"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other", "x,v"), []byte("not rcs"), 0644))
+	return dir
+}
+
+func TestFetchCVSRoot(t *testing.T) {
+	fakeSnapshotTools(t)
+	server := snapshotServer(t)
+
+	for _, method := range []string{SnapshotRsync, SnapshotSSHTar} {
+		t.Run(method, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "mirror")
+			config := SnapshotConfig{Remote: "host:" + server, Dest: dest, Method: method, Module: "mod"}
+			snapshot, err := FetchCVSRoot(context.Background(), config)
+			require.NoError(t, err)
+			require.Equal(t, 1, snapshot.Files)
+			require.Positive(t, snapshot.Bytes)
+			require.FileExists(t, filepath.Join(dest, "mod", "README.txt,v"))
+			require.NoDirExists(t, filepath.Join(dest, "other"))
+
+			// Refreshing picks up new files
+			require.NoError(t, os.CopyFS(filepath.Join(server, "mod", "sub"), os.DirFS("../../test/fixtures/cvs/simple")))
+			defer func() { require.NoError(t, os.RemoveAll(filepath.Join(server, "mod", "sub"))) }()
+			snapshot, err = FetchCVSRoot(context.Background(), config)
+			require.NoError(t, err)
+			require.Equal(t, 2, snapshot.Files)
+		})
+	}
+
+	// The whole repository includes the broken RCS file
+	_, err := FetchCVSRoot(context.Background(), SnapshotConfig{Remote: "host:" + server, Dest: filepath.Join(t.TempDir(), "all")})
+	require.ErrorContains(t, err, "unreadable RCS files")
+
+	_, err = FetchCVSRoot(context.Background(), SnapshotConfig{Remote: "host:" + server, Dest: t.TempDir(), Method: "ftp"})
+	require.Error(t, err)
+	_, err = FetchCVSRoot(context.Background(), SnapshotConfig{Remote: "host:" + server, Dest: t.TempDir(), Module: "../x"})
+	require.Error(t, err)
+}
+
+func TestVerifyCVSRoot(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := VerifyCVSRoot(dir, "")
+	require.ErrorContains(t, err, "CVSROOT")
+
+	require.NoError(t, os.CopyFS(dir, os.DirFS("../../test/fixtures/cvs/simple")))
+	files, size, err := VerifyCVSRoot(dir, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, files)
+	require.Positive(t, size)
+
+	_, _, err = VerifyCVSRoot(dir, "missing")
+	require.ErrorContains(t, err, "module missing")
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "#cvs.lock"), 0755))
+	_, _, err = VerifyCVSRoot(dir, "")
+	require.ErrorContains(t, err, "locked")
+}
+
+func TestExtractTar_RejectsUnsafePaths(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0644}))
+	require.NoError(t, tw.Close())
+	require.ErrorContains(t, extractTar(&buf, t.TempDir()), "unsafe path")
+}
+
+func TestRun_ReportsSourceSnapshot(t *testing.T) {
+	source := t.TempDir()
+	target := filepath.Join(t.TempDir(), "repo")
+	stateFile := filepath.Join(t.TempDir(), "state.db")
+
+	taken := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	db, err := storage.NewStateDB(stateFile)
+	require.NoError(t, err)
+	require.NoError(t, RecordSnapshot(db, &CVSSnapshot{Remote: "host:/cvs", Dest: source, Method: SnapshotRsync, TakenAt: taken}))
+	require.NoError(t, db.Close())
+
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: source,
+		TargetPath: target,
+		StateFile:  stateFile,
+		Logger:     logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: []*vcs.Commit{{Revision: "1", Author: "a", Date: taken, Message: "m",
+		Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a")}}}}}
+	require.NoError(t, m.Run())
+	require.True(t, taken.Equal(m.Report().SourceSnapshot))
+
+	markdown, err := os.ReadFile(ReportPath(target) + ReportExtMarkdown)
+	require.NoError(t, err)
+	require.Contains(t, string(markdown), "| Snapshot | 2024-06-01 08:00:00 UTC |")
+}
@@ -0,0 +1,328 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// SplitRule routes the files below a source directory into a target
+// repository, where the directory becomes the repository root
+type SplitRule struct {
+	Path   string // Source directory ("" = every file no other rule claims)
+	Target string // Path of the target repository
+}
+
+// SplitConfig holds the configuration of a split migration, which
+// distributes the history of one source over several target repositories
+type SplitConfig struct {
+	Rules           []SplitRule
+	ContinueOnError bool                                  // Keep going after a target fails
+	Logger          *slog.Logger                          // Structured logger (nil = logging.Default())
+	TargetConfig    func(rule SplitRule) *MigrationConfig // Builds the migration config of a target; all must share the source
+
+	source vcs.VCSReader // Shared source reader (nil = built from the first target config)
+}
+
+// SplitResult summarizes a split migration run
+type SplitResult struct {
+	Completed []string          // Targets migrated
+	Failed    map[string]string // Target -> error message
+	Reports   map[string]*MigrationReport
+}
+
+// RunSplit reads the source once and migrates the files routed to each rule
+// into its target repository. A file belongs to the rule with the longest
+// matching directory; files no rule claims are not migrated. Every target
+// keeps its own state and report, so a failed target can be resumed on
+// its own.
+func RunSplit(config *SplitConfig) (*SplitResult, error) {
+	if len(config.Rules) == 0 {
+		return nil, fmt.Errorf("at least one split rule is required")
+	}
+	if config.TargetConfig == nil {
+		return nil, fmt.Errorf("target config builder is required")
+	}
+	rules, err := normalizeSplitRules(config.Rules)
+	if err != nil {
+		return nil, err
+	}
+	logger := logging.OrDefault(config.Logger)
+
+	// Read the shared source once
+	if config.source == nil {
+		reader := NewMigrator(config.TargetConfig(rules[0]))
+		if err := reader.initSource(); err != nil {
+			return nil, fmt.Errorf("failed to init source: %w", err)
+		}
+		config.source = reader.source
+	}
+	source, err := readSplitSource(config.source)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := source.reader.Close(); err != nil {
+			logger.Warn("failed to close source", "error", err)
+		}
+	}()
+
+	if unclaimed := source.unclaimed(rules); len(unclaimed) > 0 {
+		logger.Warn("files not routed to any split target", "count", len(unclaimed), "example", unclaimed[0])
+	}
+
+	result := &SplitResult{Failed: make(map[string]string), Reports: make(map[string]*MigrationReport)}
+	for _, rule := range rules {
+		migrationConfig := config.TargetConfig(rule)
+		if migrationConfig.Logger == nil {
+			migrationConfig.Logger = config.Logger
+		}
+		migrationConfig.TargetPath = rule.Target
+
+		migrator := NewMigrator(migrationConfig)
+		migrator.source = source.subtree(rule, rules)
+
+		logger.Info("migrating split target", "path", rule.Path, "target", rule.Target)
+		runErr := migrator.Run()
+		result.Reports[rule.Target] = migrator.Report()
+		if runErr != nil {
+			logger.Error("split target failed", "target", rule.Target, "error", runErr)
+			result.Failed[rule.Target] = runErr.Error()
+			if !config.ContinueOnError {
+				return result, fmt.Errorf("target %s failed: %w", rule.Target, runErr)
+			}
+			continue
+		}
+		result.Completed = append(result.Completed, rule.Target)
+	}
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d of %d targets failed", len(result.Failed), len(rules))
+	}
+	return result, nil
+}
+
+// normalizeSplitRules trims slashes from the rule paths and rejects rules
+// without a target and duplicate paths or targets
+func normalizeSplitRules(rules []SplitRule) ([]SplitRule, error) {
+	paths := make(map[string]bool)
+	targets := make(map[string]bool)
+	normalized := make([]SplitRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.Path = strings.Trim(rule.Path, "/")
+		if rule.Target == "" {
+			return nil, fmt.Errorf("split rule for %q has no target", rule.Path)
+		}
+		if paths[rule.Path] {
+			return nil, fmt.Errorf("duplicate split rule for %q", rule.Path)
+		}
+		if targets[rule.Target] {
+			return nil, fmt.Errorf("split target %s is used by more than one rule", rule.Target)
+		}
+		paths[rule.Path], targets[rule.Target] = true, true
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
+
+// splitRoute returns the rule a file belongs to and its path relative to
+// the rule's directory, or nil if no rule claims the file
+func splitRoute(path string, rules []SplitRule) (*SplitRule, string) {
+	var best *SplitRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Path != "" && !strings.HasPrefix(path, rule.Path+"/") {
+			continue
+		}
+		if best == nil || len(rule.Path) > len(best.Path) {
+			best = rule
+		}
+	}
+	if best == nil || best.Path == "" {
+		return best, path
+	}
+	return best, path[len(best.Path)+1:]
+}
+
+// splitSource holds the history of the shared source, read once
+type splitSource struct {
+	reader   vcs.VCSReader
+	commits  []*vcs.Commit
+	branches []string
+	tags     map[string]string
+	details  map[string]vcs.TagInfo
+}
+
+func readSplitSource(reader vcs.VCSReader) (*splitSource, error) {
+	if err := reader.Validate(); err != nil {
+		return nil, fmt.Errorf("source validation failed: %w", err)
+	}
+	s := &splitSource{reader: reader}
+	iter, err := reader.GetCommits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	for iter.Next() {
+		s.commits = append(s.commits, iter.Commit())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterator error: %w", err)
+	}
+	if s.branches, err = reader.GetBranches(); err != nil {
+		return nil, fmt.Errorf("failed to get branches: %w", err)
+	}
+	if s.tags, err = reader.GetTags(); err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	if detailer, ok := reader.(vcs.TagDetailer); ok {
+		if s.details, err = detailer.GetTagDetails(); err != nil {
+			return nil, fmt.Errorf("failed to get tag details: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// unclaimed returns the paths of the files no rule claims, sorted
+func (s *splitSource) unclaimed(rules []SplitRule) []string {
+	seen := make(map[string]bool)
+	for _, c := range s.commits {
+		for _, fc := range c.Files {
+			if rule, _ := splitRoute(fc.Path, rules); rule == nil {
+				seen[fc.Path] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// subtree returns a reader over the part of the history routed to rule
+func (s *splitSource) subtree(rule SplitRule, rules []SplitRule) *subtreeReader {
+	return &subtreeReader{source: s, rule: rule, rules: rules}
+}
+
+// subtreeReader serves the commits of the shared source restricted to the
+// files of one split rule, with paths relative to the rule's directory.
+// Every call returns fresh copies, as the migrator rewrites commits.
+type subtreeReader struct {
+	source *splitSource
+	rule   SplitRule
+	rules  []SplitRule
+}
+
+func (r *subtreeReader) Validate() error { return nil }
+
+func (r *subtreeReader) GetCommits() (vcs.CommitIterator, error) {
+	var commits []*vcs.Commit
+	for _, c := range r.source.commits {
+		var files []vcs.FileChange
+		for _, fc := range c.Files {
+			rel, in := r.route(fc.Path)
+			oldRel, oldIn := "", false
+			if fc.OldPath != "" {
+				oldRel, oldIn = r.route(fc.OldPath)
+			}
+			switch {
+			case in && (fc.OldPath == "" || oldIn):
+				fc.Path, fc.OldPath = rel, oldRel
+				files = append(files, fc)
+			case in:
+				// Renamed or copied into the subtree
+				fc.Path, fc.Action, fc.OldPath = rel, vcs.ActionAdd, ""
+				files = append(files, fc)
+			case oldIn && fc.Action == vcs.ActionRename:
+				// Renamed out of the subtree
+				files = append(files, vcs.FileChange{Path: oldRel, Action: vcs.ActionDelete})
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		copied := *c
+		copied.Files = files
+		copied.Parents = append([]string(nil), c.Parents...)
+		commits = append(commits, &copied)
+	}
+	return &sliceIterator{commits: commits, index: -1}, nil
+}
+
+// route returns the path of a file relative to the subtree and whether the
+// file belongs to it
+func (r *subtreeReader) route(path string) (string, bool) {
+	rule, rel := splitRoute(path, r.rules)
+	return rel, rule != nil && rule.Target == r.rule.Target
+}
+
+func (r *subtreeReader) GetBranches() ([]string, error) {
+	return append([]string(nil), r.source.branches...), nil
+}
+
+// GetTags returns the tags covering files of the subtree when the source
+// can tell, and all tags otherwise
+func (r *subtreeReader) GetTags() (map[string]string, error) {
+	details, err := r.GetTagDetails()
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		tags := make(map[string]string, len(r.source.tags))
+		for name, rev := range r.source.tags {
+			tags[name] = rev
+		}
+		return tags, nil
+	}
+	tags := make(map[string]string, len(details))
+	for name, info := range details {
+		tags[name] = info.Revision
+	}
+	return tags, nil
+}
+
+// GetTagDetails returns the tags of the subtree with paths relative to it.
+// Files below the directories of nested rules belong to those rules, so
+// their tags are left out.
+func (r *subtreeReader) GetTagDetails() (map[string]vcs.TagInfo, error) {
+	detailer, ok := r.source.reader.(vcs.SubtreeTagDetailer)
+	if !ok {
+		return r.source.details, nil
+	}
+	var nested []string
+	for _, other := range r.rules {
+		if other.Path != r.rule.Path && (r.rule.Path == "" || strings.HasPrefix(other.Path, r.rule.Path+"/")) {
+			nested = append(nested, other.Path)
+		}
+	}
+	if r.rule.Path == "" && len(nested) == 0 {
+		return r.source.details, nil
+	}
+	details, err := detailer.GetSubtreeTagDetails(r.rule.Path, nested...)
+	if err != nil {
+		return nil, err
+	}
+	if r.rule.Path != "" {
+		for name, info := range details {
+			info.Path = info.Path[len(r.rule.Path)+1:]
+			details[name] = info
+		}
+	}
+	return details, nil
+}
+
+func (r *subtreeReader) Close() error { return nil }
+
+// sliceIterator iterates over commits held in memory
+type sliceIterator struct {
+	commits []*vcs.Commit
+	index   int
+}
+
+func (i *sliceIterator) Next() bool {
+	i.index++
+	return i.index < len(i.commits)
+}
+
+func (i *sliceIterator) Commit() *vcs.Commit { return i.commits[i.index] }
+
+func (i *sliceIterator) Err() error { return nil }
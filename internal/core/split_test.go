@@ -0,0 +1,160 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRoute(t *testing.T) {
+	rules := []SplitRule{{Path: "lib", Target: "lib"}, {Path: "lib/net", Target: "net"}, {Path: "", Target: "rest"}}
+
+	rule, rel := splitRoute("lib/net/socket.c", rules)
+	require.Equal(t, "net", rule.Target, "the longest directory wins")
+	require.Equal(t, "socket.c", rel)
+
+	rule, rel = splitRoute("lib/util.c", rules)
+	require.Equal(t, "lib", rule.Target)
+	require.Equal(t, "util.c", rel)
+
+	rule, rel = splitRoute("library.txt", rules)
+	require.Equal(t, "rest", rule.Target, "only whole directory names match")
+	require.Equal(t, "library.txt", rel)
+
+	rule, _ = splitRoute("README", rules[:2])
+	require.Nil(t, rule)
+}
+
+func TestNormalizeSplitRules(t *testing.T) {
+	rules, err := normalizeSplitRules([]SplitRule{{Path: "/lib/", Target: "a"}})
+	require.NoError(t, err)
+	require.Equal(t, "lib", rules[0].Path)
+
+	_, err = normalizeSplitRules([]SplitRule{{Path: "lib"}})
+	require.Error(t, err)
+	_, err = normalizeSplitRules([]SplitRule{{Path: "lib", Target: "a"}, {Path: "lib/", Target: "b"}})
+	require.Error(t, err)
+	_, err = normalizeSplitRules([]SplitRule{{Path: "lib", Target: "a"}, {Path: "app", Target: "a"}})
+	require.Error(t, err)
+}
+
+func TestRunSplit(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(path string) vcs.FileChange {
+		return vcs.FileChange{Path: path, Action: vcs.ActionAdd, Revision: "1.1", Content: []byte(path + "\n")}
+	}
+	source := &mockReaderWithCommits{commits: []*vcs.Commit{
+		{Revision: "1", Author: "alice", Date: date, Message: "both", Files: []vcs.FileChange{
+			add("lib/util.c"), add("app/main.c"), add("README"),
+		}},
+		{Revision: "2", Author: "bob", Date: date.Add(time.Hour), Message: "lib only", Files: []vcs.FileChange{
+			add("lib/sub/more.c"),
+		}},
+	}}
+
+	dir := t.TempDir()
+	config := &SplitConfig{
+		Rules: []SplitRule{
+			{Path: "lib", Target: filepath.Join(dir, "lib")},
+			{Path: "app", Target: filepath.Join(dir, "app")},
+		},
+		TargetConfig: func(rule SplitRule) *MigrationConfig {
+			return &MigrationConfig{SourceType: "cvs", SourcePath: "/src", Logger: logging.Discard()}
+		},
+		Logger: logging.Discard(),
+		source: source,
+	}
+	result, err := RunSplit(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "lib"), filepath.Join(dir, "app")}, result.Completed)
+	require.Equal(t, 2, result.Reports[filepath.Join(dir, "lib")].Commits.Applied)
+	require.Equal(t, 1, result.Reports[filepath.Join(dir, "app")].Commits.Applied)
+
+	repo, err := gogit.PlainOpen(filepath.Join(dir, "lib"))
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "lib/util.c\n", readTreeFile(t, tree, "util.c"))
+	require.Equal(t, "lib/sub/more.c\n", readTreeFile(t, tree, "sub/more.c"))
+	_, err = tree.File("README")
+	require.Error(t, err, "unclaimed files are not migrated")
+
+	// The source commits are left untouched for the next target
+	require.Equal(t, "lib/util.c", source.commits[0].Files[0].Path)
+}
+
+func TestSubtreeReader_Renames(t *testing.T) {
+	rules := []SplitRule{{Path: "lib", Target: "lib"}, {Path: "app", Target: "app"}}
+	source := &splitSource{commits: []*vcs.Commit{{Revision: "1", Files: []vcs.FileChange{
+		{Path: "lib/b.c", OldPath: "lib/a.c", Action: vcs.ActionRename},
+		{Path: "lib/c.c", OldPath: "app/c.c", Action: vcs.ActionRename},
+		{Path: "app/d.c", OldPath: "lib/d.c", Action: vcs.ActionRename},
+		{Path: "app/e.c", OldPath: "lib/e.c", Action: vcs.ActionCopy},
+	}}}}
+
+	iter, err := (&subtreeReader{source: source, rule: rules[0], rules: rules}).GetCommits()
+	require.NoError(t, err)
+	require.True(t, iter.Next())
+	require.Equal(t, []vcs.FileChange{
+		{Path: "b.c", OldPath: "a.c", Action: vcs.ActionRename},
+		{Path: "c.c", Action: vcs.ActionAdd},
+		{Path: "d.c", Action: vcs.ActionDelete},
+	}, iter.Commit().Files)
+}
+
+// subtreeTagReader is a source whose tags each cover one file
+type subtreeTagReader struct {
+	mockReaderWithCommits
+	tagged map[string]string // Tag -> path of the file it covers
+}
+
+func (r *subtreeTagReader) GetSubtreeTagDetails(dir string, exclude ...string) (map[string]vcs.TagInfo, error) {
+	details := make(map[string]vcs.TagInfo)
+	for name, path := range r.tagged {
+		if dir != "" && !strings.HasPrefix(path, dir+"/") {
+			continue
+		}
+		excluded := false
+		for _, e := range exclude {
+			excluded = excluded || strings.HasPrefix(path, e+"/")
+		}
+		if !excluded {
+			details[name] = vcs.TagInfo{Name: name, Path: path, Revision: "1.1"}
+		}
+	}
+	return details, nil
+}
+
+func TestSubtreeReader_NestedRuleTags(t *testing.T) {
+	rules := []SplitRule{{Path: "lib", Target: "lib"}, {Path: "lib/net", Target: "net"}, {Path: "", Target: "rest"}}
+	source := &splitSource{reader: &subtreeTagReader{tagged: map[string]string{
+		"LIB_1": "lib/util.c", "NET_1": "lib/net/socket.c", "DOC_1": "README",
+	}}}
+
+	tagNames := func(rule SplitRule) []string {
+		details, err := source.subtree(rule, rules).GetTagDetails()
+		require.NoError(t, err)
+		names := make(map[string]bool)
+		for name := range details {
+			names[name] = true
+		}
+		return sortedKeys(names)
+	}
+	require.Equal(t, []string{"LIB_1"}, tagNames(rules[0]), "tags of the nested rule stay with it")
+	require.Equal(t, []string{"NET_1"}, tagNames(rules[1]))
+	require.Equal(t, []string{"DOC_1"}, tagNames(rules[2]))
+
+	details, err := source.subtree(rules[1], rules).GetTagDetails()
+	require.NoError(t, err)
+	require.Equal(t, "socket.c", details["NET_1"].Path)
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// ReportMemory records how a migration kept within its memory budget
+type ReportMemory struct {
+	BudgetBytes       int64 `json:"budgetBytes"`
+	ContentCacheBytes int64 `json:"contentCacheBytes"` // Reserved for the content cache in memory
+	SourceTextBytes   int64 `json:"sourceTextBytes"`   // RCS delta texts kept in memory
+	SourceFilesOnDisk int   `json:"sourceFilesOnDisk"` // RCS files read again from disk instead
+	PeakBufferedBytes int64 `json:"peakBufferedBytes"` // Most commit content held in memory at once
+	SpilledFiles      int   `json:"spilledFiles"`      // File contents written to the spill directory
+	SpilledBytes      int64 `json:"spilledBytes"`
+}
+
+// commitSpool keeps the content of the pending commits within a memory
+// budget. Content that does not fit is written to a temporary directory and
+// streamed back from there when its commit is applied; the content of
+// applied commits is released. Content read lazily from its source, like
+// CVS revisions reconstructed when their commit is applied, is not held and
+// left alone.
+type commitSpool struct {
+	budget   int64
+	parent   string // Directory the spill directory is created in
+	dir      string // Spill directory, created on the first spill
+	buffered int64  // Bytes of content held in memory
+	report   *ReportMemory
+}
+
+// contentCacheSize returns the bytes of CVS revisions the content cache
+// holds in memory: ContentCacheSize, or its default, within half of the
+// memory budget
+func (m *Migrator) contentCacheSize() int64 {
+	size := m.config.ContentCacheSize
+	if size == 0 {
+		size = cvs.DefaultContentCacheSize
+	}
+	if m.config.MemoryBudget > 0 {
+		size = max(min(size, m.config.MemoryBudget/2), 1)
+	}
+	return size
+}
+
+// cacheReserve returns the part of the memory budget the content cache may
+// fill
+func (m *Migrator) cacheReserve() int64 {
+	if m.contentCache == nil {
+		return 0
+	}
+	return m.contentCache.MaxBytes()
+}
+
+// startSpool buffers the commits from startIdx on within what the content
+// cache and the RCS delta texts left of the memory budget. The content of
+// the commits before startIdx, which an earlier run applied, is released.
+func (m *Migrator) startSpool(commits []*vcs.Commit, startIdx int) (*commitSpool, error) {
+	report := &ReportMemory{BudgetBytes: m.config.MemoryBudget, ContentCacheBytes: m.cacheReserve()}
+	if m.textBudget != nil {
+		report.SourceTextBytes, report.SourceFilesOnDisk = m.textBudget.Stats()
+	}
+	m.report.Memory = report
+
+	s := &commitSpool{
+		budget: max(m.config.MemoryBudget-report.ContentCacheBytes-report.SourceTextBytes, 0),
+		parent: m.config.SpillDir,
+		report: report,
+	}
+	for _, commit := range commits[:startIdx] {
+		s.release(commit)
+	}
+	if err := s.buffer(commits[startIdx:]); err != nil {
+		_ = s.close()
+		return nil, err
+	}
+	if report.SpilledFiles > 0 || report.SourceFilesOnDisk > 0 {
+		m.Logger().Info("memory budget exceeded; keeping content on disk",
+			"budget", report.BudgetBytes,
+			"source_files_on_disk", report.SourceFilesOnDisk,
+			"spilled_files", report.SpilledFiles,
+			"spilled_bytes", report.SpilledBytes,
+		)
+	}
+	return s, nil
+}
+
+// buffer accounts for the content of the pending commits in order, spilling
+// the content that would exceed the budget
+func (s *commitSpool) buffer(commits []*vcs.Commit) error {
+	for _, commit := range commits {
+		for i := range commit.Files {
+			fc := &commit.Files[i]
+			size := int64(len(fc.Content))
+			if fc.Source != nil || size == 0 {
+				continue
+			}
+			if s.buffered+size <= s.budget {
+				s.buffered += size
+				s.report.PeakBufferedBytes = max(s.report.PeakBufferedBytes, s.buffered)
+				continue
+			}
+			if err := s.spill(fc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spill moves the content of a file change to the spill directory
+func (s *commitSpool) spill(fc *vcs.FileChange) error {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp(s.parent, "git-migrator-spill-")
+		if err != nil {
+			return fmt.Errorf("failed to create spill directory: %w", err)
+		}
+		s.dir = dir
+	}
+	path := filepath.Join(s.dir, strconv.Itoa(s.report.SpilledFiles))
+	if err := os.WriteFile(path, fc.Content, 0600); err != nil {
+		return fmt.Errorf("failed to spill %s: %w", fc.Path, err)
+	}
+	s.report.SpilledFiles++
+	s.report.SpilledBytes += int64(len(fc.Content))
+	fc.Content = nil
+	fc.Source = func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	return nil
+}
+
+// release drops the content of a commit that was applied or skipped
+func (s *commitSpool) release(commit *vcs.Commit) {
+	for i := range commit.Files {
+		fc := &commit.Files[i]
+		if fc.Source == nil {
+			s.buffered -= int64(len(fc.Content))
+			fc.Content = nil
+		}
+	}
+}
+
+// close removes the spill directory
+func (s *commitSpool) close() error {
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_MemoryBudgetSpillsContent(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var commits []*vcs.Commit
+	for i, content := range []string{"version1", "version2", "version3"} {
+		commits = append(commits, &vcs.Commit{Revision: content, Author: "alice", Date: date.Add(time.Duration(i) * time.Hour), Message: content,
+			Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Content: []byte(content)}}})
+	}
+
+	spillDir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: target,
+		MemoryBudget: 10, SpillDir: spillDir, Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+
+	// Only the first commit fits into the budget
+	require.Equal(t, &ReportMemory{BudgetBytes: 10, PeakBufferedBytes: 8, SpilledFiles: 2, SpilledBytes: 16}, m.Report().Memory)
+	entries, err := os.ReadDir(spillDir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "the spill directory is removed")
+	require.Nil(t, commits[0].Files[0].Content, "applied content is released")
+
+	repo, err := gogit.PlainOpen(target)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "version3", readTreeFile(t, tree, "f.txt"))
+	parent, err := commit.Parent(0)
+	require.NoError(t, err)
+	tree, err = parent.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "version2", readTreeFile(t, tree, "f.txt"))
+}
+
+func TestRun_MemoryBudgetCVSSource(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(taggedRCS), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "g.txt,v"), []byte(taggedRCS), 0644))
+
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: repo, TargetPath: filepath.Join(t.TempDir(), "repo"),
+		MemoryBudget: 1, Logger: logging.Discard()})
+	require.NoError(t, m.Run())
+	require.Equal(t, 2, m.Report().Memory.SourceFilesOnDisk)
+	require.Zero(t, m.Report().Memory.SourceTextBytes)
+	require.Equal(t, int64(1), m.Report().Memory.ContentCacheBytes)
+	require.Equal(t, 2, m.Report().Commits.Applied)
+}
+
+func TestMigrator_ContentCacheSize(t *testing.T) {
+	for _, c := range []struct {
+		cache, budget, want int64
+	}{
+		{0, 0, cvs.DefaultContentCacheSize},
+		{10 << 20, 0, 10 << 20},
+		{0, 1 << 30, cvs.DefaultContentCacheSize},
+		{0, 100 << 20, 50 << 20}, // At most half of the budget
+		{10 << 20, 100 << 20, 10 << 20},
+		{0, 1, 1},
+	} {
+		m := NewMigrator(&MigrationConfig{ContentCacheSize: c.cache, MemoryBudget: c.budget})
+		require.Equal(t, c.want, m.contentCacheSize(), c)
+	}
+}
@@ -2,14 +2,19 @@
 package core
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/logging"
 	"github.com/adamf123git/git-migrator/internal/mapping"
 	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/storage"
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	cvspkg "github.com/adamf123git/git-migrator/internal/vcs/cvs"
 	gitpkg "github.com/adamf123git/git-migrator/internal/vcs/git"
@@ -37,13 +42,43 @@ type SyncConfig struct {
 	AuthorMap  map[string]string // CVS user → "Name <email>" (or Git name → CVS user)
 	StateFile  string            // Path to the JSON state file (empty = no persistence)
 	DryRun     bool              // When true, log planned changes without applying them
+	Logger     *slog.Logger      // Structured logger (nil = logging.Default())
+	LogDir     string            // Directory for per-sync log files (empty = disabled)
+	CVSWriter  string            // How commits are written to CVS: CVSWriterAuto, CVSWriterClient or CVSWriterNative
+	CVSClient  cvspkg.Client     // How the cvs client is run (zero = "cvs" from PATH); Timeout is set from Timeouts
+	Timeouts   Timeouts          // Time limits of reading history, each commit and each cvs client call (zero = none); Refs is unused
+	// ForceUnlock takes over the lock of the Git repository even if another
+	// run holds it.
+	ForceUnlock bool
+	// CheckIgnore lists path.Match patterns, matched against the path or
+	// its base name, of files Check does not compare (e.g. ".gitignore").
+	CheckIgnore []string
 }
 
+// CVS writers used by Git → CVS syncs.
+const (
+	// CVSWriterAuto uses the cvs client when it is installed and the
+	// native writer otherwise.
+	CVSWriterAuto = ""
+	// CVSWriterClient commits through a checkout using the cvs client.
+	CVSWriterClient = "client"
+	// CVSWriterNative appends revisions to the RCS files directly.
+	CVSWriterNative = "native"
+)
+
 // SyncState records the most recent sync position for each direction.
 type SyncState struct {
 	LastGitCommit string    `json:"last_git_commit"` // Hash of the last Git commit synced to CVS
 	LastCVSSync   time.Time `json:"last_cvs_sync"`   // Timestamp of the last CVS commit synced to Git
 	SyncedAt      time.Time `json:"synced_at"`       // Wall-clock time of the last sync
+
+	// CVSFiles are the RCS files read by the last complete CVS → Git
+	// pass; the next pass only reads those that changed since
+	CVSFiles map[string]cvspkg.FileStamp `json:"cvs_files,omitempty"`
+	// CVSRevisions are the file revisions, by path, of the commits synced
+	// at LastCVSSync. CVS dates have a one-second resolution, so the next
+	// pass reads that second again and leaves them out.
+	CVSRevisions map[string][]string `json:"cvs_revisions,omitempty"`
 }
 
 // Syncer orchestrates bidirectional synchronisation between a Git repository
@@ -53,6 +88,8 @@ type Syncer struct {
 	authorMap *mapping.AuthorMap
 	reporter  *progress.Reporter
 	state     *SyncState
+	lock      *Lock // Held on the Git repository while Run writes it (nil = none)
+	logger    *slog.Logger
 }
 
 // NewSyncer creates a new Syncer from the supplied configuration.
@@ -61,11 +98,51 @@ func NewSyncer(config *SyncConfig) *Syncer {
 		config:    config,
 		authorMap: mapping.NewAuthorMap(config.AuthorMap),
 		reporter:  progress.NewReporter(0),
+		logger:    logging.OrDefault(config.Logger),
 	}
 }
 
+// Logger returns the logger used by the syncer.
+func (s *Syncer) Logger() *slog.Logger {
+	return logging.OrDefault(s.logger)
+}
+
 // Run executes the configured sync operation.
 func (s *Syncer) Run() error {
+	// Attach the sync ID to every log entry and optionally capture the
+	// entries in a per-sync log file.
+	syncID := s.generateSyncID()
+	s.logger = logging.OrDefault(s.config.Logger).With("migration_id", syncID)
+	if s.config.LogDir != "" {
+		syncLog, err := logging.OpenMigrationLog(s.config.Logger, s.config.LogDir, syncID)
+		if err != nil {
+			return fmt.Errorf("failed to open sync log: %w", err)
+		}
+		s.logger = syncLog.Logger
+		defer func() {
+			if err := syncLog.Close(); err != nil {
+				s.logger.Warn("failed to close sync log", "error", err)
+			}
+		}()
+	}
+	s.logger.Info("starting sync", "direction", s.config.Direction, "git", s.config.GitPath, "cvs", s.config.CVSPath)
+
+	// Keep migrations and other syncs away from the repositories meanwhile.
+	// A missing Git repository has nothing to protect; the sync reports it.
+	if _, err := os.Stat(s.config.GitPath); err == nil && !s.config.DryRun {
+		lock, err := AcquireLock(LockPath(s.config.GitPath), "sync", s.config.ForceUnlock)
+		if err != nil {
+			return err
+		}
+		s.lock = lock
+		defer func() {
+			if err := lock.Release(); err != nil {
+				s.logger.Warn("failed to release lock", "error", err)
+			}
+			s.lock = nil
+		}()
+	}
+
 	if err := s.loadState(); err != nil {
 		return fmt.Errorf("failed to load sync state: %w", err)
 	}
@@ -83,16 +160,15 @@ func (s *Syncer) Run() error {
 		// Advance LastGitCommit to the current Git HEAD so that the
 		// subsequent Git→CVS pass does not re-apply the commits that were
 		// just imported from CVS (which would create an infinite sync loop).
-		gitReader := gitpkg.NewReader(s.config.GitPath)
-		if validateErr := gitReader.Validate(); validateErr == nil {
-			if headCommit, headErr := gitReader.GetHeadRevision(); headErr == nil && headCommit != "" {
+		if gitRepo, openErr := s.openGitReadOnly(); openErr == nil {
+			if headCommit, headErr := gitRepo.ResolveRevision("HEAD"); headErr == nil && headCommit != "" {
 				s.state.LastGitCommit = headCommit
 			} else if headErr != nil {
-				log.Printf("Warning: could not read Git HEAD after cvs-to-git sync; bidirectional cycle prevention may not work: %v", headErr)
+				s.Logger().Warn("could not read Git HEAD after cvs-to-git sync; bidirectional cycle prevention may not work", "error", headErr)
 			}
-			_ = gitReader.Close()
+			_ = gitRepo.Close()
 		} else {
-			log.Printf("Warning: could not open Git repo after cvs-to-git sync; bidirectional cycle prevention may not work: %v", validateErr)
+			s.Logger().Warn("could not open Git repo after cvs-to-git sync; bidirectional cycle prevention may not work", "error", openErr)
 		}
 		return s.syncGitToCVS()
 	default:
@@ -100,6 +176,17 @@ func (s *Syncer) Run() error {
 	}
 }
 
+// openGitReadOnly opens the Git repository for the checks that only read
+// it, without a worktree, so that bare repositories work too
+func (s *Syncer) openGitReadOnly() (*gitpkg.Writer, error) {
+	repo := gitpkg.NewWriter()
+	repo.SetLogger(s.Logger())
+	if err := repo.OpenReadOnly(s.config.GitPath); err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	return repo, nil
+}
+
 // syncGitToCVS fetches commits from Git that are newer than the last sync
 // and applies them to the CVS repository.
 func (s *Syncer) syncGitToCVS() error {
@@ -111,21 +198,15 @@ func (s *Syncer) syncGitToCVS() error {
 	}
 	defer func() {
 		if err := gitReader.Close(); err != nil {
-			log.Printf("Warning: failed to close git reader: %v", err)
+			s.Logger().Warn("failed to close git reader", "error", err)
 		}
 	}()
 
-	iter, err := gitReader.GetCommitsSince(s.state.LastGitCommit)
+	newCommits, err := s.readCommits("git", gitReader, func() (vcs.CommitIterator, error) {
+		return gitReader.GetCommitsSince(s.state.LastGitCommit)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get git commits: %w", err)
-	}
-
-	var newCommits []*vcs.Commit
-	for iter.Next() {
-		newCommits = append(newCommits, iter.Commit())
-	}
-	if err := iter.Err(); err != nil {
-		return fmt.Errorf("error iterating git commits: %w", err)
+		return err
 	}
 
 	if len(newCommits) == 0 {
@@ -141,46 +222,45 @@ func (s *Syncer) syncGitToCVS() error {
 			if len(rev) > 8 {
 				rev = rev[:8]
 			}
-			log.Printf("DRY RUN: would sync git commit %s (%s) to CVS", rev, c.Message)
+			s.Logger().Info("dry run: would sync git commit to CVS", "revision", rev, "message", c.Message)
 		}
 		return nil
 	}
 
-	// Prepare CVS working directory
-	workDir, cleanup, err := s.prepareCVSWorkDir()
+	cvsWriter, cleanup, err := s.openCVSWriter()
 	if err != nil {
 		return err
 	}
 	if cleanup != nil {
 		defer cleanup()
 	}
-
-	cvsWriter := cvspkg.NewWriter(s.config.CVSPath, s.config.CVSModule)
-	if err := cvsWriter.Init(workDir); err != nil {
-		return fmt.Errorf("failed to initialise CVS writer: %w", err)
-	}
 	defer func() {
 		if err := cvsWriter.Close(); err != nil {
-			log.Printf("Warning: failed to close CVS writer: %v", err)
+			s.Logger().Warn("failed to close CVS writer", "error", err)
 		}
 	}()
 
+	s.startProgress(len(newCommits))
 	for _, commit := range newCommits {
+		if err := s.lock.Err(); err != nil {
+			return err
+		}
 		rev := commit.Revision
 		if len(rev) > 8 {
 			rev = rev[:8]
 		}
 		s.reporter.SetOperation(fmt.Sprintf("Applying git commit %s to CVS", rev))
 
-		if err := cvsWriter.ApplyCommit(commit); err != nil {
+		if err := withTimeout(s.config.Timeouts.Commit, func() error { return cvsWriter.ApplyCommit(commit) }, cvsWriter); err != nil {
 			return fmt.Errorf("failed to apply git commit %s to CVS: %w", commit.Revision, err)
 		}
 
 		s.state.LastGitCommit = commit.Revision
 		s.state.SyncedAt = time.Now()
 		if err := s.saveState(); err != nil {
-			log.Printf("Warning: failed to save sync state: %v", err)
+			s.Logger().Warn("failed to save sync state", "error", err)
 		}
+		s.reporter.Increment()
 	}
 
 	s.reporter.SetOperation(fmt.Sprintf("Git → CVS: synced %d commit(s)", len(newCommits)))
@@ -198,29 +278,37 @@ func (s *Syncer) syncCVSToGit() error {
 	}
 	defer func() {
 		if err := cvsReader.Close(); err != nil {
-			log.Printf("Warning: failed to close CVS reader: %v", err)
+			s.Logger().Warn("failed to close CVS reader", "error", err)
 		}
 	}()
 
-	iter, err := cvsReader.GetCommits()
-	if err != nil {
-		return fmt.Errorf("failed to get CVS commits: %w", err)
-	}
-
-	var newCommits []*vcs.Commit
-	for iter.Next() {
-		c := iter.Commit()
-		if !s.state.LastCVSSync.IsZero() && !c.Date.After(s.state.LastCVSSync) {
-			continue
+	// Files unchanged since the last complete pass hold no new revisions
+	since := ""
+	if !s.state.LastCVSSync.IsZero() {
+		since = cvspkg.RevisionCursor(s.state.LastCVSSync)
+		if s.state.CVSRevisions == nil {
+			// States saved without the synced revisions resume after the
+			// synced second, as they always did
+			since = cvspkg.RevisionCursor(s.state.LastCVSSync.Add(time.Second))
 		}
-		newCommits = append(newCommits, c)
+		cvsReader.SetKnownFiles(s.state.CVSFiles)
+		cvsReader.SetSyncedRevisions(s.state.CVSRevisions)
+	}
+	newCommits, err := s.readCommits("CVS", cvsReader, func() (vcs.CommitIterator, error) {
+		return cvsReader.GetCommitsSince(since)
+	})
+	if err != nil {
+		return err
 	}
-	if err := iter.Err(); err != nil {
-		return fmt.Errorf("error iterating CVS commits: %w", err)
+	for _, file := range cvsReader.SkippedFiles() {
+		s.Logger().Warn("skipped unreadable RCS file; it is read again on the next sync", "file", file.Path, "error", file.Err)
 	}
 
 	if len(newCommits) == 0 {
 		s.reporter.SetOperation("CVS → Git: up to date")
+		if !s.config.DryRun {
+			s.saveCVSFiles(cvsReader)
+		}
 		return nil
 	}
 
@@ -228,43 +316,149 @@ func (s *Syncer) syncCVSToGit() error {
 
 	if s.config.DryRun {
 		for _, c := range newCommits {
-			log.Printf("DRY RUN: would sync CVS commit %s (%s) to Git", c.Revision, c.Message)
+			s.Logger().Info("dry run: would sync CVS commit to Git", "revision", c.Revision, "message", c.Message)
 		}
 		return nil
 	}
 
 	gitWriter := gitpkg.NewWriter()
+	gitWriter.SetLogger(s.Logger())
 	if err := gitWriter.Open(s.config.GitPath); err != nil {
 		return fmt.Errorf("failed to open git repository: %w", err)
 	}
 	defer func() {
 		if err := gitWriter.Close(); err != nil {
-			log.Printf("Warning: failed to close git writer: %v", err)
+			s.Logger().Warn("failed to close git writer", "error", err)
 		}
 	}()
 
+	s.startProgress(len(newCommits))
 	for _, commit := range newCommits {
+		if err := s.lock.Err(); err != nil {
+			return err
+		}
 		name, email := s.authorMap.Get(commit.Author)
 		commit.Author = name
 		commit.Email = email
 
 		s.reporter.SetOperation(fmt.Sprintf("Applying CVS commit %s to Git", commit.Revision))
 
-		if err := gitWriter.ApplyCommit(commit); err != nil {
+		if err := withTimeout(s.config.Timeouts.Commit, func() error { return gitWriter.ApplyCommit(commit) }, gitWriter); err != nil {
 			return fmt.Errorf("failed to apply CVS commit %s to Git: %w", commit.Revision, err)
 		}
 
-		s.state.LastCVSSync = commit.Date
+		s.recordCVSCommit(commit)
 		s.state.SyncedAt = time.Now()
 		if err := s.saveState(); err != nil {
-			log.Printf("Warning: failed to save sync state: %v", err)
+			s.Logger().Warn("failed to save sync state", "error", err)
 		}
+		s.reporter.Increment()
 	}
 
+	s.saveCVSFiles(cvsReader)
 	s.reporter.SetOperation(fmt.Sprintf("CVS → Git: synced %d commit(s)", len(newCommits)))
 	return nil
 }
 
+// readCommits collects the commits of the iterator that since returns from
+// reader within the SourceScan timeout; side names the repository in errors
+func (s *Syncer) readCommits(side string, reader vcs.VCSReader, since func() (vcs.CommitIterator, error)) ([]*vcs.Commit, error) {
+	var commits []*vcs.Commit
+	err := withTimeout(s.config.Timeouts.SourceScan, func() error {
+		iter, err := since()
+		if err != nil {
+			return fmt.Errorf("failed to get %s commits: %w", side, err)
+		}
+		var read []*vcs.Commit
+		for iter.Next() {
+			read = append(read, iter.Commit())
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("error iterating %s commits: %w", side, err)
+		}
+		commits = read
+		return nil
+	}, reader)
+	if errors.Is(err, vcs.ErrTimeout) {
+		return nil, fmt.Errorf("failed to read %s history: %w", side, err)
+	}
+	return commits, err
+}
+
+// recordCVSCommit moves the CVS → Git cursor to commit, remembering its
+// file revisions alongside the others synced in the same second
+func (s *Syncer) recordCVSCommit(commit *vcs.Commit) {
+	if commit.Date.Before(s.state.LastCVSSync) {
+		return
+	}
+	if commit.Date.After(s.state.LastCVSSync) || s.state.CVSRevisions == nil {
+		s.state.LastCVSSync = commit.Date
+		s.state.CVSRevisions = make(map[string][]string)
+	}
+	for _, f := range commit.Files {
+		s.state.CVSRevisions[f.Path] = append(s.state.CVSRevisions[f.Path], f.Revision)
+	}
+}
+
+// saveCVSFiles records the RCS files read by a complete CVS → Git pass
+func (s *Syncer) saveCVSFiles(reader *cvspkg.Reader) {
+	s.state.CVSFiles = reader.FileStamps()
+	if err := s.saveState(); err != nil {
+		s.Logger().Warn("failed to save sync state", "error", err)
+	}
+}
+
+// startProgress resets the progress to a pass over total commits.
+func (s *Syncer) startProgress(total int) {
+	s.reporter.SetTotal(total)
+	s.reporter.SetCurrent(0)
+	s.reporter.Start()
+}
+
+// openCVSWriter opens the CVS writer selected by the CVSWriter option. The
+// cleanup function, if any, must be called after the writer is closed.
+func (s *Syncer) openCVSWriter() (vcs.VCSWriter, func(), error) {
+	kind := s.config.CVSWriter
+	if kind == CVSWriterAuto {
+		kind = CVSWriterNative
+		if _, err := s.config.CVSClient.LookPath(); err == nil {
+			kind = CVSWriterClient
+		}
+	}
+
+	switch kind {
+	case CVSWriterNative:
+		s.Logger().Debug("using native CVS writer")
+		// The module may be an alias defined in CVSROOT/modules
+		dir, err := cvspkg.ModuleDirectory(s.config.CVSPath, s.config.CVSModule)
+		if err != nil {
+			return nil, nil, err
+		}
+		w := cvspkg.NewNativeWriter(dir)
+		if err := w.Open(s.config.CVSPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to open CVS repository: %w", err)
+		}
+		return w, nil, nil
+	case CVSWriterClient:
+		workDir, cleanup, err := s.prepareCVSWorkDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		w := cvspkg.NewWriter(s.config.CVSPath, s.config.CVSModule)
+		w.SetClient(s.config.CVSClient)
+		w.SetTimeout(s.config.Timeouts.CVSCommand)
+		if err := w.Init(workDir); err != nil {
+			if cleanup != nil {
+				cleanup()
+			}
+			return nil, nil, fmt.Errorf("failed to initialise CVS writer: %w", err)
+		}
+		return w, cleanup, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown CVS writer %q", s.config.CVSWriter)
+	}
+}
+
 // prepareCVSWorkDir returns the CVS working directory path and an optional
 // cleanup function.  When CVSWorkDir is configured it is used directly;
 // otherwise a temporary directory is created.
@@ -279,11 +473,18 @@ func (s *Syncer) prepareCVSWorkDir() (dir string, cleanup func(), err error) {
 	}
 	return tmp, func() {
 		if err := os.RemoveAll(tmp); err != nil {
-			log.Printf("Warning: failed to clean up CVS work directory: %v", err)
+			s.Logger().Warn("failed to clean up CVS work directory", "error", err)
 		}
 	}, nil
 }
 
+// generateSyncID derives a stable identifier from the repository pair.
+func (s *Syncer) generateSyncID() string {
+	data := s.config.GitPath + ":" + s.config.CVSPath + ":" + s.config.CVSModule
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:8])
+}
+
 // loadState reads the sync state from disk.  Missing state file is not an
 // error; it simply means the sync starts from scratch.
 func (s *Syncer) loadState() error {
@@ -318,7 +519,7 @@ func (s *Syncer) saveState() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := os.WriteFile(s.config.StateFile, data, 0600); err != nil {
+	if err := storage.WriteFileAtomic(s.config.StateFile, data, 0600); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 	return nil
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	cvspkg "github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// DriftReport compares the files at the CVS trunk head with the files at the
+// Git HEAD.
+type DriftReport struct {
+	CheckedAt time.Time   `json:"checkedAt"`
+	GitPath   string      `json:"gitPath"`
+	GitHead   string      `json:"gitHead"`
+	CVSPath   string      `json:"cvsPath"`
+	CVSModule string      `json:"cvsModule"`
+	CVSFiles  int         `json:"cvsFiles"`
+	GitFiles  int         `json:"gitFiles"`
+	OnlyInCVS []string    `json:"onlyInCvs"`
+	OnlyInGit []string    `json:"onlyInGit"`
+	Different []DriftFile `json:"different"`
+}
+
+// DriftFile is a file whose content differs between CVS and Git.
+type DriftFile struct {
+	Path        string `json:"path"`
+	CVSRevision string `json:"cvsRevision"`
+	CVSHash     string `json:"cvsHash"` // SHA-256 of the CVS content
+	GitHash     string `json:"gitHash"` // SHA-256 of the Git content
+}
+
+// Drifted reports whether CVS and Git hold different files.
+func (r *DriftReport) Drifted() bool {
+	return len(r.OnlyInCVS) > 0 || len(r.OnlyInGit) > 0 || len(r.Different) > 0
+}
+
+// Summary describes the drift in one line.
+func (r *DriftReport) Summary() string {
+	if !r.Drifted() {
+		return fmt.Sprintf("in sync: %d files match", r.CVSFiles)
+	}
+	return fmt.Sprintf("drift detected: %d differ, %d only in CVS, %d only in Git",
+		len(r.Different), len(r.OnlyInCVS), len(r.OnlyInGit))
+}
+
+// WriteFile writes the report as JSON.
+func (r *DriftReport) WriteFile(name string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// Check compares the CVS trunk head of the module with the Git HEAD file by
+// file without changing either repository or the sync state. Paths matching
+// CheckIgnore are left out.
+func (s *Syncer) Check() (*DriftReport, error) {
+	s.reporter.SetOperation("Checking for drift")
+	for _, pattern := range s.config.CheckIgnore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid check ignore pattern %q: %w", pattern, err)
+		}
+	}
+
+	report := &DriftReport{
+		CheckedAt: time.Now(),
+		GitPath:   s.config.GitPath,
+		CVSPath:   s.config.CVSPath,
+		CVSModule: s.config.CVSModule,
+		OnlyInCVS: []string{},
+		OnlyInGit: []string{},
+		Different: []DriftFile{},
+	}
+
+	cvsFiles, err := s.cvsHeadFiles()
+	if err != nil {
+		return nil, err
+	}
+	gitFiles, err := s.gitHeadFiles(report)
+	if err != nil {
+		return nil, err
+	}
+	report.CVSFiles, report.GitFiles = len(cvsFiles), len(gitFiles)
+
+	for p, cvsFile := range cvsFiles {
+		gitFile, ok := gitFiles[p]
+		if !ok {
+			report.OnlyInCVS = append(report.OnlyInCVS, p)
+			continue
+		}
+		cvsHash, err := hashContent(cvsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CVS file %s: %w", p, err)
+		}
+		gitHash, err := hashContent(gitFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Git file %s: %w", p, err)
+		}
+		if cvsHash != gitHash {
+			report.Different = append(report.Different, DriftFile{
+				Path:        p,
+				CVSRevision: cvsFile.Revision,
+				CVSHash:     cvsHash,
+				GitHash:     gitHash,
+			})
+		}
+	}
+	for p := range gitFiles {
+		if _, ok := cvsFiles[p]; !ok {
+			report.OnlyInGit = append(report.OnlyInGit, p)
+		}
+	}
+
+	sort.Strings(report.OnlyInCVS)
+	sort.Strings(report.OnlyInGit)
+	sort.Slice(report.Different, func(i, j int) bool { return report.Different[i].Path < report.Different[j].Path })
+	s.Logger().Info("drift check finished",
+		"cvs_files", report.CVSFiles,
+		"git_files", report.GitFiles,
+		"different", len(report.Different),
+		"only_in_cvs", len(report.OnlyInCVS),
+		"only_in_git", len(report.OnlyInGit),
+	)
+	return report, nil
+}
+
+// cvsHeadFiles returns the live files a fresh checkout of the CVS trunk
+// holds: the head revision of each file, or the newest revision on its
+// default branch while it has one, as for files only ever updated on the
+// vendor branch by "cvs import".
+func (s *Syncer) cvsHeadFiles() (map[string]vcs.FileChange, error) {
+	reader := cvspkg.NewModuleReader(s.config.CVSPath, s.config.CVSModule)
+	if err := reader.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to open CVS repository: %w", err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			s.Logger().Warn("failed to close CVS reader", "error", err)
+		}
+	}()
+
+	checkout, err := reader.Checkout("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CVS trunk: %w", err)
+	}
+	files := make(map[string]vcs.FileChange, len(checkout))
+	for _, fc := range checkout {
+		if !s.checkIgnored(fc.Path) {
+			files[fc.Path] = fc
+		}
+	}
+	return files, nil
+}
+
+// gitHeadFiles returns the files of the Git HEAD commit.
+func (s *Syncer) gitHeadFiles(report *DriftReport) (map[string]vcs.FileChange, error) {
+	repo, err := s.openGitReadOnly()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := repo.Close(); err != nil {
+			s.Logger().Warn("failed to close git repository", "error", err)
+		}
+	}()
+
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git HEAD: %w", err)
+	}
+	report.GitHead = head
+
+	list, err := repo.BranchFiles("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git files: %w", err)
+	}
+	files := make(map[string]vcs.FileChange, len(list))
+	for _, fc := range list {
+		if !s.checkIgnored(fc.Path) {
+			files[fc.Path] = fc
+		}
+	}
+	return files, nil
+}
+
+// checkIgnored reports whether p matches a CheckIgnore pattern, either as a
+// whole or by its base name.
+func (s *Syncer) checkIgnored(p string) bool {
+	for _, pattern := range s.config.CheckIgnore {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hashContent returns the hex SHA-256 of a file's content.
+func hashContent(fc vcs.FileChange) (string, error) {
+	rc, err := fc.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	cvspkg "github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// writeCVSCommit checks files into module "mod" of the CVS repository at root
+func writeCVSCommit(t *testing.T, root string, files ...vcs.FileChange) {
+	t.Helper()
+	w := cvspkg.NewNativeWriter("mod")
+	require.NoError(t, w.Init(root))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "alice", Date: time.Now(), Message: "change", Files: files}))
+}
+
+func TestSyncerCheck(t *testing.T) {
+	gitDir := createTestGitRepo(t) // README.md = "hello"
+	cvsDir := createTestCVSRepo(t)
+	writeCVSCommit(t, cvsDir, vcs.FileChange{Path: "README.md", Action: vcs.ActionAdd, Content: []byte("hello")})
+
+	stateFile := filepath.Join(t.TempDir(), "sync.json")
+	s := NewSyncer(&SyncConfig{
+		GitPath:   gitDir,
+		CVSPath:   cvsDir,
+		CVSModule: "mod",
+		StateFile: stateFile,
+		Logger:    logging.Discard(),
+	})
+	report, err := s.Check()
+	require.NoError(t, err)
+	require.False(t, report.Drifted(), report.Summary())
+	require.Equal(t, 1, report.CVSFiles)
+	require.Len(t, report.GitHead, 40)
+	require.NoFileExists(t, stateFile)
+
+	writeCVSCommit(t, cvsDir,
+		vcs.FileChange{Path: "README.md", Action: vcs.ActionModify, Content: []byte("changed in CVS")},
+		vcs.FileChange{Path: "src/new.c", Action: vcs.ActionAdd, Content: []byte("x")},
+		vcs.FileChange{Path: "notes.txt", Action: vcs.ActionAdd, Content: []byte("y")},
+	)
+	writeCVSCommit(t, cvsDir, vcs.FileChange{Path: "notes.txt", Action: vcs.ActionDelete})
+
+	report, err = s.Check()
+	require.NoError(t, err)
+	require.True(t, report.Drifted())
+	require.Equal(t, []string{"src/new.c"}, report.OnlyInCVS)
+	require.Empty(t, report.OnlyInGit)
+	require.Len(t, report.Different, 1)
+	require.Equal(t, "README.md", report.Different[0].Path)
+	require.Equal(t, "1.2", report.Different[0].CVSRevision)
+	require.NotEqual(t, report.Different[0].CVSHash, report.Different[0].GitHash)
+	require.Contains(t, report.Summary(), "1 differ, 1 only in CVS")
+
+	reportFile := filepath.Join(t.TempDir(), "drift.json")
+	require.NoError(t, report.WriteFile(reportFile))
+	data, err := os.ReadFile(reportFile)
+	require.NoError(t, err)
+	var decoded DriftReport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, report.Different, decoded.Different)
+
+	s.config.CheckIgnore = []string{"README.md", "src/*"}
+	report, err = s.Check()
+	require.NoError(t, err)
+	require.False(t, report.Drifted(), report.Summary())
+
+	s.config.CheckIgnore = []string{"["}
+	_, err = s.Check()
+	require.Error(t, err)
+}
+
+func TestSyncerCheck_BareRepository(t *testing.T) {
+	bare := filepath.Join(t.TempDir(), "bare.git")
+	_, err := gogit.PlainClone(bare, true, &gogit.CloneOptions{URL: createTestGitRepo(t)})
+	require.NoError(t, err)
+	cvsDir := createTestCVSRepo(t)
+	writeCVSCommit(t, cvsDir, vcs.FileChange{Path: "README.md", Action: vcs.ActionAdd, Content: []byte("hello")})
+
+	// The check only reads Git, so a repository without a worktree will do
+	s := NewSyncer(&SyncConfig{GitPath: bare, CVSPath: cvsDir, CVSModule: "mod", Logger: logging.Discard()})
+	report, err := s.Check()
+	require.NoError(t, err)
+	require.False(t, report.Drifted(), report.Summary())
+	require.Len(t, report.GitHead, 40)
+}
+
+// vendorRCS is a file imported twice with "cvs import": the default branch
+// 1.1.1 holds the current revision 1.1.1.2
+const vendorRCS = `head	1.1;
+branch	1.1.1;
+access;
+symbols
+	start:1.1.1.1
+	vendor:1.1.1;
+locks; strict;
+comment	@# @;
+
+
+1.1
+date	2024.01.01.00.00.00;	author alice;	state Exp;
+branches
+	1.1.1.1;
+next	;
+
+1.1.1.1
+date	2024.01.01.00.00.00;	author alice;	state Exp;
+branches;
+next	1.1.1.2;
+
+1.1.1.2
+date	2024.02.01.00.00.00;	author alice;	state Exp;
+branches;
+next	;
+
+
+desc
+@@
+
+
+1.1
+log
+@Initial revision
+@
+text
+@hello
+@
+
+
+1.1.1.1
+log
+@Import
+@
+text
+@@
+
+
+1.1.1.2
+log
+@Vendor update
+@
+text
+@d1 1
+a1 1
+vendor v2
+@
+`
+
+func TestSyncerCheck_VendorBranch(t *testing.T) {
+	gitDir := createTestGitRepo(t) // README.md = "hello"
+	cvsDir := createTestCVSRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(cvsDir, "mod"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(cvsDir, "mod", "README.md,v"), []byte(vendorRCS), 0444))
+
+	s := NewSyncer(&SyncConfig{GitPath: gitDir, CVSPath: cvsDir, CVSModule: "mod", Logger: logging.Discard()})
+	report, err := s.Check()
+	require.NoError(t, err)
+	require.Len(t, report.Different, 1, report.Summary())
+	require.Equal(t, "1.1.1.2", report.Different[0].CVSRevision)
+}
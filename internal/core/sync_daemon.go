@@ -0,0 +1,410 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	cvspkg "github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// Daemon scheduling defaults.
+const (
+	DefaultSyncInterval     = 5 * time.Minute
+	DefaultSyncPollInterval = 10 * time.Second
+	DefaultSyncDebounce     = time.Second
+	DefaultSyncJitter       = 0.1
+)
+
+// Reasons a daemon sync is started, reported in DaemonHealth.LastTrigger.
+const (
+	TriggerStartup  = "startup"
+	TriggerInterval = "interval"
+	TriggerChange   = "change"
+	TriggerManual   = "manual"
+)
+
+// DaemonConfig controls when a SyncDaemon runs the sync.
+type DaemonConfig struct {
+	Interval     time.Duration // Run at least this often (default: DefaultSyncInterval)
+	Jitter       float64       // Fraction of Interval added or removed at random, 0 to 1 (0 = none)
+	Watch        bool          // Also run when files in the CVS module change
+	PollInterval time.Duration // How often Watch scans the CVS module alongside file system notifications (default: DefaultSyncPollInterval)
+	Debounce     time.Duration // How long the CVS module must be quiet and unlocked before Watch starts a sync (default: DefaultSyncDebounce)
+	HealthAddr   string        // Address of the HTTP health endpoint, e.g. ":8081" (empty = disabled)
+}
+
+// DaemonHealth describes the state of a SyncDaemon.
+type DaemonHealth struct {
+	Status      string    `json:"status"` // starting, idle, syncing or stopped
+	Runs        int       `json:"runs"`
+	Failures    int       `json:"failures"`
+	LastTrigger string    `json:"lastTrigger,omitempty"`
+	LastRun     time.Time `json:"lastRun,omitzero"`
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+	LastError   string    `json:"lastError,omitempty"`
+	NextRun     time.Time `json:"nextRun,omitzero"`
+}
+
+// SyncDaemon runs a sync repeatedly until its context is cancelled. Syncs
+// never overlap: triggers arriving during a sync are coalesced into a single
+// follow-up run.
+type SyncDaemon struct {
+	syncConfig *SyncConfig
+	config     DaemonConfig
+	logger     *slog.Logger
+	triggers   chan string
+
+	// runSync performs one sync; replaced in tests.
+	runSync func() error
+
+	mu     sync.Mutex
+	health DaemonHealth
+}
+
+// NewSyncDaemon creates a daemon running the sync described by syncConfig.
+func NewSyncDaemon(syncConfig *SyncConfig, config DaemonConfig) *SyncDaemon {
+	if config.Interval <= 0 {
+		config.Interval = DefaultSyncInterval
+	}
+	config.Jitter = min(max(config.Jitter, 0), 1)
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultSyncPollInterval
+	}
+	if config.Debounce <= 0 {
+		config.Debounce = DefaultSyncDebounce
+	}
+	d := &SyncDaemon{
+		syncConfig: syncConfig,
+		config:     config,
+		logger:     logging.OrDefault(syncConfig.Logger),
+		triggers:   make(chan string, 1),
+		health:     DaemonHealth{Status: "starting"},
+	}
+	d.runSync = func() error { return NewSyncer(syncConfig).Run() }
+	return d
+}
+
+// Run syncs once immediately and then whenever the interval elapses, the
+// CVS module changes or Trigger is called. When ctx is cancelled Run waits
+// for a sync in progress to finish, stops the health endpoint and returns.
+func (d *SyncDaemon) Run(ctx context.Context) error {
+	if d.config.HealthAddr != "" {
+		stop, err := d.serveHealth()
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if d.config.Watch {
+		go d.watch(ctx)
+	}
+
+	d.logger.Info("sync daemon started",
+		"interval", d.config.Interval,
+		"watch", d.config.Watch,
+		"health_addr", d.config.HealthAddr,
+	)
+
+	reason := TriggerStartup
+	for {
+		// select picks among ready cases at random, so a pending trigger
+		// may win over the cancellation
+		if ctx.Err() != nil {
+			d.mu.Lock()
+			d.health.Status = "stopped"
+			d.health.NextRun = time.Time{}
+			d.mu.Unlock()
+			d.logger.Info("sync daemon stopped")
+			return nil
+		}
+		d.sync(reason)
+
+		next := d.nextDelay()
+		d.setNextRun(time.Now().Add(next))
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+			reason = TriggerInterval
+		case reason = <-d.triggers:
+			timer.Stop()
+		}
+	}
+}
+
+// Trigger requests a sync as soon as the current one, if any, finishes.
+func (d *SyncDaemon) Trigger(reason string) {
+	select {
+	case d.triggers <- reason:
+	default:
+		// A sync is already pending
+	}
+}
+
+// Health returns a snapshot of the daemon state.
+func (d *SyncDaemon) Health() DaemonHealth {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.health
+}
+
+// HealthHandler serves the daemon state as JSON. The status code is 503
+// when the last sync failed.
+func (d *SyncDaemon) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := d.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if health.LastError != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			d.logger.Warn("failed to encode health response", "error", err)
+		}
+	})
+}
+
+func (d *SyncDaemon) sync(reason string) {
+	d.mu.Lock()
+	d.health.Status = "syncing"
+	d.health.LastTrigger = reason
+	d.health.LastRun = time.Now()
+	d.mu.Unlock()
+
+	d.logger.Info("daemon sync started", "trigger", reason)
+	err := d.runSync()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.health.Status = "idle"
+	d.health.Runs++
+	if err != nil {
+		d.health.Failures++
+		d.health.LastError = err.Error()
+		d.logger.Error("daemon sync failed", "trigger", reason, "error", err)
+		return
+	}
+	d.health.LastError = ""
+	d.health.LastSuccess = time.Now()
+	d.logger.Info("daemon sync finished", "trigger", reason)
+}
+
+func (d *SyncDaemon) setNextRun(t time.Time) {
+	d.mu.Lock()
+	d.health.NextRun = t
+	d.mu.Unlock()
+}
+
+// nextDelay returns the interval with jitter applied, so that several
+// daemons started together do not hit the repositories at the same time.
+func (d *SyncDaemon) nextDelay() time.Duration {
+	spread := time.Duration(float64(d.config.Interval) * d.config.Jitter)
+	if spread <= 0 {
+		return d.config.Interval
+	}
+	return d.config.Interval - spread + rand.N(2*spread+1) //nolint:gosec // scheduling only
+}
+
+// watch triggers a sync when the RCS files of the CVS module change. File
+// system notifications report changes as they happen; polling runs alongside
+// them, as notifications miss changes made by other hosts of a network file
+// system.
+func (d *SyncDaemon) watch(ctx context.Context) {
+	changes := make(chan struct{}, 1)
+	changed := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+			// A change is already pending
+		}
+	}
+	go d.poll(ctx, changed)
+
+	events, err := cvspkg.NewModuleReader(d.syncConfig.CVSPath, d.syncConfig.CVSModule).Watch(ctx)
+	if err != nil {
+		d.logger.Warn("file system notifications unavailable; polling the CVS module", "error", err, "interval", d.config.PollInterval)
+	} else {
+		go func() {
+			for event := range events {
+				if event.Err != nil {
+					d.logger.Warn("CVS module watch error", "error", event.Err)
+				} else {
+					d.logger.Debug("CVS module changed", "file", event.Path, "op", event.Op)
+				}
+				changed() // Changes may have been missed on errors
+			}
+		}()
+	}
+	d.debounce(ctx, changes)
+}
+
+// debounce triggers a sync once the changes have stopped for Debounce and
+// no CVS lock is held in the module, so a commit still being written is
+// not read half done.
+func (d *SyncDaemon) debounce(ctx context.Context, changes <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+		}
+		timer := time.NewTimer(d.config.Debounce)
+	quiet:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-changes:
+				timer.Reset(d.config.Debounce)
+			case <-timer.C:
+				if !d.moduleLocked() {
+					break quiet
+				}
+				d.logger.Debug("waiting for the CVS lock to be released", "module", d.syncConfig.CVSModule)
+				timer.Reset(d.config.Debounce)
+			}
+		}
+		d.Trigger(TriggerChange)
+	}
+}
+
+// poll scans the CVS module every PollInterval and calls changed when its
+// RCS files change.
+func (d *SyncDaemon) poll(ctx context.Context, changed func()) {
+	module := d.syncConfig.CVSModule
+	last, err := d.moduleFingerprint()
+	if err != nil {
+		d.logger.Warn("failed to scan CVS module", "module", module, "error", err)
+	}
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := d.moduleFingerprint()
+		if err != nil {
+			d.logger.Warn("failed to scan CVS module", "module", module, "error", err)
+			continue
+		}
+		if current != last {
+			d.logger.Debug("CVS module changed", "module", module)
+			last = current
+			changed()
+		}
+	}
+}
+
+// moduleLocked reports whether a CVS master or write lock is held in a
+// directory of the CVS module, as while a commit is written
+func (d *SyncDaemon) moduleLocked() bool {
+	dirs, err := cvspkg.ResolveModule(d.syncConfig.CVSPath, d.syncConfig.CVSModule)
+	if err != nil {
+		return false
+	}
+	for _, md := range dirs {
+		locked := false
+		_ = filepath.WalkDir(filepath.Join(d.syncConfig.CVSPath, md.Dir), func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Removed meanwhile
+			}
+			if name := entry.Name(); strings.HasPrefix(name, "#cvs.lock") || strings.HasPrefix(name, "#cvs.wfl") {
+				locked = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if locked {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleFingerprint summarizes the RCS files of the directories of the CVS
+// module, which may be defined in CVSROOT/modules
+func (d *SyncDaemon) moduleFingerprint() (string, error) {
+	dirs, err := cvspkg.ResolveModule(d.syncConfig.CVSPath, d.syncConfig.CVSModule)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(dirs))
+	for _, md := range dirs {
+		fp, err := cvsFingerprint(filepath.Join(d.syncConfig.CVSPath, md.Dir))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fp)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// cvsFingerprint summarizes the RCS files below root by count, total size
+// and newest modification time. Any commit changes at least one of them.
+func cvsFingerprint(root string) (string, error) {
+	var count, size int64
+	var newest time.Time
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ",v") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		count++
+		size += info.Size()
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%d", count, size, newest.UnixNano()), nil
+}
+
+// serveHealth starts the health endpoint and returns a function stopping it.
+func (d *SyncDaemon) serveHealth() (func(), error) {
+	listener, err := net.Listen("tcp", d.config.HealthAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", d.config.HealthAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/health", d.HealthHandler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			d.logger.Error("health endpoint failed", "error", err)
+		}
+	}()
+	d.logger.Info("health endpoint listening", "addr", listener.Addr().String())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			d.logger.Warn("failed to stop health endpoint", "error", err)
+		}
+	}, nil
+}
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDaemon(config DaemonConfig, run func() error) *SyncDaemon {
+	d := NewSyncDaemon(&SyncConfig{Logger: logging.Discard()}, config)
+	d.runSync = run
+	return d
+}
+
+func TestSyncDaemon_IntervalAndShutdown(t *testing.T) {
+	var runs atomic.Int32
+	d := newTestDaemon(DaemonConfig{Interval: 10 * time.Millisecond}, func() error {
+		runs.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	health := d.Health()
+	require.Equal(t, "stopped", health.Status)
+	require.GreaterOrEqual(t, health.Runs, 3)
+	require.Zero(t, health.Failures)
+	require.Equal(t, TriggerInterval, health.LastTrigger)
+}
+
+func TestSyncDaemon_TriggersDoNotOverlap(t *testing.T) {
+	var running, overlaps, runs atomic.Int32
+	release := make(chan struct{})
+	d := newTestDaemon(DaemonConfig{Interval: time.Hour}, func() error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer running.Add(-1)
+		if runs.Add(1) == 1 {
+			<-release
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	// Triggers during the startup sync are coalesced into one follow-up run
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, 5*time.Second, time.Millisecond)
+	d.Trigger(TriggerManual)
+	d.Trigger(TriggerManual)
+	d.Trigger(TriggerManual)
+	close(release)
+
+	require.Eventually(t, func() bool { return d.Health().Runs == 2 }, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, int32(2), runs.Load())
+	require.Zero(t, overlaps.Load())
+	require.Equal(t, TriggerManual, d.Health().LastTrigger)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestSyncDaemon_NoSyncAfterCancel(t *testing.T) {
+	var runs atomic.Int32
+	var cancel context.CancelFunc
+	var d *SyncDaemon
+	d = newTestDaemon(DaemonConfig{Interval: time.Hour}, func() error {
+		runs.Add(1)
+		// Shutdown arrives together with a trigger
+		d.Trigger(TriggerManual)
+		cancel()
+		return nil
+	})
+	for i := range 20 {
+		ctx, stop := context.WithCancel(context.Background())
+		cancel = stop
+		require.NoError(t, d.Run(ctx))
+		stop()
+		require.Equal(t, int32(i+1), runs.Load(), "the pending trigger starts no sync")
+		select {
+		case <-d.triggers:
+		default:
+		}
+	}
+	require.Equal(t, "stopped", d.Health().Status)
+}
+
+func TestSyncDaemon_HealthHandler(t *testing.T) {
+	fail := errors.New("cvs unavailable")
+	d := newTestDaemon(DaemonConfig{}, func() error { return fail })
+	d.sync(TriggerManual)
+
+	rec := httptest.NewRecorder()
+	d.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var health DaemonHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	require.Equal(t, 1, health.Failures)
+	require.Equal(t, "cvs unavailable", health.LastError)
+
+	d.runSync = func() error { return nil }
+	d.sync(TriggerManual)
+	rec = httptest.NewRecorder()
+	d.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 2, d.Health().Runs)
+	require.False(t, d.Health().LastSuccess.IsZero())
+}
+
+func TestSyncDaemon_NextDelayJitter(t *testing.T) {
+	d := newTestDaemon(DaemonConfig{Interval: time.Minute, Jitter: 0.5}, nil)
+	for range 100 {
+		delay := d.nextDelay()
+		require.GreaterOrEqual(t, delay, 30*time.Second)
+		require.LessOrEqual(t, delay, 90*time.Second)
+	}
+
+	d = newTestDaemon(DaemonConfig{Interval: time.Minute}, nil)
+	require.Equal(t, time.Minute, d.nextDelay())
+}
+
+func TestCVSFingerprint(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt,v"), []byte("head 1.1;"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("ignored"), 0644))
+
+	before, err := cvsFingerprint(root)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("still ignored"), 0644))
+	same, err := cvsFingerprint(root)
+	require.NoError(t, err)
+	require.Equal(t, before, same)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt,v"), []byte("head 1.2;"), 0644))
+	after, err := cvsFingerprint(root)
+	require.NoError(t, err)
+	require.NotEqual(t, before, after)
+
+	_, err = cvsFingerprint(filepath.Join(root, "missing"))
+	require.Error(t, err)
+}
+
+func TestSyncDaemon_WatchTriggersSync(t *testing.T) {
+	root := t.TempDir()
+	rcs := filepath.Join(root, "mod", "a.txt,v")
+	require.NoError(t, os.MkdirAll(filepath.Dir(rcs), 0755))
+	require.NoError(t, os.WriteFile(rcs, []byte("head 1.1;"), 0644))
+
+	var runs atomic.Int32
+	d := NewSyncDaemon(&SyncConfig{CVSPath: root, CVSModule: "mod", Logger: logging.Discard()},
+		DaemonConfig{Interval: time.Hour, Watch: true, PollInterval: 5 * time.Millisecond, Debounce: 5 * time.Millisecond})
+	d.runSync = func() error { runs.Add(1); return nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, os.WriteFile(rcs, []byte("head 1.2; longer"), 0644))
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, 5*time.Second, time.Millisecond)
+	require.Equal(t, TriggerChange, d.Health().LastTrigger)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestSyncDaemon_WatchFallsBackToPolling(t *testing.T) {
+	root := t.TempDir()
+	var runs atomic.Int32
+	d := NewSyncDaemon(&SyncConfig{CVSPath: root, CVSModule: "mod", Logger: logging.Discard()},
+		DaemonConfig{Interval: time.Hour, Watch: true, PollInterval: 5 * time.Millisecond, Debounce: 5 * time.Millisecond})
+	d.runSync = func() error { runs.Add(1); return nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	// The missing module cannot be watched, but polling notices it appear
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	rcs := filepath.Join(root, "mod", "a.txt,v")
+	require.NoError(t, os.MkdirAll(filepath.Dir(rcs), 0755))
+	require.NoError(t, os.WriteFile(rcs, []byte("head 1.1;"), 0644))
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestSyncDaemon_WatchWaitsForCVSLock(t *testing.T) {
+	root := t.TempDir()
+	rcs := filepath.Join(root, "mod", "a.txt,v")
+	require.NoError(t, os.MkdirAll(filepath.Dir(rcs), 0755))
+	require.NoError(t, os.WriteFile(rcs, []byte("head 1.1;"), 0644))
+
+	var runs atomic.Int32
+	d := NewSyncDaemon(&SyncConfig{CVSPath: root, CVSModule: "mod", Logger: logging.Discard()},
+		DaemonConfig{Interval: time.Hour, Watch: true, PollInterval: 5 * time.Millisecond, Debounce: 10 * time.Millisecond})
+	d.runSync = func() error { runs.Add(1); return nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// A commit in progress holds the lock while it rewrites the file
+	lock := filepath.Join(root, "mod", "#cvs.lock")
+	require.NoError(t, os.Mkdir(lock, 0755))
+	require.NoError(t, os.WriteFile(rcs, []byte("head 1.2; longer"), 0644))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int32(1), runs.Load(), "no sync while the module is locked")
+
+	require.NoError(t, os.Remove(lock))
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, 5*time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(2), runs.Load(), "the changes start a single sync")
+
+	cancel()
+	require.NoError(t, <-done)
+}
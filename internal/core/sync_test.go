@@ -11,6 +11,8 @@ import (
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
+
+	cvspkg "github.com/adamf123git/git-migrator/internal/vcs/cvs"
 )
 
 // createTestGitRepo initialises a minimal Git repo in dir with one commit and
@@ -520,3 +522,104 @@ func TestSyncerSaveState_WriteError(t *testing.T) {
 	err := s.saveState()
 	require.Error(t, err, "saveState should fail when the directory does not exist")
 }
+
+// TestSyncerSyncGitToCVS_NativeWriter syncs Git commits into a CVS
+// repository without the cvs client and reads them back.
+func TestSyncerSyncGitToCVS_NativeWriter(t *testing.T) {
+	gitDir := createTestGitRepo(t)
+	cvsDir := createTestCVSRepo(t)
+
+	s := NewSyncer(&SyncConfig{
+		GitPath:   gitDir,
+		CVSPath:   cvsDir,
+		CVSModule: "mod",
+		CVSWriter: CVSWriterNative,
+		Direction: SyncGitToCVS,
+		StateFile: filepath.Join(t.TempDir(), "sync.json"),
+	})
+	require.NoError(t, s.Run())
+
+	iter, err := cvspkg.NewModuleReader(cvsDir, "mod").GetCommits()
+	require.NoError(t, err)
+	require.True(t, iter.Next())
+	commit := iter.Commit()
+	require.Equal(t, "initial commit\n", commit.Message)
+	require.Len(t, commit.Files, 1)
+	content, err := commit.Files[0].ReadContent()
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(content))
+	require.False(t, iter.Next())
+
+	s.config.CVSWriter = "rsh"
+	s.state.LastGitCommit = ""
+	require.ErrorContains(t, s.syncGitToCVS(), "unknown CVS writer")
+}
+
+// TestSyncerSyncCVSToGit_Incremental checks that a pass only reads the RCS
+// files changed since the previous one
+func TestSyncerSyncCVSToGit_Incremental(t *testing.T) {
+	gitDir := createTestGitRepo(t)
+	cvsDir := createTestCVSRepo(t)
+	rcsPath := filepath.Join(cvsDir, "a.txt,v")
+	rcs := "head\t1.1;\naccess;\nsymbols;\nlocks; strict;\n\n1.1\ndate\t2024.01.01.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\n\ndesc\n@@\n\n1.1\nlog\n@first@\ntext\n@one\n@\n"
+	require.NoError(t, os.WriteFile(rcsPath, []byte(rcs), 0644))
+
+	stateFile := filepath.Join(t.TempDir(), "sync.json")
+	config := &SyncConfig{GitPath: gitDir, CVSPath: cvsDir, Direction: SyncCVSToGit, StateFile: stateFile}
+	s := NewSyncer(config)
+	require.NoError(t, s.Run())
+	require.Contains(t, s.state.CVSFiles, "a.txt,v")
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), s.state.LastCVSSync.UTC())
+
+	// An unchanged file is not read again, even if its history looks new
+	s = NewSyncer(config)
+	require.NoError(t, s.loadState())
+	s.state.LastCVSSync = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.saveState())
+	require.NoError(t, s.Run())
+	require.Equal(t, 2023, s.state.LastCVSSync.Year())
+}
+
+// TestSyncerSyncCVSToGit_SameSecond checks that a commit dated in the second
+// of the last synced one is synced by the next pass, and only once
+func TestSyncerSyncCVSToGit_SameSecond(t *testing.T) {
+	gitDir := createTestGitRepo(t)
+	cvsDir := createTestCVSRepo(t)
+	rcs := func(author, text string) []byte {
+		return []byte("head\t1.1;\naccess;\nsymbols;\nlocks; strict;\n\n1.1\ndate\t2024.01.01.00.00.00;\tauthor " + author +
+			";\tstate Exp;\nbranches;\nnext\t;\n\ndesc\n@@\n\n1.1\nlog\n@" + text + "@\ntext\n@" + text + "\n@\n")
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(cvsDir, "a.txt,v"), rcs("alice", "one"), 0644))
+
+	config := &SyncConfig{GitPath: gitDir, CVSPath: cvsDir, Direction: SyncCVSToGit, StateFile: filepath.Join(t.TempDir(), "sync.json")}
+	sync := func() int {
+		s := NewSyncer(config)
+		require.NoError(t, s.Run())
+		repo, err := gogit.PlainOpen(gitDir)
+		require.NoError(t, err)
+		log, err := repo.Log(&gogit.LogOptions{})
+		require.NoError(t, err)
+		commits := 0
+		require.NoError(t, log.ForEach(func(*object.Commit) error { commits++; return nil }))
+		return commits
+	}
+	require.Equal(t, 2, sync())
+
+	require.NoError(t, os.WriteFile(filepath.Join(cvsDir, "b.txt,v"), rcs("bob", "two"), 0644))
+	require.Equal(t, 3, sync())
+	require.Equal(t, 3, sync())
+
+	// A rewritten file gives its synced revisions again
+	a2 := "head\t1.2;\naccess;\nsymbols;\nlocks; strict;\n\n" +
+		"1.2\ndate\t2024.01.01.00.00.00;\tauthor carol;\tstate Exp;\nbranches;\nnext\t1.1;\n\n" +
+		"1.1\ndate\t2024.01.01.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\n\n" +
+		"desc\n@@\n\n1.2\nlog\n@three@\ntext\n@three\n@\n\n1.1\nlog\n@one@\ntext\n@d1 1\na1 1\none\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(cvsDir, "a.txt,v"), []byte(a2), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(cvsDir, "a.txt,v"), later, later))
+	require.Equal(t, 4, sync())
+
+	s := NewSyncer(config)
+	require.NoError(t, s.loadState())
+	require.Equal(t, map[string][]string{"a.txt": {"1.1", "1.2"}, "b.txt": {"1.1"}}, s.state.CVSRevisions)
+}
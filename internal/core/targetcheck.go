@@ -0,0 +1,163 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// freeDiskSpace returns the bytes available to this process on the file
+// system holding path; replaced in tests
+var freeDiskSpace = diskFree
+
+// checkTarget runs the safety checks on the target directory before the
+// repository is created or opened, and reports whether it must be created.
+// It refuses a target inside the source tree, a non-empty directory that
+// is not a repository unless AllowExisting is set, and a new repository on
+// a file system without room for it.
+func (m *Migrator) checkTarget() (bool, error) {
+	target := m.config.TargetPath
+	if inside, source := m.targetInSource(); inside {
+		return false, fmt.Errorf("target %s is inside the source tree %s; choose a target outside of it", target, source)
+	}
+
+	info, err := os.Stat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, m.checkDiskSpace()
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check target %s: %w", target, err)
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("target %s is not a directory", target)
+	}
+
+	// Without a way to tell repositories apart, any existing directory is
+	// opened as one
+	checker, ok := m.target.(interface{ IsRepo(path string) bool })
+	if !ok || checker.IsRepo(target) {
+		return false, nil
+	}
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return false, fmt.Errorf("failed to read target %s: %w", target, err)
+	}
+	if len(entries) > 0 && !m.config.AllowExisting {
+		return false, fmt.Errorf("target %s is a non-empty directory that is not a repository; empty it, choose another target or allow existing files (--allow-existing)", target)
+	}
+	return true, m.checkDiskSpace()
+}
+
+// sourceDir returns the local directory holding the migrated source, or ""
+// if the source is not a local directory
+func (m *Migrator) sourceDir() string {
+	if m.config.SourceModule != "" {
+		dir := filepath.Join(m.config.SourcePath, m.config.SourceModule)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	if info, err := os.Stat(m.config.SourcePath); err == nil && info.IsDir() {
+		return m.config.SourcePath
+	}
+	return ""
+}
+
+// targetInSource reports whether the target lies within the local source
+// tree, following symbolic links, and returns the source tree
+func (m *Migrator) targetInSource() (bool, string) {
+	if info, err := os.Stat(m.config.SourcePath); err != nil || !info.IsDir() {
+		return false, ""
+	}
+	source, err := realPath(m.config.SourcePath)
+	if err != nil {
+		return false, ""
+	}
+	target, err := realPath(m.config.TargetPath)
+	if err != nil {
+		return false, ""
+	}
+	rel, err := filepath.Rel(source, target)
+	if err != nil {
+		return false, ""
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))), m.config.SourcePath
+}
+
+// realPath returns the absolute path with symbolic links resolved, for a
+// path that need not exist yet
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// checkDiskSpace fails if the file system of the target has less room than
+// the source tree takes up, which the new repository is estimated to need
+func (m *Migrator) checkDiskSpace() error {
+	source := m.sourceDir()
+	if source == "" {
+		return nil
+	}
+	needed, err := dirSize(source)
+	if err != nil {
+		m.Logger().Warn("failed to estimate repository size, skipping disk space check", "error", err)
+		return nil
+	}
+	dir := m.config.TargetPath
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		m.Logger().Debug("free disk space unknown, skipping disk space check", "error", err)
+		return nil
+	}
+	if free < needed {
+		return fmt.Errorf("not enough disk space for target %s: %s free, about %s needed (the size of %s); free up space or choose a target on another file system",
+			m.config.TargetPath, formatMiB(free), formatMiB(needed), source)
+	}
+	return nil
+}
+
+// dirSize returns the size of the regular files below dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// formatMiB formats a byte count in MiB
+func formatMiB(n int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
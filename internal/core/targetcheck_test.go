@@ -0,0 +1,71 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func targetCheckMigrator(t *testing.T, source, target string) *Migrator {
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: source, TargetPath: target,
+		StateFile: filepath.Join(t.TempDir(), "state.db"), Logger: logging.Discard()})
+	m.source = &mockReaderWithCommits{commits: integrityTestCommits()}
+	return m
+}
+
+func TestRun_TargetInsideSource(t *testing.T) {
+	source := t.TempDir()
+	m := targetCheckMigrator(t, source, filepath.Join(source, "module", "repo"))
+	require.ErrorContains(t, m.Run(), "is inside the source tree "+source)
+
+	// Symbolic links do not hide the source
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(source, link))
+	m = targetCheckMigrator(t, source, filepath.Join(link, "repo"))
+	require.ErrorContains(t, m.Run(), "is inside the source tree")
+
+	// A sibling sharing the name prefix is outside
+	m = targetCheckMigrator(t, source, source+"-git")
+	require.NoError(t, m.Run())
+}
+
+func TestRun_TargetNotEmpty(t *testing.T) {
+	target := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(target, "notes.txt"), []byte("x"), 0o644))
+
+	m := targetCheckMigrator(t, t.TempDir(), target)
+	require.ErrorContains(t, m.Run(), "is a non-empty directory that is not a repository")
+
+	m = targetCheckMigrator(t, t.TempDir(), target)
+	m.config.AllowExisting = true
+	require.NoError(t, m.Run())
+	require.Equal(t, 4, m.Report().Commits.Applied)
+
+	// The repository created there is reopened by later runs
+	m = targetCheckMigrator(t, t.TempDir(), target)
+	require.NoError(t, m.Run())
+}
+
+func TestRun_TargetEmptyDirectory(t *testing.T) {
+	m := targetCheckMigrator(t, t.TempDir(), t.TempDir())
+	require.NoError(t, m.Run())
+	require.Equal(t, 4, m.Report().Commits.Applied)
+}
+
+func TestRun_TargetDiskSpace(t *testing.T) {
+	source := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(source, "f.txt,v"), make([]byte, 3<<20), 0o644))
+	defer func(orig func(string) (int64, error)) { freeDiskSpace = orig }(freeDiskSpace)
+
+	freeDiskSpace = func(string) (int64, error) { return 1 << 20, nil }
+	m := targetCheckMigrator(t, source, filepath.Join(t.TempDir(), "new", "repo"))
+	require.ErrorContains(t, m.Run(), "1.0 MiB free, about 3.0 MiB needed")
+
+	freeDiskSpace = func(string) (int64, error) { return 0, errors.ErrUnsupported }
+	m = targetCheckMigrator(t, source, filepath.Join(t.TempDir(), "repo"))
+	require.NoError(t, m.Run(), "an unknown free space skips the check")
+}
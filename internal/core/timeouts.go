@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Timeouts limits how long the phases of a migration or sync may take, so
+// that a hung file system or a stuck cvs process fails the run instead of
+// stalling it. Zero durations never time out.
+type Timeouts struct {
+	SourceScan time.Duration // Reading the history of the source
+	Commit     time.Duration // Applying one commit to the target, retries included
+	Refs       time.Duration // Creating the branches, and then the tags
+	CVSCommand time.Duration // One call of the cvs client
+}
+
+// withTimeout runs op with a deadline of timeout. The readers and writers
+// of uses implementing vcs.ContextSetter get a context done at the deadline
+// and stop early; others run to the end. Either way op has returned when
+// withTimeout does, so a timed-out operation never runs on behind the
+// caller. An op failing after the deadline fails with vcs.ErrTimeout; one
+// that completed anyway succeeds, so that its result is recorded.
+func withTimeout(timeout time.Duration, op func() error, uses ...any) error {
+	if timeout <= 0 {
+		return op()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, use := range uses {
+		if setter, ok := use.(vcs.ContextSetter); ok {
+			setter.SetContext(ctx)
+			defer setter.SetContext(nil)
+		}
+	}
+
+	err := op()
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w after %s", vcs.ErrTimeout, timeout)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeout(t *testing.T) {
+	failed := errors.New("failed")
+	require.ErrorIs(t, withTimeout(0, func() error { return failed }), failed)
+	require.ErrorIs(t, withTimeout(time.Minute, func() error { return failed }), failed)
+
+	// Operations that cannot stop early are waited for, and keep their
+	// result if they complete
+	finished := false
+	require.NoError(t, withTimeout(10*time.Millisecond, func() error { time.Sleep(30 * time.Millisecond); finished = true; return nil }))
+	require.True(t, finished)
+	err := withTimeout(10*time.Millisecond, func() error { time.Sleep(30 * time.Millisecond); return failed })
+	require.ErrorIs(t, err, vcs.ErrTimeout)
+	require.EqualError(t, err, "timed out after 10ms")
+
+	// Readers and writers implementing vcs.ContextSetter stop at the deadline
+	r := &hangingReader{}
+	start := time.Now()
+	err = withTimeout(10*time.Millisecond, func() error { _, err := r.GetCommits(); return err }, r)
+	require.ErrorIs(t, err, vcs.ErrTimeout)
+	require.Less(t, time.Since(start), 10*time.Second)
+	require.Nil(t, r.ctx, "the context is taken back")
+}
+
+// hangingReader is a source whose history cannot be read until its context
+// is done, like one on a hung NFS mount
+type hangingReader struct {
+	mockReaderWithCommits
+	ctx context.Context
+}
+
+func (r *hangingReader) SetContext(ctx context.Context) { r.ctx = ctx }
+
+func (r *hangingReader) GetCommits() (vcs.CommitIterator, error) {
+	<-r.ctx.Done()
+	return nil, r.ctx.Err()
+}
+
+func TestRun_SourceScanTimeout(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs", SourcePath: "/src", TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Timeouts: Timeouts{SourceScan: 20 * time.Millisecond}, Logger: logging.Discard(),
+	})
+	m.source = &hangingReader{}
+
+	err := m.Run()
+	require.ErrorIs(t, err, vcs.ErrTimeout)
+	require.ErrorContains(t, err, "failed to read source history")
+}
+
+// hangingWriter is a Git writer whose commits do not finish until their
+// context is done
+type hangingWriter struct {
+	*git.Writer
+	ctx context.Context
+}
+
+func (w *hangingWriter) SetContext(ctx context.Context) { w.ctx = ctx }
+
+func (w *hangingWriter) ApplyCommit(commit *vcs.Commit) error {
+	<-w.ctx.Done()
+	return w.ctx.Err()
+}
+
+func init() {
+	vcs.RegisterWriter("hanging-test", func(map[string]string) (vcs.VCSWriter, error) {
+		return &hangingWriter{Writer: git.NewWriter()}, nil
+	})
+}
+
+func TestRun_CommitTimeout(t *testing.T) {
+	commits := []*vcs.Commit{{Revision: "1.1", Author: "a", Date: time.Now(), Message: "m",
+		Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}}}
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs", SourcePath: "/src", TargetType: "hanging-test", TargetPath: filepath.Join(t.TempDir(), "repo"),
+		Timeouts: Timeouts{Commit: 20 * time.Millisecond}, Logger: logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+
+	err := m.Run()
+	require.ErrorIs(t, err, vcs.ErrTimeout)
+	require.ErrorContains(t, err, "failed to apply commit 1.1: timed out after 20ms")
+}
+
+func TestRun_CommitTimeoutEndsContinueOnError(t *testing.T) {
+	commits := []*vcs.Commit{
+		{Revision: "1.1", Author: "a", Date: time.Now(), Message: "m", Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x")}}},
+		{Revision: "1.2", Author: "a", Date: time.Now().Add(time.Minute), Message: "m", Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionModify, Content: []byte("y")}}},
+	}
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs", SourcePath: "/src", TargetType: "hanging-test", TargetPath: filepath.Join(t.TempDir(), "repo"),
+		ErrorPolicy: ErrorPolicyContinue, Timeouts: Timeouts{Commit: 20 * time.Millisecond}, Logger: logging.Discard(),
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+
+	err := m.Run()
+	require.ErrorIs(t, err, vcs.ErrTimeout)
+	require.ErrorContains(t, err, "failed to apply commit 1.1")
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+)
+
+// UmbrellaConfig wires the modules of a batch together in an umbrella
+// repository once all of them are migrated
+type UmbrellaConfig struct {
+	Path string // Path of the umbrella repository
+	Mode string // git.UmbrellaSubmodule (default) or git.UmbrellaSubtree
+}
+
+// UmbrellaReport records where every module ended up in the umbrella
+// repository. It is written next to the umbrella repository as JSON.
+type UmbrellaReport struct {
+	Path    string          `json:"path"`
+	Mode    string          `json:"mode"`
+	Commit  string          `json:"commit,omitempty"` // Umbrella commit written by this run, if any
+	Modules []UmbrellaEntry `json:"modules"`
+}
+
+// UmbrellaEntry maps a module to its place in the umbrella repository
+type UmbrellaEntry struct {
+	Module     string `json:"module"`
+	Path       string `json:"path"`          // Directory in the umbrella repository
+	Repository string `json:"repository"`    // Migrated repository
+	URL        string `json:"url,omitempty"` // Submodule URL
+	Commit     string `json:"commit"`        // Module commit the umbrella records
+}
+
+// writeUmbrella wires the modules of a batch into the umbrella repository
+// and writes the umbrella report. The umbrella commit uses the committer of
+// the first module's configuration.
+func writeUmbrella(config *BatchConfig) (*UmbrellaReport, error) {
+	umbrella := config.Umbrella
+	mode := umbrella.Mode
+	if mode == "" {
+		mode = git.UmbrellaSubmodule
+	}
+	report := &UmbrellaReport{Path: umbrella.Path, Mode: mode, Modules: []UmbrellaEntry{}}
+
+	var modules []git.UmbrellaModule
+	opts := git.UmbrellaOptions{Mode: mode}
+	for i, module := range config.Modules {
+		migrationConfig := config.ModuleConfig(module)
+		entry := git.UmbrellaModule{Name: module, Path: module, Repository: migrationConfig.TargetPath}
+		if migrationConfig.Push != nil {
+			entry.URL = migrationConfig.Push.URL
+		}
+		modules = append(modules, entry)
+
+		if i == 0 && migrationConfig.Committer != "" {
+			name, email, err := mapping.ParseAuthor(migrationConfig.Committer)
+			if err != nil {
+				return nil, fmt.Errorf("invalid committer: %w", err)
+			}
+			opts.Name, opts.Email = name, email
+		}
+	}
+
+	result, err := git.WriteUmbrella(umbrella.Path, modules, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.Commit = result.Commit
+	for _, module := range modules {
+		url := ""
+		if mode == git.UmbrellaSubmodule {
+			url = module.URL
+			if url == "" {
+				url = module.Repository
+			}
+		}
+		report.Modules = append(report.Modules, UmbrellaEntry{
+			Module:     module.Name,
+			Path:       module.Path,
+			Repository: module.Repository,
+			URL:        url,
+			Commit:     result.Heads[module.Name],
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(ReportPath(umbrella.Path)+ReportExtJSON, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write umbrella report: %w", err)
+	}
+	return report, nil
+}
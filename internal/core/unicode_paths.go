@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization forms of MigrationConfig.UnicodeForm
+const (
+	// NormalizeNFC composes characters, as Windows and Linux tools write
+	// them: "é" is one code point
+	NormalizeNFC = "nfc"
+	// NormalizeNFD decomposes characters, as older macOS file systems
+	// write them: "é" is "e" followed by a combining accent
+	NormalizeNFD = "nfd"
+)
+
+// ReportNormalized is a path of the history rewritten to the configured
+// Unicode normalization form
+type ReportNormalized struct {
+	Path       string `json:"path"`
+	Normalized string `json:"normalized"`
+}
+
+// validatePathNormalization checks the configured normalization form
+func (m *Migrator) validatePathNormalization() error {
+	switch m.config.UnicodeForm {
+	case "", NormalizeNFC, NormalizeNFD:
+		return nil
+	default:
+		return fmt.Errorf("unsupported path normalization: %s", m.config.UnicodeForm)
+	}
+}
+
+// normalizePaths rewrites every path of the history to the Unicode
+// normalization form of UnicodeForm, so a file committed from macOS
+// and from Windows keeps one path. Spellings that exist at the same time on
+// a branch are merged into one file with a warning, and deleting one of them
+// keeps the file while another is still live. Without a form it warns about
+// paths that differ from another path only by normalization.
+func (m *Migrator) normalizePaths(commits []*vcs.Commit) {
+	form := norm.NFC
+	if m.config.UnicodeForm == NormalizeNFD {
+		form = norm.NFD
+	}
+
+	report := m.currentReport()
+	seen := make(map[string]string) // Source path -> normalized path
+	spellings := make(map[string]int)
+	live := make(map[string]unicodeSpellings) // Branch -> spellings live on it
+	merged := make(map[string]bool)           // Normalized paths warned about
+	for _, commit := range commits {
+		branch := live[commit.Branch]
+		if branch == nil {
+			branch = live[""].clone()
+			live[commit.Branch] = branch
+		}
+		files := commit.Files[:0]
+		for _, fc := range commit.Files {
+			normalized, ok := seen[fc.Path]
+			if !ok {
+				normalized = form.String(fc.Path)
+				seen[fc.Path] = normalized
+				spellings[normalized]++
+				if normalized != fc.Path && m.config.UnicodeForm != "" {
+					report.Normalized = append(report.Normalized, ReportNormalized{Path: fc.Path, Normalized: normalized})
+				}
+			}
+			if m.config.UnicodeForm == "" {
+				files = append(files, fc)
+				continue
+			}
+
+			switch fc.Action {
+			case vcs.ActionDelete:
+				if branch.remove(normalized, fc.Path) {
+					m.Logger().Debug("kept a path deleted in one Unicode spelling while another is live",
+						"path", fc.Path, "commit", commit.Revision)
+					continue
+				}
+			case vcs.ActionRename:
+				// The old spelling goes away, but not a file another keeps live
+				if branch.remove(form.String(fc.OldPath), fc.OldPath) {
+					fc.Action, fc.OldPath = vcs.ActionAdd, ""
+				}
+			}
+			if fc.Action != vcs.ActionDelete && branch.add(normalized, fc.Path) && !merged[normalized] {
+				merged[normalized] = true
+				m.warn("paths differing only by Unicode normalization exist at the same time and are merged into one file",
+					"path", normalized, "spellings", len(branch[normalized]), "commit", commit.Revision)
+			}
+			fc.Path = normalized
+			if fc.OldPath != "" {
+				fc.OldPath = form.String(fc.OldPath)
+			}
+			files = append(files, fc)
+		}
+		commit.Files = files
+	}
+
+	if m.config.UnicodeForm != "" {
+		return
+	}
+	var mixed []string
+	for normalized, n := range spellings {
+		if n > 1 {
+			mixed = append(mixed, normalized)
+		}
+	}
+	sort.Strings(mixed)
+	for _, normalized := range mixed {
+		m.warn("paths differ only by Unicode normalization and are migrated as different files; set a path normalization to merge them",
+			"path", normalized, "spellings", spellings[normalized])
+	}
+}
+
+// unicodeSpellings holds the source spellings of each normalized path that
+// are live on a branch
+type unicodeSpellings map[string]map[string]bool
+
+// clone copies the spellings, for a branch starting from them
+func (u unicodeSpellings) clone() unicodeSpellings {
+	c := make(unicodeSpellings, len(u))
+	for normalized, live := range u {
+		c[normalized] = maps.Clone(live)
+	}
+	return c
+}
+
+// add records spelling as live and reports whether another spelling of
+// the normalized path is live too
+func (u unicodeSpellings) add(normalized, spelling string) bool {
+	if u[normalized] == nil {
+		u[normalized] = map[string]bool{}
+	}
+	u[normalized][spelling] = true
+	return len(u[normalized]) > 1
+}
+
+// remove drops spelling and reports whether another spelling of the
+// normalized path is still live
+func (u unicodeSpellings) remove(normalized, spelling string) bool {
+	delete(u[normalized], spelling)
+	return len(u[normalized]) > 0
+}
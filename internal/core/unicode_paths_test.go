@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePaths(t *testing.T) {
+	const nfc, nfd = "docs/r\u00e9sum\u00e9.txt", "docs/re\u0301sume\u0301.txt"
+	history := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "1", Files: []vcs.FileChange{
+				{Path: nfc, Action: vcs.ActionAdd},
+				{Path: "plain.txt", Action: vcs.ActionAdd},
+			}},
+			// The file is committed again from macOS
+			{Revision: "2", Files: []vcs.FileChange{{Path: nfc, Action: vcs.ActionDelete}, {Path: nfd, Action: vcs.ActionAdd}}},
+			{Revision: "3", Files: []vcs.FileChange{{Path: nfd, Action: vcs.ActionModify}}},
+		}
+	}
+
+	// Without a form the paths are kept and the mix is flagged
+	m := NewMigrator(&MigrationConfig{Logger: logging.Discard()})
+	require.NoError(t, m.validatePathNormalization())
+	commits := history()
+	m.normalizePaths(commits)
+	require.Equal(t, nfd, commits[1].Files[1].Path)
+	require.Empty(t, m.currentReport().Normalized)
+	require.Len(t, m.currentReport().Warnings, 1)
+	require.Contains(t, m.currentReport().Warnings[0], "Unicode normalization")
+
+	m = NewMigrator(&MigrationConfig{UnicodeForm: NormalizeNFC, Logger: logging.Discard()})
+	commits = history()
+	m.normalizePaths(commits)
+	for _, c := range commits {
+		for _, fc := range c.Files {
+			require.NotEqual(t, nfd, fc.Path)
+		}
+	}
+	require.Equal(t, nfc, commits[2].Files[0].Path)
+	require.Equal(t, []ReportNormalized{{Path: nfd, Normalized: nfc}}, m.currentReport().Normalized)
+	require.Empty(t, m.currentReport().Warnings)
+
+	m = NewMigrator(&MigrationConfig{UnicodeForm: NormalizeNFD, Logger: logging.Discard()})
+	commits = history()
+	m.normalizePaths(commits)
+	require.Equal(t, nfd, commits[0].Files[0].Path)
+	require.Equal(t, "plain.txt", commits[0].Files[1].Path)
+	require.Equal(t, []ReportNormalized{{Path: nfc, Normalized: nfd}}, m.currentReport().Normalized)
+
+	m = NewMigrator(&MigrationConfig{UnicodeForm: "nfkc"})
+	require.ErrorContains(t, m.validatePathNormalization(), "unsupported path normalization")
+}
+
+func TestNormalizePaths_LiveSpellings(t *testing.T) {
+	const nfc, nfd = "r\u00e9sum\u00e9.txt", "re\u0301sume\u0301.txt"
+	commits := []*vcs.Commit{
+		{Revision: "1", Files: []vcs.FileChange{{Path: nfc, Action: vcs.ActionAdd}}},
+		{Revision: "2", Files: []vcs.FileChange{{Path: nfd, Action: vcs.ActionAdd}}},
+		{Revision: "3", Files: []vcs.FileChange{{Path: nfc, Action: vcs.ActionDelete}}},
+		{Revision: "4", Files: []vcs.FileChange{{Path: nfd, Action: vcs.ActionDelete}}},
+	}
+	m := NewMigrator(&MigrationConfig{UnicodeForm: NormalizeNFC, Logger: logging.Discard()})
+	m.normalizePaths(commits)
+
+	// Both spellings are live after 2, so the file is kept until both are gone
+	require.Len(t, m.currentReport().Warnings, 1)
+	require.Contains(t, m.currentReport().Warnings[0], "exist at the same time")
+	require.Empty(t, commits[2].Files)
+	require.Equal(t, []vcs.FileChange{{Path: nfc, Action: vcs.ActionDelete}}, commits[3].Files)
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"crypto/sha1" //nolint:gosec // short stable suffix only
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// WindowsMaxPath is the length of the longest path Windows checks out by
+// default (MAX_PATH), in UTF-16 code units. Paths of the repository must
+// stay below it together with the directory the repository is cloned into.
+const WindowsMaxPath = 260
+
+// windowsReserved are the device names Windows reserves, with or without an
+// extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// WindowsPath is a path of the history that Windows cannot check out
+type WindowsPath struct {
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+}
+
+// ReportSanitized is a path of the history rewritten to be valid on Windows
+type ReportSanitized struct {
+	Path      string `json:"path"`
+	Sanitized string `json:"sanitized"`
+	Problem   string `json:"problem"`
+}
+
+// WindowsPathProblem describes why Windows cannot check out a path, or
+// returns "" if it can
+func WindowsPathProblem(p string) string {
+	for _, name := range strings.Split(p, "/") {
+		if i := strings.IndexFunc(name, isInvalidWindowsRune); i >= 0 {
+			return fmt.Sprintf("%q contains the character %q", name, name[i])
+		}
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+			return fmt.Sprintf("%q ends with a dot or space", name)
+		}
+		if isWindowsReserved(name) {
+			return fmt.Sprintf("%q is a reserved device name", name)
+		}
+	}
+	if windowsLength(p) >= WindowsMaxPath {
+		return fmt.Sprintf("longer than %d characters", WindowsMaxPath-1)
+	}
+	return ""
+}
+
+// windowsLength returns the length of p in UTF-16 code units, which
+// Windows measures paths in: one per character, two beyond the Basic
+// Multilingual Plane
+func windowsLength(p string) int {
+	n := 0
+	for _, r := range p {
+		n += max(utf16.RuneLen(r), 1)
+	}
+	return n
+}
+
+// isInvalidWindowsRune reports whether Windows rejects r in file names
+func isInvalidWindowsRune(r rune) bool {
+	return r < 32 || strings.ContainsRune(`<>:"\|?*`, r)
+}
+
+// isWindowsReserved reports whether a file name is a device name like
+// "CON" or "aux.c"
+func isWindowsReserved(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	return windowsReserved[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+// sanitizeWindowsPaths rewrites the paths of the history that Windows
+// cannot check out when SanitizeWindows is set, and warns about them
+// otherwise. Invalid characters become "_", trailing dots and spaces are
+// removed, "_" is appended to reserved names and too long file names are
+// shortened, keeping a hash of the original path so they stay unique.
+func (m *Migrator) sanitizeWindowsPaths(commits []*vcs.Commit) {
+	report := m.currentReport()
+	seen := map[string]string{}  // Source path -> path written
+	taken := map[string]string{} // Path written -> source path
+	for _, commit := range commits {
+		for _, fc := range commit.Files {
+			taken[fc.Path] = fc.Path
+		}
+	}
+	var invalid []string
+	for _, commit := range commits {
+		for i := range commit.Files {
+			fc := &commit.Files[i]
+			if written, ok := seen[fc.OldPath]; ok {
+				fc.OldPath = written // Renamed or copied from a path written before
+			}
+			if written, ok := seen[fc.Path]; ok {
+				fc.Path = written
+				continue
+			}
+			written := fc.Path
+			problem := WindowsPathProblem(fc.Path)
+			if problem != "" {
+				invalid = append(invalid, fc.Path)
+				if m.config.SanitizeWindows {
+					written = sanitizeWindowsPath(fc.Path)
+					if other, ok := taken[written]; ok && other != fc.Path {
+						written = shortenWindowsPath(fc.Path, written, 0)
+					}
+					report.Sanitized = append(report.Sanitized, ReportSanitized{Path: fc.Path, Sanitized: written, Problem: problem})
+				}
+			}
+			seen[fc.Path] = written
+			if written != fc.Path {
+				taken[written] = fc.Path
+			}
+			fc.Path = written
+		}
+	}
+	if len(invalid) > 0 && !m.config.SanitizeWindows {
+		m.warn("paths cannot be checked out on Windows; enable Windows path sanitation to rewrite them",
+			"paths", len(invalid), "first", invalid[0])
+	}
+}
+
+// rewritePaths normalizes the paths of the history, sanitizes those Windows
+// cannot check out and then resolves case conflicts, which sanitized names
+// can cause as well
+func (m *Migrator) rewritePaths(commits []*vcs.Commit) error {
+	m.normalizePaths(commits)
+	m.sanitizeWindowsPaths(commits)
+	return m.resolveCaseConflicts(commits)
+}
+
+// sanitizeWindowsPath returns a path Windows can check out for p
+func sanitizeWindowsPath(p string) string {
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		name = strings.Map(func(r rune) rune {
+			if isInvalidWindowsRune(r) {
+				return '_'
+			}
+			return r
+		}, name)
+		name = strings.TrimRight(name, ". ")
+		if name == "" {
+			name = "_"
+		}
+		if isWindowsReserved(name) {
+			base, ext, _ := strings.Cut(name, ".")
+			name = base + "_"
+			if ext != "" {
+				name += "." + ext
+			}
+		}
+		names[i] = name
+	}
+	sanitized := strings.Join(names, "/")
+	if windowsLength(sanitized) >= WindowsMaxPath {
+		sanitized = shortenWindowsPath(p, sanitized, WindowsMaxPath-1)
+	}
+	return sanitized
+}
+
+// shortenWindowsPath appends a hash of the source path to the file name of
+// sanitized, shortening the name so the path has at most limit UTF-16 code
+// units (0 = no limit). The extension is kept and directories are never
+// shortened.
+func shortenWindowsPath(source, sanitized string, limit int) string {
+	sum := sha1.Sum([]byte(source)) //nolint:gosec // short stable suffix only
+	hash := "~" + hex.EncodeToString(sum[:4])
+	dir, name := path.Split(sanitized)
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	keep := limit - windowsLength(dir) - windowsLength(ext) - len(hash)
+	if keep < 1 {
+		keep = 1 // The directories alone are too long
+	}
+	if limit > 0 && keep < windowsLength(base) {
+		n := 0
+		for i, r := range base {
+			if n += max(utf16.RuneLen(r), 1); n > keep {
+				base = base[:i]
+				break
+			}
+		}
+		base = strings.TrimRight(base, ". ")
+	}
+	return dir + base + hash + ext
+}
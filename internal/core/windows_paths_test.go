@@ -0,0 +1,97 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowsPathProblem(t *testing.T) {
+	long := strings.Repeat("d/", 100) + strings.Repeat("f", 60) + ".c"
+	for p, want := range map[string]string{
+		"src/main.c":             "",
+		"src/CON":                `"CON" is a reserved device name`,
+		"lpt1.log":               `"lpt1.log" is a reserved device name`,
+		"Console.c":              "",
+		"notes./a.txt":           `"notes." ends with a dot or space`,
+		"draft ":                 `"draft " ends with a dot or space`,
+		"a:b.txt":                `"a:b.txt" contains the character ':'`,
+		"what?/x":                `"what?" contains the character '?'`,
+		"tab\there":              `"tab\there" contains the character '\t'`,
+		long:                     "longer than 259 characters",
+		strings.Repeat("a", 258): "",
+		strings.Repeat("é", 258): "", // 516 bytes
+		strings.Repeat("😀", 130): "longer than 259 characters",
+	} {
+		require.Equal(t, want, WindowsPathProblem(p), p)
+	}
+}
+
+func TestSanitizeWindowsPaths(t *testing.T) {
+	long := strings.Repeat("dir/", 60) + strings.Repeat("f", 40) + ".txt"
+	history := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "1", Files: []vcs.FileChange{
+				{Path: "con/aux.c", Action: vcs.ActionAdd},
+				{Path: "notes. /a:b.txt", Action: vcs.ActionAdd},
+				{Path: "a_b.txt", Action: vcs.ActionAdd},
+				{Path: "a?b.txt", Action: vcs.ActionAdd},
+				{Path: long, Action: vcs.ActionAdd},
+			}},
+			{Revision: "2", Files: []vcs.FileChange{
+				{Path: "con/aux.c", Action: vcs.ActionModify},
+			}},
+		}
+	}
+
+	// Without sanitation the paths are kept and flagged
+	m := NewMigrator(&MigrationConfig{Logger: logging.Discard()})
+	commits := history()
+	m.sanitizeWindowsPaths(commits)
+	require.Equal(t, "con/aux.c", commits[0].Files[0].Path)
+	require.Empty(t, m.currentReport().Sanitized)
+	require.Len(t, m.currentReport().Warnings, 1)
+
+	m = NewMigrator(&MigrationConfig{SanitizeWindows: true, Logger: logging.Discard()})
+	commits = history()
+	m.sanitizeWindowsPaths(commits)
+	require.Equal(t, "con_/aux_.c", commits[0].Files[0].Path)
+	require.Equal(t, "notes/a_b.txt", commits[0].Files[1].Path)
+	require.Equal(t, "a_b.txt", commits[0].Files[2].Path)
+	collided := commits[0].Files[3].Path
+	require.True(t, strings.HasPrefix(collided, "a_b~") && strings.HasSuffix(collided, ".txt"), collided)
+	shortened := commits[0].Files[4].Path
+	require.Len(t, shortened, WindowsMaxPath-1)
+	require.True(t, strings.HasSuffix(shortened, ".txt"))
+	require.Empty(t, WindowsPathProblem(shortened))
+	require.Equal(t, "con_/aux_.c", commits[1].Files[0].Path)
+	require.Len(t, m.currentReport().Sanitized, 4)
+	require.Empty(t, m.currentReport().Warnings)
+
+	// Lengths are counted in UTF-16 code units, as Windows does
+	m = NewMigrator(&MigrationConfig{SanitizeWindows: true, Logger: logging.Discard()})
+	commits = []*vcs.Commit{{Revision: "1", Files: []vcs.FileChange{
+		{Path: strings.Repeat("é", 100) + "/" + strings.Repeat("😀", 100) + ".txt", Action: vcs.ActionAdd},
+	}}}
+	m.sanitizeWindowsPaths(commits)
+	shortened = commits[0].Files[0].Path
+	require.Greater(t, len(shortened), WindowsMaxPath)
+	require.Equal(t, WindowsMaxPath-2, windowsLength(shortened)) // No room for half an emoji
+	require.Empty(t, WindowsPathProblem(shortened))
+	require.True(t, utf8.ValidString(shortened))
+}
+
+func TestRewritePaths_SanitizeBeforeCaseConflicts(t *testing.T) {
+	m := NewMigrator(&MigrationConfig{SanitizeWindows: true, CaseConflicts: CaseConflictSuffix, Logger: logging.Discard()})
+	commits := []*vcs.Commit{
+		{Revision: "c1", Files: []vcs.FileChange{{Path: "a_b.txt", Action: vcs.ActionAdd}}},
+		{Revision: "c2", Files: []vcs.FileChange{{Path: "A?b.txt", Action: vcs.ActionAdd}}},
+	}
+	require.NoError(t, m.rewritePaths(commits))
+	require.Equal(t, "A_b_2.txt", commits[1].Files[0].Path)
+	require.Equal(t, []ReportCaseClash{{Path: "a_b.txt", Conflict: "A_b.txt", Commit: "c2", Resolved: "A_b_2.txt"}}, m.currentReport().CaseClashes)
+}
@@ -0,0 +1,308 @@
+// Package importer translates configuration of other migration tools into
+// git-migrator configuration.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+)
+
+// Result holds an imported configuration and the settings that could not
+// be translated
+type Result struct {
+	Config   *core.MigrationConfig
+	Unmapped []string
+}
+
+// ctx settings that only configure cvs2git's own output pipeline
+var cvs2gitPipelineSettings = map[string]bool{
+	"ctx.output_option":      true,
+	"ctx.revision_collector": true,
+	"ctx.revision_reader":    true,
+	"ctx.tmpdir":             true,
+}
+
+// ImportCVS2Git reads a cvs2git or cvs2svn options file and translates the
+// supported settings: the project path, author transforms, trunk_only,
+// exclusion rules and literal symbol renames.
+func ImportCVS2Git(r io.Reader) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read options file: %w", err)
+	}
+	statements, err := parsePython(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse options file: %w", err)
+	}
+
+	imp := &cvs2gitImport{
+		vars: make(map[string]pyValue),
+		result: &Result{Config: &core.MigrationConfig{
+			SourceType: "cvs",
+			AuthorMap:  make(map[string]string),
+			BranchMap:  make(map[string]string),
+			TagMap:     make(map[string]string),
+		}},
+	}
+	for _, stmt := range statements {
+		imp.statement(stmt)
+	}
+	if imp.result.Config.SourcePath == "" {
+		return nil, fmt.Errorf("options file does not add a project (run_options.add_project)")
+	}
+	return imp.result, nil
+}
+
+type cvs2gitImport struct {
+	vars     map[string]pyValue
+	projects int
+	result   *Result
+}
+
+func (imp *cvs2gitImport) unmapped(format string, args ...interface{}) {
+	imp.result.Unmapped = append(imp.result.Unmapped, fmt.Sprintf(format, args...))
+}
+
+// resolve replaces a variable reference with its assigned value
+func (imp *cvs2gitImport) resolve(v pyValue) pyValue {
+	if v.kind == pyName {
+		if assigned, ok := imp.vars[v.text]; ok {
+			return assigned
+		}
+	}
+	return v
+}
+
+func (imp *cvs2gitImport) statement(stmt pyStatement) {
+	switch {
+	case stmt.target == "":
+		if stmt.value.text == "run_options.add_project" || stmt.value.text == "run_options.set_project" {
+			imp.project(stmt.value)
+		}
+
+	case strings.HasPrefix(stmt.target, "ctx."):
+		imp.ctxSetting(stmt.target, imp.resolve(stmt.value))
+
+	default:
+		imp.vars[stmt.target] = stmt.value
+	}
+}
+
+func (imp *cvs2gitImport) ctxSetting(name string, value pyValue) {
+	config := imp.result.Config
+	switch {
+	case name == "ctx.trunk_only":
+		if value.kind == pyName && value.text == "True" {
+			config.ExcludeBranches = append(config.ExcludeBranches, "*")
+			config.ExcludeTags = append(config.ExcludeTags, "*")
+		}
+	case name == "ctx.output_option":
+		if value.kind == pyCall {
+			if transforms, ok := value.kwargs["author_transforms"]; ok {
+				imp.authorTransforms(imp.resolve(transforms))
+			}
+		}
+	case cvs2gitPipelineSettings[name]:
+		// Not applicable: git-migrator writes the repository directly
+	case name == "ctx.username":
+		imp.unmapped("%s: git-migrator creates no synthetic commits", name)
+	default:
+		imp.unmapped("%s is not supported", name)
+	}
+}
+
+func (imp *cvs2gitImport) authorTransforms(v pyValue) {
+	if v.kind != pyDict {
+		imp.unmapped("author_transforms: expected a dict literal")
+		return
+	}
+	for i, key := range v.keys {
+		if key.kind != pyString {
+			continue
+		}
+		value := imp.resolve(v.items[i])
+		switch {
+		case value.kind == pyTuple && len(value.items) == 2 &&
+			value.items[0].kind == pyString && value.items[1].kind == pyString:
+			imp.result.Config.AuthorMap[key.text] = fmt.Sprintf("%s <%s>", value.items[0].text, value.items[1].text)
+		case value.kind == pyString:
+			if _, _, err := mapping.ParseAuthor(value.text); err == nil {
+				imp.result.Config.AuthorMap[key.text] = value.text
+			} else {
+				imp.unmapped("author_transforms[%s]: %q has no email address", key.text, value.text)
+			}
+		default:
+			imp.unmapped("author_transforms[%s]: unsupported value", key.text)
+		}
+	}
+}
+
+func (imp *cvs2gitImport) project(call pyValue) {
+	imp.projects++
+	if imp.projects > 1 {
+		imp.unmapped("%s: only the first project is imported", call.text)
+		return
+	}
+
+	if len(call.items) == 0 || imp.resolve(call.items[0]).kind != pyString {
+		imp.unmapped("%s: project path is not a string literal", call.text)
+		return
+	}
+	imp.result.Config.SourcePath = imp.resolve(call.items[0]).text
+
+	keys := make([]string, 0, len(call.kwargs))
+	for key := range call.kwargs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := call.kwargs[key]
+		switch key {
+		case "symbol_transforms":
+			for _, transform := range imp.list(value) {
+				imp.symbolTransform(transform)
+			}
+		case "symbol_strategy_rules":
+			for _, rule := range imp.list(value) {
+				imp.strategyRule(rule)
+			}
+		case "trunk_path", "branches_path", "tags_path", "initial_directories":
+			// Subversion repository layout, not used for Git output
+		default:
+			imp.unmapped("%s: argument %s is not supported", call.text, key)
+		}
+	}
+}
+
+// list returns the items of a list or tuple, resolving variables
+func (imp *cvs2gitImport) list(v pyValue) []pyValue {
+	v = imp.resolve(v)
+	if v.kind != pyList && v.kind != pyTuple {
+		imp.unmapped("expected a list literal")
+		return nil
+	}
+	items := make([]pyValue, 0, len(v.items))
+	for _, item := range v.items {
+		items = append(items, imp.resolve(item))
+	}
+	return items
+}
+
+func (imp *cvs2gitImport) symbolTransform(v pyValue) {
+	config := imp.result.Config
+	switch {
+	case v.kind == pyCall && v.text == "RegexpSymbolTransform" && len(v.items) == 2 &&
+		v.items[0].kind == pyString && v.items[1].kind == pyString:
+		from, ok := anchoredLiteral(v.items[0].text)
+		if !ok || strings.Contains(v.items[1].text, `\`) {
+			imp.unmapped("RegexpSymbolTransform(%q, %q): only exact ^name$ renames are supported", v.items[0].text, v.items[1].text)
+			return
+		}
+		// CVS symbols become either branches or tags; map both
+		config.BranchMap[from] = v.items[1].text
+		config.TagMap[from] = v.items[1].text
+
+	case v.kind == pyCall && v.text == "IgnoreSymbolTransform" && len(v.items) == 1 && v.items[0].kind == pyString:
+		imp.excludePattern("IgnoreSymbolTransform", v.items[0].text)
+
+	case v.kind == pyCall:
+		imp.unmapped("symbol transform %s is not supported", v.text)
+	default:
+		imp.unmapped("unsupported symbol transform")
+	}
+}
+
+func (imp *cvs2gitImport) strategyRule(v pyValue) {
+	switch {
+	case v.kind == pyCall && v.text == "ExcludeRegexpStrategyRule" && len(v.items) == 1 && v.items[0].kind == pyString:
+		imp.excludePattern(v.text, v.items[0].text)
+	case v.kind == pyCall:
+		imp.unmapped("symbol strategy rule %s is not supported; CVS symbols keep their branch or tag type", v.text)
+	default:
+		imp.unmapped("unsupported symbol strategy rule")
+	}
+}
+
+// excludePattern excludes symbols matching a cvs2git regular expression,
+// which must be expressible as a glob
+func (imp *cvs2gitImport) excludePattern(rule, pattern string) {
+	glob, ok := regexpToGlob(pattern)
+	if !ok {
+		imp.unmapped("%s(%q): pattern cannot be expressed as a glob", rule, pattern)
+		return
+	}
+	config := imp.result.Config
+	config.ExcludeBranches = append(config.ExcludeBranches, glob)
+	config.ExcludeTags = append(config.ExcludeTags, glob)
+}
+
+// regexpToGlob converts a regular expression that must match a whole
+// symbol name into a RefFilter pattern. Only literals, '.' and '.*' can
+// be converted; '.*' becomes "**", which like it matches across '/'.
+func regexpToGlob(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	var convert func(re *syntax.Regexp) bool
+	convert = func(re *syntax.Regexp) bool {
+		switch re.Op {
+		case syntax.OpConcat:
+			for _, sub := range re.Sub {
+				if !convert(sub) {
+					return false
+				}
+			}
+			return true
+		case syntax.OpLiteral:
+			if re.Flags&syntax.FoldCase != 0 {
+				return false
+			}
+			for _, r := range re.Rune {
+				if strings.ContainsRune(`*?[\`, r) {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+			return true
+		case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+			b.WriteByte('?')
+			return true
+		case syntax.OpStar:
+			if op := re.Sub[0].Op; op != syntax.OpAnyCharNotNL && op != syntax.OpAnyChar {
+				return false
+			}
+			b.WriteString("**")
+			return true
+		case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpEndLine, syntax.OpEndText, syntax.OpEmptyMatch:
+			// cvs2git anchors the pattern at both ends anyway
+			return true
+		}
+		return false
+	}
+	if !convert(re) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// anchoredLiteral returns the literal of a "^literal$" pattern
+func anchoredLiteral(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, "^") || !strings.HasSuffix(pattern, "$") {
+		return "", false
+	}
+	re, err := syntax.Parse(pattern[1:len(pattern)-1], syntax.Perl)
+	if err != nil || re.Op != syntax.OpLiteral || re.Flags&syntax.FoldCase != 0 {
+		return "", false
+	}
+	return string(re.Rune), true
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCVS2Git(t *testing.T) {
+	f, err := os.Open("testdata/cvs2git.options")
+	require.NoError(t, err)
+	defer f.Close()
+
+	result, err := ImportCVS2Git(f)
+	require.NoError(t, err)
+	config := result.Config
+
+	require.Equal(t, "cvs", config.SourceType)
+	require.Equal(t, "/srv/cvs/product", config.SourcePath)
+	require.Equal(t, map[string]string{
+		"jrandom": "J. Random <jrandom@example.com>",
+		"mhagger": "Michael Haggerty <mhagger@alum.mit.edu>",
+		"brane":   "Branko Čibej <brane@xbc.nu>",
+	}, config.AuthorMap)
+	require.Equal(t, map[string]string{"MAIN": "main"}, config.BranchMap)
+	require.Equal(t, map[string]string{"MAIN": "main"}, config.TagMap)
+	require.Equal(t, []string{"nightly-**", "build.**"}, config.ExcludeTags)
+	require.Equal(t, config.ExcludeTags, config.ExcludeBranches)
+
+	unmapped := strings.Join(result.Unmapped, "\n")
+	require.Contains(t, unmapped, "ctx.cvs_log_decoder")
+	require.Contains(t, unmapped, "ctx.username")
+	require.Contains(t, unmapped, "author_transforms[nobody]")
+	require.Contains(t, unmapped, `release-(\\d+)_(\\d+)`)
+	require.Contains(t, unmapped, `tmp_[0-9]+`)
+	require.Contains(t, unmapped, "UnambiguousUsageRule")
+	require.NotContains(t, unmapped, "revision_collector")
+}
+
+func TestImportCVS2Git_TrunkOnly(t *testing.T) {
+	src := "ctx.trunk_only = True\nrun_options.set_project('/cvs/repo')\nrun_options.add_project('/cvs/other')\n"
+	result, err := ImportCVS2Git(strings.NewReader(src))
+	require.NoError(t, err)
+	require.Equal(t, "/cvs/repo", result.Config.SourcePath)
+	require.Equal(t, []string{"*"}, result.Config.ExcludeBranches)
+	require.Equal(t, []string{"*"}, result.Config.ExcludeTags)
+	require.Len(t, result.Unmapped, 1)
+	require.Contains(t, result.Unmapped[0], "only the first project")
+}
+
+func TestImportCVS2Git_NoProject(t *testing.T) {
+	_, err := ImportCVS2Git(strings.NewReader("ctx.trunk_only = True\n"))
+	require.Error(t, err)
+}
+
+func TestRegexpToGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		ok      bool
+	}{
+		{`nightly-.*`, "nightly-**", true},
+		{`^v1\.0$`, "v1.0", true},
+		{`rc.`, "rc?", true},
+		{`a\*b`, `a\*b`, true},
+		{`tmp_[0-9]+`, "", false},
+		{`(?i)abc`, "", false},
+		{`a|b`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := regexpToGlob(tt.pattern)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("regexpToGlob(%q) = %q, %v; want %q, %v", tt.pattern, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePython(t *testing.T) {
+	src := `x = [1, 'a' "b", (2, 3), {'k': f(y=1)}]  # comment
+s = r'\d' + 'x'
+call(a,
+     b=\
+     "c")
+if x:
+    pass
+`
+	statements, err := parsePython(src)
+	require.NoError(t, err)
+	require.Len(t, statements, 3)
+
+	list := statements[0].value
+	require.Equal(t, pyList, list.kind)
+	require.Len(t, list.items, 4)
+	require.Equal(t, "ab", list.items[1].text)
+	require.Equal(t, pyTuple, list.items[2].kind)
+	require.Equal(t, pyDict, list.items[3].kind)
+	require.Equal(t, "f", list.items[3].items[0].text)
+
+	require.Equal(t, pyUnknown, statements[1].value.kind)
+
+	call := statements[2].value
+	require.Equal(t, "call", call.text)
+	require.Equal(t, "c", call.kwargs["b"].text)
+}
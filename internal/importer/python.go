@@ -0,0 +1,395 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The cvs2git and cvs2svn options files are Python scripts. Rather than
+// executing them, the importer understands the subset of Python they are
+// written in: assignments and calls whose arguments are literals, names,
+// calls, lists, tuples and dicts. Anything else evaluates to pyUnknown.
+
+type pyTokenKind int
+
+const (
+	tokName pyTokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+	tokNewline
+)
+
+type pyToken struct {
+	kind pyTokenKind
+	text string
+}
+
+// tokenizePython splits Python source into tokens. Newlines inside brackets
+// are dropped, as Python joins those lines implicitly.
+func tokenizePython(src string) ([]pyToken, error) {
+	var tokens []pyToken
+	depth := 0
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+		case c == '\n':
+			if depth == 0 && len(tokens) > 0 && tokens[len(tokens)-1].kind != tokNewline {
+				tokens = append(tokens, pyToken{kind: tokNewline})
+			}
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case c == '"' || c == '\'' || (isStringPrefix(src[i:]) && i+1 < len(src)):
+			value, n, err := readPythonString(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, pyToken{kind: tokString, text: value})
+			i += n
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, pyToken{kind: tokName, text: src[i:j]})
+			i = j
+		case unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.' || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, pyToken{kind: tokNumber, text: src[i:j]})
+			i = j
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			tokens = append(tokens, pyToken{kind: tokOp, text: string(c)})
+			i++
+		}
+	}
+	if len(tokens) > 0 && tokens[len(tokens)-1].kind != tokNewline {
+		tokens = append(tokens, pyToken{kind: tokNewline})
+	}
+	return tokens, nil
+}
+
+// isStringPrefix reports whether s starts with a string prefix such as r'
+func isStringPrefix(s string) bool {
+	n := 0
+	for n < len(s) && n < 2 && strings.ContainsRune("rRuUbB", rune(s[n])) {
+		n++
+	}
+	return n > 0 && n < len(s) && (s[n] == '\'' || s[n] == '"')
+}
+
+// readPythonString reads a string literal with optional prefix and returns
+// its value and the number of bytes consumed
+func readPythonString(s string) (string, int, error) {
+	i := 0
+	raw := false
+	for i < len(s) && s[i] != '\'' && s[i] != '"' {
+		if s[i] == 'r' || s[i] == 'R' {
+			raw = true
+		}
+		i++
+	}
+	quote := s[i : i+1]
+	if strings.HasPrefix(s[i:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	i += len(quote)
+
+	var b strings.Builder
+	for i < len(s) {
+		if strings.HasPrefix(s[i:], quote) {
+			return b.String(), i + len(quote), nil
+		}
+		c := s[i]
+		if c == '\n' && len(quote) == 1 {
+			break
+		}
+		if c == '\\' && i+1 < len(s) {
+			if raw {
+				b.WriteByte(c)
+				b.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+			switch s[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '\'', '"':
+				b.WriteByte(s[i+1])
+			case '\n':
+			default:
+				b.WriteByte(c)
+				b.WriteByte(s[i+1])
+			}
+			i += 2
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+type pyKind int
+
+const (
+	pyUnknown pyKind = iota
+	pyString
+	pyNumber
+	pyName
+	pyCall
+	pyList
+	pyTuple
+	pyDict
+)
+
+// pyValue is a parsed Python expression
+type pyValue struct {
+	kind   pyKind
+	text   string             // String value, number text, dotted name or called function
+	items  []pyValue          // Call arguments, list/tuple items or dict values
+	keys   []pyValue          // Dict keys
+	kwargs map[string]pyValue // Call keyword arguments
+}
+
+// pyStatement is a top-level assignment or call
+type pyStatement struct {
+	target string  // Assigned name, empty for calls
+	value  pyValue // Assigned value or the call itself
+}
+
+type pyParser struct {
+	tokens []pyToken
+	pos    int
+}
+
+// parsePython returns the assignments and calls of a Python script.
+// Statements it cannot parse are skipped.
+func parsePython(src string) ([]pyStatement, error) {
+	tokens, err := tokenizePython(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pyParser{tokens: tokens}
+	var statements []pyStatement
+	for p.pos < len(p.tokens) {
+		start := p.pos
+		if stmt, ok := p.statement(); ok && p.peek().kind == tokNewline {
+			statements = append(statements, stmt)
+		}
+		// Skip to the end of the line
+		if p.pos == start {
+			p.pos++
+		}
+		for p.pos < len(p.tokens) && p.tokens[p.pos-1].kind != tokNewline {
+			p.pos++
+		}
+	}
+	return statements, nil
+}
+
+func (p *pyParser) peek() pyToken {
+	if p.pos >= len(p.tokens) {
+		return pyToken{kind: tokNewline}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *pyParser) next() pyToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *pyParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *pyParser) statement() (pyStatement, bool) {
+	if p.peek().kind != tokName {
+		return pyStatement{}, false
+	}
+	switch p.peek().text {
+	case "import", "from", "if", "else", "elif", "for", "while", "def", "class", "return", "try", "except", "with":
+		return pyStatement{}, false
+	}
+
+	name := p.dottedName()
+	if p.isOp("=") {
+		p.next()
+		return pyStatement{target: name, value: p.expr()}, true
+	}
+	if p.isOp("(") {
+		return pyStatement{value: p.call(name)}, true
+	}
+	return pyStatement{}, false
+}
+
+func (p *pyParser) dottedName() string {
+	name := p.next().text
+	for p.isOp(".") {
+		p.next()
+		if p.peek().kind != tokName {
+			break
+		}
+		name += "." + p.next().text
+	}
+	return name
+}
+
+func (p *pyParser) expr() pyValue {
+	v := p.primary()
+	// Operators and other constructs are not evaluated
+	if t := p.peek(); t.kind == tokName || (t.kind == tokOp && !strings.Contains(",)]}:=", t.text)) {
+		p.skipExpr()
+		return pyValue{kind: pyUnknown}
+	}
+	return v
+}
+
+// skipExpr skips the rest of an expression up to a delimiter at depth 0
+func (p *pyParser) skipExpr() {
+	depth := 0
+	for {
+		t := p.peek()
+		if t.kind == tokNewline {
+			return
+		}
+		if t.kind == tokOp {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth == 0 {
+					return
+				}
+				depth--
+			case ",", ":":
+				if depth == 0 {
+					return
+				}
+			}
+		}
+		p.next()
+	}
+}
+
+func (p *pyParser) primary() pyValue {
+	t := p.peek()
+	switch {
+	case t.kind == tokString:
+		var s strings.Builder
+		for p.peek().kind == tokString {
+			s.WriteString(p.next().text)
+		}
+		return pyValue{kind: pyString, text: s.String()}
+	case t.kind == tokNumber:
+		p.next()
+		return pyValue{kind: pyNumber, text: t.text}
+	case t.kind == tokName:
+		name := p.dottedName()
+		if p.isOp("(") {
+			return p.call(name)
+		}
+		return pyValue{kind: pyName, text: name}
+	case t.kind == tokOp && t.text == "[":
+		p.next()
+		return pyValue{kind: pyList, items: p.items("]")}
+	case t.kind == tokOp && t.text == "(":
+		p.next()
+		items := p.items(")")
+		if len(items) == 1 {
+			return items[0]
+		}
+		return pyValue{kind: pyTuple, items: items}
+	case t.kind == tokOp && t.text == "{":
+		p.next()
+		return p.dict()
+	}
+	p.skipExpr()
+	return pyValue{kind: pyUnknown}
+}
+
+// items parses comma separated expressions up to the closing bracket
+func (p *pyParser) items(closing string) []pyValue {
+	var items []pyValue
+	for !p.isOp(closing) && p.peek().kind != tokNewline {
+		items = append(items, p.expr())
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if p.isOp(closing) {
+		p.next()
+	}
+	return items
+}
+
+func (p *pyParser) call(name string) pyValue {
+	p.next() // (
+	v := pyValue{kind: pyCall, text: name, kwargs: make(map[string]pyValue)}
+	for !p.isOp(")") && p.peek().kind != tokNewline {
+		if p.peek().kind == tokName && p.pos+1 < len(p.tokens) &&
+			p.tokens[p.pos+1].kind == tokOp && p.tokens[p.pos+1].text == "=" {
+			key := p.next().text
+			p.next() // =
+			v.kwargs[key] = p.expr()
+		} else {
+			v.items = append(v.items, p.expr())
+		}
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if p.isOp(")") {
+		p.next()
+	}
+	return v
+}
+
+func (p *pyParser) dict() pyValue {
+	v := pyValue{kind: pyDict}
+	for !p.isOp("}") && p.peek().kind != tokNewline {
+		key := p.expr()
+		if !p.isOp(":") {
+			p.skipExpr()
+			break
+		}
+		p.next()
+		v.keys = append(v.keys, key)
+		v.items = append(v.items, p.expr())
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if p.isOp("}") {
+		p.next()
+	}
+	return v
+}
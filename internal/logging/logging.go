@@ -0,0 +1,186 @@
+// Package logging provides structured logging for git-migrator.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Options controls how a logger is constructed
+type Options struct {
+	Level  string    // debug, info, warn, error (default: info)
+	Format string    // text or json (default: text)
+	Output io.Writer // Destination (default: os.Stderr)
+}
+
+var defaultLogger atomic.Pointer[slog.Logger]
+
+func init() {
+	defaultLogger.Store(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+}
+
+// New creates a structured logger from the given options
+func New(opts Options) (*slog.Logger, error) {
+	handler, err := NewHandler(opts)
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(handler), nil
+}
+
+// NewHandler creates a slog handler from the given options
+func NewHandler(opts Options) (slog.Handler, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		return slog.NewTextHandler(out, handlerOpts), nil
+	case "json":
+		return slog.NewJSONHandler(out, handlerOpts), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s (supported: text, json)", opts.Format)
+	}
+}
+
+// ParseLevel converts a level name into a slog.Level. An empty name maps to info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unsupported log level: %s (supported: debug, info, warn, error)", name)
+	}
+}
+
+// Default returns the process-wide logger
+func Default() *slog.Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the process-wide logger. A nil logger is ignored.
+func SetDefault(logger *slog.Logger) {
+	if logger != nil {
+		defaultLogger.Store(logger)
+	}
+}
+
+// OrDefault returns logger, or the process-wide logger when logger is nil
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger != nil {
+		return logger
+	}
+	return Default()
+}
+
+// Discard returns a logger that drops every record
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(127)}))
+}
+
+// MigrationLog captures the log entries of a single migration in a file
+type MigrationLog struct {
+	Path   string
+	Logger *slog.Logger
+	file   *os.File
+}
+
+// OpenMigrationLog creates (or appends to) <dir>/<id>.log and returns a
+// logger that writes JSON entries to that file in addition to base.
+// Every entry carries the migration_id attribute.
+func OpenMigrationLog(base *slog.Logger, dir, id string) (*MigrationLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	path := filepath.Join(dir, id+".log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration log: %w", err)
+	}
+
+	fileHandler := slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(NewTeeHandler(OrDefault(base).Handler(), fileHandler)).With("migration_id", id)
+
+	return &MigrationLog{Path: path, Logger: logger, file: file}, nil
+}
+
+// Close closes the underlying log file
+func (ml *MigrationLog) Close() error {
+	if ml.file == nil {
+		return nil
+	}
+	return ml.file.Close()
+}
+
+// TeeHandler fans each record out to several handlers
+type TeeHandler struct {
+	handlers []slog.Handler
+}
+
+// NewTeeHandler creates a handler that forwards records to all handlers
+func NewTeeHandler(handlers ...slog.Handler) *TeeHandler {
+	return &TeeHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the wrapped handlers accepts the level
+func (t *TeeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle forwards the record to every handler that accepts its level
+func (t *TeeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t.handlers {
+		if h.Enabled(ctx, record.Level) {
+			if err := h.Handle(ctx, record.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a TeeHandler whose handlers all carry attrs
+func (t *TeeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &TeeHandler{handlers: handlers}
+}
+
+// WithGroup returns a TeeHandler whose handlers all use the group name
+func (t *TeeHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &TeeHandler{handlers: handlers}
+}
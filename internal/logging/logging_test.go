@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"info":    slog.LevelInfo,
+		"DEBUG":   slog.LevelDebug,
+		"warn":    slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	}
+	for name, want := range cases {
+		got, err := ParseLevel(name)
+		require.NoError(t, err, name)
+		require.Equal(t, want, got, name)
+	}
+
+	_, err := ParseLevel("loud")
+	require.Error(t, err)
+}
+
+func TestNew_JSONFormatAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(Options{Level: "warn", Format: "json", Output: &buf})
+	require.NoError(t, err)
+
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "shown", entry["msg"])
+	require.Equal(t, "value", entry["key"])
+}
+
+func TestNew_InvalidFormat(t *testing.T) {
+	_, err := New(Options{Format: "xml"})
+	require.Error(t, err)
+}
+
+func TestDefaultAndOrDefault(t *testing.T) {
+	orig := Default()
+	defer SetDefault(orig)
+
+	custom := Discard()
+	SetDefault(custom)
+	require.Same(t, custom, Default())
+	require.Same(t, custom, OrDefault(nil))
+
+	other := Discard()
+	require.Same(t, other, OrDefault(other))
+
+	// nil must not replace the default
+	SetDefault(nil)
+	require.Same(t, custom, Default())
+}
+
+func TestOpenMigrationLog_WritesToFileAndBase(t *testing.T) {
+	var buf bytes.Buffer
+	base, err := New(Options{Output: &buf})
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "logs")
+	ml, err := OpenMigrationLog(base, dir, "abc123")
+	require.NoError(t, err)
+
+	ml.Logger.Info("hello", "commit", "1.1")
+	ml.Logger.Debug("detail")
+	require.NoError(t, ml.Close())
+
+	require.Equal(t, filepath.Join(dir, "abc123.log"), ml.Path)
+	data, err := os.ReadFile(ml.Path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// The file captures debug entries even when the base logger does not
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "hello", entry["msg"])
+	require.Equal(t, "abc123", entry["migration_id"])
+	require.Equal(t, "1.1", entry["commit"])
+
+	require.Contains(t, buf.String(), "migration_id=abc123")
+	require.NotContains(t, buf.String(), "detail")
+}
+
+func TestTeeHandler_WithGroup(t *testing.T) {
+	var a, b bytes.Buffer
+	logger := slog.New(NewTeeHandler(
+		slog.NewTextHandler(&a, nil),
+		slog.NewTextHandler(&b, nil),
+	)).WithGroup("g")
+
+	logger.Info("msg", "k", "v")
+	require.Contains(t, a.String(), "g.k=v")
+	require.Contains(t, b.String(), "g.k=v")
+}
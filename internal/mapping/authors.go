@@ -2,9 +2,13 @@
 package mapping
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // AuthorMap maps CVS usernames to Git author info
@@ -42,6 +46,21 @@ func (am *AuthorMap) Get(username string) (string, string) {
 	return username, fmt.Sprintf("%s@%s", username, am.defaultEmail)
 }
 
+// ErrUnmappedAuthor is returned by Lookup for usernames the map does not
+// cover
+var ErrUnmappedAuthor = errors.New("unmapped author")
+
+// Lookup returns the Git author name and email the map configures for a CVS
+// username, without the default identity Get falls back to
+func (am *AuthorMap) Lookup(username string) (string, string, error) {
+	if format, ok := am.mapping[username]; ok {
+		if name, email, err := ParseAuthor(format); err == nil {
+			return name, email, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrUnmappedAuthor, username)
+}
+
 // ParseAuthor parses a "Name <email>" string
 func ParseAuthor(format string) (string, string, error) {
 	// Pattern: "Name <email>"
@@ -61,6 +80,25 @@ func ParseAuthor(format string) (string, string, error) {
 	return name, email, nil
 }
 
+// LoadAuthorFile reads a YAML author map of logins to "Name <email>", as
+// written by authors extract --format yaml and exported after a migration
+func LoadAuthorFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read author map: %w", err)
+	}
+	var authors map[string]string
+	if err := yaml.Unmarshal(data, &authors); err != nil {
+		return nil, fmt.Errorf("failed to parse author map %s: %w", path, err)
+	}
+	for login, author := range authors {
+		if _, _, err := ParseAuthor(author); err != nil {
+			return nil, fmt.Errorf("author map %s: %s: author must be written as \"Name <email>\"", path, login)
+		}
+	}
+	return authors, nil
+}
+
 // AuthorExtractor extracts unique authors from a repository
 type AuthorExtractor struct {
 	authors map[string]bool
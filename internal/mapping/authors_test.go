@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -126,6 +127,23 @@ func TestAuthorMapGetInvalidFormat(t *testing.T) {
 	}
 }
 
+func TestAuthorMapLookup(t *testing.T) {
+	am := NewAuthorMap(map[string]string{
+		"johndoe": "John Doe <john@example.com>",
+		"baduser": "invalid format without angle brackets",
+	})
+
+	name, email, err := am.Lookup("johndoe")
+	if err != nil || name != "John Doe" || email != "john@example.com" {
+		t.Errorf("Lookup(johndoe) = %q, %q, %v", name, email, err)
+	}
+	for _, login := range []string{"baduser", "nobody"} {
+		if _, _, err := am.Lookup(login); !errors.Is(err, ErrUnmappedAuthor) {
+			t.Errorf("Lookup(%s) error = %v, want ErrUnmappedAuthor", login, err)
+		}
+	}
+}
+
 func TestParseAuthor(t *testing.T) {
 	tests := []struct {
 		name      string
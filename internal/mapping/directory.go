@@ -0,0 +1,131 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// directoryCacheEntry is a cached directory lookup
+type directoryCacheEntry struct {
+	Name     string    `yaml:"name,omitempty"`
+	Email    string    `yaml:"email,omitempty"`
+	NotFound bool      `yaml:"notFound,omitempty"`
+	Resolved time.Time `yaml:"resolved"`
+}
+
+// CachedDirectory answers lookups from a cache file, asking the directory
+// only for logins missing from it or cached longer than MaxAge. Logins the
+// directory does not know are cached too.
+type CachedDirectory struct {
+	dir     Directory
+	path    string
+	maxAge  time.Duration
+	entries map[string]directoryCacheEntry
+	dirty   bool
+}
+
+// NewCachedDirectory loads the cache file at path, if it exists. A maxAge
+// of zero keeps entries forever.
+func NewCachedDirectory(dir Directory, path string, maxAge time.Duration) (*CachedDirectory, error) {
+	c := &CachedDirectory{dir: dir, path: path, maxAge: maxAge, entries: make(map[string]directoryCacheEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory cache: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse directory cache %s: %w", path, err)
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]directoryCacheEntry)
+	}
+	return c, nil
+}
+
+// Lookup returns the cached entry of login, or asks the directory
+func (c *CachedDirectory) Lookup(login string) (DirectoryEntry, bool, error) {
+	if cached, ok := c.entries[login]; ok && (c.maxAge == 0 || time.Since(cached.Resolved) < c.maxAge) {
+		return DirectoryEntry{Name: cached.Name, Email: cached.Email}, !cached.NotFound, nil
+	}
+	entry, found, err := c.dir.Lookup(login)
+	if err != nil {
+		return DirectoryEntry{}, false, err
+	}
+	c.entries[login] = directoryCacheEntry{Name: entry.Name, Email: entry.Email, NotFound: !found, Resolved: time.Now().UTC()}
+	c.dirty = true
+	return entry, found, nil
+}
+
+// Save writes the cache file if lookups changed it
+func (c *CachedDirectory) Save() error {
+	if !c.dirty {
+		return nil
+	}
+	data, err := yaml.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := storage.WriteFileAtomic(c.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write directory cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// Sources of the authors in an AuthorResolution
+const (
+	AuthorFromMap       = "map"        // The author map had an entry
+	AuthorFromDirectory = "directory"  // Resolved from the directory
+	AuthorUnresolved    = "unresolved" // Neither knows the login
+)
+
+// AuthorResolution tells how the author of a login was found
+type AuthorResolution struct {
+	Login  string
+	Author string // "Name <email>", empty when unresolved
+	Source string // AuthorFromMap, AuthorFromDirectory or AuthorUnresolved
+}
+
+// ResolveAuthors looks up the logins missing from authors in the directory
+// and returns the resolution of every login, sorted by login
+func ResolveAuthors(logins []string, authors map[string]string, dir Directory) ([]AuthorResolution, error) {
+	sorted := append([]string(nil), logins...)
+	sort.Strings(sorted)
+
+	resolutions := make([]AuthorResolution, 0, len(sorted))
+	for _, login := range sorted {
+		if author, ok := authors[login]; ok {
+			resolutions = append(resolutions, AuthorResolution{Login: login, Author: author, Source: AuthorFromMap})
+			continue
+		}
+		entry, found, err := dir.Lookup(login)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			resolutions = append(resolutions, AuthorResolution{Login: login, Source: AuthorUnresolved})
+			continue
+		}
+		resolutions = append(resolutions, AuthorResolution{
+			Login:  login,
+			Author: fmt.Sprintf("%s <%s>", entry.Name, entry.Email),
+			Source: AuthorFromDirectory,
+		})
+	}
+	return resolutions, nil
+}
@@ -0,0 +1,117 @@
+package mapping
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mapDirectory is a Directory answering from a map and counting lookups
+type mapDirectory struct {
+	people  map[string]DirectoryEntry
+	lookups int
+	err     error
+}
+
+func (d *mapDirectory) Lookup(login string) (DirectoryEntry, bool, error) {
+	d.lookups++
+	if d.err != nil {
+		return DirectoryEntry{}, false, d.err
+	}
+	entry, ok := d.people[login]
+	return entry, ok, nil
+}
+
+func TestResolveAuthors(t *testing.T) {
+	dir := &mapDirectory{people: map[string]DirectoryEntry{
+		"jsmith": {Name: "John Smith", Email: "john@corp.example"},
+		"mjones": {Name: "Mary Jones", Email: "mary@corp.example"},
+	}}
+	got, err := ResolveAuthors([]string{"mjones", "jsmith", "ghost"}, map[string]string{
+		"mjones": "Mary J. <mj@example.com>",
+	}, dir)
+	if err != nil {
+		t.Fatalf("ResolveAuthors: %v", err)
+	}
+	want := []AuthorResolution{
+		{Login: "ghost", Source: AuthorUnresolved},
+		{Login: "jsmith", Author: "John Smith <john@corp.example>", Source: AuthorFromDirectory},
+		{Login: "mjones", Author: "Mary J. <mj@example.com>", Source: AuthorFromMap},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ResolveAuthors = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("resolution %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if dir.lookups != 2 {
+		t.Errorf("lookups = %d, want 2 (mapped logins are not looked up)", dir.lookups)
+	}
+
+	dir.err = errors.New("connection refused")
+	if _, err := ResolveAuthors([]string{"jsmith"}, nil, dir); err == nil {
+		t.Error("ResolveAuthors should return directory errors")
+	}
+}
+
+func TestCachedDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "ldap.yaml")
+	dir := &mapDirectory{people: map[string]DirectoryEntry{
+		"jsmith": {Name: "John Smith", Email: "john@corp.example"},
+	}}
+
+	cache, err := NewCachedDirectory(dir, path, 0)
+	if err != nil {
+		t.Fatalf("NewCachedDirectory: %v", err)
+	}
+	for range 2 {
+		if entry, ok, err := cache.Lookup("jsmith"); err != nil || !ok || entry.Name != "John Smith" {
+			t.Fatalf("Lookup(jsmith) = %+v, %v, %v", entry, ok, err)
+		}
+		if _, ok, err := cache.Lookup("ghost"); err != nil || ok {
+			t.Fatalf("Lookup(ghost) = %v, %v", ok, err)
+		}
+	}
+	if dir.lookups != 2 {
+		t.Errorf("lookups = %d, want 2", dir.lookups)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A new cache answers from the file, including unknown logins
+	reloaded, err := NewCachedDirectory(dir, path, 0)
+	if err != nil {
+		t.Fatalf("NewCachedDirectory: %v", err)
+	}
+	if entry, ok, _ := reloaded.Lookup("jsmith"); !ok || entry.Email != "john@corp.example" {
+		t.Errorf("cached Lookup(jsmith) = %+v, %v", entry, ok)
+	}
+	if _, ok, _ := reloaded.Lookup("ghost"); ok {
+		t.Error("cached Lookup(ghost) should not be found")
+	}
+	if dir.lookups != 2 {
+		t.Errorf("lookups = %d, want 2 after reload", dir.lookups)
+	}
+
+	// Entries older than the maximum age are looked up again
+	expiring, err := NewCachedDirectory(dir, path, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("NewCachedDirectory: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, _, err := expiring.Lookup("jsmith"); err != nil || dir.lookups != 3 {
+		t.Errorf("expired Lookup: lookups = %d, err = %v", dir.lookups, err)
+	}
+
+	if err := os.WriteFile(path, []byte("not: [yaml"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCachedDirectory(dir, path, 0); err == nil {
+		t.Error("NewCachedDirectory should reject a corrupt cache")
+	}
+}
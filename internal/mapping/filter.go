@@ -0,0 +1,68 @@
+package mapping
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RefFilter selects branches or tags by glob pattern. Patterns use
+// path.Match syntax, so '*' does not match '/', plus "**", which matches
+// any run of characters including '/'.
+type RefFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewRefFilter creates a filter. A name passes if it matches one of the
+// include patterns (or include is empty) and none of the exclude patterns.
+func NewRefFilter(include, exclude []string) (*RefFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return &RefFilter{include: include, exclude: exclude}, nil
+}
+
+// Allows reports whether name passes the filter
+func (f *RefFilter) Allows(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchRef(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRef matches name against pattern, trying every split of name around
+// the first "**" and matching the part before it with path.Match
+func matchRef(pattern, name string) bool {
+	i := strings.Index(pattern, "**")
+	if i < 0 {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+	head, tail := pattern[:i], strings.TrimLeft(pattern[i:], "*")
+	for j := 0; j <= len(name); j++ {
+		if ok, _ := path.Match(head, name[:j]); !ok {
+			continue
+		}
+		for k := j; k <= len(name); k++ {
+			if matchRef(tail, name[k:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
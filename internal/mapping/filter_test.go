@@ -0,0 +1,79 @@
+package mapping
+
+import (
+	"testing"
+)
+
+func TestRefFilter(t *testing.T) {
+	f, err := NewRefFilter([]string{"RELEASE_*", "V*"}, []string{"*_RC*", "V0_*"})
+	if err != nil {
+		t.Fatalf("NewRefFilter failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"RELEASE_1_0", true},
+		{"RELEASE_1_0_RC1", false},
+		{"V2_0", true},
+		{"V0_9", false},
+		{"NIGHTLY_20240101", false},
+	}
+	for _, tt := range tests {
+		if got := f.Allows(tt.name); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefFilterExcludeOnly(t *testing.T) {
+	f, err := NewRefFilter(nil, []string{"nightly-*"})
+	if err != nil {
+		t.Fatalf("NewRefFilter failed: %v", err)
+	}
+	if f.Allows("nightly-2024-01-01") {
+		t.Error("nightly tag should be excluded")
+	}
+	if !f.Allows("v1.0") {
+		t.Error("v1.0 should be allowed")
+	}
+}
+
+func TestRefFilterDoubleStar(t *testing.T) {
+	f, err := NewRefFilter(nil, []string{"release/**", "**-tmp"})
+	if err != nil {
+		t.Fatalf("NewRefFilter failed: %v", err)
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"release/1.0", false},
+		{"release/1.0/hotfix", false},
+		{"feature/x-tmp", false},
+		{"feature/x", true},
+		{"release", true},
+	}
+	for _, tt := range tests {
+		if got := f.Allows(tt.name); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefFilterNil(t *testing.T) {
+	var f *RefFilter
+	if !f.Allows("anything") {
+		t.Error("nil filter should allow everything")
+	}
+}
+
+func TestRefFilterInvalidPattern(t *testing.T) {
+	if _, err := NewRefFilter([]string{"[a-"}, nil); err == nil {
+		t.Error("expected error for invalid include pattern")
+	}
+	if _, err := NewRefFilter(nil, []string{"[a-"}); err == nil {
+		t.Error("expected error for invalid exclude pattern")
+	}
+}
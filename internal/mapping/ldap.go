@@ -0,0 +1,481 @@
+package mapping
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DirectoryEntry is a person found in a corporate directory
+type DirectoryEntry struct {
+	Name  string
+	Email string
+}
+
+// Directory resolves source logins to people
+type Directory interface {
+	// Lookup returns the person with the login; ok is false if there is none
+	Lookup(login string) (entry DirectoryEntry, ok bool, err error)
+}
+
+// LDAPConfig configures an LDAP or Active Directory connection
+type LDAPConfig struct {
+	URL            string        // ldap://host[:389], secured with StartTLS, or ldaps://host[:636]
+	BindDN         string        // Empty for an anonymous bind
+	Password       string        // Password of BindDN
+	BaseDN         string        // Subtree searched for people
+	LoginAttribute string        // Attribute holding the login (default uid; sAMAccountName for Active Directory)
+	NameAttribute  string        // Attribute holding the display name (default displayName, falling back to cn)
+	MailAttribute  string        // Attribute holding the email address (default mail)
+	ObjectClass    string        // Only match entries of this class, e.g. person (empty = any)
+	Timeout        time.Duration // Per request (default 10s)
+	AllowPlaintext bool          // Skip StartTLS on ldap:// URLs, sending the password in cleartext
+}
+
+// startTLSOID names the StartTLS extended operation (RFC 4511)
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// maxBERLength bounds the elements read from a server, which directory
+// entries stay far below
+const maxBERLength = 16 << 20
+
+// LDAPDirectory looks people up in an LDAP directory. It connects on the
+// first lookup and keeps the connection until Close.
+type LDAPDirectory struct {
+	config    LDAPConfig
+	tlsConfig *tls.Config // Verifies the server (nil = system roots)
+	conn      net.Conn
+	reader    *bufio.Reader
+	nextID    int
+}
+
+// NewLDAPDirectory checks the configuration and returns a directory using it
+func NewLDAPDirectory(config LDAPConfig) (*LDAPDirectory, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("ldap: url is required")
+	}
+	if config.BaseDN == "" {
+		return nil, fmt.Errorf("ldap: baseDN is required")
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return nil, fmt.Errorf("ldap: url must be ldap://host or ldaps://host: %s", config.URL)
+	}
+	if config.LoginAttribute == "" {
+		config.LoginAttribute = "uid"
+	}
+	if config.NameAttribute == "" {
+		config.NameAttribute = "displayName"
+	}
+	if config.MailAttribute == "" {
+		config.MailAttribute = "mail"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &LDAPDirectory{config: config}, nil
+}
+
+// Lookup searches the directory for the entry whose login attribute is login
+func (d *LDAPDirectory) Lookup(login string) (DirectoryEntry, bool, error) {
+	if err := d.connect(); err != nil {
+		return DirectoryEntry{}, false, err
+	}
+	attrs, found, err := d.search(login)
+	if err != nil {
+		// The connection may be unusable; reconnect on the next lookup
+		_ = d.Close()
+		return DirectoryEntry{}, false, err
+	}
+	if !found {
+		return DirectoryEntry{}, false, nil
+	}
+
+	entry := DirectoryEntry{
+		Name:  firstValue(attrs, d.config.NameAttribute, "cn"),
+		Email: firstValue(attrs, d.config.MailAttribute),
+	}
+	if entry.Name == "" || entry.Email == "" {
+		// A service account or a person without a mailbox is no author
+		return DirectoryEntry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+// Close unbinds and closes the connection, if any
+func (d *LDAPDirectory) Close() error {
+	if d.conn == nil {
+		return nil
+	}
+	d.nextID++
+	_, _ = d.conn.Write(berMessage(d.nextID, berTLV(0x42, nil))) // UnbindRequest
+	err := d.conn.Close()
+	d.conn, d.reader = nil, nil
+	return err
+}
+
+// connect dials the server and binds, unless already connected
+func (d *LDAPDirectory) connect() error {
+	if d.conn != nil {
+		return nil
+	}
+	u, _ := url.Parse(d.config.URL)
+	host := u.Host
+	if u.Port() == "" {
+		port := "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	tlsConfig := &tls.Config{ServerName: u.Hostname()}
+	if d.tlsConfig != nil {
+		tlsConfig = d.tlsConfig.Clone()
+		tlsConfig.ServerName = u.Hostname()
+	}
+	dialer := &net.Dialer{Timeout: d.config.Timeout}
+	var conn net.Conn
+	var err error
+	if u.Scheme == "ldaps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return fmt.Errorf("ldap: failed to connect to %s: %w", d.config.URL, err)
+	}
+	d.conn, d.reader = conn, bufio.NewReader(conn)
+	if u.Scheme == "ldap" && !d.config.AllowPlaintext {
+		if err := d.startTLS(tlsConfig); err != nil {
+			_ = d.Close()
+			return fmt.Errorf("ldap: StartTLS with %s failed (use ldaps://, or allow plaintext to send the password unencrypted): %w", d.config.URL, err)
+		}
+	}
+
+	// BindRequest: version 3, simple authentication
+	bind := berTLV(0x60, concat(
+		berInt(0x02, 3),
+		berTLV(0x04, []byte(d.config.BindDN)),
+		berTLV(0x80, []byte(d.config.Password)),
+	))
+	response, err := d.roundTrip(bind, 0x61)
+	if err == nil {
+		err = ldapResult(response)
+	}
+	if err != nil {
+		_ = d.Close()
+		return fmt.Errorf("ldap: bind as %q failed: %w", d.config.BindDN, err)
+	}
+	return nil
+}
+
+// startTLS upgrades the connection to TLS before anything is sent in it
+func (d *LDAPDirectory) startTLS(config *tls.Config) error {
+	response, err := d.roundTrip(berTLV(0x77, berTLV(0x80, []byte(startTLSOID))), 0x78) // ExtendedRequest
+	if err == nil {
+		err = ldapResult(response)
+	}
+	if err != nil {
+		return err
+	}
+	conn := tls.Client(d.conn, config)
+	if err := conn.SetDeadline(time.Now().Add(d.config.Timeout)); err != nil {
+		return err
+	}
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	d.conn, d.reader = conn, bufio.NewReader(conn)
+	return nil
+}
+
+// search returns the attributes of the first entry matching login
+func (d *LDAPDirectory) search(login string) (map[string][]string, bool, error) {
+	filter := berTLV(0xa3, concat( // equalityMatch
+		berTLV(0x04, []byte(d.config.LoginAttribute)),
+		berTLV(0x04, []byte(login)),
+	))
+	if d.config.ObjectClass != "" {
+		filter = berTLV(0xa0, concat( // and
+			berTLV(0xa3, concat(berTLV(0x04, []byte("objectClass")), berTLV(0x04, []byte(d.config.ObjectClass)))),
+			filter,
+		))
+	}
+	request := berTLV(0x63, concat(
+		berTLV(0x04, []byte(d.config.BaseDN)),
+		berInt(0x0a, 2), // wholeSubtree
+		berInt(0x0a, 0), // neverDerefAliases
+		berInt(0x02, 1), // sizeLimit: only the first match is used
+		berInt(0x02, int(d.config.Timeout/time.Second)),
+		berTLV(0x01, []byte{0}), // typesOnly: false
+		filter,
+		berTLV(0x30, concat(
+			berTLV(0x04, []byte(d.config.NameAttribute)),
+			berTLV(0x04, []byte("cn")),
+			berTLV(0x04, []byte(d.config.MailAttribute)),
+		)),
+	))
+
+	id, err := d.send(request)
+	if err != nil {
+		return nil, false, err
+	}
+	var attrs map[string][]string
+	found := false
+	for {
+		tag, op, err := d.receive(id)
+		if err != nil {
+			return nil, false, err
+		}
+		switch tag {
+		case 0x64: // SearchResultEntry
+			if !found {
+				if attrs, err = parseSearchEntry(op); err != nil {
+					return nil, false, err
+				}
+				found = true
+			}
+		case 0x73: // SearchResultReference: referrals are not followed
+		case 0x65: // SearchResultDone
+			if err := ldapResult(op); err != nil && !(found && errors.Is(err, errSizeLimitExceeded)) {
+				return nil, false, fmt.Errorf("ldap: search for %s=%s failed: %w", d.config.LoginAttribute, login, err)
+			}
+			return attrs, found, nil
+		default:
+			return nil, false, fmt.Errorf("ldap: unexpected response 0x%02x to search", tag)
+		}
+	}
+}
+
+// roundTrip sends a request and returns the content of its single response,
+// which must have the tag want
+func (d *LDAPDirectory) roundTrip(op []byte, want byte) ([]byte, error) {
+	id, err := d.send(op)
+	if err != nil {
+		return nil, err
+	}
+	tag, response, err := d.receive(id)
+	if err != nil {
+		return nil, err
+	}
+	if tag != want {
+		return nil, fmt.Errorf("unexpected response 0x%02x", tag)
+	}
+	return response, nil
+}
+
+// send writes an LDAPMessage holding op and returns its message ID
+func (d *LDAPDirectory) send(op []byte) (int, error) {
+	d.nextID++
+	if err := d.conn.SetDeadline(time.Now().Add(d.config.Timeout)); err != nil {
+		return 0, err
+	}
+	if _, err := d.conn.Write(berMessage(d.nextID, op)); err != nil {
+		return 0, fmt.Errorf("ldap: %w", err)
+	}
+	return d.nextID, nil
+}
+
+// receive reads the next LDAPMessage answering message id and returns the
+// tag and content of its protocol operation
+func (d *LDAPDirectory) receive(id int) (byte, []byte, error) {
+	for {
+		tag, content, err := readTLV(d.reader)
+		if err != nil {
+			return 0, nil, fmt.Errorf("ldap: failed to read response: %w", err)
+		}
+		if tag != 0x30 {
+			return 0, nil, fmt.Errorf("ldap: malformed response")
+		}
+		fields, err := splitTLVs(content)
+		if err != nil || len(fields) < 2 || fields[0].tag != 0x02 {
+			return 0, nil, fmt.Errorf("ldap: malformed response")
+		}
+		if berIntValue(fields[0].content) != id {
+			continue // Notice of disconnection or a stale response
+		}
+		return fields[1].tag, fields[1].content, nil
+	}
+}
+
+// errSizeLimitExceeded is the result of a search with more matches than the
+// size limit
+var errSizeLimitExceeded = errors.New("size limit exceeded")
+
+// ldapResult returns the error of an LDAPResult, nil for success
+func ldapResult(content []byte) error {
+	fields, err := splitTLVs(content)
+	if err != nil || len(fields) < 3 || fields[0].tag != 0x0a {
+		return fmt.Errorf("malformed result")
+	}
+	code := berIntValue(fields[0].content)
+	switch code {
+	case 0:
+		return nil
+	case 4:
+		return errSizeLimitExceeded
+	case 49:
+		return fmt.Errorf("invalid credentials")
+	}
+	if message := string(fields[2].content); message != "" {
+		return fmt.Errorf("result code %d: %s", code, message)
+	}
+	return fmt.Errorf("result code %d", code)
+}
+
+// parseSearchEntry returns the attributes of a SearchResultEntry by
+// lowercase name
+func parseSearchEntry(content []byte) (map[string][]string, error) {
+	fields, err := splitTLVs(content)
+	if err != nil || len(fields) < 2 {
+		return nil, fmt.Errorf("ldap: malformed search entry")
+	}
+	list, err := splitTLVs(fields[1].content)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: malformed search entry")
+	}
+	attrs := make(map[string][]string)
+	for _, attr := range list {
+		parts, err := splitTLVs(attr.content)
+		if err != nil || len(parts) < 2 {
+			return nil, fmt.Errorf("ldap: malformed search entry")
+		}
+		values, err := splitTLVs(parts[1].content)
+		if err != nil {
+			return nil, fmt.Errorf("ldap: malformed search entry")
+		}
+		name := strings.ToLower(string(parts[0].content))
+		for _, v := range values {
+			attrs[name] = append(attrs[name], string(v.content))
+		}
+	}
+	return attrs, nil
+}
+
+// firstValue returns the first non-empty value of the first attribute that
+// has one
+func firstValue(attrs map[string][]string, names ...string) string {
+	for _, name := range names {
+		for _, v := range attrs[strings.ToLower(name)] {
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// tlv is a decoded BER element
+type tlv struct {
+	tag     byte
+	content []byte
+}
+
+// berMessage wraps a protocol operation in an LDAPMessage
+func berMessage(id int, op []byte) []byte {
+	return berTLV(0x30, concat(berInt(0x02, id), op))
+}
+
+// berTLV encodes a BER element with a single-byte tag
+func berTLV(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+// berInt encodes a non-negative INTEGER or ENUMERATED
+func berInt(tag byte, v int) []byte {
+	content := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		content = append([]byte{byte(v)}, content...)
+	}
+	if content[0]&0x80 != 0 {
+		content = append([]byte{0}, content...)
+	}
+	return berTLV(tag, content)
+}
+
+// berIntValue decodes a non-negative INTEGER or ENUMERATED
+func berIntValue(content []byte) int {
+	v := 0
+	for _, b := range content {
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+// readTLV reads one BER element with a single-byte tag
+func readTLV(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n := int(first)
+	if first&0x80 != 0 {
+		size := int(first & 0x7f)
+		if size == 0 || size > 4 {
+			return 0, nil, fmt.Errorf("unsupported BER length")
+		}
+		n = 0
+		for range size {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			n = n<<8 | int(b)
+		}
+	}
+	if n > maxBERLength {
+		return 0, nil, fmt.Errorf("BER element of %d bytes exceeds the limit of %d", n, maxBERLength)
+	}
+	content := make([]byte, n)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return 0, nil, err
+	}
+	return tag, content, nil
+}
+
+// splitTLVs decodes the BER elements of a constructed element's content
+func splitTLVs(content []byte) ([]tlv, error) {
+	var out []tlv
+	r := bufio.NewReader(bytes.NewReader(content))
+	for {
+		if _, err := r.Peek(1); err == io.EOF {
+			return out, nil
+		}
+		tag, value, err := readTLV(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tlv{tag: tag, content: value})
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,252 @@
+package mapping
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeLDAPServer answers binds and equality searches from people, keyed by
+// the value of the searched attribute
+type fakeLDAPServer struct {
+	listener net.Listener
+	password string
+	people   map[string]map[string]string
+	tls      *tls.Config    // Accepts StartTLS with this configuration (nil = refused)
+	roots    *x509.CertPool // Trusts the certificate of tls
+
+	mu       sync.Mutex
+	searches []string // attribute=value of each search
+	tlsBinds int      // Binds received over TLS
+}
+
+func startFakeLDAPServer(t *testing.T, password string, people map[string]map[string]string) *fakeLDAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeLDAPServer{listener: listener, password: password, people: people}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	// Borrow the certificate of a TLS test server, valid for 127.0.0.1
+	https := httptest.NewTLSServer(nil)
+	https.Close()
+	s.tls = &tls.Config{Certificates: https.TLS.Certificates}
+	s.roots = x509.NewCertPool()
+	s.roots.AddCert(https.Certificate())
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// directory connects to the server, trusting its certificate
+func (s *fakeLDAPServer) directory(t *testing.T, config LDAPConfig) *LDAPDirectory {
+	t.Helper()
+	config.URL = s.url()
+	dir, err := NewLDAPDirectory(config)
+	if err != nil {
+		t.Fatalf("NewLDAPDirectory: %v", err)
+	}
+	dir.tlsConfig = &tls.Config{RootCAs: s.roots}
+	t.Cleanup(func() { _ = dir.Close() })
+	return dir
+}
+
+func (s *fakeLDAPServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	secure := false
+	for {
+		_, content, err := readTLV(r)
+		if err != nil {
+			return
+		}
+		fields, err := splitTLVs(content)
+		if err != nil || len(fields) < 2 {
+			return
+		}
+		id := berIntValue(fields[0].content)
+		op := fields[1]
+		switch op.tag {
+		case 0x60: // BindRequest
+			parts, _ := splitTLVs(op.content)
+			code := 0
+			if string(parts[2].content) != s.password {
+				code = 49
+			}
+			if secure {
+				s.mu.Lock()
+				s.tlsBinds++
+				s.mu.Unlock()
+			}
+			_, _ = conn.Write(berMessage(id, berTLV(0x61, ldapResultContent(code))))
+		case 0x77: // ExtendedRequest, only StartTLS
+			if s.tls == nil || secure {
+				_, _ = conn.Write(berMessage(id, berTLV(0x78, ldapResultContent(2))))
+				continue
+			}
+			_, _ = conn.Write(berMessage(id, berTLV(0x78, ldapResultContent(0))))
+			tlsConn := tls.Server(conn, s.tls)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, r, secure = tlsConn, bufio.NewReader(tlsConn), true
+		case 0x63: // SearchRequest
+			parts, _ := splitTLVs(op.content)
+			attr, value := equalityFilter(parts[6])
+			s.mu.Lock()
+			s.searches = append(s.searches, attr+"="+value)
+			s.mu.Unlock()
+			if person, ok := s.people[value]; ok {
+				var attrs []byte
+				for name, v := range person {
+					attrs = append(attrs, berTLV(0x30, concat(berTLV(0x04, []byte(name)), berTLV(0x31, berTLV(0x04, []byte(v)))))...)
+				}
+				entry := concat(berTLV(0x04, []byte("uid="+value+",dc=corp")), berTLV(0x30, attrs))
+				_, _ = conn.Write(berMessage(id, berTLV(0x64, entry)))
+			}
+			_, _ = conn.Write(berMessage(id, berTLV(0x65, ldapResultContent(0))))
+		case 0x42: // UnbindRequest
+			return
+		}
+	}
+}
+
+// equalityFilter returns the last equality match of a filter
+func equalityFilter(filter tlv) (string, string) {
+	if filter.tag == 0xa0 {
+		parts, _ := splitTLVs(filter.content)
+		return equalityFilter(parts[len(parts)-1])
+	}
+	parts, _ := splitTLVs(filter.content)
+	return string(parts[0].content), string(parts[1].content)
+}
+
+func ldapResultContent(code int) []byte {
+	return concat(berInt(0x0a, code), berTLV(0x04, nil), berTLV(0x04, nil))
+}
+
+func TestLDAPDirectoryLookup(t *testing.T) {
+	server := startFakeLDAPServer(t, "secret", map[string]map[string]string{
+		"jsmith": {"displayName": "John Smith", "mail": "john.smith@corp.example"},
+		"mjones": {"cn": "Mary Jones", "mail": "mary@corp.example"},
+		"svc":    {"displayName": "Build Service"},
+	})
+	dir := server.directory(t, LDAPConfig{
+		BindDN:         "cn=migrator,dc=corp",
+		Password:       "secret",
+		BaseDN:         "dc=corp",
+		LoginAttribute: "sAMAccountName",
+		ObjectClass:    "user",
+	})
+
+	entry, ok, err := dir.Lookup("jsmith")
+	if err != nil || !ok {
+		t.Fatalf("Lookup(jsmith) = %v, %v", ok, err)
+	}
+	if entry != (DirectoryEntry{Name: "John Smith", Email: "john.smith@corp.example"}) {
+		t.Errorf("Lookup(jsmith) = %+v", entry)
+	}
+
+	// The common name stands in for a missing display name
+	entry, ok, err = dir.Lookup("mjones")
+	if err != nil || !ok || entry.Name != "Mary Jones" {
+		t.Errorf("Lookup(mjones) = %+v, %v, %v", entry, ok, err)
+	}
+
+	// Unknown logins and entries without a mailbox are not found
+	for _, login := range []string{"nobody", "svc"} {
+		if _, ok, err := dir.Lookup(login); ok || err != nil {
+			t.Errorf("Lookup(%s) = %v, %v, want not found", login, ok, err)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got := strings.Join(server.searches, " "); got != "sAMAccountName=jsmith sAMAccountName=mjones sAMAccountName=nobody sAMAccountName=svc" {
+		t.Errorf("searches = %s", got)
+	}
+	if server.tlsBinds != 1 {
+		t.Errorf("%d binds over TLS, want 1: ldap:// uses StartTLS", server.tlsBinds)
+	}
+}
+
+func TestLDAPDirectoryStartTLSRequired(t *testing.T) {
+	server := startFakeLDAPServer(t, "secret", map[string]map[string]string{
+		"jsmith": {"displayName": "John Smith", "mail": "john.smith@corp.example"},
+	})
+	server.tls = nil
+
+	// Without StartTLS the password is not sent
+	dir := server.directory(t, LDAPConfig{BindDN: "cn=migrator", Password: "secret", BaseDN: "dc=corp"})
+	if _, _, err := dir.Lookup("jsmith"); err == nil || !strings.Contains(err.Error(), "StartTLS") {
+		t.Errorf("Lookup error = %v, want a StartTLS failure", err)
+	}
+
+	// unless plaintext is allowed explicitly
+	dir = server.directory(t, LDAPConfig{BindDN: "cn=migrator", Password: "secret", BaseDN: "dc=corp", AllowPlaintext: true})
+	if _, ok, err := dir.Lookup("jsmith"); !ok || err != nil {
+		t.Errorf("Lookup(jsmith) = %v, %v", ok, err)
+	}
+}
+
+func TestLDAPDirectoryBindFailure(t *testing.T) {
+	server := startFakeLDAPServer(t, "secret", nil)
+	dir := server.directory(t, LDAPConfig{BindDN: "cn=migrator", Password: "wrong", BaseDN: "dc=corp"})
+	_, _, err := dir.Lookup("jsmith")
+	if err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+		t.Errorf("Lookup error = %v, want invalid credentials", err)
+	}
+}
+
+func TestNewLDAPDirectoryValidation(t *testing.T) {
+	for _, config := range []LDAPConfig{
+		{BaseDN: "dc=corp"},
+		{URL: "ldap://host"},
+		{URL: "http://host", BaseDN: "dc=corp"},
+	} {
+		if _, err := NewLDAPDirectory(config); err == nil {
+			t.Errorf("NewLDAPDirectory(%+v) succeeded", config)
+		}
+	}
+}
+
+func TestBERRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	encoded := berTLV(0x30, concat(berInt(0x02, 200), berTLV(0x04, []byte(long))))
+	tag, content, err := readTLV(bufio.NewReader(strings.NewReader(string(encoded))))
+	if err != nil || tag != 0x30 {
+		t.Fatalf("readTLV = 0x%02x, %v", tag, err)
+	}
+	fields, err := splitTLVs(content)
+	if err != nil || len(fields) != 2 {
+		t.Fatalf("splitTLVs = %v, %v", fields, err)
+	}
+	if berIntValue(fields[0].content) != 200 || string(fields[1].content) != long {
+		t.Errorf("decoded %d, %d bytes", berIntValue(fields[0].content), len(fields[1].content))
+	}
+
+	// A length beyond the limit is rejected before anything is allocated
+	huge := []byte{0x04, 0x84, 0xff, 0xff, 0xff, 0xff}
+	if _, _, err := readTLV(bufio.NewReader(strings.NewReader(string(huge)))); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("readTLV of a 4 GB element = %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package mapping
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RefRename records a branch or tag whose name had to be changed
+type RefRename struct {
+	Kind      string // "branch" or "tag"
+	Original  string // Name after branch/tag mapping, before sanitization
+	Sanitized string // Name used in Git
+}
+
+// SanitizeRefName rewrites name so that it is a valid Git ref component
+// according to the git-check-ref-format rules. Invalid characters are
+// replaced with '_'.
+func SanitizeRefName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < 0x20 || r == 0x7f,
+			r == ' ', r == '~', r == '^', r == ':',
+			r == '?', r == '*', r == '[', r == '\\':
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+
+	for strings.Contains(s, "..") {
+		s = strings.ReplaceAll(s, "..", ".")
+	}
+	s = strings.ReplaceAll(s, "@{", "@_")
+	for strings.Contains(s, "//") {
+		s = strings.ReplaceAll(s, "//", "/")
+	}
+	s = strings.Trim(s, "/")
+
+	// Components must not start with '.' or end with ".lock"
+	parts := strings.Split(s, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			part = "_" + part[1:]
+		}
+		if strings.HasSuffix(part, ".lock") {
+			part = strings.TrimSuffix(part, ".lock") + "_lock"
+		}
+		parts[i] = part
+	}
+	s = strings.Join(parts, "/")
+
+	if strings.HasSuffix(s, ".") {
+		s = strings.TrimSuffix(s, ".") + "_"
+	}
+	if s == "" || s == "@" {
+		s = "_" + s
+	}
+	return s
+}
+
+// RefNamer assigns sanitized, unique ref names. Names are compared
+// case-insensitively so that the result is safe to check out on
+// case-insensitive file systems. Callers must assign names in a stable
+// order for the result to be deterministic.
+type RefNamer struct {
+	used map[string]bool
+}
+
+// NewRefNamer creates a new ref namer
+func NewRefNamer() *RefNamer {
+	return &RefNamer{used: make(map[string]bool)}
+}
+
+// Reserve marks name as taken without assigning it
+func (n *RefNamer) Reserve(name string) {
+	n.used[strings.ToLower(name)] = true
+}
+
+// Assign returns the sanitized form of name, with a numeric suffix appended
+// if it collides with a name assigned or reserved earlier
+func (n *RefNamer) Assign(name string) string {
+	base := SanitizeRefName(name)
+	candidate := base
+	for i := 2; n.used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+	n.used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,56 @@
+package mapping
+
+import (
+	"testing"
+)
+
+func TestSanitizeRefName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"RELEASE_1_0", "RELEASE_1_0"},
+		{"feature/login", "feature/login"},
+		{"release 1.0", "release_1.0"},
+		{"v1~beta^2", "v1_beta_2"},
+		{"a:b?c*d[e\\f", "a_b_c_d_e_f"},
+		{"v1..2...3", "v1.2.3"},
+		{"foo@{bar}", "foo@_bar}"},
+		{"/leading//slashes/", "leading/slashes"},
+		{".hidden/.dir", "_hidden/_dir"},
+		{"branch.lock", "branch_lock"},
+		{"dir.lock/name", "dir_lock/name"},
+		{"ends.", "ends_"},
+		{"tab\there", "tab_here"},
+		{"@", "_@"},
+		{"", "_"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeRefName(tt.name); got != tt.want {
+			t.Errorf("SanitizeRefName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefNamerResolvesCollisions(t *testing.T) {
+	n := NewRefNamer()
+	n.Reserve("main")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Release", "Release"},
+		{"release", "release-2"},
+		{"RELEASE", "RELEASE-3"},
+		{"MAIN", "MAIN-2"},
+		{"a b", "a_b"},
+		{"a_b", "a_b-2"},
+	}
+	for _, tt := range tests {
+		if got := n.Assign(tt.name); got != tt.want {
+			t.Errorf("Assign(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
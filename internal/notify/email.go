@@ -0,0 +1,211 @@
+// Package notify sends notifications about finished migrations.
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+)
+
+// defaultSMTPPort is the submission port, which uses STARTTLS
+const defaultSMTPPort = 587
+
+// EmailConfig configures the email sent when a migration completes or
+// fails. The password is read from an environment variable so it never
+// needs to be stored in a configuration file.
+type EmailConfig struct {
+	Host        string   `yaml:"host,omitempty"`        // SMTP server; empty disables email
+	Port        int      `yaml:"port,omitempty"`        // Default 587
+	Username    string   `yaml:"username,omitempty"`    // Empty = no authentication
+	PasswordEnv string   `yaml:"passwordEnv,omitempty"` // Environment variable holding the password
+	From        string   `yaml:"from,omitempty"`
+	To          []string `yaml:"to,omitempty"`
+	On          []string `yaml:"on,omitempty"` // "completed" and/or "failed" (empty = both)
+}
+
+// Enabled reports whether email notifications are configured
+func (c EmailConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// Validate checks an enabled configuration
+func (c EmailConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.From == "" {
+		return fmt.Errorf("from is required")
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("to requires at least one recipient")
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d", c.Port)
+	}
+	for _, status := range c.On {
+		if status != "completed" && status != "failed" {
+			return fmt.Errorf("on: unknown status %q (want completed or failed)", status)
+		}
+	}
+	return nil
+}
+
+// wants reports whether a report with the given status is sent
+func (c EmailConfig) wants(status string) bool {
+	if len(c.On) == 0 {
+		return true
+	}
+	for _, s := range c.On {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Mailer emails migration reports
+type Mailer struct {
+	config EmailConfig
+	send   func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+	now    func() time.Time
+}
+
+// NewMailer returns a mailer for an email configuration
+func NewMailer(config EmailConfig) *Mailer {
+	return &Mailer{config: config, send: smtp.SendMail, now: time.Now}
+}
+
+// SendReport emails a summary of the report with the Markdown and HTML
+// report attached. Dry runs and statuses excluded by EmailConfig.On are not
+// sent.
+func (m *Mailer) SendReport(report *core.MigrationReport) error {
+	if !m.config.Enabled() || report == nil || report.DryRun || !m.config.wants(report.Status) {
+		return nil
+	}
+
+	msg, err := m.message(report)
+	if err != nil {
+		return fmt.Errorf("failed to build notification email: %w", err)
+	}
+
+	port := m.config.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, os.Getenv(m.config.PasswordEnv), m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(port))
+	if err := m.send(addr, auth, m.config.From, m.config.To, msg); err != nil {
+		return fmt.Errorf("failed to send notification email: %w", err)
+	}
+	return nil
+}
+
+// message builds a multipart email holding the summary and the reports
+func (m *Mailer) message(report *core.MigrationReport) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	summary := textproto.MIMEHeader{}
+	summary.Set("Content-Type", "text/plain; charset=utf-8")
+	part, err := w.CreatePart(summary)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(Summary(report))); err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(core.ReportPath(report.TargetPath))
+	attachments := []struct {
+		ext, contentType string
+		write            func(*bytes.Buffer) error
+	}{
+		{core.ReportExtMarkdown, "text/markdown; charset=utf-8", func(b *bytes.Buffer) error { return report.WriteMarkdown(b) }},
+		{core.ReportExtHTML, "text/html; charset=utf-8", func(b *bytes.Buffer) error { return report.WriteHTML(b) }},
+	}
+	for _, a := range attachments {
+		var content bytes.Buffer
+		if err := a.write(&content); err != nil {
+			return nil, err
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", a.contentType)
+		header.Set("Content-Transfer-Encoding", "base64")
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + a.ext}))
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(wrapBase64(content.Bytes())); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Subject(report)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", m.now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// wrapBase64 encodes data as base64 in lines of 76 characters
+func wrapBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b bytes.Buffer
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// Subject returns the email subject of a report
+func Subject(report *core.MigrationReport) string {
+	return fmt.Sprintf("[git-migrator] Migration %s: %s", report.Status, filepath.Base(report.TargetPath))
+}
+
+// Summary returns the plain text body of a report email
+func Summary(report *core.MigrationReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Migration %s\n\n", report.Status)
+	fmt.Fprintf(&b, "Source:   %s %s\n", report.SourceType, report.SourcePath)
+	fmt.Fprintf(&b, "Target:   %s\n", report.TargetPath)
+	fmt.Fprintf(&b, "Started:  %s\n", report.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Finished: %s\n", report.FinishedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Duration: %s\n\n", time.Duration(report.DurationSeconds*float64(time.Second)).Round(time.Second))
+
+	c := report.Commits
+	fmt.Fprintf(&b, "Commits: %d total, %d applied, %d already applied, %d skipped, %d failed\n",
+		c.Total, c.Applied, c.AlreadyApplied, c.Vetoed, c.Failed)
+	fmt.Fprintf(&b, "Warnings: %d, errors: %d\n", len(report.Warnings), len(report.Errors))
+	if report.Error != "" {
+		fmt.Fprintf(&b, "\nError: %s\n", report.Error)
+	}
+	b.WriteString("\nThe full report is attached.\n")
+	return b.String()
+}
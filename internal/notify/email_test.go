@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+type sentMail struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+	msg  []byte
+}
+
+func testMailer(config EmailConfig, sent *[]sentMail) *Mailer {
+	m := NewMailer(config)
+	m.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		*sent = append(*sent, sentMail{addr, auth, from, to, msg})
+		return nil
+	}
+	m.now = func() time.Time { return time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC) }
+	return m
+}
+
+func testReport(status string) *core.MigrationReport {
+	return &core.MigrationReport{
+		SourceType:      "cvs",
+		SourcePath:      "/cvs/project",
+		TargetPath:      "/git/project",
+		Status:          status,
+		StartedAt:       time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC),
+		FinishedAt:      time.Date(2024, 5, 1, 5, 30, 0, 0, time.UTC),
+		DurationSeconds: 4.5 * 3600,
+		Commits:         core.ReportCommits{Total: 10, Applied: 9, Failed: 1},
+		Warnings:        []string{},
+		Errors:          []string{"commit 1.5 failed"},
+	}
+}
+
+func TestMailerSendReport(t *testing.T) {
+	var sent []sentMail
+	config := EmailConfig{
+		Host:        "smtp.example.com",
+		Username:    "migrator",
+		PasswordEnv: "TEST_SMTP_PASSWORD",
+		From:        "migrator@example.com",
+		To:          []string{"team@example.com", "lead@example.com"},
+	}
+	t.Setenv("TEST_SMTP_PASSWORD", "secret")
+	require.NoError(t, testMailer(config, &sent).SendReport(testReport("completed")))
+
+	require.Len(t, sent, 1)
+	require.Equal(t, "smtp.example.com:587", sent[0].addr)
+	require.NotNil(t, sent[0].auth)
+	require.Equal(t, "migrator@example.com", sent[0].from)
+	require.Equal(t, config.To, sent[0].to)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(sent[0].msg))
+	require.NoError(t, err)
+	require.Equal(t, "[git-migrator] Migration completed: project", msg.Header.Get("Subject"))
+	require.Equal(t, "team@example.com, lead@example.com", msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	part, err := reader.NextPart()
+	require.NoError(t, err)
+	body, err := io.ReadAll(part)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "Commits: 10 total, 9 applied, 0 already applied, 0 skipped, 1 failed")
+	require.Contains(t, string(body), "Duration: 4h30m0s")
+
+	var files []string
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		files = append(files, part.FileName())
+		encoded, err := io.ReadAll(part)
+		require.NoError(t, err)
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		require.NoError(t, err)
+		require.Contains(t, string(decoded), "/git/project")
+	}
+	require.Equal(t, []string{"project.migration-report.md", "project.migration-report.html"}, files)
+}
+
+func TestMailerSendReportFiltered(t *testing.T) {
+	var sent []sentMail
+	mailer := testMailer(EmailConfig{Host: "localhost", Port: 2525, From: "a@example.com", To: []string{"b@example.com"}, On: []string{"failed"}}, &sent)
+
+	require.NoError(t, mailer.SendReport(testReport("completed")))
+	dryRun := testReport("failed")
+	dryRun.DryRun = true
+	require.NoError(t, mailer.SendReport(dryRun))
+	require.Empty(t, sent)
+
+	require.NoError(t, mailer.SendReport(testReport("failed")))
+	require.Len(t, sent, 1)
+	require.Equal(t, "localhost:2525", sent[0].addr)
+	require.Nil(t, sent[0].auth)
+
+	// Disabled configurations send nothing
+	require.NoError(t, testMailer(EmailConfig{}, &sent).SendReport(testReport("failed")))
+	require.Len(t, sent, 1)
+}
+
+func TestEmailConfigValidate(t *testing.T) {
+	require.NoError(t, EmailConfig{}.Validate())
+	valid := EmailConfig{Host: "smtp", From: "a@example.com", To: []string{"b@example.com"}}
+	require.NoError(t, valid.Validate())
+
+	noFrom := valid
+	noFrom.From = ""
+	require.Error(t, noFrom.Validate())
+
+	noTo := valid
+	noTo.To = nil
+	require.Error(t, noTo.Validate())
+
+	badOn := valid
+	badOn.On = []string{"stopped"}
+	require.ErrorContains(t, badOn.Validate(), "stopped")
+}
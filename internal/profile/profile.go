@@ -0,0 +1,153 @@
+// Package profile records where a migration spends its time, per RCS file
+// and per commit, to find the bottleneck of a long migration.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of work an entry timed
+type Kind string
+
+// Kinds of timed work
+const (
+	KindParse     Kind = "parse"     // Parsing an RCS file
+	KindChangeset Kind = "changeset" // Preparing a commit: dates, authors, hooks, line endings
+	KindApply     Kind = "apply"     // Writing a commit, including reading its file contents
+)
+
+// Kinds lists the kinds in the order work happens
+var Kinds = []Kind{KindParse, KindChangeset, KindApply}
+
+// SlowestEntries is the number of slowest entries listed per kind in the
+// summary
+const SlowestEntries = 20
+
+// Entry is the time spent on one item
+type Entry struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Phase is the time spent in a migration phase
+type Phase struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Summary aggregates the entries of a kind
+type Summary struct {
+	Count        int     `json:"count"`
+	TotalSeconds float64 `json:"totalSeconds"`
+	MeanSeconds  float64 `json:"meanSeconds"`
+	MaxSeconds   float64 `json:"maxSeconds"`
+	Slowest      []Entry `json:"slowest"`
+}
+
+// Profile is the recorded timing of a run, as written to the profile file
+type Profile struct {
+	StartedAt time.Time         `json:"startedAt"`
+	Seconds   float64           `json:"seconds"`
+	Phases    []Phase           `json:"phases"`
+	Summary   map[Kind]*Summary `json:"summary"`
+	Entries   map[Kind][]Entry  `json:"entries"`
+}
+
+// Recorder collects timings. It is safe for concurrent use, and a nil
+// Recorder records nothing.
+type Recorder struct {
+	mu      sync.Mutex
+	start   time.Time
+	phases  []Phase
+	entries map[Kind][]Entry
+}
+
+// NewRecorder creates a recorder whose run starts now
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now(), entries: make(map[Kind][]Entry)}
+}
+
+// Record adds the time spent on an item
+func (r *Recorder) Record(kind Kind, name string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[kind] = append(r.entries[kind], Entry{Name: name, Seconds: d.Seconds()})
+}
+
+// Start starts timing an item; the returned function records it
+func (r *Recorder) Start(kind Kind, name string) func() {
+	if r == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { r.Record(kind, name, time.Since(start)) }
+}
+
+// AddPhase adds the time spent in a migration phase
+func (r *Recorder) AddPhase(name string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases = append(r.phases, Phase{Name: name, Seconds: d.Seconds()})
+}
+
+// Profile returns the timings recorded so far
+func (r *Recorder) Profile() *Profile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := &Profile{
+		StartedAt: r.start,
+		Seconds:   time.Since(r.start).Seconds(),
+		Phases:    append([]Phase{}, r.phases...),
+		Summary:   make(map[Kind]*Summary),
+		Entries:   make(map[Kind][]Entry),
+	}
+	for _, kind := range Kinds {
+		entries := append([]Entry{}, r.entries[kind]...)
+		p.Entries[kind] = entries
+		p.Summary[kind] = summarize(entries)
+	}
+	return p
+}
+
+// WriteFile writes the profile as JSON to path
+func (r *Recorder) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r.Profile(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+func summarize(entries []Entry) *Summary {
+	s := &Summary{Count: len(entries), Slowest: []Entry{}}
+	for _, e := range entries {
+		s.TotalSeconds += e.Seconds
+		s.MaxSeconds = max(s.MaxSeconds, e.Seconds)
+	}
+	if len(entries) > 0 {
+		s.MeanSeconds = s.TotalSeconds / float64(len(entries))
+	}
+
+	slowest := append([]Entry{}, entries...)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Seconds > slowest[j].Seconds })
+	if len(slowest) > SlowestEntries {
+		slowest = slowest[:SlowestEntries]
+	}
+	s.Slowest = append(s.Slowest, slowest...)
+	return s
+}
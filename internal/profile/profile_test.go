@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	for i := 1; i <= SlowestEntries+5; i++ {
+		r.Record(KindApply, fmt.Sprintf("commit %d", i), time.Duration(i)*time.Millisecond)
+	}
+	r.Record(KindParse, "a.c,v", 2*time.Second)
+	r.Start(KindChangeset, "1.1")()
+	r.AddPhase("apply_commits", time.Minute)
+
+	p := r.Profile()
+	require.Equal(t, []Phase{{Name: "apply_commits", Seconds: 60}}, p.Phases)
+	apply := p.Summary[KindApply]
+	require.Equal(t, SlowestEntries+5, apply.Count)
+	require.InDelta(t, 0.025, apply.MaxSeconds, 1e-9)
+	require.InDelta(t, 0.325, apply.TotalSeconds, 1e-9)
+	require.Len(t, apply.Slowest, SlowestEntries)
+	require.Equal(t, fmt.Sprintf("commit %d", SlowestEntries+5), apply.Slowest[0].Name)
+	require.Len(t, p.Entries[KindApply], SlowestEntries+5)
+	require.Equal(t, 1, p.Summary[KindChangeset].Count)
+	require.Equal(t, []Entry{{Name: "a.c,v", Seconds: 2}}, p.Entries[KindParse])
+
+	path := filepath.Join(t.TempDir(), "profile.json")
+	require.NoError(t, r.WriteFile(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var decoded Profile
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, 1, decoded.Summary[KindParse].Count)
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+	r.Record(KindParse, "a.c,v", time.Second)
+	r.Start(KindApply, "1.1")()
+	r.AddPhase("read_source", time.Second)
+}
@@ -0,0 +1,197 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bar draws the progress of a Reporter on a terminal: the percentage,
+// commit counts, ETA and current operation on one line that is redrawn in
+// place. On other outputs, such as CI logs, a plain line is printed every
+// 10 percent instead.
+type Bar struct {
+	out      io.Writer
+	tty      bool
+	columns  int           // Width of the terminal
+	interval time.Duration // Minimum time between redraws
+	verbose  bool
+
+	mu        sync.Mutex
+	status    Status
+	drawn     bool // Whether a bar is on screen
+	lastDraw  time.Time
+	lastStep  int // Last 10 percent step printed without a terminal
+	operation string
+}
+
+// barWidth is the number of columns of the bar itself
+const barWidth = 30
+
+// NewBar creates a bar writing to out; tty selects redrawing in place
+func NewBar(out io.Writer, tty bool) *Bar {
+	columns := 80
+	if c, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && c > 20 {
+		columns = c
+	}
+	return &Bar{out: out, tty: tty, columns: columns, interval: 100 * time.Millisecond, lastStep: -1}
+}
+
+// SetVerbose prints every new operation on its own line above the bar
+func (b *Bar) SetVerbose(verbose bool) {
+	b.mu.Lock()
+	b.verbose = verbose
+	b.mu.Unlock()
+}
+
+// Attach draws the progress of r until the returned function is called,
+// which draws the final state and ends the line
+func (b *Bar) Attach(r *Reporter) func() {
+	unsubscribe := r.Subscribe(b.update)
+	return func() {
+		unsubscribe()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.status = r.Status()
+		if b.tty {
+			if b.status.Total > 0 {
+				b.drawLocked()
+			}
+			if b.drawn {
+				fmt.Fprintln(b.out)
+				b.drawn = false
+			}
+		}
+	}
+}
+
+// Println prints a line above the bar
+func (b *Bar) Println(a ...any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clearLocked()
+	fmt.Fprintln(b.out, a...)
+	b.redrawLocked()
+}
+
+// Writer returns a writer for other output to the same terminal, e.g. log
+// records, that keeps the bar below it intact
+func (b *Bar) Writer(w io.Writer) io.Writer {
+	return &barWriter{bar: b, w: w}
+}
+
+type barWriter struct {
+	bar *Bar
+	w   io.Writer
+}
+
+func (bw *barWriter) Write(p []byte) (int, error) {
+	bw.bar.mu.Lock()
+	defer bw.bar.mu.Unlock()
+	bw.bar.clearLocked()
+	n, err := bw.w.Write(p)
+	bw.bar.redrawLocked()
+	return n, err
+}
+
+// update is the Reporter subscriber
+func (b *Bar) update(status Status) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = status
+
+	if status.Operation != b.operation {
+		b.operation = status.Operation
+		if b.verbose && status.Operation != "" {
+			b.clearLocked()
+			fmt.Fprintln(b.out, "  "+status.Operation)
+			b.redrawLocked()
+		}
+	}
+
+	if !b.tty {
+		if status.Total == 0 {
+			return
+		}
+		step := int(status.Percentage) / 10
+		if step != b.lastStep {
+			b.lastStep = step
+			fmt.Fprintln(b.out, b.summary(status))
+		}
+		return
+	}
+	if time.Since(b.lastDraw) >= b.interval || status.Current == status.Total {
+		b.drawLocked()
+	}
+}
+
+// drawLocked redraws the bar; the caller must hold b.mu
+func (b *Bar) drawLocked() {
+	line := Render(b.status, b.columns-1)
+	fmt.Fprint(b.out, "\r"+line+"\x1b[K")
+	b.drawn = true
+	b.lastDraw = time.Now()
+}
+
+// clearLocked removes the bar from the screen; the caller must hold b.mu
+func (b *Bar) clearLocked() {
+	if b.tty && b.drawn {
+		fmt.Fprint(b.out, "\r\x1b[K")
+	}
+}
+
+// redrawLocked draws the bar again after clearLocked
+func (b *Bar) redrawLocked() {
+	if b.tty && b.drawn {
+		b.drawLocked()
+	}
+}
+
+// summary describes the progress without a bar
+func (b *Bar) summary(status Status) string {
+	line := fmt.Sprintf("%3.0f%% (%d/%d)", status.Percentage, status.Current, status.Total)
+	if status.ETA > 0 {
+		line += " ETA " + formatDuration(status.ETA)
+	}
+	return line
+}
+
+// Render formats a status as one line of at most columns characters:
+//
+//	[=========>          ]  45% 452/1000 ETA 1m20s Processing commit 1.2
+//
+// Before the total is known only the operation is shown.
+func Render(status Status, columns int) string {
+	var line string
+	if status.Total > 0 {
+		filled := int(status.Percentage / 100 * barWidth)
+		filled = max(0, min(filled, barWidth))
+		bar := strings.Repeat("=", filled)
+		if filled < barWidth {
+			bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+		}
+		line = fmt.Sprintf("[%s] %3.0f%% %d/%d", bar, status.Percentage, status.Current, status.Total)
+		if status.ETA > 0 {
+			line += " ETA " + formatDuration(status.ETA)
+		}
+		if status.Operation != "" {
+			line += " " + status.Operation
+		}
+	} else {
+		line = status.Operation
+	}
+
+	if r := []rune(line); columns > 0 && len(r) > columns {
+		line = string(r[:columns-1]) + "…"
+	}
+	return line
+}
+
+// formatDuration rounds d to seconds, e.g. 1m20s
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,121 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	status := Status{Current: 45, Total: 100, Percentage: 45, ETA: 80 * time.Second, Operation: "Processing commit 1.2"}
+	got := Render(status, 0)
+	want := "[=============>                ]  45% 45/100 ETA 1m20s Processing commit 1.2"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderComplete(t *testing.T) {
+	got := Render(Status{Current: 10, Total: 10, Percentage: 100}, 0)
+	want := "[" + strings.Repeat("=", barWidth) + "] 100% 10/10"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderNoTotal(t *testing.T) {
+	if got := Render(Status{Operation: "Reading source"}, 0); got != "Reading source" {
+		t.Errorf("Render() = %q, want operation only", got)
+	}
+}
+
+func TestRenderTruncates(t *testing.T) {
+	status := Status{Current: 1, Total: 2, Percentage: 50, Operation: strings.Repeat("x", 100)}
+	got := Render(status, 60)
+	if n := len([]rune(got)); n != 60 {
+		t.Errorf("len = %d, want 60", n)
+	}
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("Render() = %q, want ellipsis", got)
+	}
+}
+
+func TestBarWithoutTerminal(t *testing.T) {
+	var out bytes.Buffer
+	r := NewReporter(20)
+	bar := NewBar(&out, false)
+	done := bar.Attach(r)
+	r.Start()
+	for i := 0; i < 20; i++ {
+		r.Increment()
+	}
+	done()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("got %d lines, want one per 10%%:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[10], "100% (20/20)") {
+		t.Errorf("last line = %q", lines[10])
+	}
+	if strings.Contains(out.String(), "\r") {
+		t.Error("output without a terminal should not redraw")
+	}
+}
+
+func TestBarTerminal(t *testing.T) {
+	var out bytes.Buffer
+	r := NewReporter(2)
+	bar := NewBar(&out, true)
+	done := bar.Attach(r)
+	r.Start()
+	r.Increment()
+	bar.Println("applied 1.1")
+	r.Increment()
+	done()
+
+	s := out.String()
+	if !strings.Contains(s, "\r\x1b[Kapplied 1.1\n") {
+		t.Errorf("Println should clear the bar first: %q", s)
+	}
+	if !strings.HasSuffix(s, "] 100% 2/2\x1b[K\n") {
+		t.Errorf("final bar not drawn: %q", s)
+	}
+}
+
+func TestBarVerbose(t *testing.T) {
+	var out bytes.Buffer
+	r := NewReporter(0)
+	bar := NewBar(&out, false)
+	bar.SetVerbose(true)
+	done := bar.Attach(r)
+	r.SetOperation("Syncing CVS → Git")
+	r.SetOperation("Syncing CVS → Git")
+	r.SetOperation("CVS → Git: up to date")
+	done()
+
+	want := "  Syncing CVS → Git\n  CVS → Git: up to date\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestBarWriter(t *testing.T) {
+	var out bytes.Buffer
+	r := NewReporter(4)
+	bar := NewBar(&out, true)
+	done := bar.Attach(r)
+	r.Start()
+	r.Increment()
+
+	out.Reset()
+	if _, err := bar.Writer(&out).Write([]byte("log record\n")); err != nil {
+		t.Fatal(err)
+	}
+	done()
+
+	if !strings.HasPrefix(out.String(), "\r\x1b[Klog record\n\r[") {
+		t.Errorf("writer should clear and redraw the bar: %q", out.String())
+	}
+}
@@ -6,14 +6,59 @@ import (
 	"time"
 )
 
+// Phase identifies a stage of a migration
+type Phase string
+
+// Migration phases, in the order they normally run
+const (
+	PhaseReadSource   Phase = "read_source"
+	PhaseApplyCommits Phase = "apply_commits"
+	PhaseBranches     Phase = "create_branches"
+	PhaseTags         Phase = "create_tags"
+	PhaseRepack       Phase = "repack"
+	PhasePush         Phase = "push"
+)
+
+// PhaseTiming records how long a phase ran. Duration of the active phase is
+// measured up to the time the timing was taken.
+type PhaseTiming struct {
+	Phase    Phase
+	Start    time.Time
+	End      time.Time // Zero while the phase is still running
+	Duration time.Duration
+}
+
+// Ref kinds reported by RecordRef
+const (
+	RefBranch = "branch"
+	RefTag    = "tag"
+)
+
+// RefResult is the outcome of creating one branch or tag
+type RefResult struct {
+	Kind  string // RefBranch or RefTag
+	Name  string // Git name of the ref
+	Error string // Empty when the ref was created
+	Time  time.Time
+}
+
+// RefSubscriber is a callback for created and failed refs
+type RefSubscriber func(RefResult)
+
 // Status represents the current migration status
 type Status struct {
-	Current    int
-	Total      int
-	Percentage float64
-	Operation  string
-	ETA        time.Duration
-	StartTime  time.Time
+	Current     int
+	Total       int
+	Percentage  float64
+	Operation   string
+	ETA         time.Duration
+	StartTime   time.Time
+	Elapsed     time.Duration
+	Rate        float64 // Items (commits) per second since Start
+	Phase       Phase   // Currently running phase, empty when idle
+	Phases      []PhaseTiming
+	RefsCreated int // Branches and tags created so far
+	RefsFailed  int // Branches and tags that could not be created
 }
 
 // Subscriber is a callback for progress updates
@@ -24,10 +69,16 @@ type Reporter struct {
 	mu          sync.RWMutex
 	current     int
 	total       int
+	baseline    int // Value of current when Start was called
 	operation   string
 	startTime   time.Time
 	subscribers []Subscriber
+	refSubs     []RefSubscriber
+	refsCreated int
+	refsFailed  int
 	lastUpdate  time.Time
+	phases      []PhaseTiming
+	inPhase     bool // Whether the last entry of phases is still running
 }
 
 // NewReporter creates a new progress reporter
@@ -37,11 +88,21 @@ func NewReporter(total int) *Reporter {
 	}
 }
 
-// Start begins the progress tracking
+// Start begins the progress tracking. Progress already recorded (for example
+// when resuming) is excluded from the throughput calculation.
 func (r *Reporter) Start() {
 	r.mu.Lock()
 	r.startTime = time.Now()
 	r.lastUpdate = time.Now()
+	r.baseline = r.current
+	r.mu.Unlock()
+	r.notify()
+}
+
+// SetTotal sets the total number of items
+func (r *Reporter) SetTotal(total int) {
+	r.mu.Lock()
+	r.total = total
 	r.mu.Unlock()
 	r.notify()
 }
@@ -72,6 +133,58 @@ func (r *Reporter) SetOperation(op string) {
 	r.notify()
 }
 
+// RecordRef reports the outcome of creating a branch or tag; err is nil when
+// it was created
+func (r *Reporter) RecordRef(kind, name string, err error) {
+	result := RefResult{Kind: kind, Name: name, Time: time.Now()}
+	r.mu.Lock()
+	if err != nil {
+		result.Error = err.Error()
+		r.refsFailed++
+	} else {
+		r.refsCreated++
+	}
+	subs := make([]RefSubscriber, len(r.refSubs))
+	copy(subs, r.refSubs)
+	r.mu.Unlock()
+
+	for _, fn := range subs {
+		if fn != nil {
+			fn(result)
+		}
+	}
+	r.notify()
+}
+
+// StartPhase ends the running phase (if any) and starts timing a new one
+func (r *Reporter) StartPhase(phase Phase) {
+	r.mu.Lock()
+	now := time.Now()
+	r.endPhaseLocked(now)
+	r.phases = append(r.phases, PhaseTiming{Phase: phase, Start: now})
+	r.inPhase = true
+	r.mu.Unlock()
+	r.notify()
+}
+
+// EndPhase stops timing the running phase
+func (r *Reporter) EndPhase() {
+	r.mu.Lock()
+	r.endPhaseLocked(time.Now())
+	r.mu.Unlock()
+	r.notify()
+}
+
+func (r *Reporter) endPhaseLocked(now time.Time) {
+	if !r.inPhase {
+		return
+	}
+	p := &r.phases[len(r.phases)-1]
+	p.End = now
+	p.Duration = now.Sub(p.Start)
+	r.inPhase = false
+}
+
 // Current returns the current progress
 func (r *Reporter) Current() int {
 	r.mu.RLock()
@@ -79,6 +192,13 @@ func (r *Reporter) Current() int {
 	return r.current
 }
 
+// Total returns the total number of items
+func (r *Reporter) Total() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.total
+}
+
 // Percentage returns the progress percentage
 func (r *Reporter) Percentage() float64 {
 	r.mu.RLock()
@@ -96,27 +216,76 @@ func (r *Reporter) Operation() string {
 	return r.operation
 }
 
+// Elapsed returns the time since Start was called
+func (r *Reporter) Elapsed() time.Duration {
+	return r.Status().Elapsed
+}
+
+// Rate returns the throughput in items per second since Start
+func (r *Reporter) Rate() float64 {
+	return r.Status().Rate
+}
+
 // ETA estimates time remaining
 func (r *Reporter) ETA() time.Duration {
+	return r.Status().ETA
+}
+
+// Phase returns the currently running phase, or an empty string when idle
+func (r *Reporter) Phase() Phase {
+	return r.Status().Phase
+}
+
+// Phases returns the timings of all phases started so far
+func (r *Reporter) Phases() []PhaseTiming {
+	return r.Status().Phases
+}
+
+// Status returns a snapshot of the current progress
+func (r *Reporter) Status() Status {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.statusLocked(time.Now())
+}
 
-	if r.current == 0 || r.startTime.IsZero() {
-		return 0
+// statusLocked builds a Status snapshot; the caller must hold r.mu
+func (r *Reporter) statusLocked(now time.Time) Status {
+	status := Status{
+		Current:   r.current,
+		Total:     r.total,
+		Operation: r.operation,
+		StartTime: r.startTime,
+
+		RefsCreated: r.refsCreated,
+		RefsFailed:  r.refsFailed,
 	}
 
-	elapsed := time.Since(r.startTime)
-	if elapsed == 0 {
-		return 0
+	if r.total > 0 {
+		status.Percentage = float64(r.current) / float64(r.total) * 100
 	}
 
-	rate := float64(r.current) / elapsed.Seconds()
-	if rate == 0 {
-		return 0
+	if !r.startTime.IsZero() {
+		status.Elapsed = now.Sub(r.startTime)
+		done := r.current - r.baseline
+		if done > 0 && status.Elapsed > 0 {
+			status.Rate = float64(done) / status.Elapsed.Seconds()
+			if remaining := r.total - r.current; remaining > 0 {
+				status.ETA = time.Duration(float64(remaining) / status.Rate * float64(time.Second))
+			}
+		}
+	}
+
+	if len(r.phases) > 0 {
+		status.Phases = make([]PhaseTiming, len(r.phases))
+		copy(status.Phases, r.phases)
+	}
+	if r.inPhase {
+		last := len(status.Phases) - 1
+		status.Phase = status.Phases[last].Phase
+		status.Phases[last].Duration = now.Sub(status.Phases[last].Start)
 	}
 
-	remaining := float64(r.total-r.current) / rate
-	return time.Duration(remaining) * time.Second
+	return status
 }
 
 // Subscribe adds a progress subscriber
@@ -133,44 +302,28 @@ func (r *Reporter) Subscribe(fn Subscriber) func() {
 	}
 }
 
+// SubscribeRefs adds a subscriber called for each ref reported by RecordRef
+func (r *Reporter) SubscribeRefs(fn RefSubscriber) func() {
+	r.mu.Lock()
+	r.refSubs = append(r.refSubs, fn)
+	idx := len(r.refSubs) - 1
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		r.refSubs[idx] = nil
+		r.mu.Unlock()
+	}
+}
+
 // notify notifies all subscribers
 func (r *Reporter) notify() {
 	r.mu.RLock()
-	current := r.current
-	total := r.total
-	operation := r.operation
-	startTime := r.startTime
+	status := r.statusLocked(time.Now())
 	subscribers := make([]Subscriber, len(r.subscribers))
 	copy(subscribers, r.subscribers)
 	r.mu.RUnlock()
 
-	// Calculate status outside the lock
-	percentage := float64(0)
-	if total > 0 {
-		percentage = float64(current) / float64(total) * 100
-	}
-
-	var eta time.Duration
-	if current > 0 && !startTime.IsZero() {
-		elapsed := time.Since(startTime)
-		if elapsed > 0 {
-			rate := float64(current) / elapsed.Seconds()
-			if rate > 0 {
-				remaining := float64(total-current) / rate
-				eta = time.Duration(remaining) * time.Second
-			}
-		}
-	}
-
-	status := Status{
-		Current:    current,
-		Total:      total,
-		Percentage: percentage,
-		Operation:  operation,
-		ETA:        eta,
-		StartTime:  startTime,
-	}
-
 	for _, fn := range subscribers {
 		if fn != nil {
 			fn(status)
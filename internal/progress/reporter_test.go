@@ -1,6 +1,7 @@
 package progress
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -486,3 +487,147 @@ func TestReporterNegativeValues(t *testing.T) {
 		t.Errorf("Percentage() = %v, want negative", pct)
 	}
 }
+
+func TestReporterSetTotal(t *testing.T) {
+	r := NewReporter(0)
+	r.SetTotal(40)
+	r.SetCurrent(10)
+
+	if r.Total() != 40 {
+		t.Errorf("Total = %d, want 40", r.Total())
+	}
+	if p := r.Percentage(); p != 25 {
+		t.Errorf("Percentage = %v, want 25", p)
+	}
+}
+
+func TestReporterRateAndElapsed(t *testing.T) {
+	r := NewReporter(100)
+	if r.Rate() != 0 || r.Elapsed() != 0 {
+		t.Error("Rate and Elapsed should be zero before Start()")
+	}
+
+	r.Start()
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		r.Increment()
+	}
+
+	if r.Elapsed() < 20*time.Millisecond {
+		t.Errorf("Elapsed = %v, want >= 20ms", r.Elapsed())
+	}
+	if r.Rate() <= 0 {
+		t.Errorf("Rate = %v, want > 0", r.Rate())
+	}
+	if r.ETA() <= 0 {
+		t.Errorf("ETA = %v, want > 0", r.ETA())
+	}
+}
+
+func TestReporterRateExcludesResumedProgress(t *testing.T) {
+	r := NewReporter(100)
+	r.SetCurrent(90) // resumed progress recorded before Start
+	r.Start()
+
+	if rate := r.Rate(); rate != 0 {
+		t.Errorf("Rate = %v, want 0 when nothing was processed since Start", rate)
+	}
+	if eta := r.ETA(); eta != 0 {
+		t.Errorf("ETA = %v, want 0 without throughput", eta)
+	}
+}
+
+func TestReporterPhases(t *testing.T) {
+	r := NewReporter(10)
+	if r.Phase() != "" {
+		t.Errorf("Phase = %q, want empty before any phase", r.Phase())
+	}
+
+	r.StartPhase(PhaseReadSource)
+	time.Sleep(5 * time.Millisecond)
+	r.StartPhase(PhaseApplyCommits)
+
+	if r.Phase() != PhaseApplyCommits {
+		t.Errorf("Phase = %q, want %q", r.Phase(), PhaseApplyCommits)
+	}
+
+	phases := r.Phases()
+	if len(phases) != 2 {
+		t.Fatalf("len(Phases) = %d, want 2", len(phases))
+	}
+	if phases[0].Phase != PhaseReadSource || phases[0].End.IsZero() {
+		t.Errorf("first phase should be a finished %q, got %+v", PhaseReadSource, phases[0])
+	}
+	if phases[0].Duration < 5*time.Millisecond {
+		t.Errorf("first phase Duration = %v, want >= 5ms", phases[0].Duration)
+	}
+	if !phases[1].End.IsZero() {
+		t.Error("running phase should not have an End time")
+	}
+
+	r.EndPhase()
+	if r.Phase() != "" {
+		t.Errorf("Phase = %q, want empty after EndPhase()", r.Phase())
+	}
+	if r.Phases()[1].End.IsZero() {
+		t.Error("phase should have an End time after EndPhase()")
+	}
+
+	// EndPhase without a running phase is a no-op
+	r.EndPhase()
+	if len(r.Phases()) != 2 {
+		t.Errorf("len(Phases) = %d, want 2", len(r.Phases()))
+	}
+}
+
+func TestReporterStatusIncludesPhase(t *testing.T) {
+	r := NewReporter(10)
+
+	var received Status
+	r.Subscribe(func(s Status) {
+		received = s
+	})
+
+	r.StartPhase(PhaseTags)
+	if received.Phase != PhaseTags {
+		t.Errorf("Status.Phase = %q, want %q", received.Phase, PhaseTags)
+	}
+	if len(received.Phases) != 1 {
+		t.Errorf("len(Status.Phases) = %d, want 1", len(received.Phases))
+	}
+}
+
+func TestReporterRecordRef(t *testing.T) {
+	r := NewReporter(0)
+
+	var refs []RefResult
+	unsubscribe := r.SubscribeRefs(func(ref RefResult) {
+		refs = append(refs, ref)
+	})
+	var received Status
+	r.Subscribe(func(s Status) {
+		received = s
+	})
+
+	r.RecordRef(RefBranch, "feature", nil)
+	r.RecordRef(RefTag, "v1.0", errors.New("reference already exists"))
+
+	if len(refs) != 2 {
+		t.Fatalf("len(refs) = %d, want 2", len(refs))
+	}
+	if refs[0].Kind != RefBranch || refs[0].Name != "feature" || refs[0].Error != "" {
+		t.Errorf("refs[0] = %+v, want created branch feature", refs[0])
+	}
+	if refs[1].Kind != RefTag || refs[1].Error != "reference already exists" {
+		t.Errorf("refs[1] = %+v, want failed tag v1.0", refs[1])
+	}
+	if received.RefsCreated != 1 || received.RefsFailed != 1 {
+		t.Errorf("RefsCreated, RefsFailed = %d, %d, want 1, 1", received.RefsCreated, received.RefsFailed)
+	}
+
+	unsubscribe()
+	r.RecordRef(RefTag, "v2.0", nil)
+	if len(refs) != 2 {
+		t.Errorf("unsubscribed callback was called")
+	}
+}
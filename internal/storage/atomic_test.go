@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "state.json")
+
+	require.NoError(t, WriteFileAtomic(name, []byte("first"), 0600))
+	require.NoError(t, WriteFileAtomic(name, []byte("second"), 0600))
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(data))
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files are left behind")
+
+	require.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "state.json"), []byte("x"), 0600))
+}
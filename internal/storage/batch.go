@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"log"
+	"time"
+)
+
+// Batch module statuses
+const (
+	BatchPending    = "pending"
+	BatchInProgress = "in_progress"
+	BatchCompleted  = "completed"
+	BatchFailed     = "failed"
+)
+
+// BatchModuleState records the progress of one module of a batch migration
+type BatchModuleState struct {
+	BatchID     string
+	Module      string
+	MigrationID string
+	Status      string
+	Error       string
+	LastUpdated time.Time
+}
+
+// SaveBatchModule saves the state of a batch module
+func (sdb *StateDB) SaveBatchModule(state *BatchModuleState) error {
+	state.LastUpdated = time.Now()
+
+	query := `
+	INSERT OR REPLACE INTO batch_state
+		(batch_id, module, migration_id, status, error, last_updated)
+	VALUES
+		(?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := sdb.db.Exec(query,
+		state.BatchID,
+		state.Module,
+		state.MigrationID,
+		state.Status,
+		state.Error,
+		state.LastUpdated,
+	)
+
+	return err
+}
+
+// LoadBatch returns the module states of a batch keyed by module name
+func (sdb *StateDB) LoadBatch(batchID string) (map[string]*BatchModuleState, error) {
+	query := `
+	SELECT batch_id, module, migration_id, status, error, last_updated
+	FROM batch_state
+	WHERE batch_id = ?
+	`
+
+	rows, err := sdb.db.Query(query, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	states := make(map[string]*BatchModuleState)
+	for rows.Next() {
+		state := &BatchModuleState{}
+		if err := rows.Scan(
+			&state.BatchID,
+			&state.Module,
+			&state.MigrationID,
+			&state.Status,
+			&state.Error,
+			&state.LastUpdated,
+		); err != nil {
+			return nil, err
+		}
+		states[state.Module] = state
+	}
+
+	return states, rows.Err()
+}
+
+// DeleteBatch deletes the state of all modules of a batch
+func (sdb *StateDB) DeleteBatch(batchID string) error {
+	_, err := sdb.db.Exec("DELETE FROM batch_state WHERE batch_id = ?", batchID)
+	return err
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchModuleState(t *testing.T) {
+	db, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.SaveBatchModule(&BatchModuleState{BatchID: "b1", Module: "alpha", MigrationID: "m1", Status: BatchCompleted}))
+	require.NoError(t, db.SaveBatchModule(&BatchModuleState{BatchID: "b1", Module: "beta", MigrationID: "m2", Status: BatchInProgress}))
+	require.NoError(t, db.SaveBatchModule(&BatchModuleState{BatchID: "b2", Module: "alpha", Status: BatchPending}))
+
+	// Saving again replaces the module state
+	require.NoError(t, db.SaveBatchModule(&BatchModuleState{BatchID: "b1", Module: "beta", MigrationID: "m2", Status: BatchFailed, Error: "boom"}))
+
+	states, err := db.LoadBatch("b1")
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	require.Equal(t, BatchCompleted, states["alpha"].Status)
+	require.Equal(t, "m1", states["alpha"].MigrationID)
+	require.Equal(t, BatchFailed, states["beta"].Status)
+	require.Equal(t, "boom", states["beta"].Error)
+	require.False(t, states["beta"].LastUpdated.IsZero())
+
+	require.NoError(t, db.DeleteBatch("b1"))
+	states, err = db.LoadBatch("b1")
+	require.NoError(t, err)
+	require.Empty(t, states)
+
+	states, err = db.LoadBatch("b2")
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// PendingCommit is a commit being written to the target. It is recorded
+// before the commit is created and replaced by a checkpoint afterwards, so a
+// crash in between can be told apart from a commit that was never written.
+type PendingCommit struct {
+	MigrationID string
+	ParentHash  string   // Target head the commit is created on
+	Revisions   []string // Source revisions mapped to the new commit
+	LastCommit  string   // Source revision the checkpoint resumes after
+	Processed   int
+	Total       int
+	StartedAt   time.Time
+}
+
+// ErrNoPendingCommit is returned when a migration has no commit in progress
+var ErrNoPendingCommit = errors.New("no pending commit")
+
+// BeginCommit records that a commit is about to be written to the target,
+// replacing any earlier pending commit of the migration
+func (sdb *StateDB) BeginCommit(pending *PendingCommit) error {
+	if pending.StartedAt.IsZero() {
+		pending.StartedAt = time.Now()
+	}
+
+	query := `
+	INSERT OR REPLACE INTO pending_commits
+		(migration_id, parent_hash, revisions, last_commit, processed, total, started_at)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := sdb.db.Exec(query,
+		pending.MigrationID,
+		pending.ParentHash,
+		strings.Join(pending.Revisions, "\n"),
+		pending.LastCommit,
+		pending.Processed,
+		pending.Total,
+		pending.StartedAt,
+	)
+
+	return err
+}
+
+// PendingCommit returns the commit in progress when the migration stopped
+func (sdb *StateDB) PendingCommit(migrationID string) (*PendingCommit, error) {
+	query := `
+	SELECT migration_id, parent_hash, revisions, last_commit, processed, total, started_at
+	FROM pending_commits
+	WHERE migration_id = ?
+	`
+
+	pending := &PendingCommit{}
+	var revisions string
+	err := sdb.db.QueryRow(query, migrationID).Scan(
+		&pending.MigrationID,
+		&pending.ParentHash,
+		&revisions,
+		&pending.LastCommit,
+		&pending.Processed,
+		&pending.Total,
+		&pending.StartedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoPendingCommit
+	}
+	if err != nil {
+		return nil, err
+	}
+	if revisions != "" {
+		pending.Revisions = strings.Split(revisions, "\n")
+	}
+	return pending, nil
+}
+
+// ClearPendingCommit forgets the commit in progress, e.g. because it never
+// reached the target
+func (sdb *StateDB) ClearPendingCommit(migrationID string) error {
+	_, err := sdb.db.Exec("DELETE FROM pending_commits WHERE migration_id = ?", migrationID)
+	return err
+}
+
+// Checkpoint records in one transaction that the source revisions were
+// applied as gitHash, saves the state and clears the pending commit. Either
+// all of it is stored or none.
+func (sdb *StateDB) Checkpoint(state *MigrationState, revisions []string, gitHash string) (err error) {
+	tx, err := sdb.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Warning: failed to roll back checkpoint: %v", rbErr)
+			}
+		}
+	}()
+
+	for _, rev := range revisions {
+		if err := saveMapping(tx, state.MigrationID, rev, gitHash); err != nil {
+			return fmt.Errorf("failed to save mapping for %s: %w", rev, err)
+		}
+	}
+	if err := saveState(tx, state); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM pending_commits WHERE migration_id = ?", state.MigrationID); err != nil {
+		return fmt.Errorf("failed to clear pending commit: %w", err)
+	}
+	if _, err := tx.Exec(`
+	INSERT INTO checkpoints (migration_id, last_commit, processed, total, git_hash, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`, state.MigrationID, state.LastCommit, state.Processed, state.Total, gitHash, state.LastUpdated); err != nil {
+		return fmt.Errorf("failed to record checkpoint: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SavedCheckpoint is an entry of the checkpoint history of a migration
+type SavedCheckpoint struct {
+	ID          int64 // Identifies the checkpoint across migrations
+	MigrationID string
+	LastCommit  string // Source revision the checkpoint resumes after
+	Processed   int
+	Total       int
+	GitHash     string // Target commit written at the checkpoint
+	CreatedAt   time.Time
+}
+
+// ErrNoCheckpoint is returned when a checkpoint does not exist
+var ErrNoCheckpoint = errors.New("no such checkpoint")
+
+const checkpointColumns = `id, migration_id, last_commit, processed, total, git_hash, created_at`
+
+func scanCheckpoint(row interface{ Scan(...any) error }) (*SavedCheckpoint, error) {
+	cp := &SavedCheckpoint{}
+	err := row.Scan(&cp.ID, &cp.MigrationID, &cp.LastCommit, &cp.Processed, &cp.Total, &cp.GitHash, &cp.CreatedAt)
+	return cp, err
+}
+
+// Checkpoints returns the checkpoint history of a migration, oldest first
+func (sdb *StateDB) Checkpoints(migrationID string) ([]*SavedCheckpoint, error) {
+	rows, err := sdb.db.Query(`SELECT `+checkpointColumns+` FROM checkpoints WHERE migration_id = ? ORDER BY id`, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	var checkpoints []*SavedCheckpoint
+	for rows.Next() {
+		cp, err := scanCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// LoadCheckpoint returns a checkpoint of a migration
+func (sdb *StateDB) LoadCheckpoint(migrationID string, id int64) (*SavedCheckpoint, error) {
+	row := sdb.db.QueryRow(`SELECT `+checkpointColumns+` FROM checkpoints WHERE migration_id = ? AND id = ?`, migrationID, id)
+	cp, err := scanCheckpoint(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrNoCheckpoint, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// RewindToCheckpoint makes cp the latest checkpoint of its migration: the
+// later checkpoints and the revisions they mapped are forgotten and the
+// state resumes after cp. The target must be rewound separately.
+func (sdb *StateDB) RewindToCheckpoint(cp *SavedCheckpoint) (err error) {
+	tx, err := sdb.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Warning: failed to roll back rewind: %v", rbErr)
+			}
+		}
+	}()
+
+	if _, err := tx.Exec(`
+	DELETE FROM revision_map
+	WHERE migration_id = ?
+		AND git_hash IN (SELECT git_hash FROM checkpoints WHERE migration_id = ? AND id > ?)
+		AND git_hash NOT IN (SELECT git_hash FROM checkpoints WHERE migration_id = ? AND id <= ?)
+	`, cp.MigrationID, cp.MigrationID, cp.ID, cp.MigrationID, cp.ID); err != nil {
+		return fmt.Errorf("failed to forget later revisions: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM checkpoints WHERE migration_id = ? AND id > ?", cp.MigrationID, cp.ID); err != nil {
+		return fmt.Errorf("failed to forget later checkpoints: %w", err)
+	}
+	if _, err := tx.Exec(`
+	UPDATE migration_state
+	SET last_commit = ?, processed = ?, total = ?, status = 'in_progress', last_updated = ?
+	WHERE migration_id = ?
+	`, cp.LastCommit, cp.Processed, cp.Total, time.Now(), cp.MigrationID); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM pending_commits WHERE migration_id = ?", cp.MigrationID); err != nil {
+		return fmt.Errorf("failed to clear pending commit: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDB_PendingCommitCheckpoint(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	_, err = sdb.PendingCommit("m1")
+	require.ErrorIs(t, err, ErrNoPendingCommit)
+
+	require.NoError(t, sdb.BeginCommit(&PendingCommit{
+		MigrationID: "m1",
+		ParentHash:  "aaa",
+		Revisions:   []string{"r2|alice|1", "f.txt:1.2"},
+		LastCommit:  "r2",
+		Processed:   2,
+		Total:       3,
+	}))
+	pending, err := sdb.PendingCommit("m1")
+	require.NoError(t, err)
+	require.Equal(t, "aaa", pending.ParentHash)
+	require.Equal(t, []string{"r2|alice|1", "f.txt:1.2"}, pending.Revisions)
+	require.Equal(t, "r2", pending.LastCommit)
+	require.Equal(t, 2, pending.Processed)
+	require.Equal(t, 3, pending.Total)
+	require.False(t, pending.StartedAt.IsZero())
+
+	state := &MigrationState{MigrationID: "m1", LastCommit: "r2", Processed: 2, Total: 3, Status: "in_progress"}
+	require.NoError(t, sdb.Checkpoint(state, pending.Revisions, "bbb"))
+
+	_, err = sdb.PendingCommit("m1")
+	require.ErrorIs(t, err, ErrNoPendingCommit)
+	for _, rev := range pending.Revisions {
+		hash, ok, err := sdb.LookupRevision("m1", rev)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "bbb", hash)
+	}
+	loaded, err := sdb.Load("m1")
+	require.NoError(t, err)
+	require.Equal(t, "r2", loaded.LastCommit)
+	require.Equal(t, 2, loaded.Processed)
+
+	require.NoError(t, sdb.BeginCommit(&PendingCommit{MigrationID: "m1", LastCommit: "r3"}))
+	require.NoError(t, sdb.ClearPendingCommit("m1"))
+	_, err = sdb.PendingCommit("m1")
+	require.ErrorIs(t, err, ErrNoPendingCommit)
+}
+
+func TestStateDB_CheckpointRollsBack(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+
+	require.NoError(t, sdb.BeginCommit(&PendingCommit{MigrationID: "m1", LastCommit: "r1"}))
+	_, err = sdb.db.Exec("DROP TABLE migration_state")
+	require.NoError(t, err)
+
+	state := &MigrationState{MigrationID: "m1", LastCommit: "r1", Processed: 1, Total: 1}
+	require.Error(t, sdb.Checkpoint(state, []string{"r1"}, "aaa"))
+
+	// Neither the mapping nor the cleared pending commit survive
+	_, ok, err := sdb.LookupRevision("m1", "r1")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = sdb.PendingCommit("m1")
+	require.NoError(t, err)
+	require.NoError(t, sdb.Close())
+}
+
+func TestStateDB_WALMode(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	var mode string
+	require.NoError(t, sdb.db.QueryRow("PRAGMA journal_mode").Scan(&mode))
+	require.Equal(t, "wal", mode)
+}
+
+func TestStateDB_RewindToCheckpoint(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	for i, rev := range []string{"r1", "r2", "r3"} {
+		state := &MigrationState{MigrationID: "m1", LastCommit: rev, Processed: i + 1, Total: 3, Status: "in_progress"}
+		require.NoError(t, sdb.Checkpoint(state, []string{rev, "f.txt:1." + rev[1:]}, "hash"+rev))
+	}
+	require.NoError(t, sdb.Checkpoint(&MigrationState{MigrationID: "m2", LastCommit: "x"}, []string{"x"}, "hashx"))
+
+	checkpoints, err := sdb.Checkpoints("m1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 3)
+	require.Equal(t, "r2", checkpoints[1].LastCommit)
+	require.Equal(t, "hashr2", checkpoints[1].GitHash)
+	require.False(t, checkpoints[1].CreatedAt.IsZero())
+
+	cp, err := sdb.LoadCheckpoint("m1", checkpoints[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, checkpoints[0], cp)
+	_, err = sdb.LoadCheckpoint("m2", checkpoints[0].ID)
+	require.ErrorIs(t, err, ErrNoCheckpoint)
+
+	require.NoError(t, sdb.BeginCommit(&PendingCommit{MigrationID: "m1", LastCommit: "r4"}))
+	require.NoError(t, sdb.RewindToCheckpoint(cp))
+
+	checkpoints, err = sdb.Checkpoints("m1")
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 1)
+	for rev, want := range map[string]bool{"r1": true, "f.txt:1.1": true, "r2": false, "f.txt:1.3": false} {
+		_, ok, err := sdb.LookupRevision("m1", rev)
+		require.NoError(t, err)
+		require.Equal(t, want, ok, rev)
+	}
+	_, ok, err := sdb.LookupRevision("m2", "x")
+	require.NoError(t, err)
+	require.True(t, ok, "other migrations are untouched")
+
+	state, err := sdb.Load("m1")
+	require.NoError(t, err)
+	require.Equal(t, "r1", state.LastCommit)
+	require.Equal(t, 1, state.Processed)
+	_, err = sdb.PendingCommit("m1")
+	require.ErrorIs(t, err, ErrNoPendingCommit)
+}
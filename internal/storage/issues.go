@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"log"
+	"time"
+)
+
+// MigrationIssue is a non-fatal problem recorded during a migration
+type MigrationIssue struct {
+	MigrationID string
+	Severity    string
+	Phase       string
+	Subject     string // Revision, branch or tag the issue is about
+	Message     string
+	CreatedAt   time.Time
+}
+
+// SaveIssue appends an issue to a migration
+func (sdb *StateDB) SaveIssue(issue *MigrationIssue) error {
+	if issue.CreatedAt.IsZero() {
+		issue.CreatedAt = time.Now()
+	}
+
+	query := `
+	INSERT INTO migration_issues
+		(migration_id, severity, phase, subject, message, created_at)
+	VALUES
+		(?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := sdb.db.Exec(query,
+		issue.MigrationID,
+		issue.Severity,
+		issue.Phase,
+		issue.Subject,
+		issue.Message,
+		issue.CreatedAt,
+	)
+
+	return err
+}
+
+// LoadIssues returns the issues of a migration in the order they were saved
+func (sdb *StateDB) LoadIssues(migrationID string) ([]*MigrationIssue, error) {
+	query := `
+	SELECT migration_id, severity, phase, subject, message, created_at
+	FROM migration_issues
+	WHERE migration_id = ?
+	ORDER BY id
+	`
+
+	rows, err := sdb.db.Query(query, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	var issues []*MigrationIssue
+	for rows.Next() {
+		issue := &MigrationIssue{}
+		if err := rows.Scan(
+			&issue.MigrationID,
+			&issue.Severity,
+			&issue.Phase,
+			&issue.Subject,
+			&issue.Message,
+			&issue.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, rows.Err()
+}
+
+// DeleteIssues deletes the issues of a migration
+func (sdb *StateDB) DeleteIssues(migrationID string) error {
+	_, err := sdb.db.Exec("DELETE FROM migration_issues WHERE migration_id = ?", migrationID)
+	return err
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationIssues(t *testing.T) {
+	db, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.SaveIssue(&MigrationIssue{MigrationID: "m1", Severity: "error", Subject: "1.2", Message: "first"}))
+	require.NoError(t, db.SaveIssue(&MigrationIssue{MigrationID: "m1", Severity: "warning", Subject: "dev", Message: "second"}))
+	require.NoError(t, db.SaveIssue(&MigrationIssue{MigrationID: "m2", Severity: "warning", Message: "other"}))
+
+	issues, err := db.LoadIssues("m1")
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Equal(t, "first", issues[0].Message)
+	require.Equal(t, "1.2", issues[0].Subject)
+	require.Equal(t, "second", issues[1].Message)
+	require.False(t, issues[1].CreatedAt.IsZero())
+
+	require.NoError(t, db.DeleteIssues("m1"))
+	issues, err = db.LoadIssues("m1")
+	require.NoError(t, err)
+	require.Empty(t, issues)
+
+	issues, err = db.LoadIssues("m2")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+}
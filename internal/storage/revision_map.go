@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// RevisionMapping links a source revision to the Git commit created for it
+type RevisionMapping struct {
+	MigrationID    string
+	SourceRevision string
+	GitHash        string
+	AppliedAt      time.Time
+}
+
+// ErrMappingNotFound is returned when no revision mapping matches a lookup
+var ErrMappingNotFound = errors.New("revision mapping not found")
+
+// SaveMapping records that sourceRevision was applied as gitHash.
+// An existing mapping for the same revision is replaced.
+func (sdb *StateDB) SaveMapping(migrationID, sourceRevision, gitHash string) error {
+	return saveMapping(sdb.db, migrationID, sourceRevision, gitHash)
+}
+
+func saveMapping(db execer, migrationID, sourceRevision, gitHash string) error {
+	query := `
+	INSERT OR REPLACE INTO revision_map
+		(migration_id, source_revision, git_hash, applied_at)
+	VALUES
+		(?, ?, ?, ?)
+	`
+
+	_, err := db.Exec(query, migrationID, sourceRevision, gitHash, time.Now())
+	return err
+}
+
+// LookupRevision returns the Git hash recorded for sourceRevision.
+// The boolean result is false when no mapping exists.
+func (sdb *StateDB) LookupRevision(migrationID, sourceRevision string) (string, bool, error) {
+	query := `
+	SELECT git_hash
+	FROM revision_map
+	WHERE migration_id = ? AND source_revision = ?
+	`
+
+	var hash string
+	err := sdb.db.QueryRow(query, migrationID, sourceRevision).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return hash, true, nil
+}
+
+// LookupGitHash returns the Git hash recorded for sourceRevision in any
+// migration. When several migrations mapped the revision, the most recently
+// applied mapping wins.
+func (sdb *StateDB) LookupGitHash(sourceRevision string) (string, error) {
+	query := `
+	SELECT git_hash
+	FROM revision_map
+	WHERE source_revision = ?
+	ORDER BY applied_at DESC, rowid DESC
+	LIMIT 1
+	`
+
+	var hash string
+	err := sdb.db.QueryRow(query, sourceRevision).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrMappingNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// LookupSourceRev returns the source revisions recorded for gitHash, oldest
+// first. gitHash may be abbreviated; all commits sharing the prefix match.
+func (sdb *StateDB) LookupSourceRev(gitHash string) ([]string, error) {
+	query := `
+	SELECT DISTINCT source_revision
+	FROM revision_map
+	WHERE git_hash LIKE ? || '%'
+	ORDER BY applied_at, rowid
+	`
+
+	rows, err := sdb.db.Query(query, strings.ToLower(gitHash))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	var revisions []string
+	for rows.Next() {
+		var rev string
+		if err := rows.Scan(&rev); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, ErrMappingNotFound
+	}
+	return revisions, nil
+}
+
+// RevisionMappings returns all mappings of a migration, oldest first
+func (sdb *StateDB) RevisionMappings(migrationID string) ([]*RevisionMapping, error) {
+	query := `
+	SELECT migration_id, source_revision, git_hash, applied_at
+	FROM revision_map
+	WHERE migration_id = ?
+	ORDER BY applied_at, rowid
+	`
+
+	rows, err := sdb.db.Query(query, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	var mappings []*RevisionMapping
+	for rows.Next() {
+		m := &RevisionMapping{}
+		if err := rows.Scan(&m.MigrationID, &m.SourceRevision, &m.GitHash, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
+
+// DeleteRevisionMappings removes all mappings of a migration
+func (sdb *StateDB) DeleteRevisionMappings(migrationID string) error {
+	_, err := sdb.db.Exec("DELETE FROM revision_map WHERE migration_id = ?", migrationID)
+	return err
+}
+
+// FileMappings returns the mappings of the CVS file revisions of path in
+// the migrations migrationIDs, or in any migration if there are none. When
+// a revision was mapped several times, the most recently applied mapping
+// wins.
+func (sdb *StateDB) FileMappings(path string, migrationIDs ...string) ([]*RevisionMapping, error) {
+	filter := ""
+	if len(migrationIDs) > 0 {
+		filter = "AND migration_id IN (?" + strings.Repeat(", ?", len(migrationIDs)-1) + ")"
+	}
+	query := `
+	SELECT migration_id, source_revision, git_hash, applied_at
+	FROM revision_map
+	WHERE source_revision LIKE ? ESCAPE '\' ` + filter + `
+	ORDER BY applied_at, rowid
+	`
+
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(path)
+	args := []any{escaped + ":%"}
+	for _, id := range migrationIDs {
+		args = append(args, id)
+	}
+	rows, err := sdb.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	latest := make(map[string]*RevisionMapping)
+	var mappings []*RevisionMapping
+	for rows.Next() {
+		m := &RevisionMapping{}
+		if err := rows.Scan(&m.MigrationID, &m.SourceRevision, &m.GitHash, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		// LIKE ignores case; also skip other paths sharing the prefix
+		rev, ok := strings.CutPrefix(m.SourceRevision, path+":")
+		if !ok || strings.Contains(rev, ":") {
+			continue
+		}
+		if prev, ok := latest[m.SourceRevision]; ok {
+			*prev = *m
+			continue
+		}
+		latest[m.SourceRevision] = m
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
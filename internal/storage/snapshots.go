@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CVSSnapshot records a local mirror of a remote CVS repository
+type CVSSnapshot struct {
+	Path    string // Local CVSROOT the snapshot was written to
+	Remote  string // Repository the snapshot was taken from
+	Method  string // Transfer method, e.g. rsync
+	Module  string // Mirrored module (empty = whole repository)
+	TakenAt time.Time
+	Files   int   // RCS files in the snapshot
+	Bytes   int64 // Total size of the RCS files
+}
+
+// ErrSnapshotNotFound is returned when no snapshot of a path was recorded
+var ErrSnapshotNotFound = errors.New("cvs snapshot not found")
+
+// SaveSnapshot records a snapshot. Earlier snapshots of the same path are
+// kept as history.
+func (sdb *StateDB) SaveSnapshot(snapshot *CVSSnapshot) error {
+	if snapshot.TakenAt.IsZero() {
+		snapshot.TakenAt = time.Now()
+	}
+
+	query := `
+	INSERT INTO cvs_snapshots
+		(path, remote, method, module, taken_at, files, bytes)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := sdb.db.Exec(query,
+		snapshot.Path,
+		snapshot.Remote,
+		snapshot.Method,
+		snapshot.Module,
+		snapshot.TakenAt,
+		snapshot.Files,
+		snapshot.Bytes,
+	)
+
+	return err
+}
+
+// LatestSnapshot returns the most recent snapshot written to path
+func (sdb *StateDB) LatestSnapshot(path string) (*CVSSnapshot, error) {
+	query := `
+	SELECT path, remote, method, module, taken_at, files, bytes
+	FROM cvs_snapshots
+	WHERE path = ?
+	ORDER BY id DESC
+	LIMIT 1
+	`
+
+	snapshot := &CVSSnapshot{}
+	err := sdb.db.QueryRow(query, path).Scan(
+		&snapshot.Path,
+		&snapshot.Remote,
+		&snapshot.Method,
+		&snapshot.Module,
+		&snapshot.TakenAt,
+		&snapshot.Files,
+		&snapshot.Bytes,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCVSSnapshots(t *testing.T) {
+	db, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.LatestSnapshot("/mirror")
+	require.ErrorIs(t, err, ErrSnapshotNotFound)
+
+	first := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, db.SaveSnapshot(&CVSSnapshot{Path: "/mirror", Remote: "host:/cvs", Method: "rsync", TakenAt: first, Files: 3, Bytes: 100}))
+	require.NoError(t, db.SaveSnapshot(&CVSSnapshot{Path: "/mirror", Remote: "host:/cvs", Method: "ssh-tar", Module: "mod", TakenAt: first.Add(time.Hour), Files: 4, Bytes: 120}))
+	require.NoError(t, db.SaveSnapshot(&CVSSnapshot{Path: "/other", Remote: "host:/cvs", Method: "rsync"}))
+
+	latest, err := db.LatestSnapshot("/mirror")
+	require.NoError(t, err)
+	require.Equal(t, "ssh-tar", latest.Method)
+	require.Equal(t, "mod", latest.Module)
+	require.Equal(t, 4, latest.Files)
+	require.Equal(t, int64(120), latest.Bytes)
+	require.True(t, latest.TakenAt.Equal(first.Add(time.Hour)))
+}
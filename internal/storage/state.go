@@ -3,6 +3,7 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +13,28 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// ErrCorrupt is wrapped by the errors of state database files SQLite
+// cannot read
+var ErrCorrupt = errors.New("state database is corrupt")
+
+// SQLite result codes of damaged database files
+const (
+	sqliteCorrupt = 11
+	sqliteNotADB  = 26
+)
+
+// corrupt wraps the errors of damaged database files with ErrCorrupt
+func corrupt(err error) error {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		switch coded.Code() & 0xff {
+		case sqliteCorrupt, sqliteNotADB:
+			return fmt.Errorf("%w: %w", ErrCorrupt, err)
+		}
+	}
+	return err
+}
+
 // MigrationState represents the state of a migration
 type MigrationState struct {
 	MigrationID string
@@ -47,26 +70,28 @@ func NewStateDB(path string) (*StateDB, error) {
 		if closeErr := db.Close(); closeErr != nil {
 			log.Printf("Warning: failed to close database after ping error: %v", closeErr)
 		}
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to ping database: %w", corrupt(err))
 	}
 
 	// Set connection pool settings for better reliability
 	db.SetMaxOpenConns(1)
 	db.SetMaxIdleConns(1)
 
-	// Set SQLite pragmas for better reliability during rapid test execution
+	// Set SQLite pragmas. WAL keeps the database consistent if the process
+	// or machine crashes mid-write; a crash can at most lose the last
+	// checkpoints, which resume recovers from the target.
 	// These must be set via EXEC statements, not DSN parameters, to avoid file path issues
 	pragmas := []string{
-		"PRAGMA journal_mode=DELETE;", // Use DELETE mode (default) - more reliable for rapid creation
-		"PRAGMA busy_timeout=5000;",   // Wait up to 5 seconds for locks
-		"PRAGMA synchronous=OFF;",     // Disable sync for test reliability
+		"PRAGMA journal_mode=WAL;",   // Write-ahead log: atomic, crash-safe commits
+		"PRAGMA busy_timeout=5000;",  // Wait up to 5 seconds for locks
+		"PRAGMA synchronous=NORMAL;", // Sync at WAL checkpoints, safe in WAL mode
 	}
 	for _, pragma := range pragmas {
 		if _, err := db.Exec(pragma); err != nil {
 			if closeErr := db.Close(); closeErr != nil {
 				log.Printf("Warning: failed to close database after pragma error: %v", closeErr)
 			}
-			return nil, fmt.Errorf("failed to set pragma: %w", err)
+			return nil, fmt.Errorf("failed to set pragma: %w", corrupt(err))
 		}
 	}
 
@@ -84,6 +109,63 @@ func NewStateDB(path string) (*StateDB, error) {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_status ON migration_state(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_last_updated ON migration_state(last_updated)`,
+		`CREATE TABLE IF NOT EXISTS revision_map (
+			migration_id TEXT NOT NULL,
+			source_revision TEXT NOT NULL,
+			git_hash TEXT NOT NULL,
+			applied_at TIMESTAMP,
+			PRIMARY KEY (migration_id, source_revision)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_revision_map_hash ON revision_map(git_hash)`,
+		`CREATE TABLE IF NOT EXISTS batch_state (
+			batch_id TEXT NOT NULL,
+			module TEXT NOT NULL,
+			migration_id TEXT,
+			status TEXT,
+			error TEXT,
+			last_updated TIMESTAMP,
+			PRIMARY KEY (batch_id, module)
+		)`,
+		`CREATE TABLE IF NOT EXISTS migration_issues (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			migration_id TEXT NOT NULL,
+			severity TEXT,
+			phase TEXT,
+			subject TEXT,
+			message TEXT,
+			created_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_migration_issues ON migration_issues(migration_id)`,
+		`CREATE TABLE IF NOT EXISTS cvs_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL,
+			remote TEXT,
+			method TEXT,
+			module TEXT,
+			taken_at TIMESTAMP,
+			files INTEGER,
+			bytes INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cvs_snapshots ON cvs_snapshots(path)`,
+		`CREATE TABLE IF NOT EXISTS pending_commits (
+			migration_id TEXT PRIMARY KEY,
+			parent_hash TEXT,
+			revisions TEXT,
+			last_commit TEXT,
+			processed INTEGER,
+			total INTEGER,
+			started_at TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS checkpoints (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			migration_id TEXT NOT NULL,
+			last_commit TEXT,
+			processed INTEGER,
+			total INTEGER,
+			git_hash TEXT,
+			created_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_checkpoints ON checkpoints(migration_id)`,
 	}
 
 	for _, stmt := range schemaStatements {
@@ -91,7 +173,7 @@ func NewStateDB(path string) (*StateDB, error) {
 			if closeErr := db.Close(); closeErr != nil {
 				log.Printf("Warning: failed to close database after schema error: %v", closeErr)
 			}
-			return nil, fmt.Errorf("failed to execute schema statement: %w", err)
+			return nil, fmt.Errorf("failed to execute schema statement: %w", corrupt(err))
 		}
 	}
 
@@ -102,14 +184,23 @@ func NewStateDB(path string) (*StateDB, error) {
 		if closeErr := db.Close(); closeErr != nil {
 			log.Printf("Warning: failed to close database after verification error: %v", closeErr)
 		}
-		return nil, fmt.Errorf("failed to verify database: %w", err)
+		return nil, fmt.Errorf("failed to verify database: %w", corrupt(err))
 	}
 
 	return &StateDB{db: db}, nil
 }
 
+// execer runs statements on the database or within a transaction
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 // Save saves migration state
 func (sdb *StateDB) Save(state *MigrationState) error {
+	return saveState(sdb.db, state)
+}
+
+func saveState(db execer, state *MigrationState) error {
 	state.LastUpdated = time.Now()
 
 	query := `
@@ -119,7 +210,7 @@ func (sdb *StateDB) Save(state *MigrationState) error {
 		(?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := sdb.db.Exec(query,
+	_, err := db.Exec(query,
 		state.MigrationID,
 		state.LastCommit,
 		state.Processed,
@@ -32,3 +32,105 @@ func TestStateDB_History_Delete_Close(t *testing.T) {
 	// Close DB
 	require.NoError(t, sdb.Close())
 }
+
+func TestStateDB_RevisionMappings(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	_, ok, err := sdb.LookupRevision("m1", "1.1")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, sdb.SaveMapping("m1", "1.1", "aaa"))
+	require.NoError(t, sdb.SaveMapping("m1", "1.2", "bbb"))
+	require.NoError(t, sdb.SaveMapping("m2", "1.1", "ccc"))
+
+	hash, ok, err := sdb.LookupRevision("m1", "1.1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "aaa", hash)
+
+	// Re-saving replaces the mapping
+	require.NoError(t, sdb.SaveMapping("m1", "1.1", "ddd"))
+	hash, _, err = sdb.LookupRevision("m1", "1.1")
+	require.NoError(t, err)
+	require.Equal(t, "ddd", hash)
+
+	mappings, err := sdb.RevisionMappings("m1")
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+
+	require.NoError(t, sdb.DeleteRevisionMappings("m1"))
+	mappings, err = sdb.RevisionMappings("m1")
+	require.NoError(t, err)
+	require.Empty(t, mappings)
+
+	// Other migrations are untouched
+	_, ok, err = sdb.LookupRevision("m2", "1.1")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestStateDB_LookupGitHashAndSourceRev(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	hash := "3f2a9c1e5b7d000000000000000000000000abcd"
+	require.NoError(t, sdb.SaveMapping("m1", "1.1|alice|100", hash))
+	require.NoError(t, sdb.SaveMapping("m1", "src/main.c:1.1", hash))
+
+	got, err := sdb.LookupGitHash("src/main.c:1.1")
+	require.NoError(t, err)
+	require.Equal(t, hash, got)
+
+	_, err = sdb.LookupGitHash("src/main.c:9.9")
+	require.ErrorIs(t, err, ErrMappingNotFound)
+
+	revs, err := sdb.LookupSourceRev(hash)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.1|alice|100", "src/main.c:1.1"}, revs)
+
+	// Abbreviated, upper-case hashes match as well
+	revs, err = sdb.LookupSourceRev("3F2A9C1")
+	require.NoError(t, err)
+	require.Len(t, revs, 2)
+
+	_, err = sdb.LookupSourceRev("deadbeef")
+	require.ErrorIs(t, err, ErrMappingNotFound)
+}
+
+func TestStateDB_FileMappings(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	require.NoError(t, sdb.SaveMapping("m1", "1.1|alice|100", "aaa"))
+	require.NoError(t, sdb.SaveMapping("m1", "src/a_b.c:1.1", "aaa"))
+	require.NoError(t, sdb.SaveMapping("m1", "src/a_b.c:1.2", "bbb"))
+	require.NoError(t, sdb.SaveMapping("m1", "src/axb.c:1.1", "ccc"))
+	require.NoError(t, sdb.SaveMapping("m1", "SRC/a_b.c:1.1", "ddd"))
+	require.NoError(t, sdb.SaveMapping("m2", "src/a_b.c:1.2", "eee"))
+
+	mappings, err := sdb.FileMappings("src/a_b.c")
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+	require.Equal(t, "src/a_b.c:1.1", mappings[0].SourceRevision)
+	require.Equal(t, "aaa", mappings[0].GitHash)
+	require.Equal(t, "src/a_b.c:1.2", mappings[1].SourceRevision)
+	require.Equal(t, "eee", mappings[1].GitHash)
+
+	// Limited to one migration
+	mappings, err = sdb.FileMappings("src/a_b.c", "m1")
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+	require.Equal(t, "bbb", mappings[1].GitHash)
+	mappings, err = sdb.FileMappings("src/a_b.c", "m2", "m3")
+	require.NoError(t, err)
+	require.Len(t, mappings, 1)
+
+	mappings, err = sdb.FileMappings("src/missing.c")
+	require.NoError(t, err)
+	require.Empty(t, mappings)
+}
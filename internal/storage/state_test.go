@@ -3,6 +3,7 @@ package storage
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -63,6 +64,16 @@ func TestNewStateDBInvalidPath(t *testing.T) {
 	}
 }
 
+func TestNewStateDBCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("not a database ", 16)), 0644))
+	_, err := NewStateDB(path)
+	require.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = NewStateDB(filepath.Join(t.TempDir(), "fresh.db"))
+	require.NoError(t, err)
+}
+
 func TestStateDBSave(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "statedb-test")
 	if err != nil {
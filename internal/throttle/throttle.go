@@ -0,0 +1,127 @@
+// Package throttle limits the throughput of reads, writes and file
+// operations, so that a migration can share storage with other work.
+package throttle
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// burst is how much unused throughput a limiter saves up while idle
+const burst = 100 * time.Millisecond
+
+// Limiter paces reads to a number of bytes per second, and file operations
+// such as opens and renames to a number per second, which is what loads
+// network file systems most for many small files. It may be shared by
+// several readers, which then share the throughput. A nil *Limiter does not
+// limit anything.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64   // Bytes per second (0 = unlimited)
+	next   time.Time // When the bytes taken so far are paid for
+	ops    float64   // Operations per second (0 = unlimited)
+	nextOp time.Time // When the operations so far are paid for
+}
+
+// New returns a limiter allowing bytesPerSecond, or nil for no limit if
+// bytesPerSecond is not positive
+func New(bytesPerSecond int64) *Limiter {
+	return NewWithOps(bytesPerSecond, 0)
+}
+
+// NewWithOps returns a limiter allowing bytesPerSecond and opsPerSecond
+// file operations. A limit that is not positive does not apply; nil is
+// returned if neither does.
+func NewWithOps(bytesPerSecond, opsPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 && opsPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{rate: float64(max(bytesPerSecond, 0)), ops: float64(max(opsPerSecond, 0))}
+}
+
+// Rate returns the bytes per second the limiter allows, 0 for no limit
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// Ops returns the file operations per second the limiter allows, 0 for no
+// limit
+func (l *Limiter) Ops() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.ops)
+}
+
+// Wait blocks until n more bytes may be read
+func (l *Limiter) Wait(n int) {
+	if l == nil || n <= 0 || l.rate == 0 {
+		return
+	}
+	l.pace(&l.next, float64(n)/l.rate)
+}
+
+// Op blocks until one more file operation may start
+func (l *Limiter) Op() {
+	if l == nil || l.ops == 0 {
+		return
+	}
+	l.pace(&l.nextOp, 1/l.ops)
+}
+
+// pace books seconds of throughput after next and sleeps until they are
+// paid for
+func (l *Limiter) pace(next *time.Time, seconds float64) {
+	l.mu.Lock()
+	now := time.Now()
+	if earliest := now.Add(-burst); next.Before(earliest) {
+		*next = earliest
+	}
+	*next = next.Add(time.Duration(seconds * float64(time.Second)))
+	wait := next.Sub(now)
+	l.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// Reader returns r with its reads paced by the limiter
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &reader{r: r, limiter: l}
+}
+
+type reader struct {
+	r       io.Reader
+	limiter *Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.limiter.Wait(n)
+	return n, err
+}
+
+// Writer returns w with its writes paced by the limiter
+func (l *Limiter) Writer(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &writer{w: w, limiter: l}
+}
+
+type writer struct {
+	w       io.Writer
+	limiter *Limiter
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.limiter.Wait(len(p))
+	return w.w.Write(p)
+}
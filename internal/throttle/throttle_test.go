@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	require.Nil(t, New(0))
+	require.Nil(t, New(-1))
+	require.Equal(t, int64(1000), New(1000).Rate())
+
+	// A nil limiter passes reads through
+	var l *Limiter
+	r := bytes.NewReader(nil)
+	require.Same(t, r, l.Reader(r))
+	require.Zero(t, l.Rate())
+}
+
+func TestLimiterReader(t *testing.T) {
+	l := New(1 << 20)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, l.Reader(bytes.NewReader(make([]byte, 300<<10))))
+	require.NoError(t, err)
+	require.Equal(t, int64(300<<10), n)
+	// 300 KiB at 1 MiB/s take about 293ms, less the saved up burst
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestLimiterShared(t *testing.T) {
+	l := New(1 << 20)
+	start := time.Now()
+	done := make(chan struct{})
+	for range 2 {
+		go func() {
+			_, _ = io.Copy(io.Discard, l.Reader(bytes.NewReader(make([]byte, 150<<10))))
+			done <- struct{}{}
+		}()
+	}
+	<-done
+	<-done
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond, "both readers share the rate")
+}
+
+func TestLimiterOps(t *testing.T) {
+	require.Nil(t, NewWithOps(0, 0))
+	l := NewWithOps(0, 20)
+	require.Zero(t, l.Rate())
+	require.Equal(t, int64(20), l.Ops())
+
+	// Bytes are not limited
+	r := bytes.NewReader(make([]byte, 10<<20))
+	start := time.Now()
+	_, err := io.Copy(io.Discard, l.Reader(r))
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// 6 operations at 20 per second take about 300ms, less the burst
+	start = time.Now()
+	for range 6 {
+		l.Op()
+	}
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestLimiterWriter(t *testing.T) {
+	l := New(1 << 20)
+	var buf bytes.Buffer
+	start := time.Now()
+	n, err := io.Copy(l.Writer(&buf), bytes.NewReader(make([]byte, 300<<10)))
+	require.NoError(t, err)
+	require.Equal(t, int64(300<<10), n)
+	require.Equal(t, 300<<10, buf.Len())
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	var nilLimiter *Limiter
+	require.Same(t, &buf, nilLimiter.Writer(&buf))
+}
@@ -0,0 +1,132 @@
+// Package validation checks migration and sync requests before they start,
+// reporting every violation at once instead of failing on the first.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+)
+
+// Violation is an invalid field of a request
+type Violation struct {
+	Field   string `json:"field"`   // Path of the field, e.g. source.path or options.eol
+	Message string `json:"message"` // What is wrong and how to fix it
+}
+
+func (v Violation) Error() string { return v.Message }
+
+// Errors lists the violations of a request
+type Errors []Violation
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, v := range e {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator collects the violations of a request. The zero value is ready
+// to use.
+type Validator struct {
+	violations Errors
+}
+
+// Add records a violation
+func (v *Validator) Add(violation Violation) {
+	v.violations = append(v.violations, violation)
+}
+
+// Addf records a violation of field
+func (v *Validator) Addf(field, format string, args ...any) {
+	v.Add(Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check records a violation of field unless ok, and returns ok
+func (v *Validator) Check(ok bool, field, format string, args ...any) bool {
+	if !ok {
+		v.Addf(field, format, args...)
+	}
+	return ok
+}
+
+// Required records a violation if value is empty, and reports whether it
+// is set
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(value != "", field, "%s is required", field)
+}
+
+// OneOf records a violation unless value is one of allowed, and reports
+// whether it is. Include "" in allowed for optional fields.
+func (v *Validator) OneOf(field, value string, allowed ...string) bool {
+	var names []string
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+		if a != "" {
+			names = append(names, a)
+		}
+	}
+	list := strings.Join(names, ", ")
+	if i := strings.LastIndex(list, ", "); i >= 0 {
+		list = list[:i] + " or " + list[i+2:]
+	}
+	v.Addf(field, "%s must be %s, not %q", field, list, value)
+	return false
+}
+
+// Repository records a violation unless path is a repository of kind: a
+// local CVS repository or a remote CVSROOT for "cvs", a Git repository for
+// "git". Empty paths are left to Required.
+func (v *Validator) Repository(field, path, kind string) {
+	if path == "" || (kind == "cvs" && cvs.IsRemoteRoot(path)) {
+		return
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		v.Addf(field, "%s does not exist: %s", field, path)
+		return
+	case !info.IsDir():
+		v.Addf(field, "%s is not a directory: %s", field, path)
+		return
+	}
+	switch kind {
+	case "cvs":
+		if info, err := os.Stat(filepath.Join(path, "CVSROOT")); err != nil || !info.IsDir() {
+			v.Addf(field, "%s is not a CVS repository, it has no CVSROOT directory: %s", field, path)
+		}
+	case "git":
+		if err := git.NewReader(path).Validate(); errors.Is(err, vcs.ErrNotARepo) {
+			v.Addf(field, "%s is not a Git repository: %s", field, path)
+		} else if err != nil {
+			v.Addf(field, "%s: %v", field, err)
+		}
+	default:
+		v.Addf(field, "%s: repositories of type %q cannot be checked", field, kind)
+	}
+}
+
+// Target records a violation if path exists and is not a directory, so that
+// no repository can be created there
+func (v *Validator) Target(field, path string) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		v.Addf(field, "%s is a file, not a directory: %s", field, path)
+	}
+}
+
+// Err returns the violations as Errors, or nil if there are none
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return v.violations
+}
@@ -0,0 +1,76 @@
+package validation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorCollectsAllViolations(t *testing.T) {
+	v := &Validator{}
+	require.NoError(t, v.Err())
+
+	require.False(t, v.Required("source.path", ""))
+	require.True(t, v.Required("target.path", "/git"))
+	require.False(t, v.OneOf("options.eol", "crlf", "", "as-is", "lf"))
+	require.True(t, v.OneOf("options.eol", "", "", "as-is", "lf"))
+	require.False(t, v.Check(false, "options.resume", "options.resume needs %s", "a state file"))
+
+	err := v.Err()
+	var violations Errors
+	require.True(t, errors.As(err, &violations))
+	require.Equal(t, Errors{
+		{Field: "source.path", Message: "source.path is required"},
+		{Field: "options.eol", Message: `options.eol must be as-is or lf, not "crlf"`},
+		{Field: "options.resume", Message: "options.resume needs a state file"},
+	}, violations)
+	require.Equal(t, `source.path is required; options.eol must be as-is or lf, not "crlf"; options.resume needs a state file`, err.Error())
+}
+
+func TestValidatorRepository(t *testing.T) {
+	cvsRepo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(cvsRepo, "CVSROOT"), 0755))
+	gitRepo := t.TempDir()
+	w := git.NewWriter()
+	require.NoError(t, w.Init(gitRepo))
+	require.NoError(t, w.Close())
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	v := &Validator{}
+	v.Repository("ok.cvs", cvsRepo, "cvs")
+	v.Repository("ok.git", gitRepo, "git")
+	v.Repository("ok.remote", ":pserver:anon@cvs.example.com:/cvsroot", "cvs")
+	v.Repository("ok.empty", "", "cvs")
+	require.NoError(t, v.Err())
+
+	v.Repository("missing", filepath.Join(cvsRepo, "missing"), "cvs")
+	v.Repository("file", file, "cvs")
+	v.Repository("notcvs", gitRepo, "cvs")
+	v.Repository("notgit", cvsRepo, "git")
+	var violations Errors
+	require.True(t, errors.As(v.Err(), &violations))
+	fields := make([]string, len(violations))
+	for i, violation := range violations {
+		fields[i] = violation.Field
+	}
+	require.Equal(t, []string{"missing", "file", "notcvs", "notgit"}, fields)
+	require.Contains(t, violations[2].Message, "no CVSROOT")
+	require.Contains(t, violations[3].Message, "not a Git repository")
+}
+
+func TestValidatorTarget(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	v := &Validator{}
+	v.Target("target.path", t.TempDir())
+	v.Target("target.path", filepath.Join(t.TempDir(), "new"))
+	require.NoError(t, v.Err())
+	v.Target("target.path", file)
+	require.EqualError(t, v.Err(), "target.path is a file, not a directory: "+file)
+}
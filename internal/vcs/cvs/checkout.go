@@ -0,0 +1,94 @@
+package cvs
+
+import (
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Checkout returns the live files a fresh "cvs checkout -r branch" of the
+// module holds, or of its trunk if branch is empty, as additions. A trunk
+// checkout takes the tip of a file's default branch if it has one, such as
+// the vendor branch of a file only ever updated by "cvs import". Contents
+// are the revisions as stored in the RCS files, loaded on demand. Files
+// without the branch tag are not part of a branch checkout.
+func (r *Reader) Checkout(branch string) ([]vcs.FileChange, error) {
+	if err := r.loadRCSFiles(); err != nil {
+		return nil, err
+	}
+
+	var files []vcs.FileChange
+	for _, rcs := range r.rcsFiles {
+		if rcs.Path == "" {
+			continue
+		}
+		rev := rcs.trunkTip()
+		if branch != "" {
+			number, ok := rcs.Symbols[branch]
+			if !ok {
+				continue
+			}
+			rev = rcs.branchTip(number)
+		}
+		delta := rcs.Deltas[rev]
+		if delta == nil || delta.IsDead() {
+			continue
+		}
+		fc, _ := fileChange(rcs, rev)
+		fc.Action = vcs.ActionAdd
+		fc.Keywords = r.expandMode(rcs)
+		if delta.KeywordMode != "" {
+			fc.Keywords = delta.KeywordMode
+		}
+		fc.Binary = fc.Keywords == "b"
+		if r.remote != nil {
+			fc.Source = r.remote.source(rcs.Path, rev)
+		}
+		files = append(files, fc)
+	}
+	return files, nil
+}
+
+// trunkTip returns the revision a checkout without -r gets: the head, or
+// the newest revision on the default branch (e.g. the vendor branch 1.1.1)
+// while one is set
+func (r *RCSFile) trunkTip() string {
+	if r.Branch != "" {
+		return r.branchTip(r.Branch)
+	}
+	return r.Head
+}
+
+// branchTip returns the newest revision on the branch with the given
+// number, e.g. 1.2.2.3 for the magic branch number 1.2.0.2, or the branch
+// point if the branch has no revisions yet
+func (r *RCSFile) branchTip(number string) string {
+	parts := strings.Split(number, ".")
+	if len(parts) >= 4 && parts[len(parts)-2] == "0" {
+		// Magic branch number: 1.2.0.2 is the branch 1.2.2
+		parts = append(parts[:len(parts)-2], parts[len(parts)-1])
+	}
+	if len(parts)%2 == 0 {
+		return number // A revision rather than a branch
+	}
+	prefix := strings.Join(parts, ".") + "."
+	point := strings.Join(parts[:len(parts)-1], ".")
+
+	tip := point
+	if delta := r.Deltas[point]; delta != nil {
+		for _, first := range delta.Branches {
+			if strings.HasPrefix(first, prefix) {
+				tip = first
+				break
+			}
+		}
+	}
+	for tip != point {
+		delta := r.Deltas[tip]
+		if delta == nil || delta.Next == "" {
+			break
+		}
+		tip = delta.Next
+	}
+	return tip
+}
@@ -0,0 +1,51 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func checkoutContents(t *testing.T, r *Reader, branch string) map[string]string {
+	t.Helper()
+	files, err := r.Checkout(branch)
+	require.NoError(t, err)
+	contents := make(map[string]string)
+	for _, fc := range files {
+		data, err := fc.ReadContent()
+		require.NoError(t, err)
+		contents[fc.Path] = string(data)
+	}
+	return contents
+}
+
+func TestReader_Checkout(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "Attic"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.c,v"), []byte(contentRCS), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "Attic", "old.c,v"), []byte(rcsWithStates("Exp", "dead")), 0644))
+
+	rcs, err := NewRCSParser(strings.NewReader(contentRCS)).Parse()
+	require.NoError(t, err)
+	branchTip, err := rcs.RevisionContent("1.2.2.2")
+	require.NoError(t, err)
+
+	r := NewReader(dir)
+	require.Equal(t, map[string]string{"src/main.c": "a\nb\nc\n"}, checkoutContents(t, r, ""))
+	require.Equal(t, map[string]string{"src/main.c": string(branchTip)}, checkoutContents(t, r, "FEATURE"))
+	require.Empty(t, checkoutContents(t, r, "NO_SUCH_BRANCH"))
+}
+
+func TestRCSFile_BranchTip(t *testing.T) {
+	rcs, err := NewRCSParser(strings.NewReader(contentRCS)).Parse()
+	require.NoError(t, err)
+
+	require.Equal(t, "1.2.2.2", rcs.branchTip("1.2.0.2"))
+	require.Equal(t, "1.2.2.2", rcs.branchTip("1.2.2"))
+	require.Equal(t, "1.3", rcs.branchTip("1.3"), "a tag on a revision")
+	require.Equal(t, "1.3", rcs.branchTip("1.3.0.2"), "a branch without revisions")
+}
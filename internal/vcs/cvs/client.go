@@ -0,0 +1,187 @@
+package cvs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// clientPassEnv are the variables of this process passed on to the cvs
+// client: pserver passwords live in $HOME/.cvspass or $CVS_PASSFILE, ssh
+// needs its agent or askpass program, :gserver: and Kerberos rsh need the
+// credential cache, and Windows cannot resolve hosts without SYSTEMROOT
+var clientPassEnv = []string{
+	"HOME", "USER", "LOGNAME", "SYSTEMROOT", "TMPDIR", "CVS_PASSFILE", "KRB5CCNAME",
+	"SSH_AUTH_SOCK", "SSH_ASKPASS", "SSH_ASKPASS_REQUIRE", "DISPLAY",
+}
+
+// Client runs the cvs executable. Calls do not inherit the environment:
+// the client gets CVSROOT, CVS_RSH, PATH, a C locale and UTC, the variables
+// of clientPassEnv and Env, and ignores ~/.cvsrc, so it behaves the same on
+// every machine. The zero value runs "cvs" from the PATH of this process.
+type Client struct {
+	Binary     string            // cvs executable, a path or a name looked up in SearchPath (default "cvs")
+	SearchPath string            // PATH of the cvs process (default: PATH of this process)
+	RSH        string            // CVS_RSH, the remote shell of :ext: roots (default: CVS_RSH of this process, else "ssh")
+	Env        map[string]string // Additional environment variables, e.g. CVS_SERVER
+	Timeout    time.Duration     // Time limit of each call (0 = none)
+}
+
+// CommandError is a failed call of the cvs client with its output
+type CommandError struct {
+	Command string // cvs command, e.g. commit
+	Stdout  string
+	Stderr  string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	output := strings.TrimSpace(e.Stderr)
+	if output == "" {
+		output = strings.TrimSpace(e.Stdout)
+	}
+	if output == "" {
+		return fmt.Sprintf("cvs %s failed: %v", e.Command, e.Err)
+	}
+	return fmt.Sprintf("cvs %s failed: %v: %s", e.Command, e.Err, output)
+}
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// searchPath returns the PATH of the cvs process
+func (c Client) searchPath() string {
+	if c.SearchPath != "" {
+		return c.SearchPath
+	}
+	return os.Getenv("PATH")
+}
+
+// LookPath returns the path of the cvs executable
+func (c Client) LookPath() (string, error) {
+	name := c.Binary
+	if name == "" {
+		name = "cvs"
+	}
+	if c.SearchPath == "" || filepath.Base(name) != name {
+		return exec.LookPath(name)
+	}
+	for _, dir := range filepath.SplitList(c.SearchPath) {
+		if dir == "" {
+			continue // Never the working directory
+		}
+		if path, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s: %w", name, c.SearchPath, exec.ErrNotFound)
+}
+
+// environ returns the environment of calls for the repository root
+func (c Client) environ(root string) []string {
+	rsh := c.RSH
+	if rsh == "" {
+		rsh = os.Getenv("CVS_RSH")
+	}
+	if rsh == "" {
+		rsh = "ssh"
+	}
+	env := []string{"PATH=" + c.searchPath(), "CVSROOT=" + root, "CVS_RSH=" + rsh, "LC_ALL=C", "TZ=UTC"}
+	for _, name := range clientPassEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Env)) {
+		env = append(env, name+"="+c.Env[name]) // Later entries win
+	}
+	return env
+}
+
+// Run runs the cvs command args on the repository root in dir and returns
+// its standard output. Without a dir the command runs in an empty
+// temporary directory, so that no CVS/ directory around it is used.
+// Failures are CommandErrors; calls exceeding the Timeout fail with
+// vcs.ErrTimeout.
+func (c Client) Run(dir, root string, args ...string) ([]byte, error) {
+	return c.RunContext(context.Background(), dir, root, args...)
+}
+
+// RunContext is Run killing the command once ctx is done, which fails the
+// call with the error of ctx
+func (c Client) RunContext(ctx context.Context, dir, root string, args ...string) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	binary, err := c.LookPath()
+	if err != nil {
+		return nil, fmt.Errorf("cvs client not found: %w", err)
+	}
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "git-migrator-cvs-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cvs working directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmp) }()
+		dir = tmp
+	}
+
+	callCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(callCtx, binary, append([]string{"-f", "-d", root}, args...)...) //nolint:gosec // the binary comes from the user's configuration
+	cmd.Dir = dir
+	cmd.Env = c.environ(root)
+	cmd.WaitDelay = time.Second // Children such as ssh may hold the output open
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	} else if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %s", vcs.ErrTimeout, c.Timeout)
+	}
+	if err != nil {
+		return stdout.Bytes(), &CommandError{Command: command(args), Stdout: stdout.String(), Stderr: stderr.String(), Err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// command returns the cvs command of args, skipping global options such
+// as -Q
+func command(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// Export writes a fresh "cvs export" of module (empty = the whole
+// repository) at branch (empty = trunk) from root into dir, which must not
+// exist yet. Keywords are left as stored in the RCS files, as Checkout
+// returns them.
+func (c Client) Export(root, module, branch, dir string) error {
+	if module == "" {
+		module = "."
+	}
+	if branch == "" {
+		branch = "HEAD"
+	}
+	_, err := c.Run(filepath.Dir(dir), root, "-Q", "export", "-ko", "-r", branch, "-d", filepath.Base(dir), module)
+	return err
+}
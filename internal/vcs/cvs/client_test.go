@@ -0,0 +1,109 @@
+package cvs
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// writeFakeCVS writes a cvs client script into a new directory and returns
+// the directory
+func writeFakeCVS(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cvs client is a shell script")
+	}
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "cvs"), []byte("#!/bin/sh\n"+script), 0755))
+	return bin
+}
+
+func TestClientRunEnvironment(t *testing.T) {
+	bin := writeFakeCVS(t, "echo \"args=$*\"\necho \"dir=$(pwd)\"\nenv\n")
+	t.Setenv("CVS_RSH", "rsh")
+	t.Setenv("CVSROOT", "/inherited")
+	t.Setenv("GIT_MIGRATOR_SECRET", "leaked")
+	t.Setenv("CVS_PASSFILE", "/secrets/cvspass")
+	t.Setenv("KRB5CCNAME", "FILE:/tmp/krb5cc_1000")
+
+	client := Client{SearchPath: bin + string(os.PathListSeparator) + "/usr/bin:/bin", Env: map[string]string{"CVS_SERVER": "/opt/cvs"}}
+	out, err := client.Run("", ":ext:alice@host:/cvsroot", "-Q", "rlog", "mod")
+	require.NoError(t, err)
+
+	lines := strings.Split(string(out), "\n")
+	require.Equal(t, "args=-f -d :ext:alice@host:/cvsroot -Q rlog mod", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "dir="+os.TempDir()), lines[1])
+	_, err = os.Stat(strings.TrimPrefix(lines[1], "dir="))
+	require.True(t, os.IsNotExist(err), "the temporary working directory is removed")
+
+	require.Contains(t, lines, "CVSROOT=:ext:alice@host:/cvsroot")
+	require.Contains(t, lines, "CVS_RSH=rsh")
+	require.Contains(t, lines, "PATH="+client.SearchPath)
+	require.Contains(t, lines, "LC_ALL=C")
+	require.Contains(t, lines, "CVS_SERVER=/opt/cvs")
+	require.Contains(t, lines, "CVS_PASSFILE=/secrets/cvspass")
+	require.Contains(t, lines, "KRB5CCNAME=FILE:/tmp/krb5cc_1000")
+	require.NotContains(t, string(out), "GIT_MIGRATOR_SECRET")
+
+	// A configured remote shell wins over the inherited one
+	client.RSH = "/usr/bin/ssh"
+	out, err = client.Run("", ":ext:alice@host:/cvsroot", "rlog", "mod")
+	require.NoError(t, err)
+	require.Contains(t, strings.Split(string(out), "\n"), "CVS_RSH=/usr/bin/ssh")
+	require.NotContains(t, strings.Split(string(out), "\n"), "CVSROOT=/inherited")
+}
+
+func TestClientRunInDir(t *testing.T) {
+	bin := writeFakeCVS(t, "pwd\n")
+	dir := t.TempDir()
+
+	out, err := Client{Binary: filepath.Join(bin, "cvs"), RSH: "rsh"}.Run(dir, "/cvsroot", "update")
+	require.NoError(t, err)
+	resolved, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	require.Equal(t, resolved, strings.TrimSpace(string(out)))
+}
+
+func TestClientRunCapturesOutput(t *testing.T) {
+	bin := writeFakeCVS(t, "echo 'U file'\necho 'cvs [commit aborted]: up-to-date check failed' >&2\nexit 1\n")
+
+	out, err := Client{SearchPath: bin}.Run(t.TempDir(), "/cvsroot", "-Q", "commit", "-m", "msg")
+	var cmdErr *CommandError
+	require.True(t, errors.As(err, &cmdErr))
+	require.Equal(t, "commit", cmdErr.Command)
+	require.Equal(t, "U file\n", cmdErr.Stdout)
+	require.Equal(t, "U file\n", string(out))
+	require.EqualError(t, err, "cvs commit failed: exit status 1: cvs [commit aborted]: up-to-date check failed")
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr))
+}
+
+func TestClientRunTimeout(t *testing.T) {
+	bin := writeFakeCVS(t, "exec sleep 30\n")
+
+	_, err := Client{SearchPath: bin + string(os.PathListSeparator) + "/usr/bin:/bin", Timeout: 50 * time.Millisecond}.Run("", "/cvsroot", "rlog", "mod")
+	require.ErrorIs(t, err, vcs.ErrTimeout)
+}
+
+func TestClientLookPath(t *testing.T) {
+	bin := writeFakeCVS(t, "")
+
+	path, err := Client{SearchPath: string(os.PathListSeparator) + bin}.LookPath()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(bin, "cvs"), path)
+
+	_, err = Client{Binary: "cvs-missing", SearchPath: bin}.LookPath()
+	require.ErrorIs(t, err, exec.ErrNotFound)
+
+	_, err = Client{Binary: filepath.Join(bin, "missing")}.Run("", "/cvsroot", "rlog")
+	require.ErrorContains(t, err, "cvs client not found")
+}
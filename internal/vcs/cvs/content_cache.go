@@ -0,0 +1,220 @@
+package cvs
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// DefaultContentCacheSize is the number of bytes of file revisions a
+// ContentCache keeps in memory unless told otherwise
+const DefaultContentCacheSize = 64 << 20
+
+// ContentCache keeps the contents of file revisions reconstructed from RCS
+// deltas, so every delta chain is applied once per revision. Recently used
+// revisions are kept in memory; with a directory, the revisions returned to
+// the reader are also kept on disk across runs.
+//
+// Revisions are keyed by RCS file, revision number and revision date. CVS
+// never changes a committed revision, so the disk cache stays valid as the
+// repository grows.
+type ContentCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	order    *list.List // Most recently used first
+	entries  map[contentKey]*list.Element
+	dir      string
+	stats    CacheStats
+}
+
+// CacheStats counts how the revision contents requested from a
+// ContentCache were produced
+type CacheStats struct {
+	Hits      int64 // Served from memory
+	DiskHits  int64 // Served from the disk cache
+	Misses    int64 // Reconstructed by applying deltas
+	Evictions int64 // Revisions dropped from memory to stay within the size
+	Bytes     int64 // Bytes held in memory
+}
+
+// contentKey identifies a file revision
+type contentKey struct {
+	file string // RCS file
+	rev  string
+	date int64 // Unix time of the revision, guarding against a replaced repository
+}
+
+type contentEntry struct {
+	key       contentKey
+	data      []byte
+	persisted bool // Written to or read from the disk cache
+}
+
+// NewContentCache creates a cache holding up to maxBytes of revisions in
+// memory (0 = DefaultContentCacheSize) and, if dir is not empty, any number
+// of revisions in dir
+func NewContentCache(maxBytes int64, dir string) (*ContentCache, error) {
+	if maxBytes < 0 {
+		return nil, fmt.Errorf("invalid cache size %d", maxBytes)
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultContentCacheSize
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	return &ContentCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[contentKey]*list.Element),
+		dir:      dir,
+	}, nil
+}
+
+// MaxBytes returns the bytes of revisions the cache holds in memory at most
+func (c *ContentCache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// Stats returns the cache statistics so far
+func (c *ContentCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// get returns the cached contents of a revision. Only requests of the
+// reader, not the lookups for a starting point of a delta chain, are
+// counted; a requested revision reconstructed on the way to another is
+// written to the disk cache then.
+func (c *ContentCache) get(key contentKey, count bool) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*contentEntry)
+		if count {
+			c.stats.Hits++
+			if c.dir != "" && !entry.persisted {
+				if err := c.writeDisk(key, entry.data); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+				entry.persisted = true
+			}
+		}
+		return entry.data, true
+	}
+	if c.dir != "" {
+		if data, err := os.ReadFile(c.diskPath(key)); err == nil {
+			c.add(key, data, true)
+			if count {
+				c.stats.DiskHits++
+			}
+			return data, true
+		}
+	}
+	if count {
+		c.stats.Misses++
+	}
+	return nil, false
+}
+
+// put caches the contents of a revision in memory and, if persist is set,
+// on disk. data must not be modified afterwards.
+func (c *ContentCache) put(key contentKey, data []byte, persist bool) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	persist = persist && c.dir != ""
+	c.add(key, data, persist)
+	if !persist {
+		return nil
+	}
+	return c.writeDisk(key, data)
+}
+
+// writeDisk writes the contents of a revision to the disk cache
+func (c *ContentCache) writeDisk(key contentKey, data []byte) error {
+	path := c.diskPath(key)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+// add stores data in memory, evicting the least recently used revisions.
+// Revisions larger than the whole cache are not kept.
+func (c *ContentCache) add(key contentKey, data []byte, persisted bool) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		if persisted {
+			elem.Value.(*contentEntry).persisted = true
+		}
+		return
+	}
+	for c.bytes+size > c.maxBytes {
+		oldest := c.order.Back()
+		entry := c.order.Remove(oldest).(*contentEntry)
+		delete(c.entries, entry.key)
+		c.bytes -= int64(len(entry.data))
+		c.stats.Evictions++
+	}
+	c.entries[key] = c.order.PushFront(&contentEntry{key: key, data: data, persisted: persisted})
+	c.bytes += size
+}
+
+// diskPath returns the cache file of a revision
+func (c *ContentCache) diskPath(key contentKey) string {
+	sum := sha256.Sum256([]byte(key.file + "\x00" + key.rev + "\x00" + strconv.FormatInt(key.date, 10)))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name[2:])
+}
+
+// contentKey returns the cache key of a revision of the file
+func (r *RCSFile) contentKey(rev string) contentKey {
+	key := contentKey{file: r.cacheID, rev: rev}
+	if delta := r.Deltas[rev]; delta != nil {
+		key.date = delta.Date.Unix()
+	}
+	return key
+}
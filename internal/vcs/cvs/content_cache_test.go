@@ -0,0 +1,101 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentCache_RevisionContent(t *testing.T) {
+	want := make(map[string]string)
+	plain := parseContentRCS(t)
+	for _, rev := range []string{"1.1", "1.2", "1.3", "1.2.2.1", "1.2.2.2"} {
+		data, err := plain.RevisionContent(rev)
+		require.NoError(t, err)
+		want[rev] = string(data)
+	}
+
+	cache, err := NewContentCache(0, "")
+	require.NoError(t, err)
+	rcs := parseContentRCS(t)
+	rcs.cache, rcs.cacheID = cache, "f,v"
+	for _, rev := range []string{"1.2.2.2", "1.1", "1.2.2.1", "1.3", "1.2", "1.1"} {
+		data, err := rcs.RevisionContent(rev)
+		require.NoError(t, err)
+		require.Equal(t, want[rev], string(data), rev)
+	}
+
+	// 1.2.2.2 and 1.1 reconstruct every revision on the way, so all later
+	// requests are served from memory
+	stats := cache.Stats()
+	require.Equal(t, int64(2), stats.Misses)
+	require.Equal(t, int64(4), stats.Hits)
+	require.Positive(t, stats.Bytes)
+
+	_, err = rcs.RevisionContent("9.9")
+	require.Error(t, err)
+}
+
+func TestContentCache_Disk(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewContentCache(0, dir)
+	require.NoError(t, err)
+	rcs := parseContentRCS(t)
+	rcs.cache, rcs.cacheID = cache, "f,v"
+	first, err := rcs.RevisionContent("1.2.2.1")
+	require.NoError(t, err)
+
+	// Only the requested revision is written to disk
+	var files []string
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	}))
+	require.Len(t, files, 1)
+
+	// A new run reads it back without applying deltas
+	cache, err = NewContentCache(0, dir)
+	require.NoError(t, err)
+	rcs = parseContentRCS(t)
+	rcs.cache, rcs.cacheID = cache, "f,v"
+	again, err := rcs.RevisionContent("1.2.2.1")
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+	require.Equal(t, CacheStats{DiskHits: 1, Bytes: int64(len(again))}, cache.Stats())
+
+	// Another file with the same revision number is not confused with it
+	rcs.cacheID = "g,v"
+	_, err = rcs.RevisionContent("1.2.2.1")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), cache.Stats().Misses)
+}
+
+func TestContentCache_Eviction(t *testing.T) {
+	cache, err := NewContentCache(10, "")
+	require.NoError(t, err)
+	key := func(rev string) contentKey { return contentKey{file: "f,v", rev: rev} }
+
+	require.NoError(t, cache.put(key("1.1"), []byte("12345"), false))
+	require.NoError(t, cache.put(key("1.2"), []byte("12345"), false))
+	_, ok := cache.get(key("1.1"), true) // 1.1 becomes the most recently used
+	require.True(t, ok)
+	require.NoError(t, cache.put(key("1.3"), []byte("123"), false))
+
+	_, ok = cache.get(key("1.2"), true)
+	require.False(t, ok, "the least recently used revision is evicted")
+	_, ok = cache.get(key("1.1"), true)
+	require.True(t, ok)
+
+	require.NoError(t, cache.put(key("1.4"), []byte("12345678901"), false))
+	_, ok = cache.get(key("1.4"), true)
+	require.False(t, ok, "revisions larger than the cache are not kept")
+
+	require.Equal(t, CacheStats{Hits: 2, Misses: 2, Evictions: 1, Bytes: 8}, cache.Stats())
+
+	_, err = NewContentCache(-1, "")
+	require.Error(t, err)
+}
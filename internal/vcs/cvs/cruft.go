@@ -0,0 +1,206 @@
+package cvs
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of Cruft
+const (
+	CruftLock           = "lock"            // Lock left in a directory by a CVS server
+	CruftTempFile       = "temp-file"       // Temporary file of an interrupted RCS write
+	CruftTruncated      = "truncated"       // RCS file cut off before its end
+	CruftMissingHead    = "missing-head"    // RCS file without a valid head revision
+	CruftDanglingSymbol = "dangling-symbol" // Tag or branch of a revision the file lacks
+)
+
+// lockPrefixes are the names of the locks CVS takes in repository
+// directories: the master lock and the read, write and promotable locks
+var lockPrefixes = []string{"#cvs.lock", "#cvs.rfl", "#cvs.wfl", "#cvs.pfl"}
+
+// Cruft is an anomaly of a repository that makes a migration fail or lose
+// history, with a suggested remedy
+type Cruft struct {
+	Path    string `json:"path"` // Relative to the repository root
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Remedy  string `json:"remedy"`
+}
+
+// Cruft scans the module's directories for leftover locks and temporary
+// files and for RCS files that are truncated, lack a head revision or have
+// symbols of revisions they do not contain. Remote repositories are not
+// supported.
+func (r *Reader) Cruft() ([]Cruft, error) {
+	if r.remote != nil {
+		return nil, fmt.Errorf("scanning a remote repository for cruft is not supported")
+	}
+	dirs := []string{""}
+	if r.module != "" {
+		mds, err := r.moduleDirs()
+		if err != nil {
+			return nil, err
+		}
+		dirs = dirs[:0]
+		for _, md := range mds {
+			dirs = append(dirs, md.Dir)
+		}
+	}
+
+	var found []Cruft
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(r.path, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Unreadable entries are reported by Validate
+			}
+			rel, err := filepath.Rel(r.path, path)
+			if err != nil || seen[rel] {
+				return nil
+			}
+			seen[rel] = true
+			rel = filepath.ToSlash(rel)
+
+			name := d.Name()
+			if isLockName(name) {
+				found = append(found, lockCruft(rel, d))
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			switch {
+			case len(name) > 2 && strings.HasPrefix(name, ",") && strings.HasSuffix(name, ","):
+				found = append(found, Cruft{
+					Path:    rel,
+					Kind:    CruftTempFile,
+					Message: "temporary file of an interrupted RCS write",
+					Remedy: fmt.Sprintf("make sure no CVS or RCS process is running, compare it with %s,v and delete it",
+						strings.Trim(name, ",")),
+				})
+			case strings.HasSuffix(name, ",v"):
+				found = append(found, auditRCSFile(path, rel)...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found, nil
+}
+
+// isLockName reports whether name is a CVS lock
+func isLockName(name string) bool {
+	for _, prefix := range lockPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockCruft describes a lock, with its age if known
+func lockCruft(rel string, d fs.DirEntry) Cruft {
+	message := "CVS lock"
+	if info, err := d.Info(); err == nil {
+		message = fmt.Sprintf("CVS lock taken %s ago", time.Since(info.ModTime()).Round(time.Second))
+	}
+	return Cruft{
+		Path:    rel,
+		Kind:    CruftLock,
+		Message: message,
+		Remedy:  "make sure no CVS server process is using the repository, then delete the lock",
+	}
+}
+
+// auditRCSFile checks an RCS file for the anomalies that stop a migration
+func auditRCSFile(path, rel string) []Cruft {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil // Reported by Validate
+	}
+	restore := fmt.Sprintf("restore %s from a backup; otherwise exclude it or accept that the revisions it lacks are lost", rel)
+	if len(data) == 0 {
+		return []Cruft{{Path: rel, Kind: CruftTruncated, Message: "empty RCS file", Remedy: restore}}
+	}
+
+	parser := NewRCSParser(bytes.NewReader(data))
+	parser.SetFile(rel)
+	rcs, err := parser.Parse()
+	if err != nil {
+		return nil // Only strict parsing fails
+	}
+
+	var found []Cruft
+	var missing []string
+	for _, rev := range rcs.DeltaOrder {
+		if !parser.texts[rev] {
+			missing = append(missing, rev)
+		}
+	}
+	unterminated := false
+	for _, d := range parser.Diagnostics() {
+		unterminated = unterminated || d.Message == "unterminated string"
+	}
+	switch {
+	case len(missing) > 0:
+		found = append(found, Cruft{Path: rel, Kind: CruftTruncated,
+			Message: fmt.Sprintf("no text for revisions %s", strings.Join(missing, ", ")), Remedy: restore})
+	case unterminated:
+		found = append(found, Cruft{Path: rel, Kind: CruftTruncated,
+			Message: "file ends inside a string", Remedy: restore})
+	}
+
+	switch {
+	case rcs.Head == "" && len(rcs.DeltaOrder) > 0:
+		found = append(found, Cruft{Path: rel, Kind: CruftMissingHead, Message: "no head revision",
+			Remedy: "set head to the latest trunk revision in the admin section, or restore the file from a backup"})
+	case rcs.Head != "" && rcs.Deltas[rcs.Head] == nil:
+		found = append(found, Cruft{Path: rel, Kind: CruftMissingHead,
+			Message: fmt.Sprintf("head revision %s is not in the file", rcs.Head),
+			Remedy:  "set head to the latest trunk revision in the admin section, or restore the file from a backup"})
+	}
+
+	names := make([]string, 0, len(rcs.Symbols))
+	for name := range rcs.Symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rev := rcs.Symbols[name]
+		if symbolTarget(rev, rcs.Deltas) {
+			continue
+		}
+		found = append(found, Cruft{Path: rel, Kind: CruftDanglingSymbol,
+			Message: fmt.Sprintf("symbol %s points at revision %s, which is not in the file", name, rev),
+			Remedy: fmt.Sprintf("delete the symbol with \"rcs -n%s %s\" or exclude %s from the migration",
+				name, filepath.Base(rel), name)})
+	}
+	return found
+}
+
+// symbolTarget reports whether the revision of a symbol exists: the
+// revision itself for a tag, the branch point for a branch
+func symbolTarget(rev string, deltas map[string]*Delta) bool {
+	parts := strings.Split(rev, ".")
+	switch {
+	case len(parts) >= 4 && parts[len(parts)-2] == "0":
+		// Magic branch number 1.2.0.4 branches from 1.2
+		return deltas[strings.Join(parts[:len(parts)-2], ".")] != nil
+	case len(parts)%2 == 1 && len(parts) >= 3:
+		// Branch number, e.g. the vendor branch 1.1.1 of revision 1.1
+		return deltas[strings.Join(parts[:len(parts)-1], ".")] != nil
+	}
+	return deltas[rev] != nil
+}
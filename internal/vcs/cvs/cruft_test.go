@@ -0,0 +1,73 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cruftRCS returns a two-revision RCS file with the given head and symbols
+func cruftRCS(head, symbols string) string {
+	return "head " + head + ";\naccess;\nsymbols" + symbols + ";\nlocks;\n\n" +
+		"1.2\ndate 2024.01.02.00.00.00; author bob; state Exp;\nbranches;\nnext 1.1;\n\n" +
+		"1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\n" +
+		"desc\n@@\n\n" +
+		"1.2\nlog\n@second\n@\ntext\n@two\n@\n\n" +
+		"1.1\nlog\n@first\n@\ntext\n@d1 1\na1 1\none\n@\n"
+}
+
+func TestReaderCruft(t *testing.T) {
+	repo := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(repo, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	ok := cruftRCS("1.2", "\n\tREL_1:1.1")
+	write("src/ok.c,v", ok)
+	write("src/tags.c,v", cruftRCS("1.2", "\n\tREL_1:1.1\n\tGONE:1.7\n\tFIX:1.2.0.2\n\tOLD_BRANCH:1.5.0.2\n\tVENDOR:1.1.1"))
+	write("src/cut.c,v", ok[:len(ok)-30])
+	write("src/nohead.c,v", cruftRCS("1.9", ""))
+	write("src/empty.c,v", "")
+	write("src/,ok.c,", "partial")
+	write("src/#cvs.rfl.host.1234", "")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "src", "#cvs.lock"), 0755))
+
+	found, err := NewReader(repo).Cruft()
+	require.NoError(t, err)
+
+	byKind := make(map[string][]string)
+	for _, c := range found {
+		require.NotEmpty(t, c.Remedy)
+		byKind[c.Kind] = append(byKind[c.Kind], c.Path)
+	}
+	require.ElementsMatch(t, []string{"src/#cvs.lock", "src/#cvs.rfl.host.1234"}, byKind[CruftLock])
+	require.Equal(t, []string{"src/,ok.c,"}, byKind[CruftTempFile])
+	require.ElementsMatch(t, []string{"src/cut.c,v", "src/empty.c,v"}, byKind[CruftTruncated])
+	require.Equal(t, []string{"src/nohead.c,v"}, byKind[CruftMissingHead])
+	require.Equal(t, []string{"src/tags.c,v", "src/tags.c,v"}, byKind[CruftDanglingSymbol])
+
+	var symbols []string
+	for _, c := range found {
+		if c.Kind == CruftDanglingSymbol {
+			symbols = append(symbols, c.Message)
+		}
+	}
+	require.Contains(t, symbols[0], "symbol GONE points at revision 1.7")
+	require.Contains(t, symbols[1], "symbol OLD_BRANCH points at revision 1.5.0.2")
+}
+
+func TestReaderCruftModule(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "a", "#cvs.lock"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "b", "#cvs.lock"), 0755))
+
+	found, err := NewModuleReader(repo, "a").Cruft()
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, "a/#cvs.lock", found[0].Path)
+}
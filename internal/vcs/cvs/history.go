@@ -0,0 +1,168 @@
+package cvs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyEvents names the record types of CVSROOT/history
+var historyEvents = map[string]string{
+	"O": "checkout",
+	"E": "export",
+	"F": "release",
+	"T": "rtag",
+	"C": "update-conflict",
+	"G": "update-merge",
+	"U": "update",
+	"P": "update-patch",
+	"W": "update-delete",
+	"J": "join",
+	"M": "commit",
+	"A": "add",
+	"R": "remove",
+}
+
+// HistoryRecord is an event logged in CVSROOT/history, such as a checkout,
+// tag or commit
+type HistoryRecord struct {
+	Code       string    // Record type, e.g. "M" for a commit
+	Time       time.Time // When the event happened
+	User       string
+	Directory  string // Working directory of the client
+	Repository string // Repository directory, or module of a checkout or tag
+	Revision   string // File revision, or the options of a tag
+	File       string // File name of file events; other events log e.g. the checkout directory
+}
+
+// Event returns the name of the record type, e.g. "commit"
+func (h HistoryRecord) Event() string {
+	if name, ok := historyEvents[h.Code]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsCommit reports whether the record logs a file revision checked in by a
+// commit, add or remove
+func (h HistoryRecord) IsCommit() bool {
+	return h.Code == "M" || h.Code == "A" || h.Code == "R"
+}
+
+// Path returns the repository path of the file of a file event, or the
+// repository directory or module of other events
+func (h HistoryRecord) Path() string {
+	if h.File == "" || strings.Contains("OEFT", h.Code) {
+		return h.Repository
+	}
+	if h.Repository == "" {
+		return h.File
+	}
+	return h.Repository + "/" + h.File
+}
+
+// ParseHistory reads the records of a CVSROOT/history file. Lines that are
+// not history records are skipped; their number is returned.
+func ParseHistory(r io.Reader) ([]HistoryRecord, int, error) {
+	var records []HistoryRecord
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		record, ok := parseHistoryLine(line)
+		if !ok {
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, skipped, scanner.Err()
+}
+
+// parseHistoryLine parses "<code><hex time>|user|dir|repository|rev|file"
+func parseHistoryLine(line string) (HistoryRecord, bool) {
+	fields := strings.Split(line[1:], "|")
+	code := line[:1]
+	if _, ok := historyEvents[code]; !ok || len(fields) < 4 {
+		return HistoryRecord{}, false
+	}
+	seconds, err := strconv.ParseInt(fields[0], 16, 64)
+	if err != nil {
+		return HistoryRecord{}, false
+	}
+	for len(fields) < 6 {
+		fields = append(fields, "")
+	}
+	return HistoryRecord{
+		Code:       code,
+		Time:       time.Unix(seconds, 0).UTC(),
+		User:       fields[1],
+		Directory:  fields[2],
+		Repository: fields[3],
+		Revision:   fields[4],
+		File:       fields[5],
+	}, true
+}
+
+// History returns the records of CVSROOT/history about the reader's module,
+// with repository paths as in the module, like the file paths. It returns nothing if the
+// repository keeps no history file. Remote repositories are not supported.
+func (r *Reader) History() ([]HistoryRecord, error) {
+	if r.remote != nil {
+		return nil, fmt.Errorf("reading CVSROOT/history of a remote repository is not supported")
+	}
+	file, err := os.Open(filepath.Join(r.path, "CVSROOT", "history"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CVSROOT/history: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	records, _, err := ParseHistory(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CVSROOT/history: %w", err)
+	}
+	if r.module == "" {
+		return records, nil
+	}
+	dirs, err := r.moduleDirs()
+	if err != nil {
+		return nil, err
+	}
+	module := strings.Trim(r.module, "/")
+	var kept []HistoryRecord
+	for _, h := range records {
+		if h.Repository == module && h.Path() == module {
+			// A checkout, export or tag of the module itself
+			h.Repository = ""
+			kept = append(kept, h)
+			continue
+		}
+		for _, md := range dirs {
+			if modulePath, ok := md.contains(h.Path(), h.Path() != h.Repository); ok {
+				if h.Path() != h.Repository {
+					if modulePath = path.Dir(modulePath); modulePath == "." {
+						modulePath = ""
+					}
+				}
+				h.Repository = modulePath
+				kept = append(kept, h)
+				break
+			}
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,66 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testHistory = "O65920080|alice|~/work/*0|mod||mod\n" +
+	"M65920100|alice|~/work/mod|mod/src|1.2|main.c\r\n" +
+	"A65920100|alice|~/work/mod|mod/src|1.1|util.c\n" +
+	"T65920200|bob|<remote>|mod|[REL_1:A]\n" +
+	"M65920300|carol|~/other|other|1.3|x.c\n" +
+	"\n" +
+	"garbage line\n" +
+	"Mnothex|alice|~|mod|1.1|a.c\n"
+
+func TestParseHistory(t *testing.T) {
+	records, skipped, err := ParseHistory(strings.NewReader(testHistory))
+	require.NoError(t, err)
+	require.Equal(t, 2, skipped)
+	require.Len(t, records, 5)
+
+	checkout := records[0]
+	require.Equal(t, "checkout", checkout.Event())
+	require.Equal(t, time.Unix(0x65920080, 0).UTC(), checkout.Time)
+	require.Equal(t, "alice", checkout.User)
+	require.Equal(t, "mod", checkout.Path())
+	require.False(t, checkout.IsCommit())
+
+	commit := records[1]
+	require.Equal(t, "commit", commit.Event())
+	require.True(t, commit.IsCommit())
+	require.Equal(t, "1.2", commit.Revision)
+	require.Equal(t, "mod/src/main.c", commit.Path())
+
+	tag := records[3]
+	require.Equal(t, "rtag", tag.Event())
+	require.Equal(t, "[REL_1:A]", tag.Revision)
+	require.Empty(t, tag.File)
+}
+
+func TestReaderHistory(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+
+	records, err := NewReader(repo).History()
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "CVSROOT", "history"), []byte(testHistory), 0644))
+	records, err = NewReader(repo).History()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+
+	// A module reader keeps the module's records, relative to the module
+	records, err = NewModuleReader(repo, "mod").History()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, "", records[0].Path())
+	require.Equal(t, "src/main.c", records[1].Path())
+}
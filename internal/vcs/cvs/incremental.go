@@ -0,0 +1,117 @@
+package cvs
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// FileStamp identifies the version of an RCS file. CVS rewrites the whole
+// file on every commit, so a file with the same stamp holds no new
+// revisions.
+type FileStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// SetKnownFiles makes the reader skip the RCS files whose stamp matches
+// known, as returned by FileStamps after an earlier read. Their revisions
+// are left out of every result, so only the history added since that read
+// is returned. It must be called before the commits are read and has no
+// effect on remote repositories.
+func (r *Reader) SetKnownFiles(known map[string]FileStamp) {
+	r.known = known
+}
+
+// FileStamps returns the stamps of the RCS files of the module found by the
+// last read, files skipped as unchanged included, keyed by their path
+// relative to the repository root. Unreadable files are left out, so the
+// next read retries them.
+func (r *Reader) FileStamps() map[string]FileStamp {
+	return r.stamps
+}
+
+// SetSyncedRevisions makes GetCommitsSince leave out the file revisions in
+// synced, keyed by file path. CVS dates have a one-second resolution, so
+// the commits dated at a cursor are read again and those already synced
+// must be told apart from the ones committed later in the same second.
+func (r *Reader) SetSyncedRevisions(synced map[string][]string) {
+	r.synced = synced
+}
+
+// GetCommitsSince returns the commits dated at or after revision, a cursor
+// returned by GetHeadRevision, without the file revisions set through
+// SetSyncedRevisions. An empty revision returns all commits.
+func (r *Reader) GetCommitsSince(revision string) (vcs.CommitIterator, error) {
+	var since time.Time
+	if revision != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, revision); err != nil {
+			return nil, fmt.Errorf("invalid CVS revision cursor %q: %w", revision, err)
+		}
+	}
+
+	iter, err := r.GetCommits()
+	if err != nil {
+		return nil, err
+	}
+	var commits []*vcs.Commit
+	for iter.Next() {
+		c := iter.Commit()
+		if c.Date.Before(since) {
+			continue
+		}
+		if c = r.unsynced(c); c != nil {
+			commits = append(commits, c)
+		}
+	}
+	return &cvsCommitIterator{commits: commits}, iter.Err()
+}
+
+// unsynced returns c without the file revisions already synced, or nil when
+// all of them were
+func (r *Reader) unsynced(c *vcs.Commit) *vcs.Commit {
+	files := make([]vcs.FileChange, 0, len(c.Files))
+	for _, f := range c.Files {
+		if !slices.Contains(r.synced[f.Path], f.Revision) {
+			files = append(files, f)
+		}
+	}
+	switch len(files) {
+	case len(c.Files):
+		return c
+	case 0:
+		return nil
+	}
+	rest := *c
+	rest.Files = files
+	return &rest
+}
+
+// GetHeadRevision returns a cursor for GetCommitsSince identifying the
+// newest commit: its date in RFC 3339 format. It is empty when the
+// repository, or every file changed since SetKnownFiles, has no commits.
+func (r *Reader) GetHeadRevision() (string, error) {
+	iter, err := r.GetCommits()
+	if err != nil {
+		return "", err
+	}
+	var head time.Time
+	for iter.Next() {
+		if c := iter.Commit(); c.Date.After(head) {
+			head = c.Date
+		}
+	}
+	if err := iter.Err(); err != nil || head.IsZero() {
+		return "", err
+	}
+	return RevisionCursor(head), nil
+}
+
+// RevisionCursor returns the GetCommitsSince cursor of the commits dated at
+// or after date
+func RevisionCursor(date time.Time) string {
+	return date.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,73 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// commitRevisions returns the first file and revision of the commits
+// GetCommitsSince returns for since
+func commitRevisions(t *testing.T, r *Reader, since string) []string {
+	t.Helper()
+	iter, err := r.GetCommitsSince(since)
+	require.NoError(t, err)
+	var revs []string
+	for iter.Next() {
+		revs = append(revs, iter.Commit().Files[0].Path+"@"+iter.Commit().Revision)
+	}
+	require.NoError(t, iter.Err())
+	return revs
+}
+
+func TestReader_GetCommitsSince(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	a := filepath.Join(dir, "a.txt,v")
+	require.NoError(t, os.WriteFile(a, []byte(rcsWithStates("Exp", "Exp")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt,v"), []byte(rcsWithStates("Exp")), 0644))
+
+	r := NewReader(dir)
+	head, err := r.GetHeadRevision()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02T00:00:00Z", head)
+	require.Equal(t, []string{"a.txt@1.1", "a.txt@1.2"}, commitRevisions(t, r, "2024-01-01T00:00:00Z"))
+	require.Len(t, commitRevisions(t, r, ""), 2)
+
+	// The synced revisions of the cursor's second are left out, and a commit
+	// keeps the files not synced yet
+	r.SetSyncedRevisions(map[string][]string{"a.txt": {"1.1"}})
+	require.Equal(t, []string{"b.txt@1.1", "a.txt@1.2"}, commitRevisions(t, r, "2024-01-01T00:00:00Z"))
+	r.SetSyncedRevisions(map[string][]string{"a.txt": {"1.1"}, "b.txt": {"1.1"}})
+	require.Equal(t, []string{"a.txt@1.2"}, commitRevisions(t, r, "2024-01-01T00:00:00Z"))
+	_, err = r.GetCommitsSince("1.2")
+	require.ErrorContains(t, err, "invalid CVS revision cursor")
+
+	stamps := r.FileStamps()
+	require.Len(t, stamps, 2)
+	require.Contains(t, stamps, "b.txt,v")
+
+	// Only the rewritten file is read by a reader that knows the others
+	require.NoError(t, os.WriteFile(a, []byte(rcsWithStates("Exp", "Exp", "Exp")), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(a, later, later))
+	incremental := NewReader(dir)
+	incremental.SetKnownFiles(stamps)
+	require.Len(t, commitRevisions(t, incremental, ""), 3)
+	incremental.SetSyncedRevisions(map[string][]string{"a.txt": {"1.2"}})
+	require.Equal(t, []string{"a.txt@1.3"}, commitRevisions(t, incremental, head))
+	require.Len(t, incremental.FileStamps(), 2)
+	require.NotEqual(t, stamps["a.txt,v"], incremental.FileStamps()["a.txt,v"])
+	head, err = incremental.GetHeadRevision()
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-03T00:00:00Z", head)
+
+	unchanged := NewReader(dir)
+	unchanged.SetKnownFiles(incremental.FileStamps())
+	head, err = unchanged.GetHeadRevision()
+	require.NoError(t, err)
+	require.Empty(t, head)
+}
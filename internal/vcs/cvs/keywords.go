@@ -0,0 +1,85 @@
+package cvs
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// KeywordInfo holds the values RCS keywords expand to for a file revision
+type KeywordInfo struct {
+	RCSFile  string // Name of the RCS file, e.g. "main.c,v"
+	Source   string // Path of the RCS file in the repository
+	Revision string
+	Date     time.Time
+	Author   string
+	State    string
+	Locker   string
+	Name     string // Tag the revision was checked out with
+}
+
+// keywordPattern matches "$Keyword$" and "$Keyword: value $". $Log$ is left
+// alone: expanding it inserts the log message rather than replacing a value.
+var keywordPattern = regexp.MustCompile(`\$(Author|CVSHeader|Date|Header|Id|Locker|Name|RCSfile|Revision|Source|State)(?::[^$\n]*)?\$`)
+
+// ExpandKeywords substitutes the RCS keywords of content like "cvs checkout"
+// with keyword substitution mode mode: "kv" (the default for ""), "kvl",
+// "k"/"kk" (names only) or "v" (values only). Other modes, such as "o" and
+// "b", return the content unchanged.
+func ExpandKeywords(content []byte, mode string, info KeywordInfo) []byte {
+	switch mode {
+	case "", "kv", "kvl", "k", "kk", "v":
+	default:
+		return content
+	}
+	if !bytes.Contains(content, []byte("$")) {
+		return content
+	}
+	return keywordPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		keyword := string(keywordPattern.FindSubmatch(match)[1])
+		switch mode {
+		case "k", "kk":
+			return []byte("$" + keyword + "$")
+		case "v":
+			return []byte(info.value(keyword, false))
+		default:
+			return []byte("$" + keyword + ": " + info.value(keyword, mode == "kvl") + " $")
+		}
+	})
+}
+
+// value returns the expansion of keyword
+func (info KeywordInfo) value(keyword string, withLocker bool) string {
+	date := info.Date.UTC().Format("2006/01/02 15:04:05")
+	header := func(file string) string {
+		fields := []string{file, info.Revision, date, info.Author, info.State}
+		if withLocker && info.Locker != "" {
+			fields = append(fields, info.Locker)
+		}
+		return strings.Join(fields, " ")
+	}
+	switch keyword {
+	case "Author":
+		return info.Author
+	case "Date":
+		return date
+	case "Header", "CVSHeader":
+		return header(info.Source)
+	case "Id":
+		return header(info.RCSFile)
+	case "Locker":
+		return info.Locker
+	case "Name":
+		return info.Name
+	case "RCSfile":
+		return info.RCSFile
+	case "Revision":
+		return info.Revision
+	case "Source":
+		return info.Source
+	case "State":
+		return info.State
+	}
+	return ""
+}
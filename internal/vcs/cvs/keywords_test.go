@@ -0,0 +1,37 @@
+package cvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandKeywords(t *testing.T) {
+	info := KeywordInfo{
+		RCSFile:  "main.c,v",
+		Source:   "/cvsroot/mod/main.c,v",
+		Revision: "1.4",
+		Date:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Author:   "alice",
+		State:    "Exp",
+		Locker:   "bob",
+	}
+	content := []byte("/* $Id: main.c,v 1.3 2023/12/01 00:00:00 bob Exp $ */\n" +
+		"$Revision$ $Author$ $Log$ $Unknown$ $Date: old\n$\n")
+
+	require.Equal(t, "/* $Id: main.c,v 1.4 2024/01/02 03:04:05 alice Exp $ */\n"+
+		"$Revision: 1.4 $ $Author: alice $ $Log$ $Unknown$ $Date: old\n$\n",
+		string(ExpandKeywords(content, "kv", info)))
+	require.Equal(t, "/* $Id$ */\n$Revision$ $Author$ $Log$ $Unknown$ $Date: old\n$\n",
+		string(ExpandKeywords(content, "kk", info)))
+	require.Equal(t, "/* main.c,v 1.4 2024/01/02 03:04:05 alice Exp */\n",
+		string(ExpandKeywords([]byte("/* $Id$ */\n"), "v", info)))
+	require.Equal(t, "$Header: /cvsroot/mod/main.c,v 1.4 2024/01/02 03:04:05 alice Exp bob $",
+		string(ExpandKeywords([]byte("$Header$"), "kvl", info)))
+	require.Equal(t, "$Locker:  $", string(ExpandKeywords([]byte("$Locker$"), "", KeywordInfo{})))
+
+	for _, mode := range []string{"o", "b", "unknown"} {
+		require.Equal(t, content, ExpandKeywords(content, mode, info))
+	}
+}
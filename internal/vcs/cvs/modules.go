@@ -0,0 +1,267 @@
+package cvs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ModuleDefinition is an entry of CVSROOT/modules
+type ModuleDefinition struct {
+	Name     string
+	Alias    bool     // -a: Paths lists directories and modules to check out as they are
+	Paths    []string // Alias targets, with "!" marking excluded directories
+	Dir      string   // Repository directory of a regular module
+	Files    []string // Files of Dir making up the module (empty = the whole directory)
+	Members  []string // Modules included with "&module"
+	CheckOut string   // -d: directory the module is checked out into (default: Name)
+}
+
+// Modules holds the module definitions of CVSROOT/modules by name
+type Modules map[string]*ModuleDefinition
+
+// ModuleDir is a repository directory read as part of a module
+type ModuleDir struct {
+	Dir      string   // Repository directory, relative to the repository root
+	Prefix   string   // Path of the directory's files within the module
+	Files    []string // Files of Dir to read, non-recursively (empty = all, recursively)
+	Excludes []string // Repository directories left out
+}
+
+// maxModuleDepth bounds the nesting of modules referring to modules
+const maxModuleDepth = 16
+
+// ParseModules reads a CVSROOT/modules file. Lines ending in a backslash
+// continue on the next line; lines starting with "#" are comments.
+func ParseModules(r io.Reader) (Modules, error) {
+	modules := make(Modules)
+	scanner := bufio.NewScanner(r)
+	var pending string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if cont, ok := strings.CutSuffix(line, "\\"); ok {
+			pending += cont + " "
+			continue
+		}
+		line, pending = pending+line, ""
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		def, err := parseModuleLine(fields)
+		if err != nil {
+			return nil, err
+		}
+		modules[def.Name] = def
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if fields := strings.Fields(pending); len(fields) > 0 && !strings.HasPrefix(fields[0], "#") {
+		def, err := parseModuleLine(fields)
+		if err != nil {
+			return nil, err
+		}
+		modules[def.Name] = def
+	}
+	return modules, nil
+}
+
+// parseModuleLine parses "name [options] [dir [files...]] [&module...]"
+func parseModuleLine(fields []string) (*ModuleDefinition, error) {
+	def := &ModuleDefinition{Name: fields[0]}
+	rest := fields[1:]
+	for len(rest) > 0 && strings.HasPrefix(rest[0], "-") {
+		opt := rest[0]
+		rest = rest[1:]
+		switch opt {
+		case "-a":
+			def.Alias = true
+			def.Paths = rest
+			return def, nil
+		case "-l":
+		case "-d", "-e", "-i", "-o", "-s", "-t", "-u":
+			if len(rest) == 0 {
+				return nil, fmt.Errorf("module %s: option %s needs an argument", def.Name, opt)
+			}
+			if opt == "-d" {
+				def.CheckOut = rest[0]
+			}
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("module %s: unknown option %s", def.Name, opt)
+		}
+	}
+	for _, f := range rest {
+		switch {
+		case strings.HasPrefix(f, "&"):
+			def.Members = append(def.Members, strings.TrimPrefix(f, "&"))
+		case def.Dir == "":
+			def.Dir = strings.Trim(f, "/")
+		default:
+			def.Files = append(def.Files, f)
+		}
+	}
+	if def.Dir == "" && len(def.Members) == 0 {
+		return nil, fmt.Errorf("module %s has no directory", def.Name)
+	}
+	return def, nil
+}
+
+// LoadModules reads CVSROOT/modules of the repository at root. It returns
+// no modules if the file does not exist.
+func LoadModules(root string) (Modules, error) {
+	file, err := os.Open(filepath.Join(root, "CVSROOT", "modules"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Modules{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CVSROOT/modules: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	modules, err := ParseModules(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CVSROOT/modules: %w", err)
+	}
+	return modules, nil
+}
+
+// Resolve returns the repository directories making up the module name,
+// with the paths its files have in a checkout of the module. A name that is
+// not defined refers to the repository directory of that name.
+func (ms Modules) Resolve(name string) ([]ModuleDir, error) {
+	name = strings.Trim(name, "/")
+	def, ok := ms[name]
+	if !ok {
+		return []ModuleDir{{Dir: name}}, nil
+	}
+	return ms.resolve(def, 0)
+}
+
+func (ms Modules) resolve(def *ModuleDefinition, depth int) ([]ModuleDir, error) {
+	if depth > maxModuleDepth {
+		return nil, fmt.Errorf("module %s: modules nested too deeply, is there a cycle?", def.Name)
+	}
+	var dirs []ModuleDir
+	if def.Alias {
+		var excludes []string
+		for _, p := range def.Paths {
+			if ex, ok := strings.CutPrefix(p, "!"); ok {
+				excludes = append(excludes, strings.Trim(ex, "/"))
+			}
+		}
+		for _, p := range def.Paths {
+			if strings.HasPrefix(p, "!") {
+				continue
+			}
+			p = strings.Trim(p, "/")
+			members, err := ms.member(p, depth)
+			if err != nil {
+				return nil, err
+			}
+			if members == nil {
+				// A directory is checked out at its repository path
+				members = []ModuleDir{{Dir: p, Prefix: p}}
+			}
+			for _, md := range members {
+				md.Excludes = append(slices.Clone(md.Excludes), excludes...)
+				dirs = append(dirs, md)
+			}
+		}
+		return dirs, nil
+	}
+
+	if def.Dir != "" {
+		dirs = append(dirs, ModuleDir{Dir: def.Dir, Files: def.Files})
+	}
+	for _, m := range def.Members {
+		members, err := ms.member(m, depth)
+		if err != nil {
+			return nil, err
+		}
+		if members == nil {
+			members = []ModuleDir{{Dir: m, Prefix: path.Base(m)}}
+		}
+		dirs = append(dirs, members...)
+	}
+	return dirs, nil
+}
+
+// member resolves a module included by another one, placing its files in
+// the directory it is checked out into. It returns nil if name is not a
+// module.
+func (ms Modules) member(name string, depth int) ([]ModuleDir, error) {
+	def, ok := ms[name]
+	if !ok {
+		return nil, nil
+	}
+	dirs, err := ms.resolve(def, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if def.Alias {
+		return dirs, nil
+	}
+	checkout := def.CheckOut
+	if checkout == "" {
+		checkout = def.Name
+	}
+	for i := range dirs {
+		dirs[i].Prefix = path.Join(checkout, dirs[i].Prefix)
+	}
+	return dirs, nil
+}
+
+// ResolveModule returns the repository directories of module in the local
+// repository at root, following CVSROOT/modules
+func ResolveModule(root, module string) ([]ModuleDir, error) {
+	modules, err := LoadModules(root)
+	if err != nil {
+		return nil, err
+	}
+	return modules.Resolve(module)
+}
+
+// ModuleDirectory returns the single repository directory holding module,
+// for writers that need one. Modules spanning several directories or
+// limited to some files are rejected.
+func ModuleDirectory(root, module string) (string, error) {
+	dirs, err := ResolveModule(root, module)
+	if err != nil {
+		return "", err
+	}
+	if len(dirs) != 1 || dirs[0].Prefix != "" || len(dirs[0].Files) > 0 || len(dirs[0].Excludes) > 0 {
+		return "", fmt.Errorf("module %s does not map to a single repository directory", module)
+	}
+	return dirs[0].Dir, nil
+}
+
+// contains reports whether the repository path rel, a file or directory,
+// belongs to the module directory, and returns its path within the module
+func (md ModuleDir) contains(rel string, file bool) (string, bool) {
+	for _, ex := range md.Excludes {
+		if rel == ex || strings.HasPrefix(rel, ex+"/") {
+			return "", false
+		}
+	}
+	sub := rel
+	if md.Dir != "" {
+		if rel == md.Dir {
+			sub = ""
+		} else if rest, ok := strings.CutPrefix(rel, md.Dir+"/"); ok {
+			sub = rest
+		} else {
+			return "", false
+		}
+	}
+	if len(md.Files) > 0 && file && !slices.Contains(md.Files, sub) {
+		return "", false
+	}
+	return path.Join(md.Prefix, sub), true
+}
@@ -0,0 +1,145 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testModules = `# CVSROOT/modules
+world   -a gui lib/core !lib/core/tests
+gui     -d frontend src/gui
+docs    doc README INSTALL
+all     &gui &docs \
+        &tools
+tools   utils
+loop1   &loop2
+loop2   &loop1
+`
+
+func TestParseModules(t *testing.T) {
+	modules, err := ParseModules(strings.NewReader(testModules))
+	require.NoError(t, err)
+	require.Len(t, modules, 7)
+
+	require.True(t, modules["world"].Alias)
+	require.Equal(t, []string{"gui", "lib/core", "!lib/core/tests"}, modules["world"].Paths)
+	require.Equal(t, "src/gui", modules["gui"].Dir)
+	require.Equal(t, "frontend", modules["gui"].CheckOut)
+	require.Equal(t, []string{"README", "INSTALL"}, modules["docs"].Files)
+	require.Equal(t, []string{"gui", "docs", "tools"}, modules["all"].Members)
+
+	_, err = ParseModules(strings.NewReader("bad -x dir\n"))
+	require.Error(t, err)
+	_, err = ParseModules(strings.NewReader("empty -d\n"))
+	require.Error(t, err)
+}
+
+func TestModulesResolve(t *testing.T) {
+	modules, err := ParseModules(strings.NewReader(testModules))
+	require.NoError(t, err)
+
+	dirs, err := modules.Resolve("gui")
+	require.NoError(t, err)
+	require.Equal(t, []ModuleDir{{Dir: "src/gui"}}, dirs)
+
+	// Alias members keep their checkout layout; excludes apply to all
+	dirs, err = modules.Resolve("world")
+	require.NoError(t, err)
+	require.Len(t, dirs, 2)
+	require.Equal(t, ModuleDir{Dir: "src/gui", Prefix: "frontend", Excludes: []string{"lib/core/tests"}}, dirs[0])
+	require.Equal(t, ModuleDir{Dir: "lib/core", Prefix: "lib/core", Excludes: []string{"lib/core/tests"}}, dirs[1])
+
+	dirs, err = modules.Resolve("all")
+	require.NoError(t, err)
+	require.Equal(t, []ModuleDir{
+		{Dir: "src/gui", Prefix: "frontend"},
+		{Dir: "doc", Prefix: "docs", Files: []string{"README", "INSTALL"}},
+		{Dir: "utils", Prefix: "tools"},
+	}, dirs)
+
+	// Undefined names are directories
+	dirs, err = modules.Resolve("/plain/")
+	require.NoError(t, err)
+	require.Equal(t, []ModuleDir{{Dir: "plain"}}, dirs)
+
+	_, err = modules.Resolve("loop1")
+	require.Error(t, err)
+}
+
+func TestModuleDirContains(t *testing.T) {
+	md := ModuleDir{Dir: "lib", Prefix: "x", Excludes: []string{"lib/tests"}}
+	p, ok := md.contains("lib/a/b.c", true)
+	require.True(t, ok)
+	require.Equal(t, "x/a/b.c", p)
+	_, ok = md.contains("lib/tests/t.c", true)
+	require.False(t, ok)
+	_, ok = md.contains("library/a.c", true)
+	require.False(t, ok)
+
+	md = ModuleDir{Dir: "doc", Files: []string{"README"}}
+	p, ok = md.contains("doc/README", true)
+	require.True(t, ok)
+	require.Equal(t, "README", p)
+	_, ok = md.contains("doc/OTHER", true)
+	require.False(t, ok)
+}
+
+func TestModuleDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CVSROOT", "modules"), []byte(testModules), 0644))
+
+	got, err := ModuleDirectory(dir, "gui")
+	require.NoError(t, err)
+	require.Equal(t, "src/gui", got)
+	got, err = ModuleDirectory(dir, "plain")
+	require.NoError(t, err)
+	require.Equal(t, "plain", got)
+	_, err = ModuleDirectory(dir, "world")
+	require.Error(t, err)
+	_, err = ModuleDirectory(dir, "docs")
+	require.Error(t, err)
+}
+
+func TestModuleReader_Alias(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CVSROOT", "modules"), []byte(testModules), 0644))
+	for _, file := range []string{"src/gui/main.c,v", "lib/core/core.c,v", "lib/core/tests/t.c,v",
+		"doc/README,v", "doc/Attic/INSTALL,v", "doc/OTHER,v", "doc/sub/README,v", "utils/u.c,v"} {
+		p := filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(contentRCS), 0644))
+	}
+
+	paths := func(module string) []string {
+		r := NewModuleReader(dir, module)
+		require.NoError(t, r.Validate())
+		iter, err := r.GetCommits()
+		require.NoError(t, err)
+		seen := make(map[string]bool)
+		for iter.Next() {
+			for _, f := range iter.Commit().Files {
+				seen[f.Path] = true
+			}
+		}
+		var list []string
+		for p := range seen {
+			list = append(list, p)
+		}
+		sort.Strings(list)
+		return list
+	}
+
+	require.Equal(t, []string{"frontend/main.c", "lib/core/core.c"}, paths("world"))
+	require.Equal(t, []string{"docs/INSTALL", "docs/README", "frontend/main.c", "tools/u.c"}, paths("all"))
+	require.Equal(t, []string{"main.c"}, paths("gui"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CVSROOT", "modules"), []byte("gone missing\n"), 0644))
+	require.Error(t, NewModuleReader(dir, "gone").Validate())
+}
@@ -0,0 +1,498 @@
+package cvs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/throttle"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// lockTimeout is how long NativeWriter waits for a directory lock held by
+// another CVS process
+const lockTimeout = 30 * time.Second
+
+// NativeWriter implements VCSWriter by appending revisions directly to the
+// RCS files of a CVS repository, the way "cvs commit" does on the server,
+// so no cvs executable is needed. Commits are made on the trunk; files are
+// moved to and from the Attic when they are removed and added again.
+type NativeWriter struct {
+	root   string            // CVSROOT
+	module string            // Module directory below root
+	limit  *throttle.Limiter // Paces the reads, writes and file operations (nil = unlimited)
+
+	ctx context.Context // Stops commits and tags early, before any file is written (nil = never)
+}
+
+// NewNativeWriter creates a writer for module; Init or Open selects the
+// repository
+func NewNativeWriter(module string) *NativeWriter {
+	return &NativeWriter{module: module}
+}
+
+// Init creates a CVS repository at path, with an empty CVSROOT, and the
+// module directory. An existing repository is opened instead.
+func (w *NativeWriter) Init(path string) error {
+	if err := os.MkdirAll(filepath.Join(path, "CVSROOT"), 0755); err != nil {
+		return fmt.Errorf("failed to create CVSROOT: %w", err)
+	}
+	return w.Open(path)
+}
+
+// Open uses the CVS repository at path, creating the module directory if
+// it does not exist yet
+func (w *NativeWriter) Open(path string) error {
+	if _, err := os.Stat(filepath.Join(path, "CVSROOT")); err != nil {
+		return fmt.Errorf("%w: no CVS repository at %s", vcs.ErrNotARepo, path)
+	}
+	if w.module == "" || !fs.ValidPath(w.module) || w.module == "." {
+		return fmt.Errorf("invalid CVS module %q", w.module)
+	}
+	if err := os.MkdirAll(filepath.Join(path, filepath.FromSlash(w.module)), 0755); err != nil {
+		return fmt.Errorf("failed to create module directory: %w", err)
+	}
+	w.root = path
+	return nil
+}
+
+// SetIOLimit paces the reads and writes of RCS files, and the file
+// operations, by limiter, which may be shared with the readers of the same
+// storage
+func (w *NativeWriter) SetIOLimit(limiter *throttle.Limiter) {
+	w.limit = limiter
+}
+
+// SetContext stops the following commits, branches and tags early, before
+// any RCS file is written, once ctx is done
+func (w *NativeWriter) SetContext(ctx context.Context) {
+	w.ctx = ctx
+}
+
+// pendingFile is an RCS file updated by a commit but not written yet
+type pendingFile struct {
+	rcs      *RCSFile
+	from     string // Current ,v path ("" for a new file)
+	to       string // ,v path after the commit
+	workPath string
+}
+
+// ApplyCommit checks in every file change of commit as a new trunk
+// revision. Deleting a file that does not exist is a no-op. All revisions
+// share a commitid so readers group them into one commit again.
+func (w *NativeWriter) ApplyCommit(commit *vcs.Commit) error {
+	if w.root == "" {
+		return fmt.Errorf("CVS repository not opened – call Init or Open first")
+	}
+
+	meta := Delta{
+		Date:     commit.Date,
+		Author:   rcsAuthor(commit.Author),
+		State:    "Exp",
+		Log:      commit.Message,
+		CommitID: commitID(commit),
+	}
+	if meta.Date.IsZero() {
+		meta.Date = time.Now()
+	}
+	if !strings.HasSuffix(meta.Log, "\n") {
+		meta.Log += "\n"
+	}
+
+	// RCS files have no renames or copies
+	var pending []pendingFile
+	for _, fc := range vcs.BasicChanges(commit.Files) {
+		if err := vcs.ContextErr(w.ctx); err != nil {
+			return err
+		}
+		p, ok, err := w.prepare(fc, meta)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fc.Path, err)
+		}
+		if ok {
+			pending = append(pending, p)
+		}
+	}
+	if err := vcs.ContextErr(w.ctx); err != nil {
+		return err
+	}
+	return w.writeFiles(pending)
+}
+
+// prepare loads the RCS file of a change and checks in the new revision in
+// memory. The boolean result is false if the change leaves the file as is.
+func (w *NativeWriter) prepare(fc vcs.FileChange, meta Delta) (pendingFile, bool, error) {
+	if !fs.ValidPath(fc.Path) || fc.Path == "." || strings.Contains(fc.Path, "Attic/") {
+		return pendingFile{}, false, fmt.Errorf("invalid path")
+	}
+	live, attic := w.rcsPaths(fc.Path)
+	p := pendingFile{workPath: fc.Path, to: live}
+
+	rcs, from, err := loadRCSFile(w.limit, live, attic)
+	if err != nil {
+		return p, false, err
+	}
+	p.from = from
+	alive := rcs != nil && rcs.Deltas[rcs.Head] != nil && !rcs.Deltas[rcs.Head].IsDead()
+
+	var content []byte
+	if fc.Action == vcs.ActionDelete {
+		if !alive {
+			return p, false, nil
+		}
+		meta.State = "dead"
+		p.to = attic
+	} else {
+		if content, err = fc.ReadContent(); err != nil {
+			return p, false, fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+
+	if rcs == nil {
+		rcs = &RCSFile{
+			Deltas:      make(map[string]*Delta),
+			Symbols:     make(map[string]string),
+			Locks:       make(map[string]string),
+			StrictLocks: true,
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			rcs.Expand = "b"
+		}
+	}
+	if err := rcs.checkinTrunk(content, meta); err != nil {
+		return p, false, err
+	}
+	p.rcs = rcs
+	return p, true, nil
+}
+
+// rcsPaths returns the ,v path of a working file and its path in the Attic
+func (w *NativeWriter) rcsPaths(workPath string) (live, attic string) {
+	dir, name := path.Split(workPath)
+	base := filepath.Join(w.root, filepath.FromSlash(w.module), filepath.FromSlash(dir))
+	return filepath.Join(base, name+",v"), filepath.Join(base, "Attic", name+",v")
+}
+
+// loadRCSFile parses the live or, failing that, the Attic copy of an RCS
+// file. It returns nil if neither exists.
+func loadRCSFile(limit *throttle.Limiter, paths ...string) (*RCSFile, string, error) {
+	for _, p := range paths {
+		limit.Op()
+		f, err := os.Open(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		rcs, err := NewRCSParser(limit.Reader(f)).Parse()
+		closeErr := f.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		if closeErr != nil {
+			return nil, "", closeErr
+		}
+		return rcs, p, nil
+	}
+	return nil, "", nil
+}
+
+// writeFiles writes the updated RCS files while holding the CVS write
+// locks of their directories
+func (w *NativeWriter) writeFiles(files []pendingFile) error {
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		dirs[lockDirFor(f.to)] = true
+		if f.from != "" {
+			dirs[lockDirFor(f.from)] = true
+		}
+	}
+	unlock, err := lockDirs(dirs)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, f := range files {
+		if err := writeRCSFile(w.limit, f.rcs, f.to); err != nil {
+			return fmt.Errorf("%s: %w", f.workPath, err)
+		}
+		if f.from != "" && f.from != f.to {
+			w.limit.Op()
+			if err := os.Remove(f.from); err != nil {
+				return fmt.Errorf("%s: %w", f.workPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// lockDirFor returns the module directory whose lock protects an RCS file;
+// Attic files are protected by the lock of their parent directory
+func lockDirFor(rcsPath string) string {
+	dir := filepath.Dir(rcsPath)
+	if filepath.Base(dir) == "Attic" {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// lockDirs takes the CVS write lock of each directory the way cvs does: it
+// creates the #cvs.lock master lock, backs off while other processes hold
+// read, write or promotable locks there, and then adds its own #cvs.wfl
+// write lock. Directories are locked in sorted order so concurrent writers
+// cannot deadlock.
+func lockDirs(dirs map[string]bool) (func(), error) {
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+
+	host, _ := os.Hostname()
+	writeLock := fmt.Sprintf("#cvs.wfl.%s.%d", host, os.Getpid())
+	var locked []string // Removed in order: each write lock before its master lock
+	unlock := func() {
+		for _, lock := range locked {
+			_ = os.Remove(lock)
+		}
+	}
+	for _, dir := range sorted {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			unlock()
+			return nil, err
+		}
+		lock := filepath.Join(dir, "#cvs.lock")
+		deadline := time.Now().Add(lockTimeout)
+		for {
+			err := os.Mkdir(lock, 0755)
+			if err == nil {
+				if err = checkDirLocks(dir); err == nil {
+					break
+				}
+				_ = os.Remove(lock) // Let the other process finish
+			}
+			if (!errors.Is(err, fs.ErrExist) && !errors.Is(err, errDirLocked)) || time.Now().After(deadline) {
+				unlock()
+				return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		write := filepath.Join(dir, writeLock)
+		if err := os.WriteFile(write, nil, 0644); err != nil {
+			_ = os.Remove(lock)
+			unlock()
+			return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+		}
+		locked = append(locked, write, lock)
+	}
+	return unlock, nil
+}
+
+// errDirLocked reports a directory in which another CVS process holds a
+// read, write or promotable lock
+var errDirLocked = errors.New("directory is locked by another CVS process")
+
+// checkDirLocks returns errDirLocked if dir holds a #cvs.rfl, #cvs.wfl or
+// #cvs.pfl lock. The caller holds the master lock, so no new one appears.
+func checkDirLocks(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		for _, prefix := range lockPrefixes[1:] {
+			if strings.HasPrefix(entry.Name(), prefix) {
+				return fmt.Errorf("%w: %s", errDirLocked, entry.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// writeRCSFile replaces the RCS file at path. Like RCS, it writes the new
+// version to the ",file," lock file and renames it into place, so readers
+// always see a complete file.
+func writeRCSFile(limit *throttle.Limiter, rcs *RCSFile, rcsPath string) error {
+	if err := os.MkdirAll(filepath.Dir(rcsPath), 0755); err != nil {
+		return err
+	}
+	dir, name := filepath.Split(rcsPath)
+	tmp := filepath.Join(dir, ","+strings.TrimSuffix(name, ",v")+",")
+
+	limit.Op()
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return fmt.Errorf("RCS file is locked: %w", err)
+	}
+	if _, err := rcs.WriteTo(limit.Writer(f)); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	limit.Op()
+	if err := os.Rename(tmp, rcsPath); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// rcsAuthor turns an author name into a valid RCS login name
+func rcsAuthor(author string) string {
+	author = strings.TrimSpace(author)
+	if author == "" {
+		return "unknown"
+	}
+	var b strings.Builder
+	for _, c := range author {
+		if isAlpha(c) || isDigit(c) || c == '_' || c == '-' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if !isAlpha(rune(name[0])) {
+		name = "u" + name
+	}
+	return name
+}
+
+// commitID derives the CVS commitid of a commit, which identifies all file
+// revisions it creates
+func commitID(commit *vcs.Commit) string {
+	sum := sha256.Sum256([]byte(commit.Revision + "\x00" + commit.Date.String() + "\x00" + commit.Message))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CreateBranch creates a branch rooted at the head revision of every live
+// file in the module. Only revision "HEAD" (or "") is supported.
+func (w *NativeWriter) CreateBranch(name, revision string) error {
+	return w.tagFiles(name, revision, true)
+}
+
+// CreateTag tags the head revision of every live file in the module. Only
+// revision "HEAD" (or "") is supported; CVS tags carry no message.
+func (w *NativeWriter) CreateTag(name, revision, _ string) error {
+	return w.tagFiles(name, revision, false)
+}
+
+func (w *NativeWriter) tagFiles(name, revision string, branch bool) error {
+	if w.root == "" {
+		return fmt.Errorf("CVS repository not opened – call Init or Open first")
+	}
+	if revision != "" && revision != "HEAD" {
+		return fmt.Errorf("tagging revision %q is not supported, only HEAD", revision)
+	}
+	if !validSymbol(name) {
+		return fmt.Errorf("invalid CVS tag name %q", name)
+	}
+
+	moduleDir := filepath.Join(w.root, filepath.FromSlash(w.module))
+	var files []pendingFile
+	err := filepath.WalkDir(moduleDir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := vcs.ContextErr(w.ctx); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == "Attic" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ",v") {
+			return nil
+		}
+		rcs, _, err := loadRCSFile(w.limit, p)
+		if err != nil {
+			return err
+		}
+		head := rcs.Deltas[rcs.Head]
+		if head == nil || head.IsDead() {
+			return nil
+		}
+		if existing, ok := rcs.Symbols[name]; ok {
+			return fmt.Errorf("tag %s already exists on %s:%s", name, p, existing)
+		}
+		rcs.Symbols[name] = rcs.Head
+		if branch {
+			rcs.Symbols[name] = magicBranchNumber(rcs, rcs.Head)
+		}
+		files = append(files, pendingFile{rcs: rcs, from: p, to: p, workPath: p})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := vcs.ContextErr(w.ctx); err != nil {
+		return err
+	}
+	return w.writeFiles(files)
+}
+
+// magicBranchNumber returns the next unused CVS branch number at rev in its
+// magic form, e.g. 1.3.0.2
+func magicBranchNumber(rcs *RCSFile, rev string) string {
+	used := make(map[string]bool)
+	for _, sym := range rcs.Symbols {
+		used[sym] = true
+	}
+	if d := rcs.Deltas[rev]; d != nil {
+		for _, b := range d.Branches {
+			parts := strings.Split(b, ".")
+			used[strings.Join(parts[:len(parts)-1], ".")] = true
+		}
+	}
+	for n := 2; ; n += 2 {
+		if !used[fmt.Sprintf("%s.%d", rev, n)] && !used[fmt.Sprintf("%s.0.%d", rev, n)] {
+			return fmt.Sprintf("%s.0.%d", rev, n)
+		}
+	}
+}
+
+// validSymbol reports whether name can be used as a CVS tag: a letter
+// followed by letters, digits, "-" or "_"
+func validSymbol(name string) bool {
+	if name == "" || !isAlpha(rune(name[0])) {
+		return false
+	}
+	for _, c := range name {
+		if !isAlpha(c) && !isDigit(c) && c != '_' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Close releases any resources held by the writer
+func (w *NativeWriter) Close() error {
+	return nil
+}
+
+func init() {
+	vcs.RegisterWriter("cvs-native", func(options map[string]string) (vcs.VCSWriter, error) {
+		if options["module"] == "" {
+			return nil, fmt.Errorf("cvs-native target requires the module option")
+		}
+		return NewNativeWriter(options["module"]), nil
+	})
+}
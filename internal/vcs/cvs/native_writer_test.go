@@ -0,0 +1,227 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamf123git/git-migrator/internal/throttle"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// readBack returns the commits of module as seen by the CVS reader
+func readBack(t *testing.T, root, module string) []*vcs.Commit {
+	t.Helper()
+	r := NewModuleReader(root, module)
+	defer func() { _ = r.Close() }()
+	iter, err := r.GetCommits()
+	require.NoError(t, err)
+	var commits []*vcs.Commit
+	for iter.Next() {
+		commits = append(commits, iter.Commit())
+	}
+	require.NoError(t, iter.Err())
+	return commits
+}
+
+func TestNativeWriter_ApplyCommit(t *testing.T) {
+	root := t.TempDir()
+	w := NewNativeWriter("proj")
+	require.NoError(t, w.Init(root))
+
+	date := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	steps := []*vcs.Commit{
+		{Revision: "a1", Author: "Alice Smith", Date: date, Message: "Add files", Files: []vcs.FileChange{
+			{Path: "README", Action: vcs.ActionAdd, Content: []byte("hello\n")},
+			{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("int main;\n")},
+			{Path: "logo.bin", Action: vcs.ActionAdd, Content: []byte{0, 1, 2}},
+		}},
+		{Revision: "b2", Author: "bob", Date: date.Add(time.Hour), Message: "Edit and remove", Files: []vcs.FileChange{
+			{Path: "README", Action: vcs.ActionModify, Content: []byte("hello\nworld\n")},
+			{Path: "src/main.c", Action: vcs.ActionDelete},
+			{Path: "missing.txt", Action: vcs.ActionDelete},
+		}},
+		{Revision: "c3", Author: "bob", Date: date.Add(2 * time.Hour), Message: "Restore", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("int main() {}\n")},
+		}},
+	}
+	for _, c := range steps {
+		require.NoError(t, w.ApplyCommit(c))
+	}
+	require.NoError(t, w.Close())
+
+	require.FileExists(t, filepath.Join(root, "proj", "src", "main.c,v"))
+	require.NoFileExists(t, filepath.Join(root, "proj", "src", "Attic", "main.c,v"))
+	require.NoDirExists(t, filepath.Join(root, "proj", "#cvs.lock"))
+
+	commits := readBack(t, root, "proj")
+	require.Len(t, commits, 3)
+	require.Equal(t, "Alice_Smith", commits[0].Author)
+	require.Equal(t, "Add files\n", commits[0].Message)
+	require.Len(t, commits[0].Files, 3)
+
+	contents := make(map[string]string)
+	actions := make(map[string]vcs.Action)
+	for _, c := range commits {
+		for _, fc := range c.Files {
+			data, err := fc.ReadContent()
+			require.NoError(t, err)
+			contents[fc.Path] = string(data)
+			actions[fc.Path] = fc.Action
+		}
+	}
+	require.Equal(t, "hello\nworld\n", contents["README"])
+	require.Equal(t, "int main() {}\n", contents["src/main.c"])
+	require.Equal(t, "\x00\x01\x02", contents["logo.bin"])
+	require.NotContains(t, actions, "missing.txt")
+
+	rcs, _, err := loadRCSFile(nil, filepath.Join(root, "proj", "logo.bin,v"))
+	require.NoError(t, err)
+	require.Equal(t, "b", rcs.Expand)
+}
+
+func TestNativeWriter_DeleteMovesToAttic(t *testing.T) {
+	root := t.TempDir()
+	w := NewNativeWriter("proj")
+	require.NoError(t, w.Init(root))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Revision: "1", Author: "a", Date: time.Now(), Message: "add",
+		Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x\n")}}}))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Revision: "2", Author: "a", Date: time.Now(), Message: "rm",
+		Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionDelete}}}))
+
+	require.NoFileExists(t, filepath.Join(root, "proj", "f.txt,v"))
+	rcs, _, err := loadRCSFile(nil, filepath.Join(root, "proj", "Attic", "f.txt,v"))
+	require.NoError(t, err)
+	require.Equal(t, "1.2", rcs.Head)
+	require.True(t, rcs.Deltas["1.2"].IsDead())
+}
+
+func TestNativeWriter_Rename(t *testing.T) {
+	root := t.TempDir()
+	w := NewNativeWriter("proj")
+	require.NoError(t, w.Init(root))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Revision: "1", Author: "a", Date: time.Now(), Message: "add",
+		Files: []vcs.FileChange{{Path: "old.txt", Action: vcs.ActionAdd, Content: []byte("x\n")}}}))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Revision: "2", Author: "a", Date: time.Now(), Message: "move",
+		Files: []vcs.FileChange{{Path: "new.txt", OldPath: "old.txt", Action: vcs.ActionRename, Content: []byte("x\n")}}}))
+
+	require.FileExists(t, filepath.Join(root, "proj", "Attic", "old.txt,v"))
+	rcs, _, err := loadRCSFile(nil, filepath.Join(root, "proj", "new.txt,v"))
+	require.NoError(t, err)
+	require.Equal(t, "1.1", rcs.Head)
+}
+
+func TestNativeWriter_TagsAndBranches(t *testing.T) {
+	root := t.TempDir()
+	w := NewNativeWriter("proj")
+	require.NoError(t, w.Init(root))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Revision: "1", Author: "a", Date: time.Now(), Message: "add",
+		Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x\n")}}}))
+
+	require.NoError(t, w.CreateTag("REL_1", "HEAD", "ignored"))
+	require.NoError(t, w.CreateBranch("feature", ""))
+	require.NoError(t, w.CreateBranch("bugfix", ""))
+	require.Error(t, w.CreateTag("REL_1", "", ""))
+	require.Error(t, w.CreateTag("1bad", "", ""))
+	require.Error(t, w.CreateTag("OLD", "abc123", ""))
+
+	rcs, _, err := loadRCSFile(nil, filepath.Join(root, "proj", "f.txt,v"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"REL_1":   "1.1",
+		"feature": "1.1.0.2",
+		"bugfix":  "1.1.0.4",
+	}, rcs.Symbols)
+
+	r := NewModuleReader(root, "proj")
+	branches, err := r.GetBranches()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"feature", "bugfix"}, branches)
+}
+
+func TestNativeWriter_Errors(t *testing.T) {
+	w := NewNativeWriter("proj")
+	require.Error(t, w.ApplyCommit(&vcs.Commit{}))
+	require.Error(t, w.Open(t.TempDir()))
+	require.Error(t, NewNativeWriter("../up").Init(t.TempDir()))
+
+	root := t.TempDir()
+	require.NoError(t, w.Init(root))
+	require.Error(t, w.ApplyCommit(&vcs.Commit{Files: []vcs.FileChange{{Path: "../x", Action: vcs.ActionAdd}}}))
+
+	// A lock held by another CVS process is honoured
+	require.NoError(t, os.WriteFile(filepath.Join(root, "proj", ",f.txt,"), nil, 0444))
+	require.Error(t, w.ApplyCommit(&vcs.Commit{Files: []vcs.FileChange{{Path: "f.txt", Action: vcs.ActionAdd, Content: []byte("x\n")}}}))
+}
+
+func TestNativeWriter_WaitsForReadLock(t *testing.T) {
+	root := t.TempDir()
+	w := NewNativeWriter("proj")
+	require.NoError(t, w.Init(root))
+
+	// A reader's lock makes the writer back off until it is released
+	dir := filepath.Join(root, "proj")
+	readLock := filepath.Join(dir, "#cvs.rfl.otherhost.42")
+	require.NoError(t, os.WriteFile(readLock, nil, 0644))
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = os.Remove(readLock)
+	}()
+
+	start := time.Now()
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "alice", Message: "Add", Files: []vcs.FileChange{
+		{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a\n")},
+	}}))
+	require.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	// The master and write locks are gone afterwards
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.False(t, strings.HasPrefix(entry.Name(), "#cvs."), entry.Name())
+	}
+}
+
+func TestLockDirs_WriteLock(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := lockDirs(map[string]bool{dir: true})
+	require.NoError(t, err)
+
+	require.DirExists(t, filepath.Join(dir, "#cvs.lock"))
+	locks, err := filepath.Glob(filepath.Join(dir, "#cvs.wfl.*"))
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+
+	unlock()
+	require.NoDirExists(t, filepath.Join(dir, "#cvs.lock"))
+	require.NoFileExists(t, locks[0])
+}
+
+func TestNativeWriterRegistered(t *testing.T) {
+	w, err := vcs.NewWriter("cvs-native", map[string]string{"module": "proj"})
+	require.NoError(t, err)
+	require.IsType(t, &NativeWriter{}, w)
+}
+
+func TestNativeWriter_IOLimit(t *testing.T) {
+	root := t.TempDir()
+	w := NewNativeWriter("proj")
+	require.NoError(t, w.Init(root))
+	w.SetIOLimit(throttle.NewWithOps(0, 40))
+
+	// Every new file takes four operations: two opens looking for it, the
+	// lock file and the rename. 12 operations at 40 per second take about
+	// 300ms, less the burst.
+	start := time.Now()
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "alice", Message: "Add", Files: []vcs.FileChange{
+		{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a\n")},
+		{Path: "b.txt", Action: vcs.ActionAdd, Content: []byte("b\n")},
+		{Path: "c.txt", Action: vcs.ActionAdd, Content: []byte("c\n")},
+	}}))
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	require.Len(t, readBack(t, root, "proj"), 1)
+}
@@ -0,0 +1,66 @@
+package cvs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Diagnostic severities
+const (
+	// SeverityWarning marks input the RCS grammar allows but the parser does
+	// not understand, such as an unknown field
+	SeverityWarning = "warning"
+	// SeverityError marks malformed input the parser worked around; strict
+	// parsing fails on it
+	SeverityError = "error"
+)
+
+// Diagnostic is an anomaly found while parsing an RCS file
+type Diagnostic struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// String formats the diagnostic as "file:line:column: severity: message"
+func (d Diagnostic) String() string {
+	pos := d.File
+	if pos == "" {
+		pos = "<input>"
+	}
+	if d.Line > 0 {
+		pos += fmt.Sprintf(":%d:%d", d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+}
+
+// ParseError is returned by strict parsing for the first malformed
+// construct of an RCS file
+type ParseError struct {
+	Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	return e.Diagnostic.String()
+}
+
+// Unwrap returns the error as a vcs.ParseError, so that it matches
+// vcs.ErrParse
+func (e *ParseError) Unwrap() error {
+	return &vcs.ParseError{File: e.File, Line: e.Line, Err: errors.New(e.Message)}
+}
+
+// CountErrors returns the number of diagnostics with SeverityError
+func CountErrors(diags []Diagnostic) int {
+	n := 0
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
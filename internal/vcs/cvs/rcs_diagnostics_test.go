@@ -0,0 +1,136 @@
+package cvs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+const diagnosticsValidRCS = "head\t1.2;\naccess;\nsymbols\n\tREL_1:1.1;\nlocks; strict;\ncomment\t@# @;\n\n\n" +
+	"1.2\ndate\t2024.01.02.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t1.1;\n\n" +
+	"1.1\ndate\t2024.01.01.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\n\n\n" +
+	"desc\n@@\n\n\n1.2\nlog\n@second\n@\ntext\n@world\n@\n\n\n1.1\nlog\n@initial\n@\ntext\n@d1 1\na1 1\nhello\n@\n"
+
+func parseWithDiagnostics(t *testing.T, input string, strict bool) (*RCSFile, []Diagnostic, error) {
+	t.Helper()
+	p := NewRCSParser(strings.NewReader(input))
+	p.SetFile("a.txt,v")
+	p.SetStrict(strict)
+	rcs, err := p.Parse()
+	return rcs, p.Diagnostics(), err
+}
+
+func TestParseDiagnostics_Valid(t *testing.T) {
+	rcs, diags, err := parseWithDiagnostics(t, diagnosticsValidRCS, true)
+	require.NoError(t, err)
+	require.Empty(t, diags)
+	require.Equal(t, "1.2", rcs.Head)
+
+	for _, fixture := range []string{"branches/main.c,v", "simple/README.txt,v", "tags/config.yml,v"} {
+		data, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "fixtures", "cvs", fixture))
+		require.NoError(t, err)
+		_, diags, err := parseWithDiagnostics(t, string(data), true)
+		require.NoError(t, err, fixture)
+		require.Zero(t, CountErrors(diags), fixture)
+	}
+}
+
+func TestParseDiagnostics_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		line    int
+		column  int
+		message string
+	}{
+		{
+			name:    "missing semicolon",
+			input:   strings.Replace(diagnosticsValidRCS, "head\t1.2;", "head\t1.2", 1),
+			line:    2,
+			column:  1,
+			message: "expected ';' after head, found \"access\"",
+		},
+		{
+			name:    "invalid date",
+			input:   strings.Replace(diagnosticsValidRCS, "2024.01.02.00.00.00", "2024.01.02", 1),
+			line:    10,
+			column:  6,
+			message: "invalid date \"2024.01.02\" in revision 1.2",
+		},
+		{
+			name:    "missing next revision",
+			input:   strings.Replace(diagnosticsValidRCS, "next\t1.1;", "next\t1.0;", 1),
+			line:    9,
+			column:  1,
+			message: "revision 1.2: next revision 1.0 is not in the delta list",
+		},
+		{
+			name:    "unterminated string",
+			input:   strings.TrimSuffix(diagnosticsValidRCS, "@\n"),
+			line:    38,
+			column:  1,
+			message: "unterminated string",
+		},
+		{
+			name:    "unexpected character",
+			input:   strings.Replace(diagnosticsValidRCS, "access;", "access $;", 1),
+			line:    2,
+			column:  8,
+			message: "unexpected character '$'",
+		},
+		{
+			name:    "symbol without revision",
+			input:   strings.Replace(diagnosticsValidRCS, "REL_1:1.1", "REL_1:", 1),
+			line:    4,
+			column:  2,
+			message: "symbols entry REL_1 has no revision",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcs, diags, err := parseWithDiagnostics(t, tt.input, false)
+			require.NoError(t, err, "lenient parsing recovers")
+			require.NotNil(t, rcs)
+			require.NotEmpty(t, diags)
+			require.Equal(t, Diagnostic{
+				File: "a.txt,v", Line: tt.line, Column: tt.column,
+				Severity: SeverityError, Message: tt.message,
+			}, diags[0])
+
+			_, _, err = parseWithDiagnostics(t, tt.input, true)
+			var parseErr *ParseError
+			require.True(t, errors.As(err, &parseErr))
+			require.Equal(t, diags[0], parseErr.Diagnostic)
+			require.Contains(t, err.Error(), "a.txt,v:")
+
+			var vcsErr *vcs.ParseError
+			require.ErrorIs(t, err, vcs.ErrParse)
+			require.ErrorAs(t, err, &vcsErr)
+			require.Equal(t, "a.txt,v", vcsErr.File)
+			require.Equal(t, tt.line, vcsErr.Line)
+		})
+	}
+}
+
+func TestParseDiagnostics_Newphrases(t *testing.T) {
+	input := strings.Replace(diagnosticsValidRCS, "locks; strict;", "locks; strict;\nowner alice;", 1)
+	input = strings.Replace(input, "next\t1.1;\n", "next\t1.1;\nfilename @a.txt@;\n", 1)
+	rcs, diags, err := parseWithDiagnostics(t, input, true)
+	require.NoError(t, err, "newphrases are valid RCS")
+	require.Empty(t, diags)
+	require.Equal(t, "alice", rcs.Deltas["1.2"].Author)
+}
+
+func TestParseDiagnostics_LoneCR(t *testing.T) {
+	input := strings.Replace(diagnosticsValidRCS, "@world\n@", "@one\rtwo\r@", 1)
+	rcs, diags, err := parseWithDiagnostics(t, input, true)
+	require.NoError(t, err, "CR line endings are warnings")
+	require.Equal(t, "one\rtwo\r", rcs.Deltas["1.2"].Text)
+	require.Equal(t, []Diagnostic{{File: "a.txt,v", Line: 29, Column: 1, Severity: SeverityWarning,
+		Message: "text of revision 1.2 has CR-only line endings"}}, diags)
+}
@@ -0,0 +1,420 @@
+package cvs
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// RevisionContent reconstructs the full content of the given revision.
+//
+// RCS stores the head revision in full; earlier trunk revisions are stored as
+// reverse diffs and branch revisions as forward diffs from their branch point.
+// With a content cache, the deltas are applied from the nearest revision
+// reconstructed before.
+func (r *RCSFile) RevisionContent(rev string) ([]byte, error) {
+	if data, ok := r.cache.get(r.contentKey(rev), true); ok {
+		return data, nil
+	}
+	full, err := r.withTexts()
+	if err != nil {
+		return nil, err
+	}
+	lines, err := full.revisionLines(rev)
+	if err != nil {
+		return nil, err
+	}
+	data := bytes.Join(lines, nil)
+	if err := r.cache.put(r.contentKey(rev), data, true); err != nil {
+		log.Printf("Warning: failed to cache %s revision %s: %v", r.Path, rev, err)
+	}
+	return data, nil
+}
+
+func (r *RCSFile) revisionLines(rev string) ([][]byte, error) {
+	if _, ok := r.Deltas[rev]; !ok {
+		return nil, fmt.Errorf("revision %s not found", rev)
+	}
+
+	if !isBranchNumber(rev) {
+		return r.trunkLines(rev)
+	}
+
+	// Branch revision: start from the branch point and apply forward diffs
+	parts := strings.Split(rev, ".")
+	branchPoint := strings.Join(parts[:len(parts)-2], ".")
+	branchPrefix := strings.Join(parts[:len(parts)-1], ".") + "."
+	if r.Deltas[branchPoint] == nil {
+		return nil, fmt.Errorf("branch point of %s: revision %s not found", rev, branchPoint)
+	}
+
+	current := ""
+	for _, b := range r.Deltas[branchPoint].Branches {
+		if strings.HasPrefix(b, branchPrefix) {
+			current = b
+			break
+		}
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	for current != "" && !seen[current] && r.Deltas[current] != nil {
+		seen[current] = true
+		chain = append(chain, current)
+		if current == rev {
+			break
+		}
+		current = r.Deltas[current].Next
+	}
+	if len(chain) == 0 || chain[len(chain)-1] != rev {
+		return nil, fmt.Errorf("revision %s is not reachable from branch point %s", rev, branchPoint)
+	}
+
+	start, lines := r.cachedLines(chain)
+	if lines == nil {
+		var err error
+		if lines, err = r.revisionLines(branchPoint); err != nil {
+			return nil, fmt.Errorf("branch point of %s: %w", rev, err)
+		}
+	}
+	return r.applyChain(lines, chain[start:])
+}
+
+func (r *RCSFile) trunkLines(rev string) ([][]byte, error) {
+	head := r.Deltas[r.Head]
+	if head == nil {
+		return nil, fmt.Errorf("head revision %s not found", r.Head)
+	}
+
+	chain := []string{r.Head}
+	seen := map[string]bool{r.Head: true}
+	for current := r.Head; current != rev; {
+		next := r.Deltas[current].Next
+		if next == "" || r.Deltas[next] == nil || seen[next] {
+			return nil, fmt.Errorf("revision %s is not reachable from head %s", rev, r.Head)
+		}
+		seen[next] = true
+		chain = append(chain, next)
+		current = next
+	}
+
+	start, lines := r.cachedLines(chain)
+	if lines == nil {
+		if err := checkDeltaType(head); err != nil {
+			return nil, err
+		}
+		lines = splitLines([]byte(head.Text))
+		r.cacheLines(r.Head, lines)
+		start = 1
+	}
+	return r.applyChain(lines, chain[start:])
+}
+
+// cachedLines returns the lines of the last revision of the delta chain
+// that is cached and the index of the revision after it, or nil lines if
+// none is cached
+func (r *RCSFile) cachedLines(chain []string) (int, [][]byte) {
+	if r.cache == nil {
+		return 0, nil
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if data, ok := r.cache.get(r.contentKey(chain[i]), false); ok {
+			return i + 1, splitLines(data)
+		}
+	}
+	return 0, nil
+}
+
+// applyChain applies the deltas of the revisions in chain to lines in turn,
+// caching every revision on the way
+func (r *RCSFile) applyChain(lines [][]byte, chain []string) ([][]byte, error) {
+	for _, rev := range chain {
+		delta := r.Deltas[rev]
+		if err := checkDeltaType(delta); err != nil {
+			return nil, err
+		}
+		var err error
+		lines, err = applyRCSDiff(lines, delta.Text)
+		if err != nil {
+			return nil, fmt.Errorf("revision %s: %w", rev, err)
+		}
+		r.cacheLines(rev, lines)
+	}
+	return lines, nil
+}
+
+// cacheLines keeps an intermediate revision in memory, as a starting point
+// for later delta chains
+func (r *RCSFile) cacheLines(rev string, lines [][]byte) {
+	if r.cache != nil {
+		_ = r.cache.put(r.contentKey(rev), bytes.Join(lines, nil), false)
+	}
+}
+
+// checkDeltaType rejects delta texts stored in a form we cannot decode.
+// CVSNT marks those with a "compressed_*" delta type.
+func checkDeltaType(delta *Delta) error {
+	if strings.HasPrefix(delta.DeltaType, "compressed") {
+		return fmt.Errorf("revision %s: unsupported delta type %q", delta.Revision, delta.DeltaType)
+	}
+	return nil
+}
+
+// applyRCSDiff applies an RCS ed-style diff ("dL N" / "aL N" commands) to the
+// source lines. Line numbers in the diff refer to the source text.
+func applyRCSDiff(source [][]byte, diff string) ([][]byte, error) {
+	cmds := splitLines([]byte(diff))
+	result := make([][]byte, 0, len(source))
+	consumed := 0 // number of source lines already copied or deleted
+
+	for i := 0; i < len(cmds); i++ {
+		cmd := strings.TrimRight(string(cmds[i]), "\r\n")
+		if cmd == "" {
+			continue
+		}
+
+		op := cmd[0]
+		fields := strings.Fields(cmd[1:])
+		if (op != 'a' && op != 'd') || len(fields) != 2 {
+			return nil, fmt.Errorf("invalid diff command %q", cmd)
+		}
+		line, err1 := strconv.Atoi(fields[0])
+		count, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || line < 0 || count < 0 {
+			return nil, fmt.Errorf("invalid diff command %q", cmd)
+		}
+
+		switch op {
+		case 'd':
+			start := line - 1
+			if start < consumed || start+count > len(source) {
+				return nil, fmt.Errorf("diff command %q out of range", cmd)
+			}
+			result = append(result, source[consumed:start]...)
+			consumed = start + count
+
+		case 'a':
+			if line < consumed || line > len(source) {
+				return nil, fmt.Errorf("diff command %q out of range", cmd)
+			}
+			if count > len(cmds)-i-1 {
+				return nil, fmt.Errorf("diff command %q has too few lines", cmd)
+			}
+			result = append(result, source[consumed:line]...)
+			consumed = line
+			result = append(result, cmds[i+1:i+1+count]...)
+			i += count
+		}
+	}
+
+	result = append(result, source[consumed:]...)
+	return result, nil
+}
+
+// splitLines splits data into lines, keeping the trailing newline on each
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	for len(data) > 0 {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			lines = append(lines, data)
+			break
+		}
+		lines = append(lines, data[:idx+1])
+		data = data[idx+1:]
+	}
+	return lines
+}
+
+// RCSDiff returns the RCS delta text that turns source into target, as
+// stored for the revisions other than the head
+func RCSDiff(source, target []byte) string {
+	return makeRCSDiff(splitLines(source), splitLines(target))
+}
+
+// makeRCSDiff returns the RCS ed-style diff that turns the source lines into
+// the target lines, so that applyRCSDiff(source, makeRCSDiff(source, target))
+// yields target. Each change deletes before it adds, as RCS itself writes.
+func makeRCSDiff(source, target [][]byte) string {
+	var b strings.Builder
+	edits := diffLines(source, target)
+	src, dst := 0, 0 // lines of source and target covered so far
+	for i := 0; i < len(edits); {
+		if edits[i] == editEqual {
+			src++
+			dst++
+			i++
+			continue
+		}
+		deleted, inserted := 0, 0
+		for ; i < len(edits) && edits[i] != editEqual; i++ {
+			if edits[i] == editDelete {
+				deleted++
+			} else {
+				inserted++
+			}
+		}
+		if deleted > 0 {
+			fmt.Fprintf(&b, "d%d %d\n", src+1, deleted)
+			src += deleted
+		}
+		if inserted > 0 {
+			fmt.Fprintf(&b, "a%d %d\n", src, inserted)
+			for _, line := range target[dst : dst+inserted] {
+				b.Write(line)
+			}
+			dst += inserted
+		}
+	}
+	return b.String()
+}
+
+// Edit operations produced by diffLines
+const (
+	editEqual byte = iota
+	editDelete
+	editInsert
+)
+
+// diffLines returns a shortest edit script turning a into b. Each element
+// keeps, deletes or inserts one line.
+//
+// It uses the linear space variant of the Myers algorithm: a search from
+// both ends finds a point on a shortest path, and the halves on either side
+// are diffed in turn. Memory stays proportional to the input, however far
+// apart the two texts are.
+func diffLines(a, b [][]byte) []byte {
+	size := 2*((len(a)+len(b)+1)/2) + 2
+	d := &differ{a: a, b: b, forward: make([]int, size), reverse: make([]int, size)}
+	d.diff(0, len(a), 0, len(b))
+	return d.edits
+}
+
+// differ holds the state of one diffLines call. The furthest reaching
+// paths are reused by every split, which runs before its halves are diffed.
+type differ struct {
+	a, b             [][]byte
+	forward, reverse []int // furthest x per diagonal, from the start and from the end
+	edits            []byte
+}
+
+func (d *differ) equal(x, y int) bool {
+	return bytes.Equal(d.a[x], d.b[y])
+}
+
+func (d *differ) emit(op byte, count int) {
+	for range count {
+		d.edits = append(d.edits, op)
+	}
+}
+
+// diff appends the edits turning a[a0:a1] into b[b0:b1]
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	prefix := 0
+	for a0+prefix < a1 && b0+prefix < b1 && d.equal(a0+prefix, b0+prefix) {
+		prefix++
+	}
+	d.emit(editEqual, prefix)
+	a0, b0 = a0+prefix, b0+prefix
+
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && d.equal(a1-suffix-1, b1-suffix-1) {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	// With the common ends removed, either side is empty or a shortest path
+	// has at least two edits, so each split leaves two shorter paths
+	switch {
+	case a0 == a1:
+		d.emit(editInsert, b1-b0)
+	case b0 == b1:
+		d.emit(editDelete, a1-a0)
+	default:
+		x, y, ok := d.split(a0, a1, b0, b1)
+		if !ok {
+			d.emit(editDelete, a1-a0)
+			d.emit(editInsert, b1-b0)
+			break
+		}
+		d.diff(a0, x, b0, y)
+		d.diff(x, a1, y, b1)
+	}
+	d.emit(editEqual, suffix)
+}
+
+// split returns a point on a shortest path from (a0, b0) to (a1, b1), found
+// where the paths searched from both ends meet. It reports false when the
+// ranges have no line in common, so that no point in between helps.
+func (d *differ) split(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward, reverse := d.forward[:2*maxD+2], d.reverse[:2*maxD+2]
+	for i := range forward {
+		forward[i], reverse[i] = -1, -1
+	}
+	forward[offset+1], reverse[offset+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	// Diagonals whose paths left the grid are skipped from then on
+	fStart, fEnd, rStart, rEnd := 0, 0, 0, 0
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.equal(a0+x, b0+y) {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				r := offset + delta - k
+				if r >= 0 && r < len(reverse) && reverse[r] != -1 && x >= n-reverse[r] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+
+		for k := -step + rStart; k <= step-rEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && reverse[offset+k-1] < reverse[offset+k+1]) {
+				x = reverse[offset+k+1]
+			} else {
+				x = reverse[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.equal(a1-x-1, b1-y-1) {
+				x++
+				y++
+			}
+			reverse[offset+k] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				f := offset + delta - k
+				if f >= 0 && f < len(forward) && forward[f] != -1 && forward[f] >= n-x {
+					fx := forward[f]
+					return a0 + fx, b0 + fx - (f - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,197 @@
+package cvs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// contentRCS has trunk revisions 1.1-1.3 and a two-revision branch off 1.2
+const contentRCS = `head	1.3;
+access;
+symbols
+	FEATURE:1.2.0.2;
+locks; strict;
+comment	@# @;
+
+
+1.3
+date	2024.01.03.00.00.00;	author alice;	state Exp;
+branches;
+next	1.2;
+
+1.2
+date	2024.01.02.00.00.00;	author alice;	state Exp;
+branches
+	1.2.2.1;
+next	1.1;
+
+1.1
+date	2024.01.01.00.00.00;	author alice;	state Exp;
+branches;
+next	;
+
+1.2.2.1
+date	2024.01.04.00.00.00;	author bob;	state Exp;
+branches;
+next	1.2.2.2;
+
+1.2.2.2
+date	2024.01.05.00.00.00;	author bob;	state Exp;
+branches;
+next	;
+
+
+desc
+@@
+
+
+1.3
+log
+@third
+@
+text
+@a
+b
+c
+@
+
+
+1.2
+log
+@second
+@
+text
+@d2 1
+a2 1
+B
+@
+
+
+1.1
+log
+@first
+@
+text
+@d3 1
+@
+
+
+1.2.2.1
+log
+@branch one
+@
+text
+@a3 1
+d
+@
+
+
+1.2.2.2
+log
+@branch two
+@
+text
+@d1 1
+a1 1
+x
+@
+`
+
+func parseContentRCS(t *testing.T) *RCSFile {
+	t.Helper()
+	rcs, err := NewRCSParser(strings.NewReader(contentRCS)).Parse()
+	require.NoError(t, err)
+	return rcs
+}
+
+func TestRevisionContent_Trunk(t *testing.T) {
+	rcs := parseContentRCS(t)
+
+	cases := map[string]string{
+		"1.3": "a\nb\nc\n",
+		"1.2": "a\nB\nc\n",
+		"1.1": "a\nB\n",
+	}
+	for rev, want := range cases {
+		got, err := rcs.RevisionContent(rev)
+		require.NoError(t, err, rev)
+		require.Equal(t, want, string(got), rev)
+	}
+}
+
+func TestRevisionContent_Branch(t *testing.T) {
+	rcs := parseContentRCS(t)
+
+	got, err := rcs.RevisionContent("1.2.2.1")
+	require.NoError(t, err)
+	require.Equal(t, "a\nB\nc\nd\n", string(got))
+
+	got, err = rcs.RevisionContent("1.2.2.2")
+	require.NoError(t, err)
+	require.Equal(t, "x\nB\nc\nd\n", string(got))
+}
+
+func TestRevisionContent_UnknownRevision(t *testing.T) {
+	rcs := parseContentRCS(t)
+	_, err := rcs.RevisionContent("1.9")
+	require.Error(t, err)
+}
+
+func TestRevisionContent_BinaryHead(t *testing.T) {
+	raw := "head 1.1;\naccess;\nsymbols;\nlocks;\n\n1.1\ndate 2024.01.01.00.00.00; author a; state Exp;\nbranches;\nnext ;\n\ndesc\n@@\n\n1.1\nlog\n@bin\n@\ntext\n@\x00\xff\xfe@@\x80@\n"
+	rcs, err := NewRCSParser(strings.NewReader(raw)).Parse()
+	require.NoError(t, err)
+
+	got, err := rcs.RevisionContent("1.1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0xff, 0xfe, '@', 0x80}, got)
+}
+
+func TestApplyRCSDiff(t *testing.T) {
+	source := splitLines([]byte("one\ntwo\nthree\n"))
+
+	got, err := applyRCSDiff(source, "a0 1\nzero\nd2 1\na3 1\nfour\n")
+	require.NoError(t, err)
+	require.Equal(t, "zero\none\nthree\nfour\n", string(joinLines(got)))
+
+	// Empty diff leaves the source unchanged
+	got, err = applyRCSDiff(source, "")
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\nthree\n", string(joinLines(got)))
+}
+
+func TestApplyRCSDiff_Errors(t *testing.T) {
+	source := splitLines([]byte("one\ntwo\n"))
+
+	for _, diff := range []string{
+		"x1 1\n",       // unknown command
+		"d1\n",         // missing count
+		"d5 1\n",       // beyond end of source
+		"a1 3\nonly\n", // too few added lines
+		"d2 1\nd1 1\n", // commands out of order
+		"dfoo 1\n",     // non-numeric line
+	} {
+		_, err := applyRCSDiff(source, diff)
+		require.Error(t, err, diff)
+	}
+}
+
+func TestPreviousRevision(t *testing.T) {
+	rcs := parseContentRCS(t)
+
+	require.Equal(t, "1.2", rcs.PreviousRevision("1.3"))
+	require.Equal(t, "", rcs.PreviousRevision("1.1"))
+	require.Equal(t, "1.2", rcs.PreviousRevision("1.2.2.1"))
+	require.Equal(t, "1.2.2.1", rcs.PreviousRevision("1.2.2.2"))
+	require.Equal(t, "1.2.2.9", rcs.PreviousRevision("1.2.2.10"))
+}
+
+func joinLines(lines [][]byte) []byte {
+	var out []byte
+	for _, l := range lines {
+		out = append(out, l...)
+	}
+	return out
+}
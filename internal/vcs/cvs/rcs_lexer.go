@@ -3,6 +3,7 @@ package cvs
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"log"
 )
@@ -22,15 +23,26 @@ const (
 
 // Token represents a lexical token
 type Token struct {
-	Type  TokenType
-	Value string
-	Line  int
+	Type   TokenType
+	Value  string
+	Line   int // Line the token starts on
+	Column int // Byte column the token starts at
 }
 
 // RCSLexer tokenizes RCS file format
 type RCSLexer struct {
 	reader *bufio.Reader
 	line   int
+	col    int // Column of the next byte
+
+	afterCR bool // The last character was a carriage return
+
+	// Position before the last character read, restored when it is unread
+	prevLine, prevCol int
+	prevAfterCR       bool
+
+	// diagnose, if set, is told about input the lexer skips or repairs
+	diagnose func(line, col int, msg string)
 }
 
 // NewRCSLexer creates a new RCS lexer
@@ -38,6 +50,7 @@ func NewRCSLexer(r io.Reader) *RCSLexer {
 	return &RCSLexer{
 		reader: bufio.NewReader(r),
 		line:   1,
+		col:    1,
 	}
 }
 
@@ -45,33 +58,94 @@ func NewRCSLexer(r io.Reader) *RCSLexer {
 func (l *RCSLexer) NextToken() Token {
 	l.skipWhitespace()
 
-	char, _, err := l.reader.ReadRune()
+	line, col := l.line, l.col
+	char, err := l.readRune()
 	if err != nil {
-		return Token{Type: TokenEOF, Line: l.line}
+		return Token{Type: TokenEOF, Line: line, Column: col}
 	}
 
+	var token Token
 	switch char {
 	case ';':
-		return Token{Type: TokenSemicolon, Value: ";", Line: l.line}
+		token = Token{Type: TokenSemicolon, Value: ";"}
 	case ':':
-		return Token{Type: TokenColon, Value: ":", Line: l.line}
+		token = Token{Type: TokenColon, Value: ":"}
 	case '@':
-		return l.readString()
+		token = l.readString(line, col)
 	default:
 		if isDigit(char) || (char == '.' && isDigit(l.peekChar())) {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune before reading number: %v", err)
-			}
-			return l.readNumber()
-		}
-		if isAlpha(char) || char == '_' {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune before reading identifier: %v", err)
-			}
-			return l.readIdent()
+			l.unreadRune("before reading number")
+			token = l.readNumber()
+		} else if isIDChar(char) {
+			l.unreadRune("before reading identifier")
+			token = l.readIdent()
+		} else {
+			// Skip unknown characters
+			l.report(line, col, fmt.Sprintf("unexpected character %q", char))
+			return l.NextToken()
 		}
-		// Skip unknown characters
-		return l.NextToken()
+	}
+	token.Line, token.Column = line, col
+	return token
+}
+
+// readRune reads a character, keeping track of the position
+func (l *RCSLexer) readRune() (rune, error) {
+	char, size, err := l.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	l.advance(char, size)
+	return char, nil
+}
+
+// readByte reads a byte, keeping track of the position
+func (l *RCSLexer) readByte() (byte, error) {
+	char, err := l.reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	l.advance(rune(char), 1)
+	return char, nil
+}
+
+// advance moves the position past char. CR, LF and CRLF each end a line,
+// so positions match editors for files with any line endings.
+func (l *RCSLexer) advance(char rune, size int) {
+	l.prevLine, l.prevCol, l.prevAfterCR = l.line, l.col, l.afterCR
+	switch {
+	case char == '\n' && l.afterCR:
+		// Second half of CRLF
+	case char == '\n' || char == '\r':
+		l.line++
+		l.col = 1
+	default:
+		l.col += size
+	}
+	l.afterCR = char == '\r'
+}
+
+// unreadRune pushes back the last character read by readRune
+func (l *RCSLexer) unreadRune(context string) {
+	if err := l.reader.UnreadRune(); err != nil {
+		log.Printf("Warning: failed to unread rune %s: %v", context, err)
+		return
+	}
+	l.line, l.col, l.afterCR = l.prevLine, l.prevCol, l.prevAfterCR
+}
+
+// unreadByte pushes back the last byte read by readByte
+func (l *RCSLexer) unreadByte(context string) {
+	if err := l.reader.UnreadByte(); err != nil {
+		log.Printf("Warning: failed to unread byte %s: %v", context, err)
+		return
+	}
+	l.line, l.col, l.afterCR = l.prevLine, l.prevCol, l.prevAfterCR
+}
+
+func (l *RCSLexer) report(line, col int, msg string) {
+	if l.diagnose != nil {
+		l.diagnose(line, col, msg)
 	}
 }
 
@@ -88,34 +162,33 @@ func (l *RCSLexer) peekChar() rune {
 
 func (l *RCSLexer) skipWhitespace() {
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.readRune()
 		if err != nil {
 			return
 		}
-		if char == '\n' {
-			l.line++
-		}
 		if !isWhitespace(char) && char != '\n' {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune in skipWhitespace: %v", err)
-			}
+			l.unreadRune("in skipWhitespace")
 			return
 		}
 	}
 }
 
-func (l *RCSLexer) readString() Token {
-	var result []rune
+// readString reads an @-delimited string whose opening @ was read at line
+// and col
+func (l *RCSLexer) readString(line, col int) Token {
+	// Strings are read byte-wise so binary file content survives unchanged
+	var result []byte
 
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.readByte()
 		if err != nil {
+			l.report(line, col, "unterminated string")
 			break
 		}
 
 		if char == '@' {
 			// Check for escaped @@
-			next, _, err := l.reader.ReadRune()
+			next, err := l.readByte()
 			if err != nil {
 				break
 			}
@@ -124,62 +197,63 @@ func (l *RCSLexer) readString() Token {
 				result = append(result, '@')
 			} else {
 				// End of string - unread the extra character
-				if err := l.reader.UnreadRune(); err != nil {
-					log.Printf("Warning: failed to unread rune in readString: %v", err)
-				}
+				l.unreadByte("in readString")
 				break
 			}
 		} else {
-			if char == '\n' {
-				l.line++
-			}
 			result = append(result, char)
 		}
 	}
 
-	return Token{Type: TokenString, Value: string(result), Line: l.line}
+	return Token{Type: TokenString, Value: string(result)}
 }
 
+// readNumber reads a revision number. Per the RCS grammar an id may start
+// with digits (e.g. CVSNT commitids), so a number that continues with
+// identifier characters is returned as TokenIdent.
 func (l *RCSLexer) readNumber() Token {
 	var result []rune
+	isIdent := false
 
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.readRune()
 		if err != nil {
 			break
 		}
 		if isDigit(char) || char == '.' {
 			result = append(result, char)
+		} else if isIDChar(char) {
+			isIdent = true
+			result = append(result, char)
 		} else {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune in readNumber: %v", err)
-			}
+			l.unreadRune("in readNumber")
 			break
 		}
 	}
 
-	return Token{Type: TokenNumber, Value: string(result), Line: l.line}
+	if isIdent {
+		return Token{Type: TokenIdent, Value: string(result)}
+	}
+	return Token{Type: TokenNumber, Value: string(result)}
 }
 
 func (l *RCSLexer) readIdent() Token {
 	var result []rune
 
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.readRune()
 		if err != nil {
 			break
 		}
-		if isAlpha(char) || isDigit(char) || char == '_' || char == '-' {
+		if isIDChar(char) || isDigit(char) || char == '.' {
 			result = append(result, char)
 		} else {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune in readIdent: %v", err)
-			}
+			l.unreadRune("in readIdent")
 			break
 		}
 	}
 
-	return Token{Type: TokenIdent, Value: string(result), Line: l.line}
+	return Token{Type: TokenIdent, Value: string(result)}
 }
 
 func isWhitespace(c rune) bool {
@@ -190,6 +264,17 @@ func isAlpha(c rune) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
 }
 
+// isIDChar reports whether c may appear in an RCS id other than as a digit
+// or period: any visible graphic character except the special characters
+// $ , . : ; @
+func isIDChar(c rune) bool {
+	switch c {
+	case '$', ',', '.', ':', ';', '@':
+		return false
+	}
+	return (c > ' ' && c < 0x7f) || c >= 0xa0
+}
+
 func isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
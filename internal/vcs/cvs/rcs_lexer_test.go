@@ -295,6 +295,34 @@ func TestLexerLineCountingInString(t *testing.T) {
 	}
 }
 
+func TestLexerLineEndings(t *testing.T) {
+	// CR, CRLF and LF each end a line, inside and outside strings
+	lexer := NewRCSLexer(strings.NewReader("@a\rb\r\nc\nd@\rnext\r\n1.1 x"))
+	want := []Token{
+		{Type: TokenString, Value: "a\rb\r\nc\nd", Line: 1, Column: 1},
+		{Type: TokenIdent, Value: "next", Line: 5, Column: 1},
+		{Type: TokenNumber, Value: "1.1", Line: 6, Column: 1},
+		{Type: TokenIdent, Value: "x", Line: 6, Column: 5},
+		{Type: TokenEOF, Line: 6, Column: 6},
+	}
+	for i, w := range want {
+		if got := lexer.NextToken(); got != w {
+			t.Errorf("token %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestLexerIDChars(t *testing.T) {
+	// Any visible character except $ , . : ; @ may appear in an id
+	lexer := NewRCSLexer(strings.NewReader("x-y+z!#/ 1.2a.b"))
+	if token := lexer.NextToken(); token.Value != "x-y+z!#/" {
+		t.Errorf("first token value = %q, want %q", token.Value, "x-y+z!#/")
+	}
+	if token := lexer.NextToken(); token.Type != TokenIdent || token.Value != "1.2a.b" {
+		t.Errorf("second token = %v %q, want TokenIdent %q", token.Type, token.Value, "1.2a.b")
+	}
+}
+
 func TestLexerMultipleTokens(t *testing.T) {
 	input := "head 1.0;"
 	lexer := NewRCSLexer(strings.NewReader(input))
@@ -393,8 +421,8 @@ func TestLexerDeltaEntry(t *testing.T) {
 }
 
 func TestLexerUnknownCharacters(t *testing.T) {
-	// Unknown characters should be skipped
-	lexer := NewRCSLexer(strings.NewReader("head!1.0"))
+	// Special characters that start no token should be skipped
+	lexer := NewRCSLexer(strings.NewReader("head$1.0"))
 
 	token := lexer.NextToken()
 	if token.Type != TokenIdent {
@@ -404,7 +432,7 @@ func TestLexerUnknownCharacters(t *testing.T) {
 		t.Errorf("token value = %q, want %q", token.Value, "head")
 	}
 
-	// ! is skipped, next is number
+	// $ is skipped, next is number
 	token = lexer.NextToken()
 	if token.Type != TokenNumber {
 		t.Errorf("token type = %v, want TokenNumber", token.Type)
@@ -592,3 +620,17 @@ func TestLexerMixedContent(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerIdentStartingWithDigits(t *testing.T) {
+	lexer := NewRCSLexer(strings.NewReader("10045a5e6b1c2d3; 1.2;"))
+
+	token := lexer.NextToken()
+	if token.Type != TokenIdent || token.Value != "10045a5e6b1c2d3" {
+		t.Errorf("token = %v %q, want TokenIdent %q", token.Type, token.Value, "10045a5e6b1c2d3")
+	}
+	lexer.NextToken() // ;
+	token = lexer.NextToken()
+	if token.Type != TokenNumber || token.Value != "1.2" {
+		t.Errorf("token = %v %q, want TokenNumber %q", token.Type, token.Value, "1.2")
+	}
+}
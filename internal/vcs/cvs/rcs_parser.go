@@ -1,31 +1,94 @@
 package cvs
 
 import (
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// RCSParser parses RCS file format
+// RCSParser parses RCS file format. Parsing is lenient by default: it
+// recovers from malformed input and records what it found in Diagnostics.
+// A strict parser fails on the first malformed construct instead.
 type RCSParser struct {
-	lexer *RCSLexer
-	token Token
+	lexer  *RCSLexer
+	token  Token
+	strict bool
+	file   string // Reported in diagnostics
+
+	diagnostics []Diagnostic
+	positions   map[string]Token // Revision number token of every delta
+	texts       map[string]bool  // Revisions with a delta text
 }
 
 // NewRCSParser creates a new RCS parser
 func NewRCSParser(r io.Reader) *RCSParser {
-	lexer := NewRCSLexer(r)
-	return &RCSParser{
-		lexer: lexer,
-		token: lexer.NextToken(),
+	p := &RCSParser{
+		lexer:     NewRCSLexer(r),
+		positions: make(map[string]Token),
+		texts:     make(map[string]bool),
 	}
+	p.lexer.diagnose = func(line, col int, msg string) {
+		p.report(SeverityError, line, col, msg)
+	}
+	p.token = p.lexer.NextToken()
+	return p
+}
+
+// SetStrict makes Parse fail with a *ParseError on malformed input
+func (p *RCSParser) SetStrict(strict bool) {
+	p.strict = strict
+}
+
+// SetFile sets the file name reported in diagnostics
+func (p *RCSParser) SetFile(name string) {
+	p.file = name
+}
+
+// Diagnostics returns the anomalies found by Parse
+func (p *RCSParser) Diagnostics() []Diagnostic {
+	return p.diagnostics
 }
 
 func (p *RCSParser) advance() {
 	p.token = p.lexer.NextToken()
 }
 
+func (p *RCSParser) report(severity string, line, col int, msg string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		File:     p.file,
+		Line:     line,
+		Column:   col,
+		Severity: severity,
+		Message:  msg,
+	})
+}
+
+// errorf records malformed input at the position of tok
+func (p *RCSParser) errorf(tok Token, format string, args ...any) {
+	p.report(SeverityError, tok.Line, tok.Column, fmt.Sprintf(format, args...))
+}
+
+// warnf records input the parser does not understand at the position of tok
+func (p *RCSParser) warnf(tok Token, format string, args ...any) {
+	p.report(SeverityWarning, tok.Line, tok.Column, fmt.Sprintf(format, args...))
+}
+
+// describe names a token for diagnostics
+func describe(tok Token) string {
+	switch tok.Type {
+	case TokenEOF:
+		return "end of file"
+	case TokenString:
+		return "string"
+	case TokenSemicolon, TokenColon:
+		return fmt.Sprintf("'%s'", tok.Value)
+	default:
+		return fmt.Sprintf("%q", tok.Value)
+	}
+}
+
 func parseRCSDate(s string) time.Time {
 	// Format: YYYY.MM.DD.HH.MM.SS
 	parts := strings.Split(s, ".")
@@ -42,6 +105,20 @@ func parseRCSDate(s string) time.Time {
 	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
 }
 
+// validRCSDate reports whether s has the YYYY.MM.DD.HH.MM.SS form
+func validRCSDate(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 6 {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
 // Parse executes the main parsing logic
 func (p *RCSParser) Parse() (*RCSFile, error) {
 	rcs := &RCSFile{
@@ -62,32 +139,32 @@ func (p *RCSParser) Parse() (*RCSFile, error) {
 	// Parse delta texts (log and text for each revision)
 	p.parseDeltaTexts(rcs)
 
+	p.checkStructure(rcs)
+
+	if p.strict {
+		for _, d := range p.diagnostics {
+			if d.Severity == SeverityError {
+				return nil, &ParseError{Diagnostic: d}
+			}
+		}
+	}
 	return rcs, nil
 }
 
 // parseHeader parses the RCS header section
 func (p *RCSParser) parseHeader(rcs *RCSFile) {
-	for p.token.Type != TokenEOF {
-		if p.token.Type != TokenIdent {
-			break
-		}
-
+	for p.token.Type == TokenIdent {
+		field := p.token
 		switch p.token.Value {
 		case "head":
 			p.advance()
-			if p.token.Type == TokenNumber {
-				rcs.Head = p.token.Value
-				p.advance()
-			}
-			p.skipSemicolon()
+			rcs.Head = p.optionalNumber(field)
+			p.expectSemicolon(field)
 
 		case "branch":
 			p.advance()
-			if p.token.Type == TokenNumber {
-				rcs.Branch = p.token.Value
-				p.advance()
-			}
-			p.skipSemicolon()
+			rcs.Branch = p.optionalNumber(field)
+			p.expectSemicolon(field)
 
 		case "access":
 			p.advance()
@@ -95,42 +172,35 @@ func (p *RCSParser) parseHeader(rcs *RCSFile) {
 				rcs.Access = append(rcs.Access, p.token.Value)
 				p.advance()
 			}
-			p.skipSemicolon()
+			p.expectSemicolon(field)
 
-		case "symbols":
+		case "symbols", "locks":
 			p.advance()
+			pairs := rcs.Symbols
+			if field.Value == "locks" {
+				pairs = rcs.Locks
+			}
 			for p.token.Type == TokenIdent {
-				sym := p.token.Value
+				name := p.token
 				p.advance()
-				if p.token.Type == TokenColon {
-					p.advance()
-					if p.token.Type == TokenNumber {
-						rcs.Symbols[sym] = p.token.Value
-						p.advance()
-					}
+				if p.token.Type != TokenColon {
+					p.errorf(name, "%s entry %s has no revision", field.Value, name.Value)
+					continue
 				}
-			}
-			p.skipSemicolon()
-
-		case "locks":
-			p.advance()
-			for p.token.Type == TokenIdent {
-				lock := p.token.Value
 				p.advance()
-				if p.token.Type == TokenColon {
-					p.advance()
-					if p.token.Type == TokenNumber {
-						rcs.Locks[lock] = p.token.Value
-						p.advance()
-					}
+				if p.token.Type != TokenNumber {
+					p.errorf(name, "%s entry %s has no revision", field.Value, name.Value)
+					continue
 				}
+				pairs[name.Value] = p.token.Value
+				p.advance()
 			}
-			p.skipSemicolon()
+			p.expectSemicolon(field)
 
 		case "strict":
 			rcs.StrictLocks = true
 			p.advance()
-			p.skipSemicolon()
+			p.expectSemicolon(field)
 
 		case "comment":
 			p.advance()
@@ -138,19 +208,130 @@ func (p *RCSParser) parseHeader(rcs *RCSFile) {
 				rcs.Comment = p.token.Value
 				p.advance()
 			}
-			p.skipSemicolon()
+			p.expectSemicolon(field)
 
-		default:
-			// Unknown field - could be start of deltas or desc
-			// Don't consume the token, let outer loop handle it
+		case "expand":
+			p.advance()
+			if p.token.Type == TokenString || p.token.Type == TokenIdent {
+				rcs.Expand = p.token.Value
+				p.advance()
+			}
+			p.expectSemicolon(field)
+
+		case "desc":
+			// No deltas; let parseDesc handle it
 			return
+
+		default:
+			// Extension field, e.g. RCS 5.8 "integrity"
+			rcs.Newphrases = append(rcs.Newphrases, p.newphrase(isAdminField))
 		}
+	}
+	if p.token.Type != TokenNumber {
+		p.errorf(p.token, "expected revision or desc, found %s", describe(p.token))
+	}
+}
 
-		// Check if we've hit a revision number (start of deltas)
-		if p.token.Type == TokenNumber {
-			break
+// optionalNumber consumes the revision number value of field, if present
+func (p *RCSParser) optionalNumber(field Token) string {
+	switch p.token.Type {
+	case TokenNumber:
+		rev := p.token.Value
+		p.advance()
+		return rev
+	case TokenSemicolon:
+		return ""
+	default:
+		p.errorf(p.token, "expected revision number after %s, found %s", field.Value, describe(p.token))
+		return ""
+	}
+}
+
+// newphrase consumes a field the parser does not interpret, up to its
+// terminating semicolon. Words stop early at a token stop reports as the
+// start of the next section, so a missing semicolon loses no more than the
+// field.
+func (p *RCSParser) newphrase(stop func(Token) bool) Newphrase {
+	keyword := p.token
+	p.advance()
+	return p.newphraseValue(keyword, stop)
+}
+
+// newphraseValue consumes the words and semicolon of a field whose keyword
+// was consumed
+func (p *RCSParser) newphraseValue(keyword Token, stop func(Token) bool) Newphrase {
+	phrase := Newphrase{Keyword: keyword.Value}
+	for !stop(p.token) {
+		switch p.token.Type {
+		case TokenIdent, TokenNumber, TokenColon:
+			phrase.Words = append(phrase.Words, Word{Value: p.token.Value})
+		case TokenString:
+			phrase.Words = append(phrase.Words, Word{Value: p.token.Value, String: true})
+		default:
+			p.expectSemicolon(keyword)
+			return phrase
 		}
+		p.advance()
 	}
+	p.errorf(p.token, "expected ';' after %s, found %s", keyword.Value, describe(p.token))
+	return phrase
+}
+
+// adminFields and deltaFields are the fields the parser interprets; desc
+// ends both sections
+var (
+	adminFields = map[string]bool{"head": true, "branch": true, "access": true, "symbols": true,
+		"locks": true, "strict": true, "comment": true, "expand": true, "desc": true}
+	deltaFields = map[string]bool{"date": true, "author": true, "state": true, "branches": true,
+		"next": true, "commitid": true, "mergepoint1": true, "deltatype": true, "kopt": true,
+		"permissions": true, "desc": true}
+)
+
+// isAdminField reports whether tok is a field of the admin section the
+// parser interprets
+func isAdminField(tok Token) bool {
+	return tok.Type == TokenIdent && adminFields[tok.Value]
+}
+
+// isDeltaField reports whether tok is a field of a delta the parser
+// interprets
+func isDeltaField(tok Token) bool {
+	return tok.Type == TokenIdent && deltaFields[tok.Value]
+}
+
+// isDeltaTextField reports whether tok is a field of the delta text the
+// parser interprets
+func isDeltaTextField(tok Token) bool {
+	return tok.Type == TokenIdent && (tok.Value == "log" || tok.Value == "text")
+}
+
+// hasLoneCR reports whether s has a carriage return not followed by a
+// newline
+func hasLoneCR(s string) bool {
+	for i := strings.IndexByte(s, '\r'); i >= 0; i = strings.IndexByte(s, '\r') {
+		if i+1 == len(s) || s[i+1] != '\n' {
+			return true
+		}
+		s = s[i+1:]
+	}
+	return false
+}
+
+// phraseValue consumes the value of a delta field up to its terminating
+// semicolon and returns it. Values made of several tokens are joined with
+// spaces.
+func (p *RCSParser) phraseValue() string {
+	var parts []string
+	for p.token.Type != TokenEOF && p.token.Type != TokenSemicolon {
+		if p.token.Type == TokenColon {
+			parts = append(parts, ":")
+		} else {
+			parts = append(parts, p.token.Value)
+		}
+		p.advance()
+	}
+	p.skipSemicolon()
+	return strings.Join(parts, " ")
 }
 
 // skipSemicolon skips a semicolon if present
@@ -160,6 +341,16 @@ func (p *RCSParser) skipSemicolon() {
 	}
 }
 
+// expectSemicolon skips the semicolon terminating field, reporting a
+// missing one
+func (p *RCSParser) expectSemicolon(field Token) {
+	if p.token.Type != TokenSemicolon {
+		p.errorf(p.token, "expected ';' after %s, found %s", field.Value, describe(p.token))
+		return
+	}
+	p.advance()
+}
+
 // parseDeltas parses delta nodes (revision metadata)
 func (p *RCSParser) parseDeltas(rcs *RCSFile) {
 	for p.token.Type != TokenEOF {
@@ -173,9 +364,13 @@ func (p *RCSParser) parseDeltas(rcs *RCSFile) {
 			break
 		}
 
+		revToken := p.token
 		rev := p.token.Value
 		p.advance()
 		delta := &Delta{Revision: rev}
+		if _, ok := rcs.Deltas[rev]; ok {
+			p.errorf(revToken, "duplicate revision %s", rev)
+		}
 
 		// Parse delta fields until we hit another revision number or desc
 		for p.token.Type != TokenEOF {
@@ -188,62 +383,86 @@ func (p *RCSParser) parseDeltas(rcs *RCSFile) {
 				break
 			}
 
-			if p.token.Type == TokenIdent {
-				switch p.token.Value {
-				case "date":
-					p.advance()
-					if p.token.Type == TokenNumber {
-						delta.Date = parseRCSDate(p.token.Value)
-						p.advance()
-					}
-					p.skipSemicolon()
+			if p.token.Type != TokenIdent {
+				p.errorf(p.token, "unexpected %s in revision %s", describe(p.token), rev)
+				p.advance()
+				continue
+			}
 
-				case "author":
-					p.advance()
-					if p.token.Type == TokenIdent {
-						delta.Author = p.token.Value
-						p.advance()
+			field := p.token
+			switch p.token.Value {
+			case "date":
+				p.advance()
+				if p.token.Type == TokenNumber {
+					if !validRCSDate(p.token.Value) {
+						p.errorf(p.token, "invalid date %q in revision %s", p.token.Value, rev)
 					}
-					p.skipSemicolon()
-
-				case "state":
+					delta.Date = parseRCSDate(p.token.Value)
 					p.advance()
-					if p.token.Type == TokenIdent {
-						delta.State = p.token.Value
-						p.advance()
-					}
-					p.skipSemicolon()
+				}
+				p.expectSemicolon(field)
 
-				case "branches":
+			case "author":
+				p.advance()
+				if p.token.Type == TokenIdent {
+					delta.Author = p.token.Value
 					p.advance()
-					for p.token.Type == TokenNumber {
-						delta.Branches = append(delta.Branches, p.token.Value)
-						p.advance()
-					}
-					p.skipSemicolon()
+				}
+				p.expectSemicolon(field)
 
-				case "next":
+			case "state":
+				p.advance()
+				if p.token.Type == TokenIdent {
+					delta.State = p.token.Value
 					p.advance()
-					if p.token.Type == TokenNumber {
-						delta.Next = p.token.Value
-						p.advance()
-					}
-					p.skipSemicolon()
+				}
+				p.expectSemicolon(field)
 
-				default:
-					// Unknown field - skip it and its value
+			case "branches":
+				p.advance()
+				for p.token.Type == TokenNumber {
+					delta.Branches = append(delta.Branches, p.token.Value)
 					p.advance()
-					// Skip to semicolon
-					for p.token.Type != TokenEOF && p.token.Type != TokenSemicolon {
-						p.advance()
-					}
-					p.skipSemicolon()
 				}
-			} else {
+				p.expectSemicolon(field)
+
+			case "next":
+				p.advance()
+				delta.Next = p.optionalNumber(field)
+				p.expectSemicolon(field)
+
+			case "commitid":
+				p.advance()
+				delta.CommitID = p.phraseValue()
+
+			case "mergepoint1":
+				p.advance()
+				delta.MergePoint = p.phraseValue()
+
+			case "deltatype":
+				p.advance()
+				delta.DeltaType = p.phraseValue()
+
+			case "kopt":
+				p.advance()
+				delta.KeywordMode = p.phraseValue()
+
+			case "permissions":
 				p.advance()
+				delta.Permissions = p.phraseValue()
+
+			default:
+				delta.Newphrases = append(delta.Newphrases, p.newphrase(isDeltaField))
 			}
 		}
 
+		if delta.Date.IsZero() {
+			p.errorf(revToken, "revision %s has no date", rev)
+		}
+		if delta.Author == "" {
+			p.errorf(revToken, "revision %s has no author", rev)
+		}
+		p.positions[rev] = revToken
 		rcs.Deltas[rev] = delta
 		rcs.DeltaOrder = append(rcs.DeltaOrder, rev)
 	}
@@ -251,13 +470,17 @@ func (p *RCSParser) parseDeltas(rcs *RCSFile) {
 
 // parseDesc parses the description
 func (p *RCSParser) parseDesc(rcs *RCSFile) {
-	if p.token.Type == TokenIdent && p.token.Value == "desc" {
-		p.advance()
-		if p.token.Type == TokenString {
-			rcs.Description = p.token.Value
-			p.advance()
-		}
+	if p.token.Type != TokenIdent || p.token.Value != "desc" {
+		p.errorf(p.token, "expected desc, found %s", describe(p.token))
+		return
+	}
+	p.advance()
+	if p.token.Type != TokenString {
+		p.errorf(p.token, "expected description string, found %s", describe(p.token))
+		return
 	}
+	rcs.Description = p.token.Value
+	p.advance()
 }
 
 // parseDeltaTexts parses log and text for each revision
@@ -265,44 +488,83 @@ func (p *RCSParser) parseDeltaTexts(rcs *RCSFile) {
 	for p.token.Type != TokenEOF {
 		// Revision number
 		if p.token.Type != TokenNumber {
-			// Not a revision number, skip this token and continue looking
-			p.advance()
+			// Not a revision number: report it once and skip to the next
+			p.errorf(p.token, "expected revision number, found %s", describe(p.token))
+			for p.token.Type != TokenEOF && p.token.Type != TokenNumber {
+				p.advance()
+			}
 			continue
 		}
 
+		revToken := p.token
 		rev := p.token.Value
 		p.advance()
 
 		delta := rcs.Deltas[rev]
 		if delta == nil {
+			p.errorf(revToken, "text of revision %s, which is not in the delta list", rev)
 			delta = &Delta{Revision: rev}
 			rcs.Deltas[rev] = delta
 		}
+		if p.texts[rev] {
+			p.errorf(revToken, "duplicate text of revision %s", rev)
+		}
+		p.texts[rev] = true
 
 		// Parse log and text
 		for p.token.Type != TokenEOF && p.token.Type != TokenNumber {
-			if p.token.Type == TokenIdent {
-				switch p.token.Value {
-				case "log":
-					p.advance()
-					if p.token.Type == TokenString {
-						delta.Log = p.token.Value
-						p.advance()
-					}
-
-				case "text":
-					p.advance()
-					if p.token.Type == TokenString {
-						delta.Text = p.token.Value
-						p.advance()
+			if p.token.Type != TokenIdent {
+				p.errorf(p.token, "unexpected %s in text of revision %s", describe(p.token), rev)
+				p.advance()
+				continue
+			}
+			field := p.token
+			p.advance()
+			switch field.Value {
+			case "log", "text":
+				if p.token.Type != TokenString {
+					p.errorf(p.token, "expected %s string of revision %s, found %s", field.Value, rev, describe(p.token))
+					continue
+				}
+				if field.Value == "log" {
+					delta.Log = p.token.Value
+				} else {
+					delta.Text = p.token.Value
+					if hasLoneCR(delta.Text) {
+						p.warnf(p.token, "text of revision %s has CR-only line endings", rev)
 					}
-
-				default:
-					p.advance()
 				}
-			} else {
 				p.advance()
+
+			default:
+				delta.TextNewphrases = append(delta.TextNewphrases, p.newphraseValue(field, isDeltaTextField))
+			}
+		}
+	}
+}
+
+// checkStructure reports revisions referenced but not defined, and deltas
+// without a text
+func (p *RCSParser) checkStructure(rcs *RCSFile) {
+	if rcs.Head != "" && rcs.Deltas[rcs.Head] == nil {
+		p.errorf(Token{}, "head revision %s is not in the delta list", rcs.Head)
+	}
+	if rcs.Head == "" && len(rcs.DeltaOrder) > 0 {
+		p.errorf(Token{}, "missing head revision")
+	}
+	for _, rev := range rcs.DeltaOrder {
+		delta := rcs.Deltas[rev]
+		pos := p.positions[rev]
+		if delta.Next != "" && rcs.Deltas[delta.Next] == nil {
+			p.errorf(pos, "revision %s: next revision %s is not in the delta list", rev, delta.Next)
+		}
+		for _, branch := range delta.Branches {
+			if rcs.Deltas[branch] == nil {
+				p.errorf(pos, "revision %s: branch revision %s is not in the delta list", rev, branch)
 			}
 		}
+		if !p.texts[rev] {
+			p.errorf(pos, "revision %s has no log or text", rev)
+		}
 	}
 }
@@ -730,3 +730,146 @@ func TestParserAdvance(t *testing.T) {
 		t.Errorf("After advance, token = %v %q, want Number '1.5'", parser.token.Type, parser.token.Value)
 	}
 }
+
+func TestParserUnknownHeaderFieldKeepsLaterFields(t *testing.T) {
+	input := "head 1.5; unknown_field somevalue; branch 1.5.1;"
+	rcs, err := NewRCSParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if rcs.Branch != "1.5.1" {
+		t.Errorf("Branch = %q, want %q", rcs.Branch, "1.5.1")
+	}
+}
+
+func TestParserExpandHeader(t *testing.T) {
+	input := `head 1.1;
+access;
+symbols;
+locks; strict;
+expand @b@;
+
+1.1
+date 2024.01.15.12.30.00; author test; state Exp;
+branches;
+next ;
+
+desc
+@@
+`
+	rcs, err := NewRCSParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if rcs.Expand != "b" {
+		t.Errorf("Expand = %q, want %q", rcs.Expand, "b")
+	}
+	if rcs.Deltas["1.1"] == nil {
+		t.Error("deltas after expand should still be parsed")
+	}
+}
+
+func TestParserCVSNTDeltaFields(t *testing.T) {
+	input := `head 1.2;
+access;
+symbols;
+locks; strict;
+comment @# @;
+
+
+1.2
+date	2024.01.15.12.30.00;	author test;	state Exp;
+branches;
+next	1.1;
+deltatype	text;
+kopt	kv;
+permissions	644;
+commitid	4f2a9c1e5b7d0a8;
+mergepoint1	1.1.2.3;
+filename	main.c;
+
+1.1
+date	2024.01.14.12.30.00;	author test;	state Exp;
+branches;
+next	;
+commitid	10045a5e6b1c2d3;
+
+desc
+@@
+
+
+1.2
+log
+@merge@
+text
+@content
+@
+
+
+1.1
+log
+@initial@
+text
+@d1 1
+a1 1
+old
+@
+`
+	rcs, err := NewRCSParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	delta := rcs.Deltas["1.2"]
+	if delta == nil {
+		t.Fatal("Delta 1.2 not found")
+	}
+	if delta.CommitID != "4f2a9c1e5b7d0a8" {
+		t.Errorf("CommitID = %q, want %q", delta.CommitID, "4f2a9c1e5b7d0a8")
+	}
+	if delta.MergePoint != "1.1.2.3" {
+		t.Errorf("MergePoint = %q, want %q", delta.MergePoint, "1.1.2.3")
+	}
+	if delta.DeltaType != "text" {
+		t.Errorf("DeltaType = %q, want %q", delta.DeltaType, "text")
+	}
+	if delta.KeywordMode != "kv" {
+		t.Errorf("KeywordMode = %q, want %q", delta.KeywordMode, "kv")
+	}
+	if delta.Permissions != "644" {
+		t.Errorf("Permissions = %q, want %q", delta.Permissions, "644")
+	}
+	if delta.Next != "1.1" {
+		t.Errorf("Next = %q, want %q", delta.Next, "1.1")
+	}
+
+	// A commitid starting with digits must not be mistaken for a revision
+	if got := rcs.Deltas["1.1"].CommitID; got != "10045a5e6b1c2d3" {
+		t.Errorf("CommitID = %q, want %q", got, "10045a5e6b1c2d3")
+	}
+	if len(rcs.Deltas) != 2 {
+		t.Errorf("len(Deltas) = %d, want 2", len(rcs.Deltas))
+	}
+
+	content, err := rcs.RevisionContent("1.1")
+	if err != nil {
+		t.Fatalf("RevisionContent failed: %v", err)
+	}
+	if string(content) != "old\n" {
+		t.Errorf("content = %q, want %q", content, "old\n")
+	}
+}
+
+func TestRevisionContent_CompressedDeltaType(t *testing.T) {
+	rcs := &RCSFile{
+		Head: "1.1",
+		Deltas: map[string]*Delta{
+			"1.1": {Revision: "1.1", DeltaType: "compressed_text", Text: "x"},
+		},
+	}
+	if _, err := rcs.RevisionContent("1.1"); err == nil {
+		t.Error("expected error for compressed delta type")
+	}
+}
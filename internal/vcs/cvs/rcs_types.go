@@ -1,12 +1,15 @@
 package cvs
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
 
 // RCSFile represents a parsed RCS file
 type RCSFile struct {
+	Path        string // Working file path relative to the repository root
+	InAttic     bool   // Loaded from an Attic directory (deleted on trunk)
 	Head        string
 	Branch      string
 	Access      []string
@@ -14,9 +17,16 @@ type RCSFile struct {
 	Locks       map[string]string
 	StrictLocks bool
 	Comment     string
+	Expand      string // Keyword substitution mode (e.g. "kv", "b")
 	Description string
 	Deltas      map[string]*Delta
-	DeltaOrder  []string // Order of deltas as they appear
+	DeltaOrder  []string    // Order of deltas as they appear
+	Newphrases  []Newphrase // Admin fields the parser does not interpret, e.g. "integrity"
+
+	cache   *ContentCache // Reconstructed revisions (nil = no caching)
+	cacheID string        // Identifies the file in the cache
+
+	textFile string // RCS file to read the dropped delta texts from (empty = texts loaded)
 }
 
 // Delta represents a single revision in an RCS file
@@ -29,6 +39,34 @@ type Delta struct {
 	Next     string
 	Log      string
 	Text     string
+
+	// Extended fields written by CVSNT and CVS 1.12
+	CommitID    string // Identifies all file revisions of one changeset
+	MergePoint  string // Revision merged into this one (CVSNT mergepoint1)
+	DeltaType   string // Storage of Text, e.g. "text" or "compressed_binary" (CVSNT)
+	KeywordMode string // Per-revision keyword substitution mode (CVSNT kopt)
+	Permissions string // Octal file permissions (CVSNT)
+
+	Newphrases     []Newphrase // Other fields of the delta
+	TextNewphrases []Newphrase // Fields between the log and text of the delta text
+}
+
+// Newphrase is an RCS field the parser does not interpret ("newphrase" in
+// the RCS grammar). It is kept so rewritten files lose nothing.
+type Newphrase struct {
+	Keyword string
+	Words   []Word
+}
+
+// Word is a value of a Newphrase
+type Word struct {
+	Value  string
+	String bool // An @-string rather than an id, number or colon
+}
+
+// IsDead reports whether the revision deletes the file
+func (d *Delta) IsDead() bool {
+	return d.State == "dead"
 }
 
 // Commit represents a commit extracted from RCS deltas
@@ -38,6 +76,7 @@ type Commit struct {
 	Date     time.Time
 	Message  string
 	Branch   string // Empty for trunk
+	CommitID string // Changeset identifier, if recorded
 }
 
 // GetCommits returns commits in reverse chronological order
@@ -65,6 +104,7 @@ func (r *RCSFile) GetCommits() []*Commit {
 			Date:     delta.Date,
 			Message:  delta.Log,
 			Branch:   branch,
+			CommitID: delta.CommitID,
 		})
 
 		// Add branches from this commit
@@ -113,6 +153,30 @@ func (r *RCSFile) GetTags() map[string]string {
 	return tags
 }
 
+// PreviousRevision returns the revision that rev was derived from, or an
+// empty string for the initial revision of the file
+func (r *RCSFile) PreviousRevision(rev string) string {
+	if !isBranchNumber(rev) {
+		if delta := r.Deltas[rev]; delta != nil {
+			return delta.Next
+		}
+		return ""
+	}
+
+	parts := strings.Split(rev, ".")
+	last := parts[len(parts)-1]
+	if last == "1" {
+		// First revision on a branch derives from the branch point
+		return strings.Join(parts[:len(parts)-2], ".")
+	}
+	n := 0
+	for _, c := range last {
+		n = n*10 + int(c-'0')
+	}
+	parts[len(parts)-1] = fmt.Sprint(n - 1)
+	return strings.Join(parts, ".")
+}
+
 func isBranchNumber(rev string) bool {
 	// Magic branch numbers have ".0." in them (e.g., 1.2.0.2)
 	// Regular branch commits have 4+ components without .0. (e.g., 1.2.2.1)
@@ -0,0 +1,311 @@
+package cvs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteTo writes the RCS file in the format produced by CVS. Fields the
+// parser does not interpret are written back from the newphrases. Deltas
+// missing from DeltaOrder, as in files built by hand, are written in the
+// order of RCS: each trunk revision, then the branches off it. Delta texts
+// dropped by a TextBudget are read back from the original file.
+func (r *RCSFile) WriteTo(w io.Writer) (int64, error) {
+	order, err := r.deltaOrder()
+	if err != nil {
+		return 0, err
+	}
+	if err := r.validateIDs(); err != nil {
+		return 0, err
+	}
+	texts, err := r.withTexts()
+	if err != nil {
+		return 0, err
+	}
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	fmt.Fprintf(bw, "head\t%s;\n", r.Head)
+	if r.Branch != "" {
+		fmt.Fprintf(bw, "branch\t%s;\n", r.Branch)
+	}
+	bw.WriteString("access")
+	for _, id := range r.Access {
+		fmt.Fprintf(bw, "\n\t%s", id)
+	}
+	bw.WriteString(";\nsymbols")
+	for _, sym := range sortedKeys(r.Symbols) {
+		fmt.Fprintf(bw, "\n\t%s:%s", sym, r.Symbols[sym])
+	}
+	bw.WriteString(";\nlocks")
+	for _, id := range sortedKeys(r.Locks) {
+		fmt.Fprintf(bw, "\n\t%s:%s", id, r.Locks[id])
+	}
+	bw.WriteString(";")
+	if r.StrictLocks {
+		bw.WriteString(" strict;")
+	}
+	bw.WriteString("\n")
+	if r.Comment != "" {
+		fmt.Fprintf(bw, "comment\t%s;\n", rcsString(r.Comment))
+	}
+	if r.Expand != "" {
+		fmt.Fprintf(bw, "expand\t%s;\n", rcsString(r.Expand))
+	}
+	writeNewphrases(bw, r.Newphrases)
+	bw.WriteString("\n")
+
+	for _, rev := range order {
+		d := r.Deltas[rev]
+		fmt.Fprintf(bw, "\n%s\ndate\t%s;\tauthor %s;\tstate %s;\nbranches", rev, formatRCSDate(d.Date), d.Author, d.State)
+		for _, b := range d.Branches {
+			fmt.Fprintf(bw, "\n\t%s", b)
+		}
+		fmt.Fprintf(bw, ";\nnext\t%s;\n", d.Next)
+		for _, field := range []struct{ name, value string }{
+			{"commitid", d.CommitID},
+			{"mergepoint1", d.MergePoint},
+			{"deltatype", d.DeltaType},
+			{"kopt", d.KeywordMode},
+			{"permissions", d.Permissions},
+		} {
+			if field.value != "" {
+				fmt.Fprintf(bw, "%s\t%s;\n", field.name, field.value)
+			}
+		}
+		writeNewphrases(bw, d.Newphrases)
+	}
+
+	fmt.Fprintf(bw, "\n\ndesc\n%s\n", rcsString(r.Description))
+
+	for _, rev := range order {
+		d := r.Deltas[rev]
+		text := d.Text
+		if full := texts.Deltas[rev]; texts != r && full != nil {
+			text = full.Text
+		}
+		fmt.Fprintf(bw, "\n\n%s\nlog\n%s\n", rev, rcsString(d.Log))
+		writeNewphrases(bw, d.TextNewphrases)
+		fmt.Fprintf(bw, "text\n%s\n", rcsString(text))
+	}
+
+	err = bw.Flush()
+	return cw.n, err
+}
+
+// deltaOrder returns DeltaOrder if it lists every delta once, or else the
+// deltas reachable from the head: each revision, then the rest of its
+// line of development, then its branches, as RCS writes them
+func (r *RCSFile) deltaOrder() ([]string, error) {
+	if len(r.DeltaOrder) == len(r.Deltas) {
+		complete := true
+		seen := make(map[string]bool, len(r.DeltaOrder))
+		for _, rev := range r.DeltaOrder {
+			if r.Deltas[rev] == nil || seen[rev] {
+				complete = false
+				break
+			}
+			seen[rev] = true
+		}
+		if complete {
+			return r.DeltaOrder, nil
+		}
+	}
+
+	order := make([]string, 0, len(r.Deltas))
+	seen := make(map[string]bool, len(r.Deltas))
+	var walk func(rev string) error
+	walk = func(rev string) error {
+		if rev == "" {
+			return nil
+		}
+		d := r.Deltas[rev]
+		if d == nil {
+			return fmt.Errorf("revision %s is referenced but has no delta", rev)
+		}
+		if seen[rev] {
+			return fmt.Errorf("revision %s is reached twice", rev)
+		}
+		seen[rev] = true
+		order = append(order, rev)
+		if err := walk(d.Next); err != nil {
+			return err
+		}
+		for _, branch := range d.Branches {
+			if err := walk(branch); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(r.Head); err != nil {
+		return nil, err
+	}
+	if len(order) != len(r.Deltas) {
+		return nil, fmt.Errorf("%d deltas are not reachable from head %q", len(r.Deltas)-len(order), r.Head)
+	}
+	return order, nil
+}
+
+// validateIDs checks the names written unquoted. RCS itself also forbids
+// $ , and . in them, but CVS writes such logins and tags, so only what
+// would break the file is rejected.
+func (r *RCSFile) validateIDs() error {
+	for _, id := range r.Access {
+		if !isRCSID(id) {
+			return fmt.Errorf("invalid login %q in the access list", id)
+		}
+	}
+	for sym, rev := range r.Symbols {
+		if !isRCSID(sym) || !isRCSNum(rev) {
+			return fmt.Errorf("invalid symbol %s:%s", sym, rev)
+		}
+	}
+	for id, rev := range r.Locks {
+		if !isRCSID(id) || !isRCSNum(rev) {
+			return fmt.Errorf("invalid lock %s:%s", id, rev)
+		}
+	}
+	for rev, d := range r.Deltas {
+		if !isRCSNum(rev) {
+			return fmt.Errorf("invalid revision number %q", rev)
+		}
+		if !isRCSID(d.Author) {
+			return fmt.Errorf("revision %s: invalid author %q", rev, d.Author)
+		}
+		if d.State != "" && !isRCSID(d.State) {
+			return fmt.Errorf("revision %s: invalid state %q", rev, d.State)
+		}
+	}
+	return nil
+}
+
+// isRCSID reports whether s can be written as an unquoted RCS word
+func isRCSID(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " \t\r\n\v\f:;@")
+}
+
+// isRCSNum reports whether s is a revision number such as 1.2.2.1
+func isRCSNum(s string) bool {
+	for _, part := range strings.Split(s, ".") {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeNewphrases writes fields the parser did not interpret, one per line
+func writeNewphrases(w *bufio.Writer, phrases []Newphrase) {
+	for _, phrase := range phrases {
+		w.WriteString(phrase.Keyword)
+		for i, word := range phrase.Words {
+			sep := " "
+			if i == 0 {
+				sep = "\t"
+			}
+			w.WriteString(sep)
+			if word.String {
+				w.WriteString(rcsString(word.Value))
+			} else {
+				w.WriteString(word.Value)
+			}
+		}
+		w.WriteString(";\n")
+	}
+}
+
+// rcsString quotes s as an RCS @-string
+func rcsString(s string) string {
+	return "@" + strings.ReplaceAll(s, "@", "@@") + "@"
+}
+
+// formatRCSDate formats t as an RCS date in UTC, e.g. 2024.01.02.15.04.05
+func formatRCSDate(t time.Time) string {
+	return t.UTC().Format("2006.01.02.15.04.05")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nextTrunkRevision returns the trunk revision following rev, e.g. 1.4 for
+// 1.3
+func nextTrunkRevision(rev string) (string, error) {
+	major, minor, ok := strings.Cut(rev, ".")
+	n, err := strconv.Atoi(minor)
+	if !ok || err != nil || strings.Contains(minor, ".") {
+		return "", fmt.Errorf("not a trunk revision: %s", rev)
+	}
+	return major + "." + strconv.Itoa(n+1), nil
+}
+
+// checkinTrunk adds a revision with the given content on top of the trunk,
+// the way "ci" does: the new head stores the full text and the previous
+// head is replaced by a reverse diff. A dead revision removes the file and
+// keeps the content of the previous revision.
+func (r *RCSFile) checkinTrunk(content []byte, delta Delta) error {
+	if r.Branch != "" {
+		return fmt.Errorf("default branch %s is set; only trunk check-ins are supported", r.Branch)
+	}
+
+	if r.Head == "" {
+		delta.Revision = "1.1"
+		delta.Text = string(content)
+		r.Head = delta.Revision
+		r.Deltas[delta.Revision] = &delta
+		r.DeltaOrder = append([]string{delta.Revision}, r.DeltaOrder...)
+		return nil
+	}
+
+	prev := r.Deltas[r.Head]
+	if prev == nil {
+		return fmt.Errorf("head revision %s not found", r.Head)
+	}
+	prevLines, err := r.trunkLines(r.Head)
+	if err != nil {
+		return err
+	}
+	rev, err := nextTrunkRevision(r.Head)
+	if err != nil {
+		return err
+	}
+
+	newLines := splitLines(content)
+	if delta.IsDead() {
+		newLines = prevLines
+		content = []byte(prev.Text)
+	}
+
+	delta.Revision = rev
+	delta.Next = r.Head
+	delta.Text = string(content)
+	prev.Text = makeRCSDiff(newLines, prevLines)
+
+	r.Head = rev
+	r.Deltas[rev] = &delta
+	r.DeltaOrder = append([]string{rev}, r.DeltaOrder...)
+	return nil
+}
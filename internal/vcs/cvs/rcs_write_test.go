@@ -0,0 +1,249 @@
+package cvs
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeRCSDiff_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomLines := func() [][]byte {
+		lines := make([][]byte, rng.IntN(12))
+		for i := range lines {
+			lines[i] = []byte(fmt.Sprintf("line %d\n", rng.IntN(5)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		source, target := randomLines(), randomLines()
+		got, err := applyRCSDiff(source, makeRCSDiff(source, target))
+		require.NoError(t, err)
+		require.Equal(t, bytes.Join(target, nil), bytes.Join(got, nil), "iteration %d", i)
+	}
+}
+
+func TestDiffLines_Shortest(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	randomLines := func() [][]byte {
+		lines := make([][]byte, rng.IntN(40))
+		for i := range lines {
+			lines[i] = []byte(fmt.Sprintf("line %d\n", rng.IntN(6)))
+		}
+		return lines
+	}
+	// Length of the longest common subsequence, by dynamic programming
+	common := func(a, b [][]byte) int {
+		prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+		for i := range a {
+			for j := range b {
+				if bytes.Equal(a[i], b[j]) {
+					cur[j+1] = prev[j] + 1
+				} else {
+					cur[j+1] = max(cur[j], prev[j+1])
+				}
+			}
+			prev, cur = cur, prev
+		}
+		return prev[len(b)]
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		edits := diffLines(a, b)
+		changes := 0
+		for _, op := range edits {
+			if op != editEqual {
+				changes++
+			}
+		}
+		require.Equal(t, len(a)+len(b)-2*common(a, b), changes, "iteration %d", i)
+	}
+}
+
+func TestMakeRCSDiff_LargeRoundTrip(t *testing.T) {
+	var source, target [][]byte
+	for i := 0; i < 3000; i++ {
+		source = append(source, []byte(fmt.Sprintf("source %d\n", i)))
+		target = append(target, []byte(fmt.Sprintf("target %d\n", i)))
+		if i%7 == 0 {
+			target = append(target, []byte(fmt.Sprintf("source %d\n", i)))
+		}
+	}
+	got, err := applyRCSDiff(source, makeRCSDiff(source, target))
+	require.NoError(t, err)
+	require.Equal(t, bytes.Join(target, nil), bytes.Join(got, nil))
+}
+
+func TestMakeRCSDiff_Format(t *testing.T) {
+	source := splitLines([]byte("a\nb\nc\n"))
+	target := splitLines([]byte("a\nB\nc\nd\n"))
+	require.Equal(t, "d2 1\na2 1\nB\na3 1\nd\n", makeRCSDiff(source, target))
+	require.Empty(t, makeRCSDiff(source, source))
+}
+
+func TestRCSFileWriteTo_RoundTrip(t *testing.T) {
+	rcs, err := NewRCSParser(strings.NewReader(contentRCS)).Parse()
+	require.NoError(t, err)
+	rcs.Deltas["1.3"].CommitID = "1a2b3c"
+	rcs.Deltas["1.3"].Log = "quoted @ sign\n"
+
+	var buf bytes.Buffer
+	n, err := rcs.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+
+	parsed, err := NewRCSParser(&buf).Parse()
+	require.NoError(t, err)
+	require.Equal(t, rcs.Head, parsed.Head)
+	require.Equal(t, rcs.Symbols, parsed.Symbols)
+	require.Equal(t, rcs.DeltaOrder, parsed.DeltaOrder)
+	for rev, want := range rcs.Deltas {
+		require.Equal(t, want, parsed.Deltas[rev], rev)
+	}
+}
+
+// newphraseRCS uses newphrases in all three sections and delta texts with
+// CR, CRLF and LF line endings
+const newphraseRCS = "head\t1.2;\naccess;\nsymbols;\nlocks; strict;\nintegrity\t@@;\nowner\talice wheel:0644;\n\n" +
+	"1.2\ndate\t2024.01.02.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t1.1;\n" +
+	"filename\t@a.txt@;\nvendor.ext\t1.1.1.1 @x@@y@;\n\n" +
+	"1.1\ndate\t2024.01.01.00.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\n\n" +
+	"desc\n@@\n\n" +
+	"1.2\nlog\n@second@\nchecksum\t@abc@;\ntext\n@mac\rline\rdos\r\nline\r\nunix\n@\n\n" +
+	"1.1\nlog\n@first@\ntext\n@d1 3\na3 1\nold\r\n@\n"
+
+func TestRCSFileWriteTo_Newphrases(t *testing.T) {
+	p := NewRCSParser(strings.NewReader(newphraseRCS))
+	p.SetStrict(true)
+	rcs, err := p.Parse()
+	require.NoError(t, err)
+
+	require.Equal(t, []Newphrase{
+		{Keyword: "integrity", Words: []Word{{Value: "", String: true}}},
+		{Keyword: "owner", Words: []Word{{Value: "alice"}, {Value: "wheel"}, {Value: ":"}, {Value: "0644"}}},
+	}, rcs.Newphrases)
+	require.Equal(t, []Newphrase{
+		{Keyword: "filename", Words: []Word{{Value: "a.txt", String: true}}},
+		{Keyword: "vendor.ext", Words: []Word{{Value: "1.1.1.1"}, {Value: "x@y", String: true}}},
+	}, rcs.Deltas["1.2"].Newphrases)
+	require.Equal(t, []Newphrase{{Keyword: "checksum", Words: []Word{{Value: "abc", String: true}}}},
+		rcs.Deltas["1.2"].TextNewphrases)
+
+	content, err := rcs.RevisionContent("1.2")
+	require.NoError(t, err)
+	require.Equal(t, "mac\rline\rdos\r\nline\r\nunix\n", string(content))
+	content, err = rcs.RevisionContent("1.1")
+	require.NoError(t, err)
+	require.Equal(t, "old\r\n", string(content))
+
+	var first bytes.Buffer
+	_, err = rcs.WriteTo(&first)
+	require.NoError(t, err)
+	written := first.String()
+	require.Contains(t, written, "integrity\t@@;\nowner\talice wheel : 0644;\n")
+	require.Contains(t, written, "vendor.ext\t1.1.1.1 @x@@y@;\n")
+	require.Contains(t, written, "log\n@second@\nchecksum\t@abc@;\ntext\n")
+
+	parsed, err := NewRCSParser(&first).Parse()
+	require.NoError(t, err)
+	require.Equal(t, rcs.Newphrases, parsed.Newphrases)
+	for rev, want := range rcs.Deltas {
+		require.Equal(t, want, parsed.Deltas[rev], rev)
+	}
+	var second bytes.Buffer
+	_, err = parsed.WriteTo(&second)
+	require.NoError(t, err)
+	require.Equal(t, written, second.String())
+}
+
+func TestRCSFileCheckinTrunk(t *testing.T) {
+	rcs := &RCSFile{Deltas: make(map[string]*Delta), Symbols: make(map[string]string)}
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	revisions := []string{"one\n", "one\ntwo\n", "zero\none\n"}
+	for i, content := range revisions {
+		require.NoError(t, rcs.checkinTrunk([]byte(content), Delta{Date: date.Add(time.Duration(i) * time.Hour), Author: "alice", State: "Exp", Log: "msg\n"}))
+	}
+	require.NoError(t, rcs.checkinTrunk(nil, Delta{Date: date.Add(3 * time.Hour), Author: "alice", State: "dead", Log: "removed\n"}))
+
+	require.Equal(t, "1.4", rcs.Head)
+	require.Equal(t, []string{"1.4", "1.3", "1.2", "1.1"}, rcs.DeltaOrder)
+	require.True(t, rcs.Deltas["1.4"].IsDead())
+	for i, content := range revisions {
+		got, err := rcs.RevisionContent(fmt.Sprintf("1.%d", i+1))
+		require.NoError(t, err)
+		require.Equal(t, content, string(got))
+	}
+
+	rcs.Branch = "1.1.1"
+	require.Error(t, rcs.checkinTrunk([]byte("x\n"), Delta{State: "Exp"}))
+}
+
+func TestRCSFileWriteTo_BuiltByHand(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rcs := &RCSFile{
+		Head:    "1.2",
+		Symbols: map[string]string{"REL_1": "1.1", "FIX": "1.1.0.2"},
+		Deltas: map[string]*Delta{
+			"1.2":     {Revision: "1.2", Date: date.Add(time.Hour), Author: "alice", State: "Exp", Next: "1.1", Log: "second\n", Text: "one\ntwo\n"},
+			"1.1":     {Revision: "1.1", Date: date, Author: "first.last", State: "Exp", Branches: []string{"1.1.2.1"}, Log: "first\n", Text: "d2 1\n"},
+			"1.1.2.1": {Revision: "1.1.2.1", Date: date.Add(2 * time.Hour), Author: "bob", State: "Exp", Log: "fix\n", Text: "a1 1\nfixed\n"},
+		},
+	}
+
+	var buf bytes.Buffer
+	_, err := rcs.WriteTo(&buf)
+	require.NoError(t, err)
+	parsed, err := NewRCSParser(&buf).Parse()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.2", "1.1", "1.1.2.1"}, parsed.DeltaOrder)
+	require.Equal(t, rcs.Symbols, parsed.Symbols)
+	for rev, want := range map[string]string{"1.2": "one\ntwo\n", "1.1": "one\n", "1.1.2.1": "one\nfixed\n"} {
+		content, err := parsed.RevisionContent(rev)
+		require.NoError(t, err)
+		require.Equal(t, want, string(content), rev)
+	}
+
+	for _, broken := range []func(r *RCSFile){
+		func(r *RCSFile) { r.Deltas["1.1"].Branches = []string{"1.1.4.1"} },
+		func(r *RCSFile) { r.Head = "1.1" },
+		func(r *RCSFile) { r.Deltas["1.2"].Author = "Alice Smith" },
+		func(r *RCSFile) { r.Symbols["BAD:TAG"] = "1.1" },
+	} {
+		copied := *rcs
+		copied.Symbols = map[string]string{}
+		copied.Deltas = make(map[string]*Delta)
+		for rev, d := range rcs.Deltas {
+			d := *d
+			copied.Deltas[rev] = &d
+		}
+		broken(&copied)
+		_, err := copied.WriteTo(&bytes.Buffer{})
+		require.Error(t, err)
+	}
+}
+
+func TestRCSFileWriteTo_DroppedTexts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt,v")
+	require.NoError(t, os.WriteFile(path, []byte(newphraseRCS), 0o644))
+	rcs, err := NewRCSParser(strings.NewReader(newphraseRCS)).Parse()
+	require.NoError(t, err)
+	var want bytes.Buffer
+	_, err = rcs.WriteTo(&want)
+	require.NoError(t, err)
+
+	NewTextBudget(1).fit(rcs, path)
+	require.Empty(t, rcs.Deltas["1.2"].Text)
+	var got bytes.Buffer
+	_, err = rcs.WriteTo(&got)
+	require.NoError(t, err)
+	require.Equal(t, want.String(), got.String())
+}
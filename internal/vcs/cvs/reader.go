@@ -2,12 +2,20 @@
 package cvs
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/adamf123git/git-migrator/internal/profile"
+	"github.com/adamf123git/git-migrator/internal/throttle"
 	"github.com/adamf123git/git-migrator/internal/vcs"
 )
 
@@ -27,8 +35,25 @@ type ValidationResult struct {
 
 // Reader implements VCSReader for CVS repositories
 type Reader struct {
-	path     string
-	rcsFiles []*RCSFile
+	path         string
+	module       string // Subdirectory to read; empty reads the whole repository
+	remote       *remoteClient
+	rcsFiles     []*RCSFile
+	cache        *ContentCache // Reconstructed file revisions (nil = no caching)
+	budget       *TextBudget   // Limits the delta texts kept in memory (nil = unlimited)
+	profile      *profile.Recorder
+	strict       bool                 // Fail on malformed RCS files instead of recording diagnostics
+	parseWorkers int                  // RCS files parsed at a time (0 or 1 = one)
+	readLimit    *throttle.Limiter    // Paces the reads of RCS files (nil = unlimited)
+	dirs         []ModuleDir          // Directories of the module, resolved through CVSROOT/modules
+	wrappers     Wrappers             // CVSROOT/cvswrappers entries
+	known        map[string]FileStamp // RCS files skipped as unchanged (nil = read all)
+	stamps       map[string]FileStamp // RCS files of the module found by the last read
+	synced       map[string][]string  // File revisions GetCommitsSince leaves out, by path
+	ctx          context.Context      // Stops reading the history early (nil = never)
+
+	diagnostics []Diagnostic
+	skipped     []SkippedFile
 	// info caches repository metadata for performance optimization.
 	// Reserved for future use to avoid repeated filesystem calls when
 	// accessing repository information such as branch counts, file counts,
@@ -38,23 +63,158 @@ type Reader struct {
 	info *vcs.RepositoryInfo
 }
 
+// SkippedFile is an RCS file left out of the history because it could not
+// be read or parsed
+type SkippedFile struct {
+	Path string // File system path of the ,v file
+	Err  error
+}
+
 // NewReader creates a new CVS repository reader
 func NewReader(path string) *Reader {
-	return &Reader{path: path}
+	return NewModuleReader(path, "")
+}
+
+// NewModuleReader creates a reader for a single module (top-level
+// directory) of the CVS repository at path. File paths are reported
+// relative to the module.
+//
+// path may also be a remote CVSROOT such as
+// ":pserver:anonymous@cvs.example.org:/cvsroot" or ":ext:user@host:/cvsroot";
+// the history is then read through the cvs client, which must be installed
+// and logged in (pserver) or able to connect over ssh (ext). Remote
+// repositories require a module.
+func NewModuleReader(path, module string) *Reader {
+	r := &Reader{path: path, module: module}
+	if root, err := parseRemoteRoot(path); err == nil {
+		r.remote = newRemoteClient(root, module)
+	}
+	return r
+}
+
+// SetContentCache caches the file revisions the reader reconstructs in
+// cache, which may be shared with other readers. It must be called before
+// the commits are read.
+func (r *Reader) SetContentCache(cache *ContentCache) {
+	r.cache = cache
+}
+
+// SetTextBudget limits the delta texts the reader keeps in memory to budget,
+// which may be shared with other readers. It must be called before the
+// commits are read.
+func (r *Reader) SetTextBudget(budget *TextBudget) {
+	r.budget = budget
+}
+
+// SetProfile records the time spent parsing every RCS file in rec
+func (r *Reader) SetProfile(rec *profile.Recorder) {
+	r.profile = rec
+}
+
+// SetStrict makes reading fail with a *ParseError on the first malformed
+// RCS file. By default malformed files are read as far as possible and the
+// anomalies are returned by Diagnostics.
+func (r *Reader) SetStrict(strict bool) {
+	r.strict = strict
+}
+
+// SetParseWorkers parses up to n RCS files at a time. It must be called
+// before the commits are read.
+func (r *Reader) SetParseWorkers(n int) {
+	r.parseWorkers = n
+}
+
+// SetReadLimit paces the reads of RCS files, and the directory listings and
+// file opens, by limiter, which may be shared with other readers. It must
+// be called before the commits are read.
+func (r *Reader) SetReadLimit(limiter *throttle.Limiter) {
+	r.readLimit = limiter
+}
+
+// SetContext stops the following history reads early once ctx is done,
+// failing them with the error of ctx
+func (r *Reader) SetContext(ctx context.Context) {
+	r.ctx = ctx
+	if r.remote != nil {
+		r.remote.ctx = ctx
+	}
+}
+
+// SetClient sets how the cvs client reading a remote repository is run. It
+// has no effect on local repositories.
+func (r *Reader) SetClient(client Client) {
+	if r.remote != nil {
+		r.remote.client = client
+	}
+}
+
+// Diagnostics returns the anomalies found in the RCS files read so far,
+// ordered by file
+func (r *Reader) Diagnostics() []Diagnostic {
+	return r.diagnostics
+}
+
+// SkippedFiles returns the RCS files left out of the history because they
+// could not be read or parsed. Unless SetStrict is set, such files do not
+// fail the read.
+func (r *Reader) SkippedFiles() []SkippedFile {
+	return r.skipped
 }
 
 // Validate checks if the repository is valid and accessible
 func (r *Reader) Validate() error {
+	if r.remote != nil {
+		return r.remote.validate()
+	}
 	result := NewValidator().Validate(r.path)
 	if !result.Valid {
 		if len(result.Errors) > 0 {
-			return fmt.Errorf("validation failed: %s", result.Errors[0].Message)
+			return fmt.Errorf("validation failed: %w: %s", vcs.ErrNotARepo, result.Errors[0].Message)
+		}
+		return fmt.Errorf("validation failed: %w", vcs.ErrNotARepo)
+	}
+	if r.module != "" {
+		dirs, err := r.moduleDirs()
+		if err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+		for _, md := range dirs {
+			if info, err := os.Stat(filepath.Join(r.path, md.Dir)); err != nil || !info.IsDir() {
+				return fmt.Errorf("validation failed: %w: module %s not found", vcs.ErrNotARepo, r.module)
+			}
 		}
-		return fmt.Errorf("validation failed")
 	}
 	return nil
 }
 
+// moduleDirs returns the repository directories whose RCS files are read.
+// The module may be defined in CVSROOT/modules; otherwise it names a
+// directory.
+func (r *Reader) moduleDirs() ([]ModuleDir, error) {
+	if r.dirs != nil {
+		return r.dirs, nil
+	}
+	if r.module == "" {
+		r.dirs = []ModuleDir{{}}
+		return r.dirs, nil
+	}
+	dirs, err := ResolveModule(r.path, r.module)
+	if err != nil {
+		return nil, err
+	}
+	r.dirs = dirs
+	return dirs, nil
+}
+
+// expandMode returns the keyword substitution mode of rcs: its own, or the
+// one CVSROOT/cvswrappers sets for its name
+func (r *Reader) expandMode(rcs *RCSFile) string {
+	if rcs.Expand != "" {
+		return rcs.Expand
+	}
+	return r.wrappers.Expand(rcs.Path)
+}
+
 // GetCommits returns an iterator over all commits
 func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
 	if err := r.loadRCSFiles(); err != nil {
@@ -63,22 +223,48 @@ func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
 
 	// Collect all commits from all RCS files
 	var allCommits []*vcs.Commit
-	seen := make(map[string]bool) // Track commits by revision+author+date
+	seen := make(map[string]*vcs.Commit) // Track commits by commitid or revision+author+date
 
 	for _, rcs := range r.rcsFiles {
 		commits := rcs.GetCommits()
 		for _, c := range commits {
-			// Create a unique key for deduplication
+			fc, ok := fileChange(rcs, c.Revision)
+			if fc.Action != vcs.ActionDelete {
+				fc.Keywords = r.expandMode(rcs)
+				if d := rcs.Deltas[c.Revision]; d != nil && d.KeywordMode != "" {
+					fc.Keywords = d.KeywordMode // CVSNT per-revision mode
+				}
+				fc.Binary = fc.Keywords == "b"
+			}
+			if !ok && rcs.Path != "" {
+				// e.g. the dead trunk revision of a file that was first
+				// added on a branch; it changes nothing on its own
+				continue
+			}
+
+			// Create a unique key for deduplication. A commitid (CVSNT and
+			// CVS 1.12) identifies the changeset across files directly.
 			key := fmt.Sprintf("%s|%s|%d", c.Revision, c.Author, c.Date.Unix())
-			if !seen[key] {
-				seen[key] = true
-				allCommits = append(allCommits, &vcs.Commit{
+			if c.CommitID != "" {
+				key = "commitid|" + c.CommitID
+			}
+			commit, ok := seen[key]
+			if !ok {
+				commit = &vcs.Commit{
 					Revision: c.Revision,
 					Author:   c.Author,
 					Date:     c.Date,
 					Message:  c.Message,
 					Branch:   c.Branch,
-				})
+				}
+				seen[key] = commit
+				allCommits = append(allCommits, commit)
+			}
+			if rcs.Path != "" {
+				if r.remote != nil && fc.Source != nil {
+					fc.Source = r.remote.source(rcs.Path, c.Revision)
+				}
+				commit.Files = append(commit.Files, fc)
 			}
 		}
 	}
@@ -124,6 +310,61 @@ func (r *Reader) GetTags() (map[string]string, error) {
 	return allTags, nil
 }
 
+// GetTagDetails returns each tag with its newest tagged file revision, which
+// is the closest CVS has to a tagging date
+func (r *Reader) GetTagDetails() (map[string]vcs.TagInfo, error) {
+	return r.tagDetails("")
+}
+
+// GetSubtreeTagDetails returns the tags covering files below dir but not
+// below the excluded directories, each with its newest tagged file revision
+// among them
+func (r *Reader) GetSubtreeTagDetails(dir string, exclude ...string) (map[string]vcs.TagInfo, error) {
+	return r.tagDetails(strings.Trim(dir, "/"), exclude...)
+}
+
+// tagDetails describes the tags of the files below dir ("" = all files),
+// leaving out those below the excluded directories
+func (r *Reader) tagDetails(dir string, exclude ...string) (map[string]vcs.TagInfo, error) {
+	if err := r.loadRCSFiles(); err != nil {
+		return nil, err
+	}
+
+	details := make(map[string]vcs.TagInfo)
+	for _, rcs := range r.rcsFiles {
+		if dir != "" && !strings.HasPrefix(rcs.Path, dir+"/") || belowAny(rcs.Path, exclude) {
+			continue
+		}
+		for name, rev := range rcs.GetTags() {
+			delta := rcs.Deltas[rev]
+			if delta == nil {
+				continue
+			}
+			if info, ok := details[name]; ok && !delta.Date.After(info.Date) {
+				continue
+			}
+			details[name] = vcs.TagInfo{
+				Name:     name,
+				Path:     rcs.Path,
+				Revision: rev,
+				Author:   delta.Author,
+				Date:     delta.Date,
+			}
+		}
+	}
+	return details, nil
+}
+
+// belowAny reports whether path lies below one of the directories
+func belowAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir = strings.Trim(dir, "/"); dir != "" && strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // Close releases any resources
 func (r *Reader) Close() error {
 	return nil
@@ -135,44 +376,275 @@ func (r *Reader) loadRCSFiles() error {
 		return nil // Already loaded
 	}
 
-	// Find all ,v files (RCS files)
-	err := filepath.Walk(r.path, func(path string, info os.FileInfo, err error) error {
+	if r.remote != nil {
+		files, err := r.remote.load()
+		if err != nil {
+			return err
+		}
+		r.rcsFiles = files
+		return nil
+	}
+
+	dirs, err := r.moduleDirs()
+	if err != nil {
+		return err
+	}
+	if r.wrappers, err = LoadWrappers(r.path); err != nil {
+		return err
+	}
+
+	// Files deleted on trunk live in Attic/ subdirectories; index by working
+	// path so a stray Attic copy never shadows the live file
+	byPath := make(map[string]int)
+	r.diagnostics = nil
+	r.skipped = nil
+	r.stamps = make(map[string]FileStamp)
+
+	for _, md := range dirs {
+		if err = r.walkModuleDir(md, byPath); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		r.rcsFiles = nil
+	}
+	return err
+}
+
+// rcsCandidate is an RCS file of the module found by walkModuleDir
+type rcsCandidate struct {
+	path       string // File system path of the ,v file
+	modulePath string // Working path within the module
+	inAttic    bool
+	rel        string    // Path relative to the repository root
+	stamp      FileStamp // Recorded once the file is parsed
+}
+
+// parsedRCS is the outcome of parsing an rcsCandidate
+type parsedRCS struct {
+	rcs         *RCSFile // nil if the file could not be read
+	diagnostics []Diagnostic
+	err         error
+}
+
+// walkModuleDir loads the RCS files of a directory of the module. Up to
+// parseWorkers files are parsed at a time; their delta texts are fitted
+// into the budget before the next batch is parsed.
+func (r *Reader) walkModuleDir(md ModuleDir, byPath map[string]int) error {
+	root := filepath.Join(r.path, md.Dir)
+	var files []rcsCandidate
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err := vcs.ContextErr(r.ctx); err != nil {
+			return err
+		}
 		if err != nil {
 			return nil // Skip errors
 		}
+		rel, relErr := filepath.Rel(r.path, path)
+		if relErr != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
 		if info.IsDir() {
 			// Skip CVSROOT directory
 			if filepath.Base(path) == "CVSROOT" {
 				return filepath.SkipDir
 			}
+			if path != root {
+				// A module listing files reads them from its directory only
+				if _, ok := md.contains(rel, false); !ok || len(md.Files) > 0 && info.Name() != "Attic" {
+					return filepath.SkipDir
+				}
+			}
+			r.readLimit.Op() // Listing the directory
 			return nil
 		}
 
-		// Check if it's an RCS file (ends with ,v)
+		// Check if it's an RCS file (ends with ,v) of the module
 		if strings.HasSuffix(path, ",v") {
-			file, err := os.Open(path)
-			if err != nil {
-				return nil // Skip files we can't read
+			working, inAttic := workingPath(rel)
+			if modulePath, ok := md.contains(working, true); ok {
+				stamp := FileStamp{Size: info.Size(), ModTime: info.ModTime().UTC()}
+				if known, ok := r.known[rel]; ok && known.Size == stamp.Size && known.ModTime.Equal(stamp.ModTime) {
+					r.stamps[rel] = stamp
+					return nil
+				}
+				files = append(files, rcsCandidate{path: path, modulePath: modulePath, inAttic: inAttic, rel: rel, stamp: stamp})
 			}
-			defer func() {
-				if err := file.Close(); err != nil {
-					log.Printf("Warning: failed to close RCS file %s: %v", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := max(r.parseWorkers, 1)
+	for len(files) > 0 {
+		if err := vcs.ContextErr(r.ctx); err != nil {
+			return err
+		}
+		n := min(batch, len(files))
+		results := r.parseFiles(files[:n])
+		// Files cut short by the context are not unreadable
+		if err := vcs.ContextErr(r.ctx); err != nil {
+			return err
+		}
+		for i, parsed := range results {
+			if parsed.err != nil {
+				if r.strict {
+					return parsed.err
 				}
-			}()
+				log.Printf("Warning: skipping %s: %v", files[i].path, parsed.err)
+				r.skipped = append(r.skipped, SkippedFile{Path: files[i].path, Err: parsed.err})
+				continue
+			}
+			// Only files read in full are skipped as unchanged later on
+			r.stamps[files[i].rel] = files[i].stamp
+			r.diagnostics = append(r.diagnostics, parsed.diagnostics...)
+			if parsed.rcs != nil {
+				r.addRCSFile(parsed.rcs, files[i].path, byPath)
+			}
+		}
+		files = files[n:]
+	}
+	return nil
+}
+
+// parseFiles parses the files, in parallel when there is more than one
+func (r *Reader) parseFiles(files []rcsCandidate) []parsedRCS {
+	results := make([]parsedRCS, len(files))
+	if len(files) == 1 {
+		results[0] = r.parseFile(files[0])
+		return results
+	}
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.parseFile(files[i])
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// parseFile parses one RCS file of the module. A file that cannot be read,
+// crashes the parser or yields no revision at all fails with an error.
+func (r *Reader) parseFile(c rcsCandidate) (parsed parsedRCS) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			parsed = parsedRCS{err: fmt.Errorf("failed to parse %s: %v", c.path, rec)}
+		}
+	}()
 
-			parser := NewRCSParser(file)
-			rcs, err := parser.Parse()
-			if err != nil {
-				return nil // Skip files we can't parse
+	r.readLimit.Op()
+	file, err := os.Open(c.path)
+	if err != nil {
+		return parsedRCS{err: err}
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Warning: failed to close RCS file %s: %v", c.path, err)
+		}
+	}()
+
+	var in io.Reader = file
+	if r.ctx != nil {
+		in = bufio.NewReaderSize(vcs.ContextReader(r.ctx, file), 64<<10)
+	}
+	parser := NewRCSParser(r.readLimit.Reader(in))
+	parser.SetFile(c.path)
+	parser.SetStrict(r.strict)
+	stop := r.profile.Start(profile.KindParse, c.path)
+	rcs, err := parser.Parse()
+	stop()
+	if err != nil {
+		return parsedRCS{diagnostics: parser.Diagnostics(), err: err}
+	}
+	if len(rcs.Deltas) == 0 {
+		for _, d := range parser.Diagnostics() {
+			if d.Severity == SeverityError {
+				return parsedRCS{diagnostics: parser.Diagnostics(), err: &ParseError{Diagnostic: d}}
 			}
+		}
+	}
 
-			r.rcsFiles = append(r.rcsFiles, rcs)
+	rcs.Path, rcs.InAttic = c.modulePath, c.inAttic
+	rcs.cache, rcs.cacheID = r.cache, c.path
+	if abs, err := filepath.Abs(c.path); err == nil {
+		rcs.cacheID = abs
+	}
+	return parsedRCS{rcs: rcs, diagnostics: parser.Diagnostics()}
+}
+
+// addRCSFile adds a parsed file to the files of the reader. A live file
+// takes precedence over an Attic copy of the same path.
+func (r *Reader) addRCSFile(rcs *RCSFile, path string, byPath map[string]int) {
+	if idx, ok := byPath[rcs.Path]; ok && rcs.Path != "" {
+		if rcs.InAttic {
+			log.Printf("Warning: ignoring %s, a live copy of %s exists", path, rcs.Path)
+			return
+		}
+		if r.rcsFiles[idx].InAttic {
+			log.Printf("Warning: ignoring Attic copy of %s, a live copy exists", rcs.Path)
+			r.budget.fit(rcs, rcs.cacheID)
+			r.rcsFiles[idx] = rcs
+			return
 		}
+	}
 
-		return nil
-	})
+	r.budget.fit(rcs, rcs.cacheID)
+	byPath[rcs.Path] = len(r.rcsFiles)
+	r.rcsFiles = append(r.rcsFiles, rcs)
+}
 
-	return err
+// workingPath converts the path of a ,v file relative to the repository root
+// into the working file path, removing the Attic directory CVS moves deleted
+// files into. The boolean result reports whether the file was in an Attic.
+func workingPath(rel string) (string, bool) {
+	rel = filepath.ToSlash(strings.TrimSuffix(rel, ",v"))
+	dir, name := path.Split(rel)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "Attic" {
+		return name, true
+	}
+	if strings.HasSuffix(dir, "/Attic") {
+		return strings.TrimSuffix(dir, "Attic") + name, true
+	}
+	return rel, false
+}
+
+// fileChange describes how a revision changed its file. Content is loaded
+// lazily from the parsed RCS deltas when the change is applied.
+//
+// A dead revision deletes the file and a live revision following a dead one
+// resurrects it. The boolean result is false when the revision changes
+// nothing, i.e. a dead revision of a file that did not exist before.
+func fileChange(rcs *RCSFile, rev string) (vcs.FileChange, bool) {
+	fc := vcs.FileChange{Path: rcs.Path, Action: vcs.ActionModify, Revision: rev}
+
+	prev := rcs.PreviousRevision(rev)
+	prevDelta := rcs.Deltas[prev]
+	existed := prev != "" && prevDelta != nil && !prevDelta.IsDead()
+
+	if delta := rcs.Deltas[rev]; delta != nil && delta.IsDead() {
+		fc.Action = vcs.ActionDelete
+		return fc, existed
+	}
+
+	if !existed {
+		fc.Action = vcs.ActionAdd
+	}
+
+	fc.Source = func() (io.ReadCloser, error) {
+		data, err := rcs.RevisionContent(rev)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s revision %s: %w", rcs.Path, rev, err)
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return fc, true
 }
 
 // cvsCommitIterator implements CommitIterator for CVS
@@ -1,11 +1,16 @@
 package cvs
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/throttle"
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	"github.com/stretchr/testify/require"
 )
@@ -212,3 +217,318 @@ func TestValidate_NotDirectory(t *testing.T) {
 	require.False(t, res.Valid)
 	require.Greater(t, len(res.Errors), 0)
 }
+
+func TestGetCommits_PopulatesFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.c,v"), []byte(contentRCS), 0644))
+
+	r := NewReader(dir)
+	iter, err := r.GetCommits()
+	require.NoError(t, err)
+
+	byRev := make(map[string]*vcs.Commit)
+	for iter.Next() {
+		c := iter.Commit()
+		byRev[c.Revision] = c
+	}
+	require.NoError(t, iter.Err())
+
+	first := byRev["1.1"]
+	require.NotNil(t, first)
+	require.Len(t, first.Files, 1)
+	require.Equal(t, "src/main.c", first.Files[0].Path)
+	require.Equal(t, vcs.ActionAdd, first.Files[0].Action)
+	require.Nil(t, first.Files[0].Content, "content must be loaded lazily")
+
+	content, err := first.Files[0].ReadContent()
+	require.NoError(t, err)
+	require.Equal(t, "a\nB\n", string(content))
+
+	third := byRev["1.3"]
+	require.NotNil(t, third)
+	require.Len(t, third.Files, 1)
+	require.Equal(t, vcs.ActionModify, third.Files[0].Action)
+
+	content, err = third.Files[0].ReadContent()
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\nc\n", string(content))
+}
+
+// rcsWithStates builds a single-trunk RCS file whose revisions 1.1..1.n have
+// the given states, each revision holding its number as content
+func rcsWithStates(states ...string) string {
+	var b strings.Builder
+	n := len(states)
+	fmt.Fprintf(&b, "head\t1.%d;\naccess;\nsymbols;\nlocks; strict;\n\n", n)
+	for i := n; i >= 1; i-- {
+		next := ""
+		if i > 1 {
+			next = fmt.Sprintf("1.%d", i-1)
+		}
+		fmt.Fprintf(&b, "1.%d\ndate\t2024.01.%02d.00.00.00;\tauthor alice;\tstate %s;\nbranches;\nnext\t%s;\n\n", i, i, states[i-1], next)
+	}
+	b.WriteString("desc\n@@\n\n")
+	for i := n; i >= 1; i-- {
+		text := fmt.Sprintf("d1 1\na1 1\nrev %d\n", i)
+		if i == n {
+			text = fmt.Sprintf("rev %d\n", i)
+		}
+		fmt.Fprintf(&b, "1.%d\nlog\n@r%d\n@\ntext\n@%s@\n\n", i, i, text)
+	}
+	return b.String()
+}
+
+func fileActions(t *testing.T, dir, path string) []vcs.Action {
+	t.Helper()
+	iter, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+
+	var actions []vcs.Action
+	for iter.Next() {
+		for _, fc := range iter.Commit().Files {
+			if fc.Path == path {
+				actions = append(actions, fc.Action)
+			}
+		}
+	}
+	return actions
+}
+
+func TestGetCommits_ContextDone(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.c,v"), []byte(contentRCS), 0644))
+
+	r := NewReader(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.SetContext(ctx)
+	_, err := r.GetCommits()
+	require.ErrorIs(t, err, context.Canceled)
+
+	r.SetContext(nil)
+	iter, err := r.GetCommits()
+	require.NoError(t, err)
+	require.True(t, iter.Next())
+}
+
+func TestGetCommits_AtticFileIsDeleted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	attic := filepath.Join(dir, "src", "Attic")
+	require.NoError(t, os.MkdirAll(attic, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(attic, "gone.c,v"), []byte(rcsWithStates("Exp", "dead")), 0644))
+
+	require.Equal(t, []vcs.Action{vcs.ActionAdd, vcs.ActionDelete}, fileActions(t, dir, "src/gone.c"))
+}
+
+func TestGetCommits_ResurrectedFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "back.c,v"), []byte(rcsWithStates("Exp", "dead", "Exp", "Exp")), 0644))
+
+	require.Equal(t,
+		[]vcs.Action{vcs.ActionAdd, vcs.ActionDelete, vcs.ActionAdd, vcs.ActionModify},
+		fileActions(t, dir, "back.c"))
+}
+
+func TestGetCommits_InitialDeadRevisionIsSkipped(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Attic"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Attic", "branchonly.c,v"), []byte(rcsWithStates("dead")), 0644))
+
+	iter, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+	require.False(t, iter.Next(), "a file that never existed on trunk must not produce commits")
+}
+
+func TestGetCommits_LiveFileShadowsAtticCopy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Attic"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Attic", "dup.c,v"), []byte(rcsWithStates("Exp", "dead")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dup.c,v"), []byte(rcsWithStates("Exp", "Exp")), 0644))
+
+	require.Equal(t, []vcs.Action{vcs.ActionAdd, vcs.ActionModify}, fileActions(t, dir, "dup.c"))
+}
+
+func TestGetCommits_ParallelParse(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Attic"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Attic", "dup.c,v"), []byte(rcsWithStates("Exp", "dead")), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dup.c,v"), []byte(rcsWithStates("Exp", "Exp")), 0644))
+	for i := range 7 {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.c,v", i)), []byte(rcsWithStates("Exp", "Exp", "dead")), 0644))
+	}
+
+	commits := func(r *Reader) []string {
+		iter, err := r.GetCommits()
+		require.NoError(t, err)
+		var revs []string
+		for iter.Next() {
+			for _, fc := range iter.Commit().Files {
+				revs = append(revs, fmt.Sprintf("%s %s %v", fc.Path, fc.Revision, fc.Action))
+			}
+		}
+		require.NoError(t, iter.Err())
+		return revs
+	}
+
+	parallel := NewReader(dir)
+	parallel.SetParseWorkers(3)
+	parallel.SetReadLimit(throttle.New(1 << 30))
+	require.Equal(t, commits(NewReader(dir)), commits(parallel))
+}
+
+func TestWorkingPath(t *testing.T) {
+	cases := []struct {
+		rel   string
+		want  string
+		attic bool
+	}{
+		{"main.c,v", "main.c", false},
+		{"src/main.c,v", "src/main.c", false},
+		{"Attic/main.c,v", "main.c", true},
+		{"src/Attic/main.c,v", "src/main.c", true},
+		{"src/Attic/sub/main.c,v", "src/Attic/sub/main.c", false},
+	}
+	for _, tc := range cases {
+		got, attic := workingPath(tc.rel)
+		require.Equal(t, tc.want, got, tc.rel)
+		require.Equal(t, tc.attic, attic, tc.rel)
+	}
+}
+
+func TestGetCommits_GroupsByCommitID(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+
+	// a.c 1.1 and b.c 1.2 form one changeset; b.c 1.1 has the same revision,
+	// author and date as a.c 1.1 but belongs to an earlier changeset
+	a := strings.Replace(rcsWithStates("Exp"), "next\t;\n", "next\t;\ncommitid\t20045a5e6b1c2d3;\n", 1)
+	b := rcsWithStates("Exp", "Exp")
+	b = strings.Replace(b, "next\t1.1;\n", "next\t1.1;\ncommitid\t20045a5e6b1c2d3;\n", 1)
+	b = strings.Replace(b, "next\t;\n", "next\t;\ncommitid\t10045a5e6b1c2d3;\n", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.c,v"), []byte(a), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.c,v"), []byte(b), 0644))
+
+	iter, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+
+	changesets := make(map[string][]string)
+	for iter.Next() {
+		c := iter.Commit()
+		var files []string
+		for _, fc := range c.Files {
+			files = append(files, fc.Path+":"+fc.Revision)
+		}
+		sort.Strings(files)
+		changesets[strings.Join(files, " ")] = files
+	}
+	require.Len(t, changesets, 2)
+	require.Contains(t, changesets, "b.c:1.1")
+	require.Contains(t, changesets, "a.c:1.1 b.c:1.2")
+}
+
+func TestNewModuleReader(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	for _, module := range []string{"alpha", "beta"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, module), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, module, "main.c,v"), []byte(contentRCS), 0644))
+	}
+
+	r := NewModuleReader(dir, "alpha")
+	require.NoError(t, r.Validate())
+	iter, err := r.GetCommits()
+	require.NoError(t, err)
+	for iter.Next() {
+		for _, f := range iter.Commit().Files {
+			require.Equal(t, "main.c", f.Path)
+		}
+	}
+	require.NoError(t, iter.Err())
+
+	require.ErrorIs(t, NewModuleReader(dir, "missing").Validate(), vcs.ErrNotARepo)
+	require.ErrorIs(t, NewReader(t.TempDir()).Validate(), vcs.ErrNotARepo)
+}
+
+func TestGetTagDetails_NewestRevision(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	// contentRCS has no tags; tag 1.1 of one file and 1.2 of the other
+	older := strings.Replace(contentRCS, "FEATURE:1.2.0.2;", "FEATURE:1.2.0.2\n\tREL_1:1.1;", 1)
+	newer := strings.Replace(contentRCS, "FEATURE:1.2.0.2;", "FEATURE:1.2.0.2\n\tREL_1:1.2;", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.c,v"), []byte(older), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.c,v"), []byte(newer), 0644))
+
+	details, err := NewReader(dir).GetTagDetails()
+	require.NoError(t, err)
+	require.NotContains(t, details, "FEATURE", "branch symbols are not tags")
+
+	info := details["REL_1"]
+	require.Equal(t, "REL_1", info.Name)
+	require.Equal(t, "b.c", info.Path)
+	require.Equal(t, "1.2", info.Revision)
+	require.Equal(t, "alice", info.Author)
+	require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), info.Date)
+}
+
+func TestGetSubtreeTagDetails(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	older := strings.Replace(contentRCS, "FEATURE:1.2.0.2;", "FEATURE:1.2.0.2\n\tREL_1:1.1;", 1)
+	newer := strings.Replace(contentRCS, "FEATURE:1.2.0.2;", "FEATURE:1.2.0.2\n\tREL_1:1.2\n\tREL_2:1.2;", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "a.c,v"), []byte(older), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.c,v"), []byte(newer), 0644))
+
+	// Below lib only the older tagged revision of REL_1 counts
+	details, err := NewReader(dir).GetSubtreeTagDetails("lib/")
+	require.NoError(t, err)
+	require.Len(t, details, 1)
+	require.Equal(t, "lib/a.c", details["REL_1"].Path)
+	require.Equal(t, "1.1", details["REL_1"].Revision)
+
+	details, err = NewReader(dir).GetSubtreeTagDetails("li")
+	require.NoError(t, err)
+	require.Empty(t, details)
+
+	// Excluding lib leaves the tags of b.c
+	details, err = NewReader(dir).GetSubtreeTagDetails("", "lib")
+	require.NoError(t, err)
+	require.Len(t, details, 2)
+	require.Equal(t, "b.c", details["REL_1"].Path)
+}
+
+func TestGetCommits_SkipsUnparsableFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "good.txt,v"), []byte(rcsWithStates("Exp")), 0644))
+	bad := filepath.Join(dir, "bad.txt,v")
+	require.NoError(t, os.WriteFile(bad, []byte("\x00\x01 not an RCS file"), 0644))
+
+	r := NewReader(dir)
+	iter, err := r.GetCommits()
+	require.NoError(t, err)
+	var paths []string
+	for iter.Next() {
+		paths = append(paths, iter.Commit().Files[0].Path)
+	}
+	require.Equal(t, []string{"good.txt"}, paths)
+	require.Len(t, r.SkippedFiles(), 1)
+	require.Equal(t, bad, r.SkippedFiles()[0].Path)
+	require.Error(t, r.SkippedFiles()[0].Err)
+	require.Contains(t, r.FileStamps(), "good.txt,v")
+	require.NotContains(t, r.FileStamps(), "bad.txt,v")
+
+	strict := NewReader(dir)
+	strict.SetStrict(true)
+	_, err = strict.GetCommits()
+	require.Error(t, err)
+}
@@ -0,0 +1,353 @@
+package cvs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// remoteMethods are the CVSROOT access methods read through the cvs client
+var remoteMethods = map[string]bool{
+	"pserver": true,
+	"ext":     true,
+	"ssh":     true,
+	"extssh":  true,
+}
+
+// IsRemoteRoot reports whether root names a repository on a CVS server,
+// e.g. ":pserver:anonymous@cvs.example.org:/cvsroot" or
+// ":ext:user@host:/cvsroot", rather than a local directory
+func IsRemoteRoot(root string) bool {
+	_, err := parseRemoteRoot(root)
+	return err == nil
+}
+
+// remoteRoot is a parsed remote CVSROOT
+type remoteRoot struct {
+	method string // pserver or ext
+	root   string // CVSROOT passed to the cvs client
+	dir    string // Repository directory on the server
+}
+
+// parseRemoteRoot parses ":method:[user[:password]@]host[:[port]]/path".
+// The implicit form "[user@]host:/path" uses the ext method.
+func parseRemoteRoot(root string) (*remoteRoot, error) {
+	method, rest := "ext", root
+	if strings.HasPrefix(root, ":") {
+		var ok bool
+		method, rest, ok = strings.Cut(root[1:], ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid CVSROOT %q", root)
+		}
+		// CVSNT options such as ":pserver;proxy=...:"
+		method, _, _ = strings.Cut(method, ";")
+		if !remoteMethods[method] {
+			return nil, fmt.Errorf("unsupported CVS access method %q", method)
+		}
+	} else if strings.HasPrefix(root, "/") || !strings.Contains(root, ":/") || isDrivePath(root) {
+		return nil, fmt.Errorf("not a remote CVSROOT: %s", root)
+	}
+
+	i := strings.Index(rest, "/")
+	if i < 1 {
+		return nil, fmt.Errorf("invalid CVSROOT %q: missing repository path", root)
+	}
+	host, dir := rest[:i], rest[i:]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	if strings.TrimSuffix(host, ":") == "" || strings.HasPrefix(host, ":") {
+		return nil, fmt.Errorf("invalid CVSROOT %q: missing host", root)
+	}
+
+	clientRoot := root
+	if method == "ssh" || method == "extssh" {
+		// The ssh methods of CVSNT are spelled ext by the cvs client
+		clientRoot = ":ext:" + rest
+		method = "ext"
+	}
+	return &remoteRoot{method: method, root: clientRoot, dir: path.Clean(dir)}, nil
+}
+
+// isDrivePath reports whether root starts with a Windows drive letter, as
+// in "C:/cvsroot", which the implicit "host:/path" form would otherwise
+// read as a host named C
+func isDrivePath(root string) bool {
+	if len(root) < 2 || root[1] != ':' {
+		return false
+	}
+	c := root[0]
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// remoteClient reads a remote repository by running the cvs client
+type remoteClient struct {
+	root   *remoteRoot
+	module string
+	client Client          // Runs the cvs executable
+	ctx    context.Context // Kills the cvs calls once done (nil = never)
+
+	// run executes the cvs client and returns its standard output; replaced
+	// in tests.
+	run func(args ...string) ([]byte, error)
+}
+
+func newRemoteClient(root *remoteRoot, module string) *remoteClient {
+	c := &remoteClient{root: root, module: module}
+	c.run = c.runCVS
+	return c
+}
+
+func (c *remoteClient) runCVS(args ...string) ([]byte, error) {
+	out, err := c.client.RunContext(c.ctx, "", c.root.root, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", vcs.ErrUnreachable, err)
+	}
+	return out, nil
+}
+
+// validate checks that the module can be listed on the server
+func (c *remoteClient) validate() error {
+	if c.module == "" {
+		return fmt.Errorf("a module is required to read a remote CVS repository")
+	}
+	if _, err := c.run("-Q", "rlog", "-h", c.module); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return nil
+}
+
+// load reads the revision history of the module with rlog. The returned
+// files have no delta texts; contents are fetched with source.
+func (c *remoteClient) load() ([]*RCSFile, error) {
+	if c.module == "" {
+		return nil, fmt.Errorf("a module is required to read a remote CVS repository")
+	}
+	out, err := c.run("-Q", "rlog", c.module)
+	if err != nil {
+		return nil, err
+	}
+	return parseRlog(bytes.NewReader(out), c.root.dir, c.module)
+}
+
+// source fetches a file revision from the server without keyword expansion
+func (c *remoteClient) source(file, rev string) vcs.ContentSource {
+	return func() (io.ReadCloser, error) {
+		out, err := c.run("-Q", "checkout", "-p", "-ko", "-r", rev, path.Join(c.module, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s revision %s: %w", file, rev, err)
+		}
+		return io.NopCloser(bytes.NewReader(out)), nil
+	}
+}
+
+// rlog separators
+const (
+	rlogRevisionSep = "----------------------------"
+	rlogFileSep     = "============================================================================="
+)
+
+// parseRlog parses the output of "cvs rlog" into RCS files without delta
+// texts. dir is the repository directory on the server; paths of the
+// returned files are relative to the module.
+func parseRlog(r io.Reader, dir, module string) ([]*RCSFile, error) {
+	moduleDir := path.Join(dir, module) + "/"
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var files []*RCSFile
+	var rcs *RCSFile
+	var delta *Delta
+	var logLines []string
+	inSymbols, inLog := false, false
+
+	finishDelta := func() {
+		if delta != nil {
+			delta.Log = strings.Join(logLines, "\n")
+			if len(logLines) > 0 {
+				delta.Log += "\n"
+			}
+			rcs.Deltas[delta.Revision] = delta
+			rcs.DeltaOrder = append(rcs.DeltaOrder, delta.Revision)
+		}
+		delta, logLines = nil, nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if rcs == nil {
+			if name, ok := strings.CutPrefix(line, "RCS file: "); ok {
+				rcs = &RCSFile{
+					Symbols: make(map[string]string),
+					Locks:   make(map[string]string),
+					Deltas:  make(map[string]*Delta),
+				}
+				rel, ok := strings.CutPrefix(name, moduleDir)
+				if !ok {
+					// The server may report the repository under another
+					// name, e.g. behind a symlink
+					i := strings.Index(name, "/"+module+"/")
+					if i < 0 {
+						return nil, fmt.Errorf("rlog: %s is outside module %s", name, module)
+					}
+					rel = name[i+len(module)+2:]
+				}
+				rcs.Path, rcs.InAttic = workingPath(rel)
+			}
+			continue
+		}
+
+		if line == rlogFileSep {
+			finishDelta()
+			linkDeltas(rcs)
+			files = append(files, rcs)
+			rcs, inSymbols, inLog = nil, false, false
+			continue
+		}
+		if line == rlogRevisionSep {
+			finishDelta()
+			inSymbols, inLog = false, false
+			continue
+		}
+
+		switch {
+		case delta == nil && inSymbols && strings.HasPrefix(line, "\t"):
+			name, rev, ok := strings.Cut(strings.TrimSpace(line), ": ")
+			if ok {
+				rcs.Symbols[name] = rev
+			}
+		case delta == nil && !inLog && strings.HasPrefix(line, "revision "):
+			rev, _, _ := strings.Cut(strings.TrimPrefix(line, "revision "), "\t")
+			delta = &Delta{Revision: strings.TrimSpace(rev)}
+		case delta != nil && !inLog && strings.HasPrefix(line, "date: "):
+			if err := parseRlogDeltaLine(delta, line); err != nil {
+				return nil, fmt.Errorf("rlog %s: %w", rcs.Path, err)
+			}
+		case delta != nil && !inLog && strings.HasPrefix(line, "branches: "):
+			// Recomputed from the revision numbers by linkDeltas
+		case delta != nil:
+			inLog = true
+			logLines = append(logLines, line)
+		default:
+			inSymbols = false
+			key, value, _ := strings.Cut(line, ":")
+			value = strings.TrimSpace(value)
+			switch key {
+			case "head":
+				rcs.Head = value
+			case "branch":
+				rcs.Branch = value
+			case "keyword substitution":
+				rcs.Expand = value
+			case "symbolic names":
+				inSymbols = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if rcs != nil {
+		return nil, fmt.Errorf("rlog: truncated output for %s", rcs.Path)
+	}
+	return files, nil
+}
+
+// parseRlogDeltaLine parses "date: ...;  author: ...;  state: ...; ..."
+func parseRlogDeltaLine(delta *Delta, line string) error {
+	for _, field := range strings.Split(line, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), ": ")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "date":
+			date, err := parseRlogDate(value)
+			if err != nil {
+				return err
+			}
+			delta.Date = date
+		case "author":
+			delta.Author = value
+		case "state":
+			delta.State = value
+		case "commitid":
+			delta.CommitID = value
+		case "mergepoint":
+			delta.MergePoint = value
+		}
+	}
+	if delta.Date.IsZero() {
+		return fmt.Errorf("revision %s has no date", delta.Revision)
+	}
+	return nil
+}
+
+// parseRlogDate parses the date formats of CVS 1.11 ("2024/01/02 15:04:05",
+// UTC) and CVS 1.12 ("2024-01-02 15:04:05 +0000")
+func parseRlogDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006/01/02 15:04:05", "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// linkDeltas restores the Next and Branches links that rlog does not print
+// in RCS form: trunk revisions point to their predecessor, branch revisions
+// to their successor, and branch points list the first revision of each
+// branch.
+func linkDeltas(rcs *RCSFile) {
+	lines := make(map[string][]string) // Branch number ("" for trunk) → revisions
+	for rev := range rcs.Deltas {
+		branch := ""
+		if parts := strings.Split(rev, "."); len(parts) > 2 {
+			branch = strings.Join(parts[:len(parts)-1], ".")
+		}
+		lines[branch] = append(lines[branch], rev)
+	}
+
+	for branch, revs := range lines {
+		sort.Slice(revs, func(i, j int) bool { return CompareRevisions(revs[i], revs[j]) < 0 })
+		if branch == "" {
+			for i := len(revs) - 1; i > 0; i-- {
+				rcs.Deltas[revs[i]].Next = revs[i-1]
+			}
+			continue
+		}
+		for i := 0; i < len(revs)-1; i++ {
+			rcs.Deltas[revs[i]].Next = revs[i+1]
+		}
+		point := branch[:strings.LastIndex(branch, ".")]
+		if d := rcs.Deltas[point]; d != nil {
+			d.Branches = append(d.Branches, revs[0])
+			sort.Slice(d.Branches, func(i, j int) bool { return CompareRevisions(d.Branches[i], d.Branches[j]) < 0 })
+		}
+	}
+}
+
+// CompareRevisions orders revision numbers numerically, component by
+// component
+func CompareRevisions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na - nb
+		}
+	}
+	return len(pa) - len(pb)
+}
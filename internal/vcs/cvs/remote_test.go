@@ -0,0 +1,164 @@
+package cvs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+func TestParseRemoteRoot(t *testing.T) {
+	tests := []struct {
+		root, method, clientRoot, dir string
+	}{
+		{":pserver:anonymous@cvs.example.org:/cvsroot", "pserver", ":pserver:anonymous@cvs.example.org:/cvsroot", "/cvsroot"},
+		{":pserver:anon:secret@cvs.example.org:2401/var/cvs/", "pserver", ":pserver:anon:secret@cvs.example.org:2401/var/cvs/", "/var/cvs"},
+		{":ext:alice@host:/cvsroot", "ext", ":ext:alice@host:/cvsroot", "/cvsroot"},
+		{":ssh:alice@host:/cvsroot", "ext", ":ext:alice@host:/cvsroot", "/cvsroot"},
+		{"alice@host:/cvsroot", "ext", "alice@host:/cvsroot", "/cvsroot"},
+	}
+	for _, tt := range tests {
+		root, err := parseRemoteRoot(tt.root)
+		require.NoError(t, err, tt.root)
+		require.Equal(t, &remoteRoot{method: tt.method, root: tt.clientRoot, dir: tt.dir}, root, tt.root)
+		require.True(t, IsRemoteRoot(tt.root))
+	}
+
+	for _, root := range []string{"/var/cvs", "relative/path", ":local:/var/cvs", ":pserver:host", ":pserver:@:/cvs", `C:\cvs`, "C:/cvs", "d:/repo/cvsroot"} {
+		require.False(t, IsRemoteRoot(root), root)
+	}
+}
+
+// rlogOutput is "cvs rlog mod" output in CVS 1.12 format, with a file
+// removed on the trunk and a branch
+const rlogOutput = `
+RCS file: /cvsroot/mod/README,v
+head: 1.2
+branch:
+locks: strict
+access list:
+symbolic names:
+	REL_1: 1.1
+	FEATURE: 1.1.0.2
+keyword substitution: kv
+total revisions: 3;	selected revisions: 3
+description:
+----------------------------
+revision 1.2
+date: 2024-01-03 10:00:00 +0000;  author: alice;  state: Exp;  lines: +1 -0;  commitid: c2;
+Second line
+----------------------------
+revision 1.1
+date: 2024-01-01 10:00:00 +0000;  author: alice;  state: Exp;  commitid: c1;
+branches:  1.1.2;
+Initial import
+
+with two paragraphs
+----------------------------
+revision 1.1.2.1
+date: 2024-01-02 10:00:00 +0000;  author: bob;  state: Exp;  lines: +1 -0;  commitid: c3;
+On the branch
+=============================================================================
+
+RCS file: /cvsroot/mod/src/Attic/old.c,v
+head: 1.2
+branch:
+locks: strict
+access list:
+symbolic names:
+keyword substitution: kv
+total revisions: 2;	selected revisions: 2
+description:
+----------------------------
+revision 1.2
+date: 2024/01/03 10:00:00;  author: alice;  state: dead;  lines: +0 -0;  commitid: c2;
+Second line
+----------------------------
+revision 1.1
+date: 2024/01/01 10:00:00;  author: alice;  state: Exp;  commitid: c1;
+Initial import
+
+with two paragraphs
+=============================================================================
+`
+
+func TestParseRlog(t *testing.T) {
+	files, err := parseRlog(strings.NewReader(rlogOutput), "/cvsroot", "mod")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	readme := files[0]
+	require.Equal(t, "README", readme.Path)
+	require.Equal(t, "1.2", readme.Head)
+	require.Equal(t, "kv", readme.Expand)
+	require.Equal(t, map[string]string{"REL_1": "1.1", "FEATURE": "1.1.0.2"}, readme.Symbols)
+	require.Equal(t, []string{"1.2", "1.1", "1.1.2.1"}, readme.DeltaOrder)
+	require.Equal(t, "1.1", readme.Deltas["1.2"].Next)
+	require.Equal(t, []string{"1.1.2.1"}, readme.Deltas["1.1"].Branches)
+	require.Equal(t, "Initial import\n\nwith two paragraphs\n", readme.Deltas["1.1"].Log)
+	require.Equal(t, "c3", readme.Deltas["1.1.2.1"].CommitID)
+	require.Equal(t, time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), readme.Deltas["1.1.2.1"].Date)
+
+	old := files[1]
+	require.Equal(t, "src/old.c", old.Path)
+	require.True(t, old.InAttic)
+	require.True(t, old.Deltas["1.2"].IsDead())
+	require.Equal(t, time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), old.Deltas["1.2"].Date)
+
+	// The server may report another path for the repository
+	files, err = parseRlog(strings.NewReader(rlogOutput), "/srv/cvs", "mod")
+	require.NoError(t, err)
+	require.Equal(t, "README", files[0].Path)
+
+	_, err = parseRlog(strings.NewReader(rlogOutput), "/cvsroot", "other")
+	require.Error(t, err)
+	_, err = parseRlog(strings.NewReader(rlogOutput[:200]), "/cvsroot", "mod")
+	require.Error(t, err)
+}
+
+func TestReaderRemote(t *testing.T) {
+	r := NewModuleReader(":pserver:anonymous@cvs.example.org:/cvsroot", "mod")
+	require.NotNil(t, r.remote)
+
+	var calls []string
+	r.remote.run = func(args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(args, " "))
+		switch args[1] {
+		case "rlog":
+			return []byte(rlogOutput), nil
+		case "checkout":
+			return []byte(fmt.Sprintf("content of %s\n", args[len(args)-2]+" "+args[len(args)-1])), nil
+		}
+		return nil, fmt.Errorf("unexpected command %v", args)
+	}
+
+	require.NoError(t, r.Validate())
+	iter, err := r.GetCommits()
+	require.NoError(t, err)
+
+	var commits []*vcs.Commit
+	for iter.Next() {
+		commits = append(commits, iter.Commit())
+	}
+	require.Len(t, commits, 3)
+	require.Equal(t, "Initial import\n\nwith two paragraphs\n", commits[0].Message)
+	require.Len(t, commits[0].Files, 2)
+	require.Equal(t, "1.1.2.1", commits[1].Files[0].Revision)
+	require.Equal(t, []vcs.FileChange{{Path: "src/old.c", Action: vcs.ActionDelete, Revision: "1.2"}},
+		[]vcs.FileChange{commits[2].Files[1]})
+
+	content, err := commits[0].Files[0].ReadContent()
+	require.NoError(t, err)
+	require.Equal(t, "content of 1.1 mod/README\n", string(content))
+	require.Contains(t, calls, "-Q checkout -p -ko -r 1.1 mod/README")
+
+	tags, err := r.GetTags()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"REL_1": "1.1"}, tags)
+
+	require.Error(t, NewModuleReader(":pserver:anonymous@cvs.example.org:/cvsroot", "").Validate())
+}
@@ -0,0 +1,85 @@
+package cvs
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// TextBudget limits the bytes of RCS delta texts that readers keep in
+// memory. The texts of files parsed once the budget is used up are dropped
+// after parsing and read again from the RCS file whenever a revision of the
+// file is reconstructed, trading time for memory on very large
+// repositories.
+type TextBudget struct {
+	mu     sync.Mutex
+	max    int64
+	used   int64
+	onDisk int
+}
+
+// NewTextBudget creates a budget of max bytes of delta texts
+func NewTextBudget(max int64) *TextBudget {
+	return &TextBudget{max: max}
+}
+
+// Stats returns the bytes of delta texts kept in memory and the number of
+// RCS files whose texts are read from disk
+func (b *TextBudget) Stats() (inMemory int64, onDisk int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used, b.onDisk
+}
+
+// reserve reports whether size more bytes of delta texts may be kept in
+// memory, and if so counts them
+func (b *TextBudget) reserve(size int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used+size > b.max {
+		b.onDisk++
+		return false
+	}
+	b.used += size
+	return true
+}
+
+// fit drops the delta texts of rcs, which was parsed from file, unless they
+// fit into the budget
+func (b *TextBudget) fit(rcs *RCSFile, file string) {
+	if b == nil {
+		return
+	}
+	var size int64
+	for _, delta := range rcs.Deltas {
+		size += int64(len(delta.Text))
+	}
+	if b.reserve(size) {
+		return
+	}
+	for _, delta := range rcs.Deltas {
+		delta.Text = ""
+	}
+	rcs.textFile = file
+}
+
+// withTexts returns rcs with its delta texts, parsing its RCS file again if
+// they were dropped
+func (r *RCSFile) withTexts() (*RCSFile, error) {
+	if r.textFile == "" {
+		return r, nil
+	}
+	file, err := os.Open(r.textFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen %s: %w", r.textFile, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	full, err := NewRCSParser(file).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", r.textFile, err)
+	}
+	full.Path, full.InAttic = r.Path, r.InAttic
+	full.cache, full.cacheID = r.cache, r.cacheID
+	return full, nil
+}
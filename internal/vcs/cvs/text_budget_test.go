@@ -0,0 +1,55 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader_TextBudget(t *testing.T) {
+	readAll := func(budget *TextBudget) map[string]string {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt,v"), []byte(contentRCS), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt,v"), []byte(contentRCS), 0644))
+		reader := NewReader(dir)
+		reader.SetTextBudget(budget)
+		iter, err := reader.GetCommits()
+		require.NoError(t, err)
+
+		contents := make(map[string]string)
+		for iter.Next() {
+			for _, fc := range iter.Commit().Files {
+				if fc.Source == nil {
+					continue
+				}
+				data, err := fc.ReadContent()
+				require.NoError(t, err)
+				contents[fc.Path+"@"+fc.Revision] = string(data)
+			}
+		}
+		require.NoError(t, iter.Err())
+		return contents
+	}
+
+	want := readAll(nil)
+	require.NotEmpty(t, want)
+
+	// One file fits, the other is read again from disk
+	size := int64(0)
+	for _, delta := range parseContentRCS(t).Deltas {
+		size += int64(len(delta.Text))
+	}
+	budget := NewTextBudget(size)
+	require.Equal(t, want, readAll(budget))
+	inMemory, onDisk := budget.Stats()
+	require.Equal(t, size, inMemory)
+	require.Equal(t, 1, onDisk)
+
+	budget = NewTextBudget(1)
+	require.Equal(t, want, readAll(budget))
+	inMemory, onDisk = budget.Stats()
+	require.Zero(t, inMemory)
+	require.Equal(t, 2, onDisk)
+}
@@ -0,0 +1,190 @@
+package cvs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Operations reported by WatchEvent.Op.
+const (
+	WatchCreate = "create" // A ,v file appeared, e.g. renamed into place by a commit
+	WatchWrite  = "write"
+	WatchRemove = "remove" // Removed or renamed away, e.g. moved into the Attic
+)
+
+// WatchEvent reports a change of an RCS file of the module, or a failure
+// of the watch
+type WatchEvent struct {
+	Path string // RCS file, relative to the repository root
+	Op   string // WatchCreate, WatchWrite or WatchRemove
+	Err  error  // Set, with no Path, when events may have been lost
+}
+
+// Watch reports changes of the RCS files of the module as they happen,
+// using the file system notifications of the operating system. Directories
+// created later are watched too. The channel is closed once ctx is
+// cancelled. Remote repositories cannot be watched.
+func (r *Reader) Watch(ctx context.Context) (<-chan WatchEvent, error) {
+	if r.remote != nil {
+		return nil, fmt.Errorf("cannot watch the remote repository %s", r.path)
+	}
+	dirs, err := r.moduleDirs()
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", r.path, err)
+	}
+	w := &rcsWatcher{reader: r, dirs: dirs, watcher: watcher, events: make(chan WatchEvent, 64)}
+	for _, md := range dirs {
+		if err := w.addTree(filepath.Join(r.path, md.Dir), nil); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+	go w.run(ctx)
+	return w.events, nil
+}
+
+// rcsWatcher turns file system notifications into WatchEvents
+type rcsWatcher struct {
+	reader  *Reader
+	dirs    []ModuleDir
+	watcher *fsnotify.Watcher
+	events  chan WatchEvent
+}
+
+func (w *rcsWatcher) run(ctx context.Context) {
+	defer close(w.events)
+	defer func() { _ = w.watcher.Close() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(ctx, event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.send(ctx, WatchEvent{Err: err})
+		}
+	}
+}
+
+// handle reports a notification about an RCS file of the module and
+// watches new directories, reporting the RCS files already in them
+func (w *rcsWatcher) handle(ctx context.Context, event fsnotify.Event) {
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			var found []string
+			if err := w.addTree(event.Name, &found); err != nil {
+				w.send(ctx, WatchEvent{Err: err})
+			}
+			for _, rel := range found {
+				w.send(ctx, WatchEvent{Path: rel, Op: WatchCreate})
+			}
+			return
+		}
+	}
+
+	rel, ok := w.moduleFile(event.Name)
+	if !ok {
+		return
+	}
+	switch {
+	case event.Has(fsnotify.Create):
+		w.send(ctx, WatchEvent{Path: rel, Op: WatchCreate})
+	case event.Has(fsnotify.Write):
+		w.send(ctx, WatchEvent{Path: rel, Op: WatchWrite})
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		w.send(ctx, WatchEvent{Path: rel, Op: WatchRemove})
+	}
+}
+
+// addTree watches root and the directories below it that belong to the
+// module, appending the RCS files found to found if it is not nil
+func (w *rcsWatcher) addTree(root string, found *[]string) error {
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path != root && errors.Is(err, fs.ErrNotExist) {
+				return nil // Removed meanwhile
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			if rel, ok := w.moduleFile(path); ok && found != nil {
+				*found = append(*found, rel)
+			}
+			return nil
+		}
+		if entry.Name() == "CVSROOT" {
+			return filepath.SkipDir
+		}
+		if rel, ok := w.relative(path); ok && rel != "." && !w.moduleDir(rel) {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", root, err)
+	}
+	return nil
+}
+
+// relative returns path relative to the repository root, in slash form
+func (w *rcsWatcher) relative(path string) (string, bool) {
+	rel, err := filepath.Rel(w.reader.path, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// moduleDir reports whether the repository directory rel may hold RCS
+// files of the module
+func (w *rcsWatcher) moduleDir(rel string) bool {
+	for _, md := range w.dirs {
+		if _, ok := md.contains(rel, false); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleFile returns the path relative to the repository root of an RCS
+// file of the module
+func (w *rcsWatcher) moduleFile(path string) (string, bool) {
+	if !strings.HasSuffix(path, ",v") {
+		return "", false
+	}
+	rel, ok := w.relative(path)
+	if !ok {
+		return "", false
+	}
+	working, _ := workingPath(rel)
+	for _, md := range w.dirs {
+		if _, ok := md.contains(working, true); ok {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+func (w *rcsWatcher) send(ctx context.Context, event WatchEvent) {
+	select {
+	case w.events <- event:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,69 @@
+package cvs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nextWatchEvent waits for the next event of a watch
+func nextWatchEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "watch closed")
+		require.NoError(t, event.Err)
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no watch event")
+		return WatchEvent{}
+	}
+}
+
+func TestReader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mod"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "other"), 0755))
+	rcs := filepath.Join(dir, "mod", "a.txt,v")
+	require.NoError(t, os.WriteFile(rcs, []byte(rcsWithStates("Exp")), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := NewModuleReader(dir, "mod").Watch(ctx)
+	require.NoError(t, err)
+
+	// Files outside the module and other files are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other", "b.txt,v"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mod", "notes.txt"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(rcs, []byte(rcsWithStates("Exp", "Exp")), 0644))
+	event := nextWatchEvent(t, events)
+	require.Equal(t, "mod/a.txt,v", event.Path)
+
+	// Directories created later are watched
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mod", "sub"), 0755))
+	require.Eventually(t, func() bool {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "mod", "sub", "c.txt,v"), []byte("x"), 0644))
+		for {
+			select {
+			case event := <-events:
+				if event.Path == "mod/sub/c.txt,v" {
+					return true
+				}
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	for range events {
+	}
+
+	_, err = NewModuleReader(":pserver:anonymous@cvs.example.org:/cvsroot", "mod").Watch(context.Background())
+	require.ErrorContains(t, err, "cannot watch the remote repository")
+}
@@ -0,0 +1,83 @@
+package cvs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Wrapper is an entry of CVSROOT/cvswrappers, e.g. "*.gif -k 'b'"
+type Wrapper struct {
+	Pattern string // Shell pattern matched against file names
+	Expand  string // -k: keyword substitution mode of matching files
+	Method  string // -m: update method, COPY or MERGE
+}
+
+// Wrappers are the cvswrappers entries in file order
+type Wrappers []Wrapper
+
+// ParseWrappers reads a cvswrappers file. Lines starting with "#" are
+// comments; options other than -k and -m are ignored.
+func ParseWrappers(r io.Reader) (Wrappers, error) {
+	var wrappers Wrappers
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		w := Wrapper{Pattern: fields[0]}
+		if _, err := path.Match(w.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid wrapper pattern %q: %w", w.Pattern, err)
+		}
+		for i := 1; i+1 < len(fields); i += 2 {
+			value := strings.Trim(fields[i+1], `'"`)
+			switch fields[i] {
+			case "-k":
+				w.Expand = value
+			case "-m":
+				w.Method = value
+			}
+		}
+		wrappers = append(wrappers, w)
+	}
+	return wrappers, scanner.Err()
+}
+
+// LoadWrappers reads CVSROOT/cvswrappers of the repository at root. It
+// returns no wrappers if the file does not exist.
+func LoadWrappers(root string) (Wrappers, error) {
+	file, err := os.Open(filepath.Join(root, "CVSROOT", "cvswrappers"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CVSROOT/cvswrappers: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	wrappers, err := ParseWrappers(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CVSROOT/cvswrappers: %w", err)
+	}
+	return wrappers, nil
+}
+
+// Expand returns the keyword substitution mode the first wrapper matching
+// the base name of file sets, or "" if none does
+func (ws Wrappers) Expand(file string) string {
+	name := path.Base(file)
+	for _, w := range ws {
+		if w.Expand == "" {
+			continue
+		}
+		if ok, _ := path.Match(w.Pattern, name); ok {
+			return w.Expand
+		}
+	}
+	return ""
+}
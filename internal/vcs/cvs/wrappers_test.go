@@ -0,0 +1,45 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWrappers(t *testing.T) {
+	wrappers, err := ParseWrappers(strings.NewReader("# binaries\n*.gif -k 'b'\n*.doc -k \"b\" -m 'COPY'\n*.c -k o\n\n"))
+	require.NoError(t, err)
+	require.Len(t, wrappers, 3)
+	require.Equal(t, Wrapper{Pattern: "*.doc", Expand: "b", Method: "COPY"}, wrappers[1])
+
+	require.Equal(t, "b", wrappers.Expand("img/logo.gif"))
+	require.Equal(t, "o", wrappers.Expand("main.c"))
+	require.Equal(t, "", wrappers.Expand("README"))
+
+	_, err = ParseWrappers(strings.NewReader("[ -k 'b'\n"))
+	require.Error(t, err)
+}
+
+func TestGetCommits_WrappersMarkBinary(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CVSROOT", "cvswrappers"), []byte("*.gif -k 'b'\n*.txt -k 'b'\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo.gif,v"), []byte(contentRCS), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.c,v"), []byte(contentRCS), 0644))
+	// The file's own expansion mode wins over the wrappers
+	kv := strings.Replace(contentRCS, "comment\t@# @;", "comment\t@# @;\nexpand\t@kv@;", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt,v"), []byte(kv), 0644))
+
+	iter, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+	binary := make(map[string]bool)
+	for iter.Next() {
+		for _, f := range iter.Commit().Files {
+			binary[f.Path] = f.Binary
+		}
+	}
+	require.Equal(t, map[string]bool{"logo.gif": true, "main.c": false, "notes.txt": false}, binary)
+}
@@ -2,10 +2,11 @@
 package cvs
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/vcs"
 )
@@ -17,6 +18,9 @@ type Writer struct {
 	repoPath string // Absolute path to the CVS repository (CVSROOT)
 	module   string // CVS module name
 	workDir  string // Working directory used for checkouts
+
+	client Client          // Runs the cvs executable
+	ctx    context.Context // Kills the cvs calls of the following operations once done (nil = never)
 }
 
 // NewWriter creates a new CVS repository writer.
@@ -28,11 +32,33 @@ func NewWriter(repoPath, module string) *Writer {
 	}
 }
 
+// SetClient sets how the cvs client is run
+func (w *Writer) SetClient(client Client) {
+	w.client = client
+}
+
+// SetTimeout limits how long each call of the cvs client may take; calls
+// still running after timeout are killed and fail with vcs.ErrTimeout
+func (w *Writer) SetTimeout(timeout time.Duration) {
+	w.client.Timeout = timeout
+}
+
+// SetContext kills the cvs calls of the following operations once ctx is
+// done, failing them
+func (w *Writer) SetContext(ctx context.Context) {
+	w.ctx = ctx
+}
+
+// run runs a cvs command in dir
+func (w *Writer) run(dir string, args ...string) ([]byte, error) {
+	return w.client.RunContext(w.ctx, dir, w.repoPath, args...)
+}
+
 // Init checks out the CVS module into path, which becomes the working
 // directory for subsequent operations.
 func (w *Writer) Init(path string) error {
-	if _, err := exec.LookPath("cvs"); err != nil {
-		return fmt.Errorf("cvs command not found in PATH: %w", err)
+	if _, err := w.client.LookPath(); err != nil {
+		return fmt.Errorf("cvs client not found: %w", err)
 	}
 
 	if err := os.MkdirAll(path, 0755); err != nil {
@@ -40,16 +66,24 @@ func (w *Writer) Init(path string) error {
 	}
 
 	// Check out the module into the work directory
-	cmd := exec.Command("cvs", "-d", w.repoPath, "checkout", "-d", ".", w.module) //nolint:gosec
-	cmd.Dir = path
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("cvs checkout failed: %w\n%s", err, out)
+	if _, err := w.run(path, "checkout", "-d", ".", w.module); err != nil {
+		return err
 	}
 
 	w.workDir = path
 	return nil
 }
 
+// Open uses an existing checkout of the module at path as the working
+// directory for subsequent operations.
+func (w *Writer) Open(path string) error {
+	if _, err := os.Stat(filepath.Join(path, "CVS")); err != nil {
+		return fmt.Errorf("not a CVS working directory: %s", path)
+	}
+	w.workDir = path
+	return nil
+}
+
 // ApplyCommit applies the given commit's file changes to the CVS working
 // directory and runs `cvs commit`.
 func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
@@ -60,7 +94,8 @@ func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
 	var toAdd []string
 	var toRemove []string
 
-	for _, fc := range commit.Files {
+	// CVS has no renames or copies
+	for _, fc := range vcs.BasicChanges(commit.Files) {
 		fullPath := filepath.Join(w.workDir, fc.Path)
 
 		switch fc.Action {
@@ -68,7 +103,11 @@ func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
 			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 				return fmt.Errorf("failed to create directory for %s: %w", fc.Path, err)
 			}
-			if err := os.WriteFile(fullPath, fc.Content, 0644); err != nil {
+			content, err := fc.ReadContent()
+			if err != nil {
+				return fmt.Errorf("failed to read content for %s: %w", fc.Path, err)
+			}
+			if err := os.WriteFile(fullPath, content, 0644); err != nil {
 				return fmt.Errorf("failed to write file %s: %w", fc.Path, err)
 			}
 			if fc.Action == vcs.ActionAdd {
@@ -85,29 +124,21 @@ func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
 
 	// Stage additions
 	if len(toAdd) > 0 {
-		args := append([]string{"-d", w.repoPath, "add"}, toAdd...)
-		cmd := exec.Command("cvs", args...) //nolint:gosec
-		cmd.Dir = w.workDir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("cvs add failed: %w\n%s", err, out)
+		if _, err := w.run(w.workDir, append([]string{"add"}, toAdd...)...); err != nil {
+			return err
 		}
 	}
 
 	// Stage removals
 	if len(toRemove) > 0 {
-		args := append([]string{"-d", w.repoPath, "remove"}, toRemove...)
-		cmd := exec.Command("cvs", args...) //nolint:gosec
-		cmd.Dir = w.workDir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("cvs remove failed: %w\n%s", err, out)
+		if _, err := w.run(w.workDir, append([]string{"remove"}, toRemove...)...); err != nil {
+			return err
 		}
 	}
 
 	// Commit
-	cmd := exec.Command("cvs", "-d", w.repoPath, "commit", "-m", commit.Message) //nolint:gosec
-	cmd.Dir = w.workDir
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("cvs commit failed: %w\n%s", err, out)
+	if _, err := w.run(w.workDir, "commit", "-m", commit.Message); err != nil {
+		return err
 	}
 
 	return nil
@@ -119,28 +150,34 @@ func (w *Writer) CreateBranch(name, _ string) error {
 		return fmt.Errorf("CVS working directory not initialised – call Init first")
 	}
 
-	cmd := exec.Command("cvs", "-d", w.repoPath, "tag", "-b", name) //nolint:gosec
-	cmd.Dir = w.workDir
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("cvs tag -b %s failed: %w\n%s", name, err, out)
+	if _, err := w.run(w.workDir, "tag", "-b", name); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
 	}
 	return nil
 }
 
-// CreateTag creates a CVS tag in the working directory.
-func (w *Writer) CreateTag(name, _ string) error {
+// CreateTag creates a CVS tag in the working directory. CVS tags carry no
+// message, so message is ignored.
+func (w *Writer) CreateTag(name, _, _ string) error {
 	if w.workDir == "" {
 		return fmt.Errorf("CVS working directory not initialised – call Init first")
 	}
 
-	cmd := exec.Command("cvs", "-d", w.repoPath, "tag", name) //nolint:gosec
-	cmd.Dir = w.workDir
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("cvs tag %s failed: %w\n%s", name, err, out)
+	if _, err := w.run(w.workDir, "tag", name); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
 	}
 	return nil
 }
 
+func init() {
+	vcs.RegisterWriter("cvs", func(options map[string]string) (vcs.VCSWriter, error) {
+		if options["cvsroot"] == "" || options["module"] == "" {
+			return nil, fmt.Errorf("cvs target requires the cvsroot and module options")
+		}
+		return NewWriter(options["cvsroot"], options["module"]), nil
+	})
+}
+
 // Close releases any resources held by the writer.
 func (w *Writer) Close() error {
 	return nil
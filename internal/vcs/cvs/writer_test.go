@@ -1,7 +1,11 @@
 package cvs
 
 import (
+	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
@@ -63,19 +67,13 @@ func TestCVSWriterCreateBranch_NoWorkDir(t *testing.T) {
 
 func TestCVSWriterCreateTag_NoWorkDir(t *testing.T) {
 	w := NewWriter("/tmp/cvsroot", "mod")
-	if err := w.CreateTag("v1.0", ""); err == nil {
+	if err := w.CreateTag("v1.0", "", ""); err == nil {
 		t.Error("CreateTag should fail when working directory is not initialised")
 	}
 }
 
 func TestCVSWriterImplementsVCSWriter(t *testing.T) {
-	var _ interface {
-		Init(path string) error
-		ApplyCommit(commit *vcs.Commit) error
-		CreateBranch(name, revision string) error
-		CreateTag(name, revision string) error
-		Close() error
-	} = (*Writer)(nil)
+	var _ vcs.VCSWriter = (*Writer)(nil)
 }
 
 // TestCVSWriterApplyCommit_WithWorkDir_NoCVS verifies that ApplyCommit, when
@@ -160,7 +158,48 @@ func TestCVSWriterCreateTag_WithWorkDir_NoCVS(t *testing.T) {
 	w := NewWriter("/tmp/cvsroot", "mod")
 	w.workDir = t.TempDir()
 
-	if err := w.CreateTag("v2.0", ""); err == nil {
+	if err := w.CreateTag("v2.0", "", ""); err == nil {
 		t.Error("CreateTag should fail when cvs binary is not available")
 	}
 }
+
+func TestCVSWriterOpen(t *testing.T) {
+	w := NewWriter("/tmp/cvsroot", "mod")
+
+	dir := t.TempDir()
+	if err := w.Open(dir); err == nil {
+		t.Error("Open should fail for a directory without CVS metadata")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "CVS"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Open(dir); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if w.workDir != dir {
+		t.Errorf("workDir = %q, want %q", w.workDir, dir)
+	}
+}
+
+func TestCVSWriterTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cvs client is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "cvs"), []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	w := NewWriter("/tmp/cvsroot", "mod")
+	w.SetTimeout(100 * time.Millisecond)
+	start := time.Now()
+	err := w.Init(t.TempDir())
+	if !errors.Is(err, vcs.ErrTimeout) {
+		t.Fatalf("Init() error = %v, want vcs.ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Init() returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,40 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotARepo is wrapped by the errors of readers and writers asked to use
+// a path that holds no repository of their type
+var ErrNotARepo = errors.New("not a repository")
+
+// ErrUnreachable is wrapped by the errors of repositories on a server that
+// could not be read
+var ErrUnreachable = errors.New("repository unreachable")
+
+// ErrTimeout is wrapped by the errors of operations that exceeded their
+// time limit
+var ErrTimeout = errors.New("timed out")
+
+// ErrParse matches every ParseError
+var ErrParse = errors.New("parse error")
+
+// ParseError is a malformed construct in a file of a source repository
+type ParseError struct {
+	File string // Path of the file
+	Line int    // Line of the construct (0 = unknown)
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Is reports ErrParse as matching
+func (e *ParseError) Is(target error) bool { return target == ErrParse }
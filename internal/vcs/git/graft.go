@@ -0,0 +1,137 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TreeState describes a commit of the repository and the files of its tree
+type TreeState struct {
+	Hash  string
+	Date  time.Time         // Author date
+	Depth int               // Commits in its history, itself included
+	Files map[string]string // Path -> blob hash, submodules and symlinks excluded
+}
+
+// TreeState resolves rev and lists the files of its tree
+func (w *Writer) TreeState(rev string) (*TreeState, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+	hash, err := w.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %s: %w", rev, err)
+	}
+	commit, err := w.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", hash, err)
+	}
+	state := &TreeState{Hash: hash.String(), Date: commit.Author.When, Files: make(map[string]string)}
+	history, err := w.repo.Log(&git.LogOptions{From: *hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", hash, err)
+	}
+	defer history.Close()
+	if err := history.ForEach(func(*object.Commit) error { state.Depth++; return nil }); err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", hash, err)
+	}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk tree of %s: %w", hash, err)
+		}
+		if entry.Mode.IsFile() && entry.Mode != filemode.Symlink {
+			state.Files[name] = entry.Hash.String()
+		}
+	}
+	return state, nil
+}
+
+// StartOrphanBranch points HEAD at the new branch name, so the next commit
+// starts a history of its own next to the existing one. Commits are built
+// in the object store from then on (CommitModeObjects), leaving the working
+// tree of the existing history alone.
+func (w *Writer) StartOrphanBranch(name string) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	branch := plumbing.NewBranchReferenceName(name)
+	if err := branch.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q: %w", name, err)
+	}
+	if _, err := w.repo.Storer.Reference(branch); err == nil {
+		return fmt.Errorf("branch %s already exists", name)
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("failed to get branch %s: %w", name, err)
+	}
+	return w.switchOrphanBranch(branch, plumbing.ZeroHash)
+}
+
+// ResumeOrphanBranch continues the history StartOrphanBranch began on the
+// branch name in an earlier run. Commits are built in the object store on
+// top of its tip; a branch without commits yet is started afresh.
+func (w *Writer) ResumeOrphanBranch(name string) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	branch := plumbing.NewBranchReferenceName(name)
+	if err := branch.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q: %w", name, err)
+	}
+	tip := plumbing.ZeroHash
+	if ref, err := w.repo.Storer.Reference(branch); err == nil {
+		tip = ref.Hash()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("failed to get branch %s: %w", name, err)
+	}
+	return w.switchOrphanBranch(branch, tip)
+}
+
+// switchOrphanBranch points HEAD at branch, whose tip is the commit tip, and
+// builds the following commits in the object store
+func (w *Writer) switchOrphanBranch(branch plumbing.ReferenceName, tip plumbing.Hash) error {
+	if err := w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+	w.commitMode = CommitModeObjects
+	w.tree = treeBuilder{}
+	w.lastCommit = tip
+	return nil
+}
+
+// CreateReplaceRef makes Git show the commit replacement wherever original
+// is referenced, as "git replace original replacement" does
+func (w *Writer) CreateReplaceRef(original, replacement string) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	for _, hash := range []string{original, replacement} {
+		if !plumbing.IsHash(hash) {
+			return fmt.Errorf("invalid commit hash %q", hash)
+		}
+		if _, err := w.repo.CommitObject(plumbing.NewHash(hash)); err != nil {
+			return fmt.Errorf("unknown commit %s: %w", hash, err)
+		}
+	}
+	name := plumbing.ReferenceName("refs/replace/" + original)
+	if err := w.repo.Storer.SetReference(plumbing.NewHashReference(name, plumbing.NewHash(replacement))); err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterTreeState(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+
+	state, err := w.TreeState("HEAD~1")
+	require.NoError(t, err)
+	require.Equal(t, hashes[1], state.Hash)
+	require.Equal(t, 2, state.Depth)
+	require.True(t, state.Date.Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)))
+	require.Len(t, state.Files, 5)
+	require.Equal(t, plumbing.ComputeHash(plumbing.BlobObject, []byte("int y;\n")).String(), state.Files["src/main.c"])
+	require.NotContains(t, state.Files, "doc/old.txt")
+
+	_, err = w.TreeState("nope")
+	require.ErrorContains(t, err, "unknown revision nope")
+}
+
+func TestWriterStartOrphanBranch(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+	require.ErrorContains(t, w.StartOrphanBranch("master"), "branch master already exists")
+	require.ErrorContains(t, w.StartOrphanBranch("bad..name"), "invalid branch name")
+
+	require.NoError(t, w.StartOrphanBranch("cvs"))
+	require.Empty(t, w.HeadHash())
+	require.NoError(t, w.ApplyCommit(treeTestCommits()[0]))
+	require.Equal(t, hashes[0], w.LastCommitHash(), "the orphan history starts from scratch")
+
+	master, err := w.repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+	require.Equal(t, hashes[2], master.Hash().String(), "the existing branch is kept")
+	count, err := w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestWriterResumeOrphanBranch(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+	require.NoError(t, w.StartOrphanBranch("cvs"))
+	require.NoError(t, w.ApplyCommit(treeTestCommits()[0]))
+
+	// A resumed run opens the repository again and goes on with the branch
+	resumed := NewWriter()
+	require.NoError(t, resumed.Open(w.path))
+	require.ErrorContains(t, resumed.ResumeOrphanBranch("bad..name"), "invalid branch name")
+	require.NoError(t, resumed.ResumeOrphanBranch("cvs"))
+	require.Equal(t, CommitModeObjects, resumed.commitMode)
+	require.Equal(t, hashes[0], resumed.HeadHash())
+	require.NoError(t, resumed.ApplyCommit(treeTestCommits()[1]))
+	require.Equal(t, hashes[1], resumed.LastCommitHash(), "the commit follows the branch tip")
+
+	master, err := resumed.repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+	require.Equal(t, hashes[2], master.Hash().String())
+
+	// A branch without commits yet is started afresh
+	require.NoError(t, resumed.ResumeOrphanBranch("fresh"))
+	require.Empty(t, resumed.HeadHash())
+}
+
+func TestWriterCreateReplaceRef(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+	require.NoError(t, w.CreateReplaceRef(hashes[0], hashes[1]))
+	ref, err := w.repo.Reference(plumbing.ReferenceName("refs/replace/"+hashes[0]), false)
+	require.NoError(t, err)
+	require.Equal(t, hashes[1], ref.Hash().String())
+
+	require.ErrorContains(t, w.CreateReplaceRef("HEAD", hashes[1]), `invalid commit hash "HEAD"`)
+	missing := plumbing.ComputeHash(plumbing.BlobObject, []byte("x")).String()
+	require.ErrorContains(t, w.CreateReplaceRef(hashes[0], missing), "unknown commit "+missing)
+}
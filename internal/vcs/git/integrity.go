@@ -0,0 +1,62 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// CheckIntegrity verifies that the repository contains the commits and that
+// HEAD points at head. The repository is read afresh, so changes made by
+// other processes are seen despite the writer's caches.
+func (w *Writer) CheckIntegrity(hashes []string, head string) error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+	repo := w.repo
+	if w.path != "" {
+		fresh, err := git.PlainOpen(w.path)
+		if err != nil {
+			return fmt.Errorf("failed to open repository: %w", err)
+		}
+		repo = fresh
+	}
+
+	var problems []string
+	var missing []string
+	for _, hash := range hashes {
+		if !plumbing.IsHash(hash) {
+			continue
+		}
+		if _, err := repo.CommitObject(plumbing.NewHash(hash)); err != nil {
+			missing = append(missing, hash)
+		}
+	}
+	if len(missing) > 0 {
+		// A few hashes identify the damage; all of them would flood the log
+		const shown = 5
+		list := strings.Join(missing[:min(len(missing), shown)], ", ")
+		if len(missing) > shown {
+			list += ", ..."
+		}
+		problems = append(problems, fmt.Sprintf("%d mapped commits are missing (%s)", len(missing), list))
+	}
+
+	if head != "" {
+		ref, err := repo.Head()
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("HEAD cannot be resolved: %v", err))
+		case ref.Hash().String() != head:
+			problems = append(problems, fmt.Sprintf("HEAD is %s, expected %s", ref.Hash(), head))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterCheckIntegrity(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+	require.NoError(t, w.CheckIntegrity(hashes, hashes[2]))
+
+	err := w.CheckIntegrity(hashes, hashes[1])
+	require.ErrorContains(t, err, "HEAD is "+hashes[2]+", expected "+hashes[1])
+
+	missing := strings.Repeat("ab", 20)
+	err = w.CheckIntegrity(append(hashes, missing), hashes[2])
+	require.ErrorContains(t, err, "1 mapped commits are missing ("+missing+")")
+
+	// A deleted branch leaves HEAD dangling
+	require.NoError(t, w.repo.Storer.RemoveReference(plumbing.NewBranchReferenceName("master")))
+	require.ErrorContains(t, w.CheckIntegrity(hashes, hashes[2]), "HEAD cannot be resolved")
+}
@@ -0,0 +1,240 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// DefaultRemoteName is the remote used when PushOptions.RemoteName is empty
+const DefaultRemoteName = "origin"
+
+// PushOptions configures pushing the repository to a remote
+type PushOptions struct {
+	URL            string        // Remote URL (HTTPS, SSH or local path)
+	RemoteName     string        // Name of the remote to create or update (default: origin)
+	Username       string        // HTTP username (tokens usually accept any non-empty name)
+	Password       string        // HTTP password or access token
+	SSHKeyPath     string        // Private key for SSH URLs (default: ssh-agent)
+	SSHKeyPassword string        // Passphrase of the SSH key
+	ForceWithLease bool          // Overwrite remote refs, but only if they still hold the values listed before pushing
+	Retries        int           // Additional attempts after a failed push
+	RetryDelay     time.Duration // Delay before the first retry; doubles with each attempt (default: 2s)
+}
+
+// Push pushes all branches and tags to the remote described by opts. The
+// remote is created in the repository configuration if it does not exist,
+// or its URL is updated if it does.
+func (w *Writer) Push(opts PushOptions) error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+	if opts.URL == "" {
+		return fmt.Errorf("remote URL is required")
+	}
+	if opts.RemoteName == "" {
+		opts.RemoteName = DefaultRemoteName
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 2 * time.Second
+	}
+
+	if err := w.ensureRemote(opts.RemoteName, opts.URL); err != nil {
+		return err
+	}
+
+	auth, err := pushAuth(opts)
+	if err != nil {
+		return err
+	}
+
+	refSpecs := []config.RefSpec{"refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*"}
+	pushOpts := &git.PushOptions{
+		RemoteName: opts.RemoteName,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+	}
+	if opts.ForceWithLease {
+		if pushOpts.RefSpecs, pushOpts.RequireRemoteRefs, err = w.leaseRefSpecs(opts.RemoteName, auth); err != nil {
+			return err
+		}
+		if len(pushOpts.RefSpecs) == 0 {
+			return nil
+		}
+	}
+
+	delay := opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err = w.repo.Push(pushOpts)
+		if err == nil || errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+		if strings.Contains(err.Error(), "required to be") {
+			// The remote ref moved since it was listed; retrying would
+			// overwrite the new value
+			err = fmt.Errorf("%w: %w", ErrLeaseBroken, err)
+		}
+		if attempt >= opts.Retries || !isRetryablePushError(err) {
+			return fmt.Errorf("failed to push to %s: %w", opts.RemoteName, err)
+		}
+
+		logging.OrDefault(w.logger).Warn("push failed, retrying",
+			"remote", opts.RemoteName,
+			"attempt", attempt+1,
+			"delay", delay,
+			"error", err,
+		)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// ErrLeaseBroken reports a force-with-lease push rejected because a remote
+// ref changed between listing the remote and pushing
+var ErrLeaseBroken = errors.New("remote ref changed since it was listed")
+
+// leaseRefSpecs lists the refs of the remote and returns the refspecs that
+// force its branches and tags to the local ones, and the remote values they
+// require. Refs the remote lacks are created without forcing, so one made
+// there in the meantime is not overwritten.
+func (w *Writer) leaseRefSpecs(remoteName string, auth transport.AuthMethod) (refSpecs, required []config.RefSpec, err error) {
+	remote, err := w.repo.Remote(remoteName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get remote %s: %w", remoteName, err)
+	}
+	listed, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil, nil, fmt.Errorf("failed to list %s: %w", remoteName, err)
+	}
+	remoteRefs := make(map[plumbing.ReferenceName]plumbing.Hash)
+	for _, ref := range listed {
+		if ref.Type() == plumbing.HashReference {
+			remoteRefs[ref.Name()] = ref.Hash()
+		}
+	}
+
+	refs, err := w.repo.References()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if ref.Type() != plumbing.HashReference || !(name.IsBranch() || name.IsTag()) {
+			return nil
+		}
+		hash, exists := remoteRefs[name]
+		switch {
+		case !exists:
+			refSpecs = append(refSpecs, config.RefSpec(name+":"+name))
+		case hash != ref.Hash():
+			refSpecs = append(refSpecs, config.RefSpec("+"+name+":"+name))
+			required = append(required, config.RefSpec(hash.String()+":"+name.String()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return refSpecs, required, nil
+}
+
+// ensureRemote creates the named remote or points it at remoteURL
+func (w *Writer) ensureRemote(name, remoteURL string) error {
+	remote, err := w.repo.Remote(name)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		_, err = w.repo.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{remoteURL}})
+		if err != nil {
+			return fmt.Errorf("failed to create remote %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get remote %s: %w", name, err)
+	}
+
+	if urls := remote.Config().URLs; len(urls) == 1 && urls[0] == remoteURL {
+		return nil
+	}
+
+	cfg, err := w.repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg.Remotes[name].URLs = []string{remoteURL}
+	if err := w.repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to update remote %s: %w", name, err)
+	}
+	return nil
+}
+
+// pushAuth selects the authentication method for the remote URL
+func pushAuth(opts PushOptions) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote URL: %w", err)
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		if opts.Username == "" && opts.Password == "" {
+			return nil, nil
+		}
+		username := opts.Username
+		if username == "" {
+			username = "git"
+		}
+		return &http.BasicAuth{Username: username, Password: opts.Password}, nil
+
+	case "ssh":
+		if opts.SSHKeyPath == "" {
+			return nil, nil // Fall back to ssh-agent
+		}
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		auth, err := ssh.NewPublicKeysFromFile(user, opts.SSHKeyPath, opts.SSHKeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key: %w", err)
+		}
+		return auth, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// RedactURL hides any password embedded in a remote URL so it can be logged
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// isRetryablePushError reports whether a push may succeed when retried.
+// Authentication, authorization and rejected updates are permanent.
+func isRetryablePushError(err error) bool {
+	switch {
+	case errors.Is(err, ErrLeaseBroken),
+		errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, transport.ErrInvalidAuthMethod),
+		errors.Is(err, git.ErrNonFastForwardUpdate),
+		errors.Is(err, git.ErrForceNeeded):
+		return false
+	}
+	return true
+}
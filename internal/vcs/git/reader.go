@@ -2,18 +2,24 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
 )
 
 // Reader implements VCSReader for Git repositories
 type Reader struct {
 	path string
 	repo *gogit.Repository
+	ctx  context.Context // Stops reading the history early (nil = never)
 }
 
 // NewReader creates a new Git repository reader
@@ -21,16 +27,30 @@ func NewReader(path string) *Reader {
 	return &Reader{path: path}
 }
 
+// SetContext stops the following history reads early once ctx is done
+func (r *Reader) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
 // Validate checks if the Git repository is valid and accessible
 func (r *Reader) Validate() error {
 	repo, err := gogit.PlainOpen(r.path)
 	if err != nil {
-		return fmt.Errorf("failed to open git repository at %s: %w", r.path, err)
+		return fmt.Errorf("failed to open git repository at %s: %w", r.path, notARepo(err))
 	}
 	r.repo = repo
 	return nil
 }
 
+// notARepo returns vcs.ErrNotARepo for the go-git error of a missing
+// repository, and other errors unchanged
+func notARepo(err error) error {
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		return vcs.ErrNotARepo
+	}
+	return err
+}
+
 // GetCommits returns an iterator over all commits (oldest first)
 func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
 	if r.repo == nil {
@@ -53,12 +73,23 @@ func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
 	// Collect commits (Log returns newest first; reverse for oldest first)
 	var commits []*vcs.Commit
 	err = commitIter.ForEach(func(c *object.Commit) error {
+		if err := vcs.ContextErr(r.ctx); err != nil {
+			return err
+		}
+		files, err := r.commitFiles(c)
+		if err != nil {
+			return fmt.Errorf("commit %s: %w", c.Hash, err)
+		}
 		commits = append(commits, &vcs.Commit{
-			Revision: c.Hash.String(),
-			Author:   c.Author.Name,
-			Email:    c.Author.Email,
-			Date:     c.Author.When,
-			Message:  c.Message,
+			Revision:       c.Hash.String(),
+			Author:         c.Author.Name,
+			Email:          c.Author.Email,
+			Date:           c.Author.When,
+			Committer:      c.Committer.Name,
+			CommitterEmail: c.Committer.Email,
+			CommitDate:     c.Committer.When,
+			Message:        c.Message,
+			Files:          files,
 		})
 		return nil
 	})
@@ -74,6 +105,65 @@ func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
 	return &gitCommitIterator{commits: commits}, nil
 }
 
+// commitFiles returns the files changed by c relative to its first parent.
+// File contents are read from the object store on demand.
+func (r *Reader) commitFiles(c *object.Commit) ([]vcs.FileChange, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+	var files []vcs.FileChange
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			return nil, err
+		}
+		if action == merkletrie.Delete {
+			if change.From.TreeEntry.Mode == filemode.Submodule {
+				continue
+			}
+			files = append(files, vcs.FileChange{Path: change.From.Name, Action: vcs.ActionDelete})
+			continue
+		}
+		entry := change.To.TreeEntry
+		if entry.Mode == filemode.Submodule {
+			continue
+		}
+		fc := vcs.FileChange{Path: change.To.Name, Action: vcs.ActionModify, Source: r.blobSource(entry.Hash)}
+		if action == merkletrie.Insert {
+			fc.Action = vcs.ActionAdd
+		}
+		files = append(files, fc)
+	}
+	return files, nil
+}
+
+// blobSource opens the content of the blob hash
+func (r *Reader) blobSource(hash plumbing.Hash) vcs.ContentSource {
+	return func() (io.ReadCloser, error) {
+		blob, err := r.repo.BlobObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		return blob.Reader()
+	}
+}
+
 // GetCommitsSince returns an iterator over commits that come after the given
 // revision hash (exclusive). If revision is empty, all commits are returned.
 func (r *Reader) GetCommitsSince(revision string) (vcs.CommitIterator, error) {
@@ -172,6 +262,30 @@ func (r *Reader) GetHeadRevision() (string, error) {
 	return head.Hash().String(), nil
 }
 
+// GetCommit returns the commit with the given hash, without its files
+func (r *Reader) GetCommit(hash string) (*vcs.Commit, error) {
+	if r.repo == nil {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	return &vcs.Commit{
+		Revision:       c.Hash.String(),
+		Author:         c.Author.Name,
+		Email:          c.Author.Email,
+		Date:           c.Author.When,
+		Committer:      c.Committer.Name,
+		CommitterEmail: c.Committer.Email,
+		CommitDate:     c.Committer.When,
+		Message:        c.Message,
+	}, nil
+}
+
 // Close releases any resources held by the reader
 func (r *Reader) Close() error {
 	return nil
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -74,8 +75,8 @@ func TestGitReaderValidate_Valid(t *testing.T) {
 
 func TestGitReaderValidate_Invalid(t *testing.T) {
 	r := NewReader("/nonexistent/path/12345")
-	if err := r.Validate(); err == nil {
-		t.Error("Validate() should fail on non-existent path")
+	if err := r.Validate(); !errors.Is(err, vcs.ErrNotARepo) {
+		t.Errorf("Validate() error = %v, want vcs.ErrNotARepo", err)
 	}
 }
 
@@ -383,3 +384,88 @@ func TestGitReaderGetHeadRevision_PreValidated(t *testing.T) {
 		t.Errorf("GetHeadRevision() returned %q, want a 40-char SHA", rev)
 	}
 }
+
+func TestGitReaderGetCommits_Committer(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := gogit.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	authored := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	committed := authored.Add(time.Hour)
+	_, err = wt.Commit("applied patch", &gogit.CommitOptions{
+		AllowEmptyCommits: true,
+		Author:            &object.Signature{Name: "Alice", Email: "alice@example.com", When: authored},
+		Committer:         &object.Signature{Name: "Bob", Email: "bob@example.com", When: committed},
+	})
+	require.NoError(t, err)
+
+	iter, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+	require.True(t, iter.Next())
+	c := iter.Commit()
+	require.Equal(t, "Alice", c.Author)
+	require.Equal(t, "Bob", c.Committer)
+	require.Equal(t, "bob@example.com", c.CommitterEmail)
+	require.True(t, committed.Equal(c.CommitDate))
+}
+
+func TestGitReaderGetCommits_Files(t *testing.T) {
+	dir := createTestRepo(t, []struct {
+		file    string
+		content string
+		message string
+	}{
+		{"a.txt", "a", "add a"},
+		{"a.txt", "a2", "modify a"},
+	})
+	repo, err := gogit.PlainOpen(dir)
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = w.Remove("a.txt")
+	require.NoError(t, err)
+	_, err = w.Commit("remove a", &gogit.CommitOptions{Author: &object.Signature{Name: "T", Email: "t@example.com", When: time.Now()}})
+	require.NoError(t, err)
+
+	iter, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+	var commits []*vcs.Commit
+	for iter.Next() {
+		commits = append(commits, iter.Commit())
+	}
+	require.Len(t, commits, 3)
+
+	require.Len(t, commits[0].Files, 1)
+	require.Equal(t, vcs.ActionAdd, commits[0].Files[0].Action)
+	require.Len(t, commits[1].Files, 1)
+	require.Equal(t, vcs.ActionModify, commits[1].Files[0].Action)
+	content, err := commits[1].Files[0].ReadContent()
+	require.NoError(t, err)
+	require.Equal(t, "a2", string(content))
+	require.Equal(t, []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionDelete}}, commits[2].Files)
+}
+
+func TestGitReaderGetCommit(t *testing.T) {
+	dir := createTestRepo(t, []struct {
+		file    string
+		content string
+		message string
+	}{
+		{"a.txt", "a", "first commit"},
+	})
+
+	r := NewReader(dir)
+	head, err := r.GetHeadRevision()
+	require.NoError(t, err)
+
+	commit, err := r.GetCommit(head)
+	require.NoError(t, err)
+	require.Equal(t, head, commit.Revision)
+	require.Contains(t, commit.Message, "first commit")
+	require.Empty(t, commit.Files)
+
+	_, err = r.GetCommit("0000000000000000000000000000000000000000")
+	require.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Repack packs the loose objects that every applied commit leaves behind.
+// With the git command available, "git gc --auto" is run, which only packs
+// once enough loose objects have accumulated, or "git gc" if full is set.
+// Otherwise go-git packs all reachable objects and removes their loose
+// copies.
+func (w *Writer) Repack(full bool) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	if gitPath, err := exec.LookPath("git"); err == nil {
+		return w.gc(gitPath, full)
+	}
+	return w.repackObjects()
+}
+
+// gc runs git gc in the foreground, so that no pack is still being
+// rewritten when the repository is reopened
+func (w *Writer) gc(gitPath string, full bool) error {
+	args := []string{"-C", w.path, "-c", "gc.autoDetach=false", "gc", "--quiet"}
+	if !full {
+		args = append(args, "--auto")
+	}
+	cmd := exec.Command(gitPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git gc failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return w.reopen()
+}
+
+// reopen opens the repository again after its packs were replaced, which
+// the cached pack list of go-git does not notice
+func (w *Writer) reopen() error {
+	repo, err := git.PlainOpen(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to reopen repository after repack: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	w.repo, w.worktree = repo, worktree
+	return nil
+}
+
+// repackObjects packs all objects reachable from the references into one
+// pack and deletes the loose copies of those objects
+func (w *Writer) repackObjects() error {
+	if err := w.repo.RepackObjects(&git.RepackConfig{}); err != nil {
+		return fmt.Errorf("failed to repack objects: %w", err)
+	}
+	if err := w.reopen(); err != nil {
+		return err
+	}
+
+	loose, ok := w.repo.Storer.(storer.LooseObjectStorer)
+	if !ok {
+		return nil
+	}
+	refs, err := w.repo.References()
+	if err != nil {
+		return err
+	}
+	var tips []plumbing.Hash
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			tips = append(tips, ref.Hash())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	packed, err := revlist.Objects(w.repo.Storer, tips, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list packed objects: %w", err)
+	}
+	inPack := make(map[plumbing.Hash]bool, len(packed))
+	for _, h := range packed {
+		inPack[h] = true
+	}
+
+	var stale []plumbing.Hash
+	err = loose.ForEachObjectHash(func(h plumbing.Hash) error {
+		if inPack[h] {
+			stale = append(stale, h)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, h := range stale {
+		if err := loose.DeleteLooseObject(h); err != nil {
+			return fmt.Errorf("failed to delete loose object %s: %w", h, err)
+		}
+	}
+	logging.OrDefault(w.logger).Debug("repacked objects", "objects", len(packed), "loose_removed", len(stale))
+	return nil
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+// applyTestCommits applies n commits, each changing one file
+func applyTestCommits(t *testing.T, w *Writer, from, n int) {
+	t.Helper()
+	for i := from; i < from+n; i++ {
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{
+			Author:  "Test",
+			Email:   "test@example.com",
+			Date:    time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC),
+			Message: fmt.Sprintf("commit %d", i),
+			Files: []vcs.FileChange{{
+				Path:    fmt.Sprintf("dir/file%d.txt", i%3),
+				Action:  vcs.ActionModify,
+				Content: []byte(fmt.Sprintf("content %d", i)),
+			}},
+		}))
+	}
+}
+
+// countObjects returns the number of loose objects and pack files
+func countObjects(t *testing.T, path string) (loose, packs int) {
+	t.Helper()
+	objects := filepath.Join(path, ".git", "objects")
+	entries, err := os.ReadDir(objects)
+	require.NoError(t, err)
+	for _, e := range entries {
+		if len(e.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(objects, e.Name()))
+		require.NoError(t, err)
+		loose += len(files)
+	}
+	packed, err := filepath.Glob(filepath.Join(objects, "pack", "*.pack"))
+	require.NoError(t, err)
+	return loose, len(packed)
+}
+
+func TestWriterRepackObjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo")
+	w := NewWriter()
+	require.NoError(t, w.Init(path))
+	applyTestCommits(t, w, 0, 10)
+
+	loose, packs := countObjects(t, path)
+	require.Greater(t, loose, 0)
+	require.Zero(t, packs)
+
+	require.NoError(t, w.repackObjects())
+	loose, packs = countObjects(t, path)
+	require.Zero(t, loose, "reachable loose objects are removed")
+	require.Equal(t, 1, packs)
+
+	// The repository stays usable
+	applyTestCommits(t, w, 10, 2)
+	count, err := w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 12, count)
+	require.NoError(t, w.repackObjects())
+	count, err = w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 12, count)
+}
+
+func TestWriterRepackGC(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "repo")
+	w := NewWriter()
+	require.NoError(t, w.Init(path))
+	applyTestCommits(t, w, 0, 10)
+
+	// Below the auto threshold nothing is packed
+	require.NoError(t, w.gc(gitPath, false))
+	loose, _ := countObjects(t, path)
+	require.Greater(t, loose, 0)
+
+	require.NoError(t, w.Repack(true))
+	loose, packs := countObjects(t, path)
+	require.Zero(t, loose)
+	require.Equal(t, 1, packs)
+
+	applyTestCommits(t, w, 10, 2)
+	count, err := w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 12, count)
+}
+
+func TestWriterRepackNotInitialized(t *testing.T) {
+	require.Error(t, NewWriter().Repack(false))
+}
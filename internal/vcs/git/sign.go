@@ -0,0 +1,107 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ReadSigningKey reads the first private key of an armored OpenPGP key
+// file, decrypting it with passphrase if it is protected
+func ReadSigningKey(path, passphrase string) (*openpgp.Entity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	for _, key := range keys {
+		if key.PrivateKey == nil {
+			continue
+		}
+		if key.PrivateKey.Encrypted {
+			if passphrase == "" {
+				return nil, fmt.Errorf("signing key %s is protected by a passphrase", path)
+			}
+			if err := key.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt signing key %s: %w", path, err)
+			}
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s holds no private key", path)
+}
+
+// signTag sets the detached signature of a tag the way git tag -s does
+func signTag(tag *object.Tag, key *openpgp.Entity) error {
+	encoded := new(plumbing.MemoryObject)
+	if err := tag.Encode(encoded); err != nil {
+		return err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return err
+	}
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, key, reader, nil); err != nil {
+		return fmt.Errorf("failed to sign tag %s: %w", tag.Name, err)
+	}
+	tag.PGPSignature = signature.String()
+	return nil
+}
+
+// CommitsBetween returns the commits reachable from revision but not from
+// since, newest first; an empty since lists the whole history of revision.
+// Only the metadata and message of the commits are set.
+func (w *Writer) CommitsBetween(since, revision string) ([]*vcs.Commit, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+	exclude := make(map[plumbing.Hash]bool)
+	if since != "" {
+		hash, err := w.resolveTagTarget(since)
+		if err != nil {
+			return nil, err
+		}
+		if exclude, err = w.ancestors(hash.String()); err != nil {
+			return nil, err
+		}
+	}
+	hash, err := w.resolveTagTarget(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*vcs.Commit
+	queue := []plumbing.Hash{hash}
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		if exclude[h] {
+			continue
+		}
+		exclude[h] = true
+		c, err := w.repo.CommitObject(h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", h, err)
+		}
+		commits = append(commits, &vcs.Commit{
+			Revision: h.String(),
+			Author:   c.Author.Name,
+			Email:    c.Author.Email,
+			Date:     c.Author.When,
+			Message:  c.Message,
+		})
+		queue = append(queue, c.ParentHashes...)
+	}
+	sort.SliceStable(commits, func(i, j int) bool { return commits[i].Date.After(commits[j].Date) })
+	return commits, nil
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestSigningKey writes a new armored private key, encrypted when
+// passphrase is set, and returns its path and armored public key
+func writeTestSigningKey(t *testing.T, passphrase string) (string, string) {
+	t.Helper()
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}
+	key, err := openpgp.NewEntity("Release Manager", "", "release@corp.example", config)
+	require.NoError(t, err)
+
+	var public strings.Builder
+	w, err := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, key.Serialize(w))
+	require.NoError(t, w.Close())
+
+	if passphrase != "" {
+		require.NoError(t, key.EncryptPrivateKeys([]byte(passphrase), config))
+	}
+	var private strings.Builder
+	w, err = armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, key.SerializePrivateWithoutSigning(w, nil))
+	require.NoError(t, w.Close())
+
+	path := filepath.Join(t.TempDir(), "release.asc")
+	require.NoError(t, os.WriteFile(path, []byte(private.String()), 0o600))
+	return path, public.String()
+}
+
+func TestReadSigningKey(t *testing.T) {
+	path, _ := writeTestSigningKey(t, "secret")
+	_, err := ReadSigningKey(path, "")
+	require.ErrorContains(t, err, "protected by a passphrase")
+	_, err = ReadSigningKey(path, "wrong")
+	require.ErrorContains(t, err, "failed to decrypt")
+	key, err := ReadSigningKey(path, "secret")
+	require.NoError(t, err)
+	require.False(t, key.PrivateKey.Encrypted)
+
+	_, err = ReadSigningKey(filepath.Join(t.TempDir(), "missing.asc"), "")
+	require.ErrorContains(t, err, "failed to read signing key")
+}
+
+func TestWriterSignedTagAndCommitsBetween(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	w := NewWriter()
+	require.NoError(t, w.Init(repoPath))
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var hashes []string
+	for i, message := range []string{"first\n\nbody\n", "second\n", "third\n"} {
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{
+			Author: "Alice", Email: "alice@corp.example", Date: date.Add(time.Duration(i) * time.Hour), Message: message,
+			Files: []vcs.FileChange{{Path: "a.txt", Action: vcs.ActionModify, Content: []byte(message)}},
+		}))
+		hashes = append(hashes, w.LastCommitHash())
+	}
+
+	commits, err := w.CommitsBetween("", hashes[1])
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	require.Equal(t, hashes[1], commits[0].Revision)
+	require.Equal(t, "first\n\nbody\n", commits[1].Message)
+	commits, err = w.CommitsBetween(hashes[0], "HEAD")
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	require.Equal(t, "third\n", commits[0].Message)
+	require.Equal(t, "Alice", commits[0].Author)
+
+	keyPath, public := writeTestSigningKey(t, "")
+	key, err := ReadSigningKey(keyPath, "")
+	require.NoError(t, err)
+	require.NoError(t, w.CreateAnnotatedTag("v1.0", hashes[2], TagOptions{Message: "Release v1.0\n", SignKey: key}))
+
+	repo, err := git.PlainOpen(repoPath)
+	require.NoError(t, err)
+	ref, err := repo.Reference(plumbing.NewTagReferenceName("v1.0"), true)
+	require.NoError(t, err)
+	tag, err := repo.TagObject(ref.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "Release v1.0\n", tag.Message)
+	require.Contains(t, tag.PGPSignature, "BEGIN PGP SIGNATURE")
+	signer, err := tag.Verify(public)
+	require.NoError(t, err)
+	require.Equal(t, key.PrimaryKey.KeyId, signer.PrimaryKey.KeyId)
+}
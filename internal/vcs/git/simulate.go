@@ -0,0 +1,146 @@
+package git
+
+import (
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// InitMemory creates a repository held in memory, for simulating a
+// migration without writing to disk. Commits are built in the object store
+// as in CommitModeObjects; the repository is gone once the writer is.
+func (w *Writer) InitMemory() error {
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		return fmt.Errorf("failed to init in-memory repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	w.path = ""
+	w.repo = repo
+	w.worktree = worktree
+	w.commitMode = CommitModeObjects
+	w.tree = treeBuilder{}
+	return nil
+}
+
+// RepoStats describes the objects and references of a repository
+type RepoStats struct {
+	Commits    int               // Commit objects
+	Trees      int               // Tree objects
+	Blobs      int               // Blob objects
+	Tags       int               // Annotated tag objects
+	RawBytes   int64             // Uncompressed size of all objects
+	PackBytes  int64             // Estimated size of a pack holding all objects
+	Refs       map[string]string // Reference name -> hash, HEAD included
+	HeadTree   string            // Tree of the HEAD commit (empty = no commits)
+	HeadCommit string
+}
+
+// packEntryOverhead approximates the pack header of an object
+const packEntryOverhead = 4
+
+// Stats counts the objects of the repository and estimates the size of a
+// pack holding them. Each object is compressed on its own, as without
+// deltas, so the estimate is an upper bound of what git gc writes.
+func (w *Writer) Stats() (*RepoStats, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+	stats := &RepoStats{Refs: make(map[string]string)}
+
+	iter, err := w.repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	err = iter.ForEach(func(obj plumbing.EncodedObject) error {
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			stats.Commits++
+		case plumbing.TreeObject:
+			stats.Trees++
+		case plumbing.BlobObject:
+			stats.Blobs++
+		case plumbing.TagObject:
+			stats.Tags++
+		}
+		stats.RawBytes += obj.Size()
+		compressed, err := compressedSize(obj)
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %w", obj.Hash(), err)
+		}
+		stats.PackBytes += compressed + packEntryOverhead
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats.Commits+stats.Trees+stats.Blobs+stats.Tags > 0 {
+		// Pack header and trailing checksum
+		stats.PackBytes += 12 + 20
+	}
+
+	refs, err := w.repo.Storer.IterReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			stats.Refs[ref.Name().String()] = ref.Hash().String()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := w.repo.Head()
+	if err == nil {
+		commit, err := w.repo.CommitObject(head.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HEAD commit: %w", err)
+		}
+		stats.Refs[plumbing.HEAD.String()] = head.Hash().String()
+		stats.HeadCommit = head.Hash().String()
+		stats.HeadTree = commit.TreeHash.String()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return stats, nil
+}
+
+// compressedSize returns the zlib-compressed size of an object's content
+func compressedSize(obj plumbing.EncodedObject) (int64, error) {
+	r, err := obj.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = r.Close() }()
+	var counter countingWriter
+	zw := zlib.NewWriter(&counter)
+	if _, err := io.Copy(zw, r); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterInitMemory(t *testing.T) {
+	_, want := writeTreeTestRepo(t, CommitModeWorktree)
+
+	w := NewWriter()
+	require.NoError(t, w.InitMemory())
+	stats, err := w.Stats()
+	require.NoError(t, err)
+	require.Zero(t, stats.Commits)
+	require.Zero(t, stats.PackBytes)
+	require.Empty(t, stats.HeadTree)
+
+	var got []string
+	for _, c := range treeTestCommits() {
+		require.NoError(t, w.ApplyCommit(c))
+		got = append(got, w.LastCommitHash())
+	}
+	require.Equal(t, want, got, "the in-memory repository holds the same commits")
+	require.NoError(t, w.CreateTag("v1", "HEAD", ""))
+
+	stats, err = w.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.Commits)
+	require.Equal(t, 7, stats.Blobs)
+	require.Zero(t, stats.Tags)
+	require.Positive(t, stats.RawBytes)
+	require.Positive(t, stats.PackBytes)
+	require.Equal(t, want[2], stats.HeadCommit)
+	require.Equal(t, want[2], stats.Refs["refs/tags/v1"])
+	require.Equal(t, want[2], stats.Refs["HEAD"])
+
+	head, err := w.repo.CommitObject(w.lastCommit)
+	require.NoError(t, err)
+	require.Equal(t, head.TreeHash.String(), stats.HeadTree)
+}
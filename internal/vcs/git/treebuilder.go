@@ -0,0 +1,251 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Commit modes of the writer
+const (
+	// CommitModeWorktree writes every file into the working tree and commits
+	// through the index, like a user running git would
+	CommitModeWorktree = "worktree"
+
+	// CommitModeObjects builds blobs, trees and commits directly in the
+	// object store; the working tree stays empty
+	CommitModeObjects = "objects"
+)
+
+// treeNode is a file or directory of the tree under construction. A
+// directory's children are loaded from its tree object on first access, and
+// its hash is zero while it has changes that are not written yet.
+type treeNode struct {
+	mode     filemode.FileMode
+	hash     plumbing.Hash
+	children map[string]*treeNode // Directories only, nil until loaded
+}
+
+func (n *treeNode) isDir() bool {
+	return n.mode == filemode.Dir
+}
+
+// treeBuilder applies file changes to the tree of the last commit without a
+// working tree. Only directories on changed paths are loaded and rewritten.
+type treeBuilder struct {
+	root *treeNode
+	base plumbing.Hash // Commit the tree belongs to
+}
+
+// reset points the builder at the tree of commit, or at an empty tree for
+// the zero hash
+func (b *treeBuilder) reset(s storer.EncodedObjectStorer, commit plumbing.Hash) error {
+	b.root = &treeNode{mode: filemode.Dir, children: map[string]*treeNode{}}
+	b.base = commit
+	if commit.IsZero() {
+		return nil
+	}
+	c, err := object.GetCommit(s, commit)
+	if err != nil {
+		return fmt.Errorf("failed to get commit %s: %w", commit, err)
+	}
+	b.root.hash, b.root.children = c.TreeHash, nil
+	return nil
+}
+
+// load reads the entries of a directory from its tree object
+func (b *treeBuilder) load(s storer.EncodedObjectStorer, dir *treeNode) error {
+	if dir.children != nil {
+		return nil
+	}
+	tree, err := object.GetTree(s, dir.hash)
+	if err != nil {
+		return fmt.Errorf("failed to read tree %s: %w", dir.hash, err)
+	}
+	dir.children = make(map[string]*treeNode, len(tree.Entries))
+	for _, e := range tree.Entries {
+		dir.children[e.Name] = &treeNode{mode: e.Mode, hash: e.Hash}
+	}
+	return nil
+}
+
+// dirs returns the directories leading to path, root first, creating missing
+// ones if create is set. It returns nil if a directory is missing.
+func (b *treeBuilder) dirs(s storer.EncodedObjectStorer, path string, create bool) ([]*treeNode, error) {
+	parts := strings.Split(path, "/")
+	dirs := []*treeNode{b.root}
+	for _, name := range parts[:len(parts)-1] {
+		dir := dirs[len(dirs)-1]
+		if err := b.load(s, dir); err != nil {
+			return nil, err
+		}
+		child, ok := dir.children[name]
+		switch {
+		case ok && !child.isDir():
+			if !create {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%s: parent is a file", path)
+		case !ok:
+			if !create {
+				return nil, nil
+			}
+			child = &treeNode{mode: filemode.Dir, children: map[string]*treeNode{}}
+			dir.children[name] = child
+		}
+		dirs = append(dirs, child)
+	}
+	if err := b.load(s, dirs[len(dirs)-1]); err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// set stores the file content as a blob at path. An existing file keeps its
+// mode; new files are regular files.
+func (b *treeBuilder) set(s storer.EncodedObjectStorer, fc *vcs.FileChange) error {
+	hash, err := writeBlob(s, fc)
+	if err != nil {
+		return err
+	}
+	dirs, err := b.dirs(s, fc.Path, true)
+	if err != nil {
+		return err
+	}
+	name := fc.Path[strings.LastIndex(fc.Path, "/")+1:]
+	parent := dirs[len(dirs)-1]
+	mode := filemode.Regular
+	if old, ok := parent.children[name]; ok {
+		if old.isDir() {
+			return fmt.Errorf("%s: is a directory", fc.Path)
+		}
+		if old.hash == hash {
+			return nil
+		}
+		mode = old.mode
+	}
+	parent.children[name] = &treeNode{mode: mode, hash: hash}
+	for _, dir := range dirs {
+		dir.hash = plumbing.ZeroHash
+	}
+	return nil
+}
+
+// setEntry points path at an existing object, replacing whatever is there.
+// A directory entry takes its children from the tree object hash.
+func (b *treeBuilder) setEntry(s storer.EncodedObjectStorer, path string, mode filemode.FileMode, hash plumbing.Hash) error {
+	dirs, err := b.dirs(s, path, true)
+	if err != nil {
+		return err
+	}
+	name := path[strings.LastIndex(path, "/")+1:]
+	dirs[len(dirs)-1].children[name] = &treeNode{mode: mode, hash: hash}
+	for _, dir := range dirs {
+		dir.hash = plumbing.ZeroHash
+	}
+	return nil
+}
+
+// remove deletes the file at path, and the directories it leaves empty. It
+// reports whether the file existed.
+func (b *treeBuilder) remove(s storer.EncodedObjectStorer, path string) (bool, error) {
+	dirs, err := b.dirs(s, path, false)
+	if err != nil || dirs == nil {
+		return false, err
+	}
+	name := path[strings.LastIndex(path, "/")+1:]
+	if old, ok := dirs[len(dirs)-1].children[name]; !ok || old.isDir() {
+		return false, nil
+	}
+	delete(dirs[len(dirs)-1].children, name)
+
+	parts := strings.Split(path, "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dirs[i].hash = plumbing.ZeroHash
+		if i > 0 && len(dirs[i].children) == 0 {
+			delete(dirs[i-1].children, parts[i-1])
+		}
+	}
+	return true, nil
+}
+
+// write stores the tree objects of all changed directories and returns the
+// hash of the root tree
+func (b *treeBuilder) write(s storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	return writeTree(s, b.root)
+}
+
+func writeTree(s storer.EncodedObjectStorer, dir *treeNode) (plumbing.Hash, error) {
+	if !dir.hash.IsZero() {
+		return dir.hash, nil
+	}
+
+	tree := &object.Tree{}
+	for name, child := range dir.children {
+		if child.isDir() {
+			hash, err := writeTree(s, child)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			child.hash = hash
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: child.mode, Hash: child.hash})
+	}
+	// Git sorts directories as if their names ended with a slash
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return treeEntryKey(tree.Entries[i]) < treeEntryKey(tree.Entries[j])
+	})
+
+	obj := s.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode tree: %w", err)
+	}
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store tree: %w", err)
+	}
+	dir.hash = hash
+	return hash, nil
+}
+
+func treeEntryKey(e object.TreeEntry) string {
+	if e.Mode == filemode.Dir {
+		return e.Name + "/"
+	}
+	return e.Name
+}
+
+// writeBlob stores the content of a file change as a blob object
+func writeBlob(s storer.EncodedObjectStorer, fc *vcs.FileChange) (plumbing.Hash, error) {
+	src, err := fc.Open()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer func() { _ = src.Close() }()
+
+	obj := s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	dst, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := dst.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store blob: %w", err)
+	}
+	return hash, nil
+}
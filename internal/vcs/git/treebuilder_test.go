@@ -0,0 +1,135 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+// treeTestCommits returns commits that add, modify and delete files in
+// nested directories, emptying one directory entirely
+func treeTestCommits() []*vcs.Commit {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(path, content string) vcs.FileChange {
+		return vcs.FileChange{Path: path, Action: vcs.ActionAdd, Content: []byte(content)}
+	}
+	return []*vcs.Commit{
+		{Revision: "1", Author: "Alice", Email: "alice@example.com", Date: date, Message: "initial\n", Files: []vcs.FileChange{
+			add("README", "readme\n"), add("src/main.c", "int x;\n"), add("src/lib/util.c", "util\n"),
+			add("src/lib.h", "header\n"), add("doc/old.txt", "old\n"),
+		}},
+		{Revision: "2", Author: "Bob", Email: "bob@example.com", Date: date.Add(time.Hour), Message: "second\n", Files: []vcs.FileChange{
+			{Path: "src/main.c", Action: vcs.ActionModify, Content: []byte("int y;\n")},
+			{Path: "doc/old.txt", Action: vcs.ActionDelete},
+			{Path: "missing.txt", Action: vcs.ActionDelete},
+			add("src/lib/deep/more.c", "more\n"),
+		}},
+		{Revision: "3", Author: "Alice", Email: "alice@example.com", Date: date.Add(2 * time.Hour), Message: "unchanged\n", Files: []vcs.FileChange{
+			add("README", "readme\n"),
+		}},
+	}
+}
+
+func writeTreeTestRepo(t *testing.T, mode string) (*Writer, []string) {
+	t.Helper()
+	w := NewWriter()
+	require.NoError(t, w.SetCommitMode(mode))
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+	var hashes []string
+	for _, c := range treeTestCommits() {
+		require.NoError(t, w.ApplyCommit(c))
+		hashes = append(hashes, w.LastCommitHash())
+	}
+	return w, hashes
+}
+
+func TestWriterCommitModeObjects(t *testing.T) {
+	_, want := writeTreeTestRepo(t, CommitModeWorktree)
+	w, got := writeTreeTestRepo(t, CommitModeObjects)
+	require.Equal(t, want, got, "both modes write identical commits")
+
+	// Nothing but .git is written to the working tree
+	entries, err := os.ReadDir(w.path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ".git", entries[0].Name())
+
+	head, err := w.repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, got[len(got)-1], head.Hash().String())
+	commit, err := w.repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	_, err = tree.Tree("doc")
+	require.Error(t, err, "emptied directories are removed")
+	require.Equal(t, "more\n", readFile(t, tree, "src/lib/deep/more.c"))
+
+	// A reopened writer continues from HEAD
+	require.NoError(t, w.reopen())
+	w.tree = treeBuilder{}
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Alice", Date: time.Now(), Message: "fourth\n", Files: []vcs.FileChange{
+		{Path: "src/lib/util.c", Action: vcs.ActionDelete},
+	}}))
+	commit, err = w.repo.CommitObject(w.lastCommit)
+	require.NoError(t, err)
+	tree, err = commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "int y;\n", readFile(t, tree, "src/main.c"))
+	_, err = tree.File("src/lib/util.c")
+	require.Error(t, err)
+}
+
+func TestWriterCommitModeObjectsBranches(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeObjects)
+
+	// Switching to a branch at an older commit builds on that commit's tree
+	require.NoError(t, w.CreateBranch("old", hashes[0]))
+	require.NoError(t, w.SetDefaultBranch("old"))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Alice", Date: time.Now(), Message: "on old\n", Files: []vcs.FileChange{
+		{Path: "new.txt", Action: vcs.ActionAdd, Content: []byte("new\n")},
+	}}))
+	commit, err := w.repo.CommitObject(w.lastCommit)
+	require.NoError(t, err)
+	require.Equal(t, hashes[0], commit.ParentHashes[0].String())
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "old\n", readFile(t, tree, "doc/old.txt"))
+	ref, err := w.repo.Reference("refs/heads/old", false)
+	require.NoError(t, err)
+	require.Equal(t, commit.Hash, ref.Hash())
+
+	// A path below an existing file is rejected and leaves HEAD alone
+	err = w.ApplyCommit(&vcs.Commit{Author: "Alice", Date: time.Now(), Message: "bad\n", Files: []vcs.FileChange{
+		{Path: "README/x", Action: vcs.ActionAdd, Content: []byte("x")},
+	}})
+	require.ErrorContains(t, err, "parent is a file")
+	require.Equal(t, commit.Hash.String(), w.HeadHash())
+}
+
+func TestWriterSetCommitMode(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.SetCommitMode(""))
+	require.Equal(t, CommitModeWorktree, w.commitMode)
+	require.Error(t, w.SetCommitMode("index"))
+
+	target, err := vcs.NewWriter("git", map[string]string{"commitMode": CommitModeObjects})
+	require.NoError(t, err)
+	require.Equal(t, CommitModeObjects, target.(*Writer).commitMode)
+	_, err = vcs.NewWriter("git", map[string]string{"commitMode": "index"})
+	require.Error(t, err)
+}
+
+func readFile(t *testing.T, tree *object.Tree, path string) string {
+	t.Helper()
+	f, err := tree.File(path)
+	require.NoError(t, err)
+	content, err := f.Contents()
+	require.NoError(t, err)
+	return content
+}
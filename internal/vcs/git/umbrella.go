@@ -0,0 +1,240 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Ways of wiring modules into an umbrella repository
+const (
+	// UmbrellaSubmodule records every module as a submodule pinned to its
+	// migrated head
+	UmbrellaSubmodule = "submodule"
+
+	// UmbrellaSubtree merges the history of every module into a
+	// subdirectory, like git subtree add
+	UmbrellaSubtree = "subtree"
+)
+
+// UmbrellaModule is a migrated repository wired into an umbrella repository
+type UmbrellaModule struct {
+	Name       string // Module name, used in .gitmodules
+	Path       string // Directory in the umbrella repository
+	Repository string // Path of the migrated repository
+	URL        string // Submodule URL (default: Repository)
+}
+
+// UmbrellaOptions controls how an umbrella repository is written
+type UmbrellaOptions struct {
+	Mode  string // UmbrellaSubmodule (default) or UmbrellaSubtree
+	Name  string // Author and committer of the umbrella commit (default: git-migrator)
+	Email string
+}
+
+// UmbrellaResult describes the commit written to an umbrella repository
+type UmbrellaResult struct {
+	Commit string            // Umbrella commit ("" if nothing changed)
+	Heads  map[string]string // Module name -> recorded module commit
+}
+
+// WriteUmbrella creates or updates the repository at path so that it holds
+// every module at its path, pinned to the module's current HEAD. Rerunning
+// it after the modules changed records their new heads in a new commit. The
+// commit is dated like the newest module head, so the result only depends
+// on the modules.
+func WriteUmbrella(path string, modules []UmbrellaModule, opts UmbrellaOptions) (*UmbrellaResult, error) {
+	switch opts.Mode {
+	case "":
+		opts.Mode = UmbrellaSubmodule
+	case UmbrellaSubmodule, UmbrellaSubtree:
+	default:
+		return nil, fmt.Errorf("invalid umbrella mode %q: must be %s or %s", opts.Mode, UmbrellaSubmodule, UmbrellaSubtree)
+	}
+	if opts.Name == "" {
+		opts.Name = "git-migrator"
+	}
+
+	repo, err := openOrInit(path)
+	if err != nil {
+		return nil, err
+	}
+	s := repo.Storer
+
+	var head plumbing.Hash
+	if ref, err := repo.Head(); err == nil {
+		head = ref.Hash()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	var tree treeBuilder
+	if err := tree.reset(s, head); err != nil {
+		return nil, err
+	}
+
+	result := &UmbrellaResult{Heads: make(map[string]string)}
+	var parents []plumbing.Hash
+	if !head.IsZero() {
+		parents = append(parents, head)
+	}
+	var when time.Time
+	var gitmodules strings.Builder
+	for _, module := range modules {
+		src, err := git.PlainOpen(module.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open module %s: %w", module.Name, err)
+		}
+		ref, err := src.Head()
+		if err != nil {
+			return nil, fmt.Errorf("module %s has no commits: %w", module.Name, err)
+		}
+		commit, err := src.CommitObject(ref.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read head of module %s: %w", module.Name, err)
+		}
+		if commit.Committer.When.After(when) {
+			when = commit.Committer.When
+		}
+		result.Heads[module.Name] = commit.Hash.String()
+
+		if opts.Mode == UmbrellaSubmodule {
+			url := module.URL
+			if url == "" {
+				url = module.Repository
+			}
+			fmt.Fprintf(&gitmodules, "[submodule %q]\n\tpath = %s\n\turl = %s\n", module.Name, module.Path, url)
+			if err := tree.setEntry(s, module.Path, filemode.Submodule, commit.Hash); err != nil {
+				return nil, fmt.Errorf("module %s: %w", module.Name, err)
+			}
+			continue
+		}
+
+		// A subtree brings the module's objects along and merges its history
+		if err := copyObjects(src, repo); err != nil {
+			return nil, fmt.Errorf("failed to copy module %s: %w", module.Name, err)
+		}
+		if err := tree.setEntry(s, module.Path, filemode.Dir, commit.TreeHash); err != nil {
+			return nil, fmt.Errorf("module %s: %w", module.Name, err)
+		}
+		if !isAncestor(repo, commit.Hash, head) {
+			parents = append(parents, commit.Hash)
+		}
+	}
+	if gitmodules.Len() > 0 {
+		fc := &vcs.FileChange{Path: ".gitmodules", Content: []byte(gitmodules.String())}
+		if err := tree.set(s, fc); err != nil {
+			return nil, err
+		}
+	}
+
+	treeHash, err := tree.write(s)
+	if err != nil {
+		return nil, err
+	}
+	if !head.IsZero() && len(parents) == 1 {
+		if c, err := repo.CommitObject(head); err == nil && c.TreeHash == treeHash {
+			return result, nil
+		}
+	}
+
+	names := make([]string, 0, len(modules))
+	for _, module := range modules {
+		names = append(names, module.Name)
+	}
+	sort.Strings(names)
+	sig := object.Signature{Name: opts.Name, Email: opts.Email, When: when}
+	c := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      fmt.Sprintf("Add migrated modules as %ss\n\nModules: %s\n", opts.Mode, strings.Join(names, ", ")),
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+	obj := s.NewEncodedObject()
+	if err := c.Encode(obj); err != nil {
+		return nil, fmt.Errorf("failed to encode commit: %w", err)
+	}
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	target := plumbing.HEAD
+	if ref, err := s.Reference(plumbing.HEAD); err == nil && ref.Type() == plumbing.SymbolicReference {
+		target = ref.Target()
+	}
+	if err := s.SetReference(plumbing.NewHashReference(target, hash)); err != nil {
+		return nil, fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: hash, Mode: git.HardReset}); err != nil {
+		return nil, fmt.Errorf("failed to check out umbrella commit: %w", err)
+	}
+	result.Commit = hash.String()
+	return result, nil
+}
+
+// openOrInit opens the repository at path, creating it if it does not exist
+func openOrInit(path string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(path)
+	if err == nil {
+		return repo, nil
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	repo, err = git.PlainInit(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init repository: %w", err)
+	}
+	return repo, nil
+}
+
+// copyObjects stores every object of src in dst
+func copyObjects(src, dst *git.Repository) error {
+	iter, err := src.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	return iter.ForEach(func(obj plumbing.EncodedObject) error {
+		if dst.Storer.HasEncodedObject(obj.Hash()) == nil {
+			return nil
+		}
+		_, err := dst.Storer.SetEncodedObject(obj)
+		return err
+	})
+}
+
+// isAncestor reports whether commit is reachable from head
+func isAncestor(repo *git.Repository, commit, head plumbing.Hash) bool {
+	if head.IsZero() {
+		return false
+	}
+	c, err := repo.CommitObject(commit)
+	if err != nil {
+		return false
+	}
+	h, err := repo.CommitObject(head)
+	if err != nil {
+		return false
+	}
+	ok, err := c.IsAncestor(h)
+	return err == nil && ok
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/stretchr/testify/require"
+)
+
+// writeUmbrellaModules creates the repositories of two modules with one
+// commit each
+func writeUmbrellaModules(t *testing.T) []UmbrellaModule {
+	t.Helper()
+	dir := t.TempDir()
+	var modules []UmbrellaModule
+	for i, name := range []string{"libfoo", "tools"} {
+		w := NewWriter()
+		repo := filepath.Join(dir, name)
+		require.NoError(t, w.Init(repo))
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{
+			Author:  "Alice",
+			Date:    time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC),
+			Message: "initial\n",
+			Files:   []vcs.FileChange{{Path: "README", Action: vcs.ActionAdd, Content: []byte(name + "\n")}},
+		}))
+		modules = append(modules, UmbrellaModule{Name: name, Path: "modules/" + name, Repository: repo})
+	}
+	return modules
+}
+
+func TestWriteUmbrellaSubmodules(t *testing.T) {
+	modules := writeUmbrellaModules(t)
+	modules[1].URL = "git@example.com:tools.git"
+	path := filepath.Join(t.TempDir(), "umbrella")
+
+	result, err := WriteUmbrella(path, modules, UmbrellaOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Commit)
+
+	repo, err := git.PlainOpen(path)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(plumbing.NewHash(result.Commit))
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), commit.Committer.When.UTC())
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	entry, err := tree.FindEntry("modules/libfoo")
+	require.NoError(t, err)
+	require.Equal(t, filemode.Submodule, entry.Mode)
+	require.Equal(t, result.Heads["libfoo"], entry.Hash.String())
+	require.Equal(t, "[submodule \"libfoo\"]\n\tpath = modules/libfoo\n\turl = "+modules[0].Repository+"\n"+
+		"[submodule \"tools\"]\n\tpath = modules/tools\n\turl = git@example.com:tools.git\n",
+		readFile(t, tree, ".gitmodules"))
+
+	// Nothing changed, so no new commit
+	again, err := WriteUmbrella(path, modules, UmbrellaOptions{})
+	require.NoError(t, err)
+	require.Empty(t, again.Commit)
+}
+
+func TestWriteUmbrellaSubtrees(t *testing.T) {
+	modules := writeUmbrellaModules(t)
+	path := filepath.Join(t.TempDir(), "umbrella")
+
+	result, err := WriteUmbrella(path, modules, UmbrellaOptions{Mode: UmbrellaSubtree, Name: "Migrator"})
+	require.NoError(t, err)
+	repo, err := git.PlainOpen(path)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(plumbing.NewHash(result.Commit))
+	require.NoError(t, err)
+	require.Len(t, commit.ParentHashes, 2, "the module histories are merged")
+	require.Equal(t, "Migrator", commit.Author.Name)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+	require.Equal(t, "tools\n", readFile(t, tree, "modules/tools/README"))
+
+	data, err := os.ReadFile(filepath.Join(path, "modules", "libfoo", "README"))
+	require.NoError(t, err)
+	require.Equal(t, "libfoo\n", string(data))
+
+	// A new module commit is merged on the next run
+	w := NewWriter()
+	require.NoError(t, w.Open(modules[0].Repository))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Bob", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Message: "more\n",
+		Files: []vcs.FileChange{{Path: "NEWS", Action: vcs.ActionAdd, Content: []byte("news\n")}}}))
+	again, err := WriteUmbrella(path, modules, UmbrellaOptions{Mode: UmbrellaSubtree})
+	require.NoError(t, err)
+	commit, err = repo.CommitObject(plumbing.NewHash(again.Commit))
+	require.NoError(t, err)
+	require.Equal(t, []string{result.Commit, w.LastCommitHash()}, []string{commit.ParentHashes[0].String(), commit.ParentHashes[1].String()})
+
+	_, err = WriteUmbrella(path, modules, UmbrellaOptions{Mode: "copy"})
+	require.Error(t, err)
+}
@@ -2,14 +2,23 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/adamf123git/git-migrator/internal/logging"
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
 )
@@ -20,13 +29,60 @@ type Writer struct {
 	repo       *git.Repository
 	worktree   *git.Worktree
 	lastCommit plumbing.Hash
+	revisions  map[string]plumbing.Hash // Source revision -> applied commit
+	commitMode string
+	tree       treeBuilder // Tree of the last commit, in CommitModeObjects
+	readOnly   bool        // Opened with OpenReadOnly
+	logger     *slog.Logger
+
+	ctx context.Context // Stops commits and ref updates early (nil = never)
 }
 
+// ErrReadOnly is returned by operations that modify a repository opened
+// with OpenReadOnly
+var ErrReadOnly = errors.New("repository is opened read-only")
+
 // NewWriter creates a new Git repository writer
 func NewWriter() *Writer {
 	return &Writer{}
 }
 
+func init() {
+	vcs.RegisterWriter("git", func(options map[string]string) (vcs.VCSWriter, error) {
+		w := NewWriter()
+		if err := w.SetCommitMode(options["commitMode"]); err != nil {
+			return nil, err
+		}
+		return w, nil
+	})
+}
+
+// SetCommitMode selects how ApplyCommit builds commits: CommitModeWorktree
+// (the default, also selected by "") or CommitModeObjects
+func (w *Writer) SetCommitMode(mode string) error {
+	switch mode {
+	case "", CommitModeWorktree:
+		w.commitMode = CommitModeWorktree
+	case CommitModeObjects:
+		w.commitMode = CommitModeObjects
+	default:
+		return fmt.Errorf("unknown commit mode %q (want %s or %s)", mode, CommitModeWorktree, CommitModeObjects)
+	}
+	w.tree = treeBuilder{}
+	return nil
+}
+
+// SetLogger sets the logger used for diagnostic output
+func (w *Writer) SetLogger(logger *slog.Logger) {
+	w.logger = logger
+}
+
+// SetContext stops the following commits and ref updates early, before
+// anything is committed, once ctx is done
+func (w *Writer) SetContext(ctx context.Context) {
+	w.ctx = ctx
+}
+
 // Init creates a new repository at the given path
 func (w *Writer) Init(path string) error {
 	// Create directory if needed
@@ -84,8 +140,8 @@ func (w *Writer) InitWithConfig(path string, cfg map[string]string) error {
 
 // SetConfig sets a configuration value
 func (w *Writer) SetConfig(key, value string) error {
-	if w.repo == nil {
-		return fmt.Errorf("repository not initialized")
+	if err := w.writable(); err != nil {
+		return err
 	}
 
 	cfg, err := w.repo.Config()
@@ -151,74 +207,369 @@ func (w *Writer) IsRepo(path string) bool {
 
 // ApplyCommit applies a commit to the repository
 func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
-	if w.repo == nil || w.worktree == nil {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	if w.worktree == nil {
 		return fmt.Errorf("repository not initialized")
 	}
 
+	// Resolve merged commits before touching the worktree
+	var parents []plumbing.Hash
+	if len(commit.Parents) > 0 {
+		var err error
+		if parents, err = w.mergeParents(commit.Parents); err != nil {
+			return err
+		}
+	}
+
+	committer, committerEmail, commitDate := commit.CommitterIdentity()
+	author := &object.Signature{Name: commit.Author, Email: commit.Email, When: commit.Date}
+	committerSig := &object.Signature{Name: committer, Email: committerEmail, When: commitDate}
+
+	var hash plumbing.Hash
+	var err error
+	if w.commitMode == CommitModeObjects {
+		hash, err = w.commitObjects(commit, author, committerSig, parents)
+	} else {
+		hash, err = w.commitWorktree(commit, author, committerSig, parents)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.lastCommit = hash
+	if commit.Revision != "" {
+		if w.revisions == nil {
+			w.revisions = make(map[string]plumbing.Hash)
+		}
+		w.revisions[commit.Revision] = hash
+	}
+	return nil
+}
+
+// commitWorktree writes the file changes into the working tree, stages them
+// and commits the index. A failure resets both to HEAD, so that the files
+// already written are not committed with the next commit.
+func (w *Writer) commitWorktree(commit *vcs.Commit, author, committer *object.Signature, parents []plumbing.Hash) (hash plumbing.Hash, err error) {
+	defer func() {
+		if err == nil {
+			return
+		}
+		if resetErr := w.discardFiles(commit.Files); resetErr != nil {
+			logging.OrDefault(w.logger).Warn("failed to reset the worktree after a failed commit", "error", resetErr)
+		}
+	}()
+
 	// Process file changes
 	for _, fc := range commit.Files {
+		if err := vcs.ContextErr(w.ctx); err != nil {
+			return plumbing.ZeroHash, err
+		}
 		fullPath := filepath.Join(w.path, fc.Path)
 
 		switch fc.Action {
-		case vcs.ActionAdd, vcs.ActionModify:
+		case vcs.ActionAdd, vcs.ActionModify, vcs.ActionRename, vcs.ActionCopy:
 			// Create directory if needed
 			dir := filepath.Dir(fullPath)
 			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
+				return plumbing.ZeroHash, fmt.Errorf("failed to create directory: %w", err)
 			}
 
-			// Write file
-			if err := os.WriteFile(fullPath, fc.Content, 0644); err != nil {
-				return fmt.Errorf("failed to write file: %w", err)
+			// Write file, streaming content from its source
+			if err := writeFileContent(fullPath, &fc); err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("failed to write file: %w", err)
 			}
 
 			// Add to staging
 			_, err := w.worktree.Add(fc.Path)
 			if err != nil {
-				return fmt.Errorf("failed to add file: %w", err)
+				return plumbing.ZeroHash, fmt.Errorf("failed to add file: %w", err)
 			}
 
-		case vcs.ActionDelete:
-			// Remove file
-			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
-				return fmt.Errorf("failed to remove file: %w", err)
+			// A rename stages the removal of the old path with the addition
+			if fc.Action == vcs.ActionRename && fc.OldPath != fc.Path {
+				if err := w.removeWorktreeFile(fc.OldPath); err != nil {
+					return plumbing.ZeroHash, err
+				}
 			}
 
-			// Remove from staging
-			_, err := w.worktree.Remove(fc.Path)
-			if err != nil {
-				// Log if file wasn't tracked - this is expected for some deletions
-				log.Printf("Debug: file %s not tracked in git, skipping removal: %v", fc.Path, err)
+		case vcs.ActionDelete:
+			if err := w.removeWorktreeFile(fc.Path); err != nil {
+				return plumbing.ZeroHash, err
 			}
 		}
 	}
 
 	// Create commit
-	hash, err := w.worktree.Commit(commit.Message, &git.CommitOptions{
+	if err := vcs.ContextErr(w.ctx); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err = w.worktree.Commit(commit.Message, &git.CommitOptions{
 		AllowEmptyCommits: true,
-		Author: &object.Signature{
-			Name:  commit.Author,
-			Email: commit.Email,
-			When:  commit.Date,
-		},
-		Committer: &object.Signature{
-			Name:  commit.Author,
-			Email: commit.Email,
-			When:  commit.Date,
-		},
+		Author:            author,
+		Committer:         committer,
+		Parents:           parents,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create commit: %w", err)
+		return plumbing.ZeroHash, fmt.Errorf("failed to create commit: %w", err)
 	}
+	return hash, nil
+}
 
-	w.lastCommit = hash
+// discardFiles resets the paths of files in the working tree and the index
+// to HEAD. Other paths are left alone, since the working tree may hold
+// untracked files such as the migration state.
+func (w *Writer) discardFiles(files []vcs.FileChange) error {
+	var tree *object.Tree
+	head, err := w.repo.Head()
+	switch {
+	case err == nil:
+		commit, err := w.repo.CommitObject(head.Hash())
+		if err != nil {
+			return err
+		}
+		if tree, err = commit.Tree(); err != nil {
+			return err
+		}
+	case !errors.Is(err, plumbing.ErrReferenceNotFound):
+		return err
+	}
+
+	for _, fc := range files {
+		for _, p := range []string{fc.Path, fc.OldPath} {
+			if p == "" {
+				continue
+			}
+			if err := w.restoreFile(tree, p); err != nil {
+				return fmt.Errorf("failed to reset %s: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreFile writes the file at p in tree to the working tree and the
+// index, or removes p from both if tree lacks it
+func (w *Writer) restoreFile(tree *object.Tree, p string) error {
+	var file *object.File
+	if tree != nil {
+		var err error
+		if file, err = tree.File(p); err != nil && !errors.Is(err, object.ErrFileNotFound) {
+			return err
+		}
+	}
+	fullPath := filepath.Join(w.path, p)
+	if file == nil {
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		idx, err := w.repo.Storer.Index()
+		if err != nil {
+			return err
+		}
+		if _, err := idx.Remove(p); err != nil {
+			if errors.Is(err, index.ErrEntryNotFound) {
+				return nil
+			}
+			return err
+		}
+		return w.repo.Storer.SetIndex(idx)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if file.Mode == filemode.Executable {
+		perm = 0755
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fullPath, []byte(contents), perm); err != nil {
+		return err
+	}
+	_, err = w.worktree.Add(p)
+	return err
+}
+
+// removeWorktreeFile removes a file from the working tree and the index
+func (w *Writer) removeWorktreeFile(path string) error {
+	if err := os.Remove(filepath.Join(w.path, path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+
+	// Remove from staging
+	_, err := w.worktree.Remove(path)
+	if err != nil {
+		// Log if file wasn't tracked - this is expected for some deletions
+		logging.OrDefault(w.logger).Debug("file not tracked in git, skipping removal", "path", path, "error", err)
+	}
 	return nil
 }
 
+// commitObjects applies the file changes to the tree of HEAD in the object
+// store and commits it on the current branch, leaving the working tree and
+// the index alone
+func (w *Writer) commitObjects(commit *vcs.Commit, author, committer *object.Signature, parents []plumbing.Hash) (hash plumbing.Hash, err error) {
+	s := w.repo.Storer
+	var head plumbing.Hash
+	if ref, err := w.repo.Head(); err == nil {
+		head = ref.Hash()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	// The cached tree is only reused while HEAD is the commit it belongs to;
+	// a failure leaves it half applied, so it is dropped
+	if w.tree.root == nil || w.tree.base != head {
+		if err := w.tree.reset(s, head); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	defer func() {
+		if err != nil {
+			w.tree = treeBuilder{}
+		}
+	}()
+
+	for i := range commit.Files {
+		if err := vcs.ContextErr(w.ctx); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		fc := &commit.Files[i]
+		removePath := ""
+		switch fc.Action {
+		case vcs.ActionAdd, vcs.ActionModify, vcs.ActionRename, vcs.ActionCopy:
+			if err := w.tree.set(s, fc); err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("failed to write file: %w", err)
+			}
+			if fc.Action == vcs.ActionRename && fc.OldPath != fc.Path {
+				removePath = fc.OldPath
+			}
+		case vcs.ActionDelete:
+			removePath = fc.Path
+		}
+		if removePath != "" {
+			removed, err := w.tree.remove(s, removePath)
+			if err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("failed to remove file: %w", err)
+			}
+			if !removed {
+				logging.OrDefault(w.logger).Debug("file not tracked in git, skipping removal", "path", removePath)
+			}
+		}
+	}
+
+	if err := vcs.ContextErr(w.ctx); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	treeHash, err := w.tree.write(s)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if len(parents) == 0 && !head.IsZero() {
+		parents = []plumbing.Hash{head}
+	}
+	c := &object.Commit{
+		Author:       *author,
+		Committer:    *committer,
+		Message:      commit.Message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+	obj := s.NewEncodedObject()
+	if err := c.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode commit: %w", err)
+	}
+	if hash, err = s.SetEncodedObject(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	// Advance the current branch, or HEAD itself when detached
+	headRef, err := s.Reference(plumbing.HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	name := plumbing.HEAD
+	if headRef.Type() == plumbing.SymbolicReference {
+		name = headRef.Target()
+	}
+	if err := s.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to update %s: %w", name, err)
+	}
+	w.tree.base = hash
+	return hash, nil
+}
+
+// mergeParents returns the parents of a merge commit: the current HEAD,
+// if the repository has commits, followed by the merged commits. The tree
+// of the merge is the HEAD tree with the commit's file changes applied.
+func (w *Writer) mergeParents(merged []string) ([]plumbing.Hash, error) {
+	var parents []plumbing.Hash
+	if head, err := w.repo.Head(); err == nil {
+		parents = append(parents, head.Hash())
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	for _, rev := range merged {
+		hash, err := w.resolveParent(rev)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(parents, hash) {
+			parents = append(parents, hash)
+		}
+	}
+	return parents, nil
+}
+
+// resolveParent resolves a merged commit given as the source revision of a
+// commit applied by this writer, a commit hash or a reference
+func (w *Writer) resolveParent(rev string) (plumbing.Hash, error) {
+	if hash, ok := w.revisions[rev]; ok {
+		return hash, nil
+	}
+	if plumbing.IsHash(rev) {
+		hash := plumbing.NewHash(rev)
+		if _, err := w.repo.CommitObject(hash); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("unknown parent commit %s: %w", rev, err)
+		}
+		return hash, nil
+	}
+	h, err := w.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unknown parent revision %s: %w", rev, err)
+	}
+	return *h, nil
+}
+
+// writeFileContent materializes a file change's content on disk
+func writeFileContent(path string, fc *vcs.FileChange) error {
+	src, err := fc.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
 // CreateBranch creates a new branch
 func (w *Writer) CreateBranch(name, revision string) error {
-	if w.repo == nil {
-		return fmt.Errorf("repository not initialized")
+	if err := w.writable(); err != nil {
+		return err
 	}
 
 	// Resolve revision to hash
@@ -253,59 +604,148 @@ func (w *Writer) CreateBranch(name, revision string) error {
 	return w.repo.Storer.SetReference(ref)
 }
 
-// CreateTag creates a new tag
-func (w *Writer) CreateTag(name, revision, message string) error {
-	if w.repo == nil {
-		return fmt.Errorf("repository not initialized")
+// SetDefaultBranch makes name the branch HEAD points at. An existing branch
+// is checked out. Otherwise, in a repository without commits HEAD is simply
+// repointed, and in one with commits the current branch is renamed, so
+// trunk history ends up on name.
+func (w *Writer) SetDefaultBranch(name string) error {
+	if err := w.writable(); err != nil {
+		return err
 	}
 
-	// Resolve revision to hash
-	var hash plumbing.Hash
-	if revision == "HEAD" {
-		if w.lastCommit.IsZero() {
-			head, err := w.repo.Head()
-			if err != nil {
-				return fmt.Errorf("failed to get HEAD: %w", err)
+	branch := plumbing.NewBranchReferenceName(name)
+	if err := branch.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q: %w", name, err)
+	}
+
+	head, err := w.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	current := head.Target()
+	if head.Type() == plumbing.SymbolicReference && current == branch {
+		return nil
+	}
+
+	if existing, err := w.repo.Storer.Reference(branch); err == nil {
+		// Only the worktree of a branch at a different commit needs updating;
+		// Keep leaves untracked files such as the state database alone. In
+		// CommitModeObjects there is no worktree to update.
+		resolved, err := w.repo.Head()
+		if w.commitMode != CommitModeObjects && (err != nil || resolved.Hash() != existing.Hash()) {
+			if err := w.worktree.Checkout(&git.CheckoutOptions{Branch: branch, Keep: true}); err != nil {
+				return fmt.Errorf("failed to check out branch %s: %w", name, err)
 			}
-			hash = head.Hash()
-		} else {
-			hash = w.lastCommit
+			return nil
 		}
-	} else {
-		h, err := w.repo.ResolveRevision(plumbing.Revision(revision))
-		if err != nil {
-			hash = plumbing.NewHash(revision)
-			if hash.IsZero() {
-				return fmt.Errorf("failed to resolve revision: %w", err)
+		return w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch))
+	}
+
+	switch head.Type() {
+	case plumbing.HashReference:
+		// Detached HEAD: keep its commit reachable from the new branch
+		if err := w.repo.Storer.SetReference(plumbing.NewHashReference(branch, head.Hash())); err != nil {
+			return fmt.Errorf("failed to create branch %s: %w", name, err)
+		}
+	case plumbing.SymbolicReference:
+		// Move the current branch, if it already has commits
+		ref, err := w.repo.Storer.Reference(current)
+		switch {
+		case err == nil:
+			if err := w.repo.Storer.SetReference(plumbing.NewHashReference(branch, ref.Hash())); err != nil {
+				return fmt.Errorf("failed to create branch %s: %w", name, err)
 			}
-		} else {
-			hash = *h
+			if err := w.repo.Storer.RemoveReference(current); err != nil {
+				return fmt.Errorf("failed to remove branch %s: %w", current.Short(), err)
+			}
+		case !errors.Is(err, plumbing.ErrReferenceNotFound):
+			return fmt.Errorf("failed to get branch %s: %w", current.Short(), err)
 		}
 	}
 
+	if err := w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+	return nil
+}
+
+// TagOptions describes an annotated tag
+type TagOptions struct {
+	Message string          // Tag message (required)
+	Tagger  string          // Tagger name (default: author of the tagged commit)
+	Email   string          // Tagger email
+	Date    time.Time       // Tagging date (default: date of the tagged commit)
+	SignKey *openpgp.Entity // Key to sign the tag with (default: unsigned)
+}
+
+// CreateTag creates a new tag
+func (w *Writer) CreateTag(name, revision, message string) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+
 	if message == "" {
+		hash, err := w.resolveTagTarget(revision)
+		if err != nil {
+			return err
+		}
+
 		// Lightweight tag
 		ref := plumbing.NewHashReference(plumbing.ReferenceName("refs/tags/"+name), hash)
 		return w.repo.Storer.SetReference(ref)
 	}
 
-	// Annotated tag - get commit for tagger info
+	return w.CreateAnnotatedTag(name, revision, TagOptions{Message: message})
+}
+
+// CreateAnnotatedTag creates an annotated tag object with the given tagger
+// and date and points the tag at it
+func (w *Writer) CreateAnnotatedTag(name, revision string, opts TagOptions) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	if opts.Message == "" {
+		return fmt.Errorf("annotated tag %s requires a message", name)
+	}
+
+	hash, err := w.resolveTagTarget(revision)
+	if err != nil {
+		return err
+	}
+
+	// Default tagger info from the tagged commit
 	commit, err := w.repo.CommitObject(hash)
 	if err != nil {
 		return fmt.Errorf("failed to get commit: %w", err)
 	}
+	tagger := commit.Author
+	if opts.Tagger != "" {
+		tagger.Name, tagger.Email = opts.Tagger, opts.Email
+	}
+	if !opts.Date.IsZero() {
+		tagger.When = opts.Date
+	}
 
 	// Create tag object using object storage
 	tag := &object.Tag{
 		Name:       name,
-		Tagger:     commit.Author,
-		Message:    message,
+		Tagger:     tagger,
+		Message:    opts.Message,
 		TargetType: plumbing.CommitObject,
 		Target:     hash,
 	}
 
+	if opts.SignKey != nil {
+		if err := signTag(tag, opts.SignKey); err != nil {
+			return err
+		}
+	}
+
 	// Get object writer from storer
 	objStorer, ok := w.repo.Storer.(storer.EncodedObjectStorer)
+	if !ok && opts.SignKey != nil {
+		return fmt.Errorf("cannot write signed tag %s to this repository", name)
+	}
 	if !ok {
 		// Fallback to lightweight tag if we can't create annotated
 		ref := plumbing.NewHashReference(plumbing.ReferenceName("refs/tags/"+name), hash)
@@ -328,6 +768,30 @@ func (w *Writer) CreateTag(name, revision, message string) error {
 	return w.repo.Storer.SetReference(ref)
 }
 
+// resolveTagTarget resolves the revision a tag should point at
+func (w *Writer) resolveTagTarget(revision string) (plumbing.Hash, error) {
+	if revision == "HEAD" {
+		if !w.lastCommit.IsZero() {
+			return w.lastCommit, nil
+		}
+		head, err := w.repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		return head.Hash(), nil
+	}
+
+	h, err := w.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		hash := plumbing.NewHash(revision)
+		if hash.IsZero() {
+			return plumbing.ZeroHash, fmt.Errorf("failed to resolve revision: %w", err)
+		}
+		return hash, nil
+	}
+	return *h, nil
+}
+
 // ListBranches returns a list of branch names
 func (w *Writer) ListBranches() ([]string, error) {
 	if w.repo == nil {
@@ -350,6 +814,70 @@ func (w *Writer) ListBranches() ([]string, error) {
 	return branches, err
 }
 
+// BranchHeads returns a map of branch names to the hashes of their head
+// commits
+func (w *Writer) BranchHeads() (map[string]string, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+
+	refs, err := w.repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	heads := make(map[string]string)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() {
+			heads[ref.Name().Short()] = ref.Hash().String()
+		}
+		return nil
+	})
+
+	return heads, err
+}
+
+// BranchFiles returns the files of the tree at the tip of a branch, or of
+// HEAD if branch is empty, as additions. Contents are read from the object
+// store on demand.
+func (w *Writer) BranchFiles(branch string) ([]vcs.FileChange, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+
+	name := plumbing.HEAD
+	if branch != "" {
+		name = plumbing.NewBranchReferenceName(branch)
+	}
+	ref, err := w.repo.Reference(name, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", name.Short(), err)
+	}
+	commit, err := w.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []vcs.FileChange
+	err = tree.Files().ForEach(func(f *object.File) error {
+		blob := f.Blob
+		files = append(files, vcs.FileChange{
+			Path:   f.Name,
+			Action: vcs.ActionAdd,
+			Source: func() (io.ReadCloser, error) { return blob.Reader() },
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // ListTags returns a map of tag names to commit hashes
 func (w *Writer) ListTags() (map[string]string, error) {
 	if w.repo == nil {
@@ -395,39 +923,374 @@ func (w *Writer) GetLastCommit() (*vcs.Commit, error) {
 	}
 
 	return &vcs.Commit{
-		Revision: commit.Hash.String(),
-		Author:   commit.Author.Name,
-		Email:    commit.Author.Email,
-		Date:     commit.Author.When,
-		Message:  commit.Message,
+		Revision:       commit.Hash.String(),
+		Author:         commit.Author.Name,
+		Email:          commit.Author.Email,
+		Date:           commit.Author.When,
+		Committer:      commit.Committer.Name,
+		CommitterEmail: commit.Committer.Email,
+		CommitDate:     commit.Committer.When,
+		Message:        commit.Message,
 	}, nil
 }
 
-// GetCommitHashes returns all commit hashes in chronological order (oldest first)
-func (w *Writer) GetCommitHashes() ([]string, error) {
-	if w.repo == nil {
-		return nil, fmt.Errorf("repository not initialized")
+// LastCommitHash returns the hash of the most recent commit created by
+// ApplyCommit, or an empty string if none has been created yet
+func (w *Writer) LastCommitHash() string {
+	if w.lastCommit.IsZero() {
+		return ""
 	}
+	return w.lastCommit.String()
+}
 
-	// Get all commits via log
+// HeadHash returns the hash of the commit HEAD points to, or an empty string
+// if the repository has no commits
+func (w *Writer) HeadHash() string {
+	if !w.lastCommit.IsZero() {
+		return w.lastCommit.String()
+	}
+	if w.repo == nil {
+		return ""
+	}
 	head, err := w.repo.Head()
+	if err != nil {
+		return ""
+	}
+	return head.Hash().String()
+}
+
+// ResetHead points the current branch, or HEAD itself when detached, at
+// hash. In CommitModeWorktree the working tree is reset to match.
+func (w *Writer) ResetHead(hash string) error {
+	if err := w.writable(); err != nil {
+		return err
+	}
+	if !plumbing.IsHash(hash) {
+		return fmt.Errorf("invalid commit hash %q", hash)
+	}
+	commit := plumbing.NewHash(hash)
+	if _, err := w.repo.CommitObject(commit); err != nil {
+		return fmt.Errorf("unknown commit %s: %w", hash, err)
+	}
+
+	// Only tracked files are reset; a hard reset would also delete untracked
+	// files such as a state database kept in the working tree
+	var files []string
+	if w.commitMode != CommitModeObjects {
+		var err error
+		if files, err = w.trackedFiles(plumbing.HEAD, commit); err != nil {
+			return err
+		}
+	}
+
+	headRef, err := w.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	name := plumbing.HEAD
+	if headRef.Type() == plumbing.SymbolicReference {
+		name = headRef.Target()
+	}
+	if err := w.repo.Storer.SetReference(plumbing.NewHashReference(name, commit)); err != nil {
+		return fmt.Errorf("failed to update %s: %w", name, err)
+	}
+	if len(files) > 0 {
+		if err := w.worktree.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset, Files: files}); err != nil {
+			return fmt.Errorf("failed to reset worktree: %w", err)
+		}
+	}
+	w.lastCommit = commit
+	w.tree = treeBuilder{}
+	return nil
+}
+
+// trackedFiles returns the paths of the files in the trees of the HEAD
+// commit and of commit
+func (w *Writer) trackedFiles(head plumbing.ReferenceName, commit plumbing.Hash) ([]string, error) {
+	hashes := []plumbing.Hash{commit}
+	if ref, err := w.repo.Reference(head, true); err == nil {
+		hashes = append(hashes, ref.Hash())
+	}
+	seen := make(map[string]bool)
+	var files []string
+	for _, hash := range hashes {
+		c, err := w.repo.CommitObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+		}
+		tree, err := c.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tree of %s: %w", hash, err)
+		}
+		err = tree.Files().ForEach(func(f *object.File) error {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				files = append(files, f.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// CommitsAfter returns the commits reachable from a branch, a tag or a
+// detached HEAD but not from the commit hash
+func (w *Writer) CommitsAfter(hash string) ([]string, error) {
+	if w.repo == nil {
+		return nil, fmt.Errorf("repository not initialized")
+	}
+	base, err := w.ancestors(hash)
+	if err != nil {
+		return nil, err
+	}
+	heads, err := w.refCommits()
 	if err != nil {
 		return nil, err
 	}
 
-	commitIter, err := w.repo.Log(&git.LogOptions{From: head.Hash()})
+	var after []string
+	seen := make(map[plumbing.Hash]bool)
+	queue := make([]plumbing.Hash, 0, len(heads))
+	for _, head := range heads {
+		queue = append(queue, head)
+	}
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		if base[h] || seen[h] {
+			continue
+		}
+		seen[h] = true
+		after = append(after, h.String())
+		c, err := w.repo.CommitObject(h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", h, err)
+		}
+		queue = append(queue, c.ParentHashes...)
+	}
+	return after, nil
+}
+
+// RewindRefs points the branches whose head is not reachable from the
+// commit hash at it and deletes the tags on commits after it. It returns
+// the moved branches and the deleted tags.
+func (w *Writer) RewindRefs(hash string) (branches, tags []string, err error) {
+	if err := w.writable(); err != nil {
+		return nil, nil, err
+	}
+	base, err := w.ancestors(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	heads, err := w.refCommits()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]plumbing.ReferenceName, 0, len(heads))
+	for name := range heads {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	commit := plumbing.NewHash(hash)
+	for _, name := range names {
+		if base[heads[name]] {
+			continue
+		}
+		switch {
+		case name.IsBranch():
+			if err := w.repo.Storer.SetReference(plumbing.NewHashReference(name, commit)); err != nil {
+				return branches, tags, fmt.Errorf("failed to update %s: %w", name, err)
+			}
+			branches = append(branches, name.Short())
+		case name.IsTag():
+			if err := w.repo.Storer.RemoveReference(name); err != nil {
+				return branches, tags, fmt.Errorf("failed to delete %s: %w", name, err)
+			}
+			tags = append(tags, name.Short())
+		}
+	}
+	return branches, tags, nil
+}
+
+// IsAncestor reports whether the commit ancestor is reachable from the
+// commit descendant, or is the same commit
+func (w *Writer) IsAncestor(ancestor, descendant string) (bool, error) {
+	if w.repo == nil {
+		return false, fmt.Errorf("repository not initialized")
+	}
+	if !plumbing.IsHash(ancestor) {
+		return false, fmt.Errorf("invalid commit hash %q", ancestor)
+	}
+	reachable, err := w.ancestors(descendant)
+	if err != nil {
+		return false, err
+	}
+	return reachable[plumbing.NewHash(ancestor)], nil
+}
+
+// ancestors returns the commit hash and every commit reachable from it
+func (w *Writer) ancestors(hash string) (map[plumbing.Hash]bool, error) {
+	if !plumbing.IsHash(hash) {
+		return nil, fmt.Errorf("invalid commit hash %q", hash)
+	}
+	seen := make(map[plumbing.Hash]bool)
+	queue := []plumbing.Hash{plumbing.NewHash(hash)}
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		if seen[h] {
+			continue
+		}
+		c, err := w.repo.CommitObject(h)
+		if err != nil {
+			return nil, fmt.Errorf("unknown commit %s: %w", h, err)
+		}
+		seen[h] = true
+		queue = append(queue, c.ParentHashes...)
+	}
+	return seen, nil
+}
+
+// refCommits returns the commit every branch and tag points to, peeling
+// annotated tags, and the commit of a detached HEAD
+func (w *Writer) refCommits() (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	refs, err := w.repo.References()
 	if err != nil {
 		return nil, err
 	}
-	defer commitIter.Close()
+	heads := make(map[plumbing.ReferenceName]plumbing.Hash)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		name := ref.Name()
+		if !name.IsBranch() && !name.IsTag() && name != plumbing.HEAD {
+			return nil
+		}
+		hash := ref.Hash()
+		if tag, err := w.repo.TagObject(hash); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				return fmt.Errorf("failed to resolve tag %s: %w", name.Short(), err)
+			}
+			hash = c.Hash
+		}
+		heads[name] = hash
+		return nil
+	})
+	return heads, err
+}
 
+// HasCommit reports whether the repository contains a commit with the given hash
+func (w *Writer) HasCommit(hash string) bool {
+	if w.repo == nil || !plumbing.IsHash(hash) {
+		return false
+	}
+	_, err := w.repo.CommitObject(plumbing.NewHash(hash))
+	return err == nil
+}
+
+// GetCommitHashes returns all commit hashes reachable from HEAD in
+// chronological order (oldest first)
+func (w *Writer) GetCommitHashes() ([]string, error) {
 	var hashes []string
-	err = commitIter.ForEach(func(c *object.Commit) error {
-		hashes = append([]string{c.Hash.String()}, hashes...) // Prepend for oldest first
+	err := w.forEachCommit(func(c *object.Commit) error {
+		hashes = append(hashes, c.Hash.String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The log yields newest first
+	for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+	return hashes, nil
+}
+
+// GetCommitCount returns the number of commits reachable from HEAD
+func (w *Writer) GetCommitCount() (int, error) {
+	count := 0
+	err := w.forEachCommit(func(*object.Commit) error {
+		count++
 		return nil
 	})
+	return count, err
+}
+
+// GetCommitHashesPage returns up to limit commit hashes in chronological
+// order (oldest first), skipping the first offset commits. Only the
+// requested page is held in memory.
+func (w *Writer) GetCommitHashesPage(offset, limit int) ([]string, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("invalid page: offset %d, limit %d", offset, limit)
+	}
+
+	total, err := w.GetCommitCount()
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 || offset >= total {
+		return []string{}, nil
+	}
+
+	// Translate the oldest-first window into newest-first log positions
+	end := total - offset // exclusive
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+
+	page := make([]string, end-start)
+	pos := 0
+	err = w.forEachCommit(func(c *object.Commit) error {
+		if pos >= end {
+			return storer.ErrStop
+		}
+		if pos >= start {
+			page[end-1-pos] = c.Hash.String()
+		}
+		pos++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// forEachCommit walks the commits reachable from HEAD, newest first. An
+// empty repository has no commits.
+func (w *Writer) forEachCommit(fn func(*object.Commit) error) error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+
+	head, err := w.repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	commitIter, err := w.repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return err
+	}
+	defer commitIter.Close()
 
-	return hashes, err
+	err = commitIter.ForEach(fn)
+	if err == storer.ErrStop {
+		return nil
+	}
+	return err
 }
 
 // Close releases any resources
@@ -439,7 +1302,7 @@ func (w *Writer) Close() error {
 func (w *Writer) Open(path string) error {
 	repo, err := git.PlainOpen(path)
 	if err != nil {
-		return fmt.Errorf("failed to open repository: %w", err)
+		return fmt.Errorf("failed to open repository: %w", notARepo(err))
 	}
 
 	w.path = path
@@ -454,6 +1317,33 @@ func (w *Writer) Open(path string) error {
 	return nil
 }
 
+// OpenReadOnly opens an existing repository without a worktree, so bare
+// repositories can be opened too. Only the read operations such as
+// ListBranches, ListTags, GetCommitHashes and ResolveRevision are available;
+// operations that modify the repository fail with ErrReadOnly.
+func (w *Writer) OpenReadOnly(path string) error {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", notARepo(err))
+	}
+
+	w.path = path
+	w.repo = repo
+	w.readOnly = true
+	return nil
+}
+
+// writable returns an error unless the repository is open for writing
+func (w *Writer) writable() error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+	if w.readOnly {
+		return ErrReadOnly
+	}
+	return vcs.ContextErr(w.ctx)
+}
+
 // ResolveRevision resolves a revision string to a hash
 func (w *Writer) ResolveRevision(rev string) (string, error) {
 	if w.repo == nil {
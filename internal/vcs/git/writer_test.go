@@ -1,12 +1,17 @@
 package git
 
 import (
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/go-git/go-git/v5"
 	"github.com/stretchr/testify/require"
 )
 
@@ -279,6 +284,36 @@ func TestWriterOpenNonExistent(t *testing.T) {
 	}
 }
 
+func TestWriterOpenReadOnly(t *testing.T) {
+	src, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+	require.NoError(t, src.CreateTag("v1", hashes[1], ""))
+	bare := filepath.Join(t.TempDir(), "bare.git")
+	_, err := git.PlainClone(bare, true, &git.CloneOptions{URL: src.path})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, NewWriter().Open(bare), git.ErrIsBareRepository)
+
+	w := NewWriter()
+	require.NoError(t, w.OpenReadOnly(bare))
+	branches, err := w.ListBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"master"}, branches)
+	tags, err := w.ListTags()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"v1": hashes[1]}, tags)
+	got, err := w.GetCommitHashes()
+	require.NoError(t, err)
+	require.Equal(t, hashes, got)
+	resolved, err := w.ResolveRevision("v1")
+	require.NoError(t, err)
+	require.Equal(t, hashes[1], resolved)
+
+	require.ErrorIs(t, w.ApplyCommit(treeTestCommits()[0]), ErrReadOnly)
+	require.ErrorIs(t, w.CreateBranch("b", hashes[0]), ErrReadOnly)
+	require.ErrorIs(t, w.CreateTag("v2", hashes[0], ""), ErrReadOnly)
+	require.ErrorIs(t, w.ResetHead(hashes[0]), ErrReadOnly)
+}
+
 func TestWriterApplyCommit(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "git-writer-test")
 	if err != nil {
@@ -1040,6 +1075,31 @@ func TestWriterListBranchesNoRepo(t *testing.T) {
 	}
 }
 
+func TestWriterBranchFiles(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+	defer func() { require.NoError(t, w.Close()) }()
+
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{
+		Author: "alice", Email: "alice@example.com", Date: time.Now(), Message: "add",
+		Files: []vcs.FileChange{{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("int main;\n")}},
+	}))
+	require.NoError(t, w.CreateBranch("feature", "HEAD"))
+
+	for _, branch := range []string{"", "feature"} {
+		files, err := w.BranchFiles(branch)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, "src/main.c", files[0].Path)
+		content, err := files[0].ReadContent()
+		require.NoError(t, err)
+		require.Equal(t, "int main;\n", string(content))
+	}
+
+	_, err := w.BranchFiles("missing")
+	require.Error(t, err)
+}
+
 func TestWriterListTags(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "git-writer-test")
 	if err != nil {
@@ -1728,3 +1788,439 @@ func TestWriterCreateAnnotatedTag_WithLastCommit(t *testing.T) {
 		t.Error("Tag 'v1.0.0' not found")
 	}
 }
+
+func TestWriterLastCommitHashAndHasCommit(t *testing.T) {
+	w := NewWriter()
+	require.Equal(t, "", w.LastCommitHash())
+	require.False(t, w.HasCommit("0123456789abcdef0123456789abcdef01234567"))
+
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+
+	commit := &vcs.Commit{
+		Author:  "Test",
+		Email:   "test@example.com",
+		Date:    time.Now(),
+		Message: "Lazy content",
+		Files: []vcs.FileChange{{
+			Path:   "lazy.txt",
+			Action: vcs.ActionAdd,
+			Source: func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader("streamed")), nil
+			},
+		}},
+	}
+	require.NoError(t, w.ApplyCommit(commit))
+
+	hash := w.LastCommitHash()
+	require.Len(t, hash, 40)
+	require.True(t, w.HasCommit(hash))
+	require.False(t, w.HasCommit("0123456789abcdef0123456789abcdef01234567"))
+	require.False(t, w.HasCommit("not-a-hash"))
+
+	data, err := os.ReadFile(filepath.Join(w.path, "lazy.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "streamed", string(data))
+}
+
+func TestWriterApplyCommitFailureDiscarded(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+	add := func(path, content string) vcs.FileChange {
+		return vcs.FileChange{Path: path, Action: vcs.ActionAdd, Content: []byte(content)}
+	}
+	broken := vcs.FileChange{Path: "broken.txt", Action: vcs.ActionAdd, Source: func() (io.ReadCloser, error) {
+		return nil, errors.New("unreadable")
+	}}
+
+	untracked := filepath.Join(w.path, ".migration-state.db")
+	require.NoError(t, os.WriteFile(untracked, []byte("state"), 0644))
+
+	// Before the first commit and after it, the files of a failed commit
+	// are not committed with the next one
+	for _, first := range []bool{true, false} {
+		require.Error(t, w.ApplyCommit(&vcs.Commit{Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "failed",
+			Files: []vcs.FileChange{add("partial.txt", "partial"), {Path: "kept.txt", Action: vcs.ActionDelete}, broken}}))
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "next",
+			Files: []vcs.FileChange{add("kept.txt", "kept")}}))
+
+		head, err := w.repo.Head()
+		require.NoError(t, err)
+		commit, err := w.repo.CommitObject(head.Hash())
+		require.NoError(t, err)
+		_, err = commit.File("partial.txt")
+		require.Error(t, err, "first=%v", first)
+		_, err = commit.File("kept.txt")
+		require.NoError(t, err, "first=%v", first)
+	}
+	require.FileExists(t, untracked, "untracked files are left alone")
+}
+
+func TestWriterHeadHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo")
+	w := NewWriter()
+	require.Equal(t, "", w.HeadHash())
+	require.NoError(t, w.Init(path))
+	require.Equal(t, "", w.HeadHash(), "empty repository has no head")
+
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "first"}))
+	require.Equal(t, w.LastCommitHash(), w.HeadHash())
+	require.NoError(t, w.Close())
+
+	// A reopened repository reports HEAD before any commit is applied
+	reopened := NewWriter()
+	require.NoError(t, reopened.Open(path))
+	require.Equal(t, "", reopened.LastCommitHash())
+	require.Equal(t, w.LastCommitHash(), reopened.HeadHash())
+}
+
+func TestWriterGetCommitHashesPage(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "page-repo")))
+
+	// Empty repository
+	count, err := w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	page, err := w.GetCommitHashesPage(0, 10)
+	require.NoError(t, err)
+	require.Empty(t, page)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{
+			Author:  "Test",
+			Email:   "test@example.com",
+			Date:    base.Add(time.Duration(i) * time.Hour),
+			Message: "Commit",
+		}))
+	}
+
+	all, err := w.GetCommitHashes()
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	require.Equal(t, w.LastCommitHash(), all[4], "newest commit must be last")
+
+	count, err = w.GetCommitCount()
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+
+	page, err = w.GetCommitHashesPage(0, 2)
+	require.NoError(t, err)
+	require.Equal(t, all[0:2], page)
+
+	page, err = w.GetCommitHashesPage(2, 2)
+	require.NoError(t, err)
+	require.Equal(t, all[2:4], page)
+
+	page, err = w.GetCommitHashesPage(4, 10)
+	require.NoError(t, err)
+	require.Equal(t, all[4:], page)
+
+	page, err = w.GetCommitHashesPage(5, 10)
+	require.NoError(t, err)
+	require.Empty(t, page)
+
+	_, err = w.GetCommitHashesPage(-1, 2)
+	require.Error(t, err)
+}
+
+func TestWriterGetCommitCountNoRepo(t *testing.T) {
+	w := NewWriter()
+	_, err := w.GetCommitCount()
+	require.Error(t, err)
+	_, err = w.GetCommitHashesPage(0, 1)
+	require.Error(t, err)
+}
+
+func TestWriterApplyCommitSeparateCommitter(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+	defer func() { require.NoError(t, w.Close()) }()
+
+	authored := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{
+		Author:         "Alice",
+		Email:          "alice@example.com",
+		Date:           authored,
+		Committer:      "Migration Bot",
+		CommitterEmail: "bot@example.com",
+		Message:        "m1",
+	}))
+
+	last, err := w.GetLastCommit()
+	require.NoError(t, err)
+	require.Equal(t, "Alice", last.Author)
+	require.Equal(t, "alice@example.com", last.Email)
+	require.Equal(t, "Migration Bot", last.Committer)
+	require.Equal(t, "bot@example.com", last.CommitterEmail)
+	require.True(t, authored.Equal(last.CommitDate), "commit date defaults to the author date")
+
+	// Without a committer the author is used for both identities
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Bob", Email: "bob@example.com", Date: authored, Message: "m2"}))
+	last, err = w.GetLastCommit()
+	require.NoError(t, err)
+	require.Equal(t, "Bob", last.Committer)
+	require.Equal(t, "bob@example.com", last.CommitterEmail)
+}
+
+func TestWriterSetDefaultBranch(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+
+	// Unborn HEAD is simply repointed
+	require.NoError(t, w.SetDefaultBranch("main"))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "a", Email: "a@example.com", Date: time.Now(), Message: "m1"}))
+
+	head, err := w.repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, "refs/heads/main", head.Name().String())
+
+	branches, err := w.ListBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"main"}, branches)
+
+	// A branch with commits is renamed
+	require.NoError(t, w.SetDefaultBranch("trunk"))
+	head, err = w.repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, "refs/heads/trunk", head.Name().String())
+	branches, err = w.ListBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"trunk"}, branches)
+
+	// Setting the current branch again is a no-op
+	require.NoError(t, w.SetDefaultBranch("trunk"))
+
+	// An existing branch is checked out rather than renamed onto
+	require.NoError(t, w.CreateBranch("other", "HEAD"))
+	require.NoError(t, w.SetDefaultBranch("other"))
+	head, err = w.repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, "refs/heads/other", head.Name().String())
+	branches, err = w.ListBranches()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"trunk", "other"}, branches)
+
+	require.Error(t, w.SetDefaultBranch("bad..name"))
+}
+
+func TestWriterSetDefaultBranchNoRepo(t *testing.T) {
+	require.Error(t, NewWriter().SetDefaultBranch("main"))
+}
+
+func TestWriterCreateAnnotatedTagWithTagger(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Alice", Email: "alice@example.com", Date: time.Now(), Message: "m1"}))
+
+	tagged := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, w.CreateAnnotatedTag("v1", "HEAD", TagOptions{
+		Message: "Converted from CVS tag V1\n",
+		Tagger:  "Bob",
+		Email:   "bob@example.com",
+		Date:    tagged,
+	}))
+
+	ref, err := w.repo.Tag("v1")
+	require.NoError(t, err)
+	tag, err := w.repo.TagObject(ref.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "Bob", tag.Tagger.Name)
+	require.Equal(t, "bob@example.com", tag.Tagger.Email)
+	require.True(t, tagged.Equal(tag.Tagger.When))
+	require.Equal(t, "Converted from CVS tag V1\n", tag.Message)
+
+	// Without tagger options the tagged commit's author is used
+	require.NoError(t, w.CreateAnnotatedTag("v2", "HEAD", TagOptions{Message: "m\n"}))
+	ref, err = w.repo.Tag("v2")
+	require.NoError(t, err)
+	tag, err = w.repo.TagObject(ref.Hash())
+	require.NoError(t, err)
+	require.Equal(t, "Alice", tag.Tagger.Name)
+
+	require.Error(t, w.CreateAnnotatedTag("v3", "HEAD", TagOptions{}), "message is required")
+}
+
+func TestWriterApplyMergeCommit(t *testing.T) {
+	w := NewWriter()
+	require.NoError(t, w.Init(filepath.Join(t.TempDir(), "repo")))
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	apply := func(rev, path string, parents ...string) {
+		t.Helper()
+		date = date.Add(time.Hour)
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{
+			Revision: rev, Author: "Alice", Date: date, Message: rev, Parents: parents,
+			Files: []vcs.FileChange{{Path: path, Action: vcs.ActionAdd, Content: []byte(rev + "\n")}},
+		}))
+	}
+
+	// A branch forks from 1.1 and is merged back into the trunk
+	apply("1.1", "base.txt")
+	base := w.LastCommitHash()
+	apply("1.1.2.1", "side.txt")
+	side := w.LastCommitHash()
+	require.NoError(t, w.CreateBranch("trunk", base))
+	require.NoError(t, w.SetDefaultBranch("trunk"))
+	apply("1.2", "trunk.txt")
+	trunk := w.LastCommitHash()
+	apply("1.3", "side.txt", "1.1.2.1")
+
+	merge, err := w.repo.CommitObject(w.lastCommit)
+	require.NoError(t, err)
+	require.Equal(t, []string{trunk, side}, []string{merge.ParentHashes[0].String(), merge.ParentHashes[1].String()})
+	_, err = merge.File("trunk.txt")
+	require.NoError(t, err)
+	_, err = merge.File("side.txt")
+	require.NoError(t, err)
+
+	// Parents may be given as hashes; HEAD is never listed twice
+	head := w.LastCommitHash()
+	apply("1.4", "more.txt", base, head)
+	merge, err = w.repo.CommitObject(w.lastCommit)
+	require.NoError(t, err)
+	require.Len(t, merge.ParentHashes, 2)
+	require.Equal(t, base, merge.ParentHashes[1].String())
+
+	// An unknown parent fails before the worktree is touched
+	err = w.ApplyCommit(&vcs.Commit{Revision: "1.5", Message: "bad", Parents: []string{"9.9"},
+		Files: []vcs.FileChange{{Path: "bad.txt", Action: vcs.ActionAdd, Content: []byte("x")}}})
+	require.ErrorContains(t, err, "unknown parent revision 9.9")
+	require.NoFileExists(t, filepath.Join(w.path, "bad.txt"))
+	require.Equal(t, merge.Hash.String(), w.HeadHash())
+}
+
+func TestWriterResetHead(t *testing.T) {
+	for _, mode := range []string{CommitModeWorktree, CommitModeObjects} {
+		t.Run(mode, func(t *testing.T) {
+			w, hashes := writeTreeTestRepo(t, mode)
+			require.NoError(t, os.WriteFile(filepath.Join(w.path, "state.db"), []byte("state"), 0644))
+			require.NoError(t, w.ResetHead(hashes[0]))
+			require.FileExists(t, filepath.Join(w.path, "state.db"), "untracked files are kept")
+			require.Equal(t, hashes[0], w.HeadHash())
+			head, err := w.repo.Head()
+			require.NoError(t, err)
+			require.Equal(t, hashes[0], head.Hash().String())
+			if mode == CommitModeWorktree {
+				data, err := os.ReadFile(filepath.Join(w.path, "doc", "old.txt"))
+				require.NoError(t, err)
+				require.Equal(t, "old\n", string(data))
+				require.NoFileExists(t, filepath.Join(w.path, "src", "lib", "deep", "more.c"))
+			}
+
+			// New commits build on the checkpoint
+			require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Alice", Date: time.Now(), Message: "redo\n", Files: []vcs.FileChange{
+				{Path: "src/main.c", Action: vcs.ActionModify, Content: []byte("int z;\n")},
+			}}))
+			commit, err := w.repo.CommitObject(w.lastCommit)
+			require.NoError(t, err)
+			require.Equal(t, hashes[0], commit.ParentHashes[0].String())
+			tree, err := commit.Tree()
+			require.NoError(t, err)
+			require.Equal(t, "old\n", readFile(t, tree, "doc/old.txt"))
+
+			require.Error(t, w.ResetHead("main"))
+			require.Error(t, w.ResetHead(strings.Repeat("0", 40)))
+		})
+	}
+}
+
+func TestWriterRewindRefs(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+	require.NoError(t, w.CreateBranch("feature", "HEAD"))
+	require.NoError(t, w.CreateBranch("old", hashes[0]))
+	require.NoError(t, w.CreateTag("v1", hashes[0], ""))
+	require.NoError(t, w.CreateAnnotatedTag("v2", hashes[2], TagOptions{Message: "v2\n"}))
+
+	after, err := w.CommitsAfter(hashes[0])
+	require.NoError(t, err)
+	require.ElementsMatch(t, hashes[1:], after)
+	after, err = w.CommitsAfter(hashes[2])
+	require.NoError(t, err)
+	require.Empty(t, after)
+
+	require.NoError(t, w.ResetHead(hashes[0]))
+	branches, tags, err := w.RewindRefs(hashes[0])
+	require.NoError(t, err)
+	require.Equal(t, []string{"feature"}, branches)
+	require.Equal(t, []string{"v2"}, tags)
+
+	heads, err := w.BranchHeads()
+	require.NoError(t, err)
+	for name, head := range heads {
+		require.Equal(t, hashes[0], head, name)
+	}
+	remaining, err := w.ListTags()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"v1": hashes[0]}, remaining)
+
+	_, err = w.CommitsAfter("main")
+	require.Error(t, err)
+}
+
+func TestWriterIsAncestor(t *testing.T) {
+	w, hashes := writeTreeTestRepo(t, CommitModeWorktree)
+
+	ok, err := w.IsAncestor(hashes[0], hashes[2])
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = w.IsAncestor(hashes[2], hashes[2])
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = w.IsAncestor(hashes[2], hashes[0])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = w.IsAncestor("main", hashes[0])
+	require.Error(t, err)
+}
+
+func TestWriterApplyCommitRenameAndCopy(t *testing.T) {
+	for _, mode := range []string{CommitModeWorktree, CommitModeObjects} {
+		t.Run(mode, func(t *testing.T) {
+			w, _ := writeTreeTestRepo(t, mode)
+			require.NoError(t, w.ApplyCommit(&vcs.Commit{Author: "Alice", Date: time.Now(), Message: "move\n", Files: []vcs.FileChange{
+				{Path: "lib/main.c", OldPath: "src/main.c", Action: vcs.ActionRename, Content: []byte("int y;\n")},
+				{Path: "README.txt", OldPath: "README", Action: vcs.ActionCopy, Content: []byte("readme, copied\n")},
+			}}))
+
+			files, err := w.BranchFiles("")
+			require.NoError(t, err)
+			contents := make(map[string]string)
+			for _, fc := range files {
+				data, err := fc.ReadContent()
+				require.NoError(t, err)
+				contents[fc.Path] = string(data)
+			}
+			require.NotContains(t, contents, "src/main.c")
+			require.Equal(t, "int y;\n", contents["lib/main.c"])
+			require.Equal(t, "readme\n", contents["README"])
+			require.Equal(t, "readme, copied\n", contents["README.txt"])
+			if mode == CommitModeWorktree {
+				require.NoFileExists(t, filepath.Join(w.path, "src", "main.c"))
+				require.FileExists(t, filepath.Join(w.path, "lib", "main.c"))
+			}
+		})
+	}
+}
+
+func TestWriterSetContext(t *testing.T) {
+	for _, mode := range []string{CommitModeWorktree, CommitModeObjects} {
+		t.Run(mode, func(t *testing.T) {
+			w, _ := writeTreeTestRepo(t, mode)
+			head := w.HeadHash()
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			w.SetContext(ctx)
+
+			err := w.ApplyCommit(&vcs.Commit{Author: "Alice", Date: time.Now(), Message: "late\n", Files: []vcs.FileChange{
+				{Path: "late.txt", Action: vcs.ActionAdd, Content: []byte("late\n")},
+			}})
+			require.ErrorIs(t, err, context.Canceled)
+			require.ErrorIs(t, w.CreateBranch("late", "HEAD"), context.Canceled)
+			require.Equal(t, head, w.HeadHash())
+
+			w.SetContext(nil)
+			require.NoError(t, w.CreateBranch("late", "HEAD"))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package vcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WriterFactory creates a writer for a target type. Options carry
+// target-specific settings (e.g. the CVSROOT of a CVS target).
+type WriterFactory func(options map[string]string) (VCSWriter, error)
+
+var (
+	writersMu sync.RWMutex
+	writers   = make(map[string]WriterFactory)
+)
+
+// RegisterWriter makes a writer available under the given target type.
+// Writer packages call it from init. It panics if the name is registered
+// twice or the factory is nil.
+func RegisterWriter(name string, factory WriterFactory) {
+	writersMu.Lock()
+	defer writersMu.Unlock()
+
+	if factory == nil {
+		panic("vcs: RegisterWriter factory is nil for " + name)
+	}
+	if _, dup := writers[name]; dup {
+		panic("vcs: RegisterWriter called twice for " + name)
+	}
+	writers[name] = factory
+}
+
+// NewWriter creates a writer for the registered target type
+func NewWriter(name string, options map[string]string) (VCSWriter, error) {
+	writersMu.RLock()
+	factory, ok := writers[name]
+	writersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported target type: %s (supported: %s)", name, strings.Join(WriterTypes(), ", "))
+	}
+	return factory(options)
+}
+
+// WriterTypes returns the registered target types in sorted order
+func WriterTypes() []string {
+	writersMu.RLock()
+	defer writersMu.RUnlock()
+
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
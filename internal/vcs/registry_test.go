@@ -0,0 +1,39 @@
+package vcs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterWriterAndNewWriter(t *testing.T) {
+	wantErr := errors.New("factory called")
+	var gotOptions map[string]string
+	RegisterWriter("test-registry", func(options map[string]string) (VCSWriter, error) {
+		gotOptions = options
+		return nil, wantErr
+	})
+	defer func() {
+		writersMu.Lock()
+		delete(writers, "test-registry")
+		writersMu.Unlock()
+	}()
+
+	require.Contains(t, WriterTypes(), "test-registry")
+
+	_, err := NewWriter("test-registry", map[string]string{"k": "v"})
+	require.ErrorIs(t, err, wantErr)
+	require.Equal(t, "v", gotOptions["k"])
+
+	require.Panics(t, func() {
+		RegisterWriter("test-registry", func(map[string]string) (VCSWriter, error) { return nil, nil })
+	})
+	require.Panics(t, func() { RegisterWriter("test-nil", nil) })
+}
+
+func TestNewWriter_Unknown(t *testing.T) {
+	_, err := NewWriter("no-such-target", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported target type")
+}
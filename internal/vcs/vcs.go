@@ -2,25 +2,79 @@
 package vcs
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"time"
 )
 
 // Commit represents a single commit in a VCS
 type Commit struct {
-	Revision string    // VCS-specific revision identifier
-	Author   string    // Commit author
-	Email    string    // Author email (if available)
-	Date     time.Time // Commit timestamp
-	Message  string    // Commit message
-	Branch   string    // Branch name (empty for trunk/main)
-	Files    []FileChange
+	Revision       string    // VCS-specific revision identifier
+	Author         string    // Commit author
+	Email          string    // Author email (if available)
+	Date           time.Time // Commit timestamp
+	Committer      string    // Committer, if different from the author
+	CommitterEmail string    // Committer email (if available)
+	CommitDate     time.Time // Commit timestamp of the committer (zero = Date)
+	Message        string    // Commit message
+	Branch         string    // Branch name (empty for trunk/main)
+	Parents        []string  // Merged commits, as source revisions or Git hashes
+	Files          []FileChange
+}
+
+// CommitterIdentity returns the committer of the commit, falling back to the
+// author for sources that record a single identity
+func (c *Commit) CommitterIdentity() (name, email string, when time.Time) {
+	name, email, when = c.Committer, c.CommitterEmail, c.CommitDate
+	if name == "" {
+		name, email = c.Author, c.Email
+	}
+	if when.IsZero() {
+		when = c.Date
+	}
+	return name, email, when
 }
 
 // FileChange represents a file change in a commit
 type FileChange struct {
-	Path    string // File path
-	Action  Action // Add, Modify, Delete
-	Content []byte // File content (for Add/Modify)
+	Path     string        // File path
+	Action   Action        // Add, Modify, Delete, Rename or Copy
+	OldPath  string        // Path renamed or copied from (for Rename/Copy)
+	Revision string        // Source revision of this file (e.g. CVS "1.4"), if known
+	Content  []byte        // File content at Path (all but Delete), used when Source is nil
+	Source   ContentSource // Lazily opens the file content (all but Delete)
+	Binary   bool          // The source stores the file as binary (e.g. CVS -kb); content is then never treated as text
+	Keywords string        // Keyword substitution mode of the source (e.g. CVS "kv", "o"), if known
+}
+
+// ContentSource opens file content on demand so that readers do not need to
+// hold the content of the whole history in memory
+type ContentSource func() (io.ReadCloser, error)
+
+// Open returns a reader over the file content, materializing it from Source
+// when one is set
+func (fc *FileChange) Open() (io.ReadCloser, error) {
+	if fc.Source != nil {
+		return fc.Source()
+	}
+	return io.NopCloser(bytes.NewReader(fc.Content)), nil
+}
+
+// ReadContent returns the full file content
+func (fc *FileChange) ReadContent() ([]byte, error) {
+	if fc.Source == nil {
+		return fc.Content, nil
+	}
+	rc, err := fc.Source()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	if closeErr := rc.Close(); err == nil {
+		err = closeErr
+	}
+	return data, err
 }
 
 // Action represents the type of file change
@@ -30,8 +84,38 @@ const (
 	ActionModify Action = iota
 	ActionAdd
 	ActionDelete
+	ActionRename // Moves OldPath to Path, possibly changing its content
+	ActionCopy   // Adds Path as a copy of OldPath, possibly changed, keeping OldPath
 )
 
+// BasicChanges returns files with every rename replaced by the deletion of
+// its old path and the addition of its new path, and every copy by the
+// addition of its new path. Writers that cannot record renames and copies
+// apply these instead.
+func BasicChanges(files []FileChange) []FileChange {
+	var out []FileChange
+	for i, fc := range files {
+		if fc.Action != ActionRename && fc.Action != ActionCopy {
+			if out != nil {
+				out = append(out, fc)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]FileChange, 0, len(files)+1), files[:i]...)
+		}
+		if fc.Action == ActionRename {
+			out = append(out, FileChange{Path: fc.OldPath, Action: ActionDelete})
+		}
+		fc.Action, fc.OldPath = ActionAdd, ""
+		out = append(out, fc)
+	}
+	if out == nil {
+		return files
+	}
+	return out
+}
+
 // VCSReader defines the interface for reading from a VCS repository
 type VCSReader interface {
 	// Validate checks if the repository is valid and accessible
@@ -50,6 +134,87 @@ type VCSReader interface {
 	Close() error
 }
 
+// TagInfo describes a tag of the source repository
+type TagInfo struct {
+	Name     string    // Original tag name
+	Path     string    // File of the newest tagged revision, for per-file VCSs
+	Revision string    // Newest tagged revision
+	Author   string    // Author of the newest tagged revision
+	Date     time.Time // Date of the newest tagged revision
+}
+
+// TagDetailer is implemented by readers that can describe their tags in
+// more detail than GetTags
+type TagDetailer interface {
+	// GetTagDetails returns tag information keyed by tag name
+	GetTagDetails() (map[string]TagInfo, error)
+}
+
+// SubtreeTagDetailer is implemented by readers of per-file VCSs, whose tags
+// can cover any part of the tree
+type SubtreeTagDetailer interface {
+	// GetSubtreeTagDetails returns the tags covering files below dir but
+	// not below any of the excluded directories, each with its newest
+	// tagged revision among those files
+	GetSubtreeTagDetails(dir string, exclude ...string) (map[string]TagInfo, error)
+}
+
+// ContextSetter is implemented by readers and writers whose operations can
+// stop early: once the context set by SetContext is done, they fail with
+// its error instead of running on
+type ContextSetter interface {
+	// SetContext sets the context of the following operations; nil sets
+	// none
+	SetContext(ctx context.Context)
+}
+
+// ContextErr returns the error of ctx, or nil for a nil ctx
+func ContextErr(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// ContextReader returns a reader of r that fails with the error of ctx once
+// ctx is done, even while a read is blocked in the operating system, e.g.
+// on a hung NFS mount. The blocked read is left to finish in the
+// background. A nil ctx returns r.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx == nil {
+		return r
+	}
+	return contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	// An abandoned read must not write to p once Read returned
+	buf := make([]byte, len(p))
+	go func() {
+		n, err := c.r.Read(buf)
+		done <- result{n, err}
+	}()
+	select {
+	case res := <-done:
+		return copy(p, buf[:res.n]), res.err
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	}
+}
+
 // CommitIterator provides iteration over commits
 type CommitIterator interface {
 	// Next advances to the next commit, returns false when done
@@ -67,19 +232,64 @@ type VCSWriter interface {
 	// Init creates a new repository at the given path
 	Init(path string) error
 
+	// Open opens an existing repository at the given path
+	Open(path string) error
+
 	// ApplyCommit applies a commit to the repository
 	ApplyCommit(commit *Commit) error
 
 	// CreateBranch creates a new branch
 	CreateBranch(name, revision string) error
 
-	// CreateTag creates a new tag
-	CreateTag(name, revision string) error
+	// CreateTag creates a new tag; targets without annotated tags ignore
+	// the message
+	CreateTag(name, revision, message string) error
 
 	// Close releases any resources
 	Close() error
 }
 
+// CommitTracker is implemented by writers that can identify the commits
+// they create, which lets migrations skip already applied commits
+type CommitTracker interface {
+	// LastCommitHash returns the identifier of the most recently applied
+	// commit, or an empty string if none was applied
+	LastCommitHash() string
+
+	// HasCommit reports whether the target contains the commit
+	HasCommit(hash string) bool
+}
+
+// HeadTracker is implemented by writers that can report the commit the next
+// ApplyCommit builds on, which lets an interrupted migration find out whether
+// its last commit was written
+type HeadTracker interface {
+	// HeadHash returns the identifier of the current head commit, or an
+	// empty string if the target has no commits
+	HeadHash() string
+}
+
+// HeadResetter is implemented by writers that can move their head back to
+// an earlier commit, which lets a migration be rewound to a checkpoint
+type HeadResetter interface {
+	// ResetHead points the current branch at the commit, discarding the
+	// commits after it
+	ResetHead(hash string) error
+}
+
+// HistoryRewinder is implemented by writers that can also take back the
+// branches and tags written after a commit, which lets a target be rolled
+// back to a checkpoint
+type HistoryRewinder interface {
+	HeadResetter
+	// CommitsAfter returns the commits reachable from a branch or tag but
+	// not from the commit
+	CommitsAfter(hash string) ([]string, error)
+	// RewindRefs points the branches beyond the commit at it and deletes the
+	// tags on later commits, returning their names
+	RewindRefs(hash string) (branches, tags []string, err error)
+}
+
 // RepositoryInfo contains metadata about a repository
 type RepositoryInfo struct {
 	Path     string
@@ -1,6 +1,11 @@
 package vcs
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,3 +46,107 @@ func TestCommitWithDate(t *testing.T) {
 	c := Commit{Date: now}
 	require.Equal(t, now, c.Date)
 }
+
+func TestCommitterIdentity(t *testing.T) {
+	authored := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := Commit{Author: "alice", Email: "alice@example.com", Date: authored}
+
+	name, email, when := c.CommitterIdentity()
+	require.Equal(t, "alice", name)
+	require.Equal(t, "alice@example.com", email)
+	require.Equal(t, authored, when)
+
+	committed := authored.Add(time.Hour)
+	c.Committer, c.CommitterEmail, c.CommitDate = "bot", "bot@example.com", committed
+	name, email, when = c.CommitterIdentity()
+	require.Equal(t, "bot", name)
+	require.Equal(t, "bot@example.com", email)
+	require.Equal(t, committed, when)
+}
+
+func TestFileChangeOpen_PrefersSource(t *testing.T) {
+	calls := 0
+	fc := FileChange{
+		Path:    "a.txt",
+		Content: []byte("inline"),
+		Source: func() (io.ReadCloser, error) {
+			calls++
+			return io.NopCloser(strings.NewReader("lazy")), nil
+		},
+	}
+
+	data, err := fc.ReadContent()
+	require.NoError(t, err)
+	require.Equal(t, "lazy", string(data))
+	require.Equal(t, 1, calls)
+}
+
+func TestFileChangeOpen_FallsBackToContent(t *testing.T) {
+	fc := FileChange{Path: "a.txt", Content: []byte("inline")}
+
+	rc, err := fc.Open()
+	require.NoError(t, err)
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "inline", string(data))
+}
+
+func TestFileChangeReadContent_SourceError(t *testing.T) {
+	fc := FileChange{
+		Path: "a.txt",
+		Source: func() (io.ReadCloser, error) {
+			return nil, errors.New("boom")
+		},
+	}
+
+	_, err := fc.ReadContent()
+	require.Error(t, err)
+}
+
+func TestBasicChanges(t *testing.T) {
+	files := []FileChange{{Path: "a", Action: ActionModify}}
+	require.Equal(t, files, BasicChanges(files))
+
+	files = []FileChange{
+		{Path: "a", Action: ActionModify},
+		{Path: "new.c", OldPath: "old.c", Action: ActionRename, Content: []byte("x")},
+		{Path: "copy.c", OldPath: "new.c", Action: ActionCopy, Content: []byte("y")},
+		{Path: "b", Action: ActionDelete},
+	}
+	require.Equal(t, []FileChange{
+		{Path: "a", Action: ActionModify},
+		{Path: "old.c", Action: ActionDelete},
+		{Path: "new.c", Action: ActionAdd, Content: []byte("x")},
+		{Path: "copy.c", Action: ActionAdd, Content: []byte("y")},
+		{Path: "b", Action: ActionDelete},
+	}, BasicChanges(files))
+	require.Equal(t, ActionRename, files[1].Action, "the input is not modified")
+}
+
+func TestParseError(t *testing.T) {
+	err := error(&ParseError{File: "a.txt,v", Line: 3, Err: errors.New("unexpected token")})
+	require.Equal(t, "a.txt,v:3: unexpected token", err.Error())
+	require.ErrorIs(t, fmt.Errorf("reading: %w", err), ErrParse)
+	require.Equal(t, "a.txt,v: unexpected token", (&ParseError{File: "a.txt,v", Err: errors.New("unexpected token")}).Error())
+	require.False(t, errors.Is(errors.New("other"), ErrParse))
+}
+
+func TestContextReader(t *testing.T) {
+	require.Equal(t, strings.NewReader("a"), ContextReader(nil, strings.NewReader("a")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	data, err := io.ReadAll(ContextReader(ctx, strings.NewReader("content")))
+	require.NoError(t, err)
+	require.Equal(t, "content", string(data))
+
+	// A read blocked for good, like one on a hung NFS mount, is given up
+	blocked, _ := io.Pipe()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = ContextReader(ctx, blocked).Read(make([]byte, 8))
+	require.ErrorIs(t, err, context.Canceled)
+}
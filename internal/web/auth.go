@@ -0,0 +1,120 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role is the access an API token grants
+type Role string
+
+const (
+	// RoleViewer views migrations, their reports and the configuration
+	RoleViewer Role = "viewer"
+	// RoleOperator also starts, stops, pauses and resumes migrations and
+	// changes the configuration
+	RoleOperator Role = "operator"
+)
+
+// rank orders the roles; a role grants everything a lower one does
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	}
+	return 0
+}
+
+// ParseRole returns the role of a name
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if role.rank() == 0 {
+		return "", fmt.Errorf("unknown role %q (use %s or %s)", name, RoleViewer, RoleOperator)
+	}
+	return role, nil
+}
+
+// requestToken returns the bearer token of a request. Browsers cannot set
+// headers on WebSocket requests, so their token query parameter, kept by
+// stripQueryToken, is accepted too.
+func requestToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	token, _ := r.Context().Value(queryTokenKey{}).(string)
+	return token
+}
+
+// queryTokenKey is the context key of the token query parameter of a
+// WebSocket request
+type queryTokenKey struct{}
+
+// stripQueryToken is the middleware removing the token query parameter from
+// the URL, so that it never reaches the request log. The token is kept for
+// requestToken on the WebSocket routes only.
+func stripQueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has("token") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		if strings.HasPrefix(r.URL.Path, "/ws/") {
+			ctx = context.WithValue(ctx, queryTokenKey{}, query.Get("token"))
+		}
+		query.Del("token")
+		r = r.Clone(ctx)
+		r.URL.RawQuery = query.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokens returns the API tokens, which Reload may replace
+func (s *Server) tokens() map[string]Role {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Tokens
+}
+
+// tokenRole returns the role of a token, or "" if it is unknown
+func (s *Server) tokenRole(token string) Role {
+	var role Role
+	for known, r := range s.tokens() {
+		// Compare every token in constant time so timing reveals none of them
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			role = r
+		}
+	}
+	return role
+}
+
+// requireRole is the middleware declaring the role a route needs. Without
+// configured tokens every request is allowed.
+func (s *Server) requireRole(role Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(s.tokens()) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			granted := s.tokenRole(requestToken(r))
+			if granted == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="git-migrator"`)
+				s.writeProblem(w, r, "UNAUTHORIZED", "A valid API token is required")
+				return
+			}
+			if granted.rank() < role.rank() {
+				s.writeProblem(w, r, "FORBIDDEN", fmt.Sprintf("The %s role is required", role))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
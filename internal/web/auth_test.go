@@ -0,0 +1,83 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("operator")
+	require.NoError(t, err)
+	require.Equal(t, RoleOperator, role)
+	_, err = ParseRole("admin")
+	require.ErrorContains(t, err, `unknown role "admin"`)
+}
+
+func TestServerRoles(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080, Tokens: map[string]Role{"view": RoleViewer, "ops": RoleOperator}})
+	drainAfter(t, server)
+	server.migrations["m1"] = &MigrationStatus{ID: "m1", Status: "completed"}
+
+	request := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader("{}"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Health and the UI pages need no token
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/api/health", "").Code)
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/", "").Code)
+
+	rec := request(http.MethodGet, "/api/migrations/m1", "")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"UNAUTHORIZED"`)
+	require.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+	require.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/migrations/m1", "wrong").Code)
+
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/api/migrations/m1", "view").Code)
+	require.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/migrations/m1?token=view", "").Code,
+		"query tokens are only accepted by the WebSocket")
+	require.NotEqual(t, http.StatusUnauthorized, request(http.MethodGet, "/ws/progress/m1?token=view", "").Code)
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/api/migrations/m1", "ops").Code)
+
+	rec = request(http.MethodPost, "/api/migrations/m1/stop", "view")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "The operator role is required")
+	require.Equal(t, http.StatusForbidden, request(http.MethodPost, "/api/migrations", "view").Code)
+	require.Equal(t, http.StatusForbidden, request(http.MethodPost, "/api/config", "view").Code)
+	migration, _ := server.migrationSnapshot("m1")
+	require.Equal(t, "completed", migration.Status)
+
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/api/migrations/m1/stop", "ops").Code)
+}
+
+func TestServerWithoutTokens(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestStripQueryToken(t *testing.T) {
+	var uri, token string
+	handler := stripQueryToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri, token = r.RequestURI, requestToken(r)
+	}))
+	for _, tt := range []struct{ target, uri, token string }{
+		{"/ws/progress/m1?token=secret", "/ws/progress/m1", "secret"},
+		{"/api/migrations?limit=5&token=secret", "/api/migrations?limit=5", ""},
+		{"/api/migrations?limit=5", "/api/migrations?limit=5", ""},
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+		require.Equal(t, tt.uri, uri, tt.target)
+		require.Equal(t, tt.token, token, tt.target)
+	}
+}
@@ -9,6 +9,12 @@ import (
 //go:embed static/*
 var staticFiles embed.FS
 
+// indexHTML is the page of the single-page UI, whose views are templates in
+// it and whose scripts are the modules under static/js
+//
+//go:embed static/index.html
+var indexHTML []byte
+
 // getStaticFS returns the embedded static file system
 func getStaticFS() http.FileSystem {
 	fsys, _ := fs.Sub(staticFiles, "static")
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStaticAssetsResolve checks that every asset the UI page loads and
+// every module the scripts import is embedded
+func TestStaticAssetsResolve(t *testing.T) {
+	refs := regexp.MustCompile(`(?:src|href)="/static/([^"]+)"`).FindAllStringSubmatch(string(indexHTML), -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		_, err := fs.Stat(staticFiles, "static/"+ref[1])
+		require.NoError(t, err, ref[0])
+	}
+
+	imports := regexp.MustCompile(`(?m)^import .* from '(\./[^']+)';$`)
+	modules, err := fs.Glob(staticFiles, "static/js/*.js")
+	require.NoError(t, err)
+	require.NotEmpty(t, modules)
+	for _, module := range modules {
+		data, err := fs.ReadFile(staticFiles, module)
+		require.NoError(t, err)
+		for _, imp := range imports.FindAllStringSubmatch(string(data), -1) {
+			_, err := fs.Stat(staticFiles, path.Join(path.Dir(module), imp[1]))
+			require.NoError(t, err, "%s imports %s", module, imp[1])
+		}
+	}
+
+	// Every view the router mounts has a template
+	data, err := fs.ReadFile(staticFiles, "static/js/main.js")
+	require.NoError(t, err)
+	for _, view := range regexp.MustCompile(`template: '([^']+)'`).FindAllStringSubmatch(string(data), -1) {
+		require.Contains(t, string(indexHTML), `<template id="`+view[1]+`">`)
+	}
+}
+
+func TestServeApp(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	for _, route := range []string{"/", "/new", "/config", "/migration/abc", "/migration/abc/report"} {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+		require.Equal(t, http.StatusOK, rec.Code, route)
+		require.Equal(t, string(indexHTML), rec.Body.String(), route)
+	}
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/js/main.js", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/javascript"), "modules need a JavaScript type")
+
+	// The script URL of earlier releases still loads the UI
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "import('/static/js/main.js')")
+}
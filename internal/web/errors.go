@@ -0,0 +1,34 @@
+package web
+
+import (
+	"errors"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// errorCode returns the API error code of a failure by its kind, or
+// fallback for failures of no known kind
+func errorCode(err error, fallback string) string {
+	switch {
+	case errors.Is(err, vcs.ErrNotARepo):
+		return "INVALID_REPOSITORY"
+	case errors.Is(err, vcs.ErrUnreachable):
+		return "SOURCE_UNREACHABLE"
+	case errors.Is(err, vcs.ErrTimeout):
+		return "TIMEOUT"
+	case errors.Is(err, vcs.ErrParse):
+		return "PARSE_ERROR"
+	case errors.Is(err, mapping.ErrUnmappedAuthor):
+		return "UNMAPPED_AUTHOR"
+	case errors.Is(err, core.ErrConflict):
+		return "CONFLICT"
+	case errors.Is(err, core.ErrInterrupted):
+		return "INTERRUPTED"
+	case errors.Is(err, storage.ErrCorrupt):
+		return "STATE_CORRUPT"
+	}
+	return fallback
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{fmt.Errorf("validation failed: %w", vcs.ErrNotARepo), "INVALID_REPOSITORY"},
+		{fmt.Errorf("reading: %w", &vcs.ParseError{File: "a,v", Line: 2, Err: errors.New("bad")}), "PARSE_ERROR"},
+		{fmt.Errorf("commit 1.1: %w: bob", mapping.ErrUnmappedAuthor), "UNMAPPED_AUTHOR"},
+		{fmt.Errorf("%w: held by pid 1", core.ErrLocked), "CONFLICT"},
+		{core.ErrTargetModified, "CONFLICT"},
+		{core.ErrStopped, "INTERRUPTED"},
+		{fmt.Errorf("cvs rlog failed: %w: exit status 1", vcs.ErrUnreachable), "SOURCE_UNREACHABLE"},
+		{fmt.Errorf("failed to read source history: %w after 1m0s", vcs.ErrTimeout), "TIMEOUT"},
+		{fmt.Errorf("failed to ping database: %w", storage.ErrCorrupt), "STATE_CORRUPT"},
+		{errors.New("disk full"), "MIGRATION_FAILED"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.code, errorCode(tt.err, "MIGRATION_FAILED"), tt.err.Error())
+	}
+}
+
+func TestFinishMigration_ErrorCode(t *testing.T) {
+	server := NewServer(ServerConfig{})
+	server.migrations["m1"] = &MigrationStatus{ID: "m1", Status: "running", CreatedAt: time.Now()}
+
+	require.Equal(t, "failed", server.finishMigration("m1", fmt.Errorf("commit r2: %w: bob", mapping.ErrUnmappedAuthor), nil))
+	require.Equal(t, "UNMAPPED_AUTHOR", server.migrations["m1"].ErrorCode)
+
+	require.Equal(t, "completed", server.finishMigration("m1", nil, nil))
+	require.Empty(t, server.migrations["m1"].ErrorCode)
+}
+
+func TestErrorCatalog(t *testing.T) {
+	for _, code := range []string{"INVALID_REPOSITORY", "SOURCE_UNREACHABLE", "TIMEOUT", "PARSE_ERROR", "UNMAPPED_AUTHOR", "CONFLICT", "INTERRUPTED", "STATE_CORRUPT", "MIGRATION_FAILED"} {
+		require.Contains(t, errorCatalog, code, "errorCode results are in the catalog")
+	}
+	require.Equal(t, "INTERNAL_ERROR", newProblem("NO_SUCH_CODE", "").Code)
+}
@@ -0,0 +1,174 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes of GET /api/migrations
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// MigrationList is a page of migrations
+type MigrationList struct {
+	Migrations []*MigrationStatus `json:"migrations"`
+	Total      int                `json:"total"` // Migrations matching the filters
+	Page       int                `json:"page"`
+	PageSize   int                `json:"pageSize"`
+	TotalPages int                `json:"totalPages"`
+}
+
+// listQuery holds the filters, sort order and page of a migration list
+type listQuery struct {
+	statuses   map[string]bool // Empty = any status
+	sourceType string
+	since      time.Time // Created at or after (zero = no bound)
+	until      time.Time // Created before (zero = no bound)
+	sortField  string
+	descending bool
+	page       int
+	pageSize   int
+}
+
+// parseListQuery reads the query parameters of GET /api/migrations:
+// status (comma-separated), sourceType, since and until (RFC 3339 or
+// YYYY-MM-DD, applied to the creation time), sort (createdAt, updatedAt,
+// status or percentage; prefix with "-" for descending, default
+// "-createdAt"), page (from 1) and pageSize
+func parseListQuery(r *http.Request) (listQuery, error) {
+	values := r.URL.Query()
+	q := listQuery{
+		sourceType: values.Get("sourceType"),
+		sortField:  "createdAt",
+		descending: true,
+		page:       1,
+		pageSize:   defaultPageSize,
+	}
+
+	if v := values.Get("status"); v != "" {
+		q.statuses = make(map[string]bool)
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				q.statuses[status] = true
+			}
+		}
+	}
+
+	var err error
+	if q.since, err = parseListTime(values.Get("since"), false); err != nil {
+		return q, fmt.Errorf("since: %w", err)
+	}
+	if q.until, err = parseListTime(values.Get("until"), true); err != nil {
+		return q, fmt.Errorf("until: %w", err)
+	}
+
+	if v := values.Get("sort"); v != "" {
+		q.descending = strings.HasPrefix(v, "-")
+		q.sortField = strings.TrimPrefix(v, "-")
+		switch q.sortField {
+		case "createdAt", "updatedAt", "status", "percentage":
+		default:
+			return q, fmt.Errorf("sort: unknown field %q", q.sortField)
+		}
+	}
+
+	if v := values.Get("page"); v != "" {
+		if q.page, err = strconv.Atoi(v); err != nil || q.page < 1 {
+			return q, fmt.Errorf("page must be a positive number")
+		}
+	}
+	if v := values.Get("pageSize"); v != "" {
+		if q.pageSize, err = strconv.Atoi(v); err != nil || q.pageSize < 1 || q.pageSize > maxPageSize {
+			return q, fmt.Errorf("pageSize must be between 1 and %d", maxPageSize)
+		}
+	}
+	return q, nil
+}
+
+// parseListTime parses an RFC 3339 time or a date. A date used as an upper
+// bound includes the whole day.
+func parseListTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC 3339 nor YYYY-MM-DD", v)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// match reports whether a migration passes the filters
+func (q listQuery) match(m *MigrationStatus) bool {
+	if len(q.statuses) > 0 && !q.statuses[m.Status] {
+		return false
+	}
+	if q.sourceType != "" && m.SourceType != q.sourceType {
+		return false
+	}
+	if !q.since.IsZero() && m.CreatedAt.Before(q.since) {
+		return false
+	}
+	if !q.until.IsZero() && !m.CreatedAt.Before(q.until) {
+		return false
+	}
+	return true
+}
+
+// less orders two migrations by the sort field, then by ID so pages are
+// stable
+func (q listQuery) less(a, b *MigrationStatus) bool {
+	var cmp int
+	switch q.sortField {
+	case "updatedAt":
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	case "status":
+		cmp = strings.Compare(a.Status, b.Status)
+	case "percentage":
+		cmp = a.Percentage - b.Percentage
+	default:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID, b.ID)
+	}
+	if q.descending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// apply filters, sorts and pages migrations
+func (q listQuery) apply(migrations []*MigrationStatus) MigrationList {
+	matched := make([]*MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		if q.match(m) {
+			matched = append(matched, m)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return q.less(matched[i], matched[j]) })
+
+	list := MigrationList{
+		Migrations: []*MigrationStatus{},
+		Total:      len(matched),
+		Page:       q.page,
+		PageSize:   q.pageSize,
+		TotalPages: (len(matched) + q.pageSize - 1) / q.pageSize,
+	}
+	if start := (q.page - 1) * q.pageSize; start < len(matched) {
+		list.Migrations = matched[start:min(start+q.pageSize, len(matched))]
+	}
+	return list
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerListMigrationsQuery(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	statuses := []string{"completed", "failed", "running", "completed", "stopped"}
+	for i, status := range statuses {
+		id := fmt.Sprintf("m%d", i)
+		server.migrations[id] = &MigrationStatus{
+			ID:         id,
+			Status:     status,
+			SourceType: "cvs",
+			Percentage: 10 * i,
+			CreatedAt:  base.AddDate(0, 0, i),
+		}
+	}
+	server.migrations["m1"].SourceType = "svn"
+
+	list := func(query string) (MigrationList, int) {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations?"+query, nil))
+		var response struct {
+			Data MigrationList `json:"data"`
+		}
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		}
+		return response.Data, rec.Code
+	}
+	ids := func(l MigrationList) []string {
+		var ids []string
+		for _, m := range l.Migrations {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	// Newest first by default
+	page, code := list("")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []string{"m4", "m3", "m2", "m1", "m0"}, ids(page))
+	require.Equal(t, 5, page.Total)
+	require.Equal(t, 1, page.TotalPages)
+
+	page, _ = list("pageSize=2&page=2&sort=createdAt")
+	require.Equal(t, []string{"m2", "m3"}, ids(page))
+	require.Equal(t, 3, page.TotalPages)
+
+	page, _ = list("pageSize=2&page=9")
+	require.Empty(t, page.Migrations)
+	require.Equal(t, 5, page.Total)
+
+	page, _ = list("status=completed,stopped&sort=-percentage")
+	require.Equal(t, []string{"m4", "m3", "m0"}, ids(page))
+
+	page, _ = list("sourceType=svn")
+	require.Equal(t, []string{"m1"}, ids(page))
+
+	// until includes the whole day
+	page, _ = list("since=2024-03-02&until=2024-03-03")
+	require.Equal(t, []string{"m2", "m1"}, ids(page))
+	page, _ = list("since=2024-03-04T00:00:00Z")
+	require.Equal(t, []string{"m4", "m3"}, ids(page))
+
+	for _, query := range []string{"page=0", "pageSize=1000", "sort=name", "since=yesterday"} {
+		_, code = list(query)
+		require.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
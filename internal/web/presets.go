@@ -0,0 +1,242 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/adamf123git/git-migrator/internal/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+// presetName is the form of preset names, which appear in URLs
+var presetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// presetsFile is the format of ServerConfig.PresetsPath
+type presetsFile struct {
+	Presets []Preset `json:"presets"`
+}
+
+// loadPresets reads the presets kept at path; a missing file holds none
+func loadPresets(path string) (map[string]Preset, error) {
+	presets := make(map[string]Preset)
+	if path == "" {
+		return presets, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return presets, nil
+	}
+	if err != nil {
+		return presets, fmt.Errorf("failed to read presets: %w", err)
+	}
+	var file presetsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return presets, fmt.Errorf("failed to parse presets %s: %w", path, err)
+	}
+	for _, preset := range file.Presets {
+		if err := preset.validate(); err != nil {
+			return make(map[string]Preset), fmt.Errorf("invalid preset %q in %s: %w", preset.Name, path, err)
+		}
+		presets[preset.Name] = preset
+	}
+	return presets, nil
+}
+
+// savePresets writes the presets file at path, replacing it atomically
+func savePresets(path string, presets map[string]Preset) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(presetsFile{Presets: sortedPresets(presets)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(path, data, 0o600)
+}
+
+// sortedPresets returns the presets ordered by name
+func sortedPresets(presets map[string]Preset) []Preset {
+	list := make([]Preset, 0, len(presets))
+	for _, preset := range presets {
+		list = append(list, preset)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// validate checks a preset before it is stored, reporting every violation
+func (p Preset) validate() error {
+	v := &validation.Validator{}
+	v.Check(presetName.MatchString(p.Name), "name", "name must be 1-64 letters, digits, '.', '_' or '-', starting with a letter or digit")
+	checkAuthorMap(v, p.AuthorMap)
+	checkJobOptions(v, p.Options)
+	return v.Err()
+}
+
+// apply returns req with the source type, maps and options of the preset
+// filled in where the request leaves them out
+func (p Preset) apply(req StartMigrationRequest) StartMigrationRequest {
+	if req.SourceType == "" {
+		req.SourceType = p.SourceType
+	}
+	req.AuthorMap = mergeMaps(p.AuthorMap, req.AuthorMap)
+	req.BranchMap = mergeMaps(p.BranchMap, req.BranchMap)
+	req.TagMap = mergeMaps(p.TagMap, req.TagMap)
+	req.Options = mergeMaps(p.Options, req.Options)
+	return req
+}
+
+// mergeMaps returns the entries of base overridden by those of override
+func mergeMaps[V any](base, override map[string]V) map[string]V {
+	if len(base) == 0 {
+		return override
+	}
+	merged := make(map[string]V, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// preset returns the preset with the name
+func (s *Server) preset(name string) (Preset, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	preset, exists := s.presets[name]
+	return preset, exists
+}
+
+// handleListPresets handles GET /api/presets
+func (s *Server) handleListPresets(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	presets := sortedPresets(s.presets)
+	s.mu.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(presets)); err != nil {
+		s.logger.Warn("failed to encode presets response", "error", err)
+	}
+}
+
+// handleGetPreset handles GET /api/presets/:name
+func (s *Server) handleGetPreset(w http.ResponseWriter, r *http.Request) {
+	preset, exists := s.preset(chi.URLParam(r, "name"))
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Preset not found")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(preset)); err != nil {
+		s.logger.Warn("failed to encode preset response", "error", err)
+	}
+}
+
+// handleSavePreset handles PUT /api/presets/:name, creating the preset or
+// replacing it
+func (s *Server) handleSavePreset(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	var preset Preset
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&preset); err != nil {
+		s.writeProblem(w, r, "INVALID_JSON", "Invalid JSON body")
+		return
+	}
+	if preset.Name == "" {
+		preset.Name = name
+	}
+	err := preset.validate()
+	if err == nil && preset.Name != name {
+		err = fmt.Errorf("name %q does not match the URL", preset.Name)
+	}
+	if err != nil {
+		s.writeInvalid(w, r, err)
+		return
+	}
+	preset.UpdatedAt = time.Now()
+
+	// Serialize updates so the file always holds the latest presets
+	s.presetsMu.Lock()
+	defer s.presetsMu.Unlock()
+
+	s.mu.RLock()
+	presets := make(map[string]Preset, len(s.presets)+1)
+	for k, v := range s.presets {
+		presets[k] = v
+	}
+	s.mu.RUnlock()
+	_, replaced := presets[name]
+	presets[name] = preset
+	if !s.storePresets(w, r, presets) {
+		return
+	}
+
+	if !replaced {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(SuccessResponse(preset)); err != nil {
+		s.logger.Warn("failed to encode preset response", "error", err)
+	}
+}
+
+// handleDeletePreset handles DELETE /api/presets/:name. Migrations started
+// with the preset are not affected.
+func (s *Server) handleDeletePreset(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	s.presetsMu.Lock()
+	defer s.presetsMu.Unlock()
+
+	s.mu.RLock()
+	_, exists := s.presets[name]
+	presets := make(map[string]Preset, len(s.presets))
+	for k, v := range s.presets {
+		if k != name {
+			presets[k] = v
+		}
+	}
+	s.mu.RUnlock()
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Preset not found")
+		return
+	}
+	if !s.storePresets(w, r, presets) {
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]string{
+		"name":    name,
+		"message": "Preset deleted",
+	})); err != nil {
+		s.logger.Warn("failed to encode delete preset response", "error", err)
+	}
+}
+
+// storePresets saves presets and makes them the presets of the server,
+// answering the request with an error if they cannot be saved.
+// s.presetsMu must be held.
+func (s *Server) storePresets(w http.ResponseWriter, r *http.Request, presets map[string]Preset) bool {
+	if err := savePresets(s.config.PresetsPath, presets); err != nil {
+		s.logger.Error("failed to save presets", "path", s.config.PresetsPath, "error", err)
+		s.writeProblem(w, r, "SAVE_FAILED", "Failed to save the presets")
+		return false
+	}
+	s.mu.Lock()
+	s.presets = presets
+	s.mu.Unlock()
+	return true
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+// presetRequest sends a request to the presets API
+func presetRequest(server *Server, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestServerPresets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web-presets.json")
+	server := NewServer(ServerConfig{PresetsPath: path})
+
+	body := `{"sourceType":"cvs","authorMap":{"alice":"Alice Smith <alice@corp.example>"},"branchMap":{"REL_1":"release-1"},"options":{"keywords":"strip","priority":"high"}}`
+	rec := presetRequest(server, http.MethodPut, "/api/presets/legacy", body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = presetRequest(server, http.MethodPut, "/api/presets/legacy", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	for _, invalid := range []struct{ target, body, message string }{
+		{"/api/presets/-bad", `{}`, "name must be"},
+		{"/api/presets/legacy", `{"name":"other"}`, "does not match the URL"},
+		{"/api/presets/legacy", `{"authorMap":{"bob":"bob"}}`, "bob: author must be written"},
+		{"/api/presets/legacy", `{"options":{"eol":"mac"}}`, "eol must be"},
+	} {
+		rec = presetRequest(server, http.MethodPut, invalid.target, invalid.body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, invalid.target)
+		require.Contains(t, rec.Body.String(), invalid.message)
+	}
+	require.Equal(t, http.StatusBadRequest, presetRequest(server, http.MethodPut, "/api/presets/legacy", `{"unknown":1}`).Code)
+
+	// Presets are kept across restarts
+	restarted := NewServer(ServerConfig{PresetsPath: path})
+	rec = presetRequest(restarted, http.MethodGet, "/api/presets", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Data []Preset `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	require.Equal(t, "legacy", list.Data[0].Name)
+	require.Equal(t, map[string]string{"REL_1": "release-1"}, list.Data[0].BranchMap)
+
+	require.Equal(t, http.StatusOK, presetRequest(restarted, http.MethodGet, "/api/presets/legacy", "").Code)
+	require.Equal(t, http.StatusOK, presetRequest(restarted, http.MethodDelete, "/api/presets/legacy", "").Code)
+	require.Equal(t, http.StatusNotFound, presetRequest(restarted, http.MethodGet, "/api/presets/legacy", "").Code)
+	require.Equal(t, http.StatusNotFound, presetRequest(restarted, http.MethodDelete, "/api/presets/legacy", "").Code)
+
+	presets, err := loadPresets(path)
+	require.NoError(t, err)
+	require.Empty(t, presets)
+}
+
+func TestServerStartWithPreset(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	rec := presetRequest(server, http.MethodPut, "/api/presets/legacy",
+		`{"sourceType":"cvs","authorMap":{"alice":"Alice <alice@old.example>","bob":"Bob <bob@corp.example>"},"options":{"keywords":"strip","dryRun":false}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	id := startTestMigration(t, server, StartMigrationRequest{
+		Preset:     "legacy",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+		AuthorMap:  map[string]string{"alice": "Alice Smith <alice@corp.example>"},
+		Options:    map[string]interface{}{"eol": "lf"},
+	})
+	migration, exists := server.migrationSnapshot(id)
+	require.True(t, exists)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+	require.Equal(t, "legacy", migration.Preset)
+	require.Equal(t, "cvs", migration.SourceType)
+	require.Equal(t, map[string]string{
+		"alice": "Alice Smith <alice@corp.example>",
+		"bob":   "Bob <bob@corp.example>",
+	}, migration.AuthorMap)
+	require.Equal(t, map[string]any{"keywords": "strip", "dryRun": false, "eol": "lf"}, migration.Options)
+
+	config := migrationConfig(&StartMigrationRequest{TargetPath: "/tmp/git", Options: migration.Options}, defaultSettings())
+	require.Equal(t, core.EOLLF, config.EOL)
+	require.Equal(t, []core.KeywordRule{{Pattern: "*", Mode: core.KeywordsStrip}}, config.Keywords)
+
+	rec = presetRequest(server, http.MethodPost, "/api/migrations",
+		`{"preset":"missing","sourcePath":"/src","targetPath":"/dst"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `Unknown preset \"missing\"`)
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/adamf123git/git-migrator/internal/validation"
+)
+
+// problemType is an entry of the error code catalog
+type problemType struct {
+	Status int
+	Title  string
+}
+
+// errorCatalog lists the stable error codes of the API. Clients branch on
+// the code; titles and details are for people and may change.
+var errorCatalog = map[string]problemType{
+	"VALIDATION_ERROR":   {http.StatusBadRequest, "Invalid request"},
+	"INVALID_JSON":       {http.StatusBadRequest, "Request body is not valid JSON"},
+	"UNSUPPORTED_SOURCE": {http.StatusBadRequest, "Source type not supported"},
+	"INVALID_REPOSITORY": {http.StatusBadRequest, "Not a repository"},
+	"UNAUTHORIZED":       {http.StatusUnauthorized, "API token required"},
+	"FORBIDDEN":          {http.StatusForbidden, "Role not allowed"},
+	"NOT_FOUND":          {http.StatusNotFound, "Resource not found"},
+	"REPORT_NOT_FOUND":   {http.StatusNotFound, "Report not available"},
+	"CONFLICT":           {http.StatusConflict, "Target in use"},
+	"NOT_PAUSABLE":       {http.StatusConflict, "Migration cannot be paused"},
+	"NOT_RESUMABLE":      {http.StatusConflict, "Migration cannot be resumed"},
+	"MIGRATION_STARTED":  {http.StatusConflict, "Migration already started"},
+	"INTERRUPTED":        {http.StatusConflict, "Migration interrupted"},
+	"PARSE_ERROR":        {http.StatusUnprocessableEntity, "Malformed source file"},
+	"UNMAPPED_AUTHOR":    {http.StatusUnprocessableEntity, "Author not mapped"},
+	"SOURCE_UNREACHABLE": {http.StatusBadGateway, "Source repository unreachable"},
+	"TIMEOUT":            {http.StatusGatewayTimeout, "Operation timed out"},
+	"STATE_CORRUPT":      {http.StatusInternalServerError, "State database corrupt"},
+	"CONFIG_ERROR":       {http.StatusInternalServerError, "Server configuration invalid"},
+	"INVALID_REPORT":     {http.StatusInternalServerError, "Report unreadable"},
+	"SAVE_FAILED":        {http.StatusInternalServerError, "Could not save"},
+	"MIGRATION_FAILED":   {http.StatusInternalServerError, "Migration failed"},
+	"INTERNAL_ERROR":     {http.StatusInternalServerError, "Internal error"},
+}
+
+// Problem is an RFC 7807 problem details document, the body of every API
+// error response
+type Problem struct {
+	Type     string            `json:"type"` // urn:git-migrator:error:<code>
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"` // Request path
+	Code     string            `json:"code"`               // Code of the errorCatalog
+	Errors   validation.Errors `json:"errors,omitempty"`   // Invalid request fields
+}
+
+// newProblem returns the problem details of an error code; unknown codes
+// are reported as INTERNAL_ERROR
+func newProblem(code, detail string) Problem {
+	entry, ok := errorCatalog[code]
+	if !ok {
+		code, entry = "INTERNAL_ERROR", errorCatalog["INTERNAL_ERROR"]
+	}
+	return Problem{
+		Type:   "urn:git-migrator:error:" + code,
+		Title:  entry.Title,
+		Status: entry.Status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// writeProblem answers a request with the problem details of an error code
+func (s *Server) writeProblem(w http.ResponseWriter, r *http.Request, code, detail string, fields ...validation.Violation) {
+	problem := newProblem(code, detail)
+	problem.Instance = r.URL.Path
+	problem.Errors = fields
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		s.logger.Warn("failed to encode problem response", "code", code, "error", err)
+	}
+}
+
+// writeInvalid answers a request with a VALIDATION_ERROR for err, listing
+// the fields of validation errors
+func (s *Server) writeInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	var violations validation.Errors
+	var violation validation.Violation
+	switch {
+	case errors.As(err, &violations):
+		s.writeProblem(w, r, "VALIDATION_ERROR", err.Error(), violations...)
+	case errors.As(err, &violation):
+		s.writeProblem(w, r, "VALIDATION_ERROR", err.Error(), violation)
+	default:
+		s.writeProblem(w, r, "VALIDATION_ERROR", err.Error())
+	}
+}
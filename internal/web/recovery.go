@@ -0,0 +1,167 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+)
+
+// StatusInterrupted is the status of a migration that was active when the
+// server stopped. It can be resumed from its last checkpoint.
+const StatusInterrupted = "interrupted"
+
+// migrationRecord is a migration as kept in ServerConfig.DatabasePath
+type migrationRecord struct {
+	Migration *MigrationStatus `json:"migration"`
+	StateFile string           `json:"stateFile,omitempty"`
+}
+
+// migrationsFile is the format of ServerConfig.DatabasePath
+type migrationsFile struct {
+	Migrations []migrationRecord `json:"migrations"`
+}
+
+// loadMigrations reads the migrations kept at path; a missing file holds
+// none
+func loadMigrations(path string) ([]migrationRecord, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	var file migrationsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse migrations %s: %w", path, err)
+	}
+	return file.Migrations, nil
+}
+
+// saveMigrations writes the migrations of the server to
+// ServerConfig.DatabasePath, so they survive a restart. Progress is not
+// written on every update; the state databases hold the checkpoints.
+func (s *Server) saveMigrations() {
+	path := s.config.DatabasePath
+	if path == "" {
+		return
+	}
+
+	s.migrationsMu.Lock()
+	defer s.migrationsMu.Unlock()
+
+	s.mu.RLock()
+	file := migrationsFile{Migrations: make([]migrationRecord, 0, len(s.migrations))}
+	for _, m := range s.migrations {
+		file.Migrations = append(file.Migrations, migrationRecord{Migration: m.snapshot(), StateFile: m.StateFile})
+	}
+	s.mu.RUnlock()
+	sort.Slice(file.Migrations, func(i, j int) bool {
+		return file.Migrations[i].Migration.CreatedAt.Before(file.Migrations[j].Migration.CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = storage.WriteFileAtomic(path, data, 0o600)
+	}
+	if err != nil {
+		s.logger.Warn("failed to save migrations", "path", path, "error", err)
+	}
+}
+
+// recoverMigrations restores the migrations kept by an earlier server.
+// Those that were active are marked interrupted, with the progress of
+// their last checkpoint, or completed if the state database says so.
+func (s *Server) recoverMigrations() {
+	records, err := loadMigrations(s.config.DatabasePath)
+	if err != nil {
+		s.logger.Warn("failed to restore migrations", "error", err)
+		return
+	}
+
+	interrupted := 0
+	for _, record := range records {
+		m := record.Migration
+		if m == nil || m.ID == "" {
+			continue
+		}
+		m.StateFile = record.StateFile
+		if m.Errors == nil {
+			m.Errors = []string{}
+		}
+		switch m.Status {
+		case "pending", "queued", "running", "paused":
+			s.interruptMigration(m)
+			if m.Status == StatusInterrupted {
+				interrupted++
+			}
+		}
+		s.migrations[m.ID] = m
+	}
+	if interrupted > 0 {
+		s.logger.Info("found migrations interrupted by a server restart", "count", interrupted)
+	}
+}
+
+// interruptMigration marks a migration that was active when the server
+// stopped as interrupted, taking its progress from its state database
+func (s *Server) interruptMigration(m *MigrationStatus) {
+	m.Status = StatusInterrupted
+	m.CurrentStep = "Interrupted by a server restart; resume to continue from the last checkpoint"
+	m.CommitsPerSecond, m.ETASeconds = 0, 0
+	m.UpdatedAt = time.Now()
+
+	state, err := checkpointState(m.StateFile, m.RepoID)
+	if err != nil {
+		s.logger.Warn("failed to read the checkpoint of an interrupted migration", "web_migration_id", m.ID, "error", err)
+		return
+	}
+	if state == nil {
+		return // Interrupted before its first checkpoint
+	}
+	m.ProcessedCommits, m.TotalCommits = state.Processed, state.Total
+	if state.Total > 0 {
+		m.Percentage = state.Processed * 100 / state.Total
+	}
+	if state.Status == "completed" {
+		// Finished while the server was stopping
+		m.Status = "completed"
+		m.Percentage = 100
+		m.CurrentStep = "Migration completed"
+	}
+}
+
+// checkpointState returns the state saved for a migration in the state
+// database at path, or nil if there is none
+func checkpointState(path, migrationID string) (*storage.MigrationState, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	db, err := storage.NewStateDB(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	state, err := db.Load(migrationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return state, err
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// setRecordedStatus rewrites the status of a migration in a migrations file,
+// as if the server had stopped with the migration in that status
+func setRecordedStatus(t *testing.T, path, id, status string) {
+	t.Helper()
+	records, err := loadMigrations(path)
+	require.NoError(t, err)
+	for _, record := range records {
+		if record.Migration.ID == id {
+			record.Migration.Status = status
+		}
+	}
+	data, err := json.Marshal(migrationsFile{Migrations: records})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestServerRecoverInterruptedMigration(t *testing.T) {
+	registry := filepath.Join(t.TempDir(), "web-migrations.json")
+	server := NewServer(ServerConfig{Port: 8080, DatabasePath: registry})
+	drainAfter(t, server)
+	source := writeBrokenCVSRepo(t)
+	id := startTestMigration(t, server, StartMigrationRequest{
+		SourceType: "cvs",
+		SourcePath: source,
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+	})
+	first, _ := server.migrationSnapshot(id)
+	require.NotEmpty(t, first.StateFile)
+
+	// Simulate a server stopped while the migration was running, after a
+	// checkpoint at the first of two commits
+	setRecordedStatus(t, registry, id, "running")
+	db, err := storage.NewStateDB(first.StateFile)
+	require.NoError(t, err)
+	require.NoError(t, db.Save(&storage.MigrationState{
+		MigrationID: first.RepoID,
+		Processed:   1,
+		Total:       2,
+		LastUpdated: time.Now(),
+		Status:      "in_progress",
+	}))
+	require.NoError(t, db.Close())
+
+	restarted := NewServer(ServerConfig{Port: 8080, DatabasePath: registry})
+	drainAfter(t, restarted)
+	migration, exists := restarted.migrationSnapshot(id)
+	require.True(t, exists)
+	require.Equal(t, StatusInterrupted, migration.Status)
+	require.Equal(t, 1, migration.ProcessedCommits)
+	require.Equal(t, 2, migration.TotalCommits)
+	require.Equal(t, 50, migration.Percentage)
+	require.Equal(t, first.StateFile, migration.StateFile)
+
+	// An interrupted migration can be resumed
+	require.NoError(t, os.RemoveAll(first.StateFile))
+	require.NoError(t, os.RemoveAll(source))
+	require.NoError(t, os.Rename(writeTestCVSRepo(t), source))
+	rec := httptest.NewRecorder()
+	restarted.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations/"+id+"/resume", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	restarted.mu.RLock()
+	j := restarted.jobs[id]
+	restarted.mu.RUnlock()
+	select {
+	case <-j.done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("resumed migration did not finish")
+	}
+	migration, _ = restarted.migrationSnapshot(id)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+
+	records, err := loadMigrations(registry)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "completed", records[0].Migration.Status)
+}
+
+func TestServerRecoverCompletedCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	registry := filepath.Join(dir, "web-migrations.json")
+	stateFile := filepath.Join(dir, "state.db")
+	db, err := storage.NewStateDB(stateFile)
+	require.NoError(t, err)
+	require.NoError(t, db.Save(&storage.MigrationState{MigrationID: "repo", Processed: 3, Total: 3, LastUpdated: time.Now(), Status: "completed"}))
+	require.NoError(t, db.Close())
+
+	data, err := json.Marshal(migrationsFile{Migrations: []migrationRecord{
+		{Migration: &MigrationStatus{ID: "done", RepoID: "repo", Status: "running"}, StateFile: stateFile},
+		{Migration: &MigrationStatus{ID: "queued", RepoID: "other", Status: "queued"}},
+		{Migration: &MigrationStatus{ID: "failed", RepoID: "other", Status: "failed"}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(registry, data, 0o600))
+
+	server := NewServer(ServerConfig{Port: 8080, DatabasePath: registry})
+	for id, status := range map[string]string{"done": "completed", "queued": StatusInterrupted, "failed": "failed"} {
+		migration, exists := server.migrationSnapshot(id)
+		require.True(t, exists, id)
+		require.Equal(t, status, migration.Status, id)
+	}
+	migration, _ := server.migrationSnapshot("done")
+	require.Equal(t, 100, migration.Percentage)
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"path/filepath"
+)
+
+// targetKey returns the target path in the form compared between migrations
+func targetKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// active reports whether a migration holds its target: it has not finished,
+// or it was stopped and is still finishing its current commit; s.mu must be
+// held
+func (s *Server) active(migration *MigrationStatus) bool {
+	switch migration.Status {
+	case "pending", "queued", "running", "paused":
+		return true
+	}
+	j, started := s.jobs[migration.ID]
+	return started && j.running()
+}
+
+// conflictingMigration returns the active migration other than id that
+// has the same migration ID or writes the same target, or nil if there is
+// none. Two runs into one repository would corrupt it. s.mu must be held.
+func (s *Server) conflictingMigration(id, repoID, targetPath string) *MigrationStatus {
+	target := targetKey(targetPath)
+	for _, migration := range s.migrations {
+		if migration.ID == id || !s.active(migration) {
+			continue
+		}
+		if repoID != "" && migration.RepoID == repoID || targetKey(migration.TargetPath) == target {
+			return migration
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func postMigration(server *Server, req StartMigrationRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations", bytes.NewReader(body)))
+	return rec
+}
+
+func TestServerRejectsSameTarget(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	target := filepath.Join(t.TempDir(), "git")
+	server.mu.Lock()
+	server.migrations["busy"] = &MigrationStatus{ID: "busy", Status: "running", SourcePath: "/cvs/a", TargetPath: target, RepoID: "a"}
+	server.mu.Unlock()
+
+	// The same target through another source or path spelling
+	for _, path := range []string{target, target + "/", filepath.Join(target, "..", "git")} {
+		rec := postMigration(server, StartMigrationRequest{SourceType: "cvs", SourcePath: writeTestCVSRepo(t), TargetPath: path})
+		require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Body.String(), `"code":"CONFLICT"`)
+		require.Contains(t, rec.Body.String(), "Migration busy is already writing "+target)
+	}
+
+	server.mu.RLock()
+	require.Len(t, server.migrations, 1)
+	server.mu.RUnlock()
+
+	// A finished migration no longer holds its target
+	server.mu.Lock()
+	server.migrations["busy"].Status = "completed"
+	server.mu.Unlock()
+	rec := postMigration(server, StartMigrationRequest{SourceType: "cvs", SourcePath: writeTestCVSRepo(t), TargetPath: target})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestServerConflictingMigration(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	finishing := &job{stop: make(chan struct{}), done: make(chan struct{})}
+	server.migrations["queued"] = &MigrationStatus{ID: "queued", Status: "queued", TargetPath: "/git/q", RepoID: "q"}
+	server.migrations["stopping"] = &MigrationStatus{ID: "stopping", Status: "stopped", TargetPath: "/git/s", RepoID: "s"}
+	server.migrations["failed"] = &MigrationStatus{ID: "failed", Status: "failed", TargetPath: "/git/f", RepoID: "f"}
+	server.jobs["stopping"] = finishing
+
+	require.Equal(t, "queued", server.conflictingMigration("new", "x", "/git/q").ID)
+	require.Equal(t, "queued", server.conflictingMigration("new", "q", "/git/other").ID, "same migration ID")
+	require.Nil(t, server.conflictingMigration("queued", "q", "/git/q"), "a migration does not conflict with itself")
+	require.Equal(t, "stopping", server.conflictingMigration("new", "x", "/git/s").ID, "still finishing its commit")
+	require.Nil(t, server.conflictingMigration("new", "f", "/git/f"))
+
+	close(finishing.done)
+	require.Nil(t, server.conflictingMigration("new", "x", "/git/s"))
+}
+
+func TestServerResumeConflict(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	server.migrations["failed"] = &MigrationStatus{ID: "failed", Status: "failed", SourceType: "cvs", TargetPath: "/git/t", RepoID: "a"}
+	server.migrations["running"] = &MigrationStatus{ID: "running", Status: "running", TargetPath: "/git/t", RepoID: "b"}
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations/failed/resume", nil))
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"CONFLICT"`)
+	migration, _ := server.migrationSnapshot("failed")
+	require.Equal(t, "failed", migration.Status)
+}
@@ -0,0 +1,370 @@
+package web
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/notify"
+	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/validation"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// maxRecentCommits is the number of applied commits kept for the commit log
+const maxRecentCommits = 200
+
+// job is a migration run by the server
+type job struct {
+	config   *core.MigrationConfig
+	stop     chan struct{}
+	stopOnce sync.Once
+	pause    core.PauseSwitch
+	done     chan struct{} // Closed when the run has finished
+}
+
+// running reports whether the run has not finished yet
+func (j *job) running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// requestStop asks the migration to stop after the current commit
+func (j *job) requestStop() {
+	j.stopOnce.Do(func() { close(j.stop) })
+}
+
+// priorities orders queued migrations by their "priority" option
+var priorities = map[string]int{"low": -1, "normal": 0, "high": 1}
+
+// jobOptions are the scheduling and resource options of a migration request
+type jobOptions struct {
+	priority     int
+	parseWorkers int    // RCS files parsed in parallel (0 = one at a time)
+	readLimit    int64  // Bytes per second read from the source (0 = unlimited)
+	opsLimit     int64  // File operations per second on the source (0 = unlimited)
+	eol          string // End-of-line policy (empty = core default)
+	keywords     string // RCS keyword mode of every file (empty = keep)
+	message      string // Commit message template (empty = source message)
+}
+
+// parseJobOptions validates the "priority", "maxParallelParses",
+// "ioLimitMBps", "ioOpsPerSecond", "eol", "keywords" and "messageTemplate"
+// options of a migration request, reporting every invalid option
+func parseJobOptions(options map[string]interface{}) (jobOptions, error) {
+	v := &validation.Validator{}
+	opts := checkJobOptions(v, options)
+	return opts, v.Err()
+}
+
+// checkJobOptions records a violation of v for every invalid option and
+// returns the valid ones
+func checkJobOptions(v *validation.Validator, options map[string]interface{}) jobOptions {
+	var opts jobOptions
+	if value, ok := options["priority"]; ok {
+		name, _ := value.(string)
+		priority, known := priorities[name]
+		if !known {
+			optionError(v, "priority", "priority must be low, normal or high")
+		}
+		opts.priority = priority
+	}
+	if value, ok := options["maxParallelParses"]; ok {
+		n, _ := value.(float64)
+		if n < 1 || n != math.Trunc(n) {
+			optionError(v, "maxParallelParses", "maxParallelParses must be a positive whole number")
+		}
+		opts.parseWorkers = int(n)
+	}
+	if value, ok := options["ioLimitMBps"]; ok {
+		mbps, _ := value.(float64)
+		if mbps <= 0 {
+			optionError(v, "ioLimitMBps", "ioLimitMBps must be a positive number")
+		}
+		opts.readLimit = max(int64(mbps*(1<<20)), 1)
+	}
+	if value, ok := options["ioOpsPerSecond"]; ok {
+		n, _ := value.(float64)
+		if n < 1 || n != math.Trunc(n) {
+			optionError(v, "ioOpsPerSecond", "ioOpsPerSecond must be a positive whole number")
+		}
+		opts.opsLimit = int64(n)
+	}
+	if value, ok := options["eol"]; ok {
+		opts.eol, _ = value.(string)
+		switch opts.eol {
+		case core.EOLAsIs, core.EOLLF, core.EOLCRLFByExtension:
+		default:
+			optionError(v, "eol", "eol must be %s, %s or %s", core.EOLAsIs, core.EOLLF, core.EOLCRLFByExtension)
+		}
+	}
+	if value, ok := options["keywords"]; ok {
+		opts.keywords, _ = value.(string)
+		switch opts.keywords {
+		case core.KeywordsKeep, core.KeywordsStrip, core.KeywordsExpand:
+		default:
+			optionError(v, "keywords", "keywords must be %s, %s or %s", core.KeywordsKeep, core.KeywordsStrip, core.KeywordsExpand)
+		}
+	}
+	if value, ok := options["messageTemplate"]; ok {
+		opts.message, _ = value.(string)
+		if opts.message == "" {
+			optionError(v, "messageTemplate", "messageTemplate must not be empty")
+		} else if _, err := core.ParseMessageTemplate(opts.message); err != nil {
+			optionError(v, "messageTemplate", "messageTemplate: %v", err)
+		}
+	}
+	return opts
+}
+
+// optionError records the violation of an invalid option
+func optionError(v *validation.Validator, name, format string, args ...any) {
+	v.Addf("options."+name, format, args...)
+}
+
+// migrationConfig builds the core configuration of a migration request
+// whose options have been validated, applying the server settings
+func migrationConfig(req *StartMigrationRequest, settings ConfigData) *core.MigrationConfig {
+	dryRun, ok := req.Options["dryRun"].(bool)
+	if !ok {
+		dryRun = settings.DryRun
+	}
+	opts, _ := parseJobOptions(req.Options)
+	config := &core.MigrationConfig{
+		SourceType:      req.SourceType,
+		SourcePath:      req.SourcePath,
+		TargetPath:      req.TargetPath,
+		AuthorMap:       req.AuthorMap,
+		BranchMap:       req.BranchMap,
+		TagMap:          req.TagMap,
+		EOL:             opts.eol,
+		MessageTemplate: opts.message,
+		DryRun:          dryRun,
+		ChunkSize:       settings.ChunkSize,
+		ParseWorkers:    opts.parseWorkers,
+		ReadLimit:       opts.readLimit,
+		OpsLimit:        opts.opsLimit,
+		ContentCacheDir: settings.ContentCacheDir,
+		SpillDir:        settings.SpillDir,
+		// Same location as the migrate command uses
+		StateFile: filepath.Join(filepath.Dir(req.TargetPath), ".git-migrator-state.db"),
+	}
+	if opts.keywords != "" {
+		config.Keywords = []core.KeywordRule{{Pattern: "*", Mode: opts.keywords}}
+	}
+	if settings.StateDir != "" {
+		// One database per repository, so migrations do not share state
+		config.StateFile = filepath.Join(settings.StateDir, core.NewMigrator(config).MigrationID()+".db")
+	}
+	if settings.Verbose {
+		config.LogDir = settings.LogDir
+		if config.LogDir == "" {
+			config.LogDir = filepath.Dir(config.StateFile)
+		}
+	}
+	return config
+}
+
+// queuedMigration is a migration waiting for a free slot
+type queuedMigration struct {
+	id       string
+	config   *core.MigrationConfig
+	priority int
+}
+
+// runMigration starts a migration, or queues it while MaxConcurrent
+// migrations are running, and returns its new status
+func (s *Server) runMigration(id string, config *core.MigrationConfig, priority int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	migration, exists := s.migrations[id]
+	if s.config.MaxConcurrent > 0 && s.runningJobs() >= s.config.MaxConcurrent {
+		s.queue = append(s.queue, queuedMigration{id: id, config: config, priority: priority})
+		if exists {
+			migration.Status = "queued"
+			migration.CurrentStep = "Waiting for a running migration to finish"
+			migration.UpdatedAt = time.Now()
+		}
+		return "queued"
+	}
+	s.startJob(id, config)
+	return "running"
+}
+
+// runningJobs returns the number of migrations running; s.mu must be held
+func (s *Server) runningJobs() int {
+	n := 0
+	for _, j := range s.jobs {
+		if j.running() {
+			n++
+		}
+	}
+	return n
+}
+
+// startQueued starts queued migrations while slots are free
+func (s *Server) startQueued() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.draining && (s.config.MaxConcurrent <= 0 || s.runningJobs() < s.config.MaxConcurrent) {
+		q, ok := s.popQueued()
+		if !ok {
+			return
+		}
+		// The author map may have been edited while queued
+		q.config.AuthorMap = s.migrations[q.id].AuthorMap
+		s.startJob(q.id, q.config)
+	}
+}
+
+// popQueued removes the next migration to start from the queue, highest
+// priority first and in request order within a priority. Migrations
+// stopped while queued are dropped. s.mu must be held.
+func (s *Server) popQueued() (queuedMigration, bool) {
+	next := -1
+	queue := s.queue[:0]
+	for _, q := range s.queue {
+		if migration, exists := s.migrations[q.id]; !exists || migration.Status != "queued" {
+			continue
+		}
+		if next < 0 || q.priority > queue[next].priority {
+			next = len(queue)
+		}
+		queue = append(queue, q)
+	}
+	if next < 0 {
+		s.queue = nil
+		return queuedMigration{}, false
+	}
+	q := queue[next]
+	s.queue = append(queue[:next], queue[next+1:]...)
+	return q, true
+}
+
+// startJob runs a migration in the background, feeding its progress,
+// applied commits and issues into the migration status; s.mu must be held
+func (s *Server) startJob(id string, config *core.MigrationConfig) {
+	j := &job{config: config, stop: make(chan struct{}), done: make(chan struct{})}
+	config.Stop = j.stop
+	config.Pause = &j.pause
+	config.Logger = s.logger.With("web_migration_id", id)
+	config.Hooks = []core.CommitHook{&commitRecorder{server: s, id: id}}
+	migrator := core.NewMigrator(config)
+
+	s.jobs[id] = j
+	if migration, exists := s.migrations[id]; exists {
+		migration.Status = "running"
+		migration.Refs = nil // Every run creates the refs again
+		migration.UpdatedAt = time.Now()
+	}
+
+	// The subscriber runs on the migration goroutine, so the issues can be
+	// read without racing the migrator
+	migrator.ProgressReporter().Subscribe(func(status progress.Status) {
+		issues := migrator.Issues()
+		s.mu.Lock()
+		if migration, exists := s.migrations[id]; exists {
+			migration.ApplyProgress(status)
+			migration.ApplyIssues(issues)
+		}
+		s.mu.Unlock()
+	})
+	migrator.ProgressReporter().SubscribeRefs(func(ref progress.RefResult) {
+		s.mu.Lock()
+		if migration, exists := s.migrations[id]; exists {
+			migration.Refs = append(migration.Refs, RefEntry{
+				Kind:    ref.Kind,
+				Name:    ref.Name,
+				Success: ref.Error == "",
+				Error:   ref.Error,
+				Time:    ref.Time,
+			})
+		}
+		s.mu.Unlock()
+	})
+
+	go func() {
+		defer s.startQueued()
+		defer close(j.done)
+		err := migrator.Run()
+		status := s.finishMigration(id, err, migrator.Issues())
+		s.saveMigrations()
+		s.mu.RLock()
+		email := s.config.Email
+		s.mu.RUnlock()
+		if status != "stopped" && email.Enabled() {
+			if err := notify.NewMailer(email).SendReport(migrator.Report()); err != nil {
+				s.logger.Warn("failed to send migration notification", "web_migration_id", id, "error", err)
+			}
+		}
+	}()
+}
+
+// finishMigration records the outcome of a run and returns the final status
+func (s *Server) finishMigration(id string, err error, issues []core.Issue) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	migration, exists := s.migrations[id]
+	if !exists {
+		return ""
+	}
+	migration.ApplyIssues(issues)
+	migration.ErrorCode = ""
+	switch {
+	case err == nil:
+		migration.Status = "completed"
+	case errors.Is(err, core.ErrStopped) || migration.Status == "stopped":
+		migration.Status = "stopped"
+	default:
+		migration.Status = "failed"
+		migration.ErrorCode = errorCode(err, "MIGRATION_FAILED")
+		migration.Errors = append(migration.Errors, err.Error())
+		migration.Issues = append(migration.Issues, core.Issue{
+			Severity: core.SeverityError,
+			Message:  err.Error(),
+			Time:     time.Now(),
+		})
+	}
+	migration.UpdatedAt = time.Now()
+	return migration.Status
+}
+
+// commitRecorder is a commit hook adding applied commits to the commit log
+// of a migration
+type commitRecorder struct {
+	server *Server
+	id     string
+}
+
+func (c *commitRecorder) BeforeCommit(*vcs.Commit) error { return nil }
+
+func (c *commitRecorder) AfterCommit(commit *vcs.Commit, hash string) error {
+	subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	entry := CommitEntry{
+		Revision: commit.Revision,
+		Hash:     hash,
+		Author:   commit.Author,
+		Message:  subject,
+		Time:     time.Now(),
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if migration, exists := c.server.migrations[c.id]; exists {
+		migration.AppliedCommits++
+		commits := append(migration.Commits, entry)
+		if len(commits) > maxRecentCommits {
+			commits = append([]CommitEntry(nil), commits[len(commits)-maxRecentCommits:]...)
+		}
+		migration.Commits = commits
+	}
+	return nil
+}
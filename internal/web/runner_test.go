@@ -0,0 +1,396 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// drainAfter waits for the migrations started on server when the test ends,
+// so that none still writes to a temporary directory while it is removed
+func drainAfter(t *testing.T, server *Server) {
+	t.Helper()
+	// The temporary directories are removed by the first cleanup registered,
+	// which runs last
+	_ = t.TempDir()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.drain(ctx)
+	})
+}
+
+// writeTestCVSRepo creates a CVS repository with one file committed by alice
+// and then by bob
+func writeTestCVSRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	rcs := "head 1.2;\naccess;\nsymbols;\nlocks;\n\n" +
+		"1.2\ndate 2024.01.02.00.00.00; author bob; state Exp;\nbranches;\nnext 1.1;\n\n" +
+		"1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\n" +
+		"desc\n@@\n\n1.2\nlog\n@second\n@\ntext\n@two\n@\n\n1.1\nlog\n@first\n@\ntext\n@d1 1\na1 1\none\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.txt,v"), []byte(rcs), 0644))
+	return repo
+}
+
+// writeBrokenCVSRepo writes a CVS repository whose CVSROOT/cvswrappers is
+// unreadable, so that migrating it fails
+func writeBrokenCVSRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT", "cvswrappers"), 0755))
+	return repo
+}
+
+// startTestMigration starts a migration through the API and waits for it to
+// finish
+func startTestMigration(t *testing.T, server *Server, req StartMigrationRequest) string {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+
+	server.mu.RLock()
+	j := server.jobs[response.Data.ID]
+	server.mu.RUnlock()
+	require.NotNil(t, j)
+	select {
+	case <-j.done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("migration did not finish")
+	}
+	return response.Data.ID
+}
+
+func TestServerRunMigration(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	id := startTestMigration(t, server, StartMigrationRequest{
+		SourceType: "cvs",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+		AuthorMap:  map[string]string{"alice": "Alice Smith <alice@corp.example>"},
+	})
+
+	migration, exists := server.migrationSnapshot(id)
+	require.True(t, exists)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+	require.Equal(t, 2, migration.AppliedCommits)
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations/"+id+"/commits?limit=1", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var commits struct {
+		Data struct {
+			Commits []CommitEntry `json:"commits"`
+			Applied int           `json:"applied"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &commits))
+	require.Equal(t, 2, commits.Data.Applied)
+	require.Len(t, commits.Data.Commits, 1)
+	require.Equal(t, "second", commits.Data.Commits[0].Message)
+	require.Len(t, commits.Data.Commits[0].Hash, 40)
+
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations/"+id+"/commits?limit=x", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServerRunMigrationFailed(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	id := startTestMigration(t, server, StartMigrationRequest{
+		SourceType: "cvs",
+		SourcePath: writeBrokenCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+	})
+	migration, exists := server.migrationSnapshot(id)
+	require.True(t, exists)
+	require.Equal(t, "failed", migration.Status)
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations/"+id+"/errors", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var errs struct {
+		Data struct {
+			Issues     []map[string]interface{} `json:"issues"`
+			ErrorCount int                      `json:"errorCount"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errs))
+	require.NotEmpty(t, errs.Data.Issues)
+	require.Equal(t, "error", errs.Data.Issues[len(errs.Data.Issues)-1]["severity"])
+
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations/unknown/errors", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServerResumeMigration(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	source := writeBrokenCVSRepo(t)
+	id := startTestMigration(t, server, StartMigrationRequest{
+		SourceType: "cvs",
+		SourcePath: source,
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+	})
+
+	resume := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations/"+id+"/resume", nil))
+		return rec
+	}
+
+	// The source was malformed, so the first run failed
+	migration, _ := server.migrationSnapshot(id)
+	require.Equal(t, "failed", migration.Status)
+
+	require.NoError(t, os.RemoveAll(source))
+	require.NoError(t, os.Rename(writeTestCVSRepo(t), source))
+	rec := resume(id)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"status":"running"`)
+
+	server.mu.RLock()
+	j := server.jobs[id]
+	server.mu.RUnlock()
+	select {
+	case <-j.done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("resumed migration did not finish")
+	}
+	migration, _ = server.migrationSnapshot(id)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+	require.Equal(t, 2, migration.AppliedCommits)
+
+	require.Equal(t, http.StatusConflict, resume(id).Code)
+	require.Equal(t, http.StatusNotFound, resume("unknown").Code)
+}
+
+func TestServerPauseMigration(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	j := &job{stop: make(chan struct{}), done: make(chan struct{})}
+	server.mu.Lock()
+	server.migrations["paused-id"] = &MigrationStatus{ID: "paused-id", Status: "running"}
+	server.jobs["paused-id"] = j
+	server.mu.Unlock()
+
+	post := func(action string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations/paused-id/"+action, nil))
+		return rec
+	}
+
+	rec := post("pause")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"status":"paused"`)
+	require.True(t, j.pause.Paused())
+	require.Equal(t, http.StatusConflict, post("pause").Code, "already paused")
+
+	rec = post("resume")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"status":"running"`)
+	require.False(t, j.pause.Paused())
+	migration, _ := server.migrationSnapshot("paused-id")
+	require.Equal(t, "running", migration.Status)
+
+	// A finished run cannot be paused
+	close(j.done)
+	require.Equal(t, http.StatusConflict, post("pause").Code)
+
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations/unknown/pause", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestParseJobOptions(t *testing.T) {
+	opts, err := parseJobOptions(map[string]interface{}{"priority": "high", "maxParallelParses": 4.0, "ioLimitMBps": 0.5, "ioOpsPerSecond": 200.0})
+	require.NoError(t, err)
+	require.Equal(t, jobOptions{priority: 1, parseWorkers: 4, readLimit: 1 << 19, opsLimit: 200}, opts)
+
+	opts, err = parseJobOptions(nil)
+	require.NoError(t, err)
+	require.Equal(t, jobOptions{}, opts)
+
+	opts, err = parseJobOptions(map[string]interface{}{"messageTemplate": "{{.Message}} ({{.Revision}})"})
+	require.NoError(t, err)
+	require.Equal(t, "{{.Message}} ({{.Revision}})", opts.message)
+
+	for _, options := range []map[string]interface{}{
+		{"priority": "urgent"},
+		{"priority": 1.0},
+		{"maxParallelParses": 0.0},
+		{"maxParallelParses": 1.5},
+		{"maxParallelParses": "4"},
+		{"ioLimitMBps": -1.0},
+		{"ioOpsPerSecond": 0.5},
+		{"messageTemplate": ""},
+		{"messageTemplate": "{{.Login}}"},
+	} {
+		_, err := parseJobOptions(options)
+		require.Error(t, err, options)
+	}
+}
+
+func TestServerPopQueued(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	for _, q := range []struct {
+		id       string
+		priority int
+		status   string
+	}{{"low", -1, "queued"}, {"normal-1", 0, "queued"}, {"stopped", 1, "stopped"}, {"high", 1, "queued"}, {"normal-2", 0, "queued"}} {
+		server.migrations[q.id] = &MigrationStatus{ID: q.id, Status: q.status}
+		server.queue = append(server.queue, queuedMigration{id: q.id, priority: q.priority})
+	}
+
+	var order []string
+	for {
+		q, ok := server.popQueued()
+		if !ok {
+			break
+		}
+		order = append(order, q.id)
+	}
+	require.Equal(t, []string{"high", "normal-1", "normal-2", "low"}, order)
+	require.Empty(t, server.queue)
+}
+
+func TestServerQueueMigrations(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080, MaxConcurrent: 1})
+	drainAfter(t, server)
+	blocker := &job{stop: make(chan struct{}), done: make(chan struct{})}
+	server.mu.Lock()
+	server.jobs["blocker"] = blocker
+	server.mu.Unlock()
+
+	start := func(options map[string]interface{}) string {
+		body, err := json.Marshal(StartMigrationRequest{SourceType: "cvs", SourcePath: writeTestCVSRepo(t),
+			TargetPath: filepath.Join(t.TempDir(), "git"), Options: options})
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations", bytes.NewReader(body)))
+		var response struct {
+			Data struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Equal(t, "queued", response.Data.Status)
+		return response.Data.ID
+	}
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations",
+		strings.NewReader(`{"sourceType":"cvs","sourcePath":"/src","targetPath":"/dst","options":{"priority":"urgent"}}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "VALIDATION_ERROR")
+
+	low := start(map[string]interface{}{"priority": "low", "maxParallelParses": 2.0, "ioLimitMBps": 100.0})
+	high := start(map[string]interface{}{"priority": "high"})
+	stopped := start(nil)
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/migrations/"+stopped+"/stop", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	migration, _ := server.migrationSnapshot(high)
+	require.Equal(t, "queued", migration.Status)
+	require.Equal(t, 1, migration.Priority)
+
+	// Each finished migration starts the next one
+	close(blocker.done)
+	server.startQueued()
+	require.Eventually(t, func() bool {
+		migration, _ := server.migrationSnapshot(low)
+		return migration.Status == "completed"
+	}, 30*time.Second, 10*time.Millisecond)
+
+	migration, _ = server.migrationSnapshot(high)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+	migration, _ = server.migrationSnapshot(stopped)
+	require.Equal(t, "stopped", migration.Status)
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	require.NotContains(t, server.jobs, stopped)
+	require.Equal(t, 2, server.jobs[low].config.ParseWorkers)
+	require.Equal(t, int64(100<<20), server.jobs[low].config.ReadLimit)
+}
+
+func TestServerRefEvents(t *testing.T) {
+	source := writeTestCVSRepo(t)
+	rcs, err := os.ReadFile(filepath.Join(source, "f.txt,v"))
+	require.NoError(t, err)
+	rcs = bytes.Replace(rcs, []byte("symbols;"), []byte("symbols\n\tREL_1:1.2;"), 1)
+	require.NoError(t, os.WriteFile(filepath.Join(source, "f.txt,v"), rcs, 0644))
+
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+	id := startTestMigration(t, server, StartMigrationRequest{
+		SourceType: "cvs",
+		SourcePath: source,
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+	})
+	migration, exists := server.migrationSnapshot(id)
+	require.True(t, exists)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+	require.Equal(t, 1, migration.RefsCreated)
+	require.Zero(t, migration.RefsFailed)
+
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations/"+id+"/refs", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var refs struct {
+		Data struct {
+			Refs    []RefEntry `json:"refs"`
+			Created int        `json:"created"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &refs))
+	require.Equal(t, 1, refs.Data.Created)
+	require.Len(t, refs.Data.Refs, 1)
+	require.Equal(t, "tag", refs.Data.Refs[0].Kind)
+	require.Equal(t, "REL_1", refs.Data.Refs[0].Name)
+	require.True(t, refs.Data.Refs[0].Success)
+
+	// A WebSocket client is sent the refs after the progress
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/progress/"+id, nil)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	var types []string
+	var event ProgressEvent
+	for range 3 {
+		require.NoError(t, conn.ReadJSON(&event))
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []string{"connected", "progress", "ref"}, types)
+	require.NotNil(t, event.Data.Ref)
+	require.Equal(t, "REL_1", event.Data.Ref.Name)
+
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/migrations/unknown/refs", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
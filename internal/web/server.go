@@ -3,11 +3,23 @@ package web
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/logging"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/validation"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
@@ -18,7 +30,17 @@ type Server struct {
 	config     ServerConfig
 	router     *chi.Mux
 	migrations map[string]*MigrationStatus
+	jobs       map[string]*job
+	queue      []queuedMigration // Migrations waiting for a free slot
+	settings   ConfigData        // Defaults of new migrations, edited through /api/config
+	presets    map[string]Preset // Named request defaults, edited through /api/presets
 	mu         sync.RWMutex
+	settingsMu sync.Mutex // Held while the settings file is written
+	logger     *slog.Logger
+
+	migrationsMu sync.Mutex // Held while ServerConfig.DatabasePath is written
+	presetsMu    sync.Mutex // Held while the presets are changed
+	draining     bool       // Set by Serve when stopping; no queued migration is started
 }
 
 // NewServer creates a new web server
@@ -26,7 +48,21 @@ func NewServer(config ServerConfig) *Server {
 	s := &Server{
 		config:     config,
 		migrations: make(map[string]*MigrationStatus),
+		jobs:       make(map[string]*job),
+		logger:     logging.OrDefault(config.Logger),
+	}
+
+	settings, err := loadSettings(config.ConfigPath)
+	if err != nil {
+		s.logger.Warn("using default settings", "error", err)
+	}
+	s.settings = settings
+	presets, err := loadPresets(config.PresetsPath)
+	if err != nil {
+		s.logger.Warn("starting without presets", "error", err)
 	}
+	s.presets = presets
+	s.recoverMigrations()
 
 	s.setupRouter()
 	return s
@@ -41,7 +77,8 @@ func (s *Server) Router() *chi.Mux {
 func (s *Server) setupRouter() {
 	s.router = chi.NewRouter()
 
-	// Middleware
+	// Middleware; API tokens are taken off the URL before it is logged
+	s.router.Use(stripQueryToken)
 	s.router.Use(middleware.Logger)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.RequestID)
@@ -49,24 +86,45 @@ func (s *Server) setupRouter() {
 	// Static files
 	s.router.Get("/static/*", s.serveStatic)
 
-	// Web UI routes
-	s.router.Get("/", s.serveIndex)
-	s.router.Get("/new", s.serveNewMigration)
-	s.router.Get("/config", s.serveConfig)
-	s.router.Get("/migration/{id}", s.serveMigration)
-
-	// API routes
+	// Web UI routes, all answered by the single-page UI, which routes them
+	// in the browser
+	s.router.Get("/", s.serveApp)
+	s.router.Get("/new", s.serveApp)
+	s.router.Get("/config", s.serveApp)
+	s.router.Get("/migration/{id}", s.serveApp)
+	s.router.Get("/migration/{id}/report", s.serveApp)
+
+	// API routes, each declaring the role it needs when tokens are configured
+	view := s.requireRole(RoleViewer)
+	operate := s.requireRole(RoleOperator)
 	s.router.Get("/api/health", s.handleHealth)
-	s.router.Get("/api/migrations", s.handleListMigrations)
-	s.router.Post("/api/migrations", s.handleStartMigration)
-	s.router.Get("/api/migrations/{id}", s.handleGetMigration)
-	s.router.Post("/api/migrations/{id}/stop", s.handleStopMigration)
-	s.router.Get("/api/config", s.handleGetConfig)
-	s.router.Post("/api/config", s.handleUpdateConfig)
-	s.router.Post("/api/repos/analyze", s.handleAnalyzeRepo)
+	s.router.With(view).Get("/api/migrations", s.handleListMigrations)
+	s.router.With(operate).Post("/api/migrations", s.handleStartMigration)
+	s.router.With(view).Get("/api/migrations/{id}", s.handleGetMigration)
+	s.router.With(operate).Post("/api/migrations/{id}/stop", s.handleStopMigration)
+	s.router.With(operate).Post("/api/migrations/{id}/pause", s.handlePauseMigration)
+	s.router.With(operate).Post("/api/migrations/{id}/resume", s.handleResumeMigration)
+	s.router.With(view).Get("/api/migrations/{id}/report", s.handleGetReport)
+	s.router.With(view).Get("/api/migrations/{id}/commits", s.handleGetCommits)
+	s.router.With(view).Get("/api/migrations/{id}/errors", s.handleGetErrors)
+	s.router.With(view).Get("/api/migrations/{id}/refs", s.handleGetRefs)
+	s.router.With(view).Get("/api/config", s.handleGetConfig)
+	s.router.With(operate).Post("/api/config", s.handleUpdateConfig)
+	s.router.With(view).Get("/api/presets", s.handleListPresets)
+	s.router.With(view).Get("/api/presets/{name}", s.handleGetPreset)
+	s.router.With(operate).Put("/api/presets/{name}", s.handleSavePreset)
+	s.router.With(operate).Delete("/api/presets/{name}", s.handleDeletePreset)
+	s.router.With(operate).Post("/api/repos/analyze", s.handleAnalyzeRepo)
+	s.router.With(view).Get("/api/repos/authors", s.handleScanAuthors)
+	s.router.With(operate).Post("/api/repos/authors", s.handleSaveAuthors)
 
 	// WebSocket
-	s.router.Get("/ws/progress/{id}", s.handleWebSocket)
+	s.router.With(view).Get("/ws/progress/{id}", s.handleWebSocket)
+
+	// Runtime profiles, e.g. go tool pprof http://localhost:8080/debug/pprof/profile
+	if s.config.Pprof {
+		s.router.With(operate).Mount("/debug", middleware.Profiler())
+	}
 }
 
 // serveStatic serves static files
@@ -76,39 +134,12 @@ func (s *Server) serveStatic(w http.ResponseWriter, r *http.Request) {
 	http.StripPrefix("/static/", fs).ServeHTTP(w, r)
 }
 
-// serveIndex serves the main page
-func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(indexHTML)); err != nil {
-		log.Printf("Warning: failed to write index HTML response: %v", err)
-	}
-}
-
-// serveNewMigration serves the new migration page
-func (s *Server) serveNewMigration(w http.ResponseWriter, r *http.Request) {
+// serveApp serves the single-page UI
+func (s *Server) serveApp(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(newMigrationHTML)); err != nil {
-		log.Printf("Warning: failed to write new migration HTML response: %v", err)
-	}
-}
-
-// serveConfig serves the config page
-func (s *Server) serveConfig(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(configHTML)); err != nil {
-		log.Printf("Warning: failed to write config HTML response: %v", err)
-	}
-}
-
-// serveMigration serves the migration detail page
-func (s *Server) serveMigration(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(migrationHTML)); err != nil {
-		log.Printf("Warning: failed to write migration HTML response: %v", err)
+	if _, err := w.Write(indexHTML); err != nil {
+		s.logger.Warn("failed to write UI HTML response", "error", err)
 	}
 }
 
@@ -118,21 +149,28 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		Status:  "ok",
 		Version: "0.1.0",
 	})); err != nil {
-		log.Printf("Warning: failed to encode health response: %v", err)
+		s.logger.Warn("failed to encode health response", "error", err)
 	}
 }
 
-// handleListMigrations handles GET /api/migrations
+// handleListMigrations handles GET /api/migrations. See parseListQuery for
+// the filter, sort and page parameters.
 func (s *Server) handleListMigrations(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r)
+	if err != nil {
+		s.writeInvalid(w, r, err)
+		return
+	}
+
 	s.mu.RLock()
-	migrations := make([]interface{}, 0, len(s.migrations))
+	migrations := make([]*MigrationStatus, 0, len(s.migrations))
 	for _, m := range s.migrations {
-		migrations = append(migrations, m)
+		migrations = append(migrations, m.snapshot())
 	}
 	s.mu.RUnlock()
 
-	if err := json.NewEncoder(w).Encode(SuccessResponse(migrations)); err != nil {
-		log.Printf("Warning: failed to encode migrations list response: %v", err)
+	if err := json.NewEncoder(w).Encode(SuccessResponse(query.apply(migrations))); err != nil {
+		s.logger.Warn("failed to encode migrations list response", "error", err)
 	}
 }
 
@@ -140,28 +178,61 @@ func (s *Server) handleListMigrations(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleStartMigration(w http.ResponseWriter, r *http.Request) {
 	var req StartMigrationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(ErrorResponse("INVALID_JSON", "Invalid JSON body")); encodeErr != nil {
-			log.Printf("Warning: failed to encode error response: %v", encodeErr)
-		}
+		s.writeProblem(w, r, "INVALID_JSON", "Invalid JSON body")
 		return
 	}
 
-	// Validate required fields
-	if req.SourcePath == "" || req.TargetPath == "" || req.SourceType == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		if err := json.NewEncoder(w).Encode(ErrorResponse("VALIDATION_ERROR", "Missing required fields")); err != nil {
-			log.Printf("Warning: failed to encode validation error response: %v", err)
+	if req.Preset != "" {
+		preset, exists := s.preset(req.Preset)
+		if !exists {
+			s.writeInvalid(w, r, validation.Violation{Field: "preset", Message: fmt.Sprintf("Unknown preset %q", req.Preset)})
+			return
 		}
+		req = preset.apply(req)
+	}
+
+	// Validate the request, reporting every violation
+	v := &validation.Validator{}
+	if v.Required("sourceType", req.SourceType) && v.OneOf("sourceType", req.SourceType, "cvs") {
+		v.Repository("sourcePath", req.SourcePath, req.SourceType)
+	}
+	v.Required("sourcePath", req.SourcePath)
+	if v.Required("targetPath", req.TargetPath) {
+		v.Target("targetPath", req.TargetPath)
+	}
+	checkAuthorMap(v, req.AuthorMap)
+	opts := checkJobOptions(v, req.Options)
+	if err := v.Err(); err != nil {
+		s.writeInvalid(w, r, err)
 		return
 	}
 
+	settings := s.currentSettings()
+	authors, err := settings.withDefaultAuthors(req.AuthorMap)
+	if err != nil {
+		s.writeProblem(w, r, "CONFIG_ERROR", err.Error())
+		return
+	}
+	req.AuthorMap = authors
+
 	// Create migration
+	config := migrationConfig(&req, settings)
 	id := uuid.New().String()
 	now := time.Now()
 	migration := &MigrationStatus{
 		ID:               id,
 		Status:           "pending",
+		SourceType:       req.SourceType,
+		SourcePath:       req.SourcePath,
+		TargetPath:       req.TargetPath,
+		AuthorMap:        req.AuthorMap,
+		BranchMap:        req.BranchMap,
+		TagMap:           req.TagMap,
+		Options:          req.Options,
+		Preset:           req.Preset,
+		Priority:         opts.priority,
+		RepoID:           core.NewMigrator(config).MigrationID(),
+		StateFile:        config.StateFile,
 		Percentage:       0,
 		CurrentStep:      "Initializing",
 		TotalCommits:     0,
@@ -172,21 +243,67 @@ func (s *Server) handleStartMigration(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.mu.Lock()
-	s.migrations[id] = migration
+	other := s.conflictingMigration(id, migration.RepoID, migration.TargetPath)
+	if other == nil {
+		s.migrations[id] = migration
+	}
 	s.mu.Unlock()
+	if other != nil {
+		s.writeConflict(w, r, other)
+		return
+	}
+	status := s.runMigration(id, config, opts.priority)
+	s.saveMigrations()
 
+	message := "Migration started"
+	if status == "queued" {
+		message = "Migration queued until a running migration finishes"
+	}
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]interface{}{
 		"id":      id,
-		"status":  migration.Status,
-		"message": "Migration started",
+		"status":  status,
+		"message": message,
 	})); err != nil {
-		log.Printf("Warning: failed to encode start migration response: %v", err)
+		s.logger.Warn("failed to encode start migration response", "error", err)
 	}
 }
 
+// writeConflict rejects a migration whose repository another migration is
+// writing
+func (s *Server) writeConflict(w http.ResponseWriter, r *http.Request, other *MigrationStatus) {
+	message := fmt.Sprintf("Migration %s is already writing %s; wait for it to finish or stop it", other.ID, other.TargetPath)
+	s.writeProblem(w, r, "CONFLICT", message)
+}
+
+// migrationSnapshot returns a copy of the status of a migration
+func (s *Server) migrationSnapshot(id string) (*MigrationStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	migration, exists := s.migrations[id]
+	if !exists {
+		return nil, false
+	}
+	return migration.snapshot(), true
+}
+
 // handleGetMigration handles GET /api/migrations/:id
 func (s *Server) handleGetMigration(w http.ResponseWriter, r *http.Request) {
+	migration, exists := s.migrationSnapshot(chi.URLParam(r, "id"))
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(migration)); err != nil {
+		s.logger.Warn("failed to encode migration response", "error", err)
+	}
+}
+
+// handleGetReport handles GET /api/migrations/:id/report. The report is
+// returned as JSON by default; ?format=markdown or ?format=html returns the
+// rendered document instead.
+func (s *Server) handleGetReport(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	s.mu.RLock()
@@ -194,15 +311,111 @@ func (s *Server) handleGetMigration(w http.ResponseWriter, r *http.Request) {
 	s.mu.RUnlock()
 
 	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		if err := json.NewEncoder(w).Encode(ErrorResponse("NOT_FOUND", "Migration not found")); err != nil {
-			log.Printf("Warning: failed to encode not found error response: %v", err)
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+
+	ext, contentType := core.ReportExtJSON, ""
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+	case "markdown":
+		ext, contentType = core.ReportExtMarkdown, "text/markdown; charset=utf-8"
+	case "html":
+		ext, contentType = core.ReportExtHTML, "text/html; charset=utf-8"
+	default:
+		s.writeProblem(w, r, "VALIDATION_ERROR", "format must be json, markdown or html")
+		return
+	}
+
+	data, err := os.ReadFile(core.ReportPath(migration.TargetPath) + ext)
+	if err != nil || migration.TargetPath == "" {
+		s.writeProblem(w, r, "REPORT_NOT_FOUND", "No report available for this migration")
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+		if _, err := w.Write(data); err != nil {
+			s.logger.Warn("failed to write report", "error", err)
 		}
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(SuccessResponse(migration)); err != nil {
-		log.Printf("Warning: failed to encode migration response: %v", err)
+	var report core.MigrationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		s.writeProblem(w, r, "INVALID_REPORT", err.Error())
+		return
+	}
+	if err := json.NewEncoder(w).Encode(SuccessResponse(report)); err != nil {
+		s.logger.Warn("failed to encode report response", "error", err)
+	}
+}
+
+// handleGetCommits handles GET /api/migrations/:id/commits, listing the
+// most recently applied commits, newest first. ?limit= caps the number
+// returned (default 50).
+func (s *Server) handleGetCommits(w http.ResponseWriter, r *http.Request) {
+	migration, exists := s.migrationSnapshot(chi.URLParam(r, "id"))
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.writeProblem(w, r, "VALIDATION_ERROR", "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+
+	commits := make([]CommitEntry, 0, min(limit, len(migration.Commits)))
+	for i := len(migration.Commits) - 1; i >= 0 && len(commits) < limit; i-- {
+		commits = append(commits, migration.Commits[i])
+	}
+	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]interface{}{
+		"commits": commits,
+		"applied": migration.AppliedCommits,
+	})); err != nil {
+		s.logger.Warn("failed to encode commits response", "error", err)
+	}
+}
+
+// handleGetErrors handles GET /api/migrations/:id/errors, listing the
+// errors and warnings of a migration in the order they occurred
+func (s *Server) handleGetErrors(w http.ResponseWriter, r *http.Request) {
+	migration, exists := s.migrationSnapshot(chi.URLParam(r, "id"))
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]interface{}{
+		"issues":       migration.Issues,
+		"errorCount":   migration.ErrorCount,
+		"warningCount": migration.WarningCount,
+	})); err != nil {
+		s.logger.Warn("failed to encode errors response", "error", err)
+	}
+}
+
+// handleGetRefs handles GET /api/migrations/:id/refs, listing the branches
+// and tags of the current run in the order they were created
+func (s *Server) handleGetRefs(w http.ResponseWriter, r *http.Request) {
+	migration, exists := s.migrationSnapshot(chi.URLParam(r, "id"))
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]interface{}{
+		"refs":    migration.Refs,
+		"created": migration.RefsCreated,
+		"failed":  migration.RefsFailed,
+	})); err != nil {
+		s.logger.Warn("failed to encode refs response", "error", err)
 	}
 }
 
@@ -215,54 +428,184 @@ func (s *Server) handleStopMigration(w http.ResponseWriter, r *http.Request) {
 	if exists {
 		migration.Status = "stopped"
 		migration.UpdatedAt = time.Now()
+		if j, running := s.jobs[id]; running {
+			j.requestStop()
+		}
 	}
 	s.mu.Unlock()
 
 	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		if err := json.NewEncoder(w).Encode(ErrorResponse("NOT_FOUND", "Migration not found")); err != nil {
-			log.Printf("Warning: failed to encode not found error response: %v", err)
-		}
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
 		return
 	}
+	s.saveMigrations()
 
 	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]string{
 		"id":      id,
 		"status":  "stopped",
 		"message": "Migration stopped",
 	})); err != nil {
-		log.Printf("Warning: failed to encode stop migration response: %v", err)
+		s.logger.Warn("failed to encode stop migration response", "error", err)
 	}
 }
 
-// handleGetConfig handles GET /api/config
-func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
-	if err := json.NewEncoder(w).Encode(SuccessResponse(ConfigData{
-		ChunkSize: 100,
-		Verbose:   false,
-		DryRun:    false,
+// handlePauseMigration handles POST /api/migrations/:id/pause. The
+// migration checkpoints after the current commit and waits in the running
+// process until it is resumed.
+func (s *Server) handlePauseMigration(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	s.mu.Lock()
+	migration, exists := s.migrations[id]
+	j, started := s.jobs[id]
+	pausable := exists && migration.Status == "running" && started && j.running()
+	if pausable {
+		j.pause.Pause()
+		migration.Status = "paused"
+		migration.UpdatedAt = time.Now()
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+	if !pausable {
+		s.writeProblem(w, r, "NOT_PAUSABLE", "Only running migrations can be paused")
+		return
+	}
+	s.saveMigrations()
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]string{
+		"id":      id,
+		"status":  "paused",
+		"message": "Migration pauses after the current commit",
 	})); err != nil {
-		log.Printf("Warning: failed to encode config response: %v", err)
+		s.logger.Warn("failed to encode pause migration response", "error", err)
 	}
 }
 
-// handleUpdateConfig handles POST /api/config
-func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
-	var req map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(ErrorResponse("INVALID_JSON", "Invalid JSON body")); encodeErr != nil {
-			log.Printf("Warning: failed to encode config error response: %v", encodeErr)
+// handleResumeMigration handles POST /api/migrations/:id/resume. A paused
+// migration continues in its running process; a stopped or failed one is
+// started again from the checkpoint saved in the state database.
+func (s *Server) handleResumeMigration(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	s.mu.Lock()
+	migration, exists := s.migrations[id]
+	j, started := s.jobs[id]
+	if exists && migration.Status == "paused" && started {
+		j.pause.Resume()
+		migration.Status = "running"
+		migration.UpdatedAt = time.Now()
+		s.mu.Unlock()
+		s.saveMigrations()
+
+		if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]string{
+			"id":      id,
+			"status":  "running",
+			"message": "Migration resumed",
+		})); err != nil {
+			s.logger.Warn("failed to encode resume migration response", "error", err)
+		}
+		return
+	}
+	resumable := exists && (migration.Status == "stopped" || migration.Status == "failed" || migration.Status == StatusInterrupted)
+	if resumable && started && j.running() {
+		resumable = false // Still finishing the current commit
+	}
+	var other *MigrationStatus
+	if resumable {
+		other = s.conflictingMigration(id, migration.RepoID, migration.TargetPath)
+		resumable = other == nil
+	}
+	var config *core.MigrationConfig
+	if resumable {
+		config = migrationConfig(&StartMigrationRequest{
+			SourceType: migration.SourceType,
+			SourcePath: migration.SourcePath,
+			TargetPath: migration.TargetPath,
+			AuthorMap:  migration.AuthorMap,
+			BranchMap:  migration.BranchMap,
+			TagMap:     migration.TagMap,
+			Options:    migration.Options,
+		}, s.settings)
+		if migration.StateFile != "" {
+			// Continue from the checkpoints of the earlier runs, even if
+			// the state directory was changed since
+			config.StateFile = migration.StateFile
 		}
+		config.Resume = true
+		// Claim the migration so concurrent requests cannot resume it twice
+		migration.Status = "running"
+		migration.UpdatedAt = time.Now()
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+	if other != nil {
+		s.writeConflict(w, r, other)
+		return
+	}
+	if !resumable {
+		s.writeProblem(w, r, "NOT_RESUMABLE", "Only stopped, failed or interrupted migrations can be resumed")
 		return
 	}
 
-	// In a real implementation, this would update the config file
-	// For now, just return success
+	status := s.runMigration(id, config, migration.Priority)
+	s.saveMigrations()
+
 	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]string{
-		"message": "Configuration updated",
+		"id":      id,
+		"status":  status,
+		"message": "Migration resumed",
 	})); err != nil {
-		log.Printf("Warning: failed to encode config update response: %v", err)
+		s.logger.Warn("failed to encode resume migration response", "error", err)
+	}
+}
+
+// handleGetConfig handles GET /api/config
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	if err := json.NewEncoder(w).Encode(SuccessResponse(s.currentSettings())); err != nil {
+		s.logger.Warn("failed to encode config response", "error", err)
+	}
+}
+
+// handleUpdateConfig handles POST /api/config. Fields missing from the body
+// keep their current value.
+func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	// Serialize updates so concurrent requests cannot lose each other's fields
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+
+	settings := s.currentSettings()
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		s.writeProblem(w, r, "INVALID_JSON", "Invalid JSON body")
+		return
+	}
+
+	if err := settings.validate(); err != nil {
+		s.writeInvalid(w, r, err)
+		return
+	}
+
+	if err := saveSettings(s.config.ConfigPath, settings); err != nil {
+		s.logger.Error("failed to save settings", "path", s.config.ConfigPath, "error", err)
+		s.writeProblem(w, r, "SAVE_FAILED", "Failed to save the configuration")
+		return
+	}
+
+	s.mu.Lock()
+	s.settings = settings
+	s.mu.Unlock()
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(settings)); err != nil {
+		s.logger.Warn("failed to encode config update response", "error", err)
 	}
 }
 
@@ -270,38 +613,257 @@ func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleAnalyzeRepo(w http.ResponseWriter, r *http.Request) {
 	var req AnalyzeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(ErrorResponse("INVALID_JSON", "Invalid JSON body")); encodeErr != nil {
-			log.Printf("Warning: failed to encode analyze error response: %v", encodeErr)
-		}
+		s.writeProblem(w, r, "INVALID_JSON", "Invalid JSON body")
 		return
 	}
 
 	// Validate
 	if req.SourceType == "" || req.SourcePath == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(ErrorResponse("VALIDATION_ERROR", "Missing required fields")); encodeErr != nil {
-			log.Printf("Warning: failed to encode validation error response: %v", encodeErr)
+		s.writeProblem(w, r, "VALIDATION_ERROR", "Missing required fields")
+		return
+	}
+
+	// CVS repositories get a full analysis including the size preflight
+	if req.SourceType == "cvs" {
+		analysis, err := analyzeCVS(req.SourcePath, req.LargeFileThreshold)
+		if err != nil {
+			s.writeProblem(w, r, errorCode(err, "INVALID_REPOSITORY"), err.Error())
+			return
+		}
+		if err := json.NewEncoder(w).Encode(SuccessResponse(analysis)); err != nil {
+			s.logger.Warn("failed to encode analyze response", "error", err)
 		}
 		return
 	}
 
-	// In a real implementation, this would analyze the repository
-	// For now, return a mock response
+	// Git repositories can be counted cheaply; other source types still
+	// return a placeholder analysis
+	commitCount := 0
+	if req.SourceType == "git" {
+		count, err := countGitCommits(req.SourcePath)
+		if err != nil {
+			s.writeProblem(w, r, errorCode(err, "INVALID_REPOSITORY"), err.Error())
+			return
+		}
+		commitCount = count
+	}
+
 	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]interface{}{
 		"type":        req.SourceType,
 		"path":        req.SourcePath,
-		"commitCount": 0,
+		"commitCount": commitCount,
 		"branchCount": 0,
 		"tagCount":    0,
 		"authors":     []string{},
 		"valid":       true,
 	})); err != nil {
-		log.Printf("Warning: failed to encode analyze response: %v", err)
+		s.logger.Warn("failed to encode analyze response", "error", err)
+	}
+}
+
+// handleScanAuthors handles GET /api/repos/authors. It lists the author
+// logins of ?sourcePath= (and ?module=) with a proposed mapping to
+// login@?domain=, or the mapping already stored for ?migrationId=.
+func (s *Server) handleScanAuthors(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	sourceType := query.Get("sourceType")
+	if sourceType == "" {
+		sourceType = "cvs"
+	}
+	if query.Get("sourcePath") == "" {
+		s.writeProblem(w, r, "VALIDATION_ERROR", "sourcePath is required")
+		return
+	}
+	if sourceType != "cvs" {
+		s.writeProblem(w, r, "UNSUPPORTED_SOURCE", "Author scanning supports CVS repositories only")
+		return
+	}
+
+	var stored map[string]string
+	if id := query.Get("migrationId"); id != "" {
+		s.mu.RLock()
+		if migration, exists := s.migrations[id]; exists {
+			stored = maps.Clone(migration.AuthorMap)
+		}
+		s.mu.RUnlock()
+	}
+
+	commits, err := scanCVSAuthors(query.Get("sourcePath"), query.Get("module"))
+	if err != nil {
+		s.writeProblem(w, r, errorCode(err, "INVALID_REPOSITORY"), err.Error())
+		return
+	}
+
+	domain := query.Get("domain")
+	if domain == "" {
+		domain = "example.com"
+	}
+	authors := make([]AuthorEntry, 0, len(commits))
+	for login, count := range commits {
+		entry := AuthorEntry{Login: login, Commits: count}
+		if author, ok := stored[login]; ok {
+			entry.Author, entry.Mapped = author, true
+		} else {
+			entry.Author = fmt.Sprintf("%s <%s@%s>", login, login, domain)
+		}
+		authors = append(authors, entry)
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].Login < authors[j].Login })
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(authors)); err != nil {
+		s.logger.Warn("failed to encode authors response", "error", err)
+	}
+}
+
+// handleSaveAuthors handles POST /api/repos/authors, storing the author map
+// of a migration that is not running, to be used when it is started or
+// resumed. Logins with an empty mapping are left to the default mapping.
+func (s *Server) handleSaveAuthors(w http.ResponseWriter, r *http.Request) {
+	var req AuthorMapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeProblem(w, r, "INVALID_JSON", "Invalid JSON body")
+		return
+	}
+
+	authors := make(map[string]string, len(req.Authors))
+	for login, author := range req.Authors {
+		if author = strings.TrimSpace(author); author != "" {
+			authors[login] = author
+		}
+	}
+	if err := validateAuthorMap(authors); err != nil {
+		s.writeInvalid(w, r, err)
+		return
+	}
+
+	s.mu.Lock()
+	migration, exists := s.migrations[req.MigrationID]
+	pending := exists && migration.Status != "running" && migration.Status != "completed"
+	if pending {
+		migration.AuthorMap = authors
+		migration.UpdatedAt = time.Now()
+	}
+	s.mu.Unlock()
+	if pending {
+		s.saveMigrations()
+	}
+
+	if !exists {
+		s.writeProblem(w, r, "NOT_FOUND", "Migration not found")
+		return
+	}
+	if !pending {
+		s.writeProblem(w, r, "MIGRATION_STARTED", "The author map cannot be changed while the migration runs or after it completed")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SuccessResponse(map[string]interface{}{
+		"migrationId": req.MigrationID,
+		"authors":     len(authors),
+	})); err != nil {
+		s.logger.Warn("failed to encode author map response", "error", err)
+	}
+}
+
+// validateAuthorMap checks that every author is written as "Name <email>"
+func validateAuthorMap(authors map[string]string) error {
+	v := &validation.Validator{}
+	checkAuthorMap(v, authors)
+	return v.Err()
+}
+
+// checkAuthorMap records a violation of v for every author that is not
+// written as "Name <email>"
+func checkAuthorMap(v *validation.Validator, authors map[string]string) {
+	for _, login := range slices.Sorted(maps.Keys(authors)) {
+		if _, _, err := mapping.ParseAuthor(authors[login]); err != nil {
+			v.Addf("authorMap."+login, "%s: author must be written as \"Name <email>\"", login)
+		}
+	}
+}
+
+// scanCVSAuthors returns the number of commits of every author login in a
+// CVS repository or one of its modules
+func scanCVSAuthors(path, module string) (map[string]int, error) {
+	reader := cvs.NewModuleReader(path, module)
+	defer func() { _ = reader.Close() }()
+	if err := reader.Validate(); err != nil {
+		return nil, err
+	}
+
+	iter, err := reader.GetCommits()
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[string]int)
+	for iter.Next() {
+		commits[iter.Commit().Author]++
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// analyzeCVS reads the whole history of a CVS repository and returns its
+// analysis, including the size preflight
+func analyzeCVS(path string, threshold int64) (map[string]interface{}, error) {
+	reader := cvs.NewReader(path)
+	defer func() { _ = reader.Close() }()
+	if err := reader.Validate(); err != nil {
+		return nil, err
+	}
+
+	branches, err := reader.GetBranches()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := reader.GetTags()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := reader.GetCommits()
+	if err != nil {
+		return nil, err
+	}
+
+	authors := mapping.NewAuthorExtractor()
+	preflight := core.NewPreflight(threshold)
+	for iter.Next() {
+		commit := iter.Commit()
+		authors.Add(commit.Author)
+		preflight.Add(commit)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	report := preflight.Report()
+	return map[string]interface{}{
+		"type":        "cvs",
+		"path":        path,
+		"commitCount": report.Commits,
+		"branchCount": len(branches),
+		"tagCount":    len(tags),
+		"authors":     authors.List(),
+		"valid":       true,
+		"preflight":   report,
+		"diagnostics": reader.Diagnostics(),
+	}, nil
+}
+
+// countGitCommits returns the number of commits reachable from HEAD
+func countGitCommits(path string) (int, error) {
+	w := git.NewWriter()
+	if err := w.OpenReadOnly(path); err != nil {
+		return 0, err
 	}
+	defer func() { _ = w.Close() }()
+	return w.GetCommitCount()
 }
 
-// Start starts the web server
+// Start starts the web server. Unlike Serve, it runs until the process
+// exits.
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.config.Port)
 	fmt.Printf("Starting web server on %s\n", addr)
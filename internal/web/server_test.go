@@ -5,9 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -210,10 +217,13 @@ func TestServerHandleListMigrations(t *testing.T) {
 		t.Error("Success should be true")
 	}
 
-	// Data should be an array
-	_, ok := response.Data.([]interface{})
+	// Data should be a page holding an array
+	data, ok := response.Data.(map[string]interface{})
 	if !ok {
-		t.Error("Data should be an array")
+		t.Fatal("Data should be an object")
+	}
+	if _, ok := data["migrations"].([]interface{}); !ok {
+		t.Error("migrations should be an array")
 	}
 }
 
@@ -243,24 +253,32 @@ func TestServerHandleListMigrationsWithData(t *testing.T) {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	data, ok := response.Data.([]interface{})
+	data, ok := response.Data.(map[string]interface{})
 	if !ok {
-		t.Fatal("Data should be an array")
+		t.Fatal("Data should be an object")
+	}
+	migrations, ok := data["migrations"].([]interface{})
+	if !ok {
+		t.Fatal("migrations should be an array")
 	}
 
-	if len(data) != 1 {
-		t.Errorf("Data length = %d, want 1", len(data))
+	if len(migrations) != 1 {
+		t.Errorf("migrations length = %d, want 1", len(migrations))
+	}
+	if data["total"] != float64(1) {
+		t.Errorf("total = %v, want 1", data["total"])
 	}
 }
 
 func TestServerHandleStartMigration(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	migrationReq := StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 		Options: map[string]interface{}{
 			"dryRun": true,
 		},
@@ -300,6 +318,7 @@ func TestServerHandleStartMigration(t *testing.T) {
 
 func TestServerHandleStartMigrationInvalidJSON(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	req := httptest.NewRequest(http.MethodPost, "/api/migrations", bytes.NewReader([]byte("invalid json")))
@@ -312,22 +331,19 @@ func TestServerHandleStartMigrationInvalidJSON(t *testing.T) {
 		t.Errorf("Status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 
-	var response APIResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+	var problem Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	if response.Success {
-		t.Error("Success should be false")
-	}
-
-	if response.Error == nil {
-		t.Error("Error should not be nil")
+	if problem.Code != "INVALID_JSON" {
+		t.Errorf("Code = %s, want INVALID_JSON", problem.Code)
 	}
 }
 
 func TestServerHandleStartMigrationMissingFields(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	tests := []struct {
@@ -375,6 +391,46 @@ func TestServerHandleStartMigrationMissingFields(t *testing.T) {
 	}
 }
 
+func TestServerHandleStartMigrationFieldErrors(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
+
+	body := `{"sourceType":"cvs","authorMap":{"bob":"bob","amy":"amy@"},"options":{"eol":"mac","priority":"urgent"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/migrations", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, "VALIDATION_ERROR", problem.Code)
+	var fields []string
+	for _, f := range problem.Errors {
+		fields = append(fields, f.Field)
+	}
+	require.Equal(t, []string{"sourcePath", "targetPath", "authorMap.amy", "authorMap.bob", "options.priority", "options.eol"}, fields)
+	require.Contains(t, problem.Detail, "sourcePath is required")
+}
+
+func TestServerHandleStartMigrationInvalidPaths(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	target := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(target, nil, 0644))
+
+	rec := postMigration(server, StartMigrationRequest{SourceType: "cvs", SourcePath: t.TempDir(), TargetPath: target})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Len(t, problem.Errors, 2)
+	require.Equal(t, "sourcePath", problem.Errors[0].Field)
+	require.Contains(t, problem.Errors[0].Message, "no CVSROOT")
+	require.Equal(t, "targetPath", problem.Errors[1].Field)
+
+	rec = postMigration(server, StartMigrationRequest{SourceType: "svn", SourcePath: "/svn", TargetPath: t.TempDir()})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `sourceType must be cvs, not \"svn\"`)
+}
+
 func TestServerHandleGetMigration(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
 	router := server.Router()
@@ -428,21 +484,13 @@ func TestServerHandleGetMigrationNotFound(t *testing.T) {
 		t.Errorf("Status = %d, want %d", rec.Code, http.StatusNotFound)
 	}
 
-	var response APIResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+	var problem Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	if response.Success {
-		t.Error("Success should be false")
-	}
-
-	if response.Error == nil {
-		t.Error("Error should not be nil")
-	}
-
-	if response.Error.Code != "NOT_FOUND" {
-		t.Errorf("Error code = %s, want NOT_FOUND", response.Error.Code)
+	if problem.Code != "NOT_FOUND" {
+		t.Errorf("Error code = %s, want NOT_FOUND", problem.Code)
 	}
 }
 
@@ -486,6 +534,49 @@ func TestServerHandleStopMigration(t *testing.T) {
 	}
 }
 
+func TestServerHandleGetReport(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+	router := server.Router()
+
+	target := filepath.Join(t.TempDir(), "repo")
+	report := &core.MigrationReport{MigrationID: "abc", Status: "completed", Commits: core.ReportCommits{Total: 7}}
+	require.NoError(t, report.WriteFiles(core.ReportPath(target)))
+
+	server.mu.Lock()
+	server.migrations["report-id"] = &MigrationStatus{ID: "report-id", Status: "completed", TargetPath: target}
+	server.migrations["no-report-id"] = &MigrationStatus{ID: "no-report-id", Status: "running", TargetPath: filepath.Join(t.TempDir(), "x")}
+	server.mu.Unlock()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	rec := get("/api/migrations/report-id/report")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response struct {
+		Success bool                 `json:"success"`
+		Data    core.MigrationReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.True(t, response.Success)
+	require.Equal(t, 7, response.Data.Commits.Total)
+
+	rec = get("/api/migrations/report-id/report?format=markdown")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/markdown")
+	require.Contains(t, rec.Body.String(), "# Migration Report")
+
+	rec = get("/api/migrations/report-id/report?format=html")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+
+	require.Equal(t, http.StatusBadRequest, get("/api/migrations/report-id/report?format=pdf").Code)
+	require.Equal(t, http.StatusNotFound, get("/api/migrations/no-report-id/report").Code)
+	require.Equal(t, http.StatusNotFound, get("/api/migrations/unknown/report").Code)
+}
+
 func TestServerHandleStopMigrationNotFound(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
 	router := server.Router()
@@ -612,6 +703,42 @@ func TestServerHandleAnalyzeRepo(t *testing.T) {
 	}
 }
 
+func TestServerHandleAnalyzeRepo_CVSPreflight(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "CVSROOT"), 0755))
+	rcs := "head 1.1;\naccess;\nsymbols;\nlocks;\n\n" +
+		"1.1\ndate 2024.01.01.00.00.00; author alice; state Exp;\nbranches;\nnext ;\n\n" +
+		"desc\n@@\n\n1.1\nlog\n@first\n@\ntext\n@0123456789\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "f.bin,v"), []byte(rcs), 0644))
+
+	server := NewServer(ServerConfig{Port: 8080})
+	body, err := json.Marshal(AnalyzeRequest{SourceType: "cvs", SourcePath: repo, LargeFileThreshold: 5})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/repos/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data struct {
+			CommitCount int      `json:"commitCount"`
+			Authors     []string `json:"authors"`
+			Preflight   struct {
+				LargeFiles []struct {
+					Path    string `json:"path"`
+					MaxSize int64  `json:"maxSize"`
+				} `json:"largeFiles"`
+			} `json:"preflight"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Data.CommitCount)
+	require.Equal(t, []string{"alice"}, response.Data.Authors)
+	require.Len(t, response.Data.Preflight.LargeFiles, 1)
+	require.Equal(t, "f.bin", response.Data.Preflight.LargeFiles[0].Path)
+	require.Equal(t, int64(11), response.Data.Preflight.LargeFiles[0].MaxSize)
+}
+
 func TestServerHandleAnalyzeRepoInvalidJSON(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
 	router := server.Router()
@@ -652,17 +779,19 @@ func TestServerHandleAnalyzeRepoMissingFields(t *testing.T) {
 
 func TestServerConcurrentMigrationAccess(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	// Start multiple migrations concurrently
 	done := make(chan bool, 10)
 
+	source, target := writeTestCVSRepo(t), filepath.Join(t.TempDir(), "test")
 	for i := 0; i < 10; i++ {
 		go func(idx int) {
 			migrationReq := StartMigrationRequest{
 				SourceType: "cvs",
-				SourcePath: "/tmp/test",
-				TargetPath: "/tmp/test",
+				SourcePath: source,
+				TargetPath: filepath.Join(target, strconv.Itoa(idx)), // One target each, as a shared one conflicts
 			}
 
 			body, _ := json.Marshal(migrationReq)
@@ -706,12 +835,13 @@ func TestServeStatic(t *testing.T) {
 
 func TestServerMigrationStatusFields(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	migrationReq := StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 	}
 
 	body, _ := json.Marshal(migrationReq)
@@ -758,20 +888,17 @@ func TestServerErrorResponseType(t *testing.T) {
 
 	router.ServeHTTP(rec, req)
 
-	var response APIResponse
-	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
-
-	if response.Error == nil {
-		t.Fatal("Error should not be nil")
-	}
-
-	if response.Error.Code == "" {
-		t.Error("Error code should not be empty")
-	}
-
-	if response.Error.Message == "" {
-		t.Error("Error message should not be empty")
-	}
+	require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, Problem{
+		Type:     "urn:git-migrator:error:NOT_FOUND",
+		Title:    "Resource not found",
+		Status:   http.StatusNotFound,
+		Detail:   "Migration not found",
+		Instance: "/api/migrations/nonexistent",
+		Code:     "NOT_FOUND",
+	}, problem)
 }
 
 func TestServerServeStaticNonExistent(t *testing.T) {
@@ -818,12 +945,13 @@ func TestServerRoutesNotExist(t *testing.T) {
 
 func TestServerStartMigrationWithEmptyOptions(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	migrationReq := StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 		Options:    nil, // nil options
 	}
 
@@ -843,13 +971,14 @@ func TestServerStartMigrationWithEmptyOptions(t *testing.T) {
 
 func TestServerMultipleMigrationStopStart(t *testing.T) {
 	server := NewServer(ServerConfig{Port: 8080})
+	drainAfter(t, server)
 	router := server.Router()
 
 	// Create first migration
 	migrationReq := StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test1",
-		TargetPath: "/tmp/test1",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "test1"),
 	}
 
 	body, _ := json.Marshal(migrationReq)
@@ -863,6 +992,8 @@ func TestServerMultipleMigrationStopStart(t *testing.T) {
 	id1 := response1.Data.(map[string]interface{})["id"].(string)
 
 	// Create second migration
+	migrationReq.TargetPath = filepath.Join(t.TempDir(), "test2")
+	body, _ = json.Marshal(migrationReq)
 	req = httptest.NewRequest(http.MethodPost, "/api/migrations", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	rec = httptest.NewRecorder()
@@ -879,14 +1010,14 @@ func TestServerMultipleMigrationStopStart(t *testing.T) {
 
 	// Verify first is stopped, second is not
 	server.mu.RLock()
-	m1 := server.migrations[id1]
-	m2 := server.migrations[id2]
+	status1 := server.migrations[id1].Status
+	status2 := server.migrations[id2].Status
 	server.mu.RUnlock()
 
-	if m1.Status != "stopped" {
-		t.Errorf("Migration 1 status = %s, want stopped", m1.Status)
+	if status1 != "stopped" {
+		t.Errorf("Migration 1 status = %s, want stopped", status1)
 	}
-	if m2.Status == "stopped" {
+	if status2 == "stopped" {
 		t.Error("Migration 2 should not be stopped")
 	}
 }
@@ -919,3 +1050,105 @@ func TestServerStart(t *testing.T) {
 	// The server will continue running, but we've verified it started successfully
 	// In a real test, we'd have a way to shut it down gracefully
 }
+
+func TestServerHandleAnalyzeRepoGitCommitCount(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	w := git.NewWriter()
+	require.NoError(t, w.Init(repoPath))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.ApplyCommit(&vcs.Commit{
+			Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "commit",
+		}))
+	}
+
+	server := NewServer(ServerConfig{Port: 8080})
+	body, err := json.Marshal(AnalyzeRequest{SourceType: "git", SourcePath: repoPath})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/repos/analyze", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, float64(3), response.Data["commitCount"])
+
+	// A path that is not a Git repository is rejected
+	body, err = json.Marshal(AnalyzeRequest{SourceType: "git", SourcePath: t.TempDir()})
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/api/repos/analyze", bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServerHandleAuthors(t *testing.T) {
+	repo := writeTestCVSRepo(t)
+
+	server := NewServer(ServerConfig{Port: 8080})
+	server.migrations["m1"] = &MigrationStatus{ID: "m1", Status: "pending"}
+	server.migrations["m2"] = &MigrationStatus{ID: "m2", Status: "running"}
+
+	scan := func(query string) []AuthorEntry {
+		req := httptest.NewRequest(http.MethodGet, "/api/repos/authors?sourcePath="+repo+query, nil)
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var response struct {
+			Data []AuthorEntry `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response.Data
+	}
+	save := func(req AuthorMapRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/repos/authors", bytes.NewReader(body)))
+		return rec
+	}
+
+	require.Equal(t, []AuthorEntry{
+		{Login: "alice", Commits: 1, Author: "alice <alice@corp.example>"},
+		{Login: "bob", Commits: 1, Author: "bob <bob@corp.example>"},
+	}, scan("&domain=corp.example"))
+
+	rec := save(AuthorMapRequest{MigrationID: "m1", Authors: map[string]string{"alice": "Alice Smith <alice@corp.example>", "bob": " "}})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, map[string]string{"alice": "Alice Smith <alice@corp.example>"}, server.migrations["m1"].AuthorMap)
+
+	// The stored mapping is returned when scanning for the migration
+	authors := scan("&migrationId=m1")
+	require.Equal(t, AuthorEntry{Login: "alice", Commits: 1, Author: "Alice Smith <alice@corp.example>", Mapped: true}, authors[0])
+	require.False(t, authors[1].Mapped)
+
+	rec = save(AuthorMapRequest{MigrationID: "m1", Authors: map[string]string{"alice": "Alice Smith"}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "alice")
+
+	require.Equal(t, http.StatusConflict, save(AuthorMapRequest{MigrationID: "m2"}).Code)
+	require.Equal(t, http.StatusNotFound, save(AuthorMapRequest{MigrationID: "missing"}).Code)
+}
+
+func TestServerHandleScanAuthorsInvalid(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 8080})
+
+	for _, query := range []string{"", "?sourcePath=/x&sourceType=svn", "?sourcePath=/nonexistent/cvs"} {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/repos/authors"+query, nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestServerPprof(t *testing.T) {
+	get := func(server *Server) int {
+		rec := httptest.NewRecorder()
+		server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusNotFound, get(NewServer(ServerConfig{Port: 8080})))
+	require.Equal(t, http.StatusOK, get(NewServer(ServerConfig{Port: 8080, Pprof: true})))
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultDrainTimeout is how long Serve lets running migrations finish
+// after it was told to stop
+const DefaultDrainTimeout = 5 * time.Minute
+
+// Serve runs the web server until ctx is done, then drains it: no new
+// requests are accepted and running migrations get drainTimeout to finish.
+// Those still running are then stopped after their current commit and left
+// interrupted, to be resumed from their checkpoint after a restart; queued
+// migrations are not started.
+func (s *Server) Serve(ctx context.Context, drainTimeout time.Duration) error {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.config.Port),
+		Handler:           s.router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServe() }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("draining web server", "timeout", drainTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		s.logger.Warn("failed to shut down the HTTP server", "error", err)
+	}
+	s.drain(drainCtx)
+	return nil
+}
+
+// drain waits for the running migrations to finish until ctx is done, then
+// stops the remaining ones and marks them interrupted
+func (s *Server) drain(ctx context.Context) {
+	s.mu.Lock()
+	s.draining = true
+	running := make(map[string]*job)
+	for id, j := range s.jobs {
+		if j.running() {
+			running[id] = j
+		}
+	}
+	s.mu.Unlock()
+
+	for id, j := range running {
+		select {
+		case <-j.done:
+			delete(running, id)
+		case <-ctx.Done():
+		}
+	}
+
+	if len(running) > 0 {
+		s.logger.Warn("stopping migrations still running after the drain timeout", "count", len(running))
+		interrupted := make(map[string]bool)
+		s.mu.Lock()
+		for id, j := range running {
+			// Those stopped through the API stay stopped
+			if migration, exists := s.migrations[id]; exists && migration.Status != "stopped" {
+				interrupted[id] = true
+			}
+			j.requestStop()
+		}
+		s.mu.Unlock()
+		for _, j := range running {
+			<-j.done
+		}
+
+		s.mu.Lock()
+		for id := range interrupted {
+			if migration := s.migrations[id]; migration.Status == "stopped" {
+				migration.Status = StatusInterrupted
+				migration.CurrentStep = "Stopped by a server shutdown; resume to continue from the last checkpoint"
+				migration.UpdatedAt = time.Now()
+			}
+		}
+		s.mu.Unlock()
+	}
+	s.saveMigrations()
+}
+
+// Reload applies the API tokens, report emails and concurrency limit of
+// config and rereads the settings and presets files. Everything is checked
+// first: if any of it is invalid, nothing is applied. Running migrations are
+// not affected; the new settings apply to migrations started afterwards.
+func (s *Server) Reload(config ServerConfig) error {
+	var errs []error
+	if config.MaxConcurrent < 0 {
+		errs = append(errs, fmt.Errorf("the concurrency limit must not be negative"))
+	}
+	for _, role := range config.Tokens {
+		if role.rank() == 0 {
+			// The token itself is secret
+			errs = append(errs, fmt.Errorf("an API token has the unknown role %q", role))
+		}
+	}
+	if err := config.Email.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("email: %w", err))
+	}
+	settings, err := loadSettings(s.config.ConfigPath)
+	errs = append(errs, err)
+	s.presetsMu.Lock()
+	defer s.presetsMu.Unlock()
+	presets, err := loadPresets(s.config.PresetsPath)
+	errs = append(errs, err)
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.config.Tokens = config.Tokens
+	s.config.Email = config.Email
+	s.config.MaxConcurrent = config.MaxConcurrent
+	s.settings = settings
+	s.presets = presets
+	s.mu.Unlock()
+
+	// A higher limit frees slots for queued migrations
+	s.startQueued()
+	return nil
+}
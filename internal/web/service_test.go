@@ -0,0 +1,115 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/notify"
+	"github.com/stretchr/testify/require"
+)
+
+// addTestJob registers a running migration whose job finishes as stopped
+// once a stop is requested
+func addTestJob(server *Server, id string) *job {
+	j := &job{stop: make(chan struct{}), done: make(chan struct{})}
+	server.mu.Lock()
+	server.migrations[id] = &MigrationStatus{ID: id, Status: "running", CreatedAt: time.Now()}
+	server.jobs[id] = j
+	server.mu.Unlock()
+	go func() {
+		<-j.stop
+		server.finishMigration(id, core.ErrStopped, nil)
+		close(j.done)
+	}()
+	return j
+}
+
+func TestServerReload(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "web.yaml")
+	server := NewServer(ServerConfig{ConfigPath: settingsPath})
+	status := func(token string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/migrations", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		server.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	require.Equal(t, http.StatusOK, status(""))
+
+	require.NoError(t, os.WriteFile(settingsPath, []byte("chunkSize: 25\n"), 0o600))
+	require.NoError(t, server.Reload(ServerConfig{Tokens: map[string]Role{"secret": RoleViewer}, MaxConcurrent: 2}))
+	require.Equal(t, http.StatusUnauthorized, status(""))
+	require.Equal(t, http.StatusOK, status("secret"))
+	require.Equal(t, 25, server.currentSettings().ChunkSize)
+
+	// Invalid settings are reported and nothing is applied
+	require.NoError(t, os.WriteFile(settingsPath, []byte("chunkSize: [\n"), 0o600))
+	require.Error(t, server.Reload(ServerConfig{}))
+	require.Equal(t, 25, server.currentSettings().ChunkSize)
+	require.Equal(t, http.StatusUnauthorized, status(""))
+
+	// So is an invalid server configuration
+	require.NoError(t, os.WriteFile(settingsPath, []byte("chunkSize: 50\n"), 0o600))
+	err := server.Reload(ServerConfig{Tokens: map[string]Role{"other": "admin"}, MaxConcurrent: -1, Email: notify.EmailConfig{Host: "smtp.example.com"}})
+	require.ErrorContains(t, err, "unknown role")
+	require.ErrorContains(t, err, "must not be negative")
+	require.ErrorContains(t, err, "email:")
+	require.Equal(t, 25, server.currentSettings().ChunkSize)
+	require.Equal(t, http.StatusOK, status("secret"))
+	require.Equal(t, 2, server.config.MaxConcurrent)
+}
+
+func TestServerDrain(t *testing.T) {
+	registry := filepath.Join(t.TempDir(), "web-migrations.json")
+	server := NewServer(ServerConfig{DatabasePath: registry})
+	addTestJob(server, "slow")
+	stopped := addTestJob(server, "stopped")
+	server.mu.Lock()
+	server.migrations["stopped"].Status = "stopped"
+	server.mu.Unlock()
+	stopped.requestStop()
+	<-stopped.done
+
+	// Running migrations are interrupted once the drain times out
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	server.drain(ctx)
+
+	migration, _ := server.migrationSnapshot("slow")
+	require.Equal(t, StatusInterrupted, migration.Status)
+	migration, _ = server.migrationSnapshot("stopped")
+	require.Equal(t, "stopped", migration.Status)
+
+	records, err := loadMigrations(registry)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	// No queued migration starts while draining
+	server.mu.Lock()
+	server.migrations["queued"] = &MigrationStatus{ID: "queued", Status: "queued"}
+	server.queue = append(server.queue, queuedMigration{id: "queued"})
+	server.mu.Unlock()
+	server.startQueued()
+	migration, _ = server.migrationSnapshot("queued")
+	require.Equal(t, "queued", migration.Status)
+}
+
+func TestServerServeStops(t *testing.T) {
+	server := NewServer(ServerConfig{Port: 0})
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, time.Second) }()
+	cancel()
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSettings are the settings of a server without a settings file
+func defaultSettings() ConfigData {
+	return ConfigData{ChunkSize: 100}
+}
+
+// loadSettings reads the settings file at path; a missing file yields the
+// defaults
+func loadSettings(path string) (ConfigData, error) {
+	settings := defaultSettings()
+	if path == "" {
+		return settings, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to read settings: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return defaultSettings(), fmt.Errorf("failed to parse settings %s: %w", path, err)
+	}
+	if err := settings.validate(); err != nil {
+		return defaultSettings(), fmt.Errorf("invalid settings %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// saveSettings writes the settings file at path, replacing it atomically
+func saveSettings(path string, settings ConfigData) error {
+	if path == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(path, data, 0o600)
+}
+
+// validate checks the settings before they are applied
+func (c ConfigData) validate() error {
+	if c.ChunkSize < 1 {
+		return fmt.Errorf("chunkSize must be at least 1")
+	}
+	for _, dir := range []struct{ name, path string }{
+		{"stateDir", c.StateDir}, {"logDir", c.LogDir}, {"contentCacheDir", c.ContentCacheDir}, {"spillDir", c.SpillDir},
+	} {
+		if dir.path != "" && !filepath.IsAbs(dir.path) {
+			return fmt.Errorf("%s must be an absolute path", dir.name)
+		}
+	}
+	if c.AuthorMapFile != "" {
+		if !filepath.IsAbs(c.AuthorMapFile) {
+			return fmt.Errorf("authorMapFile must be an absolute path")
+		}
+		if _, err := mapping.LoadAuthorFile(c.AuthorMapFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDefaultAuthors returns the author map of a request over the entries of
+// the default author map file
+func (c ConfigData) withDefaultAuthors(authors map[string]string) (map[string]string, error) {
+	if c.AuthorMapFile == "" {
+		return authors, nil
+	}
+	merged, err := mapping.LoadAuthorFile(c.AuthorMapFile)
+	if err != nil {
+		return nil, err
+	}
+	for login, author := range authors {
+		merged[login] = author
+	}
+	return merged, nil
+}
+
+// currentSettings returns the settings applied to new migrations
+func (s *Server) currentSettings() ConfigData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// postSettings posts a settings update and returns the response
+func postSettings(t *testing.T, server *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/config", bytes.NewReader([]byte(body))))
+	return rec
+}
+
+// getSettings returns the settings reported by GET /api/config
+func getSettings(t *testing.T, server *Server) ConfigData {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	server.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response struct {
+		Data ConfigData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response.Data
+}
+
+func TestServerSettingsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "git-migrator", "web.yaml")
+	server := NewServer(ServerConfig{ConfigPath: path})
+	require.Equal(t, defaultSettings(), getSettings(t, server))
+
+	stateDir := t.TempDir()
+	rec := postSettings(t, server, `{"chunkSize": 25, "verbose": true, "stateDir": "`+stateDir+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	want := ConfigData{ChunkSize: 25, Verbose: true, StateDir: stateDir}
+	require.Equal(t, want, getSettings(t, server))
+
+	// Fields missing from an update keep their value
+	rec = postSettings(t, server, `{"dryRun": true}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	want.DryRun = true
+	require.Equal(t, want, getSettings(t, server))
+
+	// A restarted server reads them back
+	require.Equal(t, want, getSettings(t, NewServer(ServerConfig{ConfigPath: path})))
+}
+
+func TestServerSettingsValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.yaml")
+	server := NewServer(ServerConfig{ConfigPath: path})
+
+	badAuthors := filepath.Join(t.TempDir(), "authors.yaml")
+	require.NoError(t, os.WriteFile(badAuthors, []byte("alice: Alice\n"), 0o644))
+
+	for name, body := range map[string]string{
+		"chunk size":     `{"chunkSize": 0}`,
+		"relative dir":   `{"logDir": "logs"}`,
+		"missing map":    `{"authorMapFile": "` + filepath.Join(t.TempDir(), "missing.yaml") + `"}`,
+		"invalid author": `{"authorMapFile": "` + badAuthors + `"}`,
+	} {
+		rec := postSettings(t, server, body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, name)
+		require.Contains(t, rec.Body.String(), "VALIDATION_ERROR", name)
+	}
+	rec := postSettings(t, server, `{"chunk": 10}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "INVALID_JSON")
+
+	require.Equal(t, defaultSettings(), getSettings(t, server))
+	_, err := os.Stat(path)
+	require.True(t, os.IsNotExist(err), "rejected settings must not be saved")
+}
+
+func TestMigrationConfigSettings(t *testing.T) {
+	req := &StartMigrationRequest{SourceType: "cvs", SourcePath: "/src", TargetPath: "/work/git"}
+
+	config := migrationConfig(req, defaultSettings())
+	require.Equal(t, 100, config.ChunkSize)
+	require.Equal(t, "/work/.git-migrator-state.db", config.StateFile)
+	require.Empty(t, config.LogDir)
+	require.False(t, config.DryRun)
+
+	config = migrationConfig(req, ConfigData{ChunkSize: 10, Verbose: true, DryRun: true, StateDir: "/state", SpillDir: "/spill"})
+	require.Equal(t, 10, config.ChunkSize)
+	require.Equal(t, "/state", filepath.Dir(config.StateFile))
+	require.Equal(t, "/state", config.LogDir)
+	require.Equal(t, "/spill", config.SpillDir)
+	require.True(t, config.DryRun)
+
+	// The request's dryRun option wins over the default
+	req.Options = map[string]interface{}{"dryRun": false}
+	require.False(t, migrationConfig(req, ConfigData{ChunkSize: 10, DryRun: true}).DryRun)
+}
+
+func TestServerSettingsAppliedToMigrations(t *testing.T) {
+	authors := filepath.Join(t.TempDir(), "authors.yaml")
+	require.NoError(t, os.WriteFile(authors, []byte("alice: Alice Default <alice@default.example>\nbob: Bob Jones <bob@corp.example>\n"), 0o644))
+	stateDir := t.TempDir()
+
+	server := NewServer(ServerConfig{ConfigPath: filepath.Join(t.TempDir(), "web.yaml")})
+	drainAfter(t, server)
+	settings, err := json.Marshal(ConfigData{ChunkSize: 1, Verbose: true, AuthorMapFile: authors, StateDir: stateDir})
+	require.NoError(t, err)
+	rec := postSettings(t, server, string(settings))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	id := startTestMigration(t, server, StartMigrationRequest{
+		SourceType: "cvs",
+		SourcePath: writeTestCVSRepo(t),
+		TargetPath: filepath.Join(t.TempDir(), "git"),
+		AuthorMap:  map[string]string{"alice": "Alice Smith <alice@corp.example>"},
+	})
+	migration, exists := server.migrationSnapshot(id)
+	require.True(t, exists)
+	require.Equal(t, "completed", migration.Status, migration.Errors)
+
+	// The request's entries win over the default author map
+	require.Equal(t, map[string]string{
+		"alice": "Alice Smith <alice@corp.example>",
+		"bob":   "Bob Jones <bob@corp.example>",
+	}, migration.AuthorMap)
+
+	// The state database and the debug log are kept in the state directory
+	entries, err := os.ReadDir(stateDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.Contains(t, names, migration.RepoID+".db")
+	require.Greater(t, len(names), 1, "verbose migrations write a log file: %v", names)
+}
@@ -0,0 +1,121 @@
+// API client and formatting helpers shared by the views
+
+// API token of servers that require one, kept for the browser session
+const TOKEN_KEY = 'gitMigratorToken';
+
+export function apiToken() {
+    return sessionStorage.getItem(TOKEN_KEY) || '';
+}
+
+// Send a request with the API token. When the server asks for a token, the
+// user is prompted once and the request is repeated with it.
+async function request(endpoint, options = {}, retried = false) {
+    const token = apiToken();
+    const response = await fetch(endpoint, {
+        ...options,
+        headers: {
+            'Content-Type': 'application/json',
+            ...(token ? { Authorization: `Bearer ${token}` } : {}),
+            ...options.headers,
+        },
+    });
+
+    if (response.status === 401 && !retried) {
+        const entered = window.prompt('API token');
+        if (entered) {
+            sessionStorage.setItem(TOKEN_KEY, entered.trim());
+            return request(endpoint, options, true);
+        }
+    }
+    return response;
+}
+
+// Error of a failed API request, carrying the problem details (RFC 7807)
+// the server answered with
+export class APIError extends Error {
+    constructor(problem) {
+        super(problem.detail || problem.title || 'Request failed');
+        this.problem = problem;
+    }
+
+    // Messages of the invalid request fields, keyed by form field name
+    fieldErrors() {
+        const errors = {};
+        for (const e of this.problem.errors || []) {
+            errors[e.field.replace(/^options\./, '')] = e.message;
+        }
+        return errors;
+    }
+}
+
+// Call a JSON endpoint and return the data of its response envelope
+export async function api(endpoint, options = {}) {
+    const response = await request(endpoint, options);
+    const data = await response.json();
+    if (!response.ok || !data.success) {
+        throw new APIError(data);
+    }
+    return data.data;
+}
+
+// Fetch a document, e.g. a report, as text
+export async function apiText(endpoint) {
+    const response = await request(endpoint);
+    if (!response.ok) {
+        let message = 'Request failed';
+        try {
+            message = (await response.json()).detail || message;
+        } catch {
+            // Not a problem document
+        }
+        throw new Error(message);
+    }
+    return response.text();
+}
+
+// Escape text for use in HTML
+export function escapeHTML(text) {
+    const div = document.createElement('div');
+    div.textContent = text ?? '';
+    return div.innerHTML.replace(/"/g, '&quot;');
+}
+
+// Format a byte count using binary units, e.g. "1.5 MiB"
+export function formatSize(bytes) {
+    const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
+    let value = bytes;
+    let i = 0;
+    while (value >= 1024 && i < units.length - 1) {
+        value /= 1024;
+        i++;
+    }
+    return i === 0 ? `${value} B` : `${value.toFixed(1)} ${units[i]}`;
+}
+
+// Format a number of seconds as e.g. "1h 2m 3s"
+export function formatDuration(seconds) {
+    const total = Math.round(seconds);
+    const h = Math.floor(total / 3600);
+    const m = Math.floor((total % 3600) / 60);
+    const s = total % 60;
+    if (h > 0) return `${h}h ${m}m ${s}s`;
+    if (m > 0) return `${m}m ${s}s`;
+    return `${s}s`;
+}
+
+// Statuses of migrations that will not change without a user action
+export function isFinished(status) {
+    return status === 'completed' || status === 'failed' || status === 'stopped' || status === 'interrupted';
+}
+
+// Show the messages of a validation result next to the form fields named
+// by its keys, and return whether there were none
+export function showFieldErrors(form, errors) {
+    form.querySelectorAll('.field-error').forEach(el => {
+        el.textContent = errors[el.dataset.errorFor] || '';
+    });
+    form.querySelectorAll('input, select').forEach(el => {
+        el.classList.toggle('invalid', Boolean(errors[el.name]));
+    });
+    return Object.keys(errors).length === 0;
+}
@@ -0,0 +1,62 @@
+// Configuration editor: the server defaults applied to new migrations
+
+import { api, escapeHTML, showFieldErrors } from './api.js';
+
+// Settings holding an absolute path, empty for the built-in default
+const PATH_FIELDS = ['authorMapFile', 'stateDir', 'logDir', 'contentCacheDir', 'spillDir'];
+
+export function mountConfig(root) {
+    const form = root.querySelector('#config-form');
+    const message = root.querySelector('#config-message');
+
+    function showMessage(text, isError) {
+        message.textContent = text;
+        message.className = isError ? 'error' : 'success';
+    }
+
+    function fill(config) {
+        form.querySelector('#chunkSize').value = config.chunkSize || 100;
+        form.querySelector('#verbose').checked = config.verbose || false;
+        form.querySelector('#dryRun').checked = config.dryRun || false;
+        for (const name of PATH_FIELDS) {
+            form.querySelector(`#${name}`).value = config[name] || '';
+        }
+    }
+
+    api('/api/config').then(fill).catch(err => {
+        message.innerHTML = `Failed to load configuration: ${escapeHTML(err.message)}`;
+        message.className = 'error';
+    });
+
+    form.addEventListener('submit', async (e) => {
+        e.preventDefault();
+
+        const formData = new FormData(form);
+        const data = {
+            chunkSize: Number(formData.get('chunkSize')),
+            verbose: formData.has('verbose'),
+            dryRun: formData.has('dryRun'),
+        };
+        const errors = {};
+        if (!Number.isInteger(data.chunkSize) || data.chunkSize < 1) {
+            errors.chunkSize = 'Enter a whole number of at least 1';
+        }
+        for (const name of PATH_FIELDS) {
+            data[name] = formData.get(name).trim();
+            if (data[name] && !data[name].startsWith('/') && !/^[A-Za-z]:[\\/]/.test(data[name])) {
+                errors[name] = 'Enter an absolute path, or leave empty for the default';
+            }
+        }
+        if (!showFieldErrors(form, errors)) return;
+
+        try {
+            fill(await api('/api/config', {
+                method: 'POST',
+                body: JSON.stringify(data),
+            }));
+            showMessage('Configuration saved.', false);
+        } catch (err) {
+            showMessage(`Failed to save configuration: ${err.message}`, true);
+        }
+    });
+}
@@ -0,0 +1,110 @@
+// Dashboard: the migration list with live progress bars
+
+import { api, escapeHTML, isFinished } from './api.js';
+
+// Refresh interval of the list while a migration on it is active
+const REFRESH_MS = 2000;
+
+export function mountDashboard(root) {
+    const list = root.querySelector('#migrations-list');
+    const filters = root.querySelector('#migration-filters');
+    let page = 1;
+    let timer = null;
+    let mounted = true;
+
+    async function load() {
+        clearTimeout(timer);
+        const params = new URLSearchParams({ page, pageSize: 20 });
+        for (const [name, value] of new FormData(filters)) {
+            if (value) params.set(name, value);
+        }
+
+        let result;
+        try {
+            result = await api(`/api/migrations?${params}`);
+        } catch (err) {
+            list.innerHTML = `<p class="error">Error loading migrations: ${escapeHTML(err.message)}</p>`;
+            return;
+        }
+        if (!mounted) return;
+
+        renderPagination(result);
+        if (result.total === 0) {
+            list.innerHTML = '<p>No migrations yet. <a href="/new" data-link>Start one</a></p>';
+            return;
+        }
+        list.innerHTML = result.migrations.map(renderItem).join('');
+
+        if (result.migrations.some(m => !isFinished(m.status))) {
+            timer = setTimeout(load, REFRESH_MS);
+        }
+    }
+
+    function renderPagination(result) {
+        root.querySelector('#page-info').textContent =
+            `Page ${result.page} of ${Math.max(result.totalPages, 1)} (${result.total} migrations)`;
+        root.querySelector('#prev-page').disabled = result.page <= 1;
+        root.querySelector('#next-page').disabled = result.page >= result.totalPages;
+    }
+
+    // Migrations interrupted by a server restart are resumed from the list
+    list.addEventListener('click', async (event) => {
+        const button = event.target.closest('[data-resume]');
+        if (!button) return;
+        button.disabled = true;
+        try {
+            await api(`/api/migrations/${encodeURIComponent(button.dataset.resume)}/resume`, { method: 'POST' });
+        } catch (err) {
+            alert(`Failed to resume migration: ${err.message}`);
+        }
+        load();
+    });
+
+    filters.addEventListener('change', () => {
+        page = 1;
+        load();
+    });
+    root.querySelector('#prev-page').addEventListener('click', () => {
+        page--;
+        load();
+    });
+    root.querySelector('#next-page').addEventListener('click', () => {
+        page++;
+        load();
+    });
+
+    load();
+    return () => {
+        mounted = false;
+        clearTimeout(timer);
+    };
+}
+
+function renderItem(m) {
+    const id = escapeHTML(m.id);
+    const status = escapeHTML(m.status);
+    const percentage = Math.min(Math.max(m.percentage || 0, 0), 100);
+    const commits = m.totalCommits ? `${m.processedCommits} / ${m.totalCommits} commits` : escapeHTML(m.currentStep || '');
+    return `
+        <div class="migration-item">
+            <div class="migration-summary">
+                <div>
+                    <a href="/migration/${id}" data-link><strong>${id.substring(0, 8)}</strong></a>
+                    <span class="migration-status ${status}">${status}</span>
+                    <small>${new Date(m.createdAt).toLocaleString()}</small>
+                </div>
+                <small class="migration-paths">${escapeHTML(m.sourcePath || '')} &rarr; ${escapeHTML(m.targetPath || '')}</small>
+                <div class="progress-container compact">
+                    <div class="progress-bar">
+                        <div class="progress-fill" style="width: ${percentage}%"></div>
+                    </div>
+                    <span>${percentage}% &middot; ${commits}</span>
+                </div>
+            </div>
+            <div class="migration-actions">
+                ${m.status === 'interrupted' ? `<button type="button" data-resume="${id}">Resume</button>` : ''}
+                <a href="/migration/${id}" class="button" data-link>View</a>
+            </div>
+        </div>
+    `;
+}
@@ -0,0 +1,16 @@
+// Git-Migrator web UI
+
+import { startRouter } from './router.js';
+import { mountDashboard } from './dashboard.js';
+import { mountNewMigration } from './new-migration.js';
+import { mountConfig } from './config.js';
+import { mountMigration } from './migration.js';
+import { mountReport } from './report.js';
+
+startRouter([
+    { pattern: /^\/$/, template: 'view-dashboard', title: 'Dashboard', mount: mountDashboard },
+    { pattern: /^\/new$/, template: 'view-new', title: 'New Migration', mount: mountNewMigration },
+    { pattern: /^\/config$/, template: 'view-config', title: 'Configuration', mount: mountConfig },
+    { pattern: /^\/migration\/([^/]+)$/, template: 'view-migration', title: 'Migration', mount: mountMigration },
+    { pattern: /^\/migration\/([^/]+)\/report$/, template: 'view-report', title: 'Report', mount: mountReport },
+]);
@@ -0,0 +1,205 @@
+// Migration detail: live progress over the WebSocket, the commit log, the
+// created branches and tags, the issues and the stop, pause and resume
+// actions
+
+import { api, apiToken, escapeHTML, formatDuration, isFinished } from './api.js';
+
+// Poll interval of the commit log and issues while the migration runs
+const REFRESH_MS = 2000;
+
+export function mountMigration(root, migrationId) {
+    const $ = (selector) => root.querySelector(selector);
+    const path = `/api/migrations/${encodeURIComponent(migrationId)}`;
+    let ws = null;
+    let reconnectTimer = null;
+    let refreshTimer = null;
+    let mounted = true;
+    let finished = false; // No progress events follow until a resume
+
+    $('#report-link').setAttribute('href', `/migration/${encodeURIComponent(migrationId)}/report`);
+
+    function connectWebSocket() {
+        const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+        const query = apiToken() ? `?token=${encodeURIComponent(apiToken())}` : '';
+        ws = new WebSocket(`${protocol}//${window.location.host}/ws/progress/${encodeURIComponent(migrationId)}${query}`);
+
+        ws.onmessage = (event) => {
+            const message = JSON.parse(event.data);
+            if (message.type === 'ref') {
+                addRef(message.data.ref);
+                return;
+            }
+            handleProgressUpdate(message.data || {});
+        };
+        ws.onerror = (err) => {
+            console.error('WebSocket error:', err);
+        };
+        ws.onclose = () => {
+            if (mounted && !finished) reconnectTimer = setTimeout(connectWebSocket, 3000);
+        };
+    }
+
+    function handleProgressUpdate(data) {
+        const percentage = data.percentage || 0;
+        $('#progress-fill').style.width = `${percentage}%`;
+        $('#progress-text').textContent = `${percentage}%`;
+
+        const status = $('#status');
+        status.textContent = data.status || 'Unknown';
+        status.className = `migration-status ${data.status}`;
+
+        $('#currentStep').textContent = data.currentStep || '-';
+        $('#commits').textContent = `${data.processedCommits || 0} / ${data.totalCommits || 0}`;
+        $('#rate').textContent = data.commitsPerSecond ? `${data.commitsPerSecond.toFixed(1)} commits/s` : '-';
+        $('#elapsed').textContent = data.elapsedSeconds ? formatDuration(data.elapsedSeconds) : '-';
+        $('#eta').textContent = data.etaSeconds ? formatDuration(data.etaSeconds) : '-';
+
+        if (data.phases && data.phases.length > 0) {
+            $('#phases').classList.remove('hidden');
+            $('#phase-list').innerHTML = data.phases.map(p =>
+                `<li>${escapeHTML(p.name)}: ${formatDuration(p.durationSeconds)}${p.done ? '' : ' (running)'}</li>`
+            ).join('');
+        }
+
+        // The list itself is filled from the issues endpoint by refreshDetails
+        if (data.errorCount > 0 || data.warningCount > 0 || (data.errors && data.errors.length > 0)) {
+            $('#errors').classList.remove('hidden');
+            $('#error-counts').textContent = `${data.errorCount || 0} errors, ${data.warningCount || 0} warnings`;
+        }
+
+        // Paused, stopped, failed and interrupted migrations can continue from their checkpoint
+        $('#resume-btn').classList.toggle('hidden', !['paused', 'stopped', 'failed', 'interrupted'].includes(data.status));
+        $('#pause-btn').classList.toggle('hidden', data.status !== 'running');
+        $('#report-link').classList.toggle('hidden', !isFinished(data.status));
+
+        if (data.refsCreated > 0 || data.refsFailed > 0) {
+            $('#ref-log').classList.remove('hidden');
+            $('#ref-counts').textContent = `${data.refsCreated || 0} created, ${data.refsFailed || 0} failed`;
+        }
+
+        finished = isFinished(data.status);
+        if (finished && ws) ws.close();
+    }
+
+    // Poll the migration, its commit log and its issues while it runs
+    async function refreshDetails() {
+        clearTimeout(refreshTimer);
+        let migration;
+        try {
+            migration = await api(path);
+            const [commits, issues, refs] = await Promise.all([
+                api(`${path}/commits?limit=50`),
+                api(`${path}/errors`),
+                api(`${path}/refs`),
+            ]);
+            if (!mounted) return;
+            handleProgressUpdate(migration);
+            renderDetails(migration, commits, issues);
+            renderRefs(refs.refs);
+        } catch (err) {
+            console.error('Failed to refresh migration:', err);
+        }
+        if (mounted && (!migration || !isFinished(migration.status))) {
+            refreshTimer = setTimeout(refreshDetails, REFRESH_MS);
+        }
+    }
+
+    function renderDetails(migration, commits, issues) {
+        $('#source').textContent = migration.sourcePath ? `${migration.sourceType}: ${migration.sourcePath}` : '-';
+        $('#target').textContent = migration.targetPath || '-';
+        $('#phase').textContent = migration.phase || '-';
+
+        if (commits.commits.length > 0) {
+            $('#commit-log').classList.remove('hidden');
+            $('#commit-rows').innerHTML = commits.commits.map(c => `
+                <tr>
+                    <td>${escapeHTML(c.revision)}</td>
+                    <td><code>${escapeHTML((c.hash || '').slice(0, 8))}</code></td>
+                    <td>${escapeHTML(c.author)}</td>
+                    <td>${escapeHTML(c.message)}</td>
+                    <td>${new Date(c.time).toLocaleTimeString()}</td>
+                </tr>
+            `).join('');
+        }
+
+        if (issues.issues.length > 0) {
+            $('#errors').classList.remove('hidden');
+            $('#error-list').innerHTML = issues.issues.map(i => `
+                <li class="issue ${escapeHTML(i.severity)}">
+                    ${new Date(i.time).toLocaleTimeString()} [${escapeHTML(i.severity)}]
+                    ${i.subject ? `${escapeHTML(i.subject)}: ` : ''}${escapeHTML(i.message)}
+                </li>
+            `).join('');
+        }
+    }
+
+    function refRow(ref) {
+        const result = ref.success
+            ? '<span class="success">created</span>'
+            : `<span class="error">failed: ${escapeHTML(ref.error)}</span>`;
+        return `<tr><td>${escapeHTML(ref.kind)}</td><td>${escapeHTML(ref.name)}</td>
+            <td>${result}</td><td>${new Date(ref.time).toLocaleTimeString()}</td></tr>`;
+    }
+
+    // Show the refs of the current run, newest first
+    function renderRefs(refs) {
+        if (refs.length === 0) return;
+        $('#ref-log').classList.remove('hidden');
+        $('#ref-rows').innerHTML = refs.slice().reverse().map(refRow).join('');
+    }
+
+    // Add a ref announced over the WebSocket until the next refresh
+    function addRef(ref) {
+        $('#ref-log').classList.remove('hidden');
+        $('#ref-rows').insertAdjacentHTML('afterbegin', refRow(ref));
+    }
+
+    async function action(name, button, failure) {
+        try {
+            await api(`${path}/${name}`, { method: 'POST' });
+            return true;
+        } catch (err) {
+            alert(`${failure}: ${err.message}`);
+            return false;
+        } finally {
+            button.blur();
+        }
+    }
+
+    const stopBtn = $('#stop-btn');
+    stopBtn.addEventListener('click', async () => {
+        if (!confirm('Are you sure you want to stop this migration?')) return;
+        if (await action('stop', stopBtn, 'Failed to stop migration')) {
+            stopBtn.disabled = true;
+            refreshDetails();
+        }
+    });
+
+    const pauseBtn = $('#pause-btn');
+    pauseBtn.addEventListener('click', async () => {
+        if (await action('pause', pauseBtn, 'Failed to pause migration')) {
+            pauseBtn.classList.add('hidden');
+            refreshDetails();
+        }
+    });
+
+    const resumeBtn = $('#resume-btn');
+    resumeBtn.addEventListener('click', async () => {
+        if (await action('resume', resumeBtn, 'Failed to resume migration')) {
+            resumeBtn.classList.add('hidden');
+            stopBtn.disabled = false;
+            finished = false;
+            if (!ws || ws.readyState === WebSocket.CLOSED) connectWebSocket();
+            refreshDetails();
+        }
+    });
+
+    connectWebSocket();
+    refreshDetails();
+    return () => {
+        mounted = false;
+        clearTimeout(reconnectTimer);
+        clearTimeout(refreshTimer);
+        if (ws) ws.close();
+    };
+}
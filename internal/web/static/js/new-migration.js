@@ -0,0 +1,278 @@
+// New migration: the start form with validation, the size preflight and
+// the author mapping editor
+
+import { api, APIError, escapeHTML, formatSize, showFieldErrors } from './api.js';
+import { navigate } from './router.js';
+
+// A Git author as the server expects it: "Name <email>"
+const AUTHOR_PATTERN = /^[^<>]*\S[^<>]*<[^<>\s]+>$/;
+
+export function mountNewMigration(root) {
+    const form = root.querySelector('#migration-form');
+    const formError = root.querySelector('#form-error');
+
+    form.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        formError.classList.add('hidden');
+
+        const request = migrationRequest(form, root);
+        const errors = validateRequest(request);
+        if (!showFieldErrors(form, errors)) {
+            if (errors.authors) showFormError(formError, errors.authors);
+            return;
+        }
+
+        try {
+            const result = await api('/api/migrations', {
+                method: 'POST',
+                body: JSON.stringify(request),
+            });
+            navigate(`/migration/${encodeURIComponent(result.id)}`);
+        } catch (err) {
+            if (err instanceof APIError) showFieldErrors(form, err.fieldErrors());
+            showFormError(formError, `Failed to start migration: ${err.message}`);
+        }
+    });
+
+    // Clear the message of a field once it is edited
+    form.addEventListener('input', (e) => {
+        const message = form.querySelector(`.field-error[data-error-for="${e.target.name}"]`);
+        if (message) message.textContent = '';
+        e.target.classList.remove('invalid');
+    });
+
+    // Start from the server's dry run default
+    api('/api/config').then(config => {
+        form.querySelector('#dryRun').checked = config.dryRun || false;
+    }).catch(err => console.error('Failed to load configuration:', err));
+
+    setupPresets(root, form);
+    setupAnalyzeButton(root, form);
+    setupAuthorEditor(root, form);
+}
+
+// Offer the server's presets; choosing one fills in the fields it sets; its
+// maps and other options are applied by the server
+function setupPresets(root, form) {
+    const select = form.querySelector('#preset');
+    const description = root.querySelector('#preset-description');
+    let presets = [];
+
+    api('/api/presets').then(list => {
+        presets = list;
+        for (const preset of list) {
+            const option = document.createElement('option');
+            option.value = preset.name;
+            option.textContent = preset.name;
+            select.appendChild(option);
+        }
+    }).catch(err => console.error('Failed to load presets:', err));
+
+    select.addEventListener('change', () => {
+        const preset = presets.find(p => p.name === select.value);
+        description.textContent = preset?.description || '';
+        if (!preset) return;
+        if (preset.sourceType) form.querySelector('#sourceType').value = preset.sourceType;
+        const options = preset.options || {};
+        if (typeof options.dryRun === 'boolean') form.querySelector('#dryRun').checked = options.dryRun;
+        if (options.priority) form.querySelector('#priority').value = options.priority;
+        for (const name of ['maxParallelParses', 'ioLimitMBps', 'ioOpsPerSecond']) {
+            if (options[name] !== undefined) form.querySelector(`#${name}`).value = options[name];
+        }
+    });
+}
+
+function showFormError(el, message) {
+    el.textContent = message;
+    el.classList.remove('hidden');
+}
+
+// Build the start request from the form and the author editor
+function migrationRequest(form, root) {
+    const formData = new FormData(form);
+    const options = {
+        dryRun: formData.has('dryRun'),
+        priority: formData.get('priority'),
+    };
+    const parses = formData.get('maxParallelParses').trim();
+    if (parses !== '') options.maxParallelParses = Number(parses);
+    const ioLimit = formData.get('ioLimitMBps').trim();
+    if (ioLimit !== '') options.ioLimitMBps = Number(ioLimit);
+    const ioOps = formData.get('ioOpsPerSecond').trim();
+    if (ioOps !== '') options.ioOpsPerSecond = Number(ioOps);
+
+    const request = {
+        sourceType: formData.get('sourceType'),
+        sourcePath: formData.get('sourcePath').trim(),
+        targetPath: formData.get('targetPath').trim(),
+        options,
+    };
+    const preset = formData.get('preset');
+    if (preset) request.preset = preset;
+    const authors = collectAuthorMap(root);
+    if (authors) request.authorMap = authors;
+    return request;
+}
+
+// Check a start request the way the server does, returning the messages by
+// form field
+export function validateRequest(request) {
+    const errors = {};
+    if (!request.sourcePath) errors.sourcePath = 'Enter the path of the source repository';
+    if (!request.targetPath) {
+        errors.targetPath = 'Enter the path of the Git repository to create';
+    } else if (request.sourcePath) {
+        const source = request.sourcePath.replace(/\/+$/, '');
+        const target = request.targetPath.replace(/\/+$/, '');
+        if (target === source || target.startsWith(`${source}/`)) {
+            errors.targetPath = 'The target must be outside of the source tree';
+        }
+    }
+
+    const { maxParallelParses, ioLimitMBps, ioOpsPerSecond } = request.options;
+    if (maxParallelParses !== undefined && !(Number.isInteger(maxParallelParses) && maxParallelParses >= 1)) {
+        errors.maxParallelParses = 'Enter a whole number of at least 1';
+    }
+    if (ioLimitMBps !== undefined && !(ioLimitMBps > 0)) {
+        errors.ioLimitMBps = 'Enter a positive number, or leave empty for no limit';
+    }
+    if (ioOpsPerSecond !== undefined && !(Number.isInteger(ioOpsPerSecond) && ioOpsPerSecond >= 1)) {
+        errors.ioOpsPerSecond = 'Enter a whole number of at least 1, or leave empty for no limit';
+    }
+
+    for (const [login, author] of Object.entries(request.authorMap || {})) {
+        if (!AUTHOR_PATTERN.test(author)) {
+            errors.authors = `${login}: author must be written as "Name <email>"`;
+            break;
+        }
+    }
+    return errors;
+}
+
+// Analyze the source repository and show the size preflight report
+function setupAnalyzeButton(root, form) {
+    const button = root.querySelector('#analyze-btn');
+    const section = root.querySelector('#preflight');
+    const report = root.querySelector('#preflight-report');
+
+    button.addEventListener('click', async () => {
+        const formData = new FormData(form);
+        const data = {
+            sourceType: formData.get('sourceType'),
+            sourcePath: formData.get('sourcePath').trim(),
+        };
+        if (!showFieldErrors(form, data.sourcePath ? {} : { sourcePath: 'Enter the path of the source repository' })) {
+            return;
+        }
+
+        button.disabled = true;
+        section.classList.remove('hidden');
+        report.innerHTML = '<p>Analyzing repository history...</p>';
+        try {
+            const result = await api('/api/repos/analyze', {
+                method: 'POST',
+                body: JSON.stringify(data),
+            });
+            report.innerHTML = renderPreflight(result);
+        } catch (err) {
+            report.innerHTML = `<p class="error">Analysis failed: ${escapeHTML(err.message)}</p>`;
+        } finally {
+            button.disabled = false;
+        }
+    });
+}
+
+// Scan the source repository for author logins and show the mapping editor
+function setupAuthorEditor(root, form) {
+    const button = root.querySelector('#scan-authors-btn');
+    const section = root.querySelector('#author-editor');
+    const list = root.querySelector('#author-list');
+
+    button.addEventListener('click', async () => {
+        const formData = new FormData(form);
+        const sourcePath = formData.get('sourcePath').trim();
+        if (!showFieldErrors(form, sourcePath ? {} : { sourcePath: 'Enter the path of the source repository' })) {
+            return;
+        }
+        const params = new URLSearchParams({
+            sourceType: formData.get('sourceType'),
+            sourcePath,
+            domain: root.querySelector('#authorDomain').value,
+        });
+
+        button.disabled = true;
+        section.classList.remove('hidden');
+        list.innerHTML = '<p>Scanning repository history...</p>';
+        try {
+            const authors = await api(`/api/repos/authors?${params}`);
+            if (authors.length === 0) {
+                list.innerHTML = '<p>No authors found.</p>';
+                return;
+            }
+            list.innerHTML = `<table id="author-table">
+                <tr><th>Login</th><th>Commits</th><th>Git author</th></tr>
+                ${authors.map(a => `<tr><td>${escapeHTML(a.login)}</td><td>${a.commits}</td>
+                    <td><input type="text" data-login="${escapeHTML(a.login)}" value="${escapeHTML(a.author)}"></td></tr>`).join('')}
+            </table>`;
+        } catch (err) {
+            list.innerHTML = `<p class="error">Author scan failed: ${escapeHTML(err.message)}</p>`;
+        } finally {
+            button.disabled = false;
+        }
+    });
+}
+
+// Return the edited author map, or null if the editor was not used. Empty
+// entries keep the default mapping.
+function collectAuthorMap(root) {
+    const inputs = root.querySelectorAll('#author-table input[data-login]');
+    if (inputs.length === 0) return null;
+
+    const authors = {};
+    inputs.forEach(input => {
+        const author = input.value.trim();
+        if (author) authors[input.dataset.login] = author;
+    });
+    return authors;
+}
+
+function renderPreflight(result) {
+    const p = result.preflight;
+    let html = `<p>${result.commitCount} commits, ${result.branchCount} branches, ${result.tagCount} tags</p>`;
+    if (!p) return html;
+
+    html += `<p>${p.files} files (${p.revisions} revisions), history size ${formatSize(p.totalSize)},
+        estimated pack size ${formatSize(p.estimatedPackSize)}</p>`;
+
+    if (p.largeFiles.length > 0) {
+        html += `<h4>Files larger than ${formatSize(p.threshold)}</h4><table>
+            <tr><th>Path</th><th>Largest</th><th>Revision</th><th>Large revisions</th><th>Type</th></tr>`;
+        html += p.largeFiles.map(f => `<tr><td>${escapeHTML(f.path)}</td><td>${formatSize(f.maxSize)}</td>
+            <td>${escapeHTML(f.revision)}</td><td>${f.revisions}</td><td>${f.binary ? 'binary' : 'text'}</td></tr>`).join('');
+        html += '</table>';
+    }
+
+    if (p.extensions.length > 0) {
+        html += `<h4>Size by extension</h4><table>
+            <tr><th>Extension</th><th>Size</th><th>Files</th><th>Revisions</th></tr>`;
+        html += p.extensions.slice(0, 10).map(e => `<tr><td>${escapeHTML(e.extension) || '(none)'}</td>
+            <td>${formatSize(e.totalSize)}</td><td>${e.files}</td><td>${e.revisions}</td></tr>`).join('');
+        html += '</table>';
+    }
+
+    if (p.recommendations.length > 0) {
+        html += '<h4>Recommendations</h4><ul>';
+        html += p.recommendations.map(r => `<li>${escapeHTML(r)}</li>`).join('');
+        html += '</ul>';
+    }
+
+    const diags = result.diagnostics || [];
+    if (diags.length > 0) {
+        html += `<h4>Parse diagnostics</h4><table>
+            <tr><th>File</th><th>Line</th><th>Column</th><th>Severity</th><th>Message</th></tr>`;
+        html += diags.map(d => `<tr><td>${escapeHTML(d.file)}</td><td>${d.line || ''}</td><td>${d.column || ''}</td>
+            <td>${escapeHTML(d.severity)}</td><td>${escapeHTML(d.message)}</td></tr>`).join('');
+        html += '</table>';
+    }
+    return html;
+}
@@ -0,0 +1,54 @@
+// Report viewer: the HTML report of a migration, or its Markdown and JSON
+// forms as text
+
+import { apiText, escapeHTML } from './api.js';
+
+export function mountReport(root, migrationId) {
+    const content = root.querySelector('#report-content');
+    const tabs = root.querySelectorAll('.tabs button');
+    const path = `/api/migrations/${encodeURIComponent(migrationId)}/report`;
+    let shown = null;
+
+    root.querySelector('#migration-link').setAttribute('href', `/migration/${encodeURIComponent(migrationId)}`);
+
+    async function show(format) {
+        shown = format;
+        tabs.forEach(tab => tab.classList.toggle('active', tab.dataset.format === format));
+        content.innerHTML = '<p>Loading report...</p>';
+
+        let text;
+        try {
+            text = await apiText(`${path}?format=${format}`);
+        } catch (err) {
+            if (shown === format) {
+                content.innerHTML = `<p class="error">${escapeHTML(err.message)}</p>`;
+            }
+            return;
+        }
+        if (shown !== format) return; // Another tab was chosen meanwhile
+
+        if (format === 'html') {
+            // The report is a complete document with its own styles; the
+            // sandbox keeps its content from running scripts in the UI
+            const frame = document.createElement('iframe');
+            frame.className = 'report-frame';
+            frame.setAttribute('sandbox', '');
+            frame.srcdoc = text;
+            content.replaceChildren(frame);
+            return;
+        }
+        if (format === 'json') {
+            text = JSON.stringify(JSON.parse(text), null, 2);
+        }
+        const pre = document.createElement('pre');
+        pre.className = 'report-text';
+        pre.textContent = text;
+        content.replaceChildren(pre);
+    }
+
+    tabs.forEach(tab => tab.addEventListener('click', () => show(tab.dataset.format)));
+    show('html');
+    return () => {
+        shown = null;
+    };
+}
@@ -0,0 +1,52 @@
+// Client-side routing: the server answers every UI path with index.html
+// and the router mounts the view of the path into #app
+
+let routes = [];
+let unmount = null;
+
+// Show the view of a path and record it in the browser history
+export function navigate(path) {
+    history.pushState(null, '', path);
+    render();
+}
+
+// Start routing. Each route has a path pattern whose groups are passed to
+// its mount function, the id of its view template and a title; mount may
+// return a function releasing what it started, e.g. timers.
+export function startRouter(routeTable) {
+    routes = routeTable;
+
+    document.addEventListener('click', (e) => {
+        const link = e.target.closest('a[data-link]');
+        if (!link || e.button !== 0 || e.metaKey || e.ctrlKey || e.shiftKey || e.altKey) return;
+        e.preventDefault();
+        navigate(link.getAttribute('href'));
+    });
+    window.addEventListener('popstate', render);
+    render();
+}
+
+function render() {
+    if (unmount) {
+        unmount();
+        unmount = null;
+    }
+
+    const app = document.getElementById('app');
+    const path = window.location.pathname;
+    for (const route of routes) {
+        const match = path.match(route.pattern);
+        if (!match) continue;
+
+        document.title = `${route.title} - Git-Migrator`;
+        app.replaceChildren(document.getElementById(route.template).content.cloneNode(true));
+        document.querySelectorAll('nav a').forEach(a => {
+            a.classList.toggle('active', a.getAttribute('href') === path);
+        });
+        unmount = route.mount(app, ...match.slice(1).map(decodeURIComponent)) || null;
+        return;
+    }
+
+    document.title = 'Not Found - Git-Migrator';
+    app.replaceChildren(document.getElementById('view-not-found').content.cloneNode(true));
+}
@@ -1,47 +1,178 @@
 package web
 
 import (
+	"log/slog"
 	"time"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/notify"
+	"github.com/adamf123git/git-migrator/internal/progress"
 )
 
-// APIResponse is the standard response format for all API endpoints
+// APIResponse is the envelope of successful API responses; errors are
+// answered with a Problem
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
-	Error   *APIError   `json:"error,omitempty"`
-}
-
-// APIError represents an error in an API response
-type APIError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
 }
 
 // StartMigrationRequest is the request body for starting a migration
 type StartMigrationRequest struct {
+	Preset     string                 `json:"preset,omitempty"` // Preset supplying the fields the request leaves out
 	SourceType string                 `json:"sourceType"`
 	SourcePath string                 `json:"sourcePath"`
 	TargetPath string                 `json:"targetPath"`
+	AuthorMap  map[string]string      `json:"authorMap,omitempty"` // Login to "Name <email>"
+	BranchMap  map[string]string      `json:"branchMap,omitempty"` // Source branch to Git branch
+	TagMap     map[string]string      `json:"tagMap,omitempty"`    // Source tag to Git tag
 	Options    map[string]interface{} `json:"options,omitempty"`
 }
 
+// Preset is a named set of migration request fields kept by the server. A
+// request naming it gets the preset's source type, maps and options; its
+// own map entries and options take precedence.
+type Preset struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	SourceType  string            `json:"sourceType,omitempty"`
+	AuthorMap   map[string]string `json:"authorMap,omitempty"`
+	BranchMap   map[string]string `json:"branchMap,omitempty"`
+	TagMap      map[string]string `json:"tagMap,omitempty"`
+	Options     map[string]any    `json:"options,omitempty"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
 // AnalyzeRequest is the request body for repository analysis
 type AnalyzeRequest struct {
-	SourceType string `json:"sourceType"`
-	SourcePath string `json:"sourcePath"`
+	SourceType         string `json:"sourceType"`
+	SourcePath         string `json:"sourcePath"`
+	LargeFileThreshold int64  `json:"largeFileThreshold,omitempty"` // Bytes (0 = default)
+}
+
+// AuthorEntry is an author login found in a source repository
+type AuthorEntry struct {
+	Login   string `json:"login"`
+	Commits int    `json:"commits"`
+	Author  string `json:"author"` // Stored mapping or a "login <login@domain>" proposal
+	Mapped  bool   `json:"mapped"` // Whether Author comes from the stored map
+}
+
+// AuthorMapRequest is the request body for storing the author map of a
+// pending migration
+type AuthorMapRequest struct {
+	MigrationID string            `json:"migrationId"`
+	Authors     map[string]string `json:"authors"` // Login to "Name <email>"
+}
+
+// CommitEntry is a commit applied by a migration
+type CommitEntry struct {
+	Revision string    `json:"revision"`
+	Hash     string    `json:"hash"`
+	Author   string    `json:"author"`
+	Message  string    `json:"message"` // First line of the commit message
+	Time     time.Time `json:"time"`
 }
 
 // MigrationStatus represents the status of a migration
 type MigrationStatus struct {
-	ID               string    `json:"id"`
-	Status           string    `json:"status"`
-	Percentage       int       `json:"percentage"`
-	CurrentStep      string    `json:"currentStep"`
-	TotalCommits     int       `json:"totalCommits"`
-	ProcessedCommits int       `json:"processedCommits"`
-	Errors           []string  `json:"errors"`
-	CreatedAt        time.Time `json:"createdAt"`
-	UpdatedAt        time.Time `json:"updatedAt"`
+	ID               string            `json:"id"`
+	Status           string            `json:"status"`
+	SourceType       string            `json:"sourceType,omitempty"`
+	SourcePath       string            `json:"sourcePath,omitempty"`
+	TargetPath       string            `json:"targetPath,omitempty"`
+	AuthorMap        map[string]string `json:"authorMap,omitempty"`
+	BranchMap        map[string]string `json:"branchMap,omitempty"`
+	TagMap           map[string]string `json:"tagMap,omitempty"`
+	Options          map[string]any    `json:"options,omitempty"`
+	Preset           string            `json:"preset,omitempty"` // Preset the migration was started with
+	RepoID           string            `json:"repoId,omitempty"` // Migration ID of the state database and report, derived from source and target
+	Priority         int               `json:"priority"`         // -1 low, 0 normal, 1 high
+	Percentage       int               `json:"percentage"`
+	CurrentStep      string            `json:"currentStep"`
+	TotalCommits     int               `json:"totalCommits"`
+	ProcessedCommits int               `json:"processedCommits"`
+	CommitsPerSecond float64           `json:"commitsPerSecond"`
+	ElapsedSeconds   float64           `json:"elapsedSeconds"`
+	ETASeconds       float64           `json:"etaSeconds"`
+	Phase            string            `json:"phase,omitempty"`
+	Phases           []PhaseInfo       `json:"phases,omitempty"`
+	Errors           []string          `json:"errors"`
+	WarningCount     int               `json:"warningCount"`
+	ErrorCount       int               `json:"errorCount"`
+	AppliedCommits   int               `json:"appliedCommits"`
+	RefsCreated      int               `json:"refsCreated"` // Branches and tags created so far
+	RefsFailed       int               `json:"refsFailed"`  // Branches and tags that could not be created
+	CreatedAt        time.Time         `json:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
+
+	ErrorCode string `json:"errorCode,omitempty"` // API error code of the failure, e.g. PARSE_ERROR
+
+	StateFile string        `json:"-"` // State database of the run, kept for resuming
+	Commits   []CommitEntry `json:"-"` // Most recent applied commits, oldest first
+	Refs      []RefEntry    `json:"-"` // Created and failed branches and tags, in creation order
+	Issues    []core.Issue  `json:"-"`
+}
+
+// snapshot returns a copy of the status that later updates do not change
+func (m *MigrationStatus) snapshot() *MigrationStatus {
+	c := *m
+	c.Commits = append([]CommitEntry(nil), m.Commits...)
+	c.Refs = append([]RefEntry(nil), m.Refs...)
+	c.Issues = append([]core.Issue(nil), m.Issues...)
+	return &c
+}
+
+// PhaseInfo describes how long a migration phase ran
+type PhaseInfo struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Done            bool    `json:"done"`
+}
+
+// ApplyProgress copies a progress snapshot into the migration status
+func (m *MigrationStatus) ApplyProgress(status progress.Status) {
+	m.Percentage = int(status.Percentage)
+	m.CurrentStep = status.Operation
+	m.TotalCommits = status.Total
+	m.ProcessedCommits = status.Current
+	m.CommitsPerSecond = status.Rate
+	m.ElapsedSeconds = status.Elapsed.Seconds()
+	m.ETASeconds = status.ETA.Seconds()
+	m.Phase = string(status.Phase)
+	m.RefsCreated = status.RefsCreated
+	m.RefsFailed = status.RefsFailed
+	m.Phases = make([]PhaseInfo, 0, len(status.Phases))
+	for _, p := range status.Phases {
+		m.Phases = append(m.Phases, PhaseInfo{
+			Name:            string(p.Phase),
+			DurationSeconds: p.Duration.Seconds(),
+			Done:            !p.End.IsZero(),
+		})
+	}
+	m.UpdatedAt = time.Now()
+}
+
+// ApplyIssues copies the issues recorded by a migration into the status.
+// Errors lists the error messages; warnings are only counted.
+func (m *MigrationStatus) ApplyIssues(issues []core.Issue) {
+	m.WarningCount, m.ErrorCount = core.CountIssues(issues)
+	m.Issues = append([]core.Issue(nil), issues...)
+	m.Errors = make([]string, 0, m.ErrorCount)
+	for _, issue := range issues {
+		if issue.Severity == core.SeverityError {
+			m.Errors = append(m.Errors, issue.Message)
+		}
+	}
+	m.UpdatedAt = time.Now()
+}
+
+// RefEntry is a branch or tag the migration created or failed to create
+type RefEntry struct {
+	Kind    string    `json:"kind"` // branch or tag
+	Name    string    `json:"name"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	Time    time.Time `json:"time"`
 }
 
 // ProgressEvent is a WebSocket event for progress updates
@@ -52,20 +183,36 @@ type ProgressEvent struct {
 
 // ProgressData contains the progress details
 type ProgressData struct {
-	MigrationID      string   `json:"migrationId"`
-	Status           string   `json:"status"`
-	Percentage       int      `json:"percentage"`
-	CurrentStep      string   `json:"currentStep"`
-	TotalCommits     int      `json:"totalCommits"`
-	ProcessedCommits int      `json:"processedCommits"`
-	Errors           []string `json:"errors"`
+	MigrationID      string      `json:"migrationId"`
+	Status           string      `json:"status"`
+	Percentage       int         `json:"percentage"`
+	CurrentStep      string      `json:"currentStep"`
+	TotalCommits     int         `json:"totalCommits"`
+	ProcessedCommits int         `json:"processedCommits"`
+	CommitsPerSecond float64     `json:"commitsPerSecond"`
+	ElapsedSeconds   float64     `json:"elapsedSeconds"`
+	ETASeconds       float64     `json:"etaSeconds"`
+	Phase            string      `json:"phase,omitempty"`
+	Phases           []PhaseInfo `json:"phases,omitempty"`
+	Errors           []string    `json:"errors"`
+	WarningCount     int         `json:"warningCount"`
+	ErrorCount       int         `json:"errorCount"`
+	RefsCreated      int         `json:"refsCreated"`
+	RefsFailed       int         `json:"refsFailed"`
+	Ref              *RefEntry   `json:"ref,omitempty"` // The ref of a "ref" event
 }
 
 // ServerConfig is the configuration for the web server
 type ServerConfig struct {
-	Port         int
-	ConfigPath   string
-	DatabasePath string
+	Port          int
+	ConfigPath    string             // File keeping the settings edited in the UI (empty = kept in memory only)
+	DatabasePath  string             // File keeping the migrations across restarts (empty = kept in memory only)
+	PresetsPath   string             // File keeping the presets (empty = kept in memory only)
+	Logger        *slog.Logger       // Structured logger (nil = logging.Default())
+	Email         notify.EmailConfig // Report emails for finished migrations
+	Pprof         bool               // Serve the Go runtime profiles under /debug/pprof/
+	Tokens        map[string]Role    // API tokens and their roles (empty = no authentication)
+	MaxConcurrent int                // Migrations run at a time, the rest queued by priority (0 = unlimited)
 }
 
 // HealthStatus represents the health check response
@@ -74,22 +221,18 @@ type HealthStatus struct {
 	Version string `json:"version"`
 }
 
-// ConfigData represents the configuration response
+// ConfigData holds the server defaults applied to the migrations it
+// starts. It is edited through /api/config and kept in
+// ServerConfig.ConfigPath.
 type ConfigData struct {
-	ChunkSize int  `json:"chunkSize"`
-	Verbose   bool `json:"verbose"`
-	DryRun    bool `json:"dryRun"`
-}
-
-// ErrorResponse creates an error API response
-func ErrorResponse(code, message string) APIResponse {
-	return APIResponse{
-		Success: false,
-		Error: &APIError{
-			Code:    code,
-			Message: message,
-		},
-	}
+	ChunkSize       int    `json:"chunkSize" yaml:"chunkSize"`                                 // Save state every N commits
+	Verbose         bool   `json:"verbose" yaml:"verbose"`                                     // Keep a debug log file per migration
+	DryRun          bool   `json:"dryRun" yaml:"dryRun"`                                       // Default of the dryRun option
+	AuthorMapFile   string `json:"authorMapFile,omitempty" yaml:"authorMapFile,omitempty"`     // YAML login to "Name <email>" map merged under each request's map
+	StateDir        string `json:"stateDir,omitempty" yaml:"stateDir,omitempty"`               // State databases (empty = next to the target)
+	LogDir          string `json:"logDir,omitempty" yaml:"logDir,omitempty"`                   // Debug log files of verbose migrations (empty = the state directory)
+	ContentCacheDir string `json:"contentCacheDir,omitempty" yaml:"contentCacheDir,omitempty"` // CVS file revisions kept across runs (empty = memory only)
+	SpillDir        string `json:"spillDir,omitempty" yaml:"spillDir,omitempty"`               // Commit content beyond the memory budget (empty = system temp)
 }
 
 // SuccessResponse creates a success API response
@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/progress"
+	"github.com/adamf123git/git-migrator/internal/validation"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -22,36 +25,6 @@ func TestAPIResponseSuccess(t *testing.T) {
 	if response.Data != "test data" {
 		t.Errorf("Data = %v, want 'test data'", response.Data)
 	}
-
-	if response.Error != nil {
-		t.Error("Error should be nil for success response")
-	}
-}
-
-func TestAPIResponseError(t *testing.T) {
-	response := APIResponse{
-		Success: false,
-		Error: &APIError{
-			Code:    "TEST_ERROR",
-			Message: "Test error message",
-		},
-	}
-
-	if response.Success {
-		t.Error("Success should be false")
-	}
-
-	if response.Error == nil {
-		t.Fatal("Error should not be nil")
-	}
-
-	if response.Error.Code != "TEST_ERROR" {
-		t.Errorf("Error.Code = %q, want %q", response.Error.Code, "TEST_ERROR")
-	}
-
-	if response.Error.Message != "Test error message" {
-		t.Errorf("Error.Message = %q, want %q", response.Error.Message, "Test error message")
-	}
 }
 
 func TestAPIResponseJSON(t *testing.T) {
@@ -83,16 +56,6 @@ func TestAPIResponseJSON(t *testing.T) {
 				Data:    nil,
 			},
 		},
-		{
-			name: "error response",
-			response: APIResponse{
-				Success: false,
-				Error: &APIError{
-					Code:    "ERR001",
-					Message: "Something went wrong",
-				},
-			},
-		},
 	}
 
 	for _, tt := range tests {
@@ -111,39 +74,19 @@ func TestAPIResponseJSON(t *testing.T) {
 	}
 }
 
-func TestAPIError(t *testing.T) {
-	err := &APIError{
-		Code:    "VALIDATION_ERROR",
-		Message: "Invalid input",
-	}
-
-	if err.Code != "VALIDATION_ERROR" {
-		t.Errorf("Code = %q, want %q", err.Code, "VALIDATION_ERROR")
-	}
-
-	if err.Message != "Invalid input" {
-		t.Errorf("Message = %q, want %q", err.Message, "Invalid input")
-	}
-}
-
-func TestAPIErrorJSON(t *testing.T) {
-	apiErr := &APIError{
-		Code:    "TEST_CODE",
-		Message: "Test message",
-	}
-	data, err := json.Marshal(apiErr)
+func TestProblemJSON(t *testing.T) {
+	problem := newProblem("VALIDATION_ERROR", "sourcePath is required")
+	problem.Errors = validation.Errors{{Field: "sourcePath", Message: "sourcePath is required"}}
+	data, err := json.Marshal(problem)
 	require.NoError(t, err)
-
-	var decoded APIError
-	err = json.Unmarshal(data, &decoded)
-	require.NoError(t, err)
-
-	if decoded.Code != "TEST_CODE" {
-		t.Errorf("Code = %q, want %q", decoded.Code, "TEST_CODE")
-	}
-	if decoded.Message != "Test message" {
-		t.Errorf("Message = %q, want %q", decoded.Message, "Test message")
-	}
+	require.JSONEq(t, `{
+		"type": "urn:git-migrator:error:VALIDATION_ERROR",
+		"title": "Invalid request",
+		"status": 400,
+		"detail": "sourcePath is required",
+		"code": "VALIDATION_ERROR",
+		"errors": [{"field": "sourcePath", "message": "sourcePath is required"}]
+	}`, string(data))
 }
 
 func TestStartMigrationRequest(t *testing.T) {
@@ -550,54 +493,6 @@ func TestConfigDataJSON(t *testing.T) {
 	}
 }
 
-func TestErrorResponse(t *testing.T) {
-	response := ErrorResponse("NOT_FOUND", "Resource not found")
-
-	if response.Success != false {
-		t.Error("Success should be false")
-	}
-
-	if response.Data != nil {
-		t.Error("Data should be nil")
-	}
-
-	if response.Error == nil {
-		t.Fatal("Error should not be nil")
-	}
-
-	if response.Error.Code != "NOT_FOUND" {
-		t.Errorf("Error.Code = %q, want %q", response.Error.Code, "NOT_FOUND")
-	}
-
-	if response.Error.Message != "Resource not found" {
-		t.Errorf("Error.Message = %q, want %q", response.Error.Message, "Resource not found")
-	}
-}
-
-func TestErrorResponseEmptyCode(t *testing.T) {
-	response := ErrorResponse("", "Error message")
-
-	if response.Error.Code != "" {
-		t.Errorf("Error.Code = %q, want empty", response.Error.Code)
-	}
-
-	if response.Error.Message != "Error message" {
-		t.Errorf("Error.Message = %q, want %q", response.Error.Message, "Error message")
-	}
-}
-
-func TestErrorResponseEmptyMessage(t *testing.T) {
-	response := ErrorResponse("CODE", "")
-
-	if response.Error.Code != "CODE" {
-		t.Errorf("Error.Code = %q, want %q", response.Error.Code, "CODE")
-	}
-
-	if response.Error.Message != "" {
-		t.Errorf("Error.Message = %q, want empty", response.Error.Message)
-	}
-}
-
 func TestSuccessResponse(t *testing.T) {
 	response := SuccessResponse("test data")
 
@@ -608,10 +503,6 @@ func TestSuccessResponse(t *testing.T) {
 	if response.Data != "test data" {
 		t.Errorf("Data = %v, want 'test data'", response.Data)
 	}
-
-	if response.Error != nil {
-		t.Error("Error should be nil")
-	}
 }
 
 func TestSuccessResponseWithMap(t *testing.T) {
@@ -692,29 +583,6 @@ func TestSuccessResponseJSONRoundTrip(t *testing.T) {
 	}
 }
 
-func TestErrorResponseJSONRoundTrip(t *testing.T) {
-	original := ErrorResponse("TEST_ERROR", "Test error message")
-
-	data, err := json.Marshal(original)
-	require.NoError(t, err)
-
-	var decoded APIResponse
-	err = json.Unmarshal(data, &decoded)
-	require.NoError(t, err)
-
-	if decoded.Success {
-		t.Error("Success should be false after round trip")
-	}
-
-	if decoded.Error == nil {
-		t.Fatal("Error should not be nil after round trip")
-	}
-
-	if decoded.Error.Code != "TEST_ERROR" {
-		t.Errorf("Error.Code = %q, want %q", decoded.Error.Code, "TEST_ERROR")
-	}
-}
-
 func TestMigrationStatusZeroValues(t *testing.T) {
 	status := MigrationStatus{}
 
@@ -898,14 +766,59 @@ func TestConfigDataZeroValues(t *testing.T) {
 	}
 }
 
-func TestAPIErrorEmpty(t *testing.T) {
-	err := &APIError{}
+func TestMigrationStatusApplyProgress(t *testing.T) {
+	start := time.Now().Add(-10 * time.Second)
+	status := &MigrationStatus{ID: "m1", Status: "running"}
+
+	status.ApplyProgress(progress.Status{
+		Current:    25,
+		Total:      100,
+		Percentage: 25,
+		Operation:  "Processing commit 1.4",
+		ETA:        30 * time.Second,
+		StartTime:  start,
+		Elapsed:    10 * time.Second,
+		Rate:       2.5,
+		Phase:      progress.PhaseApplyCommits,
+		Phases: []progress.PhaseTiming{
+			{Phase: progress.PhaseReadSource, Start: start, End: start.Add(time.Second), Duration: time.Second},
+			{Phase: progress.PhaseApplyCommits, Start: start.Add(time.Second), Duration: 9 * time.Second},
+		},
+	})
+
+	assert.Equal(t, 25, status.Percentage)
+	assert.Equal(t, "Processing commit 1.4", status.CurrentStep)
+	assert.Equal(t, 100, status.TotalCommits)
+	assert.Equal(t, 25, status.ProcessedCommits)
+	assert.Equal(t, 2.5, status.CommitsPerSecond)
+	assert.Equal(t, 10.0, status.ElapsedSeconds)
+	assert.Equal(t, 30.0, status.ETASeconds)
+	assert.Equal(t, "apply_commits", status.Phase)
+	require.Len(t, status.Phases, 2)
+	assert.Equal(t, PhaseInfo{Name: "read_source", DurationSeconds: 1, Done: true}, status.Phases[0])
+	assert.False(t, status.Phases[1].Done)
+	assert.False(t, status.UpdatedAt.IsZero())
+
+	data, err := json.Marshal(status)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"commitsPerSecond":2.5`)
+	assert.Contains(t, string(data), `"phase":"apply_commits"`)
+}
+
+func TestMigrationStatusApplyIssues(t *testing.T) {
+	status := &MigrationStatus{ID: "m1", Errors: []string{}}
+	status.ApplyIssues([]core.Issue{
+		{Severity: core.SeverityWarning, Message: "failed to create branch branch=dev"},
+		{Severity: core.SeverityError, Message: "failed to apply commit revision=1.2"},
+		{Severity: core.SeverityWarning, Message: "failed to create tag tag=REL"},
+	})
 
-	if err.Code != "" {
-		t.Errorf("Code = %q, want empty", err.Code)
-	}
+	assert.Equal(t, 2, status.WarningCount)
+	assert.Equal(t, 1, status.ErrorCount)
+	assert.Equal(t, []string{"failed to apply commit revision=1.2"}, status.Errors)
 
-	if err.Message != "" {
-		t.Errorf("Message = %q, want empty", err.Message)
-	}
+	data, err := json.Marshal(status)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"warningCount":2`)
+	assert.Contains(t, string(data), `"errorCount":1`)
 }
@@ -2,7 +2,6 @@ package web
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -22,12 +21,12 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		s.logger.Warn("websocket upgrade failed", "error", err)
 		return
 	}
 	defer func() {
 		if err := conn.Close(); err != nil {
-			log.Printf("Warning: failed to close WebSocket connection: %v", err)
+			s.logger.Warn("failed to close WebSocket connection", "error", err)
 		}
 	}()
 
@@ -35,18 +34,16 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	s.sendProgressEvent(conn, migrationID, "connected", "Connected to migration progress")
 
 	// Check if migration exists
-	s.mu.RLock()
-	migration, exists := s.migrations[migrationID]
-	s.mu.RUnlock()
-
+	migration, exists := s.migrationSnapshot(migrationID)
 	if !exists {
 		// Migration not found, send error and close
 		s.sendProgressEvent(conn, migrationID, "error", "Migration not found")
 		return
 	}
 
-	// Send current status
+	// Send current status and the refs created so far
 	s.sendFullProgress(conn, migration)
+	sentRefs := s.sendRefEvents(conn, migration, 0)
 
 	// Keep connection alive and send updates
 	for {
@@ -56,17 +53,19 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Send periodic status updates
-		s.mu.RLock()
-		currentMigration, stillExists := s.migrations[migrationID]
-		s.mu.RUnlock()
+		currentMigration, stillExists := s.migrationSnapshot(migrationID)
 
 		if !stillExists {
 			s.sendProgressEvent(conn, migrationID, "error", "Migration no longer exists")
 			break
 		}
 
-		// Send update if status changed
+		// Send update if status changed, and the refs created since
 		s.sendFullProgress(conn, currentMigration)
+		if sentRefs > len(currentMigration.Refs) {
+			sentRefs = 0 // Resumed: the refs are being created again
+		}
+		sentRefs = s.sendRefEvents(conn, currentMigration, sentRefs)
 
 		// If migration is complete, close connection
 		if currentMigration.Status == "completed" || currentMigration.Status == "failed" || currentMigration.Status == "stopped" {
@@ -104,21 +103,53 @@ func (s *Server) sendFullProgress(conn *websocket.Conn, migration *MigrationStat
 			CurrentStep:      migration.CurrentStep,
 			TotalCommits:     migration.TotalCommits,
 			ProcessedCommits: migration.ProcessedCommits,
+			CommitsPerSecond: migration.CommitsPerSecond,
+			ElapsedSeconds:   migration.ElapsedSeconds,
+			ETASeconds:       migration.ETASeconds,
+			Phase:            migration.Phase,
+			Phases:           migration.Phases,
 			Errors:           migration.Errors,
+			WarningCount:     migration.WarningCount,
+			ErrorCount:       migration.ErrorCount,
+			RefsCreated:      migration.RefsCreated,
+			RefsFailed:       migration.RefsFailed,
 		},
 	}
 	s.sendJSON(conn, event)
 }
 
+// sendRefEvents sends a "ref" event for each ref of the migration from index
+// from on, returning the number of refs sent in total
+func (s *Server) sendRefEvents(conn *websocket.Conn, migration *MigrationStatus, from int) int {
+	for i := from; i < len(migration.Refs); i++ {
+		ref := migration.Refs[i]
+		message := "Created " + ref.Kind + " " + ref.Name
+		if !ref.Success {
+			message = "Failed to create " + ref.Kind + " " + ref.Name
+		}
+		s.sendJSON(conn, ProgressEvent{
+			Type: "ref",
+			Data: ProgressData{
+				MigrationID: migration.ID,
+				Status:      migration.Status,
+				CurrentStep: message,
+				Errors:      []string{},
+				Ref:         &ref,
+			},
+		})
+	}
+	return len(migration.Refs)
+}
+
 // sendJSON sends a JSON message to the WebSocket client
 func (s *Server) sendJSON(conn *websocket.Conn, v interface{}) {
 	data, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("JSON marshal error: %v", err)
+		s.logger.Error("failed to marshal websocket message", "error", err)
 		return
 	}
 
 	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		log.Printf("WebSocket write error: %v", err)
+		s.logger.Warn("failed to write websocket message", "error", err)
 	}
 }
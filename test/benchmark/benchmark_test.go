@@ -0,0 +1,181 @@
+// Package benchmark measures the migration pipeline on repositories
+// synthesized by test/gen. Run with:
+//
+//	go test -run '^$' -bench . -benchmem ./test/benchmark/
+package benchmark
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/adamf123git/git-migrator/test/gen"
+)
+
+// benchOptions is the repository most benchmarks run on
+var benchOptions = gen.Options{Files: 50, Revisions: 20, Branches: 2, BinaryRatio: 0.1}
+
+// fixture generates a repository and returns its path
+func fixture(b *testing.B, opts gen.Options) (string, *gen.Result) {
+	b.Helper()
+	dir := filepath.Join(b.TempDir(), "cvs")
+	result, err := gen.Generate(dir, opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return dir, result
+}
+
+// rcsFiles returns the contents of the RCS files of a repository
+func rcsFiles(b *testing.B, dir string) [][]byte {
+	b.Helper()
+	var files [][]byte
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ",v") {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		files = append(files, data)
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return files
+}
+
+// BenchmarkRCSParse parses every RCS file of the repository
+func BenchmarkRCSParse(b *testing.B) {
+	dir, result := fixture(b, benchOptions)
+	files := rcsFiles(b, dir)
+	b.SetBytes(result.Bytes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range files {
+			if _, err := cvs.NewRCSParser(bytes.NewReader(data)).Parse(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkRevisionContent reconstructs every revision of every file from
+// its deltas, without a content cache
+func BenchmarkRevisionContent(b *testing.B) {
+	dir, _ := fixture(b, benchOptions)
+	var parsed []*cvs.RCSFile
+	for _, data := range rcsFiles(b, dir) {
+		rcs, err := cvs.NewRCSParser(bytes.NewReader(data)).Parse()
+		if err != nil {
+			b.Fatal(err)
+		}
+		parsed = append(parsed, rcs)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, rcs := range parsed {
+			for _, rev := range rcs.DeltaOrder {
+				if _, err := rcs.RevisionContent(rev); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkChangesets reads a repository and groups its file revisions
+// into commits
+func BenchmarkChangesets(b *testing.B) {
+	for _, files := range []int{50, 200} {
+		b.Run("files="+strconv.Itoa(files), func(b *testing.B) {
+			opts := benchOptions
+			opts.Files = files
+			dir, _ := fixture(b, opts)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				iter, err := cvs.NewReader(dir).GetCommits()
+				if err != nil {
+					b.Fatal(err)
+				}
+				for iter.Next() {
+				}
+				if err := iter.Err(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGitWriter applies the trunk commits of a repository to a new Git
+// repository in each commit mode
+func BenchmarkGitWriter(b *testing.B) {
+	dir, _ := fixture(b, benchOptions)
+	iter, err := cvs.NewReader(dir).GetCommits()
+	if err != nil {
+		b.Fatal(err)
+	}
+	var commits []*vcs.Commit
+	for iter.Next() {
+		if commit := iter.Commit(); commit.Branch == "" {
+			commits = append(commits, commit)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, mode := range []string{git.CommitModeWorktree, git.CommitModeObjects} {
+		b.Run(mode, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				writer := git.NewWriter()
+				if err := writer.SetCommitMode(mode); err != nil {
+					b.Fatal(err)
+				}
+				if err := writer.Init(filepath.Join(b.TempDir(), "git")); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				for _, commit := range commits {
+					if err := writer.ApplyCommit(commit); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(len(commits)*b.N)/b.Elapsed().Seconds(), "commits/s")
+		})
+	}
+}
+
+// BenchmarkMigrate runs a whole migration, branches included
+func BenchmarkMigrate(b *testing.B) {
+	dir, result := fixture(b, benchOptions)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		target := filepath.Join(b.TempDir(), "git")
+		b.StartTimer()
+		migrator := core.NewMigrator(&core.MigrationConfig{
+			SourceType: "cvs",
+			SourcePath: dir,
+			TargetPath: target,
+			StateFile:  target + ".state.db",
+			Logger:     logger,
+		})
+		if err := migrator.Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(result.Commits*b.N)/b.Elapsed().Seconds(), "commits/s")
+}
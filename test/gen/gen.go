@@ -0,0 +1,280 @@
+// Package gen synthesizes CVS repositories of configurable size for
+// benchmarks and load tests.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+)
+
+// Options describes the repository to generate. Zero values take the
+// defaults of DefaultOptions, except Branches and BinaryRatio.
+type Options struct {
+	Files           int     // RCS files to create
+	Revisions       int     // Trunk revisions per file
+	Branches        int     // Branches sprouting from trunk revisions
+	BranchRevisions int     // Revisions per file on each branch
+	BinaryRatio     float64 // Fraction of files stored as binary (-kb)
+	FilesPerCommit  int     // Files changed together by one commit
+	Dirs            int     // Directories the files are spread over
+	Lines           int     // Lines of the first revision of a text file
+	Seed            int64   // Seed of the generated contents
+}
+
+// DefaultOptions returns the options of a small repository
+func DefaultOptions() Options {
+	return Options{
+		Files:           100,
+		Revisions:       10,
+		Branches:        2,
+		BranchRevisions: 2,
+		BinaryRatio:     0.1,
+		FilesPerCommit:  5,
+		Dirs:            4,
+		Lines:           50,
+		Seed:            1,
+	}
+}
+
+// Result summarizes a generated repository
+type Result struct {
+	Files       int
+	BinaryFiles int
+	Revisions   int   // File revisions, on trunk and branches
+	Commits     int   // Changesets the revisions form
+	Bytes       int64 // Size of the RCS files
+}
+
+var authors = []string{"alice", "bob", "carol", "dave"}
+
+// baseDate is the date of the first commit
+var baseDate = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Generate writes a CVS repository to dir, which must not exist or be
+// empty. Files changed by one commit share their author, message, date and
+// revision number, so the reader groups them into one changeset. The
+// contents depend on the options only.
+func Generate(dir string, opts Options) (*Result, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	cvsroot := filepath.Join(dir, "CVSROOT")
+	if err := os.MkdirAll(cvsroot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", cvsroot, err)
+	}
+	for _, name := range []string{"history", "val-tags"} {
+		if err := os.WriteFile(filepath.Join(cvsroot, name), nil, 0644); err != nil {
+			return nil, fmt.Errorf("failed to create CVSROOT/%s: %w", name, err)
+		}
+	}
+
+	groups := (opts.Files + opts.FilesPerCommit - 1) / opts.FilesPerCommit
+	result := &Result{
+		Files:   opts.Files,
+		Commits: groups * (opts.Revisions + opts.Branches*opts.BranchRevisions),
+	}
+	for f := 0; f < opts.Files; f++ {
+		binary := int(float64(f+1)*opts.BinaryRatio) > int(float64(f)*opts.BinaryRatio)
+		ext := ".txt"
+		if binary {
+			ext = ".bin"
+			result.BinaryFiles++
+		}
+		rel := filepath.Join(fmt.Sprintf("dir%02d", f%opts.Dirs), fmt.Sprintf("file%05d%s,v", f, ext))
+
+		rcs := generateFile(opts, f, binary)
+		result.Revisions += len(rcs.Deltas)
+		n, err := writeFile(filepath.Join(dir, rel), rcs)
+		if err != nil {
+			return nil, err
+		}
+		result.Bytes += n
+	}
+	return result, nil
+}
+
+// withDefaults validates the options and fills in the defaults
+func (o Options) withDefaults() (Options, error) {
+	if o.Files < 0 || o.Revisions < 0 || o.Branches < 0 || o.BranchRevisions < 0 ||
+		o.FilesPerCommit < 0 || o.Dirs < 0 || o.Lines < 0 {
+		return o, fmt.Errorf("counts must not be negative")
+	}
+	if o.BinaryRatio < 0 || o.BinaryRatio > 1 {
+		return o, fmt.Errorf("binary ratio must be between 0 and 1, got %v", o.BinaryRatio)
+	}
+	def := DefaultOptions()
+	for _, field := range []struct{ value, def *int }{
+		{&o.Files, &def.Files},
+		{&o.Revisions, &def.Revisions},
+		{&o.BranchRevisions, &def.BranchRevisions},
+		{&o.FilesPerCommit, &def.FilesPerCommit},
+		{&o.Dirs, &def.Dirs},
+		{&o.Lines, &def.Lines},
+	} {
+		if *field.value == 0 {
+			*field.value = *field.def
+		}
+	}
+	return o, nil
+}
+
+// generateFile builds the RCS file with index f. Branch b sprouts from an
+// evenly spaced trunk revision of every file.
+func generateFile(opts Options, f int, binary bool) *cvs.RCSFile {
+	rng := rand.New(rand.NewSource(opts.Seed*1000003 + int64(f)))
+	groups := (opts.Files + opts.FilesPerCommit - 1) / opts.FilesPerCommit
+	group := f / opts.FilesPerCommit
+
+	rcs := &cvs.RCSFile{
+		Head:    "1." + strconv.Itoa(opts.Revisions),
+		Symbols: make(map[string]string),
+		Locks:   make(map[string]string),
+		Deltas:  make(map[string]*cvs.Delta),
+	}
+	if binary {
+		rcs.Expand = "b"
+	}
+
+	trunkDate := func(j int) time.Time {
+		return baseDate.Add(time.Duration(j*groups+group) * time.Hour)
+	}
+	contents := make([][]byte, opts.Revisions)
+	contents[0] = initialContent(rng, f, opts.Lines, binary)
+	for j := 1; j < opts.Revisions; j++ {
+		contents[j] = mutate(rng, contents[j-1], binary, fmt.Sprintf("1.%d", j+1))
+	}
+	for j := opts.Revisions; j >= 1; j-- {
+		rev := "1." + strconv.Itoa(j)
+		delta := &cvs.Delta{
+			Revision: rev,
+			Date:     trunkDate(j - 1),
+			Author:   authors[(j+group)%len(authors)],
+			State:    "Exp",
+			Log:      fmt.Sprintf("Change %d of group %d\n", j, group),
+		}
+		if j > 1 {
+			delta.Next = "1." + strconv.Itoa(j-1)
+		}
+		if j == opts.Revisions {
+			delta.Text = string(contents[j-1])
+		} else {
+			delta.Text = cvs.RCSDiff(contents[j], contents[j-1])
+		}
+		rcs.Deltas[rev] = delta
+		rcs.DeltaOrder = append(rcs.DeltaOrder, rev)
+	}
+
+	perRoot := make(map[int]int) // Branches sprouted from each trunk revision
+	for b := 0; b < opts.Branches; b++ {
+		root := min((b+1)*opts.Revisions/(opts.Branches+1), opts.Revisions-1)
+		rootRev := "1." + strconv.Itoa(root+1)
+		number := strconv.Itoa(2 + 2*perRoot[root])
+		perRoot[root]++
+		rcs.Symbols[fmt.Sprintf("branch-%d", b)] = rootRev + ".0." + number
+
+		prev := contents[root]
+		for i := 0; i < opts.BranchRevisions; i++ {
+			rev := rootRev + "." + number + "." + strconv.Itoa(i+1)
+			cur := mutate(rng, prev, binary, rev)
+			delta := &cvs.Delta{
+				Revision: rev,
+				Date:     trunkDate(root).Add(time.Duration(b*opts.BranchRevisions+i+1) * time.Second),
+				Author:   authors[(b+i)%len(authors)],
+				State:    "Exp",
+				Log:      fmt.Sprintf("Change %d of group %d on branch-%d\n", i+1, group, b),
+				Text:     cvs.RCSDiff(prev, cur),
+			}
+			if i+1 < opts.BranchRevisions {
+				delta.Next = rootRev + "." + number + "." + strconv.Itoa(i+2)
+			}
+			if i == 0 {
+				rcs.Deltas[rootRev].Branches = append(rcs.Deltas[rootRev].Branches, rev)
+			}
+			rcs.Deltas[rev] = delta
+			rcs.DeltaOrder = append(rcs.DeltaOrder, rev)
+			prev = cur
+		}
+	}
+	return rcs
+}
+
+// initialContent returns the first revision of a file
+func initialContent(rng *rand.Rand, f, lines int, binary bool) []byte {
+	if binary {
+		data := make([]byte, lines*64)
+		rng.Read(data)
+		return data
+	}
+	var b bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "file %d line %d: %s\n", f, i+1, word(rng))
+	}
+	return b.Bytes()
+}
+
+// mutate returns a new revision of content: a text file gets a line
+// replaced and possibly one inserted or deleted, a binary file a block
+// overwritten
+func mutate(rng *rand.Rand, content []byte, binary bool, rev string) []byte {
+	if binary {
+		data := bytes.Clone(content)
+		n := min(64, len(data))
+		rng.Read(data[rng.Intn(len(data)-n+1):][:n])
+		return data
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	lines = lines[:len(lines)-1] // Content ends with a newline
+	i := rng.Intn(len(lines))
+	lines[i] = fmt.Sprintf("revision %s: %s\n", rev, word(rng))
+	switch rng.Intn(3) {
+	case 0:
+		at := rng.Intn(len(lines) + 1)
+		lines = append(lines[:at], append([]string{fmt.Sprintf("added in %s\n", rev)}, lines[at:]...)...)
+	case 1:
+		if len(lines) > 1 {
+			at := rng.Intn(len(lines))
+			lines = append(lines[:at], lines[at+1:]...)
+		}
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// word returns a random lowercase word
+func word(rng *rand.Rand) string {
+	b := make([]byte, 4+rng.Intn(8))
+	for i := range b {
+		b[i] = byte('a' + rng.Intn(26))
+	}
+	return string(b)
+}
+
+// writeFile writes an RCS file, creating its directory
+func writeFile(path string, rcs *cvs.RCSFile) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	n, err := rcs.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return n, nil
+}
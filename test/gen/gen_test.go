@@ -0,0 +1,72 @@
+package gen
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cvs")
+	opts := Options{Files: 12, Revisions: 4, Branches: 2, BinaryRatio: 0.25, FilesPerCommit: 3, Lines: 10}
+	result, err := Generate(dir, opts)
+	require.NoError(t, err)
+	require.Equal(t, 12, result.Files)
+	require.Equal(t, 3, result.BinaryFiles)
+	require.Equal(t, 12*(4+2*2), result.Revisions)
+	require.Equal(t, 4*(4+2*2), result.Commits)
+
+	reader := cvs.NewReader(dir)
+	require.NoError(t, reader.Validate())
+	iter, err := reader.GetCommits()
+	require.NoError(t, err)
+	commits := 0
+	for iter.Next() {
+		commit := iter.Commit()
+		require.Len(t, commit.Files, 3, "commit %s", commit.Message)
+		for i := range commit.Files {
+			rc, err := commit.Files[i].Open()
+			require.NoError(t, err)
+			_, err = io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+		}
+		commits++
+	}
+	require.NoError(t, iter.Err())
+	require.Equal(t, result.Commits, commits)
+
+	branches, err := reader.GetBranches()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"branch-0", "branch-1"}, branches)
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	dirA, dirB := filepath.Join(t.TempDir(), "a"), filepath.Join(t.TempDir(), "b")
+	_, err := Generate(dirA, Options{Files: 3, BinaryRatio: 0.5})
+	require.NoError(t, err)
+	_, err = Generate(dirB, Options{Files: 3, BinaryRatio: 0.5})
+	require.NoError(t, err)
+
+	a, err := os.ReadFile(filepath.Join(dirA, "dir01", "file00001.bin,v"))
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(dirB, "dir01", "file00001.bin,v"))
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	_, err := Generate(t.TempDir(), Options{BinaryRatio: 2})
+	require.Error(t, err)
+	_, err = Generate(t.TempDir(), Options{Files: -1})
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "x"), nil, 0644))
+	_, err = Generate(dir, Options{})
+	require.Error(t, err, "the directory is not empty")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/adamf123git/git-migrator/internal/web"
@@ -55,9 +56,11 @@ func TestAPIListMigrations(t *testing.T) {
 	assert.True(t, response.Success)
 	assert.NotNil(t, response.Data)
 
-	// Data should be an array of migrations
-	_, ok := response.Data.([]interface{})
-	assert.True(t, ok, "Data should be an array")
+	// Data should be a page of migrations
+	data, ok := response.Data.(map[string]interface{})
+	require.True(t, ok, "Data should be an object")
+	_, ok = data["migrations"].([]interface{})
+	assert.True(t, ok, "migrations should be an array")
 }
 
 // TestAPIStartMigration tests starting a new migration
@@ -69,8 +72,8 @@ func TestAPIStartMigration(t *testing.T) {
 
 	migrationReq := web.StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: "../../../test/fixtures/cvs/simple",
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 		Options: map[string]interface{}{
 			"dryRun": true,
 		},
@@ -110,8 +113,8 @@ func TestAPIGetMigrationStatus(t *testing.T) {
 	// First create a migration
 	migrationReq := web.StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: "../../../test/fixtures/cvs/simple",
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 		Options: map[string]interface{}{
 			"dryRun": true,
 		},
@@ -159,8 +162,8 @@ func TestAPIStopMigration(t *testing.T) {
 	// First create a migration
 	migrationReq := web.StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: "../../../test/fixtures/cvs/simple",
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 		Options: map[string]interface{}{
 			"dryRun": true,
 		},
@@ -253,7 +256,7 @@ func TestAPIAnalyzeRepository(t *testing.T) {
 
 	analyzeReq := web.AnalyzeRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
+		SourcePath: "../../../test/fixtures/cvs/simple",
 	}
 
 	body, err := json.Marshal(analyzeReq)
@@ -283,13 +286,14 @@ func TestAPIErrorResponse(t *testing.T) {
 	router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 
-	var response web.APIResponse
-	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	var problem web.Problem
+	err := json.Unmarshal(rec.Body.Bytes(), &problem)
 	require.NoError(t, err)
 
-	assert.False(t, response.Success)
-	assert.NotNil(t, response.Error)
+	assert.Equal(t, "NOT_FOUND", problem.Code)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
 }
 
 // TestAPIInvalidJSON tests handling invalid JSON
@@ -307,10 +311,9 @@ func TestAPIInvalidJSON(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-	var response web.APIResponse
-	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	var problem web.Problem
+	err := json.Unmarshal(rec.Body.Bytes(), &problem)
 	require.NoError(t, err)
 
-	assert.False(t, response.Success)
-	assert.NotNil(t, response.Error)
+	assert.Equal(t, "INVALID_JSON", problem.Code)
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -163,8 +164,8 @@ func TestWebSocketCloseOnCompletion(t *testing.T) {
 	// Create a migration first via API
 	migrationReq := web.StartMigrationRequest{
 		SourceType: "cvs",
-		SourcePath: "/tmp/test-cvs",
-		TargetPath: "/tmp/test-git",
+		SourcePath: "../../../test/fixtures/cvs/simple",
+		TargetPath: filepath.Join(t.TempDir(), "test-git"),
 		Options: map[string]interface{}{
 			"dryRun": true,
 		},